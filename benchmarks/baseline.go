@@ -0,0 +1,210 @@
+/*
+Package benchmarks holds the LessGo benchmark suite and helpers to gate performance regressions.
+
+The suite covers router matching, middleware chains, JSON encoding and decoding, caching and rate
+limiting. RunLoad drives concurrent load against a running application and reports latency
+percentiles and error rates per route, to compare middleware configurations end to end. Results of `go test -bench` can be stored as a baseline and later runs compared against
+it, failing when a benchmark got slower or allocates more than the configured thresholds allow.
+Downstream applications can use the same helpers for their own benchmarks.
+
+Usage:
+
+	go test -run '^$' -bench . -benchmem ./benchmarks | tee bench.txt
+	go run ./benchmarks/cmd/benchgate -baseline benchmarks/baseline.json -update < bench.txt
+	go run ./benchmarks/cmd/benchgate -baseline benchmarks/baseline.json -max-slowdown 0.15 < bench.txt
+	go run ./benchmarks/cmd/loadgen -c 50 -d 30s "GET http://localhost:8080/products" "GET http://localhost:8080/products/42"
+*/
+package benchmarks
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Result is the outcome of a single benchmark.
+type Result struct {
+	Name        string  `json:"name"`
+	Iterations  int64   `json:"iterations"`
+	NsPerOp     float64 `json:"ns_per_op"`
+	BytesPerOp  float64 `json:"bytes_per_op"`
+	AllocsPerOp float64 `json:"allocs_per_op"`
+}
+
+// Baseline maps benchmark names to their stored results.
+type Baseline map[string]Result
+
+// Thresholds are the allowed relative increases before a change counts as a regression,
+// e.g. 0.10 allows a benchmark to get 10% slower. A negative threshold disables the check.
+type Thresholds struct {
+	NsPerOp     float64
+	BytesPerOp  float64
+	AllocsPerOp float64
+}
+
+// DefaultThresholds allows 10% slowdowns and no additional allocations.
+func DefaultThresholds() Thresholds {
+	return Thresholds{NsPerOp: 0.10, BytesPerOp: 0.10, AllocsPerOp: 0}
+}
+
+// Regression describes a metric that exceeds its threshold.
+type Regression struct {
+	Name     string
+	Metric   string
+	Baseline float64
+	Current  float64
+}
+
+// Change returns the relative increase of the metric.
+func (r Regression) Change() float64 {
+	if r.Baseline == 0 {
+		return 1
+	}
+	return (r.Current - r.Baseline) / r.Baseline
+}
+
+func (r Regression) String() string {
+	return fmt.Sprintf("%s: %s %.2f -> %.2f (%+.1f%%)", r.Name, r.Metric, r.Baseline, r.Current, r.Change()*100)
+}
+
+// benchLine matches a result line such as
+// BenchmarkRouter/static-8  1000000  1052 ns/op  480 B/op  6 allocs/op
+var benchLine = regexp.MustCompile(`^(Benchmark\S+?)(?:-\d+)?\s+(\d+)\s+(.*)$`)
+
+// ParseOutput reads results from `go test -bench` output. Other lines are ignored. When a
+// benchmark appears several times (-count), the fastest run is kept to reduce noise.
+func ParseOutput(r io.Reader) ([]Result, error) {
+	best := make(map[string]Result)
+	var order []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		match := benchLine.FindStringSubmatch(strings.TrimSpace(scanner.Text()))
+		if match == nil {
+			continue
+		}
+		iterations, _ := strconv.ParseInt(match[2], 10, 64)
+		result := Result{Name: match[1], Iterations: iterations}
+
+		fields := strings.Fields(match[3])
+		for i := 0; i+1 < len(fields); i += 2 {
+			value, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				continue
+			}
+			switch fields[i+1] {
+			case "ns/op":
+				result.NsPerOp = value
+			case "B/op":
+				result.BytesPerOp = value
+			case "allocs/op":
+				result.AllocsPerOp = value
+			}
+		}
+
+		existing, seen := best[result.Name]
+		if !seen {
+			order = append(order, result.Name)
+		}
+		if !seen || result.NsPerOp < existing.NsPerOp {
+			best[result.Name] = result
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, 0, len(order))
+	for _, name := range order {
+		results = append(results, best[name])
+	}
+	return results, nil
+}
+
+// LoadBaseline reads a baseline stored with SaveBaseline.
+func LoadBaseline(path string) (Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	baseline := make(Baseline)
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		return nil, fmt.Errorf("parsing baseline %s: %w", path, err)
+	}
+	return baseline, nil
+}
+
+// SaveBaseline stores results as the new baseline.
+func SaveBaseline(path string, results []Result) error {
+	baseline := make(Baseline, len(results))
+	for _, result := range results {
+		baseline[result.Name] = result
+	}
+	data, err := json.MarshalIndent(baseline, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Compare returns the metrics of current results that regressed beyond the thresholds.
+// Benchmarks missing from the baseline are ignored.
+func Compare(baseline Baseline, current []Result, thresholds Thresholds) []Regression {
+	var regressions []Regression
+	for _, result := range current {
+		base, ok := baseline[result.Name]
+		if !ok {
+			continue
+		}
+		for _, metric := range []struct {
+			name      string
+			base, cur float64
+			threshold float64
+		}{
+			{"ns/op", base.NsPerOp, result.NsPerOp, thresholds.NsPerOp},
+			{"B/op", base.BytesPerOp, result.BytesPerOp, thresholds.BytesPerOp},
+			{"allocs/op", base.AllocsPerOp, result.AllocsPerOp, thresholds.AllocsPerOp},
+		} {
+			if metric.threshold < 0 {
+				continue
+			}
+			if metric.cur > metric.base*(1+metric.threshold) {
+				regressions = append(regressions, Regression{
+					Name: result.Name, Metric: metric.name, Baseline: metric.base, Current: metric.cur,
+				})
+			}
+		}
+	}
+	sort.Slice(regressions, func(i, j int) bool {
+		return regressions[i].Name < regressions[j].Name
+	})
+	return regressions
+}
+
+// Check compares `go test -bench` output against the baseline at path and returns an error
+// listing all regressions, if any.
+func Check(baselinePath string, output io.Reader, thresholds Thresholds) error {
+	baseline, err := LoadBaseline(baselinePath)
+	if err != nil {
+		return err
+	}
+	results, err := ParseOutput(output)
+	if err != nil {
+		return err
+	}
+	regressions := Compare(baseline, results, thresholds)
+	if len(regressions) == 0 {
+		return nil
+	}
+	lines := make([]string, len(regressions))
+	for i, regression := range regressions {
+		lines[i] = regression.String()
+	}
+	return fmt.Errorf("%d performance regressions:\n%s", len(regressions), strings.Join(lines, "\n"))
+}
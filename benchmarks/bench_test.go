@@ -0,0 +1,155 @@
+package benchmarks_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/hokamsingh/lessgo/internal/core/context"
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+	"github.com/hokamsingh/lessgo/internal/core/router"
+)
+
+func TestMain(m *testing.M) {
+	// The router logs every request, which would dominate the measurements
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+type user struct {
+	ID     int      `json:"id"`
+	Name   string   `json:"name"`
+	Email  string   `json:"email"`
+	Roles  []string `json:"roles"`
+	Active bool     `json:"active"`
+}
+
+var sampleUser = user{ID: 42, Name: "Ann", Email: "ann@example.com", Roles: []string{"admin", "editor"}, Active: true}
+
+// serve runs the request against the handler b.N times.
+func serve(b *testing.B, handler http.Handler, method, target string, body []byte) {
+	b.Helper()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var reader io.Reader
+		if body != nil {
+			reader = bytes.NewReader(body)
+		}
+		req := httptest.NewRequest(method, target, reader)
+		req.RemoteAddr = "192.0.2.1:1234"
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code >= 500 {
+			b.Fatalf("unexpected status %d", rec.Code)
+		}
+	}
+}
+
+// chain applies middleware the same way Router.Start does.
+func chain(handler http.Handler, mws ...middleware.Middleware) http.Handler {
+	for _, m := range mws {
+		handler = m.Handle(handler)
+	}
+	return handler
+}
+
+func noop(ctx *context.Context) {
+	ctx.Res.WriteHeader(http.StatusNoContent)
+}
+
+func BenchmarkRouter(b *testing.B) {
+	r := router.NewRouter()
+	for i := 0; i < 50; i++ {
+		r.Get(fmt.Sprintf("/static/resource%d", i), noop)
+		r.Get(fmt.Sprintf("/api/v1/resource%d/{id}", i), noop)
+		r.Get(fmt.Sprintf("/api/v1/resource%d/{id}/children/{child}", i), noop)
+	}
+
+	b.Run("static", func(b *testing.B) {
+		serve(b, r.Mux, http.MethodGet, "/static/resource25", nil)
+	})
+	b.Run("param", func(b *testing.B) {
+		serve(b, r.Mux, http.MethodGet, "/api/v1/resource25/123", nil)
+	})
+	b.Run("nested_params", func(b *testing.B) {
+		serve(b, r.Mux, http.MethodGet, "/api/v1/resource49/123/children/456", nil)
+	})
+	b.Run("not_found", func(b *testing.B) {
+		serve(b, r.Mux, http.MethodGet, "/missing/route", nil)
+	})
+}
+
+func BenchmarkMiddlewareChain(b *testing.B) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	cors := middleware.NewCORSMiddleware(*middleware.NewCorsOptions(
+		[]string{"*"}, []string{"GET", "POST"}, []string{"Content-Type"}))
+
+	b.Run("none", func(b *testing.B) {
+		serve(b, chain(handler), http.MethodGet, "/", nil)
+	})
+	b.Run("cors", func(b *testing.B) {
+		serve(b, chain(handler, cors), http.MethodGet, "/", nil)
+	})
+	b.Run("cors_xss_cookies", func(b *testing.B) {
+		serve(b, chain(handler, cors, middleware.NewXSSProtection(), middleware.NewCookieParser()), http.MethodGet, "/", nil)
+	})
+}
+
+func BenchmarkJSON(b *testing.B) {
+	r := router.NewRouter()
+	r.Get("/user", func(ctx *context.Context) {
+		ctx.JSON(http.StatusOK, sampleUser)
+	})
+	r.Post("/user", func(ctx *context.Context) {
+		var u user
+		if err := ctx.Body(&u); err != nil {
+			ctx.Error(http.StatusBadRequest, "invalid body")
+			return
+		}
+		ctx.Res.WriteHeader(http.StatusNoContent)
+	})
+	body := []byte(`{"id":42,"name":"Ann","email":"ann@example.com","roles":["admin","editor"],"active":true}`)
+
+	b.Run("encode", func(b *testing.B) {
+		serve(b, r.Mux, http.MethodGet, "/user", nil)
+	})
+	b.Run("decode", func(b *testing.B) {
+		serve(b, r.Mux, http.MethodPost, "/user", body)
+	})
+}
+
+// BenchmarkRateLimiter measures a single client, which is throttled once it exceeds the limit.
+func BenchmarkRateLimiter(b *testing.B) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	limiter := middleware.NewRateLimiter(middleware.InMemory, *middleware.NewInMemoryConfig(16, 100, time.Minute, time.Minute))
+	serve(b, chain(handler, limiter), http.MethodGet, "/", nil)
+}
+
+// BenchmarkCaching needs a Redis server, e.g. BENCH_REDIS_ADDR=localhost:6379.
+func BenchmarkCaching(b *testing.B) {
+	addr := os.Getenv("BENCH_REDIS_ADDR")
+	if addr == "" {
+		b.Skip("BENCH_REDIS_ADDR not set")
+	}
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	defer client.Close()
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":42,"name":"Ann"}`))
+	})
+	caching := middleware.NewCaching(client, time.Minute, true)
+	serve(b, chain(handler, caching), http.MethodGet, "/cached", nil)
+}
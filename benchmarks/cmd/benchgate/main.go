@@ -0,0 +1,48 @@
+// Command benchgate compares `go test -bench` output read from stdin against a stored baseline
+// and exits with a non-zero status when a benchmark regressed beyond the thresholds.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/hokamsingh/lessgo/benchmarks"
+)
+
+func main() {
+	baselinePath := flag.String("baseline", "benchmarks/baseline.json", "path of the baseline file")
+	update := flag.Bool("update", false, "store the results as the new baseline instead of comparing")
+	defaults := benchmarks.DefaultThresholds()
+	maxSlowdown := flag.Float64("max-slowdown", defaults.NsPerOp, "allowed relative increase of ns/op, -1 to disable")
+	maxBytes := flag.Float64("max-bytes", defaults.BytesPerOp, "allowed relative increase of B/op, -1 to disable")
+	maxAllocs := flag.Float64("max-allocs", defaults.AllocsPerOp, "allowed relative increase of allocs/op, -1 to disable")
+	flag.Parse()
+
+	output, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		log.Fatalf("Reading benchmark output: %v", err)
+	}
+
+	if *update {
+		results, err := benchmarks.ParseOutput(bytes.NewReader(output))
+		if err != nil {
+			log.Fatalf("Parsing benchmark output: %v", err)
+		}
+		if err := benchmarks.SaveBaseline(*baselinePath, results); err != nil {
+			log.Fatalf("Saving baseline: %v", err)
+		}
+		fmt.Printf("Stored %d results in %s\n", len(results), *baselinePath)
+		return
+	}
+
+	thresholds := benchmarks.Thresholds{NsPerOp: *maxSlowdown, BytesPerOp: *maxBytes, AllocsPerOp: *maxAllocs}
+	if err := benchmarks.Check(*baselinePath, bytes.NewReader(output), thresholds); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Println("No performance regressions")
+}
@@ -0,0 +1,122 @@
+// Command loadgen drives concurrent load against a running application and prints latency
+// percentiles and error rates per route. Each argument is a target, a URL optionally preceded
+// by the method and followed by a weight:
+//
+//	loadgen -c 50 -d 30s "GET http://localhost:8080/products 4" "POST http://localhost:8080/orders"
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+
+	"github.com/hokamsingh/lessgo/benchmarks"
+)
+
+type headers http.Header
+
+func (h headers) String() string { return "" }
+
+func (h headers) Set(value string) error {
+	name, v, ok := strings.Cut(value, ":")
+	if !ok {
+		return fmt.Errorf("expected Name: value, got %q", value)
+	}
+	http.Header(h).Add(strings.TrimSpace(name), strings.TrimSpace(v))
+	return nil
+}
+
+func main() {
+	defaults := benchmarks.NewLoadOptions()
+	concurrency := flag.Int("c", defaults.Concurrency, "number of requests in flight")
+	duration := flag.Duration("d", defaults.Duration, "duration of the run, 0 to send -n requests")
+	requests := flag.Int("n", 0, "number of requests to send, 0 to run for -d")
+	rate := flag.Int("rate", 0, "requests per second, 0 for as fast as possible")
+	timeout := flag.Duration("timeout", defaults.Timeout, "timeout per request")
+	body := flag.String("body", "", "body of the requests, @file reads it from a file")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	header := headers{}
+	flag.Var(header, "H", "header to send, e.g. -H 'Authorization: Bearer token', repeatable")
+	flag.Parse()
+
+	var payload []byte
+	if strings.HasPrefix(*body, "@") {
+		var err error
+		if payload, err = os.ReadFile((*body)[1:]); err != nil {
+			log.Fatalf("Reading body: %v", err)
+		}
+	} else if *body != "" {
+		payload = []byte(*body)
+	}
+
+	var targets []benchmarks.Target
+	for _, arg := range flag.Args() {
+		target, err := parseTarget(arg)
+		if err != nil {
+			log.Fatal(err)
+		}
+		target.Header = http.Header(header)
+		if target.Method != http.MethodGet && target.Method != http.MethodHead {
+			target.Body = payload
+		}
+		targets = append(targets, target)
+	}
+	if len(targets) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: loadgen [flags] \"[METHOD] URL [WEIGHT]\"...")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	options := benchmarks.LoadOptions{
+		Concurrency: *concurrency,
+		Duration:    *duration,
+		Requests:    *requests,
+		Rate:        *rate,
+		Timeout:     *timeout,
+	}
+	report, err := benchmarks.RunLoad(ctx, targets, options)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(report)
+	} else {
+		err = report.Write(os.Stdout)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// parseTarget parses "[METHOD] URL [WEIGHT]".
+func parseTarget(arg string) (benchmarks.Target, error) {
+	fields := strings.Fields(arg)
+	target := benchmarks.Target{Method: http.MethodGet}
+	if len(fields) > 1 && !strings.Contains(fields[0], "/") {
+		target.Method = strings.ToUpper(fields[0])
+		fields = fields[1:]
+	}
+	if len(fields) == 0 || len(fields) > 2 {
+		return target, fmt.Errorf("invalid target %q, expected \"[METHOD] URL [WEIGHT]\"", arg)
+	}
+	target.URL = fields[0]
+	if len(fields) == 2 {
+		weight, err := strconv.Atoi(fields[1])
+		if err != nil || weight <= 0 {
+			return target, fmt.Errorf("invalid weight in target %q", arg)
+		}
+		target.Weight = weight
+	}
+	return target, nil
+}
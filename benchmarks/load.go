@@ -0,0 +1,307 @@
+package benchmarks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+)
+
+// Target is a request sent by RunLoad.
+type Target struct {
+	// Name groups the results, by default the method and URL.
+	Name   string
+	Method string
+	URL    string
+	Header http.Header
+	Body   []byte
+	// Weight is the share of the requests sent to the target relative to the others, 1 when zero.
+	Weight int
+}
+
+// LoadOptions configures RunLoad. The load stops after Duration or once Requests requests
+// have been sent, whichever comes first.
+type LoadOptions struct {
+	Concurrency int           // Number of requests in flight
+	Duration    time.Duration // Zero runs until Requests are sent
+	Requests    int           // Zero runs for Duration
+	Rate        int           // Requests per second over all workers, zero for as fast as possible
+	Timeout     time.Duration // Per request
+	Client      *http.Client
+}
+
+// NewLoadOptions creates LoadOptions running 10 concurrent requests for 10 seconds.
+func NewLoadOptions() *LoadOptions {
+	return &LoadOptions{
+		Concurrency: 10,
+		Duration:    10 * time.Second,
+		Timeout:     10 * time.Second,
+	}
+}
+
+// RouteReport are the results of a target.
+type RouteReport struct {
+	Name      string        `json:"name"`
+	Requests  int           `json:"requests"`
+	Errors    int           `json:"errors"`
+	ErrorRate float64       `json:"error_rate"`
+	Statuses  map[int]int   `json:"statuses"`
+	Mean      time.Duration `json:"mean"`
+	P50       time.Duration `json:"p50"`
+	P90       time.Duration `json:"p90"`
+	P99       time.Duration `json:"p99"`
+	Max       time.Duration `json:"max"`
+}
+
+// LoadReport are the results of RunLoad.
+type LoadReport struct {
+	Duration   time.Duration `json:"duration"`
+	Requests   int           `json:"requests"`
+	Errors     int           `json:"errors"`
+	Throughput float64       `json:"throughput"` // Requests per second
+	Routes     []RouteReport `json:"routes"`
+}
+
+// routeSamples collects the latencies and outcomes of a target.
+type routeSamples struct {
+	mu        sync.Mutex
+	latencies []time.Duration
+	errors    int
+	statuses  map[int]int
+}
+
+// RunLoad sends requests to the targets from Concurrency workers until the duration or
+// number of requests is reached or ctx is canceled, and reports latency percentiles and
+// error rates per target. Failed requests and 5xx responses count as errors; the statuses of
+// a target show rejections such as 429 of a rate limiter.
+//
+// Example usage:
+//
+//	options := benchmarks.NewLoadOptions()
+//	options.Concurrency = 50
+//	report, err := benchmarks.RunLoad(ctx, []benchmarks.Target{
+//		{Method: http.MethodGet, URL: "http://localhost:8080/products", Weight: 4},
+//		{Method: http.MethodGet, URL: "http://localhost:8080/products/42"},
+//	}, *options)
+//	report.Write(os.Stdout)
+func RunLoad(ctx context.Context, targets []Target, options LoadOptions) (*LoadReport, error) {
+	if len(targets) == 0 {
+		return nil, errors.New("no targets to load")
+	}
+	if options.Duration <= 0 && options.Requests <= 0 {
+		return nil, errors.New("either a duration or a number of requests is required")
+	}
+	if options.Concurrency <= 0 {
+		options.Concurrency = 1
+	}
+	client := options.Client
+	if client == nil {
+		client = &http.Client{
+			Timeout: options.Timeout,
+			Transport: &http.Transport{
+				MaxIdleConns:        options.Concurrency,
+				MaxIdleConnsPerHost: options.Concurrency,
+			},
+		}
+	}
+
+	// Expand the targets by weight, workers pick them round-robin
+	targets = append([]Target{}, targets...)
+	var schedule []int
+	for i, target := range targets {
+		if target.Method == "" {
+			targets[i].Method = http.MethodGet
+		}
+		if target.Name == "" {
+			targets[i].Name = targets[i].Method + " " + target.URL
+		}
+		weight := target.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		for j := 0; j < weight; j++ {
+			schedule = append(schedule, i)
+		}
+	}
+	samples := make([]*routeSamples, len(targets))
+	for i := range samples {
+		samples[i] = &routeSamples{statuses: map[int]int{}}
+	}
+
+	if options.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.Duration)
+		defer cancel()
+	}
+	var tick <-chan time.Time
+	if options.Rate > 0 {
+		ticker := time.NewTicker(time.Second / time.Duration(options.Rate))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	var sent int64
+	var wg sync.WaitGroup
+	start := time.Now()
+	for w := 0; w < options.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				n := atomic.AddInt64(&sent, 1)
+				if options.Requests > 0 && n > int64(options.Requests) {
+					return
+				}
+				if tick != nil {
+					select {
+					case <-tick:
+					case <-ctx.Done():
+						return
+					}
+				}
+				if ctx.Err() != nil {
+					return
+				}
+				i := schedule[int(n-1)%len(schedule)]
+				status, latency, err := send(ctx, client, targets[i])
+				if err != nil && ctx.Err() != nil {
+					// Requests cut off by the end of the run are not counted
+					return
+				}
+				samples[i].record(status, latency, err)
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	report := &LoadReport{Duration: elapsed}
+	for i, target := range targets {
+		route := samples[i].report(target.Name)
+		report.Requests += route.Requests
+		report.Errors += route.Errors
+		report.Routes = append(report.Routes, route)
+	}
+	if elapsed > 0 {
+		report.Throughput = float64(report.Requests) / elapsed.Seconds()
+	}
+	return report, nil
+}
+
+// send performs the request of the target and reads the response.
+func send(ctx context.Context, client *http.Client, target Target) (int, time.Duration, error) {
+	var body io.Reader
+	if target.Body != nil {
+		body = bytes.NewReader(target.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, target.Method, target.URL, body)
+	if err != nil {
+		return 0, 0, err
+	}
+	for name, values := range target.Header {
+		req.Header[name] = values
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, time.Since(start), err
+	}
+	_, err = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return resp.StatusCode, time.Since(start), err
+}
+
+func (s *routeSamples) record(status int, latency time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latencies = append(s.latencies, latency)
+	if err != nil || status >= http.StatusInternalServerError {
+		s.errors++
+	}
+	if err == nil {
+		s.statuses[status]++
+	}
+}
+
+func (s *routeSamples) report(name string) RouteReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	route := RouteReport{Name: name, Requests: len(s.latencies), Errors: s.errors, Statuses: s.statuses}
+	if route.Requests == 0 {
+		return route
+	}
+	sorted := append([]time.Duration{}, s.latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	var total time.Duration
+	for _, latency := range sorted {
+		total += latency
+	}
+	route.ErrorRate = float64(route.Errors) / float64(route.Requests)
+	route.Mean = total / time.Duration(len(sorted))
+	route.P50 = percentile(sorted, 0.50)
+	route.P90 = percentile(sorted, 0.90)
+	route.P99 = percentile(sorted, 0.99)
+	route.Max = sorted[len(sorted)-1]
+	return route
+}
+
+// percentile returns the nearest-rank percentile of sorted samples.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(p*float64(len(sorted))+0.5) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
+
+// Write prints the report as a table, one row per target.
+func (r *LoadReport) Write(w io.Writer) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "ROUTE\tREQUESTS\tERRORS\tP50\tP90\tP99\tMAX\tSTATUSES")
+	for _, route := range r.Routes {
+		fmt.Fprintf(table, "%s\t%d\t%.2f%%\t%s\t%s\t%s\t%s\t%s\n", route.Name, route.Requests, route.ErrorRate*100,
+			round(route.P50), round(route.P90), round(route.P99), round(route.Max), formatStatuses(route.Statuses))
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\n%d requests in %s, %.1f req/s, %d errors\n", r.Requests, round(r.Duration), r.Throughput, r.Errors)
+	return err
+}
+
+func round(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond)
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond)
+	default:
+		return d.Round(time.Microsecond)
+	}
+}
+
+func formatStatuses(statuses map[int]int) string {
+	codes := make([]int, 0, len(statuses))
+	for code := range statuses {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	var b bytes.Buffer
+	for i, code := range codes {
+		if i > 0 {
+			b.WriteString(" ")
+		}
+		fmt.Fprintf(&b, "%d:%d", code, statuses[code])
+	}
+	return b.String()
+}
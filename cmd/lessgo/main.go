@@ -0,0 +1,365 @@
+// Command lessgo generates code for LessGo applications and runs their seeds. Run it from the
+// root of the application, next to go.mod:
+//
+//	lessgo gen resource user
+//	lessgo gen resource order-item -path /api/v1/orders/items -table order_items
+//	lessgo seed -env staging
+//	lessgo config sample -format yaml -o config.sample.yaml
+//	lessgo contract diff contracts/recording.json build/recording.json
+//	lessgo mock -addr :4000 -prefix /api mocks/api.yaml
+//	lessgo replay -target http://localhost:8080 -id 9f86d081 requests.ndjson
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/hokamsingh/lessgo/internal/core/config"
+	"github.com/hokamsingh/lessgo/internal/core/contract"
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+	"github.com/hokamsingh/lessgo/internal/core/mock"
+	"github.com/hokamsingh/lessgo/internal/core/replay"
+	"github.com/hokamsingh/lessgo/internal/core/router"
+	"github.com/hokamsingh/lessgo/internal/core/scaffold"
+)
+
+const usage = `usage:
+  lessgo gen resource [flags] <name>
+  lessgo seed [-pkg dir] [-env name] [-reset seeds|all] [-list]
+  lessgo config sample [-schema file] [-format env|yaml] [-o file]
+  lessgo config check [-schema file]
+  lessgo contract diff [-json] <baseline> <current>
+  lessgo contract openapi [-title name] [-version v] <recording>
+  lessgo mock [-addr :8080] [-prefix path] [-cors=false] <stubs file>
+  lessgo replay [-target url] [-id ids] [-H "Name: value"]... [-v] [-list] <records file>
+`
+
+const resourceUsage = `usage: lessgo gen resource [flags] <name>
+
+Generates the module, controller, service, repository, DTOs and tests of a resource and
+adds the module to the root module.
+
+Flags:
+`
+
+const seedUsage = `usage: lessgo seed [-pkg dir] [seed flags]
+
+Runs the seed program of the application, a main package calling LessGo.RunSeedCommand
+with its seeder. The remaining flags are passed to it:
+
+  -env name      environment whose seeds run, $ENV or development by default
+  -reset seeds   comma separated seeds to run again, all for every seed
+  -list          list the seeds of the environment instead of running them
+`
+
+func main() {
+	switch {
+	case len(os.Args) >= 3 && os.Args[1] == "gen" && os.Args[2] == "resource":
+		genResource(os.Args[3:])
+	case len(os.Args) >= 2 && os.Args[1] == "seed":
+		seed(os.Args[2:])
+	case len(os.Args) >= 3 && os.Args[1] == "config" && (os.Args[2] == "sample" || os.Args[2] == "check"):
+		configCommand(os.Args[2], os.Args[3:])
+	case len(os.Args) >= 3 && os.Args[1] == "contract" && (os.Args[2] == "diff" || os.Args[2] == "openapi"):
+		contractCommand(os.Args[2], os.Args[3:])
+	case len(os.Args) >= 2 && os.Args[1] == "mock":
+		mockServer(os.Args[2:])
+	case len(os.Args) >= 2 && os.Args[1] == "replay":
+		replayRequests(os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func genResource(args []string) {
+	defaults := scaffold.NewResourceOptions()
+	flags := flag.NewFlagSet("gen resource", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprint(os.Stderr, resourceUsage)
+		flags.PrintDefaults()
+	}
+	dir := flags.String("dir", defaults.Dir, "directory of the module packages")
+	rootModule := flags.String("root-module", defaults.RootModule, "file of the root module in -dir, empty to skip wiring")
+	path := flags.String("path", "", "route prefix, the plural of the name by default")
+	table := flags.String("table", "", "database table, the plural of the name by default")
+	force := flags.Bool("force", false, "overwrite existing files")
+	// The name may come before or after the flags
+	var name string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	flags.Parse(args)
+	if name == "" && flags.NArg() == 1 {
+		name = flags.Arg(0)
+	} else if name == "" || flags.NArg() > 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	result, err := scaffold.GenerateResource(".", name, scaffold.ResourceOptions{
+		Dir:        *dir,
+		RootModule: *rootModule,
+		Path:       *path,
+		Table:      *table,
+		Force:      *force,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	for _, file := range result.Files {
+		fmt.Println("created", file)
+	}
+	if result.Wired != "" {
+		fmt.Println("updated", result.Wired)
+	}
+	for _, note := range result.Notes {
+		fmt.Println("next:", note)
+	}
+}
+
+// seed runs the seed program with go run, passing it the flags other than -pkg.
+func seed(args []string) {
+	pkg := "./seeds"
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "-h" || arg == "-help" || arg == "--help":
+			fmt.Fprint(os.Stderr, seedUsage)
+			os.Exit(2)
+		case arg == "-pkg" || arg == "--pkg":
+			if i+1 == len(args) {
+				fmt.Fprint(os.Stderr, seedUsage)
+				os.Exit(2)
+			}
+			i++
+			pkg = args[i]
+		case strings.HasPrefix(arg, "-pkg=") || strings.HasPrefix(arg, "--pkg="):
+			pkg = arg[strings.Index(arg, "=")+1:]
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	if _, err := os.Stat(pkg); err != nil {
+		fmt.Fprintf(os.Stderr, "no seed program in %s, see lessgo seed -h\n", pkg)
+		os.Exit(1)
+	}
+
+	cmd := exec.Command("go", append([]string{"run", pkg}, rest...)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		if exit, ok := err.(*exec.ExitError); ok {
+			os.Exit(exit.ExitCode())
+		}
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// configCommand writes a sample configuration of the schema file, or checks the configuration
+// of the environment and .env file against it.
+func configCommand(command string, args []string) {
+	flags := flag.NewFlagSet("config "+command, flag.ExitOnError)
+	schemaFile := flags.String("schema", "config.schema.yaml", "schema file listing the configuration keys")
+	format := flags.String("format", "env", "format of the sample, env or yaml")
+	output := flags.String("o", "", "file the sample is written to, standard output by default")
+	flags.Parse(args)
+
+	schema, err := config.LoadSchemaFile(*schemaFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if command == "check" {
+		if err := schema.Validate(config.LoadConfig()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println("configuration is valid")
+		return
+	}
+
+	out := os.Stdout
+	if *output != "" {
+		if out, err = os.Create(*output); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer out.Close()
+	}
+	switch *format {
+	case "env":
+		err = schema.WriteEnvSample(out)
+	case "yaml":
+		err = schema.WriteYAMLSample(out)
+	default:
+		err = fmt.Errorf("unknown format %q, use env or yaml", *format)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// contractCommand compares two recordings of the contract recorder, exiting with 1 on breaking
+// changes, or converts a recording to an OpenAPI document.
+func contractCommand(command string, args []string) {
+	flags := flag.NewFlagSet("contract "+command, flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print the changes as JSON")
+	title := flags.String("title", "API", "title of the OpenAPI document")
+	version := flags.String("version", "0.0.0", "version of the OpenAPI document")
+	flags.Parse(args)
+	if command == "diff" && flags.NArg() != 2 || command == "openapi" && flags.NArg() != 1 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	recordings := make([]*contract.Recording, flags.NArg())
+	for i, path := range flags.Args() {
+		recording, err := contract.LoadRecording(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		recordings[i] = recording
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if command == "openapi" {
+		encoder.Encode(recordings[0].OpenAPI(*title, *version))
+		return
+	}
+
+	changes := contract.Diff(recordings[0], recordings[1])
+	if *asJSON {
+		encoder.Encode(changes)
+	} else if len(changes) == 0 {
+		fmt.Println("no contract changes")
+	} else {
+		fmt.Print(contract.Summary(changes))
+	}
+	if len(contract.Breaking(changes)) > 0 {
+		os.Exit(1)
+	}
+}
+
+// mockServer serves the stubs of a YAML or JSON file, allowing cross-origin requests from
+// frontends served elsewhere unless -cors=false.
+func mockServer(args []string) {
+	flags := flag.NewFlagSet("mock", flag.ExitOnError)
+	addr := flags.String("addr", ":8080", "address the mock API listens on")
+	prefix := flags.String("prefix", "", "path prefix of the stub routes, e.g. /api")
+	cors := flags.Bool("cors", true, "allow requests from any origin")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	server, err := mock.LoadServer(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	var options []router.Option
+	if *cors {
+		options = append(options, router.WithCORS(middleware.CORSOptions{}))
+	}
+	r := router.NewRouter(options...)
+	server.RegisterRoutes(r, *prefix)
+	for _, stub := range server.Stubs() {
+		fmt.Printf("%-6s %s%s\n", stub.Method, *prefix, stub.Path)
+	}
+	fmt.Println("mock API listening on", *addr)
+	if err := http.ListenAndServe(*addr, r.Handler()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// headerFlags collects repeated -H flags.
+type headerFlags []string
+
+func (h *headerFlags) String() string { return strings.Join(*h, ", ") }
+
+func (h *headerFlags) Set(value string) error {
+	if !strings.Contains(value, ":") {
+		return fmt.Errorf("expected Name: value, got %q", value)
+	}
+	*h = append(*h, value)
+	return nil
+}
+
+// replayRequests lists the records of a file written by the request recorder or replays them
+// against a running application, printing the recorded and replayed statuses.
+func replayRequests(args []string) {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	target := flags.String("target", "http://localhost:8080", "base URL of the application")
+	ids := flags.String("id", "", "comma separated records to replay, all by default")
+	verbose := flags.Bool("v", false, "print the replayed responses")
+	list := flags.Bool("list", false, "list the records instead of replaying them")
+	var headers headerFlags
+	flags.Var(&headers, "H", "header set on the replayed requests, e.g. credentials redacted when recording")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	records, err := replay.LoadRecords(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if *ids != "" {
+		selected := map[string]bool{}
+		for _, id := range strings.Split(*ids, ",") {
+			selected[strings.TrimSpace(id)] = true
+		}
+		var filtered []replay.Record
+		for _, record := range records {
+			if selected[record.ID] {
+				filtered = append(filtered, record)
+			}
+		}
+		records = filtered
+	}
+	if len(records) == 0 {
+		fmt.Fprintln(os.Stderr, "no records to replay")
+		os.Exit(1)
+	}
+	if *list {
+		for _, record := range records {
+			fmt.Printf("%s %s %d %s %s\n", record.ID, record.Time.Format("2006-01-02T15:04:05Z"), record.Status, record.Method, record.URL)
+		}
+		return
+	}
+
+	failed := false
+	for _, record := range records {
+		result, err := replay.ReplayTo(nil, *target, record, func(r *http.Request) {
+			for _, header := range headers {
+				name, value, _ := strings.Cut(header, ":")
+				r.Header.Set(strings.TrimSpace(name), strings.TrimSpace(value))
+			}
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s %s %s: %v\n", record.ID, record.Method, record.URL, err)
+			failed = true
+			continue
+		}
+		fmt.Printf("%s %s %s: recorded %d, replayed %d\n", record.ID, record.Method, record.URL, result.RecordedStatus, result.Status)
+		if *verbose {
+			fmt.Println(result.Body)
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
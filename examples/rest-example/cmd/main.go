@@ -21,7 +21,7 @@ func main() {
 	// Load Configuration
 	cfg := LessGo.LoadConfig()
 	serverPort := cfg.Get("SERVER_PORT", "8080")
-	env := cfg.Get("ENV", "development")
+	env := cfg.Env()
 	addr := ":" + serverPort
 
 	// CORS Options
@@ -71,7 +71,7 @@ func main() {
 
 	// Start the server
 	log.Printf("Starting server on port %s in %s mode", serverPort, env)
-	// LessGo.PProfiling()
+	// App.EnableProfiling(*LessGo.NewProfilingOptions())
 	httpCfg := LessGo.NewHttpConfig()
 	if err := App.Listen(addr, httpCfg); err != nil {
 		log.Fatalf("Server failed: %v", err)
@@ -0,0 +1,406 @@
+/*
+Package analytics tracks product events such as sign-ups or completed checkouts.
+
+Handlers call ctx.Track with an event name and its properties. The Tracker samples events,
+scrubs personal data from their properties and buffers them in memory; a background loop
+writes them in batches to a Sink: an NDJSON file, a Segment-style HTTP API, a Kafka topic or
+NATS subject through a messaging.Broker, or anything implementing Sink. Requests never wait for a sink.
+
+Usage:
+
+	tracker := analytics.New(analytics.NewHTTPSink("https://api.segment.io/v1/batch", writeKey),
+		analytics.WithSampleRate(0.5), analytics.WithRedactedFields("iban"))
+	defer tracker.Close()
+	App := LessGo.App(LessGo.WithAnalytics(tracker))
+	App.Post("/checkout", func(ctx *LessGo.Context) {
+		// ...
+		ctx.Track("checkout_completed", map[string]interface{}{"total": total})
+	})
+*/
+package analytics
+
+import (
+	"context"
+	"encoding/json"
+	"hash/fnv"
+	"log"
+	"math/rand"
+	"net/http"
+	"regexp"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+	"github.com/hokamsingh/lessgo/internal/core/redact"
+)
+
+// TrackerKey is the key of the Tracker in the request values, see middleware.GetValue.
+const TrackerKey = "analytics.tracker"
+
+const (
+	defaultBatchSize     = 100
+	defaultFlushInterval = 5 * time.Second
+	defaultMaxBuffered   = 10000
+)
+
+// Event is a tracked product event.
+type Event struct {
+	// ID is unique per event, so sinks can drop duplicates of retried batches.
+	ID          string                 `json:"id"`
+	Name        string                 `json:"name"`
+	Time        time.Time              `json:"time"`
+	UserID      string                 `json:"user_id,omitempty"`
+	AnonymousID string                 `json:"anonymous_id,omitempty"`
+	RequestID   string                 `json:"request_id,omitempty"`
+	Properties  map[string]interface{} `json:"properties,omitempty"`
+	// SampleRate is the share of events of this kind that were kept, to weight counts.
+	SampleRate float64 `json:"sample_rate,omitempty"`
+}
+
+// Sink stores batches of events.
+type Sink interface {
+	Write(ctx context.Context, events []Event) error
+}
+
+// SinkFunc adapts a function to the Sink interface.
+type SinkFunc func(ctx context.Context, events []Event) error
+
+func (f SinkFunc) Write(ctx context.Context, events []Event) error {
+	return f(ctx, events)
+}
+
+// emailPattern matches e-mail addresses in property values, which are scrubbed wherever
+// they appear.
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+
+// Tracker buffers events and writes them to a sink in the background.
+type Tracker struct {
+	sink          Sink
+	batchSize     int
+	flushInterval time.Duration
+	maxBuffered   int
+	sampleRate    float64
+	eventRates    map[string]float64
+	redactor      *redact.Redactor
+
+	mu      sync.Mutex
+	events  []Event
+	dropped atomic.Int64
+
+	flushMu sync.Mutex
+	full    chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+	closed  atomic.Bool
+}
+
+// New creates a tracker writing to sink and starts its flush loop. Close flushes the
+// remaining events and stops the loop. Properties are scrubbed with redact.Default plus
+// fields such as email, phone, address and ip_address, and e-mail addresses are masked in any
+// string value.
+//
+// Example usage:
+//
+//	file, err := analytics.NewFileSink("/var/log/app/events.ndjson")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	tracker := analytics.New(file, analytics.WithFlushInterval(time.Second))
+//	defer tracker.Close()
+func New(sink Sink, options ...func(*Tracker)) *Tracker {
+	t := &Tracker{
+		sink:          sink,
+		batchSize:     defaultBatchSize,
+		flushInterval: defaultFlushInterval,
+		maxBuffered:   defaultMaxBuffered,
+		sampleRate:    1,
+		eventRates:    map[string]float64{},
+		full:          make(chan struct{}, 1),
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	for _, option := range options {
+		option(t)
+	}
+	if t.redactor == nil {
+		t.redactor = redact.Default().With(piiFields)
+	}
+	go t.loop()
+	return t
+}
+
+var piiFields = redact.WithFields("email", "phone", "phone_number", "address", "ip", "ip_address", "ssn")
+
+// WithBatchSize sets the number of buffered events that triggers a flush, 100 by default.
+func WithBatchSize(size int) func(*Tracker) {
+	return func(t *Tracker) {
+		if size > 0 {
+			t.batchSize = size
+		}
+	}
+}
+
+// WithFlushInterval sets how often buffered events are written, 5 seconds by default.
+func WithFlushInterval(interval time.Duration) func(*Tracker) {
+	return func(t *Tracker) {
+		if interval > 0 {
+			t.flushInterval = interval
+		}
+	}
+}
+
+// WithMaxBuffered bounds the events kept in memory while the sink fails, 10000 by default.
+// Oldest events are dropped first.
+func WithMaxBuffered(max int) func(*Tracker) {
+	return func(t *Tracker) {
+		if max > 0 {
+			t.maxBuffered = max
+		}
+	}
+}
+
+// WithSampleRate keeps the given share of events, between 0 and 1. Events of a user are
+// kept or dropped together, so funnels stay complete for the sampled users.
+func WithSampleRate(rate float64) func(*Tracker) {
+	return func(t *Tracker) {
+		t.sampleRate = rate
+	}
+}
+
+// WithEventSampleRate overrides the sample rate for events named name, e.g. to keep every
+// purchase while sampling page views.
+func WithEventSampleRate(name string, rate float64) func(*Tracker) {
+	return func(t *Tracker) {
+		t.eventRates[name] = rate
+	}
+}
+
+// WithRedactor sets the redactor scrubbing event properties.
+func WithRedactor(redactor *redact.Redactor) func(*Tracker) {
+	return func(t *Tracker) {
+		t.redactor = redactor
+	}
+}
+
+// WithRedactedFields masks further property names, at any depth, in addition to the
+// default ones. Names are matched case-insensitively.
+func WithRedactedFields(fields ...string) func(*Tracker) {
+	return func(t *Tracker) {
+		if t.redactor == nil {
+			t.redactor = redact.Default().With(piiFields)
+		}
+		t.redactor = t.redactor.With(redact.WithFields(fields...))
+	}
+}
+
+// Track samples, scrubs and buffers the event, filling in its ID and time when missing. It
+// reports whether the event was kept; events tracked after Close are dropped.
+func (t *Tracker) Track(event Event) bool {
+	if t.closed.Load() {
+		return false
+	}
+	rate := t.sampleRate
+	if eventRate, ok := t.eventRates[event.Name]; ok {
+		rate = eventRate
+	}
+	if !sampled(event, rate) {
+		return false
+	}
+	if event.ID == "" {
+		event.ID = uuid.New().String()
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+	if rate < 1 {
+		event.SampleRate = rate
+	}
+	event.Properties = t.scrub(event.Properties)
+
+	t.mu.Lock()
+	t.events = append(t.events, event)
+	if len(t.events) > t.maxBuffered {
+		dropped := len(t.events) - t.maxBuffered
+		t.events = t.events[dropped:]
+		t.dropped.Add(int64(dropped))
+	}
+	full := len(t.events) >= t.batchSize
+	t.mu.Unlock()
+
+	if full {
+		select {
+		case t.full <- struct{}{}:
+		default:
+		}
+	}
+	return true
+}
+
+// TrackRequest tracks an event of the user making r, taking the request ID from the
+// X-Request-ID header.
+func (t *Tracker) TrackRequest(r *http.Request, name string, properties map[string]interface{}) bool {
+	return t.Track(Event{
+		Name:       name,
+		UserID:     middleware.RequestUser(r),
+		RequestID:  r.Header.Get("X-Request-ID"),
+		Properties: properties,
+	})
+}
+
+// sampled decides whether an event is kept, by the hash of its user when known.
+func sampled(event Event, rate float64) bool {
+	if rate >= 1 {
+		return true
+	}
+	if rate <= 0 {
+		return false
+	}
+	id := event.UserID
+	if id == "" {
+		id = event.AnonymousID
+	}
+	if id == "" {
+		return rand.Float64() < rate
+	}
+	h := fnv.New64a()
+	h.Write([]byte(id))
+	return float64(h.Sum64()>>11)/(1<<53) < rate
+}
+
+// scrub returns a copy of the properties with sensitive fields and e-mail addresses masked.
+func (t *Tracker) scrub(properties map[string]interface{}) map[string]interface{} {
+	if len(properties) == 0 {
+		return properties
+	}
+	// Properties are decoded from JSON, so structs and typed maps are scrubbed too and later
+	// changes to the values are not reflected in the event
+	var decoded interface{} = properties
+	if data, err := json.Marshal(properties); err == nil {
+		var generic map[string]interface{}
+		if json.Unmarshal(data, &generic) == nil {
+			decoded = generic
+		}
+	}
+	scrubbed, _ := scrubStrings(t.redactor.Value(decoded)).(map[string]interface{})
+	return scrubbed
+}
+
+func scrubStrings(value interface{}) interface{} {
+	switch value := value.(type) {
+	case string:
+		return emailPattern.ReplaceAllString(value, redact.Mask)
+	case map[string]interface{}:
+		for key, v := range value {
+			value[key] = scrubStrings(v)
+		}
+	case []interface{}:
+		for i, v := range value {
+			value[i] = scrubStrings(v)
+		}
+	}
+	return value
+}
+
+// Flush writes the buffered events to the sink. On failure the events are kept and retried
+// on the next flush.
+func (t *Tracker) Flush(ctx context.Context) error {
+	t.flushMu.Lock()
+	defer t.flushMu.Unlock()
+
+	t.mu.Lock()
+	events := t.events
+	t.events = nil
+	t.mu.Unlock()
+	for len(events) > 0 {
+		batch := events[:min(len(events), t.batchSize)]
+		if err := t.sink.Write(ctx, batch); err != nil {
+			t.requeue(events)
+			return err
+		}
+		events = events[len(batch):]
+	}
+	return nil
+}
+
+// requeue puts the events of a failed flush back in front of the buffer.
+func (t *Tracker) requeue(events []Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.events = append(events, t.events...)
+	if len(t.events) > t.maxBuffered {
+		dropped := len(t.events) - t.maxBuffered
+		t.events = t.events[dropped:]
+		t.dropped.Add(int64(dropped))
+	}
+}
+
+// Buffered returns the number of events waiting to be written.
+func (t *Tracker) Buffered() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.events)
+}
+
+// Dropped returns the number of events dropped because the buffer was full.
+func (t *Tracker) Dropped() int64 {
+	return t.dropped.Load()
+}
+
+func (t *Tracker) loop() {
+	defer close(t.done)
+	ticker := time.NewTicker(t.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-t.full:
+		case <-t.stop:
+			return
+		}
+		if err := t.Flush(context.Background()); err != nil {
+			log.Printf("Failed to write analytics events: %v", err)
+		}
+	}
+}
+
+// Close stops the flush loop and writes the remaining events.
+func (t *Tracker) Close() error {
+	if t.closed.Swap(true) {
+		return nil
+	}
+	close(t.stop)
+	<-t.done
+	return t.Flush(context.Background())
+}
+
+// Handle stores the tracker with each request, where ctx.Track finds it.
+func (t *Tracker) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r, values := middleware.RequestValues(r)
+		values.Set(TrackerKey, t)
+		next.ServeHTTP(w, r)
+	})
+}
+
+var defaultTracker atomic.Pointer[Tracker]
+
+// SetDefault sets the tracker used for requests that did not pass a tracker middleware,
+// e.g. when tracking from background jobs.
+func SetDefault(t *Tracker) {
+	defaultTracker.Store(t)
+}
+
+// Default returns the tracker set with SetDefault, or nil.
+func Default() *Tracker {
+	return defaultTracker.Load()
+}
+
+// RequestTracker returns the tracker stored with r by the middleware, or the default one.
+func RequestTracker(r *http.Request) *Tracker {
+	if tracker, ok := middleware.GetValue(r, TrackerKey); ok {
+		return tracker.(*Tracker)
+	}
+	return Default()
+}
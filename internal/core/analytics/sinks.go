@@ -0,0 +1,192 @@
+package analytics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/messaging"
+)
+
+// FileSink appends events to a file as newline-delimited JSON.
+type FileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileSink opens, or creates, the file events are appended to.
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{file: file}, nil
+}
+
+func (s *FileSink) Write(ctx context.Context, events []Event) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, event := range events {
+		if err := encoder.Encode(event); err != nil {
+			return err
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.file.Write(buf.Bytes())
+	return err
+}
+
+// Close closes the file.
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
+
+// HTTPSink posts batches of events to a Segment-style batch API, such as the one of
+// Segment or RudderStack, authenticating with the write key as basic auth user.
+type HTTPSink struct {
+	endpoint string
+	writeKey string
+	client   *http.Client
+}
+
+// NewHTTPSink creates a sink posting to endpoint, e.g. https://api.segment.io/v1/batch.
+func NewHTTPSink(endpoint, writeKey string, options ...func(*HTTPSink)) *HTTPSink {
+	s := &HTTPSink{endpoint: endpoint, writeKey: writeKey, client: &http.Client{Timeout: 10 * time.Second}}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// WithHTTPClient sets the client used to post batches.
+func WithHTTPClient(client *http.Client) func(*HTTPSink) {
+	return func(s *HTTPSink) {
+		s.client = client
+	}
+}
+
+func (s *HTTPSink) Write(ctx context.Context, events []Event) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"batch":  s.batch(events),
+		"sentAt": time.Now().UTC().Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth(s.writeKey, "")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("analytics endpoint responded %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// batch converts the events to track calls of the Segment batch API.
+func (s *HTTPSink) batch(events []Event) []map[string]interface{} {
+	batch := make([]map[string]interface{}, 0, len(events))
+	for _, event := range events {
+		call := map[string]interface{}{
+			"type":      "track",
+			"event":     event.Name,
+			"messageId": event.ID,
+			"timestamp": event.Time.UTC().Format(time.RFC3339Nano),
+		}
+		if event.Properties != nil {
+			call["properties"] = event.Properties
+		}
+		if event.UserID != "" {
+			call["userId"] = event.UserID
+		}
+		// The API requires a user or an anonymous ID
+		switch {
+		case event.AnonymousID != "":
+			call["anonymousId"] = event.AnonymousID
+		case event.UserID == "":
+			call["anonymousId"] = event.ID
+		}
+		callContext := map[string]interface{}{"library": map[string]string{"name": "lessgo"}}
+		if event.RequestID != "" {
+			callContext["requestId"] = event.RequestID
+		}
+		if event.SampleRate > 0 {
+			callContext["sampleRate"] = event.SampleRate
+		}
+		call["context"] = callContext
+		batch = append(batch, call)
+	}
+	return batch
+}
+
+// BrokerSink publishes events as JSON to a subject of a message broker, keyed by user so
+// the events of a user stay in order. With messaging.KafkaBroker the subject is a Kafka
+// topic, written through the Kafka REST Proxy over HTTP; NATS and the memory broker work too.
+type BrokerSink struct {
+	broker  messaging.Broker
+	subject string
+}
+
+// NewBrokerSink creates a sink publishing to the subject of broker.
+//
+// Example usage:
+//
+//	sink := analytics.NewBrokerSink(messaging.NewKafkaBroker("http://kafka-rest:8082"), "analytics.events")
+func NewBrokerSink(broker messaging.Broker, subject string) *BrokerSink {
+	return &BrokerSink{broker: broker, subject: subject}
+}
+
+func (s *BrokerSink) Write(ctx context.Context, events []Event) error {
+	for i, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		key := event.UserID
+		if key == "" {
+			key = event.AnonymousID
+		}
+		msg := &messaging.Message{
+			Subject: s.subject,
+			Data:    data,
+			Key:     key,
+			Headers: map[string]string{messaging.MessageIDHeader: event.ID},
+		}
+		if err := s.broker.Publish(ctx, msg); err != nil {
+			// Events already published are written again on retry, consumers drop them by ID
+			return fmt.Errorf("publishing event %d of %d: %w", i+1, len(events), err)
+		}
+	}
+	return nil
+}
+
+// Multi returns a sink writing events to all of the given sinks. It returns the errors of
+// all sinks that failed.
+func Multi(sinks ...Sink) Sink {
+	return SinkFunc(func(ctx context.Context, events []Event) error {
+		var errs []error
+		for _, sink := range sinks {
+			if err := sink.Write(ctx, events); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	})
+}
@@ -0,0 +1,202 @@
+/*
+Package audit records who changed what through the API.
+
+The middleware writes an Entry for every mutating request (POST, PUT, PATCH and DELETE) with
+the acting user, the route and the IDs of the resources in its path, the response status and a
+SHA-256 hash of the request payload. Entries go to one or more sinks: an NDJSON file, a
+database table, a Kafka topic or anything implementing Sink. Sensitive payload fields are
+masked before an entry leaves the process.
+
+Usage:
+
+	file, err := audit.NewFileSink("/var/log/app/audit.ndjson")
+	if err != nil {
+		log.Fatal(err)
+	}
+	App := LessGo.App(
+		LessGo.WithAudit(audit.Multi(file, audit.NewSQLSink(db, "audit_log")),
+			audit.WithPayload(), audit.WithRedactedFields("iban")),
+	)
+*/
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+	"github.com/hokamsingh/lessgo/internal/core/redact"
+)
+
+// Entry is the record of one mutating request.
+type Entry struct {
+	Time        time.Time              `json:"time"`
+	Actor       string                 `json:"actor,omitempty"`
+	Method      string                 `json:"method"`
+	Route       string                 `json:"route"`
+	Path        string                 `json:"path"`
+	ResourceIDs map[string]string      `json:"resource_ids,omitempty"`
+	Status      int                    `json:"status"`
+	PayloadHash string                 `json:"payload_hash,omitempty"`
+	Payload     map[string]interface{} `json:"payload,omitempty"`
+	RequestID   string                 `json:"request_id,omitempty"`
+	ClientIP    string                 `json:"client_ip,omitempty"`
+}
+
+// Sink stores audit entries.
+type Sink interface {
+	Write(ctx context.Context, entry Entry) error
+}
+
+// SinkFunc adapts a function to the Sink interface.
+type SinkFunc func(ctx context.Context, entry Entry) error
+
+func (f SinkFunc) Write(ctx context.Context, entry Entry) error {
+	return f(ctx, entry)
+}
+
+// Middleware writes an audit entry for every mutating request.
+type Middleware struct {
+	sink            Sink
+	methods         map[string]bool
+	redactor        *redact.Redactor
+	includePayload  bool
+	requestIDHeader string
+}
+
+// New creates the audit middleware writing to the sink. Payload fields are redacted with
+// redact.Default unless WithRedactor is given.
+func New(sink Sink, options ...func(*Middleware)) *Middleware {
+	m := &Middleware{
+		sink:            sink,
+		methods:         map[string]bool{http.MethodPost: true, http.MethodPut: true, http.MethodPatch: true, http.MethodDelete: true},
+		requestIDHeader: "X-Request-ID",
+	}
+	for _, option := range options {
+		option(m)
+	}
+	return m
+}
+
+// WithMethods sets the HTTP methods that are audited.
+func WithMethods(methods ...string) func(*Middleware) {
+	return func(m *Middleware) {
+		m.methods = make(map[string]bool, len(methods))
+		for _, method := range methods {
+			m.methods[strings.ToUpper(method)] = true
+		}
+	}
+}
+
+// WithRedactor sets the redactor masking recorded payloads.
+func WithRedactor(redactor *redact.Redactor) func(*Middleware) {
+	return func(m *Middleware) {
+		m.redactor = redactor
+	}
+}
+
+// WithRedactedFields masks further JSON payload fields, at any depth, in addition to those
+// of the redactor. Names are matched case-insensitively.
+func WithRedactedFields(fields ...string) func(*Middleware) {
+	return func(m *Middleware) {
+		m.redactor = m.redactorOrDefault().With(redact.WithFields(fields...))
+	}
+}
+
+func (m *Middleware) redactorOrDefault() *redact.Redactor {
+	if m.redactor != nil {
+		return m.redactor
+	}
+	return redact.Default()
+}
+
+// WithPayload records the redacted JSON payload in addition to its hash.
+func WithPayload() func(*Middleware) {
+	return func(m *Middleware) {
+		m.includePayload = true
+	}
+}
+
+// WithRequestIDHeader sets the header entries take their request ID from.
+func WithRequestIDHeader(header string) func(*Middleware) {
+	return func(m *Middleware) {
+		m.requestIDHeader = header
+	}
+}
+
+func (m *Middleware) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !m.methods[r.Method] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		// The router stores the matched route in the value store attached here
+		r, _ = middleware.RequestValues(r)
+		body, err := middleware.ReadBody(r)
+		if err != nil {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		entry := m.entry(r, body, rec.status)
+		// The entry is written even when the client went away in the meantime
+		if err := m.sink.Write(context.WithoutCancel(r.Context()), entry); err != nil {
+			log.Printf("Failed to write audit entry for %s %s: %v", entry.Method, entry.Path, err)
+		}
+	})
+}
+
+func (m *Middleware) entry(r *http.Request, body []byte, status int) Entry {
+	route, vars := middleware.RequestRoute(r)
+	if route == "" {
+		route = r.URL.Path
+	}
+	entry := Entry{
+		Time:        time.Now().UTC(),
+		Actor:       middleware.RequestUser(r),
+		Method:      r.Method,
+		Route:       route,
+		Path:        r.URL.Path,
+		ResourceIDs: vars,
+		Status:      status,
+		RequestID:   r.Header.Get(m.requestIDHeader),
+		ClientIP:    middleware.ClientIP(r),
+	}
+	if len(body) > 0 {
+		sum := sha256.Sum256(body)
+		entry.PayloadHash = hex.EncodeToString(sum[:])
+		if m.includePayload {
+			var payload map[string]interface{}
+			if json.Unmarshal(body, &payload) == nil {
+				entry.Payload = m.redactorOrDefault().Value(payload).(map[string]interface{})
+			}
+		}
+	}
+	return entry
+}
+
+// statusRecorder captures the status code of a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
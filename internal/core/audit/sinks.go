@@ -0,0 +1,142 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/hokamsingh/lessgo/internal/core/database"
+)
+
+// FileSink appends entries to a file as newline-delimited JSON.
+type FileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileSink opens, or creates, the file entries are appended to.
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{file: file}, nil
+}
+
+func (s *FileSink) Write(ctx context.Context, entry Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.file.Write(append(line, '\n'))
+	return err
+}
+
+// Close closes the file.
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
+
+// SQLSink inserts entries into a database table with the columns time, actor, method, route,
+// path, resource_ids, status, payload_hash, payload, request_id and client_ip. Resource IDs
+// and payload are stored as JSON text.
+type SQLSink struct {
+	db      *sql.DB
+	query   string
+	dialect database.Dialect
+}
+
+// NewSQLSink creates a sink inserting into the table. Queries use ? placeholders unless
+// WithDialect names the dialect of another driver.
+func NewSQLSink(db *sql.DB, table string, options ...func(*SQLSink)) *SQLSink {
+	s := &SQLSink{db: db}
+	for _, option := range options {
+		option(s)
+	}
+	s.query = s.dialect.Rebind(fmt.Sprintf("INSERT INTO %s (time, actor, method, route, path, resource_ids, status, payload_hash, payload, request_id, client_ip) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		table))
+	return s
+}
+
+// WithDialect sets the placeholder style of the database driver.
+//
+// Example usage:
+//
+//	sink := audit.NewSQLSink(db, "audit_log", audit.WithDialect(database.DialectOf("postgres")))
+func WithDialect(dialect database.Dialect) func(*SQLSink) {
+	return func(s *SQLSink) {
+		s.dialect = dialect
+	}
+}
+
+func (s *SQLSink) Write(ctx context.Context, entry Entry) error {
+	resourceIDs, err := json.Marshal(entry.ResourceIDs)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(entry.Payload)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, s.query, entry.Time, entry.Actor, entry.Method, entry.Route, entry.Path,
+		string(resourceIDs), entry.Status, entry.PayloadHash, string(payload), entry.RequestID, entry.ClientIP)
+	return err
+}
+
+// Producer publishes a message to a Kafka topic. Adapters for Kafka clients such as
+// segmentio/kafka-go or confluent-kafka-go implement it in a few lines.
+type Producer interface {
+	Produce(ctx context.Context, topic string, key, value []byte) error
+}
+
+// KafkaSink publishes entries as JSON to a Kafka topic, keyed by actor so the entries of a
+// user stay in order.
+type KafkaSink struct {
+	producer Producer
+	topic    string
+}
+
+// NewKafkaSink creates a sink publishing to the topic.
+func NewKafkaSink(producer Producer, topic string) *KafkaSink {
+	return &KafkaSink{producer: producer, topic: topic}
+}
+
+func (s *KafkaSink) Write(ctx context.Context, entry Entry) error {
+	value, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return s.producer.Produce(ctx, s.topic, []byte(entry.Actor), value)
+}
+
+// Recorder receives arbitrary records, like *logexport.Exporter.
+type Recorder interface {
+	Record(record interface{}) error
+}
+
+// RecorderSink writes entries to a recorder, e.g. to ship them with the log export.
+func RecorderSink(recorder Recorder) Sink {
+	return SinkFunc(func(ctx context.Context, entry Entry) error {
+		return recorder.Record(entry)
+	})
+}
+
+// Multi returns a sink writing entries to all of the given sinks. It returns the errors of
+// all sinks that failed.
+func Multi(sinks ...Sink) Sink {
+	return SinkFunc(func(ctx context.Context, entry Entry) error {
+		var errs []error
+		for _, sink := range sinks {
+			if err := sink.Write(ctx, entry); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	})
+}
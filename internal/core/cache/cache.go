@@ -0,0 +1,328 @@
+/*
+Package cache provides a fast in-process cache bounded by memory.
+
+Entries are spread over shards, each a least recently used list with its own lock, so
+concurrent access rarely contends. When a shard exceeds its share of the byte limit, its least
+recently used entries are evicted. Expired entries are removed when read and by a janitor
+goroutine sweeping the shards at a jittered interval, so instances started together do not
+sweep in lockstep.
+
+Typed wraps a store, in memory or in Redis, with encoding and loading of values of one type.
+
+Usage:
+
+	c := cache.New(cache.WithMaxBytes(64<<20), cache.WithDefaultTTL(5*time.Minute))
+	defer c.Close()
+
+	c.Set("user:42", data, 0)
+	if data, ok := c.Get("user:42"); ok {
+		// ...
+	}
+	log.Printf("cache: %+v", c.Stats())
+
+	users := cache.NewTyped[User](cache.NewMemoryStore(c), *cache.NewTypedOptions("user:"))
+	user, err := users.GetOrLoad(ctx, id, time.Minute, func(ctx context.Context) (User, error) {
+		return repo.FindUser(ctx, id)
+	})
+*/
+package cache
+
+import (
+	"container/list"
+	"hash/fnv"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stats are the counters of a cache.
+type Stats struct {
+	Hits        int64 `json:"hits"`
+	Misses      int64 `json:"misses"`
+	Evictions   int64 `json:"evictions"`
+	Expirations int64 `json:"expirations"`
+	Entries     int   `json:"entries"`
+	// Bytes is the size of the keys and values held.
+	Bytes int64 `json:"bytes"`
+}
+
+// Cache is a sharded LRU cache of byte values with a TTL per entry and a bound on the bytes
+// held. It is safe for concurrent use.
+type Cache struct {
+	shards        []*shard
+	maxBytes      int64
+	defaultTTL    time.Duration
+	sweepInterval time.Duration
+
+	hits        atomic.Int64
+	misses      atomic.Int64
+	evictions   atomic.Int64
+	expirations atomic.Int64
+
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+type shard struct {
+	mu       sync.Mutex
+	entries  map[string]*list.Element
+	lru      *list.List // front is the most recently used
+	bytes    int64
+	maxBytes int64
+}
+
+type entry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+func (e *entry) size() int64 {
+	return int64(len(e.key) + len(e.value))
+}
+
+func (e *entry) expired(now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
+
+// New creates a cache and starts its janitor. By default it has 16 shards, holds up to
+// 64 MiB, keeps entries without TTL until they are evicted and sweeps every minute.
+func New(options ...func(*Cache)) *Cache {
+	c := &Cache{
+		shards:        make([]*shard, 16),
+		maxBytes:      64 << 20,
+		sweepInterval: time.Minute,
+		stop:          make(chan struct{}),
+	}
+	for _, option := range options {
+		option(c)
+	}
+	for i := range c.shards {
+		c.shards[i] = &shard{
+			entries:  make(map[string]*list.Element),
+			lru:      list.New(),
+			maxBytes: c.maxBytes / int64(len(c.shards)),
+		}
+	}
+	if c.sweepInterval > 0 {
+		go c.janitor()
+	}
+	return c
+}
+
+// WithShards sets the number of shards, rounded up to a power of two.
+func WithShards(n int) func(*Cache) {
+	return func(c *Cache) {
+		shards := 1
+		for shards < n {
+			shards <<= 1
+		}
+		c.shards = make([]*shard, shards)
+	}
+}
+
+// WithMaxBytes bounds the size of the keys and values held.
+func WithMaxBytes(n int64) func(*Cache) {
+	return func(c *Cache) {
+		c.maxBytes = n
+	}
+}
+
+// WithDefaultTTL sets the TTL of entries set without one.
+func WithDefaultTTL(ttl time.Duration) func(*Cache) {
+	return func(c *Cache) {
+		c.defaultTTL = ttl
+	}
+}
+
+// WithSweepInterval sets how often the janitor removes expired entries; zero disables it.
+func WithSweepInterval(interval time.Duration) func(*Cache) {
+	return func(c *Cache) {
+		c.sweepInterval = interval
+	}
+}
+
+func (c *Cache) shard(key string) *shard {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return c.shards[h.Sum32()&uint32(len(c.shards)-1)]
+}
+
+// Get returns the value of key and marks it recently used. The value must not be modified.
+func (c *Cache) Get(key string) ([]byte, bool) {
+	s := c.shard(key)
+	s.mu.Lock()
+	element, ok := s.entries[key]
+	if !ok {
+		s.mu.Unlock()
+		c.misses.Add(1)
+		return nil, false
+	}
+	e := element.Value.(*entry)
+	if e.expired(time.Now()) {
+		s.remove(element)
+		s.mu.Unlock()
+		c.expirations.Add(1)
+		c.misses.Add(1)
+		return nil, false
+	}
+	s.lru.MoveToFront(element)
+	s.mu.Unlock()
+	c.hits.Add(1)
+	return e.value, true
+}
+
+// Set stores value under key for ttl, or the default TTL when ttl is zero. Values larger than
+// a shard's share of the byte limit are not stored.
+func (c *Cache) Set(key string, value []byte, ttl time.Duration) {
+	c.set(key, value, ttl, false)
+}
+
+// Add stores value under key like Set, unless key is already cached. It reports whether the
+// value was stored, so concurrent callers can claim a key exactly once.
+func (c *Cache) Add(key string, value []byte, ttl time.Duration) bool {
+	return c.set(key, value, ttl, true)
+}
+
+func (c *Cache) set(key string, value []byte, ttl time.Duration, onlyNew bool) bool {
+	if ttl <= 0 {
+		ttl = c.defaultTTL
+	}
+	now := time.Now()
+	e := &entry{key: key, value: value}
+	if ttl > 0 {
+		e.expires = now.Add(ttl)
+	}
+	s := c.shard(key)
+	if e.size() > s.maxBytes {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if element, ok := s.entries[key]; ok {
+		if onlyNew && !element.Value.(*entry).expired(now) {
+			return false
+		}
+		s.remove(element)
+	}
+	s.entries[key] = s.lru.PushFront(e)
+	s.bytes += e.size()
+	for s.bytes > s.maxBytes {
+		s.remove(s.lru.Back())
+		c.evictions.Add(1)
+	}
+	return true
+}
+
+// Delete removes the keys and returns how many of them were cached.
+func (c *Cache) Delete(keys ...string) int {
+	deleted := 0
+	now := time.Now()
+	for _, key := range keys {
+		s := c.shard(key)
+		s.mu.Lock()
+		if element, ok := s.entries[key]; ok {
+			if !element.Value.(*entry).expired(now) {
+				deleted++
+			}
+			s.remove(element)
+		}
+		s.mu.Unlock()
+	}
+	return deleted
+}
+
+// Keys returns the keys of all entries that have not expired.
+func (c *Cache) Keys() []string {
+	var keys []string
+	now := time.Now()
+	for _, s := range c.shards {
+		s.mu.Lock()
+		for key, element := range s.entries {
+			if !element.Value.(*entry).expired(now) {
+				keys = append(keys, key)
+			}
+		}
+		s.mu.Unlock()
+	}
+	return keys
+}
+
+// Clear removes all entries.
+func (c *Cache) Clear() {
+	for _, s := range c.shards {
+		s.mu.Lock()
+		s.entries = make(map[string]*list.Element)
+		s.lru.Init()
+		s.bytes = 0
+		s.mu.Unlock()
+	}
+}
+
+// Stats returns the counters of the cache.
+func (c *Cache) Stats() Stats {
+	stats := Stats{
+		Hits:        c.hits.Load(),
+		Misses:      c.misses.Load(),
+		Evictions:   c.evictions.Load(),
+		Expirations: c.expirations.Load(),
+	}
+	for _, s := range c.shards {
+		s.mu.Lock()
+		stats.Entries += len(s.entries)
+		stats.Bytes += s.bytes
+		s.mu.Unlock()
+	}
+	return stats
+}
+
+// Close stops the janitor. The cache can still be used, expired entries are then only
+// removed when read.
+func (c *Cache) Close() {
+	c.closeOnce.Do(func() { close(c.stop) })
+}
+
+func (s *shard) remove(element *list.Element) {
+	e := s.lru.Remove(element).(*entry)
+	delete(s.entries, e.key)
+	s.bytes -= e.size()
+}
+
+// janitor sweeps expired entries every sweep interval, give or take 10%.
+func (c *Cache) janitor() {
+	timer := time.NewTimer(c.jittered())
+	defer timer.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-timer.C:
+			c.sweep()
+			timer.Reset(c.jittered())
+		}
+	}
+}
+
+func (c *Cache) jittered() time.Duration {
+	jitter := int64(c.sweepInterval) / 5
+	if jitter <= 0 {
+		return c.sweepInterval
+	}
+	return c.sweepInterval - time.Duration(jitter/2) + time.Duration(rand.Int63n(jitter))
+}
+
+func (c *Cache) sweep() {
+	now := time.Now()
+	for _, s := range c.shards {
+		s.mu.Lock()
+		for _, element := range s.entries {
+			if element.Value.(*entry).expired(now) {
+				s.remove(element)
+				c.expirations.Add(1)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
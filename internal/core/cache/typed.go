@@ -0,0 +1,154 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned by loaders when the value does not exist. It is cached for the
+// negative TTL, so lookups of missing values do not reach the loader every time.
+var ErrNotFound = errors.New("cache: not found")
+
+var errLoaderPanicked = errors.New("cache: loader panicked")
+
+// Markers stored in front of the encoded value.
+const (
+	markerValue    byte = 0
+	markerNotFound byte = 1
+)
+
+// TypedOptions defines how a typed cache names and encodes its values.
+type TypedOptions struct {
+	// Prefix is prepended to every key, e.g. "user:".
+	Prefix string
+	// Codec encodes the values, JSON by default.
+	Codec Codec
+	// NegativeTTL is how long ErrNotFound results of loaders are cached. Zero disables
+	// negative caching.
+	NegativeTTL time.Duration
+}
+
+// NewTypedOptions creates TypedOptions with keys starting with prefix, the JSON codec and
+// missing values cached for a minute.
+func NewTypedOptions(prefix string) *TypedOptions {
+	return &TypedOptions{Prefix: prefix, Codec: JSON, NegativeTTL: time.Minute}
+}
+
+// Typed caches values of type T in a store, encoding and decoding them with a codec.
+// Concurrent loads of the same key are coalesced into one call of the loader.
+//
+// Example usage:
+//
+//	users := cache.NewTyped[User](cache.NewRedisStore(client), *cache.NewTypedOptions("user:"))
+//	user, err := users.GetOrLoad(ctx, id, 10*time.Minute, func(ctx context.Context) (User, error) {
+//		return repo.FindUser(ctx, id)
+//	})
+type Typed[T any] struct {
+	store   Store
+	options TypedOptions
+
+	mu    sync.Mutex
+	loads map[string]*load[T]
+}
+
+type load[T any] struct {
+	done  chan struct{}
+	value T
+	err   error
+}
+
+// NewTyped creates a typed cache keeping its values in store.
+func NewTyped[T any](store Store, options TypedOptions) *Typed[T] {
+	if options.Codec == nil {
+		options.Codec = JSON
+	}
+	return &Typed[T]{store: store, options: options, loads: map[string]*load[T]{}}
+}
+
+// Get returns the cached value of key and whether it was cached. For a cached missing value it
+// returns true and ErrNotFound.
+func (t *Typed[T]) Get(ctx context.Context, key string) (T, bool, error) {
+	var value T
+	data, found, err := t.store.Get(ctx, t.options.Prefix+key)
+	if err != nil || !found || len(data) == 0 {
+		return value, false, err
+	}
+	if data[0] == markerNotFound {
+		return value, true, ErrNotFound
+	}
+	if err := t.options.Codec.Unmarshal(data[1:], &value); err != nil {
+		return value, false, err
+	}
+	return value, true, nil
+}
+
+// Set caches value under key for ttl.
+func (t *Typed[T]) Set(ctx context.Context, key string, value T, ttl time.Duration) error {
+	data, err := t.options.Codec.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return t.store.Set(ctx, t.options.Prefix+key, append([]byte{markerValue}, data...), ttl)
+}
+
+// Delete removes the keys from the cache.
+func (t *Typed[T]) Delete(ctx context.Context, keys ...string) error {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = t.options.Prefix + key
+	}
+	return t.store.Delete(ctx, prefixed...)
+}
+
+// GetOrLoad returns the cached value of key, or calls loader and caches its result for ttl.
+// When the loader returns ErrNotFound, that is cached for the negative TTL. Other errors are
+// not cached. Failing to read or write the store is logged and the loader is used instead, so
+// an unavailable cache slows requests down rather than failing them.
+func (t *Typed[T]) GetOrLoad(ctx context.Context, key string, ttl time.Duration, loader func(context.Context) (T, error)) (T, error) {
+	value, found, err := t.Get(ctx, key)
+	if found {
+		return value, err
+	}
+	if err != nil {
+		log.Printf("Error reading cache key %s: %v", t.options.Prefix+key, err)
+	}
+	return t.load(ctx, key, ttl, loader)
+}
+
+// load calls the loader once for all concurrent loads of the key.
+func (t *Typed[T]) load(ctx context.Context, key string, ttl time.Duration, loader func(context.Context) (T, error)) (T, error) {
+	t.mu.Lock()
+	if l, ok := t.loads[key]; ok {
+		t.mu.Unlock()
+		<-l.done
+		return l.value, l.err
+	}
+	l := &load[T]{done: make(chan struct{}), err: errLoaderPanicked}
+	t.loads[key] = l
+	t.mu.Unlock()
+
+	defer func() {
+		t.mu.Lock()
+		delete(t.loads, key)
+		t.mu.Unlock()
+		close(l.done)
+	}()
+
+	// The result is shared, so it must not fail when the first caller gives up
+	ctx = context.WithoutCancel(ctx)
+	l.value, l.err = loader(ctx)
+	switch {
+	case l.err == nil:
+		if err := t.Set(ctx, key, l.value, ttl); err != nil {
+			log.Printf("Error writing cache key %s: %v", t.options.Prefix+key, err)
+		}
+	case errors.Is(l.err, ErrNotFound) && t.options.NegativeTTL > 0:
+		if err := t.store.Set(ctx, t.options.Prefix+key, []byte{markerNotFound}, t.options.NegativeTTL); err != nil {
+			log.Printf("Error writing cache key %s: %v", t.options.Prefix+key, err)
+		}
+	}
+	return l.value, l.err
+}
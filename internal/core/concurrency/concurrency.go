@@ -165,16 +165,16 @@ func (tm *TaskManager) runParallel(ctx context.Context) ([]interface{}, error) {
 
 	// Stop the worker pool and wait for results
 	pool.Stop()
+	<-doneChan // Wait until the last result has been stored
 
 	close(errChan)
 
 	// Check for errors
-	select {
-	case err := <-errChan:
+	// A closed errChan yields nil, so only a received error counts
+	if err, ok := <-errChan; ok && err != nil {
 		return nil, err
-	default:
-		return results, nil
 	}
+	return results, nil
 }
 
 // runSequential executes all tasks one by one and collects the results.
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"encoding/base64"
 	"log"
 	"os"
 	"path/filepath"
@@ -15,7 +16,8 @@ type Config map[string]string
 
 // LoadConfig loads environment variables into a Config map. It first attempts to load a `.env` file
 // using the `godotenv` package. If no `.env` file is found, it logs a message but continues to load
-// environment variables from the system.
+// environment variables from the system. Values declared for the current environment, as in
+// DB_HOST.production=db.internal, replace the values of their keys, see ResolveEnv.
 func LoadConfig() Config {
 	if err := godotenv.Load(); err != nil {
 		log.Printf("No .env file found in current directory: %v", err)
@@ -43,7 +45,7 @@ func LoadConfig() Config {
 		}
 	}
 
-	return config
+	return config.ResolveEnv()
 }
 
 // Get retrieves a string value from the Config map based on the provided key. If the key does not exist
@@ -91,6 +93,32 @@ func (c Config) GetFloat64(key string, defaultValue float64) float64 {
 	return defaultValue
 }
 
+// GetKeys retrieves a comma separated list of secret keys, newest first, as used for key
+// rotation: the first key signs or encrypts new data and all of them are accepted when
+// reading. Keys prefixed with "base64:" are decoded.
+//
+//	COOKIE_KEYS=base64:q83vEjRWeJA...,previous-key
+func (c Config) GetKeys(key string) [][]byte {
+	var keys [][]byte
+	for _, value := range strings.Split(c.Get(key, ""), ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if encoded, ok := strings.CutPrefix(value, "base64:"); ok {
+			decoded, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil {
+				log.Printf("Invalid base64 key in %s: %v", key, err)
+				continue
+			}
+			keys = append(keys, decoded)
+			continue
+		}
+		keys = append(keys, []byte(value))
+	}
+	return keys
+}
+
 // Validate checks that all the provided keys are present in the Config map. If any key is missing, it logs
 // a fatal error and exits the program. This ensures that required configuration is always set.
 func (c Config) Validate(requiredKeys ...string) {
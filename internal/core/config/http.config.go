@@ -1,5 +1,7 @@
 package config
 
+import "os"
+
 // HttpConfig holds the configuration options for the HTTP server.
 type HttpConfig struct {
 	ReadTimeout   int
@@ -8,8 +10,10 @@ type HttpConfig struct {
 	MaxHeaderSize int
 	TLSCertFile   string
 	TLSKeyFile    string
-	Security      SecurityConfig
-	Session       SessionConfig
+	// SocketMode sets the permissions of the socket file when listening on a unix socket
+	SocketMode os.FileMode
+	Security   SecurityConfig
+	Session    SessionConfig
 }
 
 // SecurityConfig holds the security-related configuration options.
@@ -91,6 +95,12 @@ func WithTLSKeyFile(keyFile string) func(*HttpConfig) {
 	}
 }
 
+func WithSocketMode(mode os.FileMode) func(*HttpConfig) {
+	return func(cfg *HttpConfig) {
+		cfg.SocketMode = mode
+	}
+}
+
 func WithHSTS(enabled bool) func(*HttpConfig) {
 	return func(cfg *HttpConfig) {
 		cfg.Security.EnableHSTS = enabled
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Environments of the common deployment profiles.
+const (
+	Development = "development"
+	Staging     = "staging"
+	Production  = "production"
+	Test        = "test"
+)
+
+// envAliases are the short names accepted for the environments.
+var envAliases = map[string]string{
+	"dev":     Development,
+	"develop": Development,
+	"local":   Development,
+	"stage":   Staging,
+	"prod":    Production,
+	"testing": Test,
+}
+
+// profileKeyPattern matches keys with an environment suffix, e.g. DB_HOST.production.
+var profileKeyPattern = regexp.MustCompile(`^(.+)\.([A-Za-z][A-Za-z0-9_-]*)$`)
+
+// NormalizeEnv returns the environment name in lower case, with aliases such as prod or dev
+// replaced by the full name.
+func NormalizeEnv(env string) string {
+	env = strings.ToLower(strings.TrimSpace(env))
+	if full, ok := envAliases[env]; ok {
+		return full
+	}
+	return env
+}
+
+// Env returns the environment the application runs in, from ENV or else APP_ENV, normalized
+// with NormalizeEnv. It is development when neither is set.
+//
+// Example usage:
+//
+//	log.Printf("Starting server in %s mode", cfg.Env())
+func (c Config) Env() string {
+	for _, key := range []string{"ENV", "APP_ENV"} {
+		if env := NormalizeEnv(c[key]); env != "" {
+			return env
+		}
+	}
+	return Development
+}
+
+// IsEnv reports whether the application runs in one of the environments, aliases included.
+//
+// Example usage:
+//
+//	if cfg.IsEnv(config.Development, config.Test) {
+//		App.Get("/debug/seed", seedHandler)
+//	}
+func (c Config) IsEnv(envs ...string) bool {
+	current := c.Env()
+	for _, env := range envs {
+		if NormalizeEnv(env) == current {
+			return true
+		}
+	}
+	return false
+}
+
+// IsDevelopment reports whether the application runs in development.
+func (c Config) IsDevelopment() bool { return c.IsEnv(Development) }
+
+// IsStaging reports whether the application runs in staging.
+func (c Config) IsStaging() bool { return c.IsEnv(Staging) }
+
+// IsProduction reports whether the application runs in production.
+func (c Config) IsProduction() bool { return c.IsEnv(Production) }
+
+// IsTest reports whether the application runs its tests.
+func (c Config) IsTest() bool { return c.IsEnv(Test) }
+
+// MergeWithEnvDefaults merges the defaults of the current environment, see
+// MergeWithDefaults. The defaults under "" apply to every environment, below those of the
+// environment.
+//
+// Example usage:
+//
+//	cfg := LoadConfig().MergeWithEnvDefaults(map[string]Config{
+//		"":                {"LOG_LEVEL": "info"},
+//		config.Development: {"LOG_LEVEL": "debug", "DB_HOST": "localhost"},
+//		config.Production:  {"LOG_LEVEL": "warn"},
+//	})
+func (c Config) MergeWithEnvDefaults(defaults map[string]Config) Config {
+	env := c.Env()
+	var matching Config
+	for name, values := range defaults {
+		if name != "" && NormalizeEnv(name) == env {
+			matching = values
+		}
+	}
+	return c.MergeWithDefaults(defaults[""].merge(matching))
+}
+
+// merge returns a copy of c with the values of over.
+func (c Config) merge(over Config) Config {
+	merged := make(Config, len(c)+len(over))
+	for k, v := range c {
+		merged[k] = v
+	}
+	for k, v := range over {
+		merged[k] = v
+	}
+	return merged
+}
+
+// ResolveEnv returns the configuration of the current environment: the value of a key
+// declared for the environment, as in DB_HOST.production=db.internal, replaces the value of
+// the key. Keys ending with a dot and a name are taken as declared for an environment and are
+// left out of the result. LoadConfig resolves its configuration already.
+//
+//	DB_HOST=localhost
+//	DB_HOST.staging=db.staging.internal
+//	DB_HOST.production=db.internal
+func (c Config) ResolveEnv() Config {
+	env := c.Env()
+	resolved := make(Config, len(c))
+	profiles := make(Config)
+	for k, v := range c {
+		match := profileKeyPattern.FindStringSubmatch(k)
+		if match == nil {
+			resolved[k] = v
+			continue
+		}
+		if NormalizeEnv(match[2]) == env {
+			profiles[match[1]] = v
+		}
+	}
+	return resolved.merge(profiles)
+}
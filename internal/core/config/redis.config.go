@@ -0,0 +1,255 @@
+package config
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/hokamsingh/lessgo/internal/core/probe"
+)
+
+// Redis topologies.
+const (
+	RedisStandalone = "standalone"
+	RedisSentinel   = "sentinel"
+	RedisCluster    = "cluster"
+)
+
+// RedisConfig holds the connection settings of Redis, for a single node, a Sentinel managed
+// primary or a cluster.
+type RedisConfig struct {
+	// Topology is standalone, sentinel or cluster. When empty, it is sentinel if MasterName
+	// is set, cluster with more than one address and standalone otherwise.
+	Topology string
+	// Addrs are the addresses of the node, the Sentinels or the cluster seed nodes.
+	Addrs []string
+	// MasterName is the name of the primary monitored by the Sentinels.
+	MasterName       string
+	Username         string
+	Password         string
+	SentinelPassword string
+	// DB selects the database; clusters only have database 0.
+	DB int
+	// TLS enables TLS with TLSConfig, or the default settings when it is nil.
+	TLS       bool
+	TLSConfig *tls.Config
+
+	PoolSize     int
+	MinIdleConns int
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+}
+
+// NewRedisConfig creates a RedisConfig for a single node at addr.
+// You can pass option functions to override default settings.
+func NewRedisConfig(addr string, options ...func(*RedisConfig)) *RedisConfig {
+	cfg := &RedisConfig{
+		Addrs:        []string{addr},
+		DialTimeout:  5 * time.Second,
+		ReadTimeout:  3 * time.Second,
+		WriteTimeout: 3 * time.Second,
+	}
+	for _, option := range options {
+		option(cfg)
+	}
+	return cfg
+}
+
+// RedisConfigFromEnv reads the Redis configuration from REDIS_* keys:
+//
+//	REDIS_TOPOLOGY            standalone, sentinel or cluster
+//	REDIS_ADDRS               comma separated addresses, localhost:6379 by default
+//	REDIS_MASTER_NAME         the Sentinel primary name
+//	REDIS_USERNAME, REDIS_PASSWORD, REDIS_SENTINEL_PASSWORD
+//	REDIS_DB                  the database number
+//	REDIS_TLS                 true to connect with TLS
+//	REDIS_POOL_SIZE, REDIS_MIN_IDLE_CONNS
+//	REDIS_DIAL_TIMEOUT, REDIS_READ_TIMEOUT, REDIS_WRITE_TIMEOUT   durations such as 3s
+func RedisConfigFromEnv(c Config) RedisConfig {
+	var addrs []string
+	for _, addr := range strings.Split(c.Get("REDIS_ADDRS", "localhost:6379"), ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	return RedisConfig{
+		Topology:         c.Get("REDIS_TOPOLOGY", ""),
+		Addrs:            addrs,
+		MasterName:       c.Get("REDIS_MASTER_NAME", ""),
+		Username:         c.Get("REDIS_USERNAME", ""),
+		Password:         c.Get("REDIS_PASSWORD", ""),
+		SentinelPassword: c.Get("REDIS_SENTINEL_PASSWORD", ""),
+		DB:               c.GetInt("REDIS_DB", 0),
+		TLS:              c.GetBool("REDIS_TLS", false),
+		PoolSize:         c.GetInt("REDIS_POOL_SIZE", 0),
+		MinIdleConns:     c.GetInt("REDIS_MIN_IDLE_CONNS", 0),
+		DialTimeout:      c.getDuration("REDIS_DIAL_TIMEOUT", 5*time.Second),
+		ReadTimeout:      c.getDuration("REDIS_READ_TIMEOUT", 3*time.Second),
+		WriteTimeout:     c.getDuration("REDIS_WRITE_TIMEOUT", 3*time.Second),
+	}
+}
+
+func (c Config) getDuration(key string, defaultValue time.Duration) time.Duration {
+	if d, err := time.ParseDuration(c.Get(key, "")); err == nil {
+		return d
+	}
+	return defaultValue
+}
+
+// ResolvedTopology returns the topology, inferring it when Topology is empty.
+func (rc RedisConfig) ResolvedTopology() string {
+	switch {
+	case rc.Topology != "":
+		return rc.Topology
+	case rc.MasterName != "":
+		return RedisSentinel
+	case len(rc.Addrs) > 1:
+		return RedisCluster
+	}
+	return RedisStandalone
+}
+
+// NewRedisClient creates a client for the configured topology and checks the connection. The
+// client is registered with probe.Default, which keeps its latency.
+//
+// Example usage:
+//
+//	client, err := config.NewRedisClient(config.RedisConfigFromEnv(cfg))
+//	if err != nil {
+//		log.Fatalf("Could not connect to Redis: %v", err)
+//	}
+func NewRedisClient(rc RedisConfig) (redis.UniversalClient, error) {
+	client, err := rc.client()
+	if err != nil {
+		return nil, err
+	}
+	timeout := rc.DialTimeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	target := probe.Redis(client)
+	if result := probe.Default().CheckTarget(ctx, target); !result.Healthy {
+		probe.Default().Remove(target.Name)
+		client.Close()
+		return nil, result.Err
+	}
+	return client, nil
+}
+
+func (rc RedisConfig) client() (redis.UniversalClient, error) {
+	if len(rc.Addrs) == 0 {
+		return nil, fmt.Errorf("config: redis needs at least one address")
+	}
+	var tlsConfig *tls.Config
+	if rc.TLS {
+		tlsConfig = rc.TLSConfig
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+	}
+
+	switch rc.ResolvedTopology() {
+	case RedisStandalone:
+		return redis.NewClient(&redis.Options{
+			Addr:         rc.Addrs[0],
+			Username:     rc.Username,
+			Password:     rc.Password,
+			DB:           rc.DB,
+			TLSConfig:    tlsConfig,
+			PoolSize:     rc.PoolSize,
+			MinIdleConns: rc.MinIdleConns,
+			DialTimeout:  rc.DialTimeout,
+			ReadTimeout:  rc.ReadTimeout,
+			WriteTimeout: rc.WriteTimeout,
+		}), nil
+	case RedisSentinel:
+		if rc.MasterName == "" {
+			return nil, fmt.Errorf("config: redis sentinel needs a master name")
+		}
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       rc.MasterName,
+			SentinelAddrs:    rc.Addrs,
+			SentinelPassword: rc.SentinelPassword,
+			Username:         rc.Username,
+			Password:         rc.Password,
+			DB:               rc.DB,
+			TLSConfig:        tlsConfig,
+			PoolSize:         rc.PoolSize,
+			MinIdleConns:     rc.MinIdleConns,
+			DialTimeout:      rc.DialTimeout,
+			ReadTimeout:      rc.ReadTimeout,
+			WriteTimeout:     rc.WriteTimeout,
+		}), nil
+	case RedisCluster:
+		if rc.DB != 0 {
+			return nil, fmt.Errorf("config: redis cluster only supports database 0")
+		}
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:        rc.Addrs,
+			Username:     rc.Username,
+			Password:     rc.Password,
+			TLSConfig:    tlsConfig,
+			PoolSize:     rc.PoolSize,
+			MinIdleConns: rc.MinIdleConns,
+			DialTimeout:  rc.DialTimeout,
+			ReadTimeout:  rc.ReadTimeout,
+			WriteTimeout: rc.WriteTimeout,
+		}), nil
+	}
+	return nil, fmt.Errorf("config: unknown redis topology %q", rc.Topology)
+}
+
+// Option functions
+
+func WithRedisTopology(topology string) func(*RedisConfig) {
+	return func(cfg *RedisConfig) {
+		cfg.Topology = topology
+	}
+}
+
+func WithRedisAddrs(addrs ...string) func(*RedisConfig) {
+	return func(cfg *RedisConfig) {
+		cfg.Addrs = addrs
+	}
+}
+
+func WithRedisSentinel(masterName string, sentinelAddrs ...string) func(*RedisConfig) {
+	return func(cfg *RedisConfig) {
+		cfg.Topology = RedisSentinel
+		cfg.MasterName = masterName
+		cfg.Addrs = sentinelAddrs
+	}
+}
+
+func WithRedisAuth(username, password string) func(*RedisConfig) {
+	return func(cfg *RedisConfig) {
+		cfg.Username = username
+		cfg.Password = password
+	}
+}
+
+func WithRedisDB(db int) func(*RedisConfig) {
+	return func(cfg *RedisConfig) {
+		cfg.DB = db
+	}
+}
+
+func WithRedisTLS(tlsConfig *tls.Config) func(*RedisConfig) {
+	return func(cfg *RedisConfig) {
+		cfg.TLS = true
+		cfg.TLSConfig = tlsConfig
+	}
+}
+
+func WithRedisPool(size, minIdle int) func(*RedisConfig) {
+	return func(cfg *RedisConfig) {
+		cfg.PoolSize = size
+		cfg.MinIdleConns = minIdle
+	}
+}
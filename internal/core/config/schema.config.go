@@ -0,0 +1,295 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Types of configuration values.
+const (
+	TypeString   = "string"
+	TypeInt      = "int"
+	TypeFloat    = "float"
+	TypeBool     = "bool"
+	TypeDuration = "duration" // e.g. 1m30s
+	TypeURL      = "url"      // Absolute URL
+	TypeList     = "list"     // Comma separated strings
+)
+
+// Field declares a configuration key.
+type Field struct {
+	Key         string   `yaml:"key"`
+	Type        string   `yaml:"type"`    // One of the Type constants, string when empty
+	Default     string   `yaml:"default"` // Value used when the key is not set
+	Required    bool     `yaml:"required"`
+	Allowed     []string `yaml:"allowed"` // Accepted values, every item for lists
+	Description string   `yaml:"description"`
+	Secret      bool     `yaml:"secret"` // Left empty in samples
+}
+
+// Schema declares the configuration keys of an application, validated at startup.
+type Schema struct {
+	Fields []Field
+}
+
+// SchemaError lists all the problems found by Schema.Validate.
+type SchemaError struct {
+	Problems []string
+}
+
+func (e *SchemaError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// NewSchema creates a schema of the fields.
+//
+// Example usage:
+//
+//	schema := config.NewSchema(
+//		config.Field{Key: "SERVER_PORT", Type: config.TypeInt, Default: "8080", Description: "Port the server listens on"},
+//		config.Field{Key: "DATABASE_URL", Type: config.TypeURL, Required: true, Secret: true},
+//		config.Field{Key: "LOG_LEVEL", Default: "info", Allowed: []string{"debug", "info", "warn", "error"}},
+//	)
+//	cfg, err := config.LoadConfigWithSchema(schema)
+func NewSchema(fields ...Field) *Schema {
+	return &Schema{Fields: fields}
+}
+
+// LoadSchemaFile reads a schema from a YAML or JSON file listing the fields, so the schema can
+// be shared with `lessgo config`.
+//
+//	# config.schema.yaml
+//	- key: SERVER_PORT
+//	  type: int
+//	  default: 8080
+//	  description: Port the server listens on
+//	- key: LOG_LEVEL
+//	  allowed: [debug, info, warn, error]
+//	  default: info
+func LoadSchemaFile(path string) (*Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var fields []Field
+	if err := yaml.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("config: parsing schema %s: %w", path, err)
+	}
+	return NewSchema(fields...), nil
+}
+
+// Add adds a field to the schema.
+func (s *Schema) Add(field Field) *Schema {
+	s.Fields = append(s.Fields, field)
+	return s
+}
+
+// Validate checks the configuration against the schema and returns a *SchemaError listing
+// every missing key, value of the wrong type and value not allowed, along with mistakes in
+// the schema itself. Defaults are validated too.
+func (s *Schema) Validate(c Config) error {
+	var problems []string
+	seen := make(map[string]bool, len(s.Fields))
+	for _, field := range s.Fields {
+		switch {
+		case field.Key == "":
+			problems = append(problems, "schema: field without a key")
+			continue
+		case seen[field.Key]:
+			problems = append(problems, fmt.Sprintf("schema: %s declared twice", field.Key))
+			continue
+		}
+		seen[field.Key] = true
+
+		value, ok := c[field.Key]
+		if !ok || value == "" {
+			if field.Default == "" {
+				if field.Required {
+					problems = append(problems, fmt.Sprintf("%s is required", field.Key))
+				}
+				continue
+			}
+			value = field.Default
+		}
+		if problem := field.check(value); problem != "" {
+			problems = append(problems, problem)
+		}
+	}
+	if len(problems) > 0 {
+		return &SchemaError{Problems: problems}
+	}
+	return nil
+}
+
+// Apply returns the configuration with the defaults of the schema for the keys not set, and
+// the error of Validate.
+func (s *Schema) Apply(c Config) (Config, error) {
+	defaults := make(Config)
+	for _, field := range s.Fields {
+		if field.Default != "" && c[field.Key] == "" {
+			defaults[field.Key] = field.Default
+		}
+	}
+	return c.merge(defaults), s.Validate(c)
+}
+
+// check returns the problem with the value of the field, empty when it is valid.
+func (f Field) check(value string) string {
+	values := []string{value}
+	var err error
+	switch f.Type {
+	case "", TypeString:
+	case TypeInt:
+		_, err = strconv.Atoi(value)
+	case TypeFloat:
+		_, err = strconv.ParseFloat(value, 64)
+	case TypeBool:
+		_, err = strconv.ParseBool(value)
+	case TypeDuration:
+		_, err = time.ParseDuration(value)
+	case TypeURL:
+		var u *url.URL
+		if u, err = url.Parse(value); err == nil && (u.Scheme == "" || u.Host == "" && u.Opaque == "" && u.Path == "") {
+			err = fmt.Errorf("not absolute")
+		}
+	case TypeList:
+		values = nil
+		for _, item := range strings.Split(value, ",") {
+			values = append(values, strings.TrimSpace(item))
+		}
+	default:
+		return fmt.Sprintf("schema: %s has the unknown type %q", f.Key, f.Type)
+	}
+	if err != nil {
+		return fmt.Sprintf("%s: %s is not a valid %s", f.Key, f.display(value), f.Type)
+	}
+	if len(f.Allowed) == 0 {
+		return ""
+	}
+	for _, v := range values {
+		if !contains(f.Allowed, v) {
+			return fmt.Sprintf("%s: %s is not one of %s", f.Key, f.display(v), strings.Join(f.Allowed, ", "))
+		}
+	}
+	return ""
+}
+
+// display quotes a value for errors, hiding secrets.
+func (f Field) display(value string) string {
+	if f.Secret {
+		return "the value"
+	}
+	return strconv.Quote(value)
+}
+
+// summary describes the type, allowed values and default of the field for samples.
+func (f Field) summary() string {
+	typ := f.Type
+	if typ == "" {
+		typ = TypeString
+	}
+	parts := []string{typ}
+	if f.Required {
+		parts = append(parts, "required")
+	}
+	if len(f.Allowed) > 0 {
+		parts = append(parts, "one of "+strings.Join(f.Allowed, ", "))
+	}
+	if f.Default != "" && !f.Secret {
+		parts = append(parts, "default "+f.Default)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// sampleValue is the value written for the field in samples.
+func (f Field) sampleValue() string {
+	if f.Secret {
+		return ""
+	}
+	return f.Default
+}
+
+// WriteEnvSample writes a documented .env file of the schema. Optional keys without a
+// default are commented out.
+//
+// Example usage:
+//
+//	file, _ := os.Create(".env.example")
+//	defer file.Close()
+//	err := schema.WriteEnvSample(file)
+func (s *Schema) WriteEnvSample(w io.Writer) error {
+	var buf bytes.Buffer
+	for i, field := range s.Fields {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		for _, line := range strings.Split(strings.TrimSpace(field.Description), "\n") {
+			if line != "" {
+				fmt.Fprintf(&buf, "# %s\n", line)
+			}
+		}
+		fmt.Fprintf(&buf, "# %s\n", field.summary())
+		prefix := ""
+		if !field.Required && field.Default == "" {
+			prefix = "# "
+		}
+		value := field.sampleValue()
+		if strings.ContainsAny(value, " #\"'\t") {
+			value = strconv.Quote(value)
+		}
+		fmt.Fprintf(&buf, "%s%s=%s\n", prefix, field.Key, value)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// WriteYAMLSample writes a documented YAML mapping of the keys of the schema to string values,
+// as taken by Kubernetes config maps and Compose environments.
+func (s *Schema) WriteYAMLSample(w io.Writer) error {
+	doc := &yaml.Node{Kind: yaml.MappingNode}
+	for _, field := range s.Fields {
+		comment := field.summary()
+		if description := strings.TrimSpace(field.Description); description != "" {
+			comment = description + "\n" + comment
+		}
+		key := &yaml.Node{Kind: yaml.ScalarNode, Value: field.Key, HeadComment: comment}
+		value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: field.sampleValue(), Style: yaml.DoubleQuotedStyle}
+		doc.Content = append(doc.Content, key, value)
+	}
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// LoadConfigWithSchema loads the configuration like LoadConfig, fills in the defaults of the
+// schema and validates it, so every problem is reported at once before the application starts.
+//
+// Example usage:
+//
+//	cfg, err := config.LoadConfigWithSchema(schema)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+func LoadConfigWithSchema(s *Schema) (Config, error) {
+	return s.Apply(LoadConfig())
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -2,15 +2,18 @@ package context
 
 import (
 	"bytes"
+	stdcontext "context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"net/url"
 
 	"github.com/gorilla/mux"
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+	"github.com/hokamsingh/lessgo/internal/core/reporting"
+	"github.com/hokamsingh/lessgo/internal/core/sanitize"
 	"github.com/hokamsingh/lessgo/internal/utils"
 )
 
@@ -18,7 +21,9 @@ import (
 type Context struct {
 	Req          *http.Request
 	Res          http.ResponseWriter
-	responseSent bool // Track whether a response has been sent
+	responseSent bool                   // Track whether a response has been sent
+	meta         map[string]interface{} // Meta values added to envelope responses
+	flash        *flashState            // Flash messages received and set, see Flash
 }
 
 // NewContext creates a new Context instance.
@@ -48,6 +53,9 @@ func (c *Context) GetJSONBody() (map[string]interface{}, bool) {
 //	status (int): The HTTP status code to send with the response.
 //	v (interface{}): The data to encode as JSON and send in the response.
 //
+// The output is indented according to the mode set with SetPrettyJSON, after
+// response pipes registered with WithPipes or Router.UsePipe have run.
+//
 // Example usage:
 //
 //	ctx.JSON(http.StatusOK, map[string]string{"message": "success"})
@@ -56,23 +64,19 @@ func (c *Context) JSON(status int, v interface{}) {
 		log.Fatal("Response already sent")
 		return
 	}
+	v, ok := c.prepareResponse(v)
+	if !ok {
+		return
+	}
+	c.sendJSON(status, v)
+}
+
+// sendJSON writes v as JSON without running response pipes.
+func (c *Context) sendJSON(status int, v interface{}) {
 	c.Res.Header().Set("Content-Type", "application/json")
 	c.Res.WriteHeader(status)
-	// Check if v is a string and if it's a valid JSON string
-	if str, ok := v.(string); ok {
-		// Check if the string is a valid JSON by attempting to unmarshal it
-		var temp interface{}
-		if err := json.Unmarshal([]byte(str), &temp); err == nil {
-			// Valid JSON string, write it directly without re-encoding
-			c.Res.Write([]byte(str))
-		} else {
-			// Invalid JSON string, encode it normally as a string
-			json.NewEncoder(c.Res).Encode(v)
-		}
-	} else {
-		// For non-string types, encode normally
-		json.NewEncoder(c.Res).Encode(v)
-	}
+	// Strings holding valid JSON are written without re-encoding, everything else is encoded
+	writeJSON(c.Res, v, c.prettyJSON())
 
 	c.responseSent = true
 	c.Res.(http.Flusher).Flush() // Ensures the data is sent to the client
@@ -110,6 +114,9 @@ func (c *Context) Send(v any) {
 //	status (int): The HTTP status code to send with the response.
 //	message (string): The error message to include in the response.
 //
+// Server errors (5xx) are sent to the error reporter set with WithErrorReporter, along with
+// the route, user and request ID.
+//
 // Example usage:
 //
 //	ctx.Error(http.StatusBadRequest, "Invalid request")
@@ -118,6 +125,9 @@ func (c *Context) Error(status int, message string) {
 		log.Fatal("Response already sent")
 		return
 	}
+	if status >= http.StatusInternalServerError {
+		reporting.ReportRequest(c.Req, errors.New(message), status)
+	}
 	c.Res.Header().Set("Content-Type", "application/json")
 	c.Res.WriteHeader(status)
 	err := json.NewEncoder(c.Res).Encode(map[string]string{"error": message})
@@ -131,7 +141,9 @@ func (c *Context) Error(status int, message string) {
 
 // Body parses the JSON request body into the provided interface.
 //
-// This method decodes the JSON body of the request into the provided value.
+// This method decodes the JSON body of the request into the provided value. Bodies sent as
+// application/x-protobuf are decoded as protobuf messages instead, so v must be one. String
+// fields with a `sanitize:"strict"` or `sanitize:"ugc"` tag are cleaned of unsafe HTML.
 //
 // Parameters:
 //
@@ -149,16 +161,23 @@ func (c *Context) Body(v interface{}) error {
 	if c.Req.Body == nil {
 		return errors.New("request body is nil")
 	}
-	bodyBytes, err := io.ReadAll(c.Req.Body)
+	// The body stays readable for later calls
+	bodyBytes, err := middleware.ReadBody(c.Req)
 	if err != nil {
 		return err
 	}
+	if messageType, ok := isProtobufContentType(c.Req.Header.Get("Content-Type")); ok {
+		// An empty body is a message with all fields at their defaults
+		return decodeProtobuf(bodyBytes, v, messageType)
+	}
 	if len(bodyBytes) == 0 {
 		return errors.New("empty request body")
 	}
-	// Reset the body so it can be read again later if needed
-	c.Req.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
-	return json.NewDecoder(bytes.NewReader(bodyBytes)).Decode(v)
+	if err := json.NewDecoder(bytes.NewReader(bodyBytes)).Decode(v); err != nil {
+		return err
+	}
+	// Fields declared with a sanitize tag are cleaned with their policy
+	return sanitize.Struct(v)
 }
 
 // Redirect sends a redirect response to the given URL.
@@ -318,3 +337,51 @@ func (c *Context) FileAttachment(filepath, filename string) {
 	}
 	http.ServeFile(c.Res, c.Req, filepath)
 }
+
+// Principal returns the authenticated identity of the request, as set by an
+// authentication middleware such as the trusted header auth.
+//
+// Example usage:
+//
+//	if principal, ok := ctx.Principal(); ok && principal.HasGroup("admins") {
+//		ctx.Send("welcome " + principal.User)
+//	}
+func (c *Context) Principal() (*middleware.Principal, bool) {
+	return middleware.GetPrincipal(c.Req.Context())
+}
+
+// ClientIP returns the IP address of the client, resolved from the forwarding headers
+// when the request came through a proxy trusted with WithTrustedProxies.
+//
+// Example usage:
+//
+//	log.Printf("login from %s", ctx.ClientIP())
+func (c *Context) ClientIP() string {
+	return middleware.ClientIP(c.Req)
+}
+
+// Context returns the context of the request. It is canceled when the client disconnects and
+// carries the deadline of WithRequestTimeout, so database, Redis and HTTP calls made with it
+// stop along with the request.
+//
+// Example usage:
+//
+//	user, err := database.One[User](ctx.Context(), db, database.Select("*").From("users").Where("id = ?", id))
+func (c *Context) Context() stdcontext.Context {
+	return c.Req.Context()
+}
+
+// RawBody returns the request body as it was received, before pipes transformed it, for
+// example to verify a signature. The body stays readable for ctx.Body. With
+// WithBodyCapture, bodies over the capture limit return middleware.ErrBodyTooLarge.
+//
+// Example usage:
+//
+//	body, err := ctx.RawBody()
+//	if err != nil || !verify(body, ctx.GetHeader("X-Signature")) {
+//		ctx.Error(http.StatusUnauthorized, "invalid signature")
+//		return
+//	}
+func (c *Context) RawBody() ([]byte, error) {
+	return middleware.RawBody(c.Req)
+}
@@ -0,0 +1,162 @@
+package context
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/crypto"
+)
+
+var (
+	// ErrNoCookieKeys is returned when signed or encrypted cookies are used before
+	// SetCookieKeys.
+	ErrNoCookieKeys = errors.New("no cookie keys configured")
+	// ErrCookieTooLarge is returned when an encoded cookie exceeds the 4096 bytes browsers keep.
+	ErrCookieTooLarge = errors.New("cookie exceeds 4096 bytes")
+)
+
+var cookieKeyring atomic.Pointer[crypto.Keyring]
+
+// SetCookieKeys sets the secrets of signed and encrypted cookies, newest first. New cookies
+// use the first key; cookies made with any of them are accepted, so keys are rotated by
+// prepending a new one and dropping the oldest once its cookies expired.
+//
+// Example usage:
+//
+//	context.SetCookieKeys(cfg.GetKeys("COOKIE_KEYS")...)
+func SetCookieKeys(secrets ...[]byte) {
+	SetCookieKeyring(crypto.KeyringFromSecrets(secrets...))
+}
+
+// SetCookieKeyring sets the keyring of signed and encrypted cookies, to share it with other
+// components or rotate keys at runtime.
+func SetCookieKeyring(keyring *crypto.Keyring) {
+	cookieKeyring.Store(keyring)
+}
+
+// loadCookieKeyring returns the cookie keyring, nil when no keys are set.
+func loadCookieKeyring() *crypto.Keyring {
+	keyring := cookieKeyring.Load()
+	if keyring == nil || keyring.Len() == 0 {
+		return nil
+	}
+	return keyring
+}
+
+// cookieExpiry returns the expiry embedded in cookies, so a copied cookie stops working when
+// the browser would have dropped it. Zero means a session cookie.
+func cookieExpiry(maxAge int) int64 {
+	if maxAge <= 0 {
+		return 0
+	}
+	return time.Now().Add(time.Duration(maxAge) * time.Second).Unix()
+}
+
+func (c *Context) setEncodedCookie(name, value string, maxAge int, path string, httpOnly bool, secure bool, sameSite http.SameSite) error {
+	if len(name)+len(value) > 4096 {
+		return ErrCookieTooLarge
+	}
+	c.SetCookie(name, value, maxAge, path, httpOnly, secure, sameSite)
+	return nil
+}
+
+// SetSignedCookie sets a cookie whose value can be read but not modified by the client. It
+// takes the same parameters as SetCookie.
+//
+// Example usage:
+//
+//	err := ctx.SetSignedCookie("cart_id", cartID, 7*24*3600, "/", true, true, http.SameSiteLax)
+func (c *Context) SetSignedCookie(name, value string, maxAge int, path string, httpOnly bool, secure bool, sameSite http.SameSite) error {
+	keyring := loadCookieKeyring()
+	if keyring == nil {
+		return ErrNoCookieKeys
+	}
+	payload := base64.RawURLEncoding.EncodeToString([]byte(value)) + "." + strconv.FormatInt(cookieExpiry(maxAge), 10)
+	// The name is signed so the cookie cannot be replayed under another name
+	signature := base64.RawURLEncoding.EncodeToString(keyring.Sign([]byte(name + "|" + payload)))
+	return c.setEncodedCookie(name, payload+"."+signature, maxAge, path, httpOnly, secure, sameSite)
+}
+
+// GetSignedCookie returns the value of a cookie set with SetSignedCookie. Cookies that were
+// modified, expired or signed with an unknown key are reported as missing.
+//
+// Example usage:
+//
+//	cartID, ok := ctx.GetSignedCookie("cart_id")
+func (c *Context) GetSignedCookie(name string) (string, bool) {
+	raw, ok := c.GetCookie(name)
+	keyring := loadCookieKeyring()
+	if !ok || keyring == nil {
+		return "", false
+	}
+	cut := strings.LastIndexByte(raw, '.')
+	if cut < 0 {
+		return "", false
+	}
+	payload := raw[:cut]
+	signature, err := base64.RawURLEncoding.DecodeString(raw[cut+1:])
+	if err != nil || !keyring.Verify([]byte(name+"|"+payload), signature) {
+		return "", false
+	}
+	encoded, expiry, _ := strings.Cut(payload, ".")
+	if expires, _ := strconv.ParseInt(expiry, 10, 64); expires > 0 && time.Now().Unix() > expires {
+		return "", false
+	}
+	value, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", false
+	}
+	return string(value), true
+}
+
+// SetEncryptedCookie sets a cookie whose value the client can neither read nor modify,
+// encrypted with AES-GCM. It takes the same parameters as SetCookie.
+//
+// Example usage:
+//
+//	err := ctx.SetEncryptedCookie("session", sessionID, 0, "/", true, true, http.SameSiteStrictMode)
+func (c *Context) SetEncryptedCookie(name, value string, maxAge int, path string, httpOnly bool, secure bool, sameSite http.SameSite) error {
+	keyring := loadCookieKeyring()
+	if keyring == nil {
+		return ErrNoCookieKeys
+	}
+	plaintext := binary.BigEndian.AppendUint64(nil, uint64(cookieExpiry(maxAge)))
+	plaintext = append(plaintext, value...)
+	sealed, err := keyring.Encrypt(plaintext, []byte(name))
+	if err != nil {
+		return err
+	}
+	return c.setEncodedCookie(name, base64.RawURLEncoding.EncodeToString(sealed), maxAge, path, httpOnly, secure, sameSite)
+}
+
+// GetEncryptedCookie returns the value of a cookie set with SetEncryptedCookie. Cookies that
+// were modified, expired or encrypted with an unknown key are reported as missing.
+//
+// Example usage:
+//
+//	sessionID, ok := ctx.GetEncryptedCookie("session")
+func (c *Context) GetEncryptedCookie(name string) (string, bool) {
+	raw, ok := c.GetCookie(name)
+	keyring := loadCookieKeyring()
+	if !ok || keyring == nil {
+		return "", false
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return "", false
+	}
+	plaintext, err := keyring.Decrypt(sealed, []byte(name))
+	if err != nil || len(plaintext) < 8 {
+		return "", false
+	}
+	if expires := int64(binary.BigEndian.Uint64(plaintext)); expires > 0 && time.Now().Unix() > expires {
+		return "", false
+	}
+	return string(plaintext[8:]), true
+}
@@ -0,0 +1,181 @@
+package context
+
+import (
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Machine-readable error codes used by ctx.Fail. Applications can add their own with RegisterErrorCode.
+const (
+	CodeBadRequest      = "BAD_REQUEST"
+	CodeValidation      = "VALIDATION_FAILED"
+	CodeUnauthorized    = "UNAUTHORIZED"
+	CodeForbidden       = "FORBIDDEN"
+	CodeNotFound        = "NOT_FOUND"
+	CodeConflict        = "CONFLICT"
+	CodeTooManyRequests = "TOO_MANY_REQUESTS"
+	CodeInternal        = "INTERNAL_ERROR"
+	CodeUnavailable     = "SERVICE_UNAVAILABLE"
+)
+
+// APIError is the error part of a response envelope.
+type APIError struct {
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+// EnvelopeOptions configures the shape of the envelope sent by ctx.OK, ctx.Created and ctx.Fail.
+type EnvelopeOptions struct {
+	DataKey  string
+	ErrorKey string
+	MetaKey  string
+
+	// IncludeTimestamp adds the response time to the meta object.
+	IncludeTimestamp bool
+	// RequestIDHeader, when set, adds the value of this request header to the meta object.
+	RequestIDHeader string
+}
+
+// NewEnvelopeOptions returns the default envelope shape: {"data": ..., "error": ..., "meta": ...}.
+func NewEnvelopeOptions() *EnvelopeOptions {
+	return &EnvelopeOptions{
+		DataKey:  "data",
+		ErrorKey: "error",
+		MetaKey:  "meta",
+	}
+}
+
+var (
+	envelopeMu      sync.RWMutex
+	envelopeOptions = *NewEnvelopeOptions()
+	errorStatuses   = map[string]int{
+		CodeBadRequest:      http.StatusBadRequest,
+		CodeValidation:      http.StatusUnprocessableEntity,
+		CodeUnauthorized:    http.StatusUnauthorized,
+		CodeForbidden:       http.StatusForbidden,
+		CodeNotFound:        http.StatusNotFound,
+		CodeConflict:        http.StatusConflict,
+		CodeTooManyRequests: http.StatusTooManyRequests,
+		CodeInternal:        http.StatusInternalServerError,
+		CodeUnavailable:     http.StatusServiceUnavailable,
+	}
+)
+
+// SetEnvelopeOptions sets the envelope shape for the whole application.
+func SetEnvelopeOptions(options EnvelopeOptions) {
+	envelopeMu.Lock()
+	defer envelopeMu.Unlock()
+	envelopeOptions = options
+}
+
+// RegisterErrorCode maps an error code to the HTTP status ctx.Fail responds with.
+//
+// Example usage:
+//
+//	context.RegisterErrorCode("PAYMENT_REQUIRED", http.StatusPaymentRequired)
+func RegisterErrorCode(code string, status int) {
+	envelopeMu.Lock()
+	defer envelopeMu.Unlock()
+	errorStatuses[code] = status
+}
+
+// StatusForCode returns the HTTP status of an error code. Unknown codes map to 500.
+func StatusForCode(code string) int {
+	envelopeMu.RLock()
+	defer envelopeMu.RUnlock()
+	if status, ok := errorStatuses[code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// SetMeta adds a value to the meta object of the next envelope response.
+//
+// Example usage:
+//
+//	ctx.SetMeta("version", "v2")
+//	ctx.OK(user)
+func (c *Context) SetMeta(key string, value interface{}) {
+	if c.meta == nil {
+		c.meta = make(map[string]interface{})
+	}
+	c.meta[key] = value
+}
+
+// OK sends data in the response envelope with status 200.
+//
+// Example usage:
+//
+//	ctx.OK(user) // {"data": {...}, "error": null}
+func (c *Context) OK(data interface{}) {
+	c.sendEnvelope(http.StatusOK, data, nil)
+}
+
+// Created sends data in the response envelope with status 201.
+//
+// Example usage:
+//
+//	ctx.Created(order)
+func (c *Context) Created(data interface{}) {
+	c.sendEnvelope(http.StatusCreated, data, nil)
+}
+
+// Fail sends an error in the response envelope. The status code is derived from the error
+// code, see RegisterErrorCode. Details are optional and typically hold field errors.
+//
+// Example usage:
+//
+//	ctx.Fail(context.CodeValidation, "Invalid input", map[string]string{"email": "is required"})
+func (c *Context) Fail(code, message string, details ...interface{}) {
+	apiErr := &APIError{Code: code, Message: message}
+	if len(details) == 1 {
+		apiErr.Details = details[0]
+	} else if len(details) > 1 {
+		apiErr.Details = details
+	}
+	c.sendEnvelope(StatusForCode(code), nil, apiErr)
+}
+
+// sendEnvelope renders the configured envelope as JSON.
+func (c *Context) sendEnvelope(status int, data interface{}, apiErr *APIError) {
+	if c.responseSent {
+		log.Fatal("Response already sent")
+		return
+	}
+	// Pipes post-process the payload only, the envelope itself keeps its shape
+	if data != nil {
+		var ok bool
+		if data, ok = c.prepareResponse(data); !ok {
+			return
+		}
+	}
+
+	envelopeMu.RLock()
+	options := envelopeOptions
+	envelopeMu.RUnlock()
+
+	meta := make(map[string]interface{}, len(c.meta)+2)
+	for key, value := range c.meta {
+		meta[key] = value
+	}
+	if options.IncludeTimestamp {
+		meta["timestamp"] = time.Now().UTC().Format(time.RFC3339)
+	}
+	if options.RequestIDHeader != "" {
+		if id := c.Req.Header.Get(options.RequestIDHeader); id != "" {
+			meta["request_id"] = id
+		}
+	}
+
+	body := map[string]interface{}{
+		options.DataKey:  data,
+		options.ErrorKey: apiErr,
+	}
+	if len(meta) > 0 && options.MetaKey != "" {
+		body[options.MetaKey] = meta
+	}
+	c.sendJSON(status, body)
+}
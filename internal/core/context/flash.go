@@ -0,0 +1,135 @@
+package context
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// FlashCookie is the cookie carrying flash messages to the next request.
+const FlashCookie = "_flash"
+
+// flashState holds the flash messages of a request: those received from the previous request
+// and those set for the next one.
+type flashState struct {
+	loaded   bool
+	received map[string][]string
+	pending  map[string][]string
+}
+
+func (c *Context) flashes() *flashState {
+	if c.flash == nil {
+		c.flash = &flashState{}
+	}
+	return c.flash
+}
+
+// Flash sets a one-shot message shown by the next request, typically after a redirect. The
+// kind groups messages, e.g. success or error. Messages are kept in a cookie, signed when
+// cookie keys are set with SetCookieKeys.
+//
+// Example usage:
+//
+//	ctx.Flash("success", "Profile saved")
+//	ctx.Redirect(http.StatusSeeOther, "/profile")
+func (c *Context) Flash(kind, message string) {
+	state := c.flashes()
+	if state.pending == nil {
+		state.pending = map[string][]string{}
+	}
+	state.pending[kind] = append(state.pending[kind], message)
+	c.writeFlashCookie()
+}
+
+// Flashes returns the flash messages by kind and clears them, so they are shown once. Messages
+// set with Flash during this request are included. Templates rendered by Negotiate can call
+// the flashes function instead:
+//
+//	{{range $kind, $messages := flashes}}
+//		{{range $messages}}<div class="alert alert-{{$kind}}">{{.}}</div>{{end}}
+//	{{end}}
+func (c *Context) Flashes() map[string][]string {
+	state := c.flashes()
+	c.loadFlashes()
+	if len(state.pending) > 0 {
+		if state.received == nil {
+			state.received = map[string][]string{}
+		}
+		for kind, messages := range state.pending {
+			state.received[kind] = append(state.received[kind], messages...)
+		}
+		state.pending = nil
+	}
+	c.writeFlashCookie()
+	return state.received
+}
+
+// hasFlashes reports whether the request received or set flash messages.
+func (c *Context) hasFlashes() bool {
+	if c.flash != nil && (len(c.flash.pending) > 0 || len(c.flash.received) > 0) {
+		return true
+	}
+	_, err := c.Req.Cookie(FlashCookie)
+	return err == nil
+}
+
+// flashFuncs returns the template functions bound to the flash messages of the request.
+func (c *Context) flashFuncs() template.FuncMap {
+	return template.FuncMap{"flashes": c.Flashes}
+}
+
+// loadFlashes reads the messages received from the previous request, once.
+func (c *Context) loadFlashes() {
+	state := c.flashes()
+	if state.loaded {
+		return
+	}
+	state.loaded = true
+	value, ok := c.GetSignedCookie(FlashCookie)
+	if !ok && loadCookieKeyring() == nil {
+		if encoded, found := c.GetCookie(FlashCookie); found {
+			decoded, err := base64.RawURLEncoding.DecodeString(encoded)
+			value, ok = string(decoded), err == nil
+		}
+	}
+	if !ok {
+		return
+	}
+	if err := json.Unmarshal([]byte(value), &state.received); err != nil {
+		state.received = nil
+	}
+}
+
+// writeFlashCookie replaces the flash cookie of the response: it carries the pending messages,
+// or deletes the received ones.
+func (c *Context) writeFlashCookie() {
+	header := c.Res.Header()
+	cookies := header.Values("Set-Cookie")
+	header.Del("Set-Cookie")
+	for _, cookie := range cookies {
+		if !strings.HasPrefix(cookie, FlashCookie+"=") {
+			header.Add("Set-Cookie", cookie)
+		}
+	}
+
+	state := c.flashes()
+	secure := c.Req.TLS != nil
+	if len(state.pending) == 0 {
+		if _, err := c.Req.Cookie(FlashCookie); err == nil {
+			c.SetCookie(FlashCookie, "", -1, "/", true, secure, http.SameSiteLaxMode)
+		}
+		return
+	}
+	data, _ := json.Marshal(state.pending)
+	if loadCookieKeyring() == nil {
+		// Without keys the messages are only encoded, which is enough for plain text shown escaped
+		c.SetCookie(FlashCookie, base64.RawURLEncoding.EncodeToString(data), 0, "/", true, secure, http.SameSiteLaxMode)
+		return
+	}
+	if err := c.SetSignedCookie(FlashCookie, string(data), 0, "/", true, secure, http.SameSiteLaxMode); err != nil {
+		log.Printf("Failed to set flash messages: %v", err)
+	}
+}
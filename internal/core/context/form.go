@@ -0,0 +1,246 @@
+package context
+
+import (
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/sanitize"
+)
+
+// defaultMaxMultipartMemory is the memory limit used when parsing multipart forms.
+const defaultMaxMultipartMemory = 32 << 20 // 32 MB
+
+var (
+	timeType           = reflect.TypeOf(time.Time{})
+	fileHeaderType     = reflect.TypeOf(&multipart.FileHeader{})
+	fileHeaderListType = reflect.TypeOf([]*multipart.FileHeader{})
+)
+
+// BindForm decodes an application/x-www-form-urlencoded or multipart/form-data request into dst,
+// which must be a pointer to a struct. Fields are matched with the `form` tag, falling back
+// to the field name.
+//
+// Supported tag options:
+//   - `form:"name,sanitize"` strips HTML from string values with the strict sanitize policy.
+//   - `sanitize:"ugc"` cleans string values with a policy of the sanitize package.
+//   - `layout:"2006-01-02"` sets the time layout of time.Time fields (RFC 3339 by default).
+//   - `default:"value"` is used when the field is absent from the form.
+//
+// Bool fields follow checkbox semantics: a missing value is false and "on", "true", "1" are true.
+// Slice fields collect repeated values, and *multipart.FileHeader fields receive uploaded files.
+//
+// Example usage:
+//
+//	type SignupForm struct {
+//		Name      string    `form:"name,sanitize"`
+//		Terms     bool      `form:"terms"`
+//		Interests []string  `form:"interests"`
+//		Birthday  time.Time `form:"birthday" layout:"2006-01-02"`
+//	}
+//
+//	var form SignupForm
+//	if err := ctx.BindForm(&form); err != nil {
+//		ctx.Error(http.StatusBadRequest, err.Error())
+//		return
+//	}
+func (c *Context) BindForm(dst interface{}) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("BindForm requires a non-nil pointer to a struct")
+	}
+
+	var files map[string][]*multipart.FileHeader
+	if strings.HasPrefix(c.Req.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := c.Req.ParseMultipartForm(defaultMaxMultipartMemory); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+			return fmt.Errorf("unable to parse multipart form: %w", err)
+		}
+		if c.Req.MultipartForm != nil {
+			files = c.Req.MultipartForm.File
+		}
+	} else if err := c.Req.ParseForm(); err != nil {
+		return fmt.Errorf("unable to parse form: %w", err)
+	}
+
+	if err := bindFormStruct(rv.Elem(), c.Req.Form, files); err != nil {
+		return err
+	}
+	return sanitize.Struct(dst)
+}
+
+// bindFormStruct assigns form values to the exported fields of the struct v.
+func bindFormStruct(v reflect.Value, values map[string][]string, files map[string][]*multipart.FileHeader) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldValue := v.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("form")
+		if tag == "-" {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && tag == "" {
+			if err := bindFormStruct(fieldValue, values, files); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+		clean := false
+		for _, opt := range strings.Split(opts, ",") {
+			clean = clean || strings.TrimSpace(opt) == "sanitize"
+		}
+
+		switch field.Type {
+		case fileHeaderType:
+			if fhs := files[name]; len(fhs) > 0 {
+				fieldValue.Set(reflect.ValueOf(fhs[0]))
+			}
+			continue
+		case fileHeaderListType:
+			if fhs := files[name]; len(fhs) > 0 {
+				fieldValue.Set(reflect.ValueOf(fhs))
+			}
+			continue
+		}
+
+		formValues, present := values[name]
+		if !present {
+			if def, ok := field.Tag.Lookup("default"); ok {
+				formValues = []string{def}
+			} else if field.Type.Kind() == reflect.Bool {
+				// Unchecked checkboxes are not submitted at all
+				fieldValue.SetBool(false)
+				continue
+			} else {
+				continue
+			}
+		}
+
+		layout := field.Tag.Get("layout")
+		if err := setFormField(fieldValue, formValues, layout, clean); err != nil {
+			return fmt.Errorf("invalid value for field %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// setFormField converts the raw form values to the type of v.
+func setFormField(v reflect.Value, values []string, layout string, clean bool) error {
+	if v.Kind() == reflect.Slice && v.Type() != reflect.TypeOf([]byte(nil)) {
+		slice := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setFormValue(slice.Index(i), value, layout, clean); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	}
+
+	if v.Kind() == reflect.Bool {
+		// Checkbox groups submit a hidden "false" before the checkbox, the last value wins
+		return setFormValue(v, values[len(values)-1], layout, clean)
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return setFormValue(v, values[0], layout, clean)
+}
+
+// setFormValue converts a single raw value to the type of v.
+func setFormValue(v reflect.Value, value, layout string, clean bool) error {
+	if v.Kind() == reflect.Ptr {
+		if value == "" {
+			return nil
+		}
+		ptr := reflect.New(v.Type().Elem())
+		if err := setFormValue(ptr.Elem(), value, layout, clean); err != nil {
+			return err
+		}
+		v.Set(ptr)
+		return nil
+	}
+
+	if v.Type() == timeType {
+		if value == "" {
+			return nil
+		}
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		parsed, err := time.Parse(layout, value)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(parsed))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		if clean {
+			value = sanitize.String(sanitize.Strict, value)
+		}
+		v.SetString(value)
+	case reflect.Bool:
+		switch strings.ToLower(value) {
+		case "on", "yes", "true", "1":
+			v.SetBool(true)
+		case "", "off", "no", "false", "0":
+			v.SetBool(false)
+		default:
+			return fmt.Errorf("%q is not a boolean", value)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if value == "" {
+			return nil
+		}
+		if v.Type() == reflect.TypeOf(time.Duration(0)) {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return err
+			}
+			v.SetInt(int64(d))
+			return nil
+		}
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if value == "" {
+			return nil
+		}
+		n, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		if value == "" {
+			return nil
+		}
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package context
+
+import (
+	"net/http"
+
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+)
+
+// EarlyHints sends the links in a 103 Early Hints response, so the client preloads them
+// while the handler prepares the page. It must be called before the response is written.
+//
+// Example usage:
+//
+//	ctx.EarlyHints(
+//		middleware.Link{URL: "/static/app.css", As: "style"},
+//		middleware.Link{URL: "/static/app.js", As: "script"},
+//	)
+//	products := loadProducts()
+//	ctx.Render(http.StatusOK, "products.html", products)
+func (c *Context) EarlyHints(links ...middleware.Link) {
+	if c.responseSent {
+		return
+	}
+	middleware.SendEarlyHints(c.Res, c.Req, links...)
+}
+
+// Push pushes the same-origin resource at target to HTTP/2 clients. It returns
+// http.ErrNotSupported when the client cannot receive pushes.
+//
+// Example usage:
+//
+//	if err := ctx.Push("/static/app.css"); err != nil {
+//		ctx.EarlyHints(middleware.Link{URL: "/static/app.css", As: "style"})
+//	}
+func (c *Context) Push(target string) error {
+	return middleware.Push(c.Res, target, &http.PushOptions{Header: http.Header{
+		"Accept-Encoding": c.Req.Header.Values("Accept-Encoding"),
+	}})
+}
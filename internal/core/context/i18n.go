@@ -0,0 +1,17 @@
+package context
+
+import (
+	"github.com/hokamsingh/lessgo/internal/core/i18n"
+)
+
+// T translates key into the locale detected by the i18n middleware. Args may be i18n.Args
+// with placeholder values or a number selecting the plural form. Without the middleware,
+// or when the key is not translated, the key is returned.
+//
+// Example usage:
+//
+//	ctx.T("greeting", i18n.Args{"name": user.Name})
+//	ctx.T("cart.items", len(items))
+func (c *Context) T(key string, args ...interface{}) string {
+	return i18n.T(c.Req.Context(), key, args...)
+}
@@ -0,0 +1,105 @@
+package context
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/i18n"
+)
+
+// LocaleSettings returns the locale and time zone a user chose, e.g. from their profile.
+// Empty values fall back to the request headers.
+type LocaleSettings func(r *http.Request) (locale string, timezone string)
+
+var localeSettings atomic.Value // LocaleSettings
+
+// SetLocaleSettings sets where user specific locale and time zone settings come from.
+//
+// Example usage:
+//
+//	context.SetLocaleSettings(func(r *http.Request) (string, string) {
+//		if user, ok := currentUser(r); ok {
+//			return user.Locale, user.TimeZone
+//		}
+//		return "", ""
+//	})
+func SetLocaleSettings(settings LocaleSettings) {
+	localeSettings.Store(settings)
+}
+
+func (c *Context) userLocaleSettings() (string, string) {
+	settings, _ := localeSettings.Load().(LocaleSettings)
+	if settings == nil {
+		return "", ""
+	}
+	return settings(c.Req)
+}
+
+// Locale returns the locale of the request: the user's setting, the locale detected by the
+// i18n middleware or the first Accept-Language entry, in that order, and "en" otherwise.
+func (c *Context) Locale() string {
+	if locale, _ := c.userLocaleSettings(); locale != "" {
+		return i18n.CanonicalLocale(locale)
+	}
+	if _, locale, ok := i18n.FromContext(c.Req.Context()); ok {
+		return locale
+	}
+	if languages := i18n.ParseAcceptLanguage(c.Req.Header.Get("Accept-Language")); len(languages) > 0 {
+		return i18n.CanonicalLocale(languages[0])
+	}
+	return "en"
+}
+
+// locations caches loaded time zones by name.
+var locations sync.Map
+
+// Location returns the time zone of the request: the user's setting, the Time-Zone or
+// X-Timezone header or the tz cookie, in that order, and UTC otherwise. Unknown zone names
+// are ignored.
+func (c *Context) Location() *time.Location {
+	_, timezone := c.userLocaleSettings()
+	candidates := []string{timezone, c.Req.Header.Get("Time-Zone"), c.Req.Header.Get("X-Timezone")}
+	if cookie, err := c.Req.Cookie("tz"); err == nil {
+		candidates = append(candidates, cookie.Value)
+	}
+	for _, name := range candidates {
+		if name == "" {
+			continue
+		}
+		if cached, ok := locations.Load(name); ok {
+			return cached.(*time.Location)
+		}
+		if location, err := time.LoadLocation(name); err == nil {
+			locations.Store(name, location)
+			return location
+		}
+	}
+	return time.UTC
+}
+
+// FormatDate formats the date of t in the request's time zone and locale, e.g. 31.12.2024 in de.
+func (c *Context) FormatDate(t time.Time) string {
+	return i18n.FormatDate(c.Locale(), t.In(c.Location()))
+}
+
+// FormatDateTime formats the date and time of t in the request's time zone and locale.
+func (c *Context) FormatDateTime(t time.Time) string {
+	return i18n.FormatDateTime(c.Locale(), t.In(c.Location()))
+}
+
+// FormatNumber formats v with the request locale's separators, e.g. 1.234,5 in de.
+func (c *Context) FormatNumber(v float64, decimals int) string {
+	return i18n.FormatNumber(c.Locale(), v, decimals)
+}
+
+// FormatCurrency formats an amount of an ISO 4217 currency for the request's locale, e.g. €1,234.50 in en.
+func (c *Context) FormatCurrency(amount float64, currency string) string {
+	return i18n.FormatCurrency(c.Locale(), amount, currency)
+}
+
+// FormatFuncs returns template functions formatting values for the request's locale and time zone.
+func (c *Context) FormatFuncs() map[string]interface{} {
+	return i18n.FuncMap(c.Locale(), c.Location())
+}
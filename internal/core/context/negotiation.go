@@ -0,0 +1,225 @@
+package context
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+)
+
+var (
+	renderersMu        sync.RWMutex
+	renderers          = map[string]Renderer{}
+	rendererOrder      []string
+	defaultContentType = ContentTypeJSON
+)
+
+// RegisterRenderer registers a renderer used by Negotiate for the given content type.
+// Registering the same content type twice replaces the previous renderer.
+//
+// Example usage:
+//
+//	context.RegisterRenderer("text/csv", func(w io.Writer, v interface{}) error {
+//		return csv.NewWriter(w).WriteAll(v.([][]string))
+//	})
+func RegisterRenderer(contentType string, renderer Renderer) {
+	renderersMu.Lock()
+	defer renderersMu.Unlock()
+	if _, exists := renderers[contentType]; !exists {
+		rendererOrder = append(rendererOrder, contentType)
+	}
+	renderers[contentType] = renderer
+}
+
+// SetDefaultContentType sets the content type used when the request has no Accept
+// header or accepts nothing that is registered. It defaults to application/json.
+func SetDefaultContentType(contentType string) {
+	renderersMu.Lock()
+	defer renderersMu.Unlock()
+	defaultContentType = contentType
+}
+
+// DefaultContentType returns the content type used when negotiation has no preference.
+func DefaultContentType() string {
+	renderersMu.RLock()
+	defer renderersMu.RUnlock()
+	return defaultContentType
+}
+
+// Negotiate sends data with the given status code, encoded in the registered content type that
+// best matches the request's Accept header. Quality values (q=) are honored and ties are broken
+// in favor of the default content type, then registration order.
+//
+// When a template name is given and templates are enabled with WithTemplateRendering,
+// text/html is offered as well and renders the named template with data.
+//
+// Example usage:
+//
+//	ctx.Negotiate(http.StatusOK, user)
+//	ctx.Negotiate(http.StatusOK, user, "user.html")
+func (c *Context) Negotiate(status int, data interface{}, template ...string) {
+	if c.responseSent {
+		log.Fatal("Response already sent")
+		return
+	}
+
+	renderersMu.RLock()
+	def := defaultContentType
+	offers := make([]string, 0, len(rendererOrder)+1)
+	for _, contentType := range rendererOrder {
+		// Protobuf is only offered for messages, other values cannot be encoded
+		if contentType != ContentTypeProtobuf || isProtobufMessage(data) {
+			offers = append(offers, contentType)
+		}
+	}
+	available := make(map[string]Renderer, len(renderers)+1)
+	for contentType, renderer := range renderers {
+		available[contentType] = renderer
+	}
+	renderersMu.RUnlock()
+
+	if len(template) > 0 {
+		tmpl := middleware.GetTemplate(c.Req.Context())
+		if c.hasFlashes() {
+			// Bind the flashes function to this request, only when there is something to show
+			tmpl = middleware.GetTemplateWithFuncs(c.Req.Context(), c.flashFuncs())
+		}
+		if tmpl != nil {
+			name := template[0]
+			offers = append(offers, ContentTypeHTML)
+			available[ContentTypeHTML] = func(w io.Writer, v interface{}) error {
+				return tmpl.ExecuteTemplate(w, name, v)
+			}
+		}
+	}
+
+	contentType := NegotiateContentType(c.Req.Header.Get("Accept"), offers, def)
+	renderer, ok := available[contentType]
+	if !ok {
+		http.Error(c.Res, "Not Acceptable", http.StatusNotAcceptable)
+		c.responseSent = true
+		return
+	}
+
+	c.Res.Header().Add("Vary", "Accept")
+	if contentType == ContentTypeHTML {
+		middleware.SetTemplateHeaders(c.Res, c.Req)
+	}
+	if contentType == ContentTypeProtobuf {
+		contentType = protobufContentType(data)
+	}
+	c.render(status, contentType, data, renderer)
+}
+
+// acceptRange is a single media range of an Accept header.
+type acceptRange struct {
+	mainType string
+	subType  string
+	q        float64
+}
+
+// parseAccept parses an Accept header into media ranges sorted by preference.
+// Ranges with invalid quality values are ignored.
+func parseAccept(header string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(fields[0]))
+		if mediaType == "" {
+			continue
+		}
+		mainType, subType, found := strings.Cut(mediaType, "/")
+		if !found {
+			if mediaType != "*" {
+				continue
+			}
+			subType = "*"
+		}
+
+		q := 1.0
+		valid := true
+		for _, param := range fields[1:] {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.ToLower(strings.TrimSpace(key)) != "q" {
+				continue
+			}
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil || parsed < 0 || parsed > 1 {
+				valid = false
+				break
+			}
+			q = parsed
+		}
+		if valid {
+			ranges = append(ranges, acceptRange{mainType: mainType, subType: subType, q: q})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+	return ranges
+}
+
+// specificity ranks how precisely a range matches a content type, or -1 when it does not match.
+func (a acceptRange) specificity(mainType, subType string) int {
+	switch {
+	case a.mainType == mainType && a.subType == subType:
+		return 2
+	case a.mainType == mainType && a.subType == "*":
+		return 1
+	case a.mainType == "*" && a.subType == "*":
+		return 0
+	default:
+		return -1
+	}
+}
+
+// NegotiateContentType selects the best of the offered content types for an Accept header.
+// The quality of an offer is taken from the most specific matching range. When the header is
+// empty or nothing offered is acceptable, def is returned.
+func NegotiateContentType(accept string, offers []string, def string) string {
+	if strings.TrimSpace(accept) == "" || len(offers) == 0 {
+		return def
+	}
+	ranges := parseAccept(accept)
+
+	best := ""
+	bestQ := 0.0
+	for _, offer := range orderOffers(offers, def) {
+		mainType, subType, _ := strings.Cut(strings.ToLower(offer), "/")
+		q, specificity := 0.0, -1
+		for _, r := range ranges {
+			if s := r.specificity(mainType, subType); s > specificity {
+				q, specificity = r.q, s
+			}
+		}
+		if specificity >= 0 && q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	if best == "" {
+		return def
+	}
+	return best
+}
+
+// orderOffers moves the default content type to the front so it wins ties.
+func orderOffers(offers []string, def string) []string {
+	ordered := make([]string, 0, len(offers))
+	for _, offer := range offers {
+		if offer == def {
+			ordered = append(ordered, offer)
+		}
+	}
+	for _, offer := range offers {
+		if offer != def {
+			ordered = append(ordered, offer)
+		}
+	}
+	return ordered
+}
@@ -0,0 +1,43 @@
+package context
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/hokamsingh/lessgo/internal/core/pagination"
+)
+
+// Pagination parses page, limit and cursor query parameters of the request.
+//
+// Example usage:
+//
+//	p := ctx.Pagination()
+//	users, total := repo.List(p.Offset(), p.Limit)
+func (c *Context) Pagination(options ...func(*pagination.Options)) *pagination.Params {
+	return pagination.FromRequest(c.Req, options...)
+}
+
+// Paginated sends a page of items in a consistent JSON envelope holding the items, pagination
+// metadata and next/prev links. The total is also sent in the X-Total-Count header and the
+// links in a Link header. In cursor mode pass a negative total when it is unknown.
+//
+// Example usage:
+//
+//	p := ctx.Pagination()
+//	users, total := repo.List(p.Offset(), p.Limit)
+//	ctx.Paginated(users, total, p)
+func (c *Context) Paginated(items interface{}, total int, p *pagination.Params) {
+	if c.responseSent {
+		log.Fatal("Response already sent")
+		return
+	}
+	envelope := p.Build(items, total)
+	if total >= 0 {
+		c.Res.Header().Set("X-Total-Count", strconv.Itoa(total))
+	}
+	if link := envelope.Links.LinkHeader(); link != "" {
+		c.Res.Header().Set("Link", link)
+	}
+	c.JSON(http.StatusOK, envelope)
+}
@@ -0,0 +1,163 @@
+package context
+
+import (
+	"bytes"
+	stdcontext "context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+)
+
+// Pipe runs around a handler. Before transforms or validates the request and aborts the
+// request by returning an error. After post-processes the data passed to ctx.JSON and the
+// other response helpers before it is encoded.
+type Pipe interface {
+	Before(c *Context) error
+	After(c *Context, data interface{}) (interface{}, error)
+}
+
+// InputPipe is a Pipe that only transforms or validates the request.
+type InputPipe func(c *Context) error
+
+// Before implements Pipe.
+func (p InputPipe) Before(c *Context) error { return p(c) }
+
+// After implements Pipe.
+func (p InputPipe) After(c *Context, data interface{}) (interface{}, error) { return data, nil }
+
+// OutputPipe is a Pipe that only post-processes response data.
+type OutputPipe func(c *Context, data interface{}) (interface{}, error)
+
+// Before implements Pipe.
+func (p OutputPipe) Before(c *Context) error { return nil }
+
+// After implements Pipe.
+func (p OutputPipe) After(c *Context, data interface{}) (interface{}, error) { return p(c, data) }
+
+// ValidationError is returned by pipes when input is invalid. It is sent as a
+// VALIDATION_FAILED error with the field errors as details.
+type ValidationError struct {
+	Fields map[string]string
+}
+
+func (e *ValidationError) Error() string {
+	fields := make([]string, 0, len(e.Fields))
+	for field, message := range e.Fields {
+		fields = append(fields, field+": "+message)
+	}
+	sort.Strings(fields)
+	return "validation failed: " + strings.Join(fields, ", ")
+}
+
+type pipesKey struct{}
+
+// WithPipes wraps a handler so the given pipes run around it.
+//
+// Example usage:
+//
+//	r.Post("/users", context.WithPipes(createUser, pipe.Trim(), pipe.Validate(CreateUserDTO{})))
+func WithPipes(handler func(*Context), pipes ...Pipe) func(*Context) {
+	return func(c *Context) {
+		if c.RunPipes(pipes...) {
+			handler(c)
+		}
+	}
+}
+
+// RunPipes runs the Before step of the pipes and registers them for the response.
+// It reports false when a pipe rejected the request, in which case an error response
+// has been sent already.
+func (c *Context) RunPipes(pipes ...Pipe) bool {
+	if len(pipes) == 0 {
+		return true
+	}
+	existing, _ := c.Req.Context().Value(pipesKey{}).([]Pipe)
+	all := make([]Pipe, 0, len(existing)+len(pipes))
+	all = append(append(all, existing...), pipes...)
+	c.Req = c.Req.WithContext(stdcontext.WithValue(c.Req.Context(), pipesKey{}, all))
+
+	for _, pipe := range pipes {
+		if err := pipe.Before(c); err != nil {
+			c.sendPipeError(err)
+			return false
+		}
+	}
+	return true
+}
+
+// applyPipes runs the After step of all registered pipes on the response data.
+func (c *Context) applyPipes(data interface{}) (interface{}, error) {
+	pipes, _ := c.Req.Context().Value(pipesKey{}).([]Pipe)
+	for _, pipe := range pipes {
+		var err error
+		if data, err = pipe.After(c, data); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// sendPipeError responds to a request rejected by a pipe.
+func (c *Context) sendPipeError(err error) {
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		c.Fail(CodeValidation, "Validation failed", validationErr.Fields)
+		return
+	}
+	c.Fail(CodeBadRequest, err.Error())
+}
+
+// TransformJSONBody decodes the JSON request body, passes it to fn and replaces the body with
+// the result, so later calls to ctx.Body see the transformed input. Requests without a JSON
+// body are left untouched.
+//
+// Example usage:
+//
+//	err := ctx.TransformJSONBody(func(body interface{}) (interface{}, error) {
+//		return lowercaseKeys(body), nil
+//	})
+func (c *Context) TransformJSONBody(fn func(body interface{}) (interface{}, error)) error {
+	if c.Req.Body == nil || !strings.HasPrefix(c.Req.Header.Get("Content-Type"), "application/json") {
+		return nil
+	}
+	data, err := middleware.ReadBody(c.Req)
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+
+	var body interface{}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return fmt.Errorf("invalid JSON body")
+	}
+	body, err = fn(body)
+	if err != nil {
+		return err
+	}
+	if data, err = json.Marshal(body); err != nil {
+		return err
+	}
+	middleware.ReplaceBody(c.Req, data)
+	return nil
+}
+
+// prepareResponse applies the pipes to response data. On failure it sends a 500 response
+// and reports false.
+func (c *Context) prepareResponse(data interface{}) (interface{}, bool) {
+	data, err := c.applyPipes(data)
+	if err != nil {
+		log.Printf("Response pipe failed: %v", err)
+		http.Error(c.Res, "Internal Server Error", http.StatusInternalServerError)
+		c.responseSent = true
+		return nil, false
+	}
+	return data, true
+}
@@ -0,0 +1,198 @@
+package context
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"reflect"
+	"sync"
+)
+
+// ContentTypeProtobuf is the content type of protobuf messages. application/protobuf is
+// accepted for request bodies as well.
+const ContentTypeProtobuf = "application/x-protobuf"
+
+// ErrNotProtobuf is returned for values that are not protobuf messages.
+var ErrNotProtobuf = errors.New("value is not a protobuf message")
+
+var (
+	protobufMu        sync.RWMutex
+	protobufMarshal   = marshalProtobuf
+	protobufUnmarshal = unmarshalProtobuf
+	protobufTypes     = map[string]reflect.Type{}
+	protobufNames     = map[reflect.Type]string{}
+)
+
+func init() {
+	RegisterRenderer(ContentTypeProtobuf, renderProtobuf)
+}
+
+// SetProtobufCodec replaces how messages are encoded. By default messages with generated
+// Marshal() and Unmarshal([]byte) methods, as by gogo/protobuf or csproto, and values
+// implementing encoding.BinaryMarshaler are supported.
+//
+// Example usage:
+//
+//	context.SetProtobufCodec(
+//		func(v interface{}) ([]byte, error) { return proto.Marshal(v.(proto.Message)) },
+//		func(data []byte, v interface{}) error { return proto.Unmarshal(data, v.(proto.Message)) },
+//	)
+func SetProtobufCodec(marshal func(v interface{}) ([]byte, error), unmarshal func(data []byte, v interface{}) error) {
+	protobufMu.Lock()
+	defer protobufMu.Unlock()
+	protobufMarshal = marshal
+	protobufUnmarshal = unmarshal
+}
+
+// RegisterProtobufType registers the message type under its fully qualified name, e.g.
+// "shop.v1.Order". Requests naming it in the messageType parameter of their Content-Type
+// are decoded by ctx.ProtobufBody, responses carry the name and Negotiate offers protobuf
+// for values of registered types.
+//
+// Example usage:
+//
+//	context.RegisterProtobufType("shop.v1.Order", &pb.Order{})
+func RegisterProtobufType(name string, message interface{}) {
+	t := reflect.TypeOf(message)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	protobufMu.Lock()
+	defer protobufMu.Unlock()
+	protobufTypes[name] = t
+	protobufNames[t] = name
+}
+
+// protobufName returns the registered name of the message type.
+func protobufName(v interface{}) (string, bool) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	protobufMu.RLock()
+	defer protobufMu.RUnlock()
+	name, ok := protobufNames[t]
+	return name, ok
+}
+
+// isProtobufMessage reports whether v can be encoded as a protobuf message: its type is
+// registered or it has the methods of generated messages.
+func isProtobufMessage(v interface{}) bool {
+	if _, ok := protobufName(v); ok {
+		return true
+	}
+	switch v.(type) {
+	case interface{ Marshal() ([]byte, error) }, encoding.BinaryMarshaler:
+		return true
+	}
+	return false
+}
+
+// isProtobufContentType reports whether the media type of a Content-Type is protobuf and
+// returns its messageType parameter.
+func isProtobufContentType(contentType string) (messageType string, ok bool) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || (mediaType != ContentTypeProtobuf && mediaType != "application/protobuf") {
+		return "", false
+	}
+	return params["messagetype"], true
+}
+
+// Protobuf sends a protobuf message with the given status code. The Content-Type names
+// the message type when it is registered with RegisterProtobufType.
+//
+// Example usage:
+//
+//	ctx.Protobuf(http.StatusOK, &pb.Order{Id: 42})
+func (c *Context) Protobuf(status int, message interface{}) {
+	if c.responseSent {
+		log.Fatal("Response already sent")
+		return
+	}
+	c.render(status, protobufContentType(message), message, renderProtobuf)
+}
+
+// protobufContentType returns the Content-Type of the message, with the messageType
+// parameter when its type is registered.
+func protobufContentType(message interface{}) string {
+	if name, ok := protobufName(message); ok {
+		return ContentTypeProtobuf + "; messageType=" + name
+	}
+	return ContentTypeProtobuf
+}
+
+// ProtobufBody decodes the protobuf request body into a new message of the type named in
+// the messageType parameter of the Content-Type, which must be registered with
+// RegisterProtobufType.
+//
+// Example usage:
+//
+//	message, err := ctx.ProtobufBody()
+//	switch message := message.(type) {
+//	case *pb.Order:
+//		// ...
+//	}
+func (c *Context) ProtobufBody() (interface{}, error) {
+	messageType, ok := isProtobufContentType(c.Req.Header.Get("Content-Type"))
+	if !ok {
+		return nil, fmt.Errorf("content type %q is not %s", c.Req.Header.Get("Content-Type"), ContentTypeProtobuf)
+	}
+	protobufMu.RLock()
+	t, registered := protobufTypes[messageType]
+	protobufMu.RUnlock()
+	if !registered {
+		return nil, fmt.Errorf("unknown protobuf message type %q", messageType)
+	}
+	message := reflect.New(t).Interface()
+	if err := c.Body(message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// decodeProtobuf decodes a protobuf request body into v, checking the messageType
+// parameter when the type of v is registered.
+func decodeProtobuf(data []byte, v interface{}, messageType string) error {
+	if name, ok := protobufName(v); ok && messageType != "" && messageType != name {
+		return fmt.Errorf("expected protobuf message %s, got %s", name, messageType)
+	}
+	protobufMu.RLock()
+	unmarshal := protobufUnmarshal
+	protobufMu.RUnlock()
+	return unmarshal(data, v)
+}
+
+func renderProtobuf(w io.Writer, v interface{}) error {
+	protobufMu.RLock()
+	marshal := protobufMarshal
+	protobufMu.RUnlock()
+	data, err := marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func marshalProtobuf(v interface{}) ([]byte, error) {
+	switch m := v.(type) {
+	case interface{ Marshal() ([]byte, error) }:
+		return m.Marshal()
+	case encoding.BinaryMarshaler:
+		return m.MarshalBinary()
+	}
+	return nil, fmt.Errorf("%w: %T", ErrNotProtobuf, v)
+}
+
+func unmarshalProtobuf(data []byte, v interface{}) error {
+	switch m := v.(type) {
+	case interface{ Unmarshal([]byte) error }:
+		return m.Unmarshal(data)
+	case encoding.BinaryUnmarshaler:
+		return m.UnmarshalBinary(data)
+	}
+	return fmt.Errorf("%w: %T", ErrNotProtobuf, v)
+}
@@ -0,0 +1,176 @@
+package context
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"gopkg.in/yaml.v3"
+)
+
+// Content types supported out of the box.
+const (
+	ContentTypeJSON    = "application/json"
+	ContentTypeXML     = "application/xml"
+	ContentTypeYAML    = "application/yaml"
+	ContentTypeMsgPack = "application/msgpack"
+	ContentTypeHTML    = "text/html"
+	ContentTypeText    = "text/plain"
+)
+
+// Renderer encodes a value for a specific content type.
+type Renderer func(w io.Writer, v interface{}) error
+
+func init() {
+	RegisterRenderer(ContentTypeJSON, renderJSON)
+	RegisterRenderer(ContentTypeXML, renderXML)
+	RegisterRenderer(ContentTypeYAML, renderYAML)
+	RegisterRenderer(ContentTypeMsgPack, renderMsgPack)
+	RegisterRenderer("application/x-msgpack", renderMsgPack)
+	RegisterRenderer(ContentTypeText, renderText)
+}
+
+// XML sends an XML response with the given status code.
+//
+// This method sets the Content-Type to application/xml and writes the XML declaration
+// followed by the encoded value.
+//
+// Example usage:
+//
+//	ctx.XML(http.StatusOK, Order{ID: 42})
+func (c *Context) XML(status int, v interface{}) {
+	if c.responseSent {
+		log.Fatal("Response already sent")
+		return
+	}
+	c.render(status, ContentTypeXML, v, renderXML)
+}
+
+// YAML sends a YAML response with the given status code.
+//
+// Example usage:
+//
+//	ctx.YAML(http.StatusOK, map[string]string{"message": "success"})
+func (c *Context) YAML(status int, v interface{}) {
+	if c.responseSent {
+		log.Fatal("Response already sent")
+		return
+	}
+	c.render(status, ContentTypeYAML, v, renderYAML)
+}
+
+// MsgPack sends a MessagePack response with the given status code.
+//
+// Example usage:
+//
+//	ctx.MsgPack(http.StatusOK, map[string]string{"message": "success"})
+func (c *Context) MsgPack(status int, v interface{}) {
+	if c.responseSent {
+		log.Fatal("Response already sent")
+		return
+	}
+	c.render(status, ContentTypeMsgPack, v, renderMsgPack)
+}
+
+// render encodes data into a pooled buffer first, so encoding errors can still
+// produce a 500 response instead of a truncated body.
+func (c *Context) render(status int, contentType string, data interface{}, renderer Renderer) {
+	data, ok := c.prepareResponse(data)
+	if !ok {
+		return
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	if err := renderer(buf, data); err != nil {
+		log.Printf("Failed to render %s response: %v", contentType, err)
+		http.Error(c.Res, "Internal Server Error", http.StatusInternalServerError)
+		c.responseSent = true
+		return
+	}
+
+	if contentType == ContentTypeHTML || strings.HasPrefix(contentType, "text/") {
+		contentType += "; charset=utf-8"
+	}
+	c.Res.Header().Set("Content-Type", contentType)
+	c.Res.WriteHeader(status)
+	c.Res.Write(buf.Bytes())
+	c.responseSent = true
+	c.flush()
+}
+
+// flush sends buffered data to the client when the writer supports it.
+func (c *Context) flush() {
+	if flusher, ok := c.Res.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// bufferPool reuses response buffers across renders.
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+func getBuffer() *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	// Avoid keeping very large buffers alive in the pool
+	if buf.Cap() <= 64<<10 {
+		bufferPool.Put(buf)
+	}
+}
+
+// msgpackEncoders reuses MessagePack encoders, which hold internal buffers.
+var msgpackEncoders = sync.Pool{
+	New: func() interface{} {
+		return msgpack.NewEncoder(nil)
+	},
+}
+
+func renderJSON(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+func renderXML(w io.Writer, v interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).Encode(v)
+}
+
+func renderYAML(w io.Writer, v interface{}) error {
+	enc := yaml.NewEncoder(w)
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+func renderMsgPack(w io.Writer, v interface{}) error {
+	enc := msgpackEncoders.Get().(*msgpack.Encoder)
+	defer msgpackEncoders.Put(enc)
+	// Reset clears the struct tag, so it is set again on every use
+	enc.Reset(w)
+	enc.SetCustomStructTag("json")
+	defer enc.Reset(nil)
+	return enc.Encode(v)
+}
+
+func renderText(w io.Writer, v interface{}) error {
+	_, err := fmt.Fprint(w, v)
+	return err
+}
@@ -0,0 +1,119 @@
+package context
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Done returns a channel closed when the request ends: the client disconnected or the
+// deadline of the request passed. Long-running handlers select on it to stop working.
+//
+// Example usage:
+//
+//	for _, row := range rows {
+//		select {
+//		case <-ctx.Done():
+//			return
+//		default:
+//		}
+//		report.Add(process(row))
+//	}
+func (c *Context) Done() <-chan struct{} {
+	return c.Req.Context().Done()
+}
+
+// IsAborted reports whether the request has ended, because the client went away or its
+// deadline passed. Nothing written to the response reaches the client anymore.
+//
+// Example usage:
+//
+//	report, err := buildReport(ctx.Context(), month)
+//	if ctx.IsAborted() {
+//		return
+//	}
+func (c *Context) IsAborted() bool {
+	return c.Req.Context().Err() != nil
+}
+
+// Stream writes the response in steps, flushing after each one, until step returns false or
+// the client goes away. It reports whether the stream ended normally, false when the client
+// disconnected or the request ended.
+//
+// Example usage:
+//
+//	ctx.SetHeader("Content-Type", "application/x-ndjson")
+//	rows := export.Rows(ctx.Context())
+//	ctx.Stream(func(w io.Writer) bool {
+//		row, ok := <-rows
+//		if ok {
+//			json.NewEncoder(w).Encode(row)
+//		}
+//		return ok
+//	})
+func (c *Context) Stream(step func(w io.Writer) bool) bool {
+	c.responseSent = true
+	for {
+		if c.IsAborted() {
+			return false
+		}
+		keepOpen := step(c.Res)
+		c.flush()
+		if !keepOpen {
+			return !c.IsAborted()
+		}
+	}
+}
+
+// SSEvent sends a server-sent event named event, with data encoded as JSON unless it is a
+// string, and flushes it. The event stream headers are sent with the first event. It returns
+// the error of the request context once the client went away, so loops end with it.
+//
+// Example usage:
+//
+//	for {
+//		select {
+//		case <-ctx.Done():
+//			return
+//		case job := <-updates:
+//			if err := ctx.SSEvent("progress", job); err != nil {
+//				return
+//			}
+//		}
+//	}
+func (c *Context) SSEvent(event string, data interface{}) error {
+	if err := c.Req.Context().Err(); err != nil {
+		return err
+	}
+	payload, ok := data.(string)
+	if !ok {
+		encoded, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		payload = string(encoded)
+	}
+	if !c.responseSent {
+		header := c.Res.Header()
+		header.Set("Content-Type", "text/event-stream")
+		header.Set("Cache-Control", "no-cache")
+		header.Set("X-Accel-Buffering", "no")
+		c.Res.WriteHeader(http.StatusOK)
+		c.responseSent = true
+	}
+	var b strings.Builder
+	if event = strings.NewReplacer("\r", "", "\n", "").Replace(event); event != "" {
+		fmt.Fprintf(&b, "event: %s\n", event)
+	}
+	for _, line := range strings.Split(payload, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+	if _, err := io.WriteString(c.Res, b.String()); err != nil {
+		return err
+	}
+	c.flush()
+	return nil
+}
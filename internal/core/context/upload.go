@@ -0,0 +1,61 @@
+package context
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/hokamsingh/lessgo/internal/core/storage"
+)
+
+// ErrNoStorage is returned by the upload helpers when no storage was configured with
+// WithStorage.
+var ErrNoStorage = errors.New("no storage configured")
+
+// Storage returns the storage configured with WithStorage.
+//
+// Example usage:
+//
+//	store, ok := ctx.Storage()
+//	if ok {
+//		url, err := store.SignedURL(ctx.Req.Context(), key, 15*time.Minute)
+//	}
+func (c *Context) Storage() (storage.Storage, bool) {
+	return storage.FromContext(c.Req.Context())
+}
+
+// SaveUpload saves the file uploaded in the form field to the storage, under prefix followed
+// by a random name keeping the extension of the file, and returns its key.
+//
+// Example usage:
+//
+//	key, err := ctx.SaveUpload("avatar", "avatars/")
+//	if err != nil {
+//		ctx.Error(http.StatusBadRequest, err.Error())
+//		return
+//	}
+func (c *Context) SaveUpload(field, prefix string) (string, error) {
+	store, ok := c.Storage()
+	if !ok {
+		return "", ErrNoStorage
+	}
+	if err := c.Req.ParseMultipartForm(defaultMaxMultipartMemory); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		return "", fmt.Errorf("unable to parse multipart form: %w", err)
+	}
+	file, header, err := c.Req.FormFile(field)
+	if err != nil {
+		return "", fmt.Errorf("unable to get file %s: %w", field, err)
+	}
+	defer file.Close()
+
+	ext := strings.ToLower(filepath.Ext(header.Filename))
+	key := path.Join(prefix, uuid.New().String()+ext)
+	if err := store.Put(c.Req.Context(), key, file, header.Header.Get("Content-Type")); err != nil {
+		return "", err
+	}
+	return key, nil
+}
@@ -0,0 +1,160 @@
+package context
+
+import (
+	"fmt"
+
+	"github.com/hokamsingh/lessgo/internal/core/analytics"
+	"github.com/hokamsingh/lessgo/internal/core/enrich"
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+)
+
+// Claims holds the claims of an authenticated token, e.g. a decoded JWT payload.
+type Claims = middleware.Claims
+
+// Set stores a value for the rest of the request, where middlewares and the handler can
+// read it with Get. Plain http.Handler middlewares use middleware.SetValue and
+// middleware.GetValue on the same store.
+//
+// Example usage:
+//
+//	ctx.Set("tenant", tenant)
+func (c *Context) Set(key string, value interface{}) {
+	var values *middleware.Values
+	c.Req, values = middleware.RequestValues(c.Req)
+	values.Set(key, value)
+}
+
+// Get returns a value stored for the request with Set.
+//
+// Example usage:
+//
+//	if tenant, ok := ctx.Get("tenant"); ok {
+//		// ...
+//	}
+func (c *Context) Get(key string) (interface{}, bool) {
+	return middleware.GetValue(c.Req, key)
+}
+
+// MustGet returns a value stored for the request and panics when it is missing, for values
+// a middleware guarantees to set.
+func (c *Context) MustGet(key string) interface{} {
+	value, ok := c.Get(key)
+	if !ok {
+		panic(fmt.Sprintf("context: no value for key %q", key))
+	}
+	return value
+}
+
+// GetAs returns a value stored for the request if it has type T.
+//
+// Example usage:
+//
+//	tenant, ok := context.GetAs[*Tenant](ctx, "tenant")
+func GetAs[T any](c *Context, key string) (T, bool) {
+	value, _ := c.Get(key)
+	typed, ok := value.(T)
+	return typed, ok
+}
+
+// GetString returns a value stored for the request as a string, or "" when it is missing
+// or not a string.
+func (c *Context) GetString(key string) string {
+	s, _ := GetAs[string](c, key)
+	return s
+}
+
+// SetClaims stores the claims of the request's token, e.g. from a JWT middleware.
+func (c *Context) SetClaims(claims Claims) {
+	c.Set(middleware.ClaimsKey, claims)
+}
+
+// Claims returns the claims stored with SetClaims, or under middleware.ClaimsKey as a
+// map[string]interface{} by a plain middleware.
+func (c *Context) Claims() (Claims, bool) {
+	switch claims := c.valueOf(middleware.ClaimsKey).(type) {
+	case Claims:
+		return claims, true
+	case map[string]interface{}:
+		return Claims(claims), true
+	}
+	return nil, false
+}
+
+// SetSession stores the session of the request.
+func (c *Context) SetSession(session interface{}) {
+	c.Set(middleware.SessionKey, session)
+}
+
+// Session returns the session stored with SetSession.
+func (c *Context) Session() (interface{}, bool) {
+	return c.Get(middleware.SessionKey)
+}
+
+// SetUser stores the user of the request, e.g. loaded by an authentication middleware.
+func (c *Context) SetUser(user interface{}) {
+	c.Set(middleware.UserKey, user)
+}
+
+// User returns the user stored with SetUser, falling back to the principal set by the
+// trusted header auth.
+func (c *Context) User() (interface{}, bool) {
+	if user, ok := c.Get(middleware.UserKey); ok {
+		return user, true
+	}
+	if principal, ok := c.Principal(); ok {
+		return principal, true
+	}
+	return nil, false
+}
+
+// Tenant returns the ID of the tenant resolved by the tenancy middleware, or "".
+//
+// Example usage:
+//
+//	orders, err := repo.List(ctx.Tenant())
+func (c *Context) Tenant() string {
+	return middleware.RequestTenant(c.Req)
+}
+
+// UserAgentInfo returns the browser, operating system and device class of the client,
+// parsed by the enrichment middleware or from the User-Agent header.
+//
+// Example usage:
+//
+//	if ctx.UserAgentInfo().Device == enrich.DeviceMobile {
+//		ctx.Redirect(http.StatusFound, "/m"+ctx.Req.URL.Path)
+//	}
+func (c *Context) UserAgentInfo() enrich.UserAgent {
+	return enrich.RequestUserAgent(c.Req)
+}
+
+// Geo returns the location of the client found by the enrichment middleware.
+//
+// Example usage:
+//
+//	if geo, ok := ctx.Geo(); ok {
+//		currency = currencies[geo.Country]
+//	}
+func (c *Context) Geo() (enrich.GeoLocation, bool) {
+	return enrich.RequestGeo(c.Req)
+}
+
+// Track records an analytics event of the current user with the tracker of WithAnalytics,
+// or analytics.SetDefault. It reports whether the event was kept, false when it was
+// sampled out or no tracker is configured.
+//
+// Example usage:
+//
+//	ctx.Track("checkout_completed", map[string]interface{}{"order": order.ID, "total": order.Total})
+func (c *Context) Track(name string, properties map[string]interface{}) bool {
+	tracker := analytics.RequestTracker(c.Req)
+	if tracker == nil {
+		return false
+	}
+	return tracker.TrackRequest(c.Req, name, properties)
+}
+
+func (c *Context) valueOf(key string) interface{} {
+	value, _ := c.Get(key)
+	return value
+}
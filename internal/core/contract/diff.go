@@ -0,0 +1,223 @@
+package contract
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Change is a difference between two recordings of a route.
+type Change struct {
+	Method   string `json:"method"`
+	Path     string `json:"path"`
+	Breaking bool   `json:"breaking"`
+	Message  string `json:"message"`
+}
+
+func (c Change) String() string {
+	kind := "change"
+	if c.Breaking {
+		kind = "BREAKING"
+	}
+	return fmt.Sprintf("%s %s %s: %s", kind, c.Method, c.Path, c.Message)
+}
+
+// Diff compares a recording with a baseline, e.g. the recording of the main branch, and
+// returns the changes sorted by route. Changes are breaking when clients of the baseline may
+// fail: a route or status code removed, a response field removed, retyped, made nullable or no
+// longer always present, and a request field added as required or retyped. Status codes
+// recorded on one side only are compared as removed or added, so recordings should cover the
+// same scenarios, e.g. by running the same test suite.
+//
+// Example usage:
+//
+//	baseline, _ := contract.LoadRecording("contracts/recording.json")
+//	for _, change := range contract.Diff(baseline, recorder.Recording()) {
+//		if change.Breaking {
+//			t.Error(change)
+//		}
+//	}
+func Diff(baseline, current *Recording) []Change {
+	var changes []Change
+	for _, before := range baseline.Routes {
+		after := current.Route(before.Method, before.Path)
+		if after == nil {
+			changes = append(changes, Change{before.Method, before.Path, true, "route removed"})
+			continue
+		}
+		add := func(breaking bool, format string, args ...interface{}) {
+			changes = append(changes, Change{before.Method, before.Path, breaking, fmt.Sprintf(format, args...)})
+		}
+
+		compareRequests("request body", before.Request, after.Request, func(breaking bool, message string) {
+			add(breaking, "%s", message)
+		})
+		for _, status := range sortedKeys(before.Responses) {
+			response, ok := after.Responses[status]
+			if !ok {
+				add(true, "response %s no longer returned", status)
+				continue
+			}
+			if response.ContentType != before.Responses[status].ContentType {
+				add(true, "response %s content type changed from %q to %q", status, before.Responses[status].ContentType, response.ContentType)
+				continue
+			}
+			compareSchemas("response "+status, before.Responses[status].Schema, response.Schema, func(breaking bool, message string) {
+				add(breaking, "%s", message)
+			})
+		}
+		for _, status := range sortedKeys(after.Responses) {
+			if _, ok := before.Responses[status]; !ok {
+				add(false, "response %s added", status)
+			}
+		}
+	}
+	for _, after := range current.Routes {
+		if baseline.Route(after.Method, after.Path) == nil {
+			changes = append(changes, Change{after.Method, after.Path, false, "route added"})
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].Path != changes[j].Path {
+			return changes[i].Path < changes[j].Path
+		}
+		return changes[i].Method < changes[j].Method
+	})
+	return changes
+}
+
+// Breaking returns the breaking changes.
+func Breaking(changes []Change) []Change {
+	var breaking []Change
+	for _, change := range changes {
+		if change.Breaking {
+			breaking = append(breaking, change)
+		}
+	}
+	return breaking
+}
+
+// compareRequests reports the changes of a request body. Clients of the baseline break when
+// the body now needs fields they do not send or values of another type.
+func compareRequests(at string, before, after *Schema, report func(breaking bool, message string)) {
+	switch {
+	case before == nil && after == nil:
+		return
+	case before == nil:
+		report(true, at+" now expected")
+		return
+	case after == nil:
+		report(false, at+" no longer sent")
+		return
+	}
+	if before.Type != after.Type && after.Type != "" && !(before.Type == TypeInteger && after.Type == TypeNumber) {
+		report(true, fmt.Sprintf("%s changed from %s to %s", at, typeName(before), typeName(after)))
+		return
+	}
+	switch after.Type {
+	case TypeArray:
+		if before.Items != nil && after.Items != nil {
+			compareRequests(at+"[]", before.Items, after.Items, report)
+		}
+	case TypeObject:
+		for _, name := range sortedKeys(after.Properties) {
+			field := at + "." + name
+			property, ok := before.Properties[name]
+			switch {
+			case after.requires(name) && !before.requires(name):
+				report(true, field+" now required")
+			case !ok:
+				report(false, field+" added")
+			}
+			if ok {
+				compareRequests(field, property, after.Properties[name], report)
+			}
+		}
+	}
+}
+
+// compareSchemas reports the changes of a response body from the schema clients expect to the
+// schema they now receive: clients break on values they did not expect.
+func compareSchemas(at string, expected, received *Schema, report func(breaking bool, message string)) {
+	switch {
+	case expected == nil && received == nil:
+		return
+	case expected == nil:
+		report(false, at+" added")
+		return
+	case received == nil:
+		report(true, at+" no longer JSON")
+		return
+	case received.Type == TypeNull && expected.Type != TypeNull:
+		// Only seen as null
+		if !expected.Nullable {
+			report(true, at+" may be null")
+		}
+		return
+	}
+	if expected.Type != received.Type && expected.Type != "" && !(expected.Type == TypeNumber && received.Type == TypeInteger) {
+		report(true, fmt.Sprintf("%s changed from %s to %s", at, typeName(expected), typeName(received)))
+		return
+	}
+	if received.Nullable && !expected.Nullable {
+		report(true, at+" may be null")
+	}
+	switch received.Type {
+	case TypeArray:
+		if expected.Items != nil && received.Items != nil {
+			compareSchemas(at+"[]", expected.Items, received.Items, report)
+		}
+	case TypeObject:
+		for _, name := range sortedKeys(expected.Properties) {
+			field := at + "." + name
+			property, ok := received.Properties[name]
+			switch {
+			case !ok && expected.requires(name):
+				report(true, field+" removed")
+				continue
+			case !ok:
+				report(false, field+" removed")
+				continue
+			case expected.requires(name) && !received.requires(name):
+				report(true, field+" no longer always present")
+			}
+			compareSchemas(field, expected.Properties[name], property, report)
+		}
+		for _, name := range sortedKeys(received.Properties) {
+			if _, ok := expected.Properties[name]; !ok {
+				report(false, at+"."+name+" added")
+			}
+		}
+	}
+}
+
+// typeName names the type of a schema in changes.
+func typeName(s *Schema) string {
+	if s.Type == "" {
+		return "any"
+	}
+	return s.Type
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Summary describes the changes one per line, breaking changes first.
+func Summary(changes []Change) string {
+	var b strings.Builder
+	for _, breaking := range []bool{true, false} {
+		for _, change := range changes {
+			if change.Breaking == breaking {
+				b.WriteString(change.String())
+				b.WriteString("\n")
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,509 @@
+package contract
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+	"github.com/hokamsingh/lessgo/internal/core/redact"
+)
+
+// RecorderOptions defines what the recorder captures.
+type RecorderOptions struct {
+	// MaxBodySize is the largest request or response body whose shape is recorded.
+	MaxBodySize int64
+	// SkipPaths are not recorded, e.g. health checks.
+	SkipPaths []string
+	// Redactor masks sensitive fields, headers and query parameters of the examples,
+	// redact.Default() when nil.
+	Redactor *redact.Redactor
+}
+
+// NewRecorderOptions creates RecorderOptions recording bodies up to 64 KB and skipping the
+// health check paths.
+func NewRecorderOptions() *RecorderOptions {
+	return &RecorderOptions{
+		MaxBodySize: 64 << 10,
+		SkipPaths:   []string{"/health", "/healthz", "/ready", "/readyz"},
+	}
+}
+
+// Recording holds the contract of every route seen, in the format of the files written by
+// WriteFile and compared by Diff.
+type Recording struct {
+	Routes []*Route `json:"routes"`
+}
+
+// Route is the recorded contract of a route and method.
+type Route struct {
+	Method string `json:"method"`
+	Path   string `json:"path"` // Route template, e.g. /users/{id}
+	// Query lists the query parameters seen.
+	Query []string `json:"query,omitempty"`
+	// Request is the shape of the JSON request bodies, nil without any.
+	Request *Schema `json:"request,omitempty"`
+	// Responses are the responses by status code.
+	Responses map[string]*Response `json:"responses"`
+	// Examples holds the first exchange recorded for each status code, redacted.
+	Examples []Interaction `json:"examples,omitempty"`
+}
+
+// Response is the recorded contract of the responses of a route with a status code.
+type Response struct {
+	ContentType string  `json:"content_type,omitempty"`
+	Schema      *Schema `json:"schema,omitempty"` // Nil for bodies that are not JSON
+}
+
+// Interaction is an example exchange with a route, and the unit of contracts.
+type Interaction struct {
+	Description string `json:"description,omitempty"`
+	// ProviderState names the state the application must be in, set up by VerifyOptions.States.
+	ProviderState string              `json:"provider_state,omitempty"`
+	Request       InteractionRequest  `json:"request"`
+	Response      InteractionResponse `json:"response"`
+}
+
+// InteractionRequest is the request of an interaction.
+type InteractionRequest struct {
+	Method  string            `json:"method"`
+	Path    string            `json:"path"` // Path and query sent
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// InteractionResponse is the response expected in an interaction. Body is an example; the
+// response matches when it has the status, headers and the shape of Schema.
+type InteractionResponse struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+	Schema  *Schema           `json:"schema,omitempty"`
+}
+
+// Recorder is the middleware recording the contracts of the routes. It is meant for
+// development and test runs: it buffers bodies and is not tuned for production traffic.
+type Recorder struct {
+	options RecorderOptions
+	skip    map[string]bool
+
+	mu     sync.Mutex
+	routes map[string]*Route
+}
+
+// NewRecorder creates the recording middleware.
+//
+// Example usage:
+//
+//	if cfg.IsDevelopment() {
+//		recorder := contract.NewRecorder(*contract.NewRecorderOptions())
+//		App.Use(recorder)
+//	}
+func NewRecorder(options RecorderOptions) *Recorder {
+	if options.MaxBodySize <= 0 {
+		options.MaxBodySize = 64 << 10
+	}
+	if options.Redactor == nil {
+		options.Redactor = redact.Default()
+	}
+	skip := make(map[string]bool, len(options.SkipPaths))
+	for _, path := range options.SkipPaths {
+		skip[path] = true
+	}
+	return &Recorder{options: options, skip: skip, routes: map[string]*Route{}}
+}
+
+func (rec *Recorder) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rec.skip[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		// The router stores the matched route in the value store
+		r, _ = middleware.RequestValues(r)
+		var requestBody []byte
+		if isJSON(r.Header.Get("Content-Type")) && r.Body != nil && r.Body != http.NoBody {
+			requestBody = rec.peekBody(r)
+		}
+		captured := &capturedResponse{ResponseWriter: w, status: http.StatusOK, limit: rec.options.MaxBodySize}
+		next.ServeHTTP(captured, r)
+
+		template, _ := middleware.RequestRoute(r)
+		if template == "" {
+			return
+		}
+		rec.record(r, template, requestBody, captured)
+	})
+}
+
+// peekBody reads the body up to the size limit and puts it back for the handler. Larger
+// bodies are not recorded.
+func (rec *Recorder) peekBody(r *http.Request) []byte {
+	data, err := io.ReadAll(io.LimitReader(r.Body, rec.options.MaxBodySize+1))
+	r.Body = readCloser{io.MultiReader(bytes.NewReader(data), r.Body), r.Body}
+	if err != nil || int64(len(data)) > rec.options.MaxBodySize {
+		return nil
+	}
+	return data
+}
+
+// record merges the exchange into the contract of its route.
+func (rec *Recorder) record(r *http.Request, template string, requestBody []byte, response *capturedResponse) {
+	contentType := response.Header().Get("Content-Type")
+	var responseSchema *Schema
+	if isJSON(contentType) && !response.truncated {
+		responseSchema = Infer(response.body.Bytes())
+	}
+	status := strconv.Itoa(response.status)
+	key := r.Method + " " + template
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	route := rec.routes[key]
+	if route == nil {
+		route = &Route{Method: r.Method, Path: template, Responses: map[string]*Response{}}
+		rec.routes[key] = route
+	}
+	for name := range r.URL.Query() {
+		if !contains(route.Query, name) {
+			route.Query = append(route.Query, name)
+			sort.Strings(route.Query)
+		}
+	}
+	if requestBody != nil {
+		route.Request = Merge(route.Request, Infer(requestBody))
+	}
+	recorded, seen := route.Responses[status]
+	if !seen {
+		recorded = &Response{ContentType: mediaType(contentType)}
+		route.Responses[status] = recorded
+	}
+	recorded.Schema = Merge(recorded.Schema, responseSchema)
+	if seen {
+		return
+	}
+
+	example := Interaction{
+		Description: key + " " + status,
+		Request: InteractionRequest{
+			Method: r.Method,
+			Path:   r.URL.Path,
+		},
+		Response: InteractionResponse{Status: response.status, Schema: responseSchema},
+	}
+	if r.URL.RawQuery != "" {
+		example.Request.Path += "?" + rec.options.Redactor.Query(r.URL.RawQuery)
+	}
+	if requestBody != nil {
+		example.Request.Headers = map[string]string{"Content-Type": r.Header.Get("Content-Type")}
+		example.Request.Body = rec.redactJSON(requestBody)
+	}
+	if contentType != "" {
+		example.Response.Headers = map[string]string{"Content-Type": mediaType(contentType)}
+	}
+	if responseSchema != nil {
+		example.Response.Body = rec.redactJSON(response.body.Bytes())
+	}
+	route.Examples = append(route.Examples, example)
+}
+
+func (rec *Recorder) redactJSON(data []byte) json.RawMessage {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, rec.options.Redactor.JSON(data)); err != nil {
+		return nil
+	}
+	return compact.Bytes()
+}
+
+// Recording returns a copy of the contracts recorded so far, sorted by path and method.
+func (rec *Recorder) Recording() *Recording {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	recording := &Recording{Routes: make([]*Route, 0, len(rec.routes))}
+	for _, route := range rec.routes {
+		copied := *route
+		copied.Query = append([]string(nil), route.Query...)
+		copied.Request = route.Request.clone()
+		copied.Responses = make(map[string]*Response, len(route.Responses))
+		for status, response := range route.Responses {
+			copied.Responses[status] = &Response{ContentType: response.ContentType, Schema: response.Schema.clone()}
+		}
+		copied.Examples = append([]Interaction(nil), route.Examples...)
+		recording.Routes = append(recording.Routes, &copied)
+	}
+	recording.sort()
+	return recording
+}
+
+// Reset drops the recorded contracts.
+func (rec *Recorder) Reset() {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.routes = map[string]*Route{}
+}
+
+// ServeHTTP serves the recording as JSON, or as an OpenAPI document with ?format=openapi and
+// as a contract with ?format=contract. Router.ExposeContractRecorder serves it on the admin
+// router.
+func (rec *Recorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	recording := rec.Recording()
+	var document interface{} = recording
+	switch r.URL.Query().Get("format") {
+	case "openapi":
+		document = recording.OpenAPI(r.URL.Query().Get("title"), r.URL.Query().Get("version"))
+	case "contract":
+		document = recording.Contract(r.URL.Query().Get("consumer"))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(document)
+}
+
+func (r *Recording) sort() {
+	sort.Slice(r.Routes, func(i, j int) bool {
+		if r.Routes[i].Path != r.Routes[j].Path {
+			return r.Routes[i].Path < r.Routes[j].Path
+		}
+		return r.Routes[i].Method < r.Routes[j].Method
+	})
+	for _, route := range r.Routes {
+		sort.Slice(route.Examples, func(i, j int) bool {
+			return route.Examples[i].Response.Status < route.Examples[j].Response.Status
+		})
+	}
+}
+
+// Route returns the contract of the route and method, nil when not recorded.
+func (r *Recording) Route(method, path string) *Route {
+	for _, route := range r.Routes {
+		if route.Method == method && route.Path == path {
+			return route
+		}
+	}
+	return nil
+}
+
+// WriteFile writes the recording as indented JSON, creating the directory.
+func (r *Recording) WriteFile(path string) error {
+	return writeJSON(path, r)
+}
+
+// LoadRecording reads a recording written by WriteFile.
+func LoadRecording(path string) (*Recording, error) {
+	recording := &Recording{}
+	if err := readJSON(path, recording); err != nil {
+		return nil, err
+	}
+	return recording, nil
+}
+
+// Contract is a set of interactions a consumer expects from the application.
+type Contract struct {
+	Consumer     string        `json:"consumer,omitempty"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Contract returns the examples of the recording as a contract of the consumer, to be
+// stored as a fixture and verified against later versions of the application.
+func (r *Recording) Contract(consumer string) *Contract {
+	contract := &Contract{Consumer: consumer, Interactions: []Interaction{}}
+	for _, route := range r.Routes {
+		contract.Interactions = append(contract.Interactions, route.Examples...)
+	}
+	return contract
+}
+
+// WriteFile writes the contract as indented JSON, creating the directory.
+func (c *Contract) WriteFile(path string) error {
+	return writeJSON(path, c)
+}
+
+// LoadContract reads a contract written by Contract.WriteFile.
+func LoadContract(path string) (*Contract, error) {
+	contract := &Contract{}
+	if err := readJSON(path, contract); err != nil {
+		return nil, err
+	}
+	return contract, nil
+}
+
+// pathParameter matches the variables of route templates, with their optional pattern.
+var pathParameter = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+
+// OpenAPI returns an OpenAPI 3.0 document describing the recorded routes, with their
+// request and response schemas and the recorded examples.
+//
+// Example usage:
+//
+//	data, _ := json.MarshalIndent(recorder.Recording().OpenAPI("Shop API", "1.4.0"), "", "  ")
+func (r *Recording) OpenAPI(title, version string) map[string]interface{} {
+	if title == "" {
+		title = "API"
+	}
+	if version == "" {
+		version = "0.0.0"
+	}
+	paths := map[string]map[string]interface{}{}
+	for _, route := range r.Routes {
+		path := pathParameter.ReplaceAllString(route.Path, "{$1}")
+		operation := map[string]interface{}{}
+
+		var parameters []map[string]interface{}
+		for _, match := range pathParameter.FindAllStringSubmatch(route.Path, -1) {
+			parameters = append(parameters, map[string]interface{}{
+				"name": match[1], "in": "path", "required": true, "schema": map[string]string{"type": TypeString},
+			})
+		}
+		for _, name := range route.Query {
+			parameters = append(parameters, map[string]interface{}{
+				"name": name, "in": "query", "schema": map[string]string{"type": TypeString},
+			})
+		}
+		if len(parameters) > 0 {
+			operation["parameters"] = parameters
+		}
+
+		examples := map[int]Interaction{}
+		for _, example := range route.Examples {
+			examples[example.Response.Status] = example
+		}
+		if route.Request != nil {
+			content := map[string]interface{}{"schema": route.Request}
+			for _, example := range route.Examples {
+				if len(example.Request.Body) > 0 {
+					content["example"] = example.Request.Body
+					break
+				}
+			}
+			operation["requestBody"] = map[string]interface{}{
+				"content": map[string]interface{}{"application/json": content},
+			}
+		}
+
+		responses := map[string]interface{}{}
+		for status, response := range route.Responses {
+			code, _ := strconv.Atoi(status)
+			described := map[string]interface{}{"description": http.StatusText(code)}
+			if response.ContentType != "" {
+				content := map[string]interface{}{}
+				if response.Schema != nil {
+					content["schema"] = response.Schema
+				}
+				if example, ok := examples[code]; ok && len(example.Response.Body) > 0 {
+					content["example"] = example.Response.Body
+				}
+				described["content"] = map[string]interface{}{response.ContentType: content}
+			}
+			responses[status] = described
+		}
+		operation["responses"] = responses
+
+		if paths[path] == nil {
+			paths[path] = map[string]interface{}{}
+		}
+		paths[path][strings.ToLower(route.Method)] = operation
+	}
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info":    map[string]string{"title": title, "version": version},
+		"paths":   paths,
+	}
+}
+
+// capturedResponse keeps a copy of the status, headers and body of a response while writing it.
+type capturedResponse struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+	limit       int64
+	truncated   bool
+}
+
+func (c *capturedResponse) WriteHeader(status int) {
+	if !c.wroteHeader && status >= http.StatusOK {
+		c.status = status
+		c.wroteHeader = true
+	}
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *capturedResponse) Write(p []byte) (int, error) {
+	c.wroteHeader = true
+	if !c.truncated {
+		if int64(c.body.Len()+len(p)) > c.limit {
+			c.truncated = true
+			c.body.Reset()
+		} else {
+			c.body.Write(p)
+		}
+	}
+	return c.ResponseWriter.Write(p)
+}
+
+func (c *capturedResponse) Flush() {
+	if flusher, ok := c.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (c *capturedResponse) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
+// readCloser reads the peeked bytes and the rest of a body, closing the original body.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+func isJSON(contentType string) bool {
+	media := mediaType(contentType)
+	return media == "application/json" || strings.HasSuffix(media, "+json")
+}
+
+func mediaType(contentType string) string {
+	media, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return contentType
+	}
+	return media
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func writeJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+func readJSON(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,170 @@
+/*
+Package contract records the shapes of the requests and responses of every route while an
+application runs in development or under its tests, and turns them into OpenAPI examples and
+contract fixtures. Comparing a recording with a baseline reports the changes that would break
+API clients, e.g. in CI, and Verify replays the contracts of consumers against the application
+in-process.
+
+Usage:
+
+	recorder := contract.NewRecorder(*contract.NewRecorderOptions())
+	App.Use(recorder)
+	...
+	err := recorder.Recording().WriteFile("contracts/recording.json")
+	changes := contract.Diff(baseline, recorder.Recording())
+	...
+	err = contract.Verify(App.Handler(), consumerContract, *contract.NewVerifyOptions()).Err()
+*/
+package contract
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+)
+
+// JSON types of schemas, as named by JSON Schema and OpenAPI.
+const (
+	TypeObject  = "object"
+	TypeArray   = "array"
+	TypeString  = "string"
+	TypeInteger = "integer"
+	TypeNumber  = "number"
+	TypeBoolean = "boolean"
+	TypeNull    = "null"
+)
+
+// Schema is the shape of a JSON value inferred from samples, in the subset of JSON Schema
+// understood by OpenAPI 3.0. An empty Type accepts any value, for values seen with different
+// types.
+type Schema struct {
+	Type       string             `json:"type,omitempty"`
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	// Required lists the properties present in every sample.
+	Required []string `json:"required,omitempty"`
+	Items    *Schema  `json:"items,omitempty"`
+	Nullable bool     `json:"nullable,omitempty"`
+}
+
+// Infer returns the schema of a JSON document, nil when data is not JSON.
+//
+// Example usage:
+//
+//	schema := contract.Infer([]byte(`{"id": 1, "tags": ["a"]}`))
+//	// {"type":"object","properties":{"id":{"type":"integer"},"tags":{"type":"array","items":{"type":"string"}}},"required":["id","tags"]}
+func Infer(data []byte) *Schema {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil || decoder.More() {
+		return nil
+	}
+	return inferValue(value)
+}
+
+func inferValue(value interface{}) *Schema {
+	switch value := value.(type) {
+	case nil:
+		return &Schema{Type: TypeNull}
+	case bool:
+		return &Schema{Type: TypeBoolean}
+	case string:
+		return &Schema{Type: TypeString}
+	case json.Number:
+		if _, err := value.Int64(); err == nil {
+			return &Schema{Type: TypeInteger}
+		}
+		return &Schema{Type: TypeNumber}
+	case []interface{}:
+		s := &Schema{Type: TypeArray}
+		for _, item := range value {
+			s.Items = Merge(s.Items, inferValue(item))
+		}
+		return s
+	case map[string]interface{}:
+		s := &Schema{Type: TypeObject, Properties: make(map[string]*Schema, len(value))}
+		for name, property := range value {
+			s.Properties[name] = inferValue(property)
+			s.Required = append(s.Required, name)
+		}
+		sort.Strings(s.Required)
+		return s
+	}
+	return &Schema{}
+}
+
+// Merge returns the schema accepting the samples of both schemas: properties missing from
+// either are no longer required, null makes a schema nullable, integers widen to numbers and
+// other differing types accept any value. Either schema may be nil.
+func Merge(a, b *Schema) *Schema {
+	switch {
+	case a == nil:
+		return b.clone()
+	case b == nil:
+		return a.clone()
+	case a.Type == TypeNull && b.Type != TypeNull:
+		merged := b.clone()
+		merged.Nullable = true
+		return merged
+	case b.Type == TypeNull && a.Type != TypeNull:
+		merged := a.clone()
+		merged.Nullable = true
+		return merged
+	}
+	nullable := a.Nullable || b.Nullable
+	switch {
+	case a.Type == b.Type:
+	case a.Type == TypeInteger && b.Type == TypeNumber, a.Type == TypeNumber && b.Type == TypeInteger:
+		return &Schema{Type: TypeNumber, Nullable: nullable}
+	default:
+		return &Schema{Nullable: nullable}
+	}
+
+	merged := &Schema{Type: a.Type, Nullable: nullable}
+	switch a.Type {
+	case TypeArray:
+		merged.Items = Merge(a.Items, b.Items)
+	case TypeObject:
+		merged.Properties = make(map[string]*Schema, len(a.Properties))
+		for name, property := range a.Properties {
+			merged.Properties[name] = Merge(property, b.Properties[name])
+		}
+		for name, property := range b.Properties {
+			if _, ok := a.Properties[name]; !ok {
+				merged.Properties[name] = property.clone()
+			}
+		}
+		for _, name := range a.Required {
+			if b.requires(name) {
+				merged.Required = append(merged.Required, name)
+			}
+		}
+	}
+	return merged
+}
+
+// requires reports whether the property is required.
+func (s *Schema) requires(name string) bool {
+	for _, required := range s.Required {
+		if required == name {
+			return true
+		}
+	}
+	return false
+}
+
+// clone returns a deep copy of the schema.
+func (s *Schema) clone() *Schema {
+	if s == nil {
+		return nil
+	}
+	c := &Schema{Type: s.Type, Nullable: s.Nullable, Items: s.Items.clone()}
+	if s.Properties != nil {
+		c.Properties = make(map[string]*Schema, len(s.Properties))
+		for name, property := range s.Properties {
+			c.Properties[name] = property.clone()
+		}
+	}
+	c.Required = append([]string(nil), s.Required...)
+	return c
+}
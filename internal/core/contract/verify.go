@@ -0,0 +1,275 @@
+package contract
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// VerifyOptions defines how contracts are replayed against the application.
+type VerifyOptions struct {
+	// States set up the provider states named by interactions, e.g. by seeding a user. An
+	// interaction whose state is not listed fails.
+	States map[string]func() error
+	// Prepare changes every request before it is sent, e.g. to add credentials or replace
+	// values redacted when the contract was recorded.
+	Prepare func(r *http.Request, interaction Interaction)
+}
+
+// NewVerifyOptions creates VerifyOptions without provider states.
+func NewVerifyOptions() *VerifyOptions {
+	return &VerifyOptions{States: map[string]func() error{}}
+}
+
+// Verification is the outcome of replaying a contract.
+type Verification struct {
+	Consumer string              `json:"consumer,omitempty"`
+	Results  []InteractionResult `json:"results"`
+}
+
+// InteractionResult is the outcome of replaying an interaction. It passed without mismatches.
+type InteractionResult struct {
+	Description string   `json:"description"`
+	Method      string   `json:"method"`
+	Path        string   `json:"path"`
+	Status      int      `json:"status"` // Status returned by the application
+	Mismatches  []string `json:"mismatches,omitempty"`
+}
+
+// Verify replays the interactions of the contract against the handler in-process, e.g. the
+// router of the application, and reports how every response differs from the expected one.
+// A response matches when it has the expected status, headers and the shape of the expected
+// body: its schema, or the schema inferred from the example body. Fields not in the contract
+// are allowed, so providers can add fields without breaking consumers.
+//
+// Example usage:
+//
+//	c, _ := contract.LoadContract("contracts/web.json")
+//	options := contract.NewVerifyOptions()
+//	options.States["user 1 exists"] = func() error { return seedUser(db, 1) }
+//	if err := contract.Verify(App.Handler(), c, *options).Err(); err != nil {
+//		t.Fatal(err)
+//	}
+func Verify(handler http.Handler, c *Contract, options VerifyOptions) *Verification {
+	verification := &Verification{Consumer: c.Consumer, Results: make([]InteractionResult, 0, len(c.Interactions))}
+	for _, interaction := range c.Interactions {
+		verification.Results = append(verification.Results, verifyInteraction(handler, interaction, options))
+	}
+	return verification
+}
+
+// VerifyFiles verifies the contracts of the files matching the pattern, e.g. contracts/*.json,
+// one verification per file.
+func VerifyFiles(handler http.Handler, pattern string, options VerifyOptions) ([]*Verification, error) {
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("contract: no contracts match %s", pattern)
+	}
+	sort.Strings(paths)
+	verifications := make([]*Verification, 0, len(paths))
+	for _, path := range paths {
+		c, err := LoadContract(path)
+		if err != nil {
+			return nil, fmt.Errorf("contract: loading %s: %w", path, err)
+		}
+		if c.Consumer == "" {
+			c.Consumer = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+		verifications = append(verifications, Verify(handler, c, options))
+	}
+	return verifications, nil
+}
+
+func verifyInteraction(handler http.Handler, interaction Interaction, options VerifyOptions) InteractionResult {
+	result := InteractionResult{
+		Description: interaction.Description,
+		Method:      interaction.Request.Method,
+		Path:        interaction.Request.Path,
+	}
+	if result.Description == "" {
+		result.Description = result.Method + " " + result.Path
+	}
+	if interaction.ProviderState != "" {
+		setUp, ok := options.States[interaction.ProviderState]
+		if !ok {
+			result.Mismatches = append(result.Mismatches, fmt.Sprintf("unknown provider state %q", interaction.ProviderState))
+			return result
+		}
+		if err := setUp(); err != nil {
+			result.Mismatches = append(result.Mismatches, fmt.Sprintf("provider state %q: %v", interaction.ProviderState, err))
+			return result
+		}
+	}
+
+	req := httptest.NewRequest(interaction.Request.Method, interaction.Request.Path, bytes.NewReader(interaction.Request.Body))
+	for name, value := range interaction.Request.Headers {
+		req.Header.Set(name, value)
+	}
+	if options.Prepare != nil {
+		options.Prepare(req, interaction)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	result.Status = w.Code
+
+	expected := interaction.Response
+	if expected.Status != 0 && w.Code != expected.Status {
+		result.Mismatches = append(result.Mismatches, fmt.Sprintf("status %d, expected %d", w.Code, expected.Status))
+	}
+	for _, name := range sortedKeys(expected.Headers) {
+		want, got := expected.Headers[name], w.Header().Get(name)
+		if http.CanonicalHeaderKey(name) == "Content-Type" {
+			got = mediaType(got)
+		}
+		if got != want {
+			result.Mismatches = append(result.Mismatches, fmt.Sprintf("header %s is %q, expected %q", name, got, want))
+		}
+	}
+	schema := expected.Schema
+	if schema == nil && len(expected.Body) > 0 {
+		schema = Infer(expected.Body)
+	}
+	if schema != nil {
+		result.Mismatches = append(result.Mismatches, schema.Match(w.Body.Bytes())...)
+	}
+	return result
+}
+
+// Passed reports whether every interaction matched.
+func (v *Verification) Passed() bool {
+	return len(v.Failed()) == 0
+}
+
+// Failed returns the results of the interactions that did not match.
+func (v *Verification) Failed() []InteractionResult {
+	var failed []InteractionResult
+	for _, result := range v.Results {
+		if len(result.Mismatches) > 0 {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// Err returns an error listing the mismatches, nil when every interaction matched.
+func (v *Verification) Err() error {
+	failed := v.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+	var b strings.Builder
+	consumer := v.Consumer
+	if consumer == "" {
+		consumer = "consumer"
+	}
+	fmt.Fprintf(&b, "contract of %s: %d of %d interactions failed", consumer, len(failed), len(v.Results))
+	for _, result := range failed {
+		fmt.Fprintf(&b, "\n  %s:", result.Description)
+		for _, mismatch := range result.Mismatches {
+			fmt.Fprintf(&b, "\n    - %s", mismatch)
+		}
+	}
+	return errors.New(b.String())
+}
+
+// TestingT is the part of testing.TB used to report verifications.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// AssertVerified verifies the contract and reports every failed interaction as a test error.
+//
+// Example usage:
+//
+//	func TestWebContract(t *testing.T) {
+//		c, _ := contract.LoadContract("contracts/web.json")
+//		contract.AssertVerified(t, App.Handler(), c, *contract.NewVerifyOptions())
+//	}
+func AssertVerified(t TestingT, handler http.Handler, c *Contract, options VerifyOptions) *Verification {
+	t.Helper()
+	verification := Verify(handler, c, options)
+	for _, result := range verification.Failed() {
+		t.Errorf("%s: %s", result.Description, strings.Join(result.Mismatches, "; "))
+	}
+	return verification
+}
+
+// Match returns how the JSON document differs from the schema, nothing when it matches.
+// Properties not in the schema are allowed.
+func (s *Schema) Match(data []byte) []string {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil || decoder.More() {
+		return []string{"body is not JSON"}
+	}
+	var mismatches []string
+	s.match("body", value, &mismatches)
+	return mismatches
+}
+
+func (s *Schema) match(at string, value interface{}, mismatches *[]string) {
+	if s == nil || s.Type == "" {
+		return
+	}
+	if value == nil {
+		if !s.Nullable && s.Type != TypeNull {
+			*mismatches = append(*mismatches, fmt.Sprintf("%s is null, expected %s", at, s.Type))
+		}
+		return
+	}
+	got := jsonType(value)
+	switch {
+	case got == s.Type, got == TypeInteger && s.Type == TypeNumber:
+	default:
+		*mismatches = append(*mismatches, fmt.Sprintf("%s is %s, expected %s", at, got, s.Type))
+		return
+	}
+	switch value := value.(type) {
+	case []interface{}:
+		for i, item := range value {
+			s.Items.match(fmt.Sprintf("%s[%d]", at, i), item, mismatches)
+		}
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := value[name]; !ok {
+				*mismatches = append(*mismatches, fmt.Sprintf("%s.%s is missing", at, name))
+			}
+		}
+		for _, name := range sortedKeys(s.Properties) {
+			if property, ok := value[name]; ok {
+				s.Properties[name].match(at+"."+name, property, mismatches)
+			}
+		}
+	}
+}
+
+// jsonType returns the type of a decoded JSON value.
+func jsonType(value interface{}) string {
+	switch value := value.(type) {
+	case bool:
+		return TypeBoolean
+	case string:
+		return TypeString
+	case json.Number:
+		if _, err := value.Int64(); err == nil {
+			return TypeInteger
+		}
+		return TypeNumber
+	case []interface{}:
+		return TypeArray
+	case map[string]interface{}:
+		return TypeObject
+	}
+	return TypeNull
+}
@@ -8,7 +8,12 @@ package controller
 
 import (
 	"fmt"
+	"reflect"
+	"strings"
+	"sync"
 
+	"github.com/hokamsingh/lessgo/internal/core/context"
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
 	"github.com/hokamsingh/lessgo/internal/core/module"
 	"github.com/hokamsingh/lessgo/internal/core/router"
 )
@@ -55,14 +60,147 @@ func (bc *BaseController) RegisterRoutes(r *router.Router) {
 
 }
 
+// MiddlewareProvider is implemented by controllers whose routes need middleware.
+//
+// Example
+//
+//	func (ac *AdminController) Use() []middleware.Middleware {
+//		return []middleware.Middleware{authMiddleware}
+//	}
+type MiddlewareProvider interface {
+	Use() []middleware.Middleware
+}
+
+// GuardProvider is implemented by controllers whose routes are protected by guards.
+type GuardProvider interface {
+	Guards() []router.Guard
+}
+
+// InterceptorProvider is implemented by controllers whose routes run interceptors, which are
+// pipes transforming the request before and the response data after the handler.
+type InterceptorProvider interface {
+	Interceptors() []context.Pipe
+}
+
+var (
+	registryMu   sync.RWMutex
+	middlewares  = map[string]middleware.Middleware{}
+	guards       = map[string]router.Guard{}
+	interceptors = map[string]context.Pipe{}
+)
+
+// RegisterMiddleware names a middleware so controllers can reference it in a `use` struct tag.
+func RegisterMiddleware(name string, m middleware.Middleware) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	middlewares[name] = m
+}
+
+// RegisterGuard names a guard so controllers can reference it in a `guards` struct tag.
+func RegisterGuard(name string, guard router.Guard) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	guards[name] = guard
+}
+
+// RegisterInterceptor names an interceptor so controllers can reference it in an `interceptors` struct tag.
+func RegisterInterceptor(name string, interceptor context.Pipe) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	interceptors[name] = interceptor
+}
+
 // RegisterModuleRoutes is a helper function to register routes for a module.
 // It will panic if there is an error during registration or if a controller does not implement the required interface.
+//
+// Middleware, guards and interceptors declared by a controller apply to all routes it registers.
+// They are declared with the Use, Guards and Interceptors methods, or with struct tags on the
+// embedded BaseController referencing names registered with RegisterMiddleware, RegisterGuard
+// and RegisterInterceptor:
+//
+//	type AdminController struct {
+//		LessGo.BaseController `use:"auth" guards:"admin" interceptors:"snake_case"`
+//	}
 func RegisterModuleRoutes(r *router.Router, m module.IModule) {
 	for _, ctrl := range m.GetControllers() {
 		c, ok := ctrl.(Controller)
 		if !ok {
 			panic(fmt.Sprintf("Controller %T does not implement controller.Controller interface", ctrl))
 		}
-		c.RegisterRoutes(r)
+		c.RegisterRoutes(scopeFor(r, ctrl))
+	}
+}
+
+// scopeFor returns a router applying the middleware, guards and interceptors declared by ctrl.
+func scopeFor(r *router.Router, ctrl interface{}) *router.Router {
+	mws, gs, ics := declaredByTags(ctrl)
+	if p, ok := ctrl.(MiddlewareProvider); ok {
+		mws = append(mws, p.Use()...)
+	}
+	if p, ok := ctrl.(GuardProvider); ok {
+		gs = append(gs, p.Guards()...)
+	}
+	if p, ok := ctrl.(InterceptorProvider); ok {
+		ics = append(ics, p.Interceptors()...)
+	}
+	if len(mws) == 0 && len(gs) == 0 && len(ics) == 0 {
+		return r
+	}
+	return r.With(mws...).WithGuards(gs...).WithPipes(ics...)
+}
+
+// declaredByTags resolves the names in the `use`, `guards` and `interceptors` tags of the
+// controller's embedded BaseController.
+func declaredByTags(ctrl interface{}) ([]middleware.Middleware, []router.Guard, []context.Pipe) {
+	t := reflect.TypeOf(ctrl)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, nil, nil
+	}
+
+	var mws []middleware.Middleware
+	var gs []router.Guard
+	var ics []context.Pipe
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.Anonymous || field.Type != reflect.TypeOf(BaseController{}) {
+			continue
+		}
+		for _, name := range tagNames(field.Tag.Get("use")) {
+			m, ok := middlewares[name]
+			if !ok {
+				panic(fmt.Sprintf("Controller %T uses unknown middleware %q", ctrl, name))
+			}
+			mws = append(mws, m)
+		}
+		for _, name := range tagNames(field.Tag.Get("guards")) {
+			g, ok := guards[name]
+			if !ok {
+				panic(fmt.Sprintf("Controller %T uses unknown guard %q", ctrl, name))
+			}
+			gs = append(gs, g)
+		}
+		for _, name := range tagNames(field.Tag.Get("interceptors")) {
+			ic, ok := interceptors[name]
+			if !ok {
+				panic(fmt.Sprintf("Controller %T uses unknown interceptor %q", ctrl, name))
+			}
+			ics = append(ics, ic)
+		}
+	}
+	return mws, gs, ics
+}
+
+func tagNames(tag string) []string {
+	var names []string
+	for _, name := range strings.Split(tag, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
 	}
+	return names
 }
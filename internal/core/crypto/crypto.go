@@ -0,0 +1,136 @@
+/*
+Package crypto provides authenticated encryption, HMAC signing, key derivation and a Keyring
+for key rotation, as used by cookies, CSRF tokens and request signing.
+
+A Keyring holds several keys: the first one, the primary, encrypts and signs; all of them
+decrypt and verify. Keys are rotated by adding a new primary and removing the old key once
+the data it protected expired. Ciphertexts and signatures carry the ID of their key so the
+right key is found without trying them all.
+
+Usage:
+
+	keys := crypto.KeyringFromSecrets(cfg.GetKeys("APP_KEYS")...)
+
+	sealed, err := keys.Encrypt([]byte("4111 1111 1111 1111"), []byte("card:42"))
+	plain, err := keys.Decrypt(sealed, []byte("card:42"))
+
+	signature := keys.Sign([]byte("user=42"))
+	ok := keys.Verify([]byte("user=42"), signature)
+*/
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"hash"
+)
+
+var (
+	// ErrInvalidKey is returned when an AES key is not 16, 24 or 32 bytes long.
+	ErrInvalidKey = errors.New("crypto: invalid key size")
+	// ErrDecrypt is returned when a ciphertext is malformed, modified or encrypted with an
+	// unknown key.
+	ErrDecrypt = errors.New("crypto: message authentication failed")
+)
+
+// RandomBytes returns n bytes from the system random source.
+func RandomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	switch len(key) {
+	case 16, 24, 32:
+	default:
+		return nil, ErrInvalidKey
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Encrypt encrypts and authenticates plaintext with AES-GCM under key, which must be 16, 24
+// or 32 bytes long. The additional data is authenticated but not encrypted, e.g. the name of
+// the field the value belongs to, so it cannot be moved to another one. The random nonce is
+// prepended to the result.
+func Encrypt(key, plaintext, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// Decrypt reverses Encrypt, failing with ErrDecrypt when the ciphertext or the additional
+// data was modified.
+func Decrypt(key, ciphertext, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < aead.NonceSize()+aead.Overhead() {
+		return nil, ErrDecrypt
+	}
+	nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, sealed, additionalData)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+// Sign returns the HMAC-SHA256 of data under key.
+func Sign(key, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// Verify reports in constant time whether signature is the HMAC-SHA256 of data under key.
+func Verify(key, data, signature []byte) bool {
+	return hmac.Equal(Sign(key, data), signature)
+}
+
+// HKDF derives length bytes from secret with HKDF-SHA256 (RFC 5869). The salt is optional;
+// info binds the result to its use.
+func HKDF(secret, salt, info []byte, length int) []byte {
+	if len(salt) == 0 {
+		salt = make([]byte, sha256.Size)
+	}
+	prk := Sign(salt, secret)
+
+	var (
+		out  = make([]byte, 0, length)
+		prev []byte
+		mac  hash.Hash = hmac.New(sha256.New, prk)
+	)
+	for counter := byte(1); len(out) < length; counter++ {
+		mac.Reset()
+		mac.Write(prev)
+		mac.Write(info)
+		mac.Write([]byte{counter})
+		prev = mac.Sum(nil)
+		out = append(out, prev...)
+	}
+	return out[:length]
+}
+
+// DeriveKey derives a 32 byte key for purpose from secret, so a single configured secret of
+// any length provides independent keys for encryption, signing and so on.
+func DeriveKey(secret []byte, purpose string) []byte {
+	return HKDF(secret, nil, []byte("lessgo:"+purpose), 32)
+}
@@ -0,0 +1,158 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+)
+
+// ErrNoKeys is returned when a Keyring without keys is used to encrypt or sign.
+var ErrNoKeys = errors.New("crypto: keyring has no keys")
+
+// Key is a secret identified by an ID, which is embedded in what the key protects.
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+// Keyring holds the keys in use, newest first. It is safe for concurrent use.
+type Keyring struct {
+	mu   sync.RWMutex
+	keys []Key
+}
+
+// NewKeyring creates a keyring with keys, the first one being the primary.
+func NewKeyring(keys ...Key) *Keyring {
+	return &Keyring{keys: append([]Key(nil), keys...)}
+}
+
+// KeyringFromSecrets creates a keyring from secrets, newest first, such as
+// config.GetKeys returns. Key IDs are derived from the secrets.
+func KeyringFromSecrets(secrets ...[]byte) *Keyring {
+	keys := make([]Key, 0, len(secrets))
+	for _, secret := range secrets {
+		sum := sha256.Sum256(secret)
+		keys = append(keys, Key{ID: hex.EncodeToString(sum[:4]), Secret: secret})
+	}
+	return NewKeyring(keys...)
+}
+
+// Primary returns the key used to encrypt and sign.
+func (k *Keyring) Primary() (Key, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if len(k.keys) == 0 {
+		return Key{}, false
+	}
+	return k.keys[0], true
+}
+
+// Get returns the key with the given ID.
+func (k *Keyring) Get(id string) (Key, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	for _, key := range k.keys {
+		if key.ID == id {
+			return key, true
+		}
+	}
+	return Key{}, false
+}
+
+// Keys returns the keys, newest first.
+func (k *Keyring) Keys() []Key {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return append([]Key(nil), k.keys...)
+}
+
+// Len returns the number of keys.
+func (k *Keyring) Len() int {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return len(k.keys)
+}
+
+// Rotate makes key the primary. Data protected by the previous keys stays readable until they
+// are removed.
+func (k *Keyring) Rotate(key Key) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	keys := []Key{key}
+	for _, existing := range k.keys {
+		if existing.ID != key.ID {
+			keys = append(keys, existing)
+		}
+	}
+	k.keys = keys
+}
+
+// Remove drops the key with the given ID.
+func (k *Keyring) Remove(id string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	keys := k.keys[:0:0]
+	for _, key := range k.keys {
+		if key.ID != id {
+			keys = append(keys, key)
+		}
+	}
+	k.keys = keys
+}
+
+// withID prefixes data with the length and the ID of key.
+func withID(key Key, data []byte) []byte {
+	out := make([]byte, 0, 1+len(key.ID)+len(data))
+	out = append(out, byte(len(key.ID)))
+	out = append(out, key.ID...)
+	return append(out, data...)
+}
+
+// splitID returns the key an output of withID was made with and the data.
+func (k *Keyring) splitID(data []byte) (Key, []byte, bool) {
+	if len(data) == 0 || len(data) < 1+int(data[0]) {
+		return Key{}, nil, false
+	}
+	key, ok := k.Get(string(data[1 : 1+data[0]]))
+	return key, data[1+data[0]:], ok
+}
+
+// Encrypt encrypts plaintext with AES-256-GCM under a key derived from the primary secret.
+// The result starts with the key ID.
+func (k *Keyring) Encrypt(plaintext, additionalData []byte) ([]byte, error) {
+	key, ok := k.Primary()
+	if !ok {
+		return nil, ErrNoKeys
+	}
+	sealed, err := Encrypt(DeriveKey(key.Secret, "encrypt"), plaintext, additionalData)
+	if err != nil {
+		return nil, err
+	}
+	return withID(key, sealed), nil
+}
+
+// Decrypt decrypts the output of Encrypt with the key it was made with.
+func (k *Keyring) Decrypt(ciphertext, additionalData []byte) ([]byte, error) {
+	key, sealed, ok := k.splitID(ciphertext)
+	if !ok {
+		return nil, ErrDecrypt
+	}
+	return Decrypt(DeriveKey(key.Secret, "encrypt"), sealed, additionalData)
+}
+
+// Sign returns the HMAC-SHA256 of data under a key derived from the primary secret, prefixed
+// with the key ID. It returns nil when the keyring is empty.
+func (k *Keyring) Sign(data []byte) []byte {
+	key, ok := k.Primary()
+	if !ok {
+		return nil
+	}
+	return withID(key, Sign(DeriveKey(key.Secret, "sign"), data))
+}
+
+// Verify reports whether signature is a signature of data made by Sign with any of the keys.
+func (k *Keyring) Verify(data, signature []byte) bool {
+	key, mac, ok := k.splitID(signature)
+	return ok && Verify(DeriveKey(key.Secret, "sign"), data, mac)
+}
@@ -0,0 +1,181 @@
+/*
+Package database holds the database of an application with the placeholder style of its
+driver, and a small query builder so services stop concatenating SQL.
+
+Values always travel as arguments, never in the query text. Table and column names cannot be
+arguments, so the builder only accepts valid identifiers for them and reports an error
+otherwise.
+
+Usage:
+
+	db, err := database.Open("postgres", cfg.Get("DATABASE_URL", ""))
+	users, err := database.All[User](ctx, db, database.Select().From("users").
+		Where("email = ?", email).OrderBy("created_at DESC").Limit(20))
+*/
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Dialect is the placeholder style of a driver.
+type Dialect int
+
+const (
+	Question Dialect = iota // ?, MySQL and SQLite
+	Dollar                  // $1, PostgreSQL
+	AtP                     // @p1, SQL Server
+	Colon                   // :1, Oracle
+)
+
+// driverDialects are the dialects of common drivers not using ? placeholders.
+var driverDialects = map[string]Dialect{
+	"postgres":         Dollar,
+	"pgx":              Dollar,
+	"pq":               Dollar,
+	"cloudsqlpostgres": Dollar,
+	"sqlserver":        AtP,
+	"mssql":            AtP,
+	"oracle":           Colon,
+	"godror":           Colon,
+}
+
+// DialectOf returns the dialect of the database/sql driver registered under name, ? for
+// unknown drivers.
+func DialectOf(driver string) Dialect {
+	return driverDialects[strings.ToLower(driver)]
+}
+
+// Placeholder returns the placeholder of the nth argument, starting at 1.
+func (d Dialect) Placeholder(n int) string {
+	switch d {
+	case Dollar:
+		return "$" + strconv.Itoa(n)
+	case AtP:
+		return "@p" + strconv.Itoa(n)
+	case Colon:
+		return ":" + strconv.Itoa(n)
+	default:
+		return "?"
+	}
+}
+
+// Rebind turns the ? placeholders of query into the placeholders of the dialect. Question
+// marks in quoted strings and identifiers are kept.
+//
+// Example usage:
+//
+//	db.QueryContext(ctx, db.Dialect().Rebind("SELECT id FROM users WHERE email = ?"), email)
+func (d Dialect) Rebind(query string) string {
+	if d == Question {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	var quote rune
+	for _, r := range query {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '?':
+			n++
+			b.WriteString(d.Placeholder(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Runner runs queries, implemented by *DB and *Tx.
+type Runner interface {
+	Dialect() Dialect
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// DB is a database handle knowing the dialect of its driver.
+type DB struct {
+	*sql.DB
+	dialect Dialect
+}
+
+// Open opens the database with the driver registered under driver and verifies the
+// connection.
+//
+// Example usage:
+//
+//	db, err := database.Open("mysql", cfg.Get("DATABASE_URL", ""))
+func Open(driver, dsn string) (*DB, error) {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("database: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("database: %w", err)
+	}
+	return New(db, driver), nil
+}
+
+// New wraps a database opened with the driver registered under driver.
+func New(db *sql.DB, driver string) *DB {
+	return &DB{DB: db, dialect: DialectOf(driver)}
+}
+
+// Dialect returns the placeholder style of the driver.
+func (db *DB) Dialect() Dialect {
+	return db.dialect
+}
+
+// Tx is a transaction knowing the dialect of its driver.
+type Tx struct {
+	*sql.Tx
+	dialect Dialect
+}
+
+// Dialect returns the placeholder style of the driver.
+func (tx *Tx) Dialect() Dialect {
+	return tx.dialect
+}
+
+// Transaction runs fn in a transaction, committed when fn returns nil and rolled back
+// otherwise, including when fn panics.
+//
+// Example usage:
+//
+//	err := db.Transaction(ctx, func(tx *database.Tx) error {
+//		if _, err := database.Exec(ctx, tx, database.Insert("orders").Set("id", id)); err != nil {
+//			return err
+//		}
+//		_, err := outbox.Add(ctx, tx, msg)
+//		return err
+//	})
+func (db *DB) Transaction(ctx context.Context, fn func(tx *Tx) error) error {
+	sqlTx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	tx := &Tx{Tx: sqlTx, dialect: db.dialect}
+	defer func() {
+		if p := recover(); p != nil {
+			sqlTx.Rollback()
+			panic(p)
+		}
+	}()
+	if err := fn(tx); err != nil {
+		if rollbackErr := sqlTx.Rollback(); rollbackErr != nil {
+			return fmt.Errorf("%w (rollback: %v)", err, rollbackErr)
+		}
+		return err
+	}
+	return sqlTx.Commit()
+}
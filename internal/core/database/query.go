@@ -0,0 +1,407 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrNoConditions is returned for updates and deletes without conditions, which would change
+// every row of the table. Call All to mean it.
+var ErrNoConditions = errors.New("database: update or delete without conditions")
+
+// identifierPattern matches table and column names, optionally qualified.
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// Statement is a query built for a dialect.
+type Statement interface {
+	// SQL returns the query with the placeholders of d and its arguments.
+	SQL(d Dialect) (string, []interface{}, error)
+}
+
+// condition is a boolean SQL expression with ? placeholders for its arguments.
+type condition struct {
+	column string // Identifier embedded in expr, validated when the statement is built
+	expr   string
+	args   []interface{}
+}
+
+// builder writes a query, numbering the placeholders of the dialect.
+type builder struct {
+	strings.Builder
+	dialect Dialect
+	args    []interface{}
+	err     error
+}
+
+// identifier writes a table or column name.
+func (b *builder) identifier(name string) {
+	b.check(name)
+	b.WriteString(name)
+}
+
+// check fails the statement when name is not a valid identifier.
+func (b *builder) check(name string) {
+	if !identifierPattern.MatchString(name) {
+		b.fail(fmt.Errorf("database: invalid identifier %q", name))
+	}
+}
+
+// arg writes the placeholder of a single argument.
+func (b *builder) arg(v interface{}) {
+	b.args = append(b.args, v)
+	b.WriteString(b.dialect.Placeholder(len(b.args)))
+}
+
+// expr writes an expression, replacing its ? placeholders.
+func (b *builder) expr(expr string, args []interface{}) {
+	if n := strings.Count(expr, "?"); n != len(args) {
+		b.fail(fmt.Errorf("database: %q has %d placeholders for %d arguments", expr, n, len(args)))
+		return
+	}
+	parts := strings.Split(expr, "?")
+	for i, part := range parts {
+		b.WriteString(part)
+		if i < len(args) {
+			b.arg(args[i])
+		}
+	}
+}
+
+// where writes the conditions joined with AND.
+func (b *builder) where(conditions []condition) {
+	for i, c := range conditions {
+		if i == 0 {
+			b.WriteString(" WHERE ")
+		} else {
+			b.WriteString(" AND ")
+		}
+		if c.column != "" {
+			b.check(c.column)
+		}
+		b.WriteString("(")
+		b.expr(c.expr, c.args)
+		b.WriteString(")")
+	}
+}
+
+func (b *builder) fail(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+func (b *builder) result() (string, []interface{}, error) {
+	if b.err != nil {
+		return "", nil, b.err
+	}
+	return b.String(), b.args, nil
+}
+
+// SelectQuery builds a SELECT statement.
+type SelectQuery struct {
+	columns    []string
+	table      string
+	conditions []condition
+	orderBy    []string
+	limit      int
+	offset     int
+}
+
+// Select starts a query of the columns, all columns when none are given. All and One select
+// the columns of their type instead.
+//
+// Example usage:
+//
+//	q := database.Select("id", "name").From("users").Where("team_id = ?", teamID).OrderBy("name")
+func Select(columns ...string) *SelectQuery {
+	return &SelectQuery{columns: columns}
+}
+
+// From sets the table.
+func (q *SelectQuery) From(table string) *SelectQuery {
+	q.table = table
+	return q
+}
+
+// Where adds a condition with ? placeholders for args. Conditions are joined with AND.
+// Values must be passed as args, never formatted into expr.
+func (q *SelectQuery) Where(expr string, args ...interface{}) *SelectQuery {
+	q.conditions = append(q.conditions, condition{expr: expr, args: args})
+	return q
+}
+
+// WhereIn adds a condition matching rows whose column is one of values. No values match no
+// rows.
+func (q *SelectQuery) WhereIn(column string, values ...interface{}) *SelectQuery {
+	q.conditions = append(q.conditions, in(column, values))
+	return q
+}
+
+// OrderBy sorts by the columns, each optionally followed by ASC or DESC.
+func (q *SelectQuery) OrderBy(columns ...string) *SelectQuery {
+	q.orderBy = append(q.orderBy, columns...)
+	return q
+}
+
+// Limit sets the maximum number of rows, zero for no limit.
+func (q *SelectQuery) Limit(n int) *SelectQuery {
+	q.limit = n
+	return q
+}
+
+// Offset skips the first n rows.
+func (q *SelectQuery) Offset(n int) *SelectQuery {
+	q.offset = n
+	return q
+}
+
+// SQL implements Statement.
+func (q *SelectQuery) SQL(d Dialect) (string, []interface{}, error) {
+	b := &builder{dialect: d}
+	b.WriteString("SELECT ")
+	if len(q.columns) == 0 {
+		b.WriteString("*")
+	}
+	for i, column := range q.columns {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.identifier(column)
+	}
+	b.WriteString(" FROM ")
+	b.identifier(q.table)
+	b.where(q.conditions)
+	for i, order := range q.orderBy {
+		if i == 0 {
+			b.WriteString(" ORDER BY ")
+		} else {
+			b.WriteString(", ")
+		}
+		column, direction, _ := strings.Cut(strings.TrimSpace(order), " ")
+		b.identifier(column)
+		switch direction = strings.ToUpper(strings.TrimSpace(direction)); direction {
+		case "":
+		case "ASC", "DESC":
+			b.WriteString(" " + direction)
+		default:
+			b.fail(fmt.Errorf("database: invalid order %q", order))
+		}
+	}
+	if q.limit > 0 {
+		b.WriteString(" LIMIT " + strconv.Itoa(q.limit))
+	}
+	if q.offset > 0 {
+		b.WriteString(" OFFSET " + strconv.Itoa(q.offset))
+	}
+	return b.result()
+}
+
+// countSQL returns the statement counting the rows matched by q.
+func (q *SelectQuery) countSQL(d Dialect) (string, []interface{}, error) {
+	b := &builder{dialect: d}
+	b.WriteString("SELECT COUNT(*) FROM ")
+	b.identifier(q.table)
+	b.where(q.conditions)
+	return b.result()
+}
+
+// InsertQuery builds an INSERT statement of a single row.
+type InsertQuery struct {
+	table     string
+	columns   []string
+	values    []interface{}
+	returning []string
+}
+
+// Insert starts an insert into table.
+//
+// Example usage:
+//
+//	q := database.Insert("users").Set("email", email).Set("name", name).Returning("id")
+func Insert(table string) *InsertQuery {
+	return &InsertQuery{table: table}
+}
+
+// Set sets the value of a column.
+func (q *InsertQuery) Set(column string, value interface{}) *InsertQuery {
+	q.columns = append(q.columns, column)
+	q.values = append(q.values, value)
+	return q
+}
+
+// Values sets the columns of the fields of the struct v, see Columns.
+func (q *InsertQuery) Values(v interface{}) *InsertQuery {
+	for _, field := range structValues(v) {
+		q.Set(field.column, field.value)
+	}
+	return q
+}
+
+// Returning returns the columns of the inserted row, as supported by PostgreSQL and SQLite.
+func (q *InsertQuery) Returning(columns ...string) *InsertQuery {
+	q.returning = columns
+	return q
+}
+
+// SQL implements Statement.
+func (q *InsertQuery) SQL(d Dialect) (string, []interface{}, error) {
+	b := &builder{dialect: d}
+	if len(q.columns) == 0 {
+		return "", nil, fmt.Errorf("database: insert into %s without values", q.table)
+	}
+	b.WriteString("INSERT INTO ")
+	b.identifier(q.table)
+	b.WriteString(" (")
+	for i, column := range q.columns {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.identifier(column)
+	}
+	b.WriteString(") VALUES (")
+	for i, value := range q.values {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.arg(value)
+	}
+	b.WriteString(")")
+	writeReturning(b, q.returning)
+	return b.result()
+}
+
+// UpdateQuery builds an UPDATE statement.
+type UpdateQuery struct {
+	table      string
+	columns    []string
+	values     []interface{}
+	conditions []condition
+	all        bool
+}
+
+// Update starts an update of table.
+//
+// Example usage:
+//
+//	q := database.Update("users").Set("name", name).Where("id = ?", id)
+func Update(table string) *UpdateQuery {
+	return &UpdateQuery{table: table}
+}
+
+// Set sets a column to value.
+func (q *UpdateQuery) Set(column string, value interface{}) *UpdateQuery {
+	q.columns = append(q.columns, column)
+	q.values = append(q.values, value)
+	return q
+}
+
+// Where adds a condition with ? placeholders for args, see SelectQuery.Where.
+func (q *UpdateQuery) Where(expr string, args ...interface{}) *UpdateQuery {
+	q.conditions = append(q.conditions, condition{expr: expr, args: args})
+	return q
+}
+
+// WhereIn adds a condition matching rows whose column is one of values.
+func (q *UpdateQuery) WhereIn(column string, values ...interface{}) *UpdateQuery {
+	q.conditions = append(q.conditions, in(column, values))
+	return q
+}
+
+// All allows the update without conditions.
+func (q *UpdateQuery) All() *UpdateQuery {
+	q.all = true
+	return q
+}
+
+// SQL implements Statement.
+func (q *UpdateQuery) SQL(d Dialect) (string, []interface{}, error) {
+	if len(q.conditions) == 0 && !q.all {
+		return "", nil, ErrNoConditions
+	}
+	if len(q.columns) == 0 {
+		return "", nil, fmt.Errorf("database: update of %s without values", q.table)
+	}
+	b := &builder{dialect: d}
+	b.WriteString("UPDATE ")
+	b.identifier(q.table)
+	b.WriteString(" SET ")
+	for i, column := range q.columns {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.identifier(column)
+		b.WriteString(" = ")
+		b.arg(q.values[i])
+	}
+	b.where(q.conditions)
+	return b.result()
+}
+
+// DeleteQuery builds a DELETE statement.
+type DeleteQuery struct {
+	table      string
+	conditions []condition
+	all        bool
+}
+
+// Delete starts a delete from table.
+//
+// Example usage:
+//
+//	q := database.Delete("sessions").Where("expires_at < ?", time.Now())
+func Delete(table string) *DeleteQuery {
+	return &DeleteQuery{table: table}
+}
+
+// Where adds a condition with ? placeholders for args, see SelectQuery.Where.
+func (q *DeleteQuery) Where(expr string, args ...interface{}) *DeleteQuery {
+	q.conditions = append(q.conditions, condition{expr: expr, args: args})
+	return q
+}
+
+// WhereIn adds a condition matching rows whose column is one of values.
+func (q *DeleteQuery) WhereIn(column string, values ...interface{}) *DeleteQuery {
+	q.conditions = append(q.conditions, in(column, values))
+	return q
+}
+
+// All allows the delete without conditions.
+func (q *DeleteQuery) All() *DeleteQuery {
+	q.all = true
+	return q
+}
+
+// SQL implements Statement.
+func (q *DeleteQuery) SQL(d Dialect) (string, []interface{}, error) {
+	if len(q.conditions) == 0 && !q.all {
+		return "", nil, ErrNoConditions
+	}
+	b := &builder{dialect: d}
+	b.WriteString("DELETE FROM ")
+	b.identifier(q.table)
+	b.where(q.conditions)
+	return b.result()
+}
+
+// in returns the condition of WhereIn.
+func in(column string, values []interface{}) condition {
+	if len(values) == 0 {
+		return condition{column: column, expr: "1 = 0"}
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")
+	return condition{column: column, expr: column + " IN (" + placeholders + ")", args: values}
+}
+
+func writeReturning(b *builder, columns []string) {
+	for i, column := range columns {
+		if i == 0 {
+			b.WriteString(" RETURNING ")
+		} else {
+			b.WriteString(", ")
+		}
+		b.identifier(column)
+	}
+}
@@ -0,0 +1,237 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Timestamps is embedded in models to have Repository manage their created_at and
+// updated_at columns.
+type Timestamps struct {
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// SoftDelete is embedded in models whose rows Repository marks as deleted in the deleted_at
+// column instead of removing them. Queries of the repository leave deleted rows out.
+type SoftDelete struct {
+	DeletedAt *time.Time `db:"deleted_at" json:"deleted_at,omitempty"`
+}
+
+// Deleted reports whether the row is soft deleted.
+func (s SoftDelete) Deleted() bool {
+	return s.DeletedAt != nil
+}
+
+var (
+	timestampsType = reflect.TypeOf(Timestamps{})
+	softDeleteType = reflect.TypeOf(SoftDelete{})
+)
+
+// Repository stores the rows of the model T in a table, following the data conventions of
+// the models: the timestamps of models embedding Timestamps are set on insert and update,
+// and rows of models embedding SoftDelete are soft deleted and can be restored or purged.
+type Repository[T any] struct {
+	runner   Runner
+	table    string
+	idColumn string
+
+	timestamps []int // Index of the embedded Timestamps, nil without
+	softDelete bool
+
+	// Now returns the time stored in the timestamps, time.Now by default.
+	Now func() time.Time
+}
+
+// NewRepository creates a repository of the rows of T in table, identified by the id
+// column.
+//
+// Example usage:
+//
+//	type User struct {
+//		ID    int64  `db:"id,omitempty" json:"id"`
+//		Email string `db:"email" json:"email"`
+//		database.Timestamps
+//		database.SoftDelete
+//	}
+//	users := database.NewRepository[User](db, "users")
+func NewRepository[T any](r Runner, table string) *Repository[T] {
+	repo := &Repository[T]{runner: r, table: table, idColumn: "id", Now: time.Now}
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() == reflect.Struct {
+		for _, f := range reflect.VisibleFields(t) {
+			switch {
+			case f.Anonymous && f.Type == timestampsType:
+				repo.timestamps = f.Index
+			case f.Anonymous && f.Type == softDeleteType:
+				repo.softDelete = true
+			}
+		}
+	}
+	return repo
+}
+
+// WithTx returns a copy of the repository running its queries in the transaction.
+//
+// Example usage:
+//
+//	err := db.Transaction(ctx, func(tx *database.Tx) error {
+//		_, err := users.WithTx(tx).Insert(ctx, &user)
+//		return err
+//	})
+func (r *Repository[T]) WithTx(tx *Tx) *Repository[T] {
+	copied := *r
+	copied.runner = tx
+	return &copied
+}
+
+// WithIDColumn sets the column identifying rows, id by default.
+func (r *Repository[T]) WithIDColumn(column string) *Repository[T] {
+	r.idColumn = column
+	return r
+}
+
+// Query starts a query of the rows, leaving soft deleted rows out.
+//
+// Example usage:
+//
+//	active, err := users.All(ctx, users.Query().Where("last_login > ?", since).OrderBy("email"))
+func (r *Repository[T]) Query() *SelectQuery {
+	q := Select().From(r.table)
+	if r.softDelete {
+		q.Where("deleted_at IS NULL")
+	}
+	return q
+}
+
+// Unscoped starts a query of all rows, including soft deleted ones.
+func (r *Repository[T]) Unscoped() *SelectQuery {
+	return Select().From(r.table)
+}
+
+// Trashed starts a query of the soft deleted rows only.
+func (r *Repository[T]) Trashed() *SelectQuery {
+	return Select().From(r.table).Where("deleted_at IS NOT NULL")
+}
+
+// All runs a query started with Query, Unscoped or Trashed, see All.
+func (r *Repository[T]) All(ctx context.Context, q *SelectQuery) ([]T, error) {
+	return All[T](ctx, r.runner, q)
+}
+
+// Count returns the number of rows matched by the query, see Count.
+func (r *Repository[T]) Count(ctx context.Context, q *SelectQuery) (int64, error) {
+	return Count(ctx, r.runner, q)
+}
+
+// Find returns the row with the ID, or sql.ErrNoRows when it is missing or soft deleted.
+func (r *Repository[T]) Find(ctx context.Context, id interface{}) (T, error) {
+	return One[T](ctx, r.runner, r.Query().Where(r.idColumn+" = ?", id))
+}
+
+// Insert inserts the row and returns its generated ID, see InsertID. The timestamps of v are
+// set first, keeping a creation time already set.
+func (r *Repository[T]) Insert(ctx context.Context, v *T) (int64, error) {
+	if stamps := r.stamps(v); stamps != nil {
+		now := r.Now()
+		if stamps.CreatedAt.IsZero() {
+			stamps.CreatedAt = now
+		}
+		stamps.UpdatedAt = now
+	}
+	return InsertID(ctx, r.runner, Insert(r.table).Values(v), r.idColumn)
+}
+
+// Update saves the columns of v, except its ID, creation time and deletion time, after
+// setting its update time. Soft deleted rows are not updated.
+func (r *Repository[T]) Update(ctx context.Context, v *T) error {
+	if stamps := r.stamps(v); stamps != nil {
+		stamps.UpdatedAt = r.Now()
+	}
+	q := Update(r.table)
+	var id interface{}
+	for _, value := range structValues(v) {
+		switch value.column {
+		case r.idColumn:
+			id = value.value
+		case "created_at", "deleted_at":
+		default:
+			q.Set(value.column, value.value)
+		}
+	}
+	if id == nil {
+		return fmt.Errorf("database: %T has no %s column", v, r.idColumn)
+	}
+	q.Where(r.idColumn+" = ?", id)
+	if r.softDelete {
+		q.Where("deleted_at IS NULL")
+	}
+	_, err := Exec(ctx, r.runner, q)
+	return err
+}
+
+// Delete soft deletes the row with the ID, or deletes it when T does not embed SoftDelete.
+// It returns sql.ErrNoRows when there is no such row.
+func (r *Repository[T]) Delete(ctx context.Context, id interface{}) error {
+	if !r.softDelete {
+		return r.Purge(ctx, id)
+	}
+	now := r.Now()
+	q := Update(r.table).Set("deleted_at", now)
+	if r.timestamps != nil {
+		q.Set("updated_at", now)
+	}
+	return expectRow(Exec(ctx, r.runner, q.Where(r.idColumn+" = ?", id).Where("deleted_at IS NULL")))
+}
+
+// Restore brings back the soft deleted row with the ID. It returns sql.ErrNoRows when there is
+// no such deleted row.
+func (r *Repository[T]) Restore(ctx context.Context, id interface{}) error {
+	q := Update(r.table).Set("deleted_at", nil)
+	if r.timestamps != nil {
+		q.Set("updated_at", r.Now())
+	}
+	return expectRow(Exec(ctx, r.runner, q.Where(r.idColumn+" = ?", id).Where("deleted_at IS NOT NULL")))
+}
+
+// Purge deletes the row with the ID for good, deleted or not. It returns sql.ErrNoRows when
+// there is no such row.
+func (r *Repository[T]) Purge(ctx context.Context, id interface{}) error {
+	return expectRow(Exec(ctx, r.runner, Delete(r.table).Where(r.idColumn+" = ?", id)))
+}
+
+// PurgeDeleted deletes the rows soft deleted before the time for good and returns their
+// number, e.g. from a retention job.
+//
+// Example usage:
+//
+//	n, err := users.PurgeDeleted(ctx, time.Now().AddDate(0, 0, -30))
+func (r *Repository[T]) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	result, err := Exec(ctx, r.runner, Delete(r.table).Where("deleted_at IS NOT NULL").Where("deleted_at < ?", before))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// stamps returns the embedded Timestamps of v, nil when T has none.
+func (r *Repository[T]) stamps(v *T) *Timestamps {
+	if r.timestamps == nil {
+		return nil
+	}
+	return reflect.ValueOf(v).Elem().FieldByIndex(r.timestamps).Addr().Interface().(*Timestamps)
+}
+
+// expectRow turns a result without affected rows into sql.ErrNoRows.
+func expectRow(result sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
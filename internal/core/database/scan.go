@@ -0,0 +1,249 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// field is a struct field mapped to a column with a `db` tag.
+type field struct {
+	column    string
+	index     []int
+	omitEmpty bool
+}
+
+// fieldCache holds the fields of struct types.
+var fieldCache sync.Map
+
+// fieldsOf returns the fields of a struct type with a `db` tag, including the fields of
+// embedded structs. The tag is the column name, optionally followed by omitempty to leave
+// zero values out of inserts, e.g. for generated IDs.
+func fieldsOf(t reflect.Type) []field {
+	if cached, ok := fieldCache.Load(t); ok {
+		return cached.([]field)
+	}
+	var fields []field
+	for _, f := range reflect.VisibleFields(t) {
+		tag, ok := f.Tag.Lookup("db")
+		if !ok || tag == "-" || !f.IsExported() || throughPointer(t, f.Index) {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		fields = append(fields, field{column: name, index: f.Index, omitEmpty: options == "omitempty"})
+	}
+	fieldCache.Store(t, fields)
+	return fields
+}
+
+// throughPointer reports whether the field at index is promoted through an embedded pointer,
+// which may be nil.
+func throughPointer(t reflect.Type, index []int) bool {
+	for _, i := range index[:len(index)-1] {
+		f := t.Field(i)
+		if f.Type.Kind() == reflect.Ptr {
+			return true
+		}
+		t = f.Type
+	}
+	return false
+}
+
+// Columns returns the columns of the struct type T from the `db` tags of its fields.
+//
+// Example usage:
+//
+//	type User struct {
+//		ID    int64  `db:"id,omitempty"`
+//		Email string `db:"email"`
+//	}
+//	database.Columns[User]() // [id email]
+func Columns[T any]() []string {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	fields := fieldsOf(t)
+	columns := make([]string, len(fields))
+	for i, f := range fields {
+		columns[i] = f.column
+	}
+	return columns
+}
+
+// columnValue is the value of a column of a struct.
+type columnValue struct {
+	column string
+	value  interface{}
+}
+
+// structValues returns the column values of the struct or struct pointer v.
+func structValues(v interface{}) []columnValue {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	var values []columnValue
+	for _, f := range fieldsOf(rv.Type()) {
+		value := rv.FieldByIndex(f.index)
+		if f.omitEmpty && value.IsZero() {
+			continue
+		}
+		values = append(values, columnValue{f.column, value.Interface()})
+	}
+	return values
+}
+
+// Exec runs the statement.
+//
+// Example usage:
+//
+//	_, err := database.Exec(ctx, db, database.Update("users").Set("name", name).Where("id = ?", id))
+func Exec(ctx context.Context, r Runner, stmt Statement) (sql.Result, error) {
+	query, args, err := stmt.SQL(r.Dialect())
+	if err != nil {
+		return nil, err
+	}
+	return r.ExecContext(ctx, query, args...)
+}
+
+// InsertID runs the insert and returns the generated ID of the row. It is read from column
+// with RETURNING on PostgreSQL and with LastInsertId on other drivers.
+//
+// Example usage:
+//
+//	id, err := database.InsertID(ctx, db, database.Insert("users").Values(user), "id")
+func InsertID(ctx context.Context, r Runner, q *InsertQuery, column string) (int64, error) {
+	if r.Dialect() == Dollar {
+		copied := *q
+		copied.returning = []string{column}
+		return One[int64](ctx, r, &copied)
+	}
+	result, err := Exec(ctx, r, q)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// All runs the query and scans the rows into values of T. Structs are scanned by the `db`
+// tags of their fields, and a SelectQuery without columns selects those of T. Other types
+// receive the single column of the rows.
+//
+// Example usage:
+//
+//	users, err := database.All[User](ctx, db, database.Select().From("users").Where("active = ?", true))
+//	emails, err := database.All[string](ctx, db, database.Select("email").From("users"))
+func All[T any](ctx context.Context, r Runner, stmt Statement) ([]T, error) {
+	if q, ok := stmt.(*SelectQuery); ok && len(q.columns) == 0 {
+		if columns := Columns[T](); len(columns) > 0 {
+			copied := *q
+			copied.columns = columns
+			stmt = &copied
+		}
+	}
+	query, args, err := stmt.SQL(r.Dialect())
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	var results []T
+	for rows.Next() {
+		var value T
+		if err := scanRow(rows, columns, &value); err != nil {
+			return nil, err
+		}
+		results = append(results, value)
+	}
+	return results, rows.Err()
+}
+
+// One runs the query and scans the first row into a T, see All. It returns sql.ErrNoRows
+// when there is none. Inserts with Returning return the values of the inserted row.
+//
+// Example usage:
+//
+//	user, err := database.One[User](ctx, db, database.Select().From("users").Where("id = ?", id))
+//	id, err := database.One[int64](ctx, db, database.Insert("users").Values(user).Returning("id"))
+func One[T any](ctx context.Context, r Runner, stmt Statement) (T, error) {
+	if q, ok := stmt.(*SelectQuery); ok {
+		copied := *q
+		copied.limit = 1
+		stmt = &copied
+	}
+	var zero T
+	results, err := All[T](ctx, r, stmt)
+	if err != nil {
+		return zero, err
+	}
+	if len(results) == 0 {
+		return zero, sql.ErrNoRows
+	}
+	return results[0], nil
+}
+
+// Count returns the number of rows matched by the query, ignoring its order, limit and offset.
+//
+// Example usage:
+//
+//	total, err := database.Count(ctx, db, database.Select().From("users").Where("active = ?", true))
+func Count(ctx context.Context, r Runner, q *SelectQuery) (int64, error) {
+	query, args, err := q.countSQL(r.Dialect())
+	if err != nil {
+		return 0, err
+	}
+	rows, err := r.QueryContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	var n int64
+	if rows.Next() {
+		if err := rows.Scan(&n); err != nil {
+			return 0, err
+		}
+	}
+	return n, rows.Err()
+}
+
+// scanRow scans the current row into dest, a pointer to a struct or a single value.
+func scanRow(rows *sql.Rows, columns []string, dest interface{}) error {
+	rv := reflect.ValueOf(dest).Elem()
+	if rv.Kind() != reflect.Struct || isScanner(rv) {
+		return rows.Scan(dest)
+	}
+	byColumn := map[string][]int{}
+	for _, f := range fieldsOf(rv.Type()) {
+		byColumn[f.column] = f.index
+	}
+	targets := make([]interface{}, len(columns))
+	for i, column := range columns {
+		index, ok := byColumn[column]
+		if !ok {
+			return fmt.Errorf("database: no field of %s for column %q", rv.Type(), column)
+		}
+		targets[i] = rv.FieldByIndex(index).Addr().Interface()
+	}
+	return rows.Scan(targets...)
+}
+
+// isScanner reports whether v scans itself, as sql.NullString or time.Time do.
+func isScanner(v reflect.Value) bool {
+	if _, ok := v.Addr().Interface().(sql.Scanner); ok {
+		return true
+	}
+	// time.Time is scanned by the driver
+	return v.Type().PkgPath() == "time"
+}
@@ -0,0 +1,229 @@
+package database
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/config"
+)
+
+// SeedFunc fills the database in the transaction of its seed.
+type SeedFunc func(ctx context.Context, tx *Tx) error
+
+// Seed is a named seed function run in some environments.
+type Seed struct {
+	Name string
+	Envs []string // Environments the seed runs in, all when empty
+	Run  SeedFunc
+}
+
+// runsIn reports whether the seed runs in the environment.
+func (s Seed) runsIn(env string) bool {
+	if len(s.Envs) == 0 {
+		return true
+	}
+	for _, e := range s.Envs {
+		if config.NormalizeEnv(e) == config.NormalizeEnv(env) {
+			return true
+		}
+	}
+	return false
+}
+
+// Seeder runs seeds in the order they are added, each once per database. Every seed runs in
+// its own transaction, which also records the seed as applied in the markers table, so a
+// failed seed leaves neither data nor marker behind and runs again next time.
+type Seeder struct {
+	db    *DB
+	table string
+	seeds []Seed
+
+	// Now returns the time recorded in the markers, time.Now by default.
+	Now func() time.Time
+}
+
+// NewSeeder creates a seeder of the database recording applied seeds in the lessgo_seeds table.
+//
+// Example usage:
+//
+//	seeder := database.NewSeeder(db).
+//		Add("roles", seedRoles).
+//		Add("demo-users", seedDemoUsers, "development", "staging")
+//	applied, err := seeder.Run(ctx, cfg.Env())
+func NewSeeder(db *DB) *Seeder {
+	return &Seeder{db: db, table: "lessgo_seeds", Now: time.Now}
+}
+
+// WithTable sets the table of the markers.
+func (s *Seeder) WithTable(table string) *Seeder {
+	s.table = table
+	return s
+}
+
+// Add adds a seed running after the seeds added before it, in the environments given or in
+// all of them when none are.
+func (s *Seeder) Add(name string, run SeedFunc, envs ...string) *Seeder {
+	s.seeds = append(s.seeds, Seed{Name: name, Envs: envs, Run: run})
+	return s
+}
+
+// Seeds returns the seeds in the order they run.
+func (s *Seeder) Seeds() []Seed {
+	return append([]Seed{}, s.seeds...)
+}
+
+// Run runs the seeds of the environment not applied yet and returns their names. It stops at
+// the first failing seed, as later seeds may depend on its data.
+func (s *Seeder) Run(ctx context.Context, env string) ([]string, error) {
+	if err := s.check(); err != nil {
+		return nil, err
+	}
+	applied, err := s.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	done := make(map[string]bool, len(applied))
+	for _, name := range applied {
+		done[name] = true
+	}
+	var ran []string
+	for _, seed := range s.seeds {
+		if done[seed.Name] || !seed.runsIn(env) {
+			continue
+		}
+		err := s.db.Transaction(ctx, func(tx *Tx) error {
+			if err := seed.Run(ctx, tx); err != nil {
+				return err
+			}
+			_, err := Exec(ctx, tx, Insert(s.table).Set("name", seed.Name).Set("env", env).Set("applied_at", s.Now()))
+			return err
+		})
+		if err != nil {
+			return ran, fmt.Errorf("database: seed %s: %w", seed.Name, err)
+		}
+		ran = append(ran, seed.Name)
+	}
+	return ran, nil
+}
+
+// Applied returns the names of the applied seeds, creating the markers table when missing.
+func (s *Seeder) Applied(ctx context.Context) ([]string, error) {
+	if !identifierPattern.MatchString(s.table) {
+		return nil, fmt.Errorf("database: invalid identifier %q", s.table)
+	}
+	create := "CREATE TABLE IF NOT EXISTS " + s.table +
+		" (name VARCHAR(255) NOT NULL PRIMARY KEY, env VARCHAR(64) NOT NULL, applied_at TIMESTAMP NOT NULL)"
+	if _, err := s.db.ExecContext(ctx, create); err != nil {
+		return nil, fmt.Errorf("database: creating the seed markers: %w", err)
+	}
+	return All[string](ctx, s.db, Select("name").From(s.table).OrderBy("applied_at"))
+}
+
+// Reset removes the markers of the seeds so the next Run runs them again, e.g. after
+// truncating their tables in integration tests. No names reset all seeds.
+func (s *Seeder) Reset(ctx context.Context, names ...string) error {
+	if _, err := s.Applied(ctx); err != nil {
+		return err
+	}
+	q := Delete(s.table)
+	if len(names) == 0 {
+		q.All()
+	} else {
+		values := make([]interface{}, len(names))
+		for i, name := range names {
+			values[i] = name
+		}
+		q.WhereIn("name", values...)
+	}
+	_, err := Exec(ctx, s.db, q)
+	return err
+}
+
+// check fails on seeds without a name or function and on names used twice.
+func (s *Seeder) check() error {
+	names := make(map[string]bool, len(s.seeds))
+	for _, seed := range s.seeds {
+		switch {
+		case seed.Name == "" || seed.Run == nil:
+			return fmt.Errorf("database: seed %q without a name or function", seed.Name)
+		case names[seed.Name]:
+			return fmt.Errorf("database: seed %s added twice", seed.Name)
+		}
+		names[seed.Name] = true
+	}
+	return nil
+}
+
+// RunSeedCommand runs the seeder from the command line arguments of a seed program, as
+// started by `lessgo seed`. The environment is taken from -env, or from the ENV and APP_ENV
+// variables, see config.Config.Env. -reset runs the given comma separated seeds again, or all with "all", and
+// -list prints the seeds of the environment with their state.
+//
+// Example usage:
+//
+//	// seeds/main.go
+//	func main() {
+//		db, err := database.Open("postgres", os.Getenv("DATABASE_URL"))
+//		...
+//		seeder := database.NewSeeder(db).Add("roles", seedRoles)
+//		if err := database.RunSeedCommand(context.Background(), seeder, os.Args[1:], os.Stdout); err != nil {
+//			log.Fatal(err)
+//		}
+//	}
+func RunSeedCommand(ctx context.Context, s *Seeder, args []string, out io.Writer) error {
+	env := config.Config{"ENV": os.Getenv("ENV"), "APP_ENV": os.Getenv("APP_ENV")}.Env()
+	flags := flag.NewFlagSet("seed", flag.ContinueOnError)
+	flags.SetOutput(out)
+	flags.StringVar(&env, "env", env, "environment whose seeds run")
+	reset := flags.String("reset", "", "comma separated seeds to run again, all for every seed")
+	list := flags.Bool("list", false, "list the seeds of the environment instead of running them")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *list {
+		applied, err := s.Applied(ctx)
+		if err != nil {
+			return err
+		}
+		done := make(map[string]bool, len(applied))
+		for _, name := range applied {
+			done[name] = true
+		}
+		for _, seed := range s.seeds {
+			if !seed.runsIn(env) {
+				continue
+			}
+			state := "pending"
+			if done[seed.Name] {
+				state = "applied"
+			}
+			fmt.Fprintf(out, "%-8s %s\n", state, seed.Name)
+		}
+		return nil
+	}
+	if *reset != "" {
+		var names []string
+		if *reset != "all" {
+			for _, name := range strings.Split(*reset, ",") {
+				names = append(names, strings.TrimSpace(name))
+			}
+		}
+		if err := s.Reset(ctx, names...); err != nil {
+			return err
+		}
+	}
+	ran, err := s.Run(ctx, env)
+	for _, name := range ran {
+		fmt.Fprintln(out, "seeded", name)
+	}
+	if err == nil && len(ran) == 0 {
+		fmt.Fprintf(out, "no pending seeds for %s\n", env)
+	}
+	return err
+}
@@ -0,0 +1,381 @@
+/*
+Package dataexport orchestrates data subject access requests (GDPR exports).
+
+Modules register exporters that collect everything they store about a user. An export runs
+all exporters on a worker pool, bundles their files into a single zip archive together with
+a manifest, stores the archive and notifies the user that it is ready for download.
+
+Usage:
+
+	orchestrator := dataexport.NewOrchestrator(
+		dataexport.NewFileArchiveStore("./exports", "https://example.com/exports"),
+		dataexport.WithNotifier(dataexport.MailNotifier(m)),
+	)
+	orchestrator.Register("profile", func(ctx context.Context, subjectID string) ([]dataexport.File, error) {
+		user, err := repo.FindUser(ctx, subjectID)
+		if err != nil {
+			return nil, err
+		}
+		data, _ := json.MarshalIndent(user, "", "  ")
+		return []dataexport.File{{Name: "profile.json", Data: data}}, nil
+	})
+
+	id, err := orchestrator.Start(dataexport.Request{SubjectID: "42", Email: "user@example.com"})
+*/
+package dataexport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/concurrency"
+	"github.com/hokamsingh/lessgo/internal/utils"
+)
+
+// File is a single file produced by an exporter.
+type File struct {
+	Name string
+	Data []byte
+}
+
+// ExporterFunc collects the data a module holds about a subject.
+type ExporterFunc func(ctx context.Context, subjectID string) ([]File, error)
+
+// Request identifies the subject of an export and how to reach them.
+type Request struct {
+	ID        string
+	SubjectID string
+	Email     string
+}
+
+// Status is the state of an export.
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusRunning   Status = "running"
+	StatusCompleted Status = "completed"
+	StatusFailed    Status = "failed"
+)
+
+// ModuleResult describes what a single exporter contributed.
+type ModuleResult struct {
+	Module string   `json:"module"`
+	Files  []string `json:"files"`
+	Error  string   `json:"error,omitempty"`
+}
+
+// Result is the outcome of an export.
+type Result struct {
+	ID          string         `json:"id"`
+	SubjectID   string         `json:"subject_id"`
+	Status      Status         `json:"status"`
+	Location    string         `json:"location,omitempty"`
+	Modules     []ModuleResult `json:"modules"`
+	Error       string         `json:"error,omitempty"`
+	RequestedAt time.Time      `json:"requested_at"`
+	CompletedAt time.Time      `json:"completed_at,omitempty"`
+}
+
+// ArchiveStore stores finished archives and returns where they can be downloaded.
+type ArchiveStore interface {
+	SaveArchive(ctx context.Context, name string, data []byte) (location string, err error)
+}
+
+// Notifier tells the subject that their export is ready or has failed, see MailNotifier and
+// PushNotifier.
+type Notifier interface {
+	NotifyExport(ctx context.Context, request Request, result *Result) error
+}
+
+// NotifierFunc adapts a function to the Notifier interface.
+type NotifierFunc func(ctx context.Context, request Request, result *Result) error
+
+// NotifyExport implements Notifier.
+func (f NotifierFunc) NotifyExport(ctx context.Context, request Request, result *Result) error {
+	return f(ctx, request, result)
+}
+
+// Orchestrator fans export requests out to the registered exporters.
+type Orchestrator struct {
+	store     ArchiveStore
+	notifier  Notifier
+	workers   int
+	timeout   time.Duration
+	resultTTL time.Duration
+
+	mu        sync.RWMutex
+	exporters map[string]ExporterFunc
+	results   map[string]*Result
+}
+
+// NewOrchestrator creates an Orchestrator saving archives to store.
+func NewOrchestrator(store ArchiveStore, options ...func(*Orchestrator)) *Orchestrator {
+	o := &Orchestrator{
+		store:     store,
+		workers:   4,
+		timeout:   10 * time.Minute,
+		resultTTL: 24 * time.Hour,
+		exporters: make(map[string]ExporterFunc),
+		results:   make(map[string]*Result),
+	}
+	for _, option := range options {
+		option(o)
+	}
+	return o
+}
+
+// WithNotifier sets the notifier called when an export finishes.
+func WithNotifier(notifier Notifier) func(*Orchestrator) {
+	return func(o *Orchestrator) {
+		o.notifier = notifier
+	}
+}
+
+// WithWorkers sets how many exporters run concurrently.
+func WithWorkers(workers int) func(*Orchestrator) {
+	return func(o *Orchestrator) {
+		if workers > 0 {
+			o.workers = workers
+		}
+	}
+}
+
+// WithTimeout bounds the duration of a whole export.
+func WithTimeout(timeout time.Duration) func(*Orchestrator) {
+	return func(o *Orchestrator) {
+		o.timeout = timeout
+	}
+}
+
+// WithResultTTL sets how long the results of finished exports are kept, 24 hours by default.
+func WithResultTTL(ttl time.Duration) func(*Orchestrator) {
+	return func(o *Orchestrator) {
+		if ttl > 0 {
+			o.resultTTL = ttl
+		}
+	}
+}
+
+// Register adds the exporter of a module. Its files are placed in a folder named after the module.
+func (o *Orchestrator) Register(module string, exporter ExporterFunc) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.exporters[module] = exporter
+}
+
+// Start runs an export in the background and returns its ID. Progress is available through Result.
+func (o *Orchestrator) Start(request Request) (string, error) {
+	if err := assignID(&request); err != nil {
+		return "", err
+	}
+	o.setResult(&Result{ID: request.ID, SubjectID: request.SubjectID, Status: StatusPending, RequestedAt: time.Now().UTC()})
+
+	go func() {
+		if _, err := o.Export(context.Background(), request); err != nil {
+			log.Printf("Data export %s failed: %v", request.ID, err)
+		}
+	}()
+	return request.ID, nil
+}
+
+// Result returns the state of an export started with Start or Export. Finished exports are
+// forgotten after the result TTL.
+func (o *Orchestrator) Result(id string) (*Result, bool) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	result, ok := o.results[id]
+	if !ok || o.expired(result, time.Now()) {
+		return nil, false
+	}
+	copied := *result
+	return &copied, true
+}
+
+// Export runs all exporters for the subject, stores the archive and notifies the subject.
+// A failing exporter does not fail the export; its error is recorded in the manifest.
+func (o *Orchestrator) Export(ctx context.Context, request Request) (*Result, error) {
+	if o.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+		defer cancel()
+	}
+	if err := assignID(&request); err != nil {
+		return nil, err
+	}
+
+	result := &Result{ID: request.ID, SubjectID: request.SubjectID, Status: StatusRunning, RequestedAt: time.Now().UTC()}
+	if existing, ok := o.Result(request.ID); ok {
+		result.RequestedAt = existing.RequestedAt
+	}
+	o.setResult(result)
+
+	err := o.run(ctx, request, result)
+	result.CompletedAt = time.Now().UTC()
+	if err != nil {
+		result.Status = StatusFailed
+		result.Error = err.Error()
+	} else {
+		result.Status = StatusCompleted
+	}
+	o.setResult(result)
+
+	if o.notifier != nil {
+		// The subject is told about exports that timed out too
+		if notifyErr := o.notifier.NotifyExport(context.WithoutCancel(ctx), request, result); notifyErr != nil {
+			log.Printf("Failed to notify subject of data export %s: %v", request.ID, notifyErr)
+		}
+	}
+	return result, err
+}
+
+// assignID gives requests without an ID a random one, as the ID ends up in the archive URL.
+func assignID(request *Request) error {
+	if request.ID != "" {
+		return nil
+	}
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return fmt.Errorf("generating export ID: %w", err)
+	}
+	request.ID = token
+	return nil
+}
+
+// run executes the exporters on the worker pool and stores the archive.
+func (o *Orchestrator) run(ctx context.Context, request Request, result *Result) error {
+	o.mu.RLock()
+	modules := make([]string, 0, len(o.exporters))
+	for module := range o.exporters {
+		modules = append(modules, module)
+	}
+	exporters := make(map[string]ExporterFunc, len(o.exporters))
+	for module, exporter := range o.exporters {
+		exporters[module] = exporter
+	}
+	o.mu.RUnlock()
+	sort.Strings(modules)
+
+	type moduleOutput struct {
+		module string
+		files  []File
+		err    error
+	}
+
+	builder := concurrency.NewTaskBuilder(concurrency.Parallel, o.workers)
+	for _, module := range modules {
+		module, exporter := module, exporters[module]
+		// Errors are returned as part of the output so one module cannot abort the others
+		builder.Add(func(ctx context.Context) (interface{}, error) {
+			files, err := safeExport(ctx, exporter, request.SubjectID)
+			return moduleOutput{module: module, files: files, err: err}, nil
+		})
+	}
+	outputs, err := builder.Run(ctx)
+	if err != nil {
+		return err
+	}
+
+	files := make(map[string][]byte)
+	for _, output := range outputs {
+		out, ok := output.(moduleOutput)
+		if !ok {
+			continue
+		}
+		moduleResult := ModuleResult{Module: out.module, Files: []string{}}
+		if out.err != nil {
+			moduleResult.Error = out.err.Error()
+		}
+		for _, file := range out.files {
+			name := path.Join(out.module, path.Clean("/" + strings.ReplaceAll(file.Name, "\\", "/"))[1:])
+			files[name] = file.Data
+			moduleResult.Files = append(moduleResult.Files, name)
+		}
+		result.Modules = append(result.Modules, moduleResult)
+	}
+
+	manifest, err := json.MarshalIndent(struct {
+		SubjectID   string         `json:"subject_id"`
+		GeneratedAt time.Time      `json:"generated_at"`
+		Modules     []ModuleResult `json:"modules"`
+	}{request.SubjectID, time.Now().UTC(), result.Modules}, "", "  ")
+	if err != nil {
+		return err
+	}
+	files["manifest.json"] = manifest
+
+	archive, err := utils.ZipFiles(files)
+	if err != nil {
+		return fmt.Errorf("creating archive: %w", err)
+	}
+	location, err := o.store.SaveArchive(ctx, request.ID+".zip", archive)
+	if err != nil {
+		return fmt.Errorf("storing archive: %w", err)
+	}
+	result.Location = location
+	return nil
+}
+
+// safeExport runs an exporter, turning a panic into an error.
+func safeExport(ctx context.Context, exporter ExporterFunc, subjectID string) (files []File, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("exporter panicked: %v", r)
+		}
+	}()
+	return exporter(ctx, subjectID)
+}
+
+// setResult stores a copy of result and forgets the expired results.
+func (o *Orchestrator) setResult(result *Result) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	now := time.Now()
+	for id, existing := range o.results {
+		if o.expired(existing, now) {
+			delete(o.results, id)
+		}
+	}
+	copied := *result
+	copied.Modules = append([]ModuleResult(nil), result.Modules...)
+	o.results[result.ID] = &copied
+}
+
+// expired reports whether result belongs to an export that finished more than the result TTL
+// ago. Pending and running exports never expire.
+func (o *Orchestrator) expired(result *Result, now time.Time) bool {
+	return !result.CompletedAt.IsZero() && now.Sub(result.CompletedAt) > o.resultTTL
+}
+
+// FileArchiveStore saves archives to a directory served at baseURL.
+type FileArchiveStore struct {
+	dir     string
+	baseURL string
+}
+
+// NewFileArchiveStore creates an ArchiveStore writing to dir. Serve dir at baseURL, for example
+// with ServeStatic, behind authentication. Archive names include the random export ID.
+func NewFileArchiveStore(dir, baseURL string) *FileArchiveStore {
+	return &FileArchiveStore{dir: dir, baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// SaveArchive implements ArchiveStore.
+func (s *FileArchiveStore) SaveArchive(ctx context.Context, name string, data []byte) (string, error) {
+	if err := os.MkdirAll(s.dir, 0750); err != nil {
+		return "", err
+	}
+	name = filepath.Base(name)
+	if err := os.WriteFile(filepath.Join(s.dir, name), data, 0640); err != nil {
+		return "", err
+	}
+	return s.baseURL + "/" + name, nil
+}
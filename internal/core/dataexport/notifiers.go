@@ -0,0 +1,69 @@
+package dataexport
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hokamsingh/lessgo/internal/core/mailer"
+	"github.com/hokamsingh/lessgo/internal/core/notify"
+)
+
+// MailNotifier emails the subject at the address of the request whether the export is ready,
+// with its download link, or has failed. The message is queued with SendAsync so delivery is
+// retried. Requests without an email address are skipped.
+//
+// Example usage:
+//
+//	orchestrator := dataexport.NewOrchestrator(store, dataexport.WithNotifier(dataexport.MailNotifier(m)))
+func MailNotifier(m *mailer.Mailer) Notifier {
+	return NotifierFunc(func(ctx context.Context, request Request, result *Result) error {
+		if request.Email == "" {
+			return nil
+		}
+		msg := &mailer.Message{To: []string{request.Email}}
+		if result.Status == StatusCompleted {
+			msg.Subject = "Your data export is ready"
+			msg.Text = "The export of your data is ready for download:\n\n" + result.Location + "\n"
+		} else {
+			msg.Subject = "Your data export failed"
+			msg.Text = "The export of your data could not be completed, please request it again later.\n"
+		}
+		return m.SendAsync(msg)
+	})
+}
+
+// PushNotifier sends the outcome of the export to the connected clients of the subject, or
+// keeps it until they connect. The payload is an object of type "data_export" with the ID,
+// status and location of the export.
+//
+// Example usage:
+//
+//	orchestrator := dataexport.NewOrchestrator(store, dataexport.WithNotifier(dataexport.PushNotifier(notifications)))
+func PushNotifier(n *notify.Notifier) Notifier {
+	return NotifierFunc(func(ctx context.Context, request Request, result *Result) error {
+		_, err := n.Notify(ctx, request.SubjectID, map[string]interface{}{
+			"type":     "data_export",
+			"id":       result.ID,
+			"status":   result.Status,
+			"location": result.Location,
+		})
+		return err
+	})
+}
+
+// MultiNotifier calls all notifiers and returns the errors of those that failed.
+//
+// Example usage:
+//
+//	dataexport.WithNotifier(dataexport.MultiNotifier(dataexport.MailNotifier(m), dataexport.PushNotifier(notifications)))
+func MultiNotifier(notifiers ...Notifier) Notifier {
+	return NotifierFunc(func(ctx context.Context, request Request, result *Result) error {
+		var errs []error
+		for _, notifier := range notifiers {
+			if err := notifier.NotifyExport(ctx, request, result); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	})
+}
@@ -0,0 +1,431 @@
+/*
+Package devconsole provides a development-only console for inspecting a running application.
+
+The console resolves services from the DI container, invokes their methods with JSON arguments,
+shows the configuration with secrets masked and publishes test events. It accepts line based
+commands over a local unix socket, an HTTP handler or any reader/writer pair such as a terminal.
+The console refuses to run unless the environment is development, see config.Config.Env. The
+HTTP handler refuses cross-origin requests and, with WithToken, requests without the token.
+
+Usage:
+
+	console := devconsole.NewConsole(cfg, devconsole.WithContainer(container))
+	console.ExposeService("users", (*UserService)(nil))
+	go console.ListenUnix("/tmp/app-console.sock")
+
+	// $ nc -U /tmp/app-console.sock
+	// > call users FindByEmail "ann@example.com"
+*/
+package devconsole
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hokamsingh/lessgo/internal/core/config"
+	"github.com/hokamsingh/lessgo/internal/core/di"
+)
+
+// ErrDisabled is returned when the console is used outside of development.
+var ErrDisabled = errors.New("dev console is only available when ENV=development")
+
+// TokenHeader is the request header carrying the token of WithToken.
+const TokenHeader = "X-Console-Token"
+
+// EventBus publishes events. Event bus implementations can be plugged into the console with WithEventBus.
+type EventBus interface {
+	Publish(topic string, payload interface{}) error
+}
+
+// Console evaluates inspection commands against the application.
+type Console struct {
+	cfg       config.Config
+	enabled   bool
+	container *di.Container
+	bus       EventBus
+	token     string
+
+	mu       sync.RWMutex
+	services map[string]func() (reflect.Value, error)
+}
+
+// NewConsole creates a console. It is only enabled in development.
+func NewConsole(cfg config.Config, options ...func(*Console)) *Console {
+	c := &Console{
+		cfg:      cfg,
+		enabled:  cfg.IsDevelopment(),
+		services: make(map[string]func() (reflect.Value, error)),
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// WithContainer sets the DI container services are resolved from.
+func WithContainer(container *di.Container) func(*Console) {
+	return func(c *Console) {
+		c.container = container
+	}
+}
+
+// WithEventBus sets the bus the emit command publishes to.
+func WithEventBus(bus EventBus) func(*Console) {
+	return func(c *Console) {
+		c.bus = bus
+	}
+}
+
+// WithToken requires the token in the X-Console-Token header of requests to the HTTP handler.
+func WithToken(token string) func(*Console) {
+	return func(c *Console) {
+		c.token = token
+	}
+}
+
+// Enabled reports whether the console accepts commands.
+func (c *Console) Enabled() bool {
+	return c.enabled
+}
+
+// Expose makes a service instance available to the console under name.
+func (c *Console) Expose(name string, service interface{}) {
+	value := reflect.ValueOf(service)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.services[name] = func() (reflect.Value, error) { return value, nil }
+}
+
+// ExposeService makes a service registered in the DI container available under name. The type is
+// given by a typed nil: (*UserService)(nil) for a pointer type, or (*UserRepository)(nil) for an
+// interface type. The service is resolved from the container on every use.
+func (c *Console) ExposeService(name string, typ interface{}) {
+	serviceType := reflect.TypeOf(typ)
+	if serviceType.Kind() == reflect.Ptr && serviceType.Elem().Kind() == reflect.Interface {
+		serviceType = serviceType.Elem()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.services[name] = func() (reflect.Value, error) {
+		if c.container == nil {
+			return reflect.Value{}, fmt.Errorf("no DI container configured")
+		}
+		return resolve(c.container, serviceType)
+	}
+}
+
+// resolve asks the container for a value of the given type by invoking a function taking it.
+func resolve(container *di.Container, serviceType reflect.Type) (reflect.Value, error) {
+	var resolved reflect.Value
+	fnType := reflect.FuncOf([]reflect.Type{serviceType}, nil, false)
+	fn := reflect.MakeFunc(fnType, func(args []reflect.Value) []reflect.Value {
+		resolved = args[0]
+		return nil
+	})
+	if err := container.Invoke(fn.Interface()); err != nil {
+		return reflect.Value{}, err
+	}
+	return resolved, nil
+}
+
+// Execute runs a single command and returns its output.
+//
+// Commands:
+//
+//	help                              list commands
+//	services                          list exposed services
+//	methods <service>                 list the methods of a service
+//	call <service> <method> [args]    call a method, args are JSON values separated by spaces
+//	config [prefix]                   show configuration, secrets are masked
+//	emit <topic> [payload]            publish a JSON payload on the event bus
+func (c *Console) Execute(line string) (string, error) {
+	if !c.enabled {
+		return "", ErrDisabled
+	}
+	command, rest, _ := strings.Cut(strings.TrimSpace(line), " ")
+	rest = strings.TrimSpace(rest)
+
+	switch command {
+	case "", "help":
+		return strings.Join([]string{
+			"services                          list exposed services",
+			"methods <service>                 list the methods of a service",
+			"call <service> <method> [args]    call a method with JSON arguments",
+			"config [prefix]                   show configuration",
+			"emit <topic> [payload]            publish a JSON payload on the event bus",
+		}, "\n"), nil
+	case "services":
+		c.mu.RLock()
+		names := make([]string, 0, len(c.services))
+		for name := range c.services {
+			names = append(names, name)
+		}
+		c.mu.RUnlock()
+		sort.Strings(names)
+		return strings.Join(names, "\n"), nil
+	case "methods":
+		service, err := c.service(rest)
+		if err != nil {
+			return "", err
+		}
+		return methodList(service), nil
+	case "call":
+		return c.call(rest)
+	case "config":
+		return c.config(rest), nil
+	case "emit":
+		return c.emit(rest)
+	}
+	return "", fmt.Errorf("unknown command %q, try help", command)
+}
+
+func (c *Console) service(name string) (reflect.Value, error) {
+	c.mu.RLock()
+	resolve, ok := c.services[name]
+	c.mu.RUnlock()
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("unknown service %q", name)
+	}
+	return resolve()
+}
+
+// methodList describes the exported methods of a service.
+func methodList(service reflect.Value) string {
+	t := service.Type()
+	lines := make([]string, 0, t.NumMethod())
+	for i := 0; i < t.NumMethod(); i++ {
+		method := t.Method(i)
+		signature := strings.TrimPrefix(method.Type.String(), "func")
+		if t.Kind() != reflect.Interface {
+			// Drop the receiver from the signature
+			in := make([]string, 0, method.Type.NumIn())
+			for j := 1; j < method.Type.NumIn(); j++ {
+				in = append(in, method.Type.In(j).String())
+			}
+			out := make([]string, 0, method.Type.NumOut())
+			for j := 0; j < method.Type.NumOut(); j++ {
+				out = append(out, method.Type.Out(j).String())
+			}
+			signature = "(" + strings.Join(in, ", ") + ")"
+			if len(out) > 0 {
+				signature += " (" + strings.Join(out, ", ") + ")"
+			}
+		}
+		lines = append(lines, method.Name+signature)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (c *Console) call(args string) (result string, err error) {
+	// A panicking method must not take the application down with it
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			result, err = "", fmt.Errorf("method panicked: %v", recovered)
+		}
+	}()
+	fields := strings.SplitN(args, " ", 3)
+	if len(fields) < 2 {
+		return "", fmt.Errorf("usage: call <service> <method> [args]")
+	}
+	service, err := c.service(fields[0])
+	if err != nil {
+		return "", err
+	}
+	method := service.MethodByName(fields[1])
+	if !method.IsValid() {
+		return "", fmt.Errorf("service %s has no method %s", fields[0], fields[1])
+	}
+
+	var raw []json.RawMessage
+	if len(fields) == 3 {
+		decoder := json.NewDecoder(strings.NewReader(fields[2]))
+		for decoder.More() {
+			var arg json.RawMessage
+			if err := decoder.Decode(&arg); err != nil {
+				return "", fmt.Errorf("invalid JSON argument: %v", err)
+			}
+			raw = append(raw, arg)
+		}
+	}
+	methodType := method.Type()
+	if len(raw) != methodType.NumIn() {
+		return "", fmt.Errorf("%s expects %d arguments, got %d", fields[1], methodType.NumIn(), len(raw))
+	}
+	in := make([]reflect.Value, len(raw))
+	for i, arg := range raw {
+		value := reflect.New(methodType.In(i))
+		if err := json.Unmarshal(arg, value.Interface()); err != nil {
+			return "", fmt.Errorf("argument %d: %v", i+1, err)
+		}
+		in[i] = value.Elem()
+	}
+
+	out := method.Call(in)
+	results := make([]interface{}, len(out))
+	for i, value := range out {
+		results[i] = value.Interface()
+		if err, ok := results[i].(error); ok {
+			results[i] = "error: " + err.Error()
+		}
+	}
+	encoded, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return fmt.Sprint(results...), nil
+	}
+	return string(encoded), nil
+}
+
+// secretMarkers identify configuration keys whose values are masked.
+var secretMarkers = []string{"SECRET", "PASSWORD", "TOKEN", "KEY", "CREDENTIAL"}
+
+func (c *Console) config(prefix string) string {
+	keys := make([]string, 0, len(c.cfg))
+	for key := range c.cfg {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	lines := make([]string, 0, len(keys))
+	for _, key := range keys {
+		value := c.cfg[key]
+		upper := strings.ToUpper(key)
+		for _, marker := range secretMarkers {
+			if strings.Contains(upper, marker) && value != "" {
+				value = "********"
+				break
+			}
+		}
+		lines = append(lines, key+"="+value)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (c *Console) emit(args string) (string, error) {
+	if c.bus == nil {
+		return "", fmt.Errorf("no event bus configured")
+	}
+	topic, payload, _ := strings.Cut(args, " ")
+	if topic == "" {
+		return "", fmt.Errorf("usage: emit <topic> [payload]")
+	}
+	var data interface{}
+	if strings.TrimSpace(payload) != "" {
+		if err := json.Unmarshal([]byte(payload), &data); err != nil {
+			return "", fmt.Errorf("invalid JSON payload: %v", err)
+		}
+	}
+	if err := c.bus.Publish(topic, data); err != nil {
+		return "", err
+	}
+	return "published " + topic, nil
+}
+
+// Serve runs a read-eval-print loop over the given reader and writer until the input ends
+// or the user types exit.
+func (c *Console) Serve(in io.Reader, out io.Writer) error {
+	if !c.enabled {
+		return ErrDisabled
+	}
+	scanner := bufio.NewScanner(in)
+	fmt.Fprint(out, "> ")
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "exit" || line == "quit" {
+			return nil
+		}
+		result, err := c.Execute(line)
+		if err != nil {
+			fmt.Fprintf(out, "error: %v\n", err)
+		} else if result != "" {
+			fmt.Fprintln(out, result)
+		}
+		fmt.Fprint(out, "> ")
+	}
+	return scanner.Err()
+}
+
+// ListenUnix serves the console on a unix socket only accessible to the current user.
+func (c *Console) ListenUnix(path string) error {
+	if !c.enabled {
+		return ErrDisabled
+	}
+	os.Remove(path)
+	listener, err := listenUnix(path)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+	log.Printf("Dev console listening on %s", path)
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			c.Serve(conn, conn)
+		}()
+	}
+}
+
+// Handler returns an HTTP handler executing the command in the POST body. It answers 404
+// outside of development so the route does not reveal itself, and 403 to requests from another
+// origin, so a web page open in the browser of the developer cannot run commands, or without
+// the token of WithToken.
+func (c *Console) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !c.enabled {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !sameOrigin(r) || (c.token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get(TokenHeader)), []byte(c.token)) != 1) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		command, err := io.ReadAll(io.LimitReader(r.Body, 64<<10))
+		if err != nil {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		result, err := c.Execute(string(command))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "error: %v\n", err)
+			return
+		}
+		fmt.Fprintln(w, result)
+	}
+}
+
+// sameOrigin reports whether the request does not come from a page of another origin. Browsers
+// send Origin with every cross-origin POST; tools such as curl send neither header.
+func sameOrigin(r *http.Request) bool {
+	if site := r.Header.Get("Sec-Fetch-Site"); site != "" && site != "same-origin" && site != "none" {
+		return false
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	parsed, err := url.Parse(origin)
+	return err == nil && parsed.Host == r.Host
+}
@@ -0,0 +1,21 @@
+//go:build !unix
+
+package devconsole
+
+import (
+	"net"
+	"os"
+)
+
+// listenUnix creates the socket and restricts it to the current user where there is no umask.
+func listenUnix(path string) (net.Listener, error) {
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
@@ -0,0 +1,16 @@
+//go:build unix
+
+package devconsole
+
+import (
+	"net"
+	"syscall"
+)
+
+// listenUnix creates the socket with a restrictive umask, so it is never accessible to other
+// users, not even between its creation and a chmod.
+func listenUnix(path string) (net.Listener, error) {
+	old := syscall.Umask(0177)
+	defer syscall.Umask(old)
+	return net.Listen("unix", path)
+}
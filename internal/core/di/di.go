@@ -50,10 +50,14 @@ import (
 	"fmt"
 	"log"
 
+	"github.com/hokamsingh/lessgo/internal/core/cache"
 	"github.com/hokamsingh/lessgo/internal/core/controller"
 	scheduler "github.com/hokamsingh/lessgo/internal/core/job"
+	"github.com/hokamsingh/lessgo/internal/core/mailer"
+	"github.com/hokamsingh/lessgo/internal/core/messaging"
 	"github.com/hokamsingh/lessgo/internal/core/module"
 	"github.com/hokamsingh/lessgo/internal/core/router"
+	"github.com/hokamsingh/lessgo/internal/core/storage"
 	"go.uber.org/dig"
 )
 
@@ -64,15 +68,19 @@ type Container struct {
 }
 
 // NewContainer creates a new instance of `Container`.
-// This method initializes the underlying `dig.Container`.
+// This method initializes the underlying `dig.Container` and applies the given options.
 //
 // Example:
 //
-//	container := di.NewContainer()
-func NewContainer() *Container {
-	return &Container{
+//	container := di.NewContainer(di.WithMailer(mailer.ConfigFromEnv(cfg)))
+func NewContainer(options ...func(*Container)) *Container {
+	c := &Container{
 		container: dig.New(),
 	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
 }
 
 // Register adds a constructor or provider to the DI container.
@@ -147,6 +155,138 @@ func (c *Container) InvokeScheduler(fn func(scheduler.Scheduler) error) error {
 	})
 }
 
+// RegisterMailer registers a `*mailer.Mailer` built from the configuration in the DI container.
+// The mailer is created when it is first injected.
+//
+// Example:
+//
+//	container := di.NewContainer()
+//	err := container.RegisterMailer(mailer.ConfigFromEnv(cfg))
+//	if err != nil {
+//		log.Fatalf("Error registering mailer: %v", err)
+//	}
+//
+//	err = container.Invoke(func(m *mailer.Mailer) {
+//		m.SendAsync(&mailer.Message{To: []string{"ops@example.com"}, Subject: "Started", Text: "Up"})
+//	})
+func (c *Container) RegisterMailer(cfg mailer.Config, options ...func(*mailer.Mailer)) error {
+	return c.Register(func() (*mailer.Mailer, error) {
+		return mailer.New(cfg, options...)
+	})
+}
+
+// WithMailer registers a mailer when creating the container, see RegisterMailer.
+//
+// Example:
+//
+//	container := di.NewContainer(di.WithMailer(mailer.ConfigFromEnv(cfg)))
+func WithMailer(cfg mailer.Config, options ...func(*mailer.Mailer)) func(*Container) {
+	return func(c *Container) {
+		if err := c.RegisterMailer(cfg, options...); err != nil {
+			log.Fatalf("Error registering mailer: %v", err)
+		}
+	}
+}
+
+// RegisterCache registers a shared in-memory `*cache.Cache` in the DI container, for services
+// caching data in process.
+//
+// Example:
+//
+//	container := di.NewContainer()
+//	err := container.RegisterCache(cache.WithMaxBytes(128 << 20))
+//	if err != nil {
+//		log.Fatalf("Error registering cache: %v", err)
+//	}
+//
+//	err = container.Invoke(func(c *cache.Cache) {
+//		c.Set("greeting", []byte("hello"), time.Minute)
+//	})
+func (c *Container) RegisterCache(options ...func(*cache.Cache)) error {
+	return c.Register(func() *cache.Cache {
+		return cache.New(options...)
+	})
+}
+
+// WithCache registers an in-memory cache when creating the container, see RegisterCache.
+//
+// Example:
+//
+//	container := di.NewContainer(di.WithCache(cache.WithDefaultTTL(time.Minute)))
+func WithCache(options ...func(*cache.Cache)) func(*Container) {
+	return func(c *Container) {
+		if err := c.RegisterCache(options...); err != nil {
+			log.Fatalf("Error registering cache: %v", err)
+		}
+	}
+}
+
+// RegisterBroker registers a message broker in the DI container, along with a
+// `*messaging.Dispatcher` running on it, so services can publish and consume messages.
+//
+// Example:
+//
+//	container := di.NewContainer()
+//	if err := container.RegisterBroker(messaging.NewMemoryBroker()); err != nil {
+//		log.Fatalf("Error registering broker: %v", err)
+//	}
+//
+//	err := container.Invoke(func(d *messaging.Dispatcher) error {
+//		messaging.RegisterModules(d, modules)
+//		return d.Start()
+//	})
+func (c *Container) RegisterBroker(broker messaging.Broker) error {
+	if err := c.Register(func() messaging.Broker { return broker }); err != nil {
+		return err
+	}
+	return c.Register(func(broker messaging.Broker) *messaging.Dispatcher {
+		return messaging.NewDispatcher(broker)
+	})
+}
+
+// WithBroker registers a message broker when creating the container, see RegisterBroker.
+//
+// Example:
+//
+//	container := di.NewContainer(di.WithBroker(broker))
+func WithBroker(broker messaging.Broker) func(*Container) {
+	return func(c *Container) {
+		if err := c.RegisterBroker(broker); err != nil {
+			log.Fatalf("Error registering broker: %v", err)
+		}
+	}
+}
+
+// RegisterStorage registers the file storage of the application in the DI container, so
+// services read and write files without depending on the driver.
+//
+// Example:
+//
+//	container := di.NewContainer()
+//	if err := container.RegisterStorage(storage.NewGCSStorage("uploads", accessID, secret)); err != nil {
+//		log.Fatalf("Error registering storage: %v", err)
+//	}
+//
+//	err := container.Invoke(func(s storage.Storage) {
+//		s.Put(ctx, "reports/daily.csv", report, "text/csv")
+//	})
+func (c *Container) RegisterStorage(s storage.Storage) error {
+	return c.Register(func() storage.Storage { return s })
+}
+
+// WithStorage registers a file storage when creating the container, see RegisterStorage.
+//
+// Example:
+//
+//	container := di.NewContainer(di.WithStorage(store))
+func WithStorage(s storage.Storage) func(*Container) {
+	return func(c *Container) {
+		if err := c.RegisterStorage(s); err != nil {
+			log.Fatalf("Error registering storage: %v", err)
+		}
+	}
+}
+
 // RegisterDependencies registers dependencies into container
 func RegisterDependencies(dependencies []interface{}) {
 	container := NewContainer()
@@ -170,7 +310,9 @@ const (
 // RegisterModules iterates over a slice of modules and registers their routes.
 func RegisterModules(r *router.Router, modules []module.IModule) error {
 	for _, module := range modules {
-		controller.RegisterModuleRoutes(r, module)
+		r.RecordModule(module.GetName(), func() {
+			controller.RegisterModuleRoutes(r, module)
+		})
 		l := fmt.Sprintf("%sLessGo :: Registered module %s%s%s", Green, Yellow, module.GetName(), Reset)
 		log.Println(l)
 	}
@@ -0,0 +1,181 @@
+/*
+Package enrich adds the parsed user agent and the location of the client to every request.
+
+The middleware parses the User-Agent header into a browser, an operating system and a device
+class, and looks the client IP up in a pluggable GeoProvider, e.g. GeoRanges loaded from the
+CSV export of a GeoIP database. Handlers read the results with ctx.UserAgentInfo and
+ctx.Geo, the access log export records them, and routing decisions can rely on them.
+
+Usage:
+
+	file, _ := os.Open("/var/lib/geoip/networks.csv")
+	ranges, err := enrich.LoadGeoCSV(file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	App := LessGo.App(LessGo.WithEnrichment(enrich.WithGeoProvider(ranges)))
+	App.Get("/", func(ctx *LessGo.Context) {
+		if geo, ok := ctx.Geo(); ok && geo.Country == "DE" {
+			// ...
+		}
+	})
+*/
+package enrich
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/cache"
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+)
+
+// Keys of the enrichment in the request values, see middleware.GetValue.
+const (
+	UserAgentKey = "enrich.user_agent"
+	GeoKey       = "enrich.geo"
+)
+
+// cdnCountryHeaders are set by CDNs and hosting platforms to the country of the client.
+var cdnCountryHeaders = []string{"CF-IPCountry", "CloudFront-Viewer-Country", "X-Vercel-IP-Country", "X-AppEngine-Country", "Fastly-Geo-Country"}
+
+// Middleware stores the parsed user agent and the location of the client with each request.
+type Middleware struct {
+	geo        GeoProvider
+	cdnHeaders bool
+	cache      *cache.Cache
+	cacheTTL   time.Duration
+	timeout    time.Duration
+}
+
+// New creates the enrichment middleware. Without a GeoProvider or CDN headers only the user
+// agent is parsed.
+func New(options ...func(*Middleware)) *Middleware {
+	m := &Middleware{cacheTTL: time.Hour, timeout: 100 * time.Millisecond}
+	for _, option := range options {
+		option(m)
+	}
+	if m.geo != nil && m.cacheTTL > 0 {
+		m.cache = cache.New(cache.WithMaxBytes(8 << 20))
+	}
+	return m
+}
+
+// WithGeoProvider locates clients with provider. Lookups are cached for an hour.
+func WithGeoProvider(provider GeoProvider) func(*Middleware) {
+	return func(m *Middleware) {
+		m.geo = provider
+	}
+}
+
+// WithGeoCacheTTL sets how long lookups are cached, zero disabling the cache for providers
+// that cache themselves.
+func WithGeoCacheTTL(ttl time.Duration) func(*Middleware) {
+	return func(m *Middleware) {
+		m.cacheTTL = ttl
+	}
+}
+
+// WithGeoTimeout bounds the time spent locating a client, 100ms by default, so a slow lookup
+// service does not delay requests. Requests are served without location on timeout.
+func WithGeoTimeout(timeout time.Duration) func(*Middleware) {
+	return func(m *Middleware) {
+		m.timeout = timeout
+	}
+}
+
+// WithCDNHeaders takes the country from the headers of CDNs such as Cloudflare
+// (CF-IPCountry) or CloudFront, when the request comes from a trusted proxy. The
+// GeoProvider is only consulted for the region and city.
+func WithCDNHeaders() func(*Middleware) {
+	return func(m *Middleware) {
+		m.cdnHeaders = true
+	}
+}
+
+func (m *Middleware) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r, values := middleware.RequestValues(r)
+		values.Set(UserAgentKey, ParseUserAgent(r.UserAgent()))
+		if location, ok := m.locate(r); ok {
+			values.Set(GeoKey, location)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// locate returns the location of the client from the CDN headers and the provider.
+func (m *Middleware) locate(r *http.Request) (GeoLocation, bool) {
+	var location GeoLocation
+	if m.cdnHeaders && middleware.FromTrustedProxy(r) {
+		for _, header := range cdnCountryHeaders {
+			// XX and T1 stand for unknown and Tor at Cloudflare
+			if country := strings.ToUpper(r.Header.Get(header)); len(country) == 2 && country != "XX" && country != "T1" {
+				location.Country = country
+				break
+			}
+		}
+	}
+	ip := net.ParseIP(middleware.ClientIP(r))
+	if m.geo == nil || ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() {
+		return location, location.Country != ""
+	}
+
+	key := ip.String()
+	if m.cache != nil {
+		if data, ok := m.cache.Get(key); ok {
+			var cached GeoLocation
+			if json.Unmarshal(data, &cached) == nil {
+				return merge(location, cached)
+			}
+		}
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), m.timeout)
+	defer cancel()
+	found, ok, err := m.geo.Lookup(ctx, ip)
+	if err != nil {
+		log.Printf("GeoIP lookup of %s failed: %v", key, err)
+		return location, location.Country != ""
+	}
+	if m.cache != nil {
+		// Unknown addresses are cached too, so they are not looked up on every request
+		data, _ := json.Marshal(found)
+		m.cache.Set(key, data, m.cacheTTL)
+	}
+	if !ok {
+		return location, location.Country != ""
+	}
+	return merge(location, found)
+}
+
+// merge completes the country from the CDN with the details of the provider, dropping
+// them when both disagree on the country.
+func merge(cdn, found GeoLocation) (GeoLocation, bool) {
+	if cdn.Country != "" && found.Country != cdn.Country {
+		return cdn, true
+	}
+	return found, found.Country != ""
+}
+
+// RequestUserAgent returns the parsed user agent of r, parsing the header when the
+// middleware did not run.
+func RequestUserAgent(r *http.Request) UserAgent {
+	if ua, ok := middleware.GetValue(r, UserAgentKey); ok {
+		return ua.(UserAgent)
+	}
+	return ParseUserAgent(r.UserAgent())
+}
+
+// RequestGeo returns the location of the client of r found by the middleware.
+func RequestGeo(r *http.Request) (GeoLocation, bool) {
+	location, ok := middleware.GetValue(r, GeoKey)
+	if !ok {
+		return GeoLocation{}, false
+	}
+	return location.(GeoLocation), true
+}
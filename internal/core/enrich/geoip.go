@@ -0,0 +1,159 @@
+package enrich
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// GeoLocation is where an IP address is located.
+type GeoLocation struct {
+	// Country is the ISO 3166-1 alpha-2 code, e.g. "DE".
+	Country   string  `json:"country,omitempty"`
+	Region    string  `json:"region,omitempty"`
+	City      string  `json:"city,omitempty"`
+	Latitude  float64 `json:"latitude,omitempty"`
+	Longitude float64 `json:"longitude,omitempty"`
+}
+
+// GeoProvider locates IP addresses, e.g. from a GeoIP database or a lookup service.
+type GeoProvider interface {
+	// Lookup returns the location of ip and false when it is unknown.
+	Lookup(ctx context.Context, ip net.IP) (GeoLocation, bool, error)
+}
+
+// GeoProviderFunc adapts a function to the GeoProvider interface.
+type GeoProviderFunc func(ctx context.Context, ip net.IP) (GeoLocation, bool, error)
+
+func (f GeoProviderFunc) Lookup(ctx context.Context, ip net.IP) (GeoLocation, bool, error) {
+	return f(ctx, ip)
+}
+
+type geoRange struct {
+	start, end net.IP
+	location   GeoLocation
+}
+
+// GeoRanges is an in-memory GeoProvider mapping IP networks to locations. Networks must not
+// overlap, as in the CSV exports of GeoIP databases.
+type GeoRanges struct {
+	mu     sync.RWMutex
+	ranges []geoRange
+	sorted bool
+}
+
+// NewGeoRanges creates an empty GeoRanges.
+func NewGeoRanges() *GeoRanges {
+	return &GeoRanges{sorted: true}
+}
+
+// Add maps the network cidr, or a single IP, to location.
+func (g *GeoRanges) Add(cidr string, location GeoLocation) error {
+	if !strings.Contains(cidr, "/") {
+		if ip := net.ParseIP(cidr); ip != nil && ip.To4() != nil {
+			cidr += "/32"
+		} else {
+			cidr += "/128"
+		}
+	}
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return err
+	}
+	start := network.IP.To16()
+	end := make(net.IP, len(start))
+	mask := network.Mask
+	if len(mask) == net.IPv4len {
+		// IPv4 masks cover the last 4 bytes of the 16 byte form
+		mask = append(net.CIDRMask(96, 128)[:12:12], mask...)
+	}
+	for i := range start {
+		end[i] = start[i] | ^mask[i]
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.ranges = append(g.ranges, geoRange{start: start, end: end, location: location})
+	g.sorted = false
+	return nil
+}
+
+// Len returns the number of networks.
+func (g *GeoRanges) Len() int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return len(g.ranges)
+}
+
+func (g *GeoRanges) Lookup(ctx context.Context, ip net.IP) (GeoLocation, bool, error) {
+	ip = ip.To16()
+	if ip == nil {
+		return GeoLocation{}, false, nil
+	}
+	g.mu.RLock()
+	if !g.sorted {
+		g.mu.RUnlock()
+		g.mu.Lock()
+		if !g.sorted {
+			sort.Slice(g.ranges, func(i, j int) bool { return bytes.Compare(g.ranges[i].start, g.ranges[j].start) < 0 })
+			g.sorted = true
+		}
+		g.mu.Unlock()
+		g.mu.RLock()
+	}
+	defer g.mu.RUnlock()
+	// The last range starting at or before ip is the only one that can contain it
+	i := sort.Search(len(g.ranges), func(i int) bool { return bytes.Compare(g.ranges[i].start, ip) > 0 }) - 1
+	if i < 0 || bytes.Compare(ip, g.ranges[i].end) > 0 {
+		return GeoLocation{}, false, nil
+	}
+	return g.ranges[i].location, true, nil
+}
+
+// LoadGeoCSV reads networks from CSV rows of network,country[,region,city[,latitude,longitude]].
+// A header row starting with "network" and rows starting with "#" are skipped.
+//
+// Example usage:
+//
+//	file, err := os.Open("/var/lib/geoip/networks.csv")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	ranges, err := enrich.LoadGeoCSV(file)
+func LoadGeoCSV(r io.Reader) (*GeoRanges, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+	reader.Comment = '#'
+	ranges := NewGeoRanges()
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return ranges, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) < 2 || row == 1 && record[0] == "network" {
+			continue
+		}
+		location := GeoLocation{Country: record[1]}
+		if len(record) >= 4 {
+			location.Region, location.City = record[2], record[3]
+		}
+		if len(record) >= 6 {
+			location.Latitude, _ = strconv.ParseFloat(record[4], 64)
+			location.Longitude, _ = strconv.ParseFloat(record[5], 64)
+		}
+		if err := ranges.Add(record[0], location); err != nil {
+			return nil, fmt.Errorf("enrich: row %d: %w", row, err)
+		}
+	}
+}
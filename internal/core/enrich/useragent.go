@@ -0,0 +1,161 @@
+package enrich
+
+import (
+	"strings"
+)
+
+// Device classes of user agents.
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+)
+
+// UserAgent is the parsed User-Agent header of a request.
+type UserAgent struct {
+	Browser        string `json:"browser,omitempty"`
+	BrowserVersion string `json:"browser_version,omitempty"`
+	OS             string `json:"os,omitempty"`
+	OSVersion      string `json:"os_version,omitempty"`
+	// Device is DeviceDesktop, DeviceMobile, DeviceTablet or DeviceBot, empty without header.
+	Device string `json:"device,omitempty"`
+	Bot    bool   `json:"bot,omitempty"`
+}
+
+// bots are substrings of crawler and HTTP library user agents, matched case-insensitively.
+var bots = []string{"bot", "crawler", "spider", "slurp", "facebookexternalhit", "curl/", "wget/", "python-requests", "python-urllib", "go-http-client", "okhttp", "java/", "headlesschrome", "lighthouse"}
+
+// browsers are checked in order, since most browsers also claim to be Chrome or Safari.
+var browsers = []struct{ token, name string }{
+	{"Edg/", "Edge"},
+	{"EdgA/", "Edge"},
+	{"EdgiOS/", "Edge"},
+	{"OPR/", "Opera"},
+	{"SamsungBrowser/", "Samsung Internet"},
+	{"YaBrowser/", "Yandex"},
+	{"Firefox/", "Firefox"},
+	{"FxiOS/", "Firefox"},
+	{"CriOS/", "Chrome"},
+	{"Chrome/", "Chrome"},
+	{"Chromium/", "Chromium"},
+	{"Trident/", "Internet Explorer"},
+	{"MSIE ", "Internet Explorer"},
+}
+
+var windowsVersions = map[string]string{"10.0": "10", "6.3": "8.1", "6.2": "8", "6.1": "7", "6.0": "Vista", "5.1": "XP"}
+
+// ParseUserAgent extracts the browser, operating system and device class of a User-Agent
+// header. It recognizes the common browsers and crawlers, not every agent in the wild.
+//
+// Example usage:
+//
+//	ua := enrich.ParseUserAgent(r.UserAgent())
+//	if ua.Device == enrich.DeviceMobile {
+//		// ...
+//	}
+func ParseUserAgent(header string) UserAgent {
+	var ua UserAgent
+	if header == "" {
+		return ua
+	}
+	lower := strings.ToLower(header)
+	for _, bot := range bots {
+		if strings.Contains(lower, bot) {
+			ua.Bot = true
+			ua.Device = DeviceBot
+			ua.Browser, ua.BrowserVersion = botName(header)
+			break
+		}
+	}
+
+	if !ua.Bot {
+		for _, browser := range browsers {
+			if version, ok := versionAfter(header, browser.token); ok {
+				ua.Browser, ua.BrowserVersion = browser.name, version
+				break
+			}
+		}
+		if ua.Browser == "" && strings.Contains(header, "Safari/") {
+			ua.Browser = "Safari"
+			ua.BrowserVersion, _ = versionAfter(header, "Version/")
+		}
+		if ua.Browser == "Internet Explorer" && strings.Contains(header, "Trident/") {
+			ua.BrowserVersion, _ = versionAfter(header, "rv:")
+		}
+	}
+
+	switch {
+	case strings.Contains(header, "Windows NT "):
+		ua.OS = "Windows"
+		version, _ := versionAfter(header, "Windows NT ")
+		ua.OSVersion = windowsVersions[version]
+	case strings.Contains(header, "iPhone") || strings.Contains(header, "iPad") || strings.Contains(header, "iPod"):
+		ua.OS = "iOS"
+		version, ok := versionAfter(header, "iPhone OS ")
+		if !ok {
+			version, _ = versionAfter(header, "CPU OS ")
+		}
+		ua.OSVersion = strings.ReplaceAll(version, "_", ".")
+	case strings.Contains(header, "Android"):
+		ua.OS = "Android"
+		ua.OSVersion, _ = versionAfter(header, "Android ")
+	case strings.Contains(header, "CrOS"):
+		ua.OS = "ChromeOS"
+	case strings.Contains(header, "Mac OS X"):
+		ua.OS = "macOS"
+		version, _ := versionAfter(header, "Mac OS X ")
+		ua.OSVersion = strings.ReplaceAll(version, "_", ".")
+	case strings.Contains(header, "Linux"):
+		ua.OS = "Linux"
+	}
+
+	if ua.Device == "" {
+		switch {
+		case strings.Contains(header, "iPad") || strings.Contains(lower, "tablet") || ua.OS == "Android" && !strings.Contains(header, "Mobile"):
+			ua.Device = DeviceTablet
+		case strings.Contains(header, "Mobi") || strings.Contains(header, "iPhone") || strings.Contains(header, "iPod"):
+			ua.Device = DeviceMobile
+		default:
+			ua.Device = DeviceDesktop
+		}
+	}
+	return ua
+}
+
+// versionAfter returns the version following token, up to the next space, semicolon or
+// parenthesis.
+func versionAfter(header, token string) (string, bool) {
+	i := strings.Index(header, token)
+	if i < 0 {
+		return "", false
+	}
+	rest := header[i+len(token):]
+	if end := strings.IndexAny(rest, " ;)"); end >= 0 {
+		rest = rest[:end]
+	}
+	return rest, true
+}
+
+// botName returns the product token of a crawler, e.g. Googlebot from
+// "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)".
+func botName(header string) (string, string) {
+	for _, field := range strings.FieldsFunc(header, func(r rune) bool { return r == ' ' || r == ';' || r == '(' || r == ')' }) {
+		name, version, _ := strings.Cut(field, "/")
+		lower := strings.ToLower(name)
+		if name == "Mozilla" || strings.HasPrefix(lower, "+http") || lower == "compatible" {
+			continue
+		}
+		for _, bot := range bots {
+			if strings.Contains(lower+"/", bot) {
+				return name, version
+			}
+		}
+	}
+	fields := strings.Fields(header)
+	if len(fields) == 0 {
+		return "", ""
+	}
+	name, version, _ := strings.Cut(fields[0], "/")
+	return name, version
+}
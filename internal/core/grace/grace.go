@@ -0,0 +1,283 @@
+/*
+Package grace restarts servers without dropping connections.
+
+On SIGHUP the running process starts a new copy of its binary and hands over its listening
+sockets as inherited file descriptors. Once the new process serves, it asks the old one to
+stop with SIGTERM; the old process stops accepting, finishes its in-flight requests and exits.
+The listening socket is never closed, so deployments without an orchestrator can replace the
+binary and send SIGHUP. SIGINT and SIGTERM shut down gracefully.
+
+The new process gets a new PID, so supervisors must not track the PID of the first process
+(e.g. systemd with Type=simple would treat the exit of the old process as a crash). With
+WithReusePort the sockets are opened with SO_REUSEPORT, which also allows a second copy to
+be started independently while the first one drains.
+
+Listen accepts TCP addresses, unix domain sockets (unix:///var/run/app.sock) and sockets
+passed by systemd socket activation (systemd or systemd:name), so all of them survive restarts.
+
+Usage:
+
+	app := grace.New(grace.WithShutdownTimeout(30 * time.Second))
+	app.Serve(&http.Server{Addr: ":8080", Handler: handler})
+	if err := app.Run(); err != nil {
+		log.Fatal(err)
+	}
+*/
+package grace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// listenFDsEnv lists the addresses of the inherited listeners, passed as fd 3 onwards.
+	listenFDsEnv = "LESSGO_LISTEN_FDS"
+	// parentEnv holds the PID of the process to stop once the new one serves.
+	parentEnv = "LESSGO_GRACE_PARENT"
+)
+
+type server struct {
+	*http.Server
+	certFile, keyFile string
+	listener          net.Listener
+}
+
+// App serves HTTP servers and restarts them on SIGHUP without closing their listeners.
+type App struct {
+	servers         []*server
+	shutdownTimeout time.Duration
+	reusePort       bool
+	socketMode      os.FileMode
+	restart         chan struct{}
+}
+
+// New creates an App. In-flight requests get 30 seconds to finish on shutdown unless
+// WithShutdownTimeout is given.
+func New(options ...func(*App)) *App {
+	a := &App{shutdownTimeout: 30 * time.Second, restart: make(chan struct{}, 1)}
+	for _, option := range options {
+		option(a)
+	}
+	return a
+}
+
+// WithShutdownTimeout sets how long in-flight requests may take to finish on shutdown.
+func WithShutdownTimeout(timeout time.Duration) func(*App) {
+	return func(a *App) {
+		a.shutdownTimeout = timeout
+	}
+}
+
+// WithReusePort opens new listeners with SO_REUSEPORT where the platform supports it.
+func WithReusePort() func(*App) {
+	return func(a *App) {
+		a.reusePort = true
+	}
+}
+
+// WithSocketMode sets the permissions of unix domain socket files.
+func WithSocketMode(mode os.FileMode) func(*App) {
+	return func(a *App) {
+		a.socketMode = mode
+	}
+}
+
+// Serve adds a server listening on its Addr, in any form accepted by Listen.
+func (a *App) Serve(srv *http.Server) {
+	a.servers = append(a.servers, &server{Server: srv})
+}
+
+// ServeTLS adds a server listening on its Addr with TLS.
+func (a *App) ServeTLS(srv *http.Server, certFile, keyFile string) {
+	a.servers = append(a.servers, &server{Server: srv, certFile: certFile, keyFile: keyFile})
+}
+
+// Restart starts a new process taking over the listeners, as on SIGHUP.
+func (a *App) Restart() {
+	select {
+	case a.restart <- struct{}{}:
+	default:
+	}
+}
+
+// Inherited reports whether the process took over its listeners from a previous one.
+func Inherited() bool {
+	return os.Getenv(listenFDsEnv) != ""
+}
+
+// Run listens on the addresses of all servers, reusing inherited sockets, and serves until
+// the process is told to stop. It returns after in-flight requests have finished.
+func (a *App) Run() error {
+	if len(a.servers) == 0 {
+		return errors.New("grace: no servers to run")
+	}
+	for _, srv := range a.servers {
+		listener, err := a.listen(srv.Addr)
+		if err != nil {
+			a.closeListeners()
+			return err
+		}
+		srv.listener = listener
+	}
+
+	errs := make(chan error, len(a.servers))
+	for _, srv := range a.servers {
+		go func(srv *server) {
+			var err error
+			if srv.certFile != "" {
+				err = srv.ServeTLS(srv.listener, srv.certFile, srv.keyFile)
+			} else {
+				err = srv.Serve(srv.listener)
+			}
+			if !errors.Is(err, http.ErrServerClosed) {
+				errs <- err
+			}
+		}(srv)
+	}
+	stopParent()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	for {
+		select {
+		case err := <-errs:
+			a.shutdown()
+			return err
+		case sig := <-signals:
+			if sig != syscall.SIGHUP {
+				return a.shutdown()
+			}
+			a.Restart()
+		case <-a.restart:
+			if err := a.startChild(); err != nil {
+				log.Printf("Restart failed, continuing to serve: %v", err)
+				continue
+			}
+			// The new process sends SIGTERM once it serves
+			log.Printf("Started new process, waiting for it to take over")
+		}
+	}
+}
+
+// listen returns the inherited listener for addr or opens a new one with Listen.
+func (a *App) listen(addr string) (net.Listener, error) {
+	for i, inherited := range strings.Split(os.Getenv(listenFDsEnv), ",") {
+		if inherited != addr {
+			continue
+		}
+		file := os.NewFile(uintptr(3+i), addr)
+		if file == nil {
+			break
+		}
+		defer file.Close()
+		listener, err := net.FileListener(file)
+		if err != nil {
+			return nil, fmt.Errorf("grace: inheriting listener for %s: %w", addr, err)
+		}
+		if unix, ok := listener.(*net.UnixListener); ok {
+			unix.SetUnlinkOnClose(false)
+		}
+		return listener, nil
+	}
+
+	listener, err := Listen(addr, ListenOptions{SocketMode: a.socketMode, ReusePort: a.reusePort})
+	if err != nil {
+		return nil, err
+	}
+	if unix, ok := listener.(*net.UnixListener); ok {
+		// The socket file must outlive this process when a restarted one takes over
+		unix.SetUnlinkOnClose(false)
+	}
+	return listener, nil
+}
+
+// startChild starts the binary again, passing the listeners as fd 3 onwards.
+func (a *App) startChild() error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	addrs := make([]string, 0, len(a.servers))
+	files := make([]*os.File, 0, len(a.servers))
+	defer func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}()
+	for _, srv := range a.servers {
+		filer, ok := srv.listener.(interface{ File() (*os.File, error) })
+		if !ok {
+			return fmt.Errorf("grace: listener for %s cannot be passed on", srv.Addr)
+		}
+		file, err := filer.File()
+		if err != nil {
+			return err
+		}
+		files = append(files, file)
+		addrs = append(addrs, srv.Addr)
+	}
+
+	env := make([]string, 0, len(os.Environ())+2)
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, listenFDsEnv+"=") && !strings.HasPrefix(kv, parentEnv+"=") {
+			env = append(env, kv)
+		}
+	}
+	env = append(env, listenFDsEnv+"="+strings.Join(addrs, ","), parentEnv+"="+strconv.Itoa(os.Getpid()))
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.Env = env
+	cmd.ExtraFiles = files
+	return cmd.Start()
+}
+
+// stopParent asks the process that started this one to stop, now that this one serves.
+func stopParent() {
+	pid, err := strconv.Atoi(os.Getenv(parentEnv))
+	if err != nil || pid != os.Getppid() {
+		return
+	}
+	if process, err := os.FindProcess(pid); err == nil {
+		process.Signal(syscall.SIGTERM)
+	}
+}
+
+// shutdown stops accepting connections and waits for in-flight requests to finish.
+func (a *App) shutdown() error {
+	ctx, cancel := context.WithTimeout(context.Background(), a.shutdownTimeout)
+	defer cancel()
+	var wg sync.WaitGroup
+	errs := make([]error, len(a.servers))
+	for i, srv := range a.servers {
+		wg.Add(1)
+		go func(i int, srv *server) {
+			defer wg.Done()
+			errs[i] = srv.Shutdown(ctx)
+		}(i, srv)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+func (a *App) closeListeners() {
+	for _, srv := range a.servers {
+		if srv.listener != nil {
+			srv.listener.Close()
+		}
+	}
+}
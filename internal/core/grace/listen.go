@@ -0,0 +1,90 @@
+package grace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ListenOptions configures the listeners opened by Listen.
+type ListenOptions struct {
+	// SocketMode sets the permissions of unix domain socket files, e.g. 0660 so only the
+	// group of the reverse proxy can connect. Zero keeps the umask default.
+	SocketMode os.FileMode
+	// ReusePort opens TCP listeners with SO_REUSEPORT where the platform supports it.
+	ReusePort bool
+}
+
+// Listen opens a listener for addr, which is one of:
+//
+//	:8080, 127.0.0.1:8080    a TCP address
+//	unix:///var/run/app.sock a unix domain socket, replacing a stale socket file
+//	systemd, systemd:web     the first, or the named, socket passed by systemd socket activation
+func Listen(addr string, options ListenOptions) (net.Listener, error) {
+	switch {
+	case strings.HasPrefix(addr, "unix://"):
+		return listenUnix(strings.TrimPrefix(addr, "unix://"), options.SocketMode)
+	case addr == "systemd" || strings.HasPrefix(addr, "systemd:"):
+		return listenSystemd(strings.TrimPrefix(strings.TrimPrefix(addr, "systemd"), ":"))
+	}
+	if addr == "" {
+		addr = ":http"
+	}
+	config := net.ListenConfig{}
+	if options.ReusePort {
+		config.Control = reusePort
+	}
+	return config.Listen(context.Background(), "tcp", addr)
+}
+
+func listenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	if path == "" {
+		return nil, errors.New("grace: unix socket address without path")
+	}
+	// A socket file left by a crashed process would make the listen fail
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("grace: unix socket %s is in use", path)
+		}
+		os.Remove(path)
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if mode != 0 {
+		if err := os.Chmod(path, mode); err != nil {
+			listener.Close()
+			return nil, err
+		}
+	}
+	return listener, nil
+}
+
+// systemd passes activated sockets as fd 3 onwards, see sd_listen_fds(3).
+const systemdFirstFD = 3
+
+func listenSystemd(name string) (net.Listener, error) {
+	if pid, err := strconv.Atoi(os.Getenv("LISTEN_PID")); err != nil || pid != os.Getpid() {
+		return nil, errors.New("grace: no sockets passed by systemd")
+	}
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count < 1 {
+		return nil, errors.New("grace: no sockets passed by systemd")
+	}
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	for i := 0; i < count; i++ {
+		if name != "" && (i >= len(names) || names[i] != name) {
+			continue
+		}
+		file := os.NewFile(uintptr(systemdFirstFD+i), "systemd:"+name)
+		defer file.Close()
+		return net.FileListener(file)
+	}
+	return nil, fmt.Errorf("grace: systemd passed no socket named %q", name)
+}
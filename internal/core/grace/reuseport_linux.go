@@ -0,0 +1,7 @@
+//go:build linux && !mips && !mipsle && !mips64 && !mips64le
+
+package grace
+
+// soReusePort is SO_REUSEPORT, which the syscall package does not define for most Linux
+// architectures.
+const soReusePort = 0xf
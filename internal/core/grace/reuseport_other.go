@@ -0,0 +1,10 @@
+//go:build !((linux && !mips && !mipsle && !mips64 && !mips64le) || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package grace
+
+import "syscall"
+
+// reusePort is a no-op where SO_REUSEPORT is not available; restarts still inherit sockets.
+func reusePort(network, address string, conn syscall.RawConn) error {
+	return nil
+}
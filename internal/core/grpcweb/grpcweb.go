@@ -0,0 +1,481 @@
+/*
+Package grpcweb makes gRPC services callable from browsers over fetch, without a proxy.
+
+Browsers cannot speak gRPC: it needs HTTP/2 trailers, which fetch does not expose. The
+Translator sits on the HTTP router in front of a gRPC server that implements http.Handler,
+such as *grpc.Server, and translates two browser friendly protocols to gRPC and back:
+
+  - gRPC-Web (application/grpc-web and application/grpc-web-text), used by grpc-web and
+    protobuf-ts clients. Trailers are sent as the last frame of the body.
+  - Connect unary calls (application/proto and application/json with a
+    Connect-Protocol-Version header), used by connect-web clients. Messages are sent
+    unframed and errors as JSON with an HTTP status.
+
+Other requests go to the next handler, so the services and the REST API share one port.
+
+Usage:
+
+	server := grpc.NewServer()
+	pb.RegisterGreeterServer(server, &greeter{})
+	App := LessGo.App(LessGo.WithGRPCWeb(server))
+*/
+package grpcweb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Frame flags of the gRPC length-prefixed message format.
+const (
+	flagCompressed = 0x01
+	flagTrailer    = 0x80
+)
+
+// DefaultMaxMessageSize is the largest Connect message read by default.
+const DefaultMaxMessageSize = 4 << 20
+
+// Translator translates gRPC-Web and Connect requests to gRPC requests to a gRPC server.
+type Translator struct {
+	grpc           http.Handler
+	services       []string
+	maxMessageSize int64
+	connect        bool
+}
+
+// New creates a Translator in front of the gRPC server.
+func New(grpc http.Handler, options ...func(*Translator)) *Translator {
+	t := &Translator{grpc: grpc, maxMessageSize: DefaultMaxMessageSize, connect: true}
+	for _, option := range options {
+		option(t)
+	}
+	return t
+}
+
+// WithServices limits translation to the fully qualified services, e.g. "helloworld.Greeter".
+// By default every gRPC-Web and Connect request is translated.
+func WithServices(services ...string) func(*Translator) {
+	return func(t *Translator) {
+		t.services = append(t.services, services...)
+	}
+}
+
+// WithMaxMessageSize sets the largest Connect request and response message in bytes,
+// DefaultMaxMessageSize by default.
+func WithMaxMessageSize(size int64) func(*Translator) {
+	return func(t *Translator) {
+		if size > 0 {
+			t.maxMessageSize = size
+		}
+	}
+}
+
+// WithoutConnect only translates gRPC-Web, leaving Connect requests to the next handler.
+func WithoutConnect() func(*Translator) {
+	return func(t *Translator) {
+		t.connect = false
+	}
+}
+
+// Handle translates gRPC-Web and Connect requests and passes other requests to next.
+func (t *Translator) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case !t.serves(r.URL.Path):
+			next.ServeHTTP(w, r)
+		case IsGRPCWebRequest(r):
+			t.serveGRPCWeb(w, r)
+		case t.connect && IsConnectRequest(r):
+			t.serveConnect(w, r)
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// ServeHTTP translates the request, for mounting the translator on the paths of the services.
+func (t *Translator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	t.Handle(http.NotFoundHandler()).ServeHTTP(w, r)
+}
+
+// IsGRPCWebRequest reports whether r is a gRPC-Web call.
+func IsGRPCWebRequest(r *http.Request) bool {
+	return r.Method == http.MethodPost && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc-web")
+}
+
+// IsConnectRequest reports whether r is a Connect unary call. Only requests with the
+// Connect-Protocol-Version header are translated, so JSON APIs on the same paths are not.
+func IsConnectRequest(r *http.Request) bool {
+	if r.Method != http.MethodPost || r.Header.Get("Connect-Protocol-Version") == "" {
+		return false
+	}
+	switch mediaType(r.Header.Get("Content-Type")) {
+	case "application/proto", "application/json":
+		return true
+	}
+	return false
+}
+
+// serves reports whether path is a method of one of the translated services.
+func (t *Translator) serves(path string) bool {
+	if len(t.services) == 0 {
+		return true
+	}
+	for _, service := range t.services {
+		if strings.HasPrefix(path, "/"+service+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// grpcRequest turns r into a gRPC request with the body and content type.
+func grpcRequest(r *http.Request, body io.Reader, contentType string) *http.Request {
+	req := r.Clone(r.Context())
+	req.Body = io.NopCloser(body)
+	req.ContentLength = -1
+	// gRPC servers refuse requests that do not look like HTTP/2
+	req.Proto, req.ProtoMajor, req.ProtoMinor = "HTTP/2", 2, 0
+	req.Header.Del("Content-Length")
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Te", "trailers")
+	return req
+}
+
+// trailers returns the trailers the gRPC server set in header: those announced in the
+// Trailer header, those with the http.TrailerPrefix and, for trailers-only responses
+// written before WriteHeader, the status.
+func trailers(header http.Header, announced []string, wroteHeader bool) http.Header {
+	trailer := http.Header{}
+	for _, key := range announced {
+		key = strings.TrimSpace(key)
+		if values, ok := header[http.CanonicalHeaderKey(key)]; ok {
+			trailer[http.CanonicalHeaderKey(key)] = values
+		}
+	}
+	for key, values := range header {
+		if name, ok := strings.CutPrefix(key, http.TrailerPrefix); ok {
+			trailer[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+	if !wroteHeader {
+		for _, key := range []string{"Grpc-Status", "Grpc-Message", "Grpc-Status-Details-Bin"} {
+			if values, ok := header[key]; ok {
+				trailer[key] = values
+			}
+		}
+	}
+	return trailer
+}
+
+// trailerFrame encodes the trailers as the last frame of a gRPC-Web body.
+func trailerFrame(trailer http.Header) []byte {
+	var block bytes.Buffer
+	for key, values := range trailer {
+		for _, value := range values {
+			fmt.Fprintf(&block, "%s: %s\r\n", strings.ToLower(key), value)
+		}
+	}
+	frame := make([]byte, 5, 5+block.Len())
+	frame[0] = flagTrailer
+	binary.BigEndian.PutUint32(frame[1:], uint32(block.Len()))
+	return append(frame, block.Bytes()...)
+}
+
+func mediaType(contentType string) string {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	return strings.TrimSpace(strings.ToLower(mediaType))
+}
+
+// serveGRPCWeb streams the call: frames are passed through as they are written, and the
+// trailers are appended as a frame once the gRPC server returns.
+func (t *Translator) serveGRPCWeb(w http.ResponseWriter, r *http.Request) {
+	contentType := r.Header.Get("Content-Type")
+	text := strings.HasPrefix(contentType, "application/grpc-web-text")
+	subtype := strings.TrimPrefix(strings.TrimPrefix(contentType, "application/grpc-web-text"), "application/grpc-web")
+
+	var body io.Reader = r.Body
+	if text {
+		body = base64.NewDecoder(base64.StdEncoding, r.Body)
+	}
+	rw := &webWriter{w: w, header: http.Header{}, text: text, contentType: contentType}
+	t.grpc.ServeHTTP(rw, grpcRequest(r, body, "application/grpc"+subtype))
+	rw.finish()
+}
+
+// webWriter translates the response of the gRPC server to gRPC-Web.
+type webWriter struct {
+	w           http.ResponseWriter
+	header      http.Header
+	announced   []string
+	text        bool
+	contentType string
+	wroteHeader bool
+}
+
+func (rw *webWriter) Header() http.Header {
+	return rw.header
+}
+
+func (rw *webWriter) WriteHeader(status int) {
+	if rw.wroteHeader {
+		return
+	}
+	rw.wroteHeader = true
+	rw.announced = strings.Split(strings.Join(rw.header.Values("Trailer"), ","), ",")
+	header := rw.w.Header()
+	for key, values := range rw.header {
+		if key == "Trailer" || strings.HasPrefix(key, http.TrailerPrefix) {
+			continue
+		}
+		header[key] = values
+	}
+	header.Set("Content-Type", rw.contentType)
+	header.Del("Content-Length")
+	// Browsers only let clients read the headers they are told about
+	header.Add("Access-Control-Expose-Headers", "Grpc-Status, Grpc-Message, Grpc-Status-Details-Bin")
+	rw.w.WriteHeader(status)
+}
+
+func (rw *webWriter) Write(data []byte) (int, error) {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	if rw.text {
+		// Each write is encoded on its own, clients decode the padded chunks one by one
+		if _, err := io.WriteString(rw.w, base64.StdEncoding.EncodeToString(data)); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+	return rw.w.Write(data)
+}
+
+func (rw *webWriter) Flush() {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	if flusher, ok := rw.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// finish writes the trailers frame.
+func (rw *webWriter) finish() {
+	trailer := trailers(rw.header, rw.announced, rw.wroteHeader)
+	if !rw.wroteHeader {
+		// A trailers-only response, sent as headers by the gRPC server
+		for key := range trailer {
+			delete(rw.header, key)
+		}
+		rw.WriteHeader(http.StatusOK)
+	}
+	if len(trailer) > 0 {
+		rw.Write(trailerFrame(trailer))
+	}
+	rw.Flush()
+}
+
+// serveConnect frames the unary request message for the gRPC server and unframes the
+// response message, or answers with a Connect error.
+func (t *Translator) serveConnect(w http.ResponseWriter, r *http.Request) {
+	contentType := mediaType(r.Header.Get("Content-Type"))
+	codec := "proto"
+	if contentType == "application/json" {
+		codec = "json"
+	}
+
+	var body io.Reader = http.MaxBytesReader(w, r.Body, t.maxMessageSize)
+	switch encoding := r.Header.Get("Content-Encoding"); encoding {
+	case "", "identity":
+	case "gzip":
+		reader, err := gzip.NewReader(body)
+		if err != nil {
+			writeConnectError(w, codeInvalidArgument, "invalid gzip body")
+			return
+		}
+		defer reader.Close()
+		body = io.LimitReader(reader, t.maxMessageSize+1)
+	default:
+		writeConnectError(w, codeUnimplemented, "unsupported content encoding "+strconv.Quote(encoding))
+		return
+	}
+	message, err := io.ReadAll(body)
+	if err != nil {
+		writeConnectError(w, codeInvalidArgument, "failed to read request: "+err.Error())
+		return
+	}
+	if int64(len(message)) > t.maxMessageSize {
+		writeConnectError(w, codeResourceExhausted, "request message too large")
+		return
+	}
+	frame := make([]byte, 5, 5+len(message))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(message)))
+	frame = append(frame, message...)
+
+	req := grpcRequest(r, bytes.NewReader(frame), "application/grpc+"+codec)
+	req.Header.Del("Content-Encoding")
+	req.Header.Del("Connect-Protocol-Version")
+	if timeout := req.Header.Get("Connect-Timeout-Ms"); timeout != "" {
+		req.Header.Del("Connect-Timeout-Ms")
+		req.Header.Set("Grpc-Timeout", timeout+"m")
+	}
+
+	rw := &bufferWriter{header: http.Header{}}
+	t.grpc.ServeHTTP(rw, req)
+	trailer := trailers(rw.header, rw.announced, rw.wroteHeader)
+
+	header := w.Header()
+	for key, values := range rw.header {
+		if key == "Trailer" || key == "Content-Type" || key == "Content-Length" || strings.HasPrefix(key, http.TrailerPrefix) || strings.HasPrefix(key, "Grpc-") {
+			continue
+		}
+		header[key] = values
+	}
+	// Connect sends unary trailers as headers with a Trailer- prefix
+	for key, values := range trailer {
+		if !strings.HasPrefix(key, "Grpc-") {
+			header["Trailer-"+key] = values
+		}
+	}
+
+	code := codeUnknown
+	if rw.status != 0 && rw.status != http.StatusOK {
+		writeConnectError(w, codeUnavailable, fmt.Sprintf("gRPC server responded with HTTP %d", rw.status))
+		return
+	}
+	if status := trailer.Get("Grpc-Status"); status != "" {
+		code, err = strconv.Atoi(status)
+		if err != nil {
+			code = codeUnknown
+		}
+	}
+	if code != codeOK {
+		message, _ := url.PathUnescape(trailer.Get("Grpc-Message"))
+		writeConnectError(w, code, message)
+		return
+	}
+	response, err := unframe(rw.body.Bytes())
+	if err != nil {
+		writeConnectError(w, codeInternal, err.Error())
+		return
+	}
+	header.Set("Content-Type", contentType)
+	header.Set("Content-Length", strconv.Itoa(len(response)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
+
+// unframe returns the message of a unary gRPC response body.
+func unframe(body []byte) ([]byte, error) {
+	if len(body) < 5 {
+		return nil, errors.New("missing response message")
+	}
+	if body[0]&flagCompressed != 0 {
+		return nil, errors.New("compressed response messages are not supported")
+	}
+	size := binary.BigEndian.Uint32(body[1:5])
+	if uint64(len(body)-5) < uint64(size) {
+		return nil, errors.New("truncated response message")
+	}
+	return body[5 : 5+size], nil
+}
+
+// bufferWriter keeps the response of a unary call.
+type bufferWriter struct {
+	header      http.Header
+	announced   []string
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rw *bufferWriter) Header() http.Header {
+	return rw.header
+}
+
+func (rw *bufferWriter) WriteHeader(status int) {
+	if !rw.wroteHeader {
+		rw.wroteHeader = true
+		rw.status = status
+		rw.announced = strings.Split(strings.Join(rw.header.Values("Trailer"), ","), ",")
+	}
+}
+
+func (rw *bufferWriter) Write(data []byte) (int, error) {
+	rw.WriteHeader(http.StatusOK)
+	return rw.body.Write(data)
+}
+
+func (rw *bufferWriter) Flush() {}
+
+// gRPC status codes.
+const (
+	codeOK = iota
+	codeCanceled
+	codeUnknown
+	codeInvalidArgument
+	codeDeadlineExceeded
+	codeNotFound
+	codeAlreadyExists
+	codePermissionDenied
+	codeResourceExhausted
+	codeFailedPrecondition
+	codeAborted
+	codeOutOfRange
+	codeUnimplemented
+	codeInternal
+	codeUnavailable
+	codeDataLoss
+	codeUnauthenticated
+	numberOfStatusCodes
+)
+
+// connectCodes are the names and HTTP statuses of the gRPC status codes in Connect.
+var connectCodes = [numberOfStatusCodes]struct {
+	name   string
+	status int
+}{
+	codeOK:                 {"ok", http.StatusOK},
+	codeCanceled:           {"canceled", 499},
+	codeUnknown:            {"unknown", http.StatusInternalServerError},
+	codeInvalidArgument:    {"invalid_argument", http.StatusBadRequest},
+	codeDeadlineExceeded:   {"deadline_exceeded", http.StatusGatewayTimeout},
+	codeNotFound:           {"not_found", http.StatusNotFound},
+	codeAlreadyExists:      {"already_exists", http.StatusConflict},
+	codePermissionDenied:   {"permission_denied", http.StatusForbidden},
+	codeResourceExhausted:  {"resource_exhausted", http.StatusTooManyRequests},
+	codeFailedPrecondition: {"failed_precondition", http.StatusBadRequest},
+	codeAborted:            {"aborted", http.StatusConflict},
+	codeOutOfRange:         {"out_of_range", http.StatusBadRequest},
+	codeUnimplemented:      {"unimplemented", http.StatusNotImplemented},
+	codeInternal:           {"internal", http.StatusInternalServerError},
+	codeUnavailable:        {"unavailable", http.StatusServiceUnavailable},
+	codeDataLoss:           {"data_loss", http.StatusInternalServerError},
+	codeUnauthenticated:    {"unauthenticated", http.StatusUnauthorized},
+}
+
+// writeConnectError writes a Connect error.
+func writeConnectError(w http.ResponseWriter, code int, message string) {
+	if code <= codeOK || code >= numberOfStatusCodes {
+		code = codeUnknown
+	}
+	body := map[string]interface{}{"code": connectCodes[code].name}
+	if message != "" {
+		body["message"] = message
+	}
+	w.Header().Del("Content-Length")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(connectCodes[code].status)
+	json.NewEncoder(w).Encode(body)
+}
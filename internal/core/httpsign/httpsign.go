@@ -0,0 +1,326 @@
+/*
+Package httpsign signs and verifies service-to-service requests with HMAC-SHA256.
+
+The client signs the method, the path and query, a timestamp and the SHA-256 digest of the
+body, and sends them with the ID of its key in the X-Signature header:
+
+	X-Signature: keyId=orders-2024,ts=1700000000,digest=<base64 SHA-256>,sig=<base64 HMAC>
+
+The server looks the key up by its ID, so keys are rotated by adding the new key to the
+verifier, switching the clients to it, then removing the old key. Requests older or newer
+than the tolerance are rejected to limit replays.
+
+Usage:
+
+	// Server
+	verifier := httpsign.NewVerifier(map[string][]byte{
+		"orders-2024": []byte(cfg.Get("ORDERS_KEY_2024", "")),
+		"orders-2025": []byte(cfg.Get("ORDERS_KEY_2025", "")),
+	})
+	internal := App.SubRouter("/internal")
+	internal.Use(verifier)
+
+	// Client
+	signer := httpsign.NewSigner("orders-2025", []byte(os.Getenv("ORDERS_KEY_2025")))
+	client := &http.Client{Transport: signer.Transport(nil)}
+*/
+package httpsign
+
+import (
+	"bytes"
+	stdcontext "context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/crypto"
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+)
+
+// Header carries the signature of a request.
+const Header = "X-Signature"
+
+var (
+	// ErrMissingSignature is returned for requests without a signature header.
+	ErrMissingSignature = errors.New("httpsign: missing signature")
+	// ErrMalformedSignature is returned when the signature header cannot be parsed.
+	ErrMalformedSignature = errors.New("httpsign: malformed signature")
+	// ErrUnknownKey is returned when the request is signed with a key the verifier does not have.
+	ErrUnknownKey = errors.New("httpsign: unknown key")
+	// ErrInvalidSignature is returned when the signature or body digest does not match.
+	ErrInvalidSignature = errors.New("httpsign: invalid signature")
+	// ErrStaleTimestamp is returned when the request was signed outside of the tolerance.
+	ErrStaleTimestamp = errors.New("httpsign: timestamp outside of tolerance")
+)
+
+// stringToSign returns the canonical form of a request. The host is left out so requests stay
+// valid behind proxies that rewrite it.
+func stringToSign(method, requestURI string, timestamp int64, digest string) string {
+	return strings.Join([]string{strings.ToUpper(method), requestURI, strconv.FormatInt(timestamp, 10), digest}, "\n")
+}
+
+func computeSignature(secret []byte, payload string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func bodyDigest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// requestURI returns the path and query of r as sent on the wire.
+func requestURI(r *http.Request) string {
+	if r.URL.Opaque != "" {
+		return r.URL.Opaque
+	}
+	uri := r.URL.EscapedPath()
+	if uri == "" {
+		uri = "/"
+	}
+	if r.URL.RawQuery != "" {
+		uri += "?" + r.URL.RawQuery
+	}
+	return uri
+}
+
+// Signer signs outgoing requests with one key, or the primary key of a keyring.
+type Signer struct {
+	keyID   string
+	secret  []byte
+	keyring *crypto.Keyring
+	now     func() time.Time
+}
+
+// NewSigner creates a signer for the key identified by keyID.
+func NewSigner(keyID string, secret []byte) *Signer {
+	return &Signer{keyID: keyID, secret: secret, now: time.Now}
+}
+
+// NewKeyringSigner creates a signer using the primary key of keyring at the time of each
+// request, so rotating the keyring switches the signing key.
+func NewKeyringSigner(keyring *crypto.Keyring) *Signer {
+	return &Signer{keyring: keyring, now: time.Now}
+}
+
+// Sign sets the signature header of r. The body is read and replaced so it can still be sent.
+func (s *Signer) Sign(r *http.Request) error {
+	var body []byte
+	if r.Body != nil && r.Body != http.NoBody {
+		data, err := io.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			return fmt.Errorf("httpsign: reading body: %w", err)
+		}
+		body = data
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		r.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+		r.ContentLength = int64(len(body))
+	}
+	keyID, secret := s.keyID, s.secret
+	if s.keyring != nil {
+		key, ok := s.keyring.Primary()
+		if !ok {
+			return crypto.ErrNoKeys
+		}
+		keyID, secret = key.ID, key.Secret
+	}
+	timestamp := s.now().Unix()
+	digest := bodyDigest(body)
+	signature := computeSignature(secret, stringToSign(r.Method, requestURI(r), timestamp, digest))
+	r.Header.Set(Header, fmt.Sprintf("keyId=%s,ts=%d,digest=%s,sig=%s", keyID, timestamp, digest, signature))
+	return nil
+}
+
+// Transport returns a RoundTripper signing the requests sent through base, or through
+// http.DefaultTransport when base is nil.
+//
+// Example usage:
+//
+//	client := &http.Client{Transport: signer.Transport(nil), Timeout: 10 * time.Second}
+func (s *Signer) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{signer: s, base: base}
+}
+
+type transport struct {
+	signer *Signer
+	base   http.RoundTripper
+}
+
+func (t *transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	// A RoundTripper must not modify the caller's request
+	signed := r.Clone(r.Context())
+	if err := t.signer.Sign(signed); err != nil {
+		return nil, err
+	}
+	return t.base.RoundTrip(signed)
+}
+
+// Verifier checks the signature of incoming requests against a set of keys.
+type Verifier struct {
+	mu        sync.RWMutex
+	keys      map[string][]byte
+	keyring   *crypto.Keyring
+	tolerance time.Duration
+	maxBody   int64
+	now       func() time.Time
+}
+
+// NewVerifier creates a verifier accepting requests signed with any of keys, by key ID.
+func NewVerifier(keys map[string][]byte, options ...func(*Verifier)) *Verifier {
+	v := &Verifier{
+		keys:      make(map[string][]byte, len(keys)),
+		tolerance: 5 * time.Minute,
+		maxBody:   10 << 20,
+		now:       time.Now,
+	}
+	for id, secret := range keys {
+		v.keys[id] = secret
+	}
+	for _, option := range options {
+		option(v)
+	}
+	return v
+}
+
+// WithTolerance sets how far the signed timestamp may be from the server clock, 5 minutes
+// by default.
+func WithTolerance(tolerance time.Duration) func(*Verifier) {
+	return func(v *Verifier) {
+		v.tolerance = tolerance
+	}
+}
+
+// WithKeyring also accepts the keys of keyring, looked up by ID at each request.
+func WithKeyring(keyring *crypto.Keyring) func(*Verifier) {
+	return func(v *Verifier) {
+		v.keyring = keyring
+	}
+}
+
+// WithMaxBodySize limits the body Handle reads to verify the digest, 10 MB by default.
+func WithMaxBodySize(size int64) func(*Verifier) {
+	return func(v *Verifier) {
+		v.maxBody = size
+	}
+}
+
+// SetKey adds or replaces a key, for rotation without restart.
+func (v *Verifier) SetKey(id string, secret []byte) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.keys[id] = secret
+}
+
+// RemoveKey stops accepting requests signed with the key.
+func (v *Verifier) RemoveKey(id string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	delete(v.keys, id)
+}
+
+// Verify checks the signature of r and returns the ID of the key it was signed with. The body
+// stays readable by later handlers; Handle limits its size before calling Verify.
+func (v *Verifier) Verify(r *http.Request) (string, error) {
+	header := r.Header.Get(Header)
+	if header == "" {
+		return "", ErrMissingSignature
+	}
+	fields := map[string]string{}
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return "", ErrMalformedSignature
+		}
+		fields[key] = value
+	}
+	keyID, digest, signature := fields["keyId"], fields["digest"], fields["sig"]
+	timestamp, err := strconv.ParseInt(fields["ts"], 10, 64)
+	if err != nil || keyID == "" || digest == "" || signature == "" {
+		return "", ErrMalformedSignature
+	}
+
+	v.mu.RLock()
+	secret, ok := v.keys[keyID]
+	v.mu.RUnlock()
+	if !ok && v.keyring != nil {
+		var key crypto.Key
+		key, ok = v.keyring.Get(keyID)
+		secret = key.Secret
+	}
+	if !ok {
+		return keyID, ErrUnknownKey
+	}
+	uri := r.RequestURI
+	if uri == "" {
+		uri = requestURI(r)
+	}
+	expected := computeSignature(secret, stringToSign(r.Method, uri, timestamp, digest))
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return keyID, ErrInvalidSignature
+	}
+	if v.tolerance > 0 {
+		if age := v.now().Sub(time.Unix(timestamp, 0)); age > v.tolerance || age < -v.tolerance {
+			return keyID, ErrStaleTimestamp
+		}
+	}
+
+	// The digest is checked last so unsigned requests cannot make the server read large bodies
+	body, err := middleware.RawBody(r)
+	if err != nil {
+		return keyID, err
+	}
+	if !hmac.Equal([]byte(digest), []byte(bodyDigest(body))) {
+		return keyID, ErrInvalidSignature
+	}
+	return keyID, nil
+}
+
+type keyIDKey struct{}
+
+// KeyID returns the ID of the key a verified request was signed with, which identifies the
+// calling service.
+func KeyID(ctx stdcontext.Context) string {
+	id, _ := ctx.Value(keyIDKey{}).(string)
+	return id
+}
+
+// Handle rejects requests without a valid signature with 401, or 413 when the body exceeds
+// the limit.
+func (v *Verifier) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = http.MaxBytesReader(w, r.Body, v.maxBody)
+		}
+		keyID, err := v.Verify(r)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) || errors.Is(err, middleware.ErrBodyTooLarge) {
+			http.Error(w, "Request Entity Too Large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil {
+			log.Printf("Rejected signed request %s %s (key %q): %v", r.Method, r.URL.Path, keyID, err)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(stdcontext.WithValue(r.Context(), keyIDKey{}, keyID)))
+	})
+}
+
+// IsAuthenticator implements middleware.Authenticator.
+func (v *Verifier) IsAuthenticator() bool { return true }
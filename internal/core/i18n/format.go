@@ -0,0 +1,162 @@
+package i18n
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format holds the conventions a locale uses to write numbers, currencies and dates.
+type Format struct {
+	Decimal        string // Decimal separator
+	Group          string // Thousands separator
+	Date           string // Go layout of a short date, e.g. 02.01.2006
+	Time           string // Go layout of a time, e.g. 15:04
+	CurrencyBefore bool   // Symbol before the amount
+	CurrencySpace  bool   // Space between symbol and amount
+}
+
+const nbsp = "\u00a0"
+
+var formats = map[string]Format{
+	"en":    {Decimal: ".", Group: ",", Date: "01/02/2006", Time: "3:04 PM", CurrencyBefore: true},
+	"en-GB": {Decimal: ".", Group: ",", Date: "02/01/2006", Time: "15:04", CurrencyBefore: true},
+	"en-IN": {Decimal: ".", Group: ",", Date: "02/01/2006", Time: "3:04 PM", CurrencyBefore: true},
+	"en-AU": {Decimal: ".", Group: ",", Date: "02/01/2006", Time: "3:04 PM", CurrencyBefore: true},
+	"de":    {Decimal: ",", Group: ".", Date: "02.01.2006", Time: "15:04", CurrencySpace: true},
+	"de-CH": {Decimal: ".", Group: "’", Date: "02.01.2006", Time: "15:04", CurrencyBefore: true, CurrencySpace: true},
+	"fr":    {Decimal: ",", Group: "\u202f", Date: "02/01/2006", Time: "15:04", CurrencySpace: true},
+	"es":    {Decimal: ",", Group: ".", Date: "02/01/2006", Time: "15:04", CurrencySpace: true},
+	"it":    {Decimal: ",", Group: ".", Date: "02/01/2006", Time: "15:04", CurrencySpace: true},
+	"nl":    {Decimal: ",", Group: ".", Date: "02-01-2006", Time: "15:04", CurrencyBefore: true, CurrencySpace: true},
+	"pt":    {Decimal: ",", Group: ".", Date: "02/01/2006", Time: "15:04", CurrencyBefore: true, CurrencySpace: true},
+	"pl":    {Decimal: ",", Group: nbsp, Date: "02.01.2006", Time: "15:04", CurrencySpace: true},
+	"ru":    {Decimal: ",", Group: nbsp, Date: "02.01.2006", Time: "15:04", CurrencySpace: true},
+	"sv":    {Decimal: ",", Group: nbsp, Date: "2006-01-02", Time: "15:04", CurrencySpace: true},
+	"tr":    {Decimal: ",", Group: ".", Date: "02.01.2006", Time: "15:04", CurrencyBefore: true},
+	"ja":    {Decimal: ".", Group: ",", Date: "2006/01/02", Time: "15:04", CurrencyBefore: true},
+	"zh":    {Decimal: ".", Group: ",", Date: "2006/01/02", Time: "15:04", CurrencyBefore: true},
+	"ko":    {Decimal: ".", Group: ",", Date: "2006. 01. 02.", Time: "15:04", CurrencyBefore: true},
+	"hi":    {Decimal: ".", Group: ",", Date: "02/01/2006", Time: "3:04 PM", CurrencyBefore: true},
+}
+
+// defaultFormat is used for locales without conventions, with ISO dates.
+var defaultFormat = Format{Decimal: ".", Group: ",", Date: "2006-01-02", Time: "15:04", CurrencyBefore: true}
+
+// RegisterFormat sets the conventions of a locale, e.g. to add one that is missing.
+// It is meant to be called during initialization.
+func RegisterFormat(locale string, format Format) {
+	formats[CanonicalLocale(locale)] = format
+}
+
+// FormatFor returns the conventions of the locale, falling back to its base language.
+func FormatFor(locale string) Format {
+	locale = CanonicalLocale(locale)
+	if format, ok := formats[locale]; ok {
+		return format
+	}
+	base, _, _ := strings.Cut(locale, "-")
+	if format, ok := formats[base]; ok {
+		return format
+	}
+	return defaultFormat
+}
+
+type currency struct {
+	symbol   string
+	decimals int
+}
+
+var currencies = map[string]currency{
+	"USD": {"$", 2}, "EUR": {"€", 2}, "GBP": {"£", 2}, "JPY": {"¥", 0}, "CNY": {"¥", 2},
+	"INR": {"₹", 2}, "RUB": {"₽", 2}, "KRW": {"₩", 0}, "BRL": {"R$", 2}, "CHF": {"CHF", 2},
+	"CAD": {"CA$", 2}, "AUD": {"A$", 2}, "SEK": {"kr", 2}, "PLN": {"zł", 2}, "TRY": {"₺", 2},
+}
+
+// FormatNumber formats v with the given number of decimals, e.g. 1234.5 as "1.234,50" in de.
+func FormatNumber(locale string, v float64, decimals int) string {
+	return formatNumber(FormatFor(locale), v, decimals)
+}
+
+func formatNumber(format Format, v float64, decimals int) string {
+	negative := v < 0 || (v == 0 && math.Signbit(v))
+	digits := strconv.FormatFloat(math.Abs(v), 'f', decimals, 64)
+	integer, fraction, _ := strings.Cut(digits, ".")
+
+	var b strings.Builder
+	if negative && strings.Trim(digits, "0.") != "" {
+		b.WriteByte('-')
+	}
+	for i, digit := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			b.WriteString(format.Group)
+		}
+		b.WriteRune(digit)
+	}
+	if fraction != "" {
+		b.WriteString(format.Decimal)
+		b.WriteString(fraction)
+	}
+	return b.String()
+}
+
+// FormatCurrency formats an amount in the given ISO 4217 currency, e.g. 1234.5 EUR as
+// "1.234,50 €" in de and "€1,234.50" in en.
+func FormatCurrency(locale string, amount float64, code string) string {
+	format := FormatFor(locale)
+	code = strings.ToUpper(code)
+	info, known := currencies[code]
+	if !known {
+		info = currency{symbol: code, decimals: 2}
+	}
+	number := formatNumber(format, math.Abs(amount), info.decimals)
+	sign := ""
+	if amount < 0 && strings.Trim(number, "0.,"+format.Group) != "" {
+		sign = "-"
+	}
+	separator := ""
+	if format.CurrencySpace || !known {
+		separator = nbsp
+	}
+	if format.CurrencyBefore {
+		return sign + info.symbol + separator + number
+	}
+	return sign + number + separator + info.symbol
+}
+
+// FormatDate formats the date of t in the locale's short form.
+func FormatDate(locale string, t time.Time) string {
+	return t.Format(FormatFor(locale).Date)
+}
+
+// FormatTime formats the time of day of t.
+func FormatTime(locale string, t time.Time) string {
+	return t.Format(FormatFor(locale).Time)
+}
+
+// FormatDateTime formats the date and time of t.
+func FormatDateTime(locale string, t time.Time) string {
+	format := FormatFor(locale)
+	return t.Format(format.Date + " " + format.Time)
+}
+
+// FuncMap returns template functions formatting values for the locale and time zone:
+// formatDate, formatTime, formatDateTime, formatNumber and formatCurrency.
+//
+// Example usage:
+//
+//	tmpl.Funcs(i18n.FuncMap(ctx.Locale(), ctx.Location()))
+//	// {{formatCurrency .Total "EUR"}} {{formatDate .CreatedAt}}
+func FuncMap(locale string, location *time.Location) map[string]interface{} {
+	if location == nil {
+		location = time.UTC
+	}
+	return map[string]interface{}{
+		"formatDate":     func(t time.Time) string { return FormatDate(locale, t.In(location)) },
+		"formatTime":     func(t time.Time) string { return FormatTime(locale, t.In(location)) },
+		"formatDateTime": func(t time.Time) string { return FormatDateTime(locale, t.In(location)) },
+		"formatNumber":   func(v float64, decimals int) string { return FormatNumber(locale, v, decimals) },
+		"formatCurrency": func(amount float64, code string) string { return FormatCurrency(locale, amount, code) },
+	}
+}
@@ -0,0 +1,329 @@
+/*
+Package i18n translates messages into the locale of the request.
+
+Translations are loaded per locale from JSON or TOML files named after the locale, e.g. en.json
+or de-AT.toml. Nested keys are addressed with dots and plural forms are objects keyed by CLDR
+plural category (zero, one, two, few, many, other). The middleware picks the locale from the
+lang query parameter, the lang cookie or the Accept-Language header.
+
+Usage:
+
+	bundle := i18n.NewBundle("en")
+	if err := bundle.LoadDir("locales"); err != nil {
+		log.Fatal(err)
+	}
+	r.Use(i18n.NewMiddleware(bundle))
+
+	// locales/en.json: {"cart": {"items": {"one": "{count} item", "other": "{count} items"}}}
+	ctx.T("cart.items", 3) // "3 items"
+*/
+package i18n
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Args holds named placeholder values, e.g. Args{"name": "Ann"} for "Hello {name}".
+// The count value selects the plural form.
+type Args map[string]interface{}
+
+// Bundle holds the translations of all locales.
+type Bundle struct {
+	defaultLocale string
+
+	mu       sync.RWMutex
+	messages map[string]map[string]interface{} // locale -> flattened key -> string or plural forms
+}
+
+// NewBundle creates an empty bundle falling back to defaultLocale.
+func NewBundle(defaultLocale string) *Bundle {
+	return &Bundle{
+		defaultLocale: CanonicalLocale(defaultLocale),
+		messages:      make(map[string]map[string]interface{}),
+	}
+}
+
+// DefaultLocale returns the locale used when no other matches.
+func (b *Bundle) DefaultLocale() string {
+	return b.defaultLocale
+}
+
+// AddMessages adds translations for a locale. Nested maps are flattened into dotted keys,
+// except maps whose keys are all plural categories, which become plural forms.
+func (b *Bundle) AddMessages(locale string, messages map[string]interface{}) {
+	locale = CanonicalLocale(locale)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.messages[locale] == nil {
+		b.messages[locale] = make(map[string]interface{})
+	}
+	flatten(b.messages[locale], "", messages)
+}
+
+func flatten(into map[string]interface{}, prefix string, messages map[string]interface{}) {
+	for key, value := range messages {
+		switch v := value.(type) {
+		case string:
+			into[prefix+key] = v
+		case map[string]interface{}:
+			if forms, ok := pluralForms(v); ok {
+				into[prefix+key] = forms
+			} else {
+				flatten(into, prefix+key+".", v)
+			}
+		default:
+			into[prefix+key] = fmt.Sprint(v)
+		}
+	}
+}
+
+func pluralForms(m map[string]interface{}) (map[string]string, bool) {
+	forms := make(map[string]string, len(m))
+	for key, value := range m {
+		s, isString := value.(string)
+		if !isString || !isPluralCategory(key) {
+			return nil, false
+		}
+		forms[key] = s
+	}
+	_, hasOther := forms["other"]
+	return forms, hasOther
+}
+
+// LoadFile loads a JSON or TOML translation file. The locale is taken from the file name.
+func (b *Bundle) LoadFile(filename string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	return b.load(path.Base(strings.ReplaceAll(filename, "\\", "/")), data)
+}
+
+// LoadDir loads all .json and .toml files of a directory.
+func (b *Bundle) LoadDir(dir string) error {
+	return b.LoadFS(os.DirFS(dir), ".")
+}
+
+// LoadFS loads all .json and .toml files of a directory in a file system, e.g. an embed.FS.
+func (b *Bundle) LoadFS(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		ext := path.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".json" && ext != ".toml") {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		if err := b.load(entry.Name(), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *Bundle) load(name string, data []byte) error {
+	ext := path.Ext(name)
+	locale := strings.TrimSuffix(name, ext)
+	messages := make(map[string]interface{})
+	switch ext {
+	case ".json":
+		if err := json.Unmarshal(data, &messages); err != nil {
+			return fmt.Errorf("i18n: parsing %s: %w", name, err)
+		}
+	case ".toml":
+		parsed, err := parseTOML(string(data))
+		if err != nil {
+			return fmt.Errorf("i18n: parsing %s: %w", name, err)
+		}
+		messages = parsed
+	default:
+		return fmt.Errorf("i18n: unsupported translation file %s", name)
+	}
+	b.AddMessages(locale, messages)
+	return nil
+}
+
+// Locales returns the locales with translations.
+func (b *Bundle) Locales() []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	locales := make([]string, 0, len(b.messages))
+	for locale := range b.messages {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Has reports whether key is translated for the locale or one of its fallbacks.
+func (b *Bundle) Has(locale, key string) bool {
+	_, ok := b.lookup(locale, key)
+	return ok
+}
+
+// lookup finds the message in the locale, its base language and the default locale.
+func (b *Bundle) lookup(locale, key string) (interface{}, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, candidate := range b.fallbacks(CanonicalLocale(locale)) {
+		if message, ok := b.messages[candidate][key]; ok {
+			return message, true
+		}
+	}
+	return nil, false
+}
+
+func (b *Bundle) fallbacks(locale string) []string {
+	candidates := []string{locale}
+	if base, _, found := strings.Cut(locale, "-"); found {
+		candidates = append(candidates, base)
+	}
+	candidates = append(candidates, b.defaultLocale)
+	if base, _, found := strings.Cut(b.defaultLocale, "-"); found {
+		candidates = append(candidates, base)
+	}
+	return candidates
+}
+
+// Translate returns the message for key in the locale, or the key itself when it is not
+// translated. Args may be an Args map of placeholder values or a number, which is used as
+// count. The count selects the plural form and fills the {count} placeholder.
+//
+// Example usage:
+//
+//	bundle.Translate("de", "greeting", i18n.Args{"name": "Ann"})
+//	bundle.Translate("en", "cart.items", 3)
+func (b *Bundle) Translate(locale, key string, args ...interface{}) string {
+	message, ok := b.lookup(locale, key)
+	if !ok {
+		return key
+	}
+	values := make(Args)
+	for _, arg := range args {
+		switch a := arg.(type) {
+		case Args:
+			for k, v := range a {
+				values[k] = v
+			}
+		case map[string]interface{}:
+			for k, v := range a {
+				values[k] = v
+			}
+		default:
+			values["count"] = a
+		}
+	}
+
+	text, isString := message.(string)
+	if !isString {
+		forms := message.(map[string]string)
+		category := "other"
+		if count, ok := toFloat(values["count"]); ok {
+			category = PluralCategory(CanonicalLocale(locale), count)
+			if count == 0 && forms["zero"] != "" {
+				category = "zero"
+			}
+		}
+		text = forms[category]
+		if text == "" {
+			text = forms["other"]
+		}
+	}
+	return interpolate(text, values)
+}
+
+func interpolate(text string, values Args) string {
+	if len(values) == 0 || !strings.Contains(text, "{") {
+		return text
+	}
+	pairs := make([]string, 0, len(values)*2)
+	for key, value := range values {
+		pairs = append(pairs, "{"+key+"}", fmt.Sprint(value))
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// CanonicalLocale normalizes a locale tag, e.g. en_us becomes en-US.
+func CanonicalLocale(locale string) string {
+	parts := strings.Split(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"), "-")
+	parts[0] = strings.ToLower(parts[0])
+	for i := 1; i < len(parts); i++ {
+		if len(parts[i]) == 2 {
+			parts[i] = strings.ToUpper(parts[i])
+		} else if len(parts[i]) == 4 {
+			parts[i] = strings.ToUpper(parts[i][:1]) + strings.ToLower(parts[i][1:])
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+type localeKey struct{}
+
+type requestLocale struct {
+	bundle *Bundle
+	locale string
+}
+
+// WithLocale stores the bundle and locale of a request in its context.
+func WithLocale(ctx context.Context, bundle *Bundle, locale string) context.Context {
+	return context.WithValue(ctx, localeKey{}, requestLocale{bundle: bundle, locale: locale})
+}
+
+// FromContext returns the bundle and locale stored by the middleware.
+func FromContext(ctx context.Context) (*Bundle, string, bool) {
+	stored, ok := ctx.Value(localeKey{}).(requestLocale)
+	return stored.bundle, stored.locale, ok
+}
+
+// T translates key into the locale stored in the context, returning the key when no
+// bundle is configured.
+func T(ctx context.Context, key string, args ...interface{}) string {
+	bundle, locale, ok := FromContext(ctx)
+	if !ok {
+		return key
+	}
+	return bundle.Translate(locale, key, args...)
+}
@@ -0,0 +1,127 @@
+package i18n
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Middleware detects the locale of each request and stores it with the bundle in the request
+// context, where ctx.T finds it.
+type Middleware struct {
+	bundle     *Bundle
+	queryParam string
+	cookieName string
+}
+
+// NewMiddleware creates the locale detection middleware. The locale is taken from the lang
+// query parameter, then the lang cookie, then the Accept-Language header, and falls back to
+// the bundle's default locale.
+func NewMiddleware(bundle *Bundle, options ...func(*Middleware)) *Middleware {
+	m := &Middleware{bundle: bundle, queryParam: "lang", cookieName: "lang"}
+	for _, option := range options {
+		option(m)
+	}
+	return m
+}
+
+// WithQueryParam sets the query parameter selecting the locale, empty to disable it.
+func WithQueryParam(name string) func(*Middleware) {
+	return func(m *Middleware) {
+		m.queryParam = name
+	}
+}
+
+// WithCookie sets the cookie selecting the locale, empty to disable it.
+func WithCookie(name string) func(*Middleware) {
+	return func(m *Middleware) {
+		m.cookieName = name
+	}
+}
+
+func (m *Middleware) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		locale := m.Detect(r)
+		w.Header().Set("Content-Language", locale)
+		w.Header().Add("Vary", "Accept-Language")
+		next.ServeHTTP(w, r.WithContext(WithLocale(r.Context(), m.bundle, locale)))
+	})
+}
+
+// Detect returns the best supported locale for the request.
+func (m *Middleware) Detect(r *http.Request) string {
+	supported := m.bundle.Locales()
+	var preferred []string
+	if m.queryParam != "" {
+		if lang := r.URL.Query().Get(m.queryParam); lang != "" {
+			preferred = append(preferred, lang)
+		}
+	}
+	if m.cookieName != "" {
+		if cookie, err := r.Cookie(m.cookieName); err == nil && cookie.Value != "" {
+			preferred = append(preferred, cookie.Value)
+		}
+	}
+	preferred = append(preferred, ParseAcceptLanguage(r.Header.Get("Accept-Language"))...)
+	if locale := Match(preferred, supported); locale != "" {
+		return locale
+	}
+	return m.bundle.DefaultLocale()
+}
+
+// ParseAcceptLanguage returns the languages of an Accept-Language header ordered by quality.
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag     string
+		quality float64
+	}
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+		quality := 1.0
+		if q, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			if parsed, err := strconv.ParseFloat(q, 64); err == nil {
+				quality = parsed
+			}
+		}
+		if quality > 0 {
+			tags = append(tags, weighted{tag, quality})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].quality > tags[j].quality
+	})
+	result := make([]string, len(tags))
+	for i, tag := range tags {
+		result[i] = tag.tag
+	}
+	return result
+}
+
+// Match returns the first supported locale for the preferred locales in order. A preferred
+// locale matches exactly, by its base language (de-AT matches de) or as the base language
+// of a supported locale (de matches de-DE).
+func Match(preferred, supported []string) string {
+	for _, want := range preferred {
+		want = CanonicalLocale(want)
+		base, _, _ := strings.Cut(want, "-")
+		for _, candidate := range []string{want, base} {
+			for _, locale := range supported {
+				if locale == candidate {
+					return locale
+				}
+			}
+		}
+		for _, locale := range supported {
+			if supportedBase, _, _ := strings.Cut(locale, "-"); supportedBase == base {
+				return locale
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,84 @@
+package i18n
+
+import (
+	"math"
+	"strings"
+)
+
+var pluralCategories = map[string]bool{"zero": true, "one": true, "two": true, "few": true, "many": true, "other": true}
+
+func isPluralCategory(name string) bool {
+	return pluralCategories[name]
+}
+
+// PluralCategory returns the CLDR plural category of n for the locale's language. The rules
+// cover common languages; unknown languages use the English rule.
+func PluralCategory(locale string, n float64) string {
+	language, _, _ := strings.Cut(strings.ToLower(locale), "-")
+	integer := n == math.Trunc(n)
+	i := int64(math.Abs(n))
+
+	switch language {
+	case "ja", "zh", "ko", "th", "vi", "id", "ms", "tr":
+		return "other"
+	case "fr", "pt":
+		if i == 0 || i == 1 {
+			return "one"
+		}
+		return "other"
+	case "ru", "uk", "be", "sr", "hr", "bs":
+		if !integer {
+			return "other"
+		}
+		switch {
+		case i%10 == 1 && i%100 != 11:
+			return "one"
+		case i%10 >= 2 && i%10 <= 4 && (i%100 < 12 || i%100 > 14):
+			return "few"
+		}
+		return "many"
+	case "pl":
+		if !integer {
+			return "other"
+		}
+		switch {
+		case i == 1:
+			return "one"
+		case i%10 >= 2 && i%10 <= 4 && (i%100 < 12 || i%100 > 14):
+			return "few"
+		}
+		return "many"
+	case "cs", "sk":
+		if !integer {
+			return "many"
+		}
+		switch {
+		case i == 1:
+			return "one"
+		case i >= 2 && i <= 4:
+			return "few"
+		}
+		return "other"
+	case "ar":
+		if !integer {
+			return "other"
+		}
+		switch {
+		case i == 0:
+			return "zero"
+		case i == 1:
+			return "one"
+		case i == 2:
+			return "two"
+		case i%100 >= 3 && i%100 <= 10:
+			return "few"
+		case i%100 >= 11:
+			return "many"
+		}
+		return "other"
+	}
+	if integer && i == 1 {
+		return "one"
+	}
+	return "other"
+}
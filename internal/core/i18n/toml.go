@@ -0,0 +1,162 @@
+package i18n
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseTOML parses the subset of TOML used by translation files: comments, [table] headers,
+// bare, quoted and dotted keys, and basic or literal string values.
+func parseTOML(data string) (map[string]interface{}, error) {
+	root := make(map[string]interface{})
+	table := root
+	for number, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if line[0] == '[' {
+			end := strings.LastIndex(line, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated table header", number+1)
+			}
+			keys, err := parseKey(line[1:end])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", number+1, err)
+			}
+			table, err = descend(root, keys)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", number+1, err)
+			}
+			continue
+		}
+
+		equals := keyEnd(line)
+		if equals < 0 {
+			return nil, fmt.Errorf("line %d: expected key = value", number+1)
+		}
+		keys, err := parseKey(line[:equals])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", number+1, err)
+		}
+		value, err := parseString(strings.TrimSpace(line[equals+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", number+1, err)
+		}
+		parent, err := descend(table, keys[:len(keys)-1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", number+1, err)
+		}
+		parent[keys[len(keys)-1]] = value
+	}
+	return root, nil
+}
+
+// keyEnd returns the index of the = separating key and value, skipping quoted keys.
+func keyEnd(line string) int {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch {
+		case quote != 0:
+			if line[i] == quote {
+				quote = 0
+			}
+		case line[i] == '"' || line[i] == '\'':
+			quote = line[i]
+		case line[i] == '=':
+			return i
+		}
+	}
+	return -1
+}
+
+func parseKey(key string) ([]string, error) {
+	var keys []string
+	rest := strings.TrimSpace(key)
+	for rest != "" {
+		var part string
+		if rest[0] == '"' || rest[0] == '\'' {
+			end := strings.IndexByte(rest[1:], rest[0])
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated quoted key")
+			}
+			part = rest[1 : end+1]
+			rest = strings.TrimSpace(rest[end+2:])
+		} else {
+			dot := strings.IndexByte(rest, '.')
+			if dot < 0 {
+				dot = len(rest)
+			}
+			part = strings.TrimSpace(rest[:dot])
+			rest = rest[dot:]
+			if part == "" {
+				return nil, fmt.Errorf("empty key")
+			}
+		}
+		keys = append(keys, part)
+		rest = strings.TrimSpace(strings.TrimPrefix(rest, "."))
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("empty key")
+	}
+	return keys, nil
+}
+
+func descend(table map[string]interface{}, keys []string) (map[string]interface{}, error) {
+	for _, key := range keys {
+		existing, ok := table[key]
+		if !ok {
+			child := make(map[string]interface{})
+			table[key] = child
+			table = child
+			continue
+		}
+		child, isTable := existing.(map[string]interface{})
+		if !isTable {
+			return nil, fmt.Errorf("key %q is already a value", key)
+		}
+		table = child
+	}
+	return table, nil
+}
+
+func parseString(value string) (string, error) {
+	if value == "" {
+		return "", fmt.Errorf("missing value")
+	}
+	switch value[0] {
+	case '\'':
+		end := strings.IndexByte(value[1:], '\'')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated string")
+		}
+		return value[1 : end+1], checkTrailing(value[end+2:])
+	case '"':
+		escaped := false
+		for i := 1; i < len(value); i++ {
+			switch {
+			case escaped:
+				escaped = false
+			case value[i] == '\\':
+				escaped = true
+			case value[i] == '"':
+				unquoted, err := strconv.Unquote(value[:i+1])
+				if err != nil {
+					return "", err
+				}
+				return unquoted, checkTrailing(value[i+1:])
+			}
+		}
+		return "", fmt.Errorf("unterminated string")
+	}
+	return "", fmt.Errorf("only string values are supported")
+}
+
+func checkTrailing(rest string) error {
+	rest = strings.TrimSpace(rest)
+	if rest != "" && rest[0] != '#' {
+		return fmt.Errorf("unexpected %q after value", rest)
+	}
+	return nil
+}
@@ -0,0 +1,403 @@
+/*
+Package logexport batches structured access and audit logs and ships them to object storage.
+
+Batches are written as gzip-compressed NDJSON objects partitioned by date, each accompanied by a
+manifest holding its SHA-256 checksum and the checksum of the previous batch. The resulting hash
+chain makes deleted or altered batches detectable, which is what compliance retention requires.
+
+Usage:
+
+	store := logexport.NewFileStore("/var/log/app-export")
+	exporter := logexport.NewExporter(store, logexport.WithPrefix("access"), logexport.WithBatchSize(500))
+	exporter.Start(time.Minute)
+	defer exporter.Stop()
+
+	App := LessGo.App(LessGo.WithRequestLogExport(exporter))
+*/
+package logexport
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/enrich"
+	scheduler "github.com/hokamsingh/lessgo/internal/core/job"
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+	"github.com/hokamsingh/lessgo/internal/core/redact"
+)
+
+const (
+	// Default number of records that triggers a flush.
+	defaultBatchSize = 1000
+
+	// Default maximum number of records kept in memory when uploads fail.
+	defaultMaxBuffered = 100000
+)
+
+// ObjectStore is the minimal object storage interface the exporter writes to.
+// Keys are never overwritten by the exporter.
+type ObjectStore interface {
+	PutObject(ctx context.Context, key string, body []byte, contentType string) error
+}
+
+// AccessRecord is the structured record written for each HTTP request.
+type AccessRecord struct {
+	Time       time.Time `json:"time"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Query      string    `json:"query,omitempty"`
+	Status     int       `json:"status"`
+	DurationMs float64   `json:"duration_ms"`
+	Bytes      int64     `json:"bytes"`
+	RemoteAddr string    `json:"remote_addr"`
+	ClientIP   string    `json:"client_ip"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	RequestID  string    `json:"request_id,omitempty"`
+	// Set when the enrichment middleware runs
+	Device  string `json:"device,omitempty"`
+	Country string `json:"country,omitempty"`
+	City    string `json:"city,omitempty"`
+}
+
+// Manifest describes an exported batch.
+type Manifest struct {
+	Key          string    `json:"key"`
+	Records      int       `json:"records"`
+	SHA256       string    `json:"sha256"`
+	PreviousHash string    `json:"previous_sha256,omitempty"`
+	From         time.Time `json:"from"`
+	To           time.Time `json:"to"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Exporter buffers records and uploads them in compressed, checksummed batches.
+type Exporter struct {
+	store       ObjectStore
+	prefix      string
+	batchSize   int
+	maxBuffered int
+
+	mu       sync.Mutex
+	records  []json.RawMessage
+	first    time.Time
+	last     time.Time
+	lastHash string
+	sequence int64
+
+	flushMu sync.Mutex
+	stop    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewExporter creates a new Exporter writing to store.
+func NewExporter(store ObjectStore, options ...func(*Exporter)) *Exporter {
+	e := &Exporter{
+		store:       store,
+		prefix:      "logs",
+		batchSize:   defaultBatchSize,
+		maxBuffered: defaultMaxBuffered,
+	}
+	for _, option := range options {
+		option(e)
+	}
+	return e
+}
+
+// WithPrefix sets the key prefix of exported objects.
+func WithPrefix(prefix string) func(*Exporter) {
+	return func(e *Exporter) {
+		e.prefix = prefix
+	}
+}
+
+// WithBatchSize sets the number of records that triggers an immediate flush.
+func WithBatchSize(size int) func(*Exporter) {
+	return func(e *Exporter) {
+		if size > 0 {
+			e.batchSize = size
+		}
+	}
+}
+
+// WithMaxBuffered bounds the records kept in memory while uploads fail. Oldest records are dropped first.
+func WithMaxBuffered(max int) func(*Exporter) {
+	return func(e *Exporter) {
+		if max > 0 {
+			e.maxBuffered = max
+		}
+	}
+}
+
+// Record adds a structured record to the current batch. The record is JSON encoded immediately,
+// so later changes to the value are not reflected in the export.
+func (e *Exporter) Record(record interface{}) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	e.mu.Lock()
+	if len(e.records) == 0 {
+		e.first = now
+	}
+	e.last = now
+	e.records = append(e.records, data)
+	if len(e.records) > e.maxBuffered {
+		dropped := len(e.records) - e.maxBuffered
+		e.records = e.records[dropped:]
+		log.Printf("Log export buffer full, dropped %d records", dropped)
+	}
+	full := len(e.records) >= e.batchSize
+	e.mu.Unlock()
+
+	if full {
+		go func() {
+			if err := e.Flush(context.Background()); err != nil {
+				log.Printf("Log export failed: %v", err)
+			}
+		}()
+	}
+	return nil
+}
+
+// Flush uploads the buffered records as a single batch. On failure the records are kept
+// and retried on the next flush.
+func (e *Exporter) Flush(ctx context.Context) error {
+	e.flushMu.Lock()
+	defer e.flushMu.Unlock()
+
+	e.mu.Lock()
+	if len(e.records) == 0 {
+		e.mu.Unlock()
+		return nil
+	}
+	records := e.records
+	from, to := e.first, e.last
+	e.records = nil
+	e.sequence++
+	sequence := e.sequence
+	previousHash := e.lastHash
+	e.mu.Unlock()
+
+	body, err := compress(records)
+	if err != nil {
+		e.requeue(records, from)
+		return err
+	}
+
+	sum := sha256.Sum256(body)
+	checksum := hex.EncodeToString(sum[:])
+	key := fmt.Sprintf("%s/%s/%s-%06d-%s.ndjson.gz",
+		e.prefix, to.Format("2006/01/02"), to.Format("20060102T150405Z"), sequence, checksum[:12])
+
+	if err := e.store.PutObject(ctx, key, body, "application/gzip"); err != nil {
+		e.requeue(records, from)
+		return fmt.Errorf("uploading %s: %w", key, err)
+	}
+
+	manifest := Manifest{
+		Key:          key,
+		Records:      len(records),
+		SHA256:       checksum,
+		PreviousHash: previousHash,
+		From:         from,
+		To:           to,
+		CreatedAt:    time.Now().UTC(),
+	}
+	manifestData, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	if err := e.store.PutObject(ctx, key+".manifest.json", manifestData, "application/json"); err != nil {
+		// The batch itself is stored, it can be verified without its manifest
+		log.Printf("Failed to upload manifest for %s: %v", key, err)
+	}
+
+	e.mu.Lock()
+	e.lastHash = checksum
+	e.mu.Unlock()
+	return nil
+}
+
+// requeue puts records of a failed batch back in front of the buffer.
+func (e *Exporter) requeue(records []json.RawMessage, from time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.records = append(records, e.records...)
+	e.first = from
+	if len(e.records) > e.maxBuffered {
+		e.records = e.records[len(e.records)-e.maxBuffered:]
+	}
+}
+
+// Start flushes the buffer every interval until Stop is called.
+func (e *Exporter) Start(interval time.Duration) {
+	e.mu.Lock()
+	if e.stop != nil {
+		e.mu.Unlock()
+		return
+	}
+	e.stop = make(chan struct{})
+	stop := e.stop
+	e.mu.Unlock()
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := e.Flush(context.Background()); err != nil {
+					log.Printf("Log export failed: %v", err)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// Schedule registers a flush job on a scheduler using a cron expression.
+//
+// Example:
+//
+//	err := exporter.Schedule(sched, "*/5 * * * *")
+func (e *Exporter) Schedule(s scheduler.Scheduler, spec string) error {
+	return s.AddJob(spec, func() {
+		if err := e.Flush(context.Background()); err != nil {
+			log.Printf("Log export failed: %v", err)
+		}
+	})
+}
+
+// Stop halts periodic flushing and uploads the remaining records.
+func (e *Exporter) Stop() error {
+	e.mu.Lock()
+	stop := e.stop
+	e.stop = nil
+	e.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		e.wg.Wait()
+	}
+	return e.Flush(context.Background())
+}
+
+// Handle records an AccessRecord for every request, making the exporter usable as a middleware.
+func (e *Exporter) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		// Share the value store with later middlewares to read the enrichment afterwards
+		r, _ = middleware.RequestValues(r)
+		next.ServeHTTP(rec, r)
+
+		record := AccessRecord{
+			Time:       start.UTC(),
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			Query:      redact.Default().Query(r.URL.RawQuery),
+			Status:     rec.status,
+			DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+			Bytes:      rec.bytes,
+			RemoteAddr: r.RemoteAddr,
+			ClientIP:   middleware.ClientIP(r),
+			UserAgent:  r.UserAgent(),
+			RequestID:  r.Header.Get("X-Request-ID"),
+		}
+		if _, ok := middleware.GetValue(r, enrich.UserAgentKey); ok {
+			record.Device = enrich.RequestUserAgent(r).Device
+		}
+		if geo, ok := enrich.RequestGeo(r); ok {
+			record.Country, record.City = geo.Country, geo.City
+		}
+		e.Record(record)
+	})
+}
+
+// statusRecorder captures the status code and size of a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (rec *statusRecorder) WriteHeader(statusCode int) {
+	rec.status = statusCode
+	rec.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (rec *statusRecorder) Write(p []byte) (int, error) {
+	n, err := rec.ResponseWriter.Write(p)
+	rec.bytes += int64(n)
+	return n, err
+}
+
+func (rec *statusRecorder) Flush() {
+	if flusher, ok := rec.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// compress encodes records as gzip-compressed NDJSON.
+func compress(records []json.RawMessage) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	for _, record := range records {
+		if _, err := gz.Write(record); err != nil {
+			return nil, err
+		}
+		if _, err := gz.Write([]byte{'\n'}); err != nil {
+			return nil, err
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// FileStore is an ObjectStore writing read-only files below a directory.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates a FileStore rooted at dir.
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+// PutObject writes body to the key, refusing to overwrite existing objects.
+func (fs *FileStore) PutObject(ctx context.Context, key string, body []byte, contentType string) error {
+	path := filepath.Join(fs.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0440)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("object %s already exists", key)
+		}
+		return err
+	}
+	if _, err := file.Write(body); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,334 @@
+/*
+Package mailer sends email through SMTP or an HTTP API (SendGrid, Amazon SES) behind one Transport
+interface.
+
+Messages can be rendered from the HTML templates of the view engine, carry attachments and be
+delivered asynchronously by a background queue that retries failed deliveries with backoff.
+
+Usage:
+
+	m, err := mailer.New(mailer.ConfigFromEnv(cfg), mailer.WithTemplates(views.Tmpl))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer m.Close()
+
+	msg := &mailer.Message{To: []string{"ann@example.com"}, Subject: "Welcome"}
+	if err := m.Render(msg, "welcome.html", user); err != nil {
+		return err
+	}
+	m.SendAsync(msg)
+*/
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/config"
+)
+
+// ErrClosed is returned when sending through a closed mailer.
+var ErrClosed = errors.New("mailer is closed")
+
+// ErrQueueFull is returned by SendAsync when the queue of messages awaiting delivery is full.
+var ErrQueueFull = errors.New("mailer: queue is full")
+
+// ErrInvalidHeader is returned when a header name or value of a message would break the
+// header block, such as a value containing a line break.
+var ErrInvalidHeader = errors.New("mailer: invalid header")
+
+// Attachment is a file attached to a message.
+type Attachment struct {
+	Filename    string
+	ContentType string // Detected from the file name when empty
+	Data        []byte
+}
+
+// Message is an email. At least one of Text and HTML should be set.
+type Message struct {
+	From        string // Defaults to the mailer's From address
+	To          []string
+	Cc          []string
+	Bcc         []string
+	ReplyTo     string
+	Subject     string
+	Text        string
+	HTML        string
+	Headers     map[string]string
+	Attachments []Attachment
+}
+
+// Attach adds an attachment to the message.
+func (m *Message) Attach(filename, contentType string, data []byte) {
+	m.Attachments = append(m.Attachments, Attachment{Filename: filename, ContentType: contentType, Data: data})
+}
+
+// Recipients returns all To, Cc and Bcc addresses.
+func (m *Message) Recipients() []string {
+	recipients := make([]string, 0, len(m.To)+len(m.Cc)+len(m.Bcc))
+	recipients = append(recipients, m.To...)
+	recipients = append(recipients, m.Cc...)
+	return append(recipients, m.Bcc...)
+}
+
+// Transport delivers messages.
+type Transport interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// TransportFunc adapts a function to the Transport interface.
+type TransportFunc func(ctx context.Context, msg *Message) error
+
+// Send implements Transport.
+func (f TransportFunc) Send(ctx context.Context, msg *Message) error {
+	return f(ctx, msg)
+}
+
+// Config selects and configures the transport.
+type Config struct {
+	Transport string // "smtp", "sendgrid" or "ses"
+	From      string
+
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+
+	SendGridAPIKey string
+
+	SESRegion          string
+	SESAccessKeyID     string
+	SESSecretAccessKey string
+
+	Workers    int           // Background senders for SendAsync
+	MaxRetries int           // Retries of a failed asynchronous delivery
+	RetryDelay time.Duration // Delay before the first retry, doubled for every further retry
+}
+
+// ConfigFromEnv reads the mailer configuration from MAIL_* keys, e.g. MAIL_TRANSPORT=smtp,
+// MAIL_FROM, MAIL_SMTP_HOST, MAIL_SMTP_PORT, MAIL_SMTP_USERNAME, MAIL_SMTP_PASSWORD,
+// MAIL_SENDGRID_API_KEY, MAIL_SES_REGION, MAIL_SES_ACCESS_KEY_ID, MAIL_SES_SECRET_ACCESS_KEY,
+// MAIL_WORKERS and MAIL_MAX_RETRIES.
+func ConfigFromEnv(cfg config.Config) Config {
+	return Config{
+		Transport:          cfg.Get("MAIL_TRANSPORT", "smtp"),
+		From:               cfg.Get("MAIL_FROM", ""),
+		SMTPHost:           cfg.Get("MAIL_SMTP_HOST", "localhost"),
+		SMTPPort:           cfg.GetInt("MAIL_SMTP_PORT", 587),
+		SMTPUsername:       cfg.Get("MAIL_SMTP_USERNAME", ""),
+		SMTPPassword:       cfg.Get("MAIL_SMTP_PASSWORD", ""),
+		SendGridAPIKey:     cfg.Get("MAIL_SENDGRID_API_KEY", ""),
+		SESRegion:          cfg.Get("MAIL_SES_REGION", "us-east-1"),
+		SESAccessKeyID:     cfg.Get("MAIL_SES_ACCESS_KEY_ID", ""),
+		SESSecretAccessKey: cfg.Get("MAIL_SES_SECRET_ACCESS_KEY", ""),
+		Workers:            cfg.GetInt("MAIL_WORKERS", 2),
+		MaxRetries:         cfg.GetInt("MAIL_MAX_RETRIES", 3),
+	}
+}
+
+// NewTransport creates the transport selected by the configuration.
+func NewTransport(cfg Config) (Transport, error) {
+	switch cfg.Transport {
+	case "", "smtp":
+		return NewSMTPTransport(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword), nil
+	case "sendgrid":
+		if cfg.SendGridAPIKey == "" {
+			return nil, fmt.Errorf("mailer: sendgrid transport needs an API key")
+		}
+		return NewSendGridTransport(cfg.SendGridAPIKey), nil
+	case "ses":
+		if cfg.SESAccessKeyID == "" || cfg.SESSecretAccessKey == "" {
+			return nil, fmt.Errorf("mailer: ses transport needs access keys")
+		}
+		return NewSESTransport(cfg.SESRegion, cfg.SESAccessKeyID, cfg.SESSecretAccessKey), nil
+	}
+	return nil, fmt.Errorf("mailer: unknown transport %q", cfg.Transport)
+}
+
+// Mailer renders and sends messages.
+type Mailer struct {
+	transport  Transport
+	from       string
+	templates  *template.Template
+	workers    int
+	maxRetries int
+	retryDelay time.Duration
+
+	queue     chan *Message
+	wg        sync.WaitGroup
+	mu        sync.RWMutex
+	closed    bool
+	startOnce sync.Once
+}
+
+// New creates a mailer from the configuration.
+func New(cfg Config, options ...func(*Mailer)) (*Mailer, error) {
+	transport, err := NewTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+	m := NewMailer(transport, cfg.From)
+	if cfg.Workers > 0 {
+		m.workers = cfg.Workers
+	}
+	if cfg.MaxRetries > 0 {
+		m.maxRetries = cfg.MaxRetries
+	}
+	if cfg.RetryDelay > 0 {
+		m.retryDelay = cfg.RetryDelay
+	}
+	for _, option := range options {
+		option(m)
+	}
+	return m, nil
+}
+
+// NewMailer creates a mailer sending through the given transport.
+func NewMailer(transport Transport, from string, options ...func(*Mailer)) *Mailer {
+	m := &Mailer{
+		transport:  transport,
+		from:       from,
+		workers:    2,
+		maxRetries: 3,
+		retryDelay: 5 * time.Second,
+		queue:      make(chan *Message, 1024),
+	}
+	for _, option := range options {
+		option(m)
+	}
+	return m
+}
+
+// WithTemplates sets the templates Render uses, e.g. the view engine's templates.
+func WithTemplates(templates *template.Template) func(*Mailer) {
+	return func(m *Mailer) {
+		m.templates = templates
+	}
+}
+
+// WithRetry sets how often and after which initial delay failed asynchronous deliveries are retried.
+func WithRetry(maxRetries int, delay time.Duration) func(*Mailer) {
+	return func(m *Mailer) {
+		m.maxRetries = maxRetries
+		m.retryDelay = delay
+	}
+}
+
+// WithWorkers sets the number of background senders used by SendAsync. Values below 1 are
+// ignored, as no message would ever be delivered.
+func WithWorkers(workers int) func(*Mailer) {
+	return func(m *Mailer) {
+		if workers > 0 {
+			m.workers = workers
+		}
+	}
+}
+
+// Render sets the HTML body of the message from the named template. When a template with
+// the same name and a .txt extension exists, e.g. welcome.txt for welcome.html, it renders
+// the plain text alternative.
+func (m *Mailer) Render(msg *Message, name string, data interface{}) error {
+	if m.templates == nil {
+		return fmt.Errorf("mailer: no templates configured")
+	}
+	var html bytes.Buffer
+	if err := m.templates.ExecuteTemplate(&html, name, data); err != nil {
+		return err
+	}
+	msg.HTML = html.String()
+
+	textName := strings.TrimSuffix(name, path.Ext(name)) + ".txt"
+	if text := m.templates.Lookup(textName); text != nil {
+		var buf bytes.Buffer
+		if err := text.Execute(&buf, data); err != nil {
+			return err
+		}
+		msg.Text = buf.String()
+	}
+	return nil
+}
+
+// Send delivers the message immediately.
+func (m *Mailer) Send(ctx context.Context, msg *Message) error {
+	if msg.From == "" {
+		msg.From = m.from
+	}
+	if msg.From == "" {
+		return fmt.Errorf("mailer: message has no sender")
+	}
+	if len(msg.Recipients()) == 0 {
+		return fmt.Errorf("mailer: message has no recipients")
+	}
+	return m.transport.Send(ctx, msg)
+}
+
+// SendAsync queues the message for background delivery. Failed deliveries are retried with
+// exponential backoff and logged once all retries failed. It does not wait for room in the
+// queue but returns ErrQueueFull, so callers can fall back to Send or drop the message.
+func (m *Mailer) SendAsync(msg *Message) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.closed {
+		return ErrClosed
+	}
+	m.startOnce.Do(m.start)
+	select {
+	case m.queue <- msg:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+func (m *Mailer) start() {
+	for i := 0; i < m.workers; i++ {
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			for msg := range m.queue {
+				m.deliver(msg)
+			}
+		}()
+	}
+}
+
+func (m *Mailer) deliver(msg *Message) {
+	delay := m.retryDelay
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		err := m.Send(ctx, msg)
+		cancel()
+		if err == nil {
+			return
+		}
+		if attempt >= m.maxRetries {
+			log.Printf("Failed to send email %q to %v after %d attempts: %v", msg.Subject, msg.To, attempt+1, err)
+			return
+		}
+		log.Printf("Error sending email %q, retrying in %s: %v", msg.Subject, delay, err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// Close stops accepting messages and waits until queued messages are delivered.
+func (m *Mailer) Close() {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return
+	}
+	m.closed = true
+	m.mu.Unlock()
+	close(m.queue)
+	m.wg.Wait()
+}
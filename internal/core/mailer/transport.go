@@ -0,0 +1,403 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/sigv4"
+)
+
+// SMTPTransport sends messages through an SMTP server, using STARTTLS when the server offers it.
+type SMTPTransport struct {
+	addr string
+	auth smtp.Auth
+}
+
+// NewSMTPTransport creates an SMTP transport. Authentication is skipped when username is empty.
+func NewSMTPTransport(host string, port int, username, password string) *SMTPTransport {
+	t := &SMTPTransport{addr: host + ":" + strconv.Itoa(port)}
+	if username != "" {
+		t.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return t
+}
+
+func (t *SMTPTransport) Send(ctx context.Context, msg *Message) error {
+	raw, err := BuildMIME(msg)
+	if err != nil {
+		return err
+	}
+	from, err := addressOnly(msg.From)
+	if err != nil {
+		return err
+	}
+	recipients := make([]string, 0, len(msg.Recipients()))
+	for _, recipient := range msg.Recipients() {
+		address, err := addressOnly(recipient)
+		if err != nil {
+			return err
+		}
+		recipients = append(recipients, address)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(t.addr, t.auth, from, recipients, raw)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func addressOnly(address string) (string, error) {
+	parsed, err := mail.ParseAddress(address)
+	if err != nil {
+		return "", fmt.Errorf("mailer: invalid address %q: %w", address, err)
+	}
+	return parsed.Address, nil
+}
+
+// BuildMIME encodes the message as a MIME email. Bcc recipients are not included in the headers.
+// Addresses are parsed and re-encoded, and header names and values containing line breaks are
+// rejected with ErrInvalidHeader, so message fields cannot inject headers.
+func BuildMIME(msg *Message) ([]byte, error) {
+	from, err := formatAddresses(msg.From)
+	if err != nil {
+		return nil, err
+	}
+	to, err := formatAddresses(msg.To...)
+	if err != nil {
+		return nil, err
+	}
+	cc, err := formatAddresses(msg.Cc...)
+	if err != nil {
+		return nil, err
+	}
+	replyTo, err := formatAddresses(msg.ReplyTo)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range msg.Headers {
+		if !validHeaderName(name) || !validHeaderValue(value) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidHeader, name)
+		}
+	}
+	for _, attachment := range msg.Attachments {
+		if !validHeaderValue(attachment.ContentType) {
+			return nil, fmt.Errorf("%w: content type of %q", ErrInvalidHeader, attachment.Filename)
+		}
+	}
+
+	var buf bytes.Buffer
+	header := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&buf, "%s: %s\r\n", name, value)
+		}
+	}
+	header("From", from)
+	header("To", to)
+	header("Cc", cc)
+	header("Reply-To", replyTo)
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	for name, value := range msg.Headers {
+		header(textproto.CanonicalMIMEHeaderKey(name), value)
+	}
+
+	body := multipart.NewWriter(&buf)
+	if len(msg.Attachments) == 0 {
+		fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", body.Boundary())
+		if err := writeAlternatives(body, msg); err != nil {
+			return nil, err
+		}
+		if err := body.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", body.Boundary())
+	var alternatives bytes.Buffer
+	inner := multipart.NewWriter(&alternatives)
+	if err := writeAlternatives(inner, msg); err != nil {
+		return nil, err
+	}
+	if err := inner.Close(); err != nil {
+		return nil, err
+	}
+	part, err := body.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {"multipart/alternative; boundary=" + inner.Boundary()},
+	})
+	if err != nil {
+		return nil, err
+	}
+	part.Write(alternatives.Bytes())
+
+	for _, attachment := range msg.Attachments {
+		contentType := attachment.ContentType
+		if contentType == "" {
+			contentType = mime.TypeByExtension(filepath.Ext(attachment.Filename))
+		}
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		part, err := body.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {contentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename})},
+		})
+		if err != nil {
+			return nil, err
+		}
+		writeBase64(part, attachment.Data)
+	}
+	if err := body.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// formatAddresses parses each address and joins them re-encoded, skipping empty ones.
+func formatAddresses(addresses ...string) (string, error) {
+	formatted := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		if address == "" {
+			continue
+		}
+		parsed, err := mail.ParseAddress(address)
+		if err != nil {
+			return "", fmt.Errorf("mailer: invalid address %q: %w", address, err)
+		}
+		formatted = append(formatted, parsed.String())
+	}
+	return strings.Join(formatted, ", "), nil
+}
+
+// validHeaderName reports whether name is a non-empty run of printable ASCII without colons.
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if name[i] <= ' ' || name[i] >= 0x7f || name[i] == ':' {
+			return false
+		}
+	}
+	return true
+}
+
+// validHeaderValue reports whether value fits on a header line.
+func validHeaderValue(value string) bool {
+	return !strings.ContainsAny(value, "\r\n\x00")
+}
+
+func writeAlternatives(w *multipart.Writer, msg *Message) error {
+	for _, alternative := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		if alternative.content == "" {
+			continue
+		}
+		part, err := w.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {alternative.contentType},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return err
+		}
+		writeBase64(part, []byte(alternative.content))
+	}
+	return nil
+}
+
+// writeBase64 writes base64 in lines of 76 characters as required by RFC 2045.
+func writeBase64(w io.Writer, data []byte) {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		io.WriteString(w, encoded[:76]+"\r\n")
+		encoded = encoded[76:]
+	}
+	io.WriteString(w, encoded+"\r\n")
+}
+
+// SendGridTransport sends messages through the SendGrid v3 mail API.
+type SendGridTransport struct {
+	apiKey   string
+	endpoint string
+	client   *http.Client
+}
+
+// NewSendGridTransport creates a SendGrid transport.
+func NewSendGridTransport(apiKey string) *SendGridTransport {
+	return &SendGridTransport{
+		apiKey:   apiKey,
+		endpoint: "https://api.sendgrid.com/v3/mail/send",
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type sendGridAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+func sendGridAddresses(addresses []string) ([]sendGridAddress, error) {
+	result := make([]sendGridAddress, 0, len(addresses))
+	for _, address := range addresses {
+		parsed, err := mail.ParseAddress(address)
+		if err != nil {
+			return nil, fmt.Errorf("mailer: invalid address %q: %w", address, err)
+		}
+		result = append(result, sendGridAddress{Email: parsed.Address, Name: parsed.Name})
+	}
+	return result, nil
+}
+
+func (t *SendGridTransport) Send(ctx context.Context, msg *Message) error {
+	from, err := sendGridAddresses([]string{msg.From})
+	if err != nil {
+		return err
+	}
+	personalization := map[string]interface{}{}
+	for key, addresses := range map[string][]string{"to": msg.To, "cc": msg.Cc, "bcc": msg.Bcc} {
+		if len(addresses) == 0 {
+			continue
+		}
+		converted, err := sendGridAddresses(addresses)
+		if err != nil {
+			return err
+		}
+		personalization[key] = converted
+	}
+
+	var content []map[string]string
+	if msg.Text != "" {
+		content = append(content, map[string]string{"type": "text/plain", "value": msg.Text})
+	}
+	if msg.HTML != "" {
+		content = append(content, map[string]string{"type": "text/html", "value": msg.HTML})
+	}
+	payload := map[string]interface{}{
+		"personalizations": []interface{}{personalization},
+		"from":             from[0],
+		"subject":          msg.Subject,
+		"content":          content,
+	}
+	if msg.ReplyTo != "" {
+		replyTo, err := sendGridAddresses([]string{msg.ReplyTo})
+		if err != nil {
+			return err
+		}
+		payload["reply_to"] = replyTo[0]
+	}
+	if len(msg.Headers) > 0 {
+		payload["headers"] = msg.Headers
+	}
+	if len(msg.Attachments) > 0 {
+		attachments := make([]map[string]string, 0, len(msg.Attachments))
+		for _, attachment := range msg.Attachments {
+			item := map[string]string{
+				"content":     base64.StdEncoding.EncodeToString(attachment.Data),
+				"filename":    attachment.Filename,
+				"disposition": "attachment",
+			}
+			if attachment.ContentType != "" {
+				item["type"] = attachment.ContentType
+			}
+			attachments = append(attachments, item)
+		}
+		payload["attachments"] = attachments
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+t.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+	return doAPIRequest(t.client, req, "sendgrid")
+}
+
+// SESTransport sends messages through the Amazon SES v2 API.
+type SESTransport struct {
+	signer   *sigv4.Signer
+	endpoint string
+	client   *http.Client
+}
+
+// NewSESTransport creates an SES transport for the given region and credentials.
+func NewSESTransport(region, accessKeyID, secretAccessKey string) *SESTransport {
+	return &SESTransport{
+		signer:   &sigv4.Signer{AccessKey: accessKeyID, SecretKey: secretAccessKey, Region: region, Service: "ses"},
+		endpoint: "https://email." + region + ".amazonaws.com/v2/email/outbound-emails",
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (t *SESTransport) Send(ctx context.Context, msg *Message) error {
+	raw, err := BuildMIME(msg)
+	if err != nil {
+		return err
+	}
+	destination := map[string][]string{}
+	if len(msg.To) > 0 {
+		destination["ToAddresses"] = msg.To
+	}
+	if len(msg.Cc) > 0 {
+		destination["CcAddresses"] = msg.Cc
+	}
+	if len(msg.Bcc) > 0 {
+		destination["BccAddresses"] = msg.Bcc
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"FromEmailAddress": msg.From,
+		"Destination":      destination,
+		"Content":          map[string]interface{}{"Raw": map[string]string{"Data": base64.StdEncoding.EncodeToString(raw)}},
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	t.signer.Sign(req, sigv4.PayloadHash(body), time.Now())
+	return doAPIRequest(t.client, req, "ses")
+}
+
+func doAPIRequest(client *http.Client, req *http.Request, provider string) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("mailer: %s responded %s: %s", provider, resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
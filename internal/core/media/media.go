@@ -0,0 +1,405 @@
+/*
+Package media serves images of a storage.Storage resized, cropped and converted on the fly.
+
+Image URLs carry the transformation in their query, e.g. /media/avatars/42.png?w=128&h=128&fit=cover,
+signed with a secret so clients cannot make the server render arbitrary sizes. Presets name
+common transformations; they may be allowed without signature as their cost is bounded.
+Results are cached in the storage, and rendering runs on a bounded pool.
+
+Usage:
+
+	images := media.NewServer(store, "/media", secret,
+		media.WithPreset("thumb", media.Options{Width: 128, Height: 128, Fit: media.FitCover}),
+		media.WithUnsignedPresets(),
+	)
+	r.ServeMedia(images)
+
+	// In a template or handler
+	url := images.URL("avatars/42.png", media.Options{Width: 640, Format: "jpeg"})
+	thumb := images.PresetURL("avatars/42.png", "thumb")
+*/
+package media
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/storage"
+)
+
+// Options describe a transformation.
+type Options struct {
+	// Width and Height of the result in pixels. When one is zero, it follows from the aspect
+	// ratio; when both are, the image keeps its size.
+	Width  int
+	Height int
+	// Fit is FitContain, FitCover or FitFill, FitContain by default.
+	Fit string
+	// Format is jpeg, png or gif, the format of the source by default.
+	Format string
+	// Quality of JPEG results, 85 by default.
+	Quality int
+}
+
+// query encodes the options in a canonical order.
+func (o Options) query() url.Values {
+	values := url.Values{}
+	if o.Width > 0 {
+		values.Set("w", strconv.Itoa(o.Width))
+	}
+	if o.Height > 0 {
+		values.Set("h", strconv.Itoa(o.Height))
+	}
+	if o.Fit != "" {
+		values.Set("fit", o.Fit)
+	}
+	if o.Format != "" {
+		values.Set("fm", o.Format)
+	}
+	if o.Quality > 0 {
+		values.Set("q", strconv.Itoa(o.Quality))
+	}
+	return values
+}
+
+// Server renders the images of a storage.
+type Server struct {
+	store           storage.Storage
+	basePath        string
+	secret          []byte
+	presets         map[string]Options
+	unsignedPresets bool
+	cachePrefix     string
+	maxSize         int
+	maxSourcePixels int64
+	maxAge          time.Duration
+	workers         chan struct{}
+	queueTimeout    time.Duration
+
+	mu      sync.Mutex
+	renders map[string]*render // renders in progress, shared by identical requests
+}
+
+type render struct {
+	done        chan struct{}
+	data        []byte
+	contentType string
+	err         error
+}
+
+// NewServer creates a server for the images of store, served under basePath and signed with
+// secret.
+func NewServer(store storage.Storage, basePath string, secret []byte, options ...func(*Server)) *Server {
+	s := &Server{
+		store:           store,
+		basePath:        "/" + strings.Trim(basePath, "/"),
+		secret:          secret,
+		presets:         map[string]Options{},
+		cachePrefix:     "_media/",
+		maxSize:         4096,
+		maxSourcePixels: 50_000_000,
+		maxAge:          365 * 24 * time.Hour,
+		workers:         make(chan struct{}, runtime.NumCPU()),
+		queueTimeout:    10 * time.Second,
+		renders:         map[string]*render{},
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// WithPreset names a transformation, requested with ?p=name.
+func WithPreset(name string, options Options) func(*Server) {
+	return func(s *Server) {
+		s.presets[name] = options
+	}
+}
+
+// WithUnsignedPresets serves presets without signature.
+func WithUnsignedPresets() func(*Server) {
+	return func(s *Server) {
+		s.unsignedPresets = true
+	}
+}
+
+// WithMediaCachePrefix sets the storage prefix of rendered images, empty to disable caching.
+func WithMediaCachePrefix(prefix string) func(*Server) {
+	return func(s *Server) {
+		s.cachePrefix = prefix
+	}
+}
+
+// WithMediaLimits bounds the width and height of results and the pixels of sources, which
+// are checked before decoding.
+func WithMediaLimits(maxSize int, maxSourcePixels int64) func(*Server) {
+	return func(s *Server) {
+		s.maxSize, s.maxSourcePixels = maxSize, maxSourcePixels
+	}
+}
+
+// WithMediaWorkers sets how many images are rendered at once, and how long requests wait
+// for a worker before failing with 503.
+func WithMediaWorkers(n int, queueTimeout time.Duration) func(*Server) {
+	return func(s *Server) {
+		s.workers = make(chan struct{}, n)
+		s.queueTimeout = queueTimeout
+	}
+}
+
+// BasePath returns the path the images are served under.
+func (s *Server) BasePath() string {
+	return s.basePath
+}
+
+func (s *Server) sign(key string, query url.Values) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(key + "?" + query.Encode()))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// URL returns the signed URL of key transformed with options.
+func (s *Server) URL(key string, options Options) string {
+	key = strings.TrimLeft(key, "/")
+	query := options.query()
+	query.Set("s", s.sign(key, options.query()))
+	return s.basePath + "/" + (&url.URL{Path: key}).EscapedPath() + "?" + query.Encode()
+}
+
+// PresetURL returns the URL of key transformed with the named preset, signed unless
+// WithUnsignedPresets is set.
+func (s *Server) PresetURL(key, preset string) string {
+	key = strings.TrimLeft(key, "/")
+	query := url.Values{"p": {preset}}
+	if !s.unsignedPresets {
+		query.Set("s", s.sign(key, url.Values{"p": {preset}}))
+	}
+	return s.basePath + "/" + (&url.URL{Path: key}).EscapedPath() + "?" + query.Encode()
+}
+
+// options parses and authorizes the transformation requested by query.
+func (s *Server) options(key string, query url.Values) (Options, error) {
+	signature := query.Get("s")
+	query.Del("s")
+	if name := query.Get("p"); name != "" {
+		preset, ok := s.presets[name]
+		if !ok {
+			return Options{}, errors.New("unknown preset")
+		}
+		if !s.unsignedPresets && !hmac.Equal([]byte(signature), []byte(s.sign(key, url.Values{"p": {name}}))) {
+			return Options{}, errors.New("invalid signature")
+		}
+		return preset, nil
+	}
+
+	var o Options
+	o.Width, _ = strconv.Atoi(query.Get("w"))
+	o.Height, _ = strconv.Atoi(query.Get("h"))
+	o.Quality, _ = strconv.Atoi(query.Get("q"))
+	o.Fit = query.Get("fit")
+	o.Format = query.Get("fm")
+	if !hmac.Equal([]byte(signature), []byte(s.sign(key, o.query()))) {
+		return Options{}, errors.New("invalid signature")
+	}
+	return o, nil
+}
+
+func (s *Server) validate(o Options) error {
+	if o.Width < 0 || o.Height < 0 || o.Width > s.maxSize || o.Height > s.maxSize {
+		return fmt.Errorf("size exceeds %d pixels", s.maxSize)
+	}
+	switch o.Fit {
+	case "", FitContain, FitCover, FitFill:
+	default:
+		return fmt.Errorf("unknown fit %q", o.Fit)
+	}
+	switch o.Format {
+	case "", "jpeg", "jpg", "png", "gif":
+	default:
+		return fmt.Errorf("unsupported format %q", o.Format)
+	}
+	if o.Quality < 0 || o.Quality > 100 {
+		return errors.New("quality must be between 1 and 100")
+	}
+	return nil
+}
+
+// ServeHTTP serves GET and HEAD requests for the images under the base path.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	key := strings.TrimPrefix(path.Clean("/"+strings.TrimPrefix(r.URL.Path, s.basePath)), "/")
+	if key == "" || (s.cachePrefix != "" && strings.HasPrefix(key, s.cachePrefix)) {
+		http.NotFound(w, r)
+		return
+	}
+	o, err := s.options(key, r.URL.Query())
+	if err == nil {
+		err = s.validate(o)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	id := s.renderID(key, o)
+	etag := `"` + id + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d, immutable", int(s.maxAge.Seconds())))
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	data, contentType, err := s.get(r.Context(), id, key, o)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		http.NotFound(w, r)
+		return
+	case errors.Is(err, errBusy):
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "Too many images being processed", http.StatusServiceUnavailable)
+		return
+	case errors.Is(err, errInvalidImage):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	case err != nil:
+		log.Printf("Error rendering image %s: %v", key, err)
+		http.Error(w, "Unable to render image", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodGet {
+		w.Write(data)
+	}
+}
+
+var (
+	errBusy         = errors.New("media: all workers busy")
+	errInvalidImage = errors.New("invalid image")
+)
+
+// renderID identifies the result of a transformation.
+func (s *Server) renderID(key string, o Options) string {
+	sum := sha256.Sum256([]byte(key + "?" + o.query().Encode()))
+	return hex.EncodeToString(sum[:16])
+}
+
+// get returns the rendered image from the cache, or renders it once for all the concurrent
+// requests.
+func (s *Server) get(ctx context.Context, id, key string, o Options) ([]byte, string, error) {
+	cacheKey := s.cachePrefix + id
+	if s.cachePrefix != "" {
+		if cached, err := s.store.Get(ctx, cacheKey); err == nil {
+			data, err := io.ReadAll(cached)
+			cached.Close()
+			if err == nil && len(data) > 0 {
+				return data, http.DetectContentType(data), nil
+			}
+		}
+	}
+
+	s.mu.Lock()
+	if pending, ok := s.renders[id]; ok {
+		s.mu.Unlock()
+		select {
+		case <-pending.done:
+			return pending.data, pending.contentType, pending.err
+		case <-ctx.Done():
+			return nil, "", ctx.Err()
+		}
+	}
+	pending := &render{done: make(chan struct{})}
+	s.renders[id] = pending
+	s.mu.Unlock()
+
+	// The render goes on for the other requests when this one is cancelled
+	pending.data, pending.contentType, pending.err = s.render(context.WithoutCancel(ctx), key, o)
+	if pending.err == nil && s.cachePrefix != "" {
+		if err := s.store.Put(ctx, cacheKey, bytes.NewReader(pending.data), pending.contentType); err != nil {
+			log.Printf("Error caching image %s: %v", key, err)
+		}
+	}
+	s.mu.Lock()
+	delete(s.renders, id)
+	s.mu.Unlock()
+	close(pending.done)
+	return pending.data, pending.contentType, pending.err
+}
+
+func (s *Server) render(ctx context.Context, key string, o Options) ([]byte, string, error) {
+	timer := time.NewTimer(s.queueTimeout)
+	defer timer.Stop()
+	select {
+	case s.workers <- struct{}{}:
+		defer func() { <-s.workers }()
+	case <-timer.C:
+		return nil, "", errBusy
+	}
+
+	file, err := s.store.Get(ctx, key)
+	if err != nil {
+		return nil, "", err
+	}
+	defer file.Close()
+	source, err := io.ReadAll(file)
+	if err != nil {
+		return nil, "", err
+	}
+	config, format, err := image.DecodeConfig(bytes.NewReader(source))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", errInvalidImage, err)
+	}
+	if int64(config.Width)*int64(config.Height) > s.maxSourcePixels {
+		return nil, "", fmt.Errorf("%w: source exceeds %d pixels", errInvalidImage, s.maxSourcePixels)
+	}
+	img, _, err := image.Decode(bytes.NewReader(source))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", errInvalidImage, err)
+	}
+	img = transform(img, o.Width, o.Height, o.Fit)
+
+	if o.Format != "" {
+		format = o.Format
+	}
+	var buffer bytes.Buffer
+	switch format {
+	case "png":
+		err = png.Encode(&buffer, img)
+		return buffer.Bytes(), "image/png", err
+	case "gif":
+		err = gif.Encode(&buffer, img, nil)
+		return buffer.Bytes(), "image/gif", err
+	default:
+		quality := o.Quality
+		if quality == 0 {
+			quality = 85
+		}
+		err = jpeg.Encode(&buffer, img, &jpeg.Options{Quality: quality})
+		return buffer.Bytes(), "image/jpeg", err
+	}
+}
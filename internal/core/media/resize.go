@@ -0,0 +1,146 @@
+package media
+
+import (
+	"image"
+	"image/draw"
+)
+
+// Fit modes, deciding how images are fitted into the requested size.
+const (
+	// FitContain scales the image to fit within the size, keeping its aspect ratio.
+	FitContain = "contain"
+	// FitCover scales the image to cover the size, keeping its aspect ratio, and crops the
+	// overflow around the center.
+	FitCover = "cover"
+	// FitFill stretches the image to the size.
+	FitFill = "fill"
+)
+
+// transform resizes src to width x height according to fit. A zero width or height follows
+// from the aspect ratio.
+func transform(src image.Image, width, height int, fit string) image.Image {
+	bounds := src.Bounds()
+	sw, sh := bounds.Dx(), bounds.Dy()
+	if sw == 0 || sh == 0 {
+		return src
+	}
+	if width == 0 && height == 0 {
+		width, height = sw, sh
+	}
+	if width == 0 {
+		width = max(1, sw*height/sh)
+		fit = FitFill
+	}
+	if height == 0 {
+		height = max(1, sh*width/sw)
+		fit = FitFill
+	}
+
+	switch fit {
+	case FitFill:
+		return resample(toRGBA(src), width, height)
+	case FitCover:
+		// Crop the source to the aspect ratio of the target, then scale
+		crop := bounds
+		if sw*height > sh*width {
+			cw := sh * width / height
+			crop.Min.X += (sw - cw) / 2
+			crop.Max.X = crop.Min.X + cw
+		} else {
+			ch := sw * height / width
+			crop.Min.Y += (sh - ch) / 2
+			crop.Max.Y = crop.Min.Y + ch
+		}
+		rgba := image.NewRGBA(image.Rect(0, 0, crop.Dx(), crop.Dy()))
+		draw.Draw(rgba, rgba.Bounds(), src, crop.Min, draw.Src)
+		return resample(rgba, width, height)
+	default:
+		if sw*height > sh*width {
+			height = max(1, sh*width/sw)
+		} else {
+			width = max(1, sw*height/sh)
+		}
+		return resample(toRGBA(src), width, height)
+	}
+}
+
+func toRGBA(src image.Image) *image.RGBA {
+	if rgba, ok := src.(*image.RGBA); ok && rgba.Rect.Min == (image.Point{}) {
+		return rgba
+	}
+	bounds := src.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+	return rgba
+}
+
+// resample scales src to width x height, averaging the covered source pixels when shrinking
+// and interpolating bilinearly when enlarging.
+func resample(src *image.RGBA, width, height int) *image.RGBA {
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	if sw == width && sh == height {
+		return src
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	if width <= sw && height <= sh {
+		boxResample(src, dst)
+	} else {
+		bilinearResample(src, dst)
+	}
+	return dst
+}
+
+func boxResample(src, dst *image.RGBA) {
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	dw, dh := dst.Rect.Dx(), dst.Rect.Dy()
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*sh/dh, max((y+1)*sh/dh, y*sh/dh+1)
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*sw/dw, max((x+1)*sw/dw, x*sw/dw+1)
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				offset := sy*src.Stride + x0*4
+				for sx := x0; sx < x1; sx++ {
+					r += uint64(src.Pix[offset])
+					g += uint64(src.Pix[offset+1])
+					b += uint64(src.Pix[offset+2])
+					a += uint64(src.Pix[offset+3])
+					offset += 4
+					n++
+				}
+			}
+			i := y*dst.Stride + x*4
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+}
+
+func bilinearResample(src, dst *image.RGBA) {
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	dw, dh := dst.Rect.Dx(), dst.Rect.Dy()
+	for y := 0; y < dh; y++ {
+		fy := max(0, (float64(y)+0.5)*float64(sh)/float64(dh)-0.5)
+		y0 := int(fy)
+		y1 := min(y0+1, sh-1)
+		wy := fy - float64(y0)
+		for x := 0; x < dw; x++ {
+			fx := max(0, (float64(x)+0.5)*float64(sw)/float64(dw)-0.5)
+			x0 := int(fx)
+			x1 := min(x0+1, sw-1)
+			wx := fx - float64(x0)
+			i := y*dst.Stride + x*4
+			for c := 0; c < 4; c++ {
+				p00 := float64(src.Pix[y0*src.Stride+x0*4+c])
+				p01 := float64(src.Pix[y0*src.Stride+x1*4+c])
+				p10 := float64(src.Pix[y1*src.Stride+x0*4+c])
+				p11 := float64(src.Pix[y1*src.Stride+x1*4+c])
+				top := p00 + (p01-p00)*wx
+				bottom := p10 + (p11-p10)*wx
+				dst.Pix[i+c] = uint8(top + (bottom-top)*wy + 0.5)
+			}
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package messaging
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	kafkaV2JSON   = "application/vnd.kafka.v2+json"
+	kafkaV2Binary = "application/vnd.kafka.binary.v2+json"
+)
+
+// KafkaBroker publishes and consumes Kafka topics through the Confluent REST Proxy (API v2),
+// so no Kafka client library or native dependency is needed. Each subscription is a consumer
+// instance of its group; offsets are committed after the handler returned, so messages are
+// delivered at least once.
+type KafkaBroker struct {
+	baseURL      string
+	client       *http.Client
+	pollInterval time.Duration
+	offsetReset  string
+
+	mu     sync.Mutex
+	subs   map[*kafkaSubscription]struct{}
+	closed bool
+}
+
+type kafkaSubscription struct {
+	broker  *KafkaBroker
+	baseURI string
+	handler Handler
+	cancel  context.CancelFunc
+	done    chan struct{}
+	once    sync.Once
+}
+
+// NewKafkaBroker creates a broker for the REST Proxy at baseURL, e.g. http://localhost:8082.
+// It does not speak the Kafka wire protocol: a Confluent REST Proxy must run in front of the
+// cluster, and baseURL must point to it rather than to a Kafka broker.
+func NewKafkaBroker(baseURL string, options ...func(*KafkaBroker)) *KafkaBroker {
+	b := &KafkaBroker{
+		baseURL:      strings.TrimRight(baseURL, "/"),
+		client:       &http.Client{Timeout: 30 * time.Second},
+		pollInterval: time.Second,
+		offsetReset:  "earliest",
+		subs:         map[*kafkaSubscription]struct{}{},
+	}
+	for _, option := range options {
+		option(b)
+	}
+	return b
+}
+
+// WithKafkaHTTPClient sets the HTTP client used to reach the REST Proxy, e.g. for TLS or auth.
+func WithKafkaHTTPClient(client *http.Client) func(*KafkaBroker) {
+	return func(b *KafkaBroker) {
+		b.client = client
+	}
+}
+
+// WithKafkaPollInterval sets how long consumers wait between empty polls.
+func WithKafkaPollInterval(interval time.Duration) func(*KafkaBroker) {
+	return func(b *KafkaBroker) {
+		b.pollInterval = interval
+	}
+}
+
+// WithKafkaOffsetReset sets where new consumer groups start, earliest or latest.
+func WithKafkaOffsetReset(reset string) func(*KafkaBroker) {
+	return func(b *KafkaBroker) {
+		b.offsetReset = reset
+	}
+}
+
+type kafkaRecord struct {
+	Topic     string  `json:"topic,omitempty"`
+	Key       *string `json:"key,omitempty"`
+	Value     *string `json:"value"`
+	Partition int     `json:"partition,omitempty"`
+	Offset    int64   `json:"offset,omitempty"`
+}
+
+func (b *KafkaBroker) Publish(ctx context.Context, msg *Message) error {
+	value := base64.StdEncoding.EncodeToString(msg.Data)
+	record := kafkaRecord{Value: &value}
+	if msg.Key != "" {
+		key := base64.StdEncoding.EncodeToString([]byte(msg.Key))
+		record.Key = &key
+	}
+	body := map[string][]kafkaRecord{"records": {record}}
+	return b.do(ctx, http.MethodPost, b.baseURL+"/topics/"+url.PathEscape(msg.Subject), kafkaV2Binary, body, nil)
+}
+
+func (b *KafkaBroker) Subscribe(subject, group string, handler Handler) (Subscription, error) {
+	b.mu.Lock()
+	closed := b.closed
+	b.mu.Unlock()
+	if closed {
+		return nil, ErrClosed
+	}
+	name := randomName()
+	if group == "" {
+		// Every subscriber without a group receives all messages
+		group = "lessgo-" + name
+	}
+
+	ctx := context.Background()
+	var instance struct {
+		BaseURI string `json:"base_uri"`
+	}
+	err := b.do(ctx, http.MethodPost, b.baseURL+"/consumers/"+url.PathEscape(group), kafkaV2JSON, map[string]string{
+		"name":               name,
+		"format":             "binary",
+		"auto.offset.reset":  b.offsetReset,
+		"auto.commit.enable": "false",
+	}, &instance)
+	if err != nil {
+		return nil, err
+	}
+	sub := &kafkaSubscription{broker: b, baseURI: instance.BaseURI, handler: handler, done: make(chan struct{})}
+	if err := b.do(ctx, http.MethodPost, sub.baseURI+"/subscription", kafkaV2JSON, map[string][]string{"topics": {subject}}, nil); err != nil {
+		sub.delete()
+		return nil, err
+	}
+
+	ctx, sub.cancel = context.WithCancel(ctx)
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+	go sub.poll(ctx)
+	return sub, nil
+}
+
+// poll fetches records until the subscription is cancelled, handling them in order and
+// committing the offsets of each batch.
+func (s *kafkaSubscription) poll(ctx context.Context) {
+	defer close(s.done)
+	b := s.broker
+	for ctx.Err() == nil {
+		var records []kafkaRecord
+		if err := b.do(ctx, http.MethodGet, s.baseURI+"/records", kafkaV2Binary, nil, &records); err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Error polling Kafka records: %v", err)
+				sleep(ctx, b.pollInterval)
+			}
+			continue
+		}
+		if len(records) == 0 {
+			sleep(ctx, b.pollInterval)
+			continue
+		}
+
+		var offsets []map[string]interface{}
+		for _, record := range records {
+			msg := &Message{Subject: record.Topic}
+			if record.Value != nil {
+				msg.Data, _ = base64.StdEncoding.DecodeString(*record.Value)
+			}
+			if record.Key != nil {
+				key, _ := base64.StdEncoding.DecodeString(*record.Key)
+				msg.Key = string(key)
+			}
+			s.handler(context.WithoutCancel(ctx), msg)
+			offsets = append(offsets, map[string]interface{}{
+				"topic":     record.Topic,
+				"partition": record.Partition,
+				"offset":    record.Offset,
+			})
+		}
+		// The commit must go through even when unsubscribing meanwhile
+		if err := b.do(context.WithoutCancel(ctx), http.MethodPost, s.baseURI+"/offsets", kafkaV2JSON, map[string]interface{}{"offsets": offsets}, nil); err != nil {
+			log.Printf("Error committing Kafka offsets: %v", err)
+		}
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
+
+// Unsubscribe stops polling once the current batch is handled and deletes the consumer
+// instance, so its partitions are reassigned to the rest of the group.
+func (s *kafkaSubscription) Unsubscribe() error {
+	var err error
+	s.once.Do(func() {
+		s.cancel()
+		<-s.done
+		s.broker.mu.Lock()
+		delete(s.broker.subs, s)
+		s.broker.mu.Unlock()
+		err = s.delete()
+	})
+	return err
+}
+
+func (s *kafkaSubscription) delete() error {
+	return s.broker.do(context.Background(), http.MethodDelete, s.baseURI, kafkaV2JSON, nil, nil)
+}
+
+// Close unsubscribes all subscriptions.
+func (b *KafkaBroker) Close() error {
+	b.mu.Lock()
+	b.closed = true
+	subs := make([]*kafkaSubscription, 0, len(b.subs))
+	for sub := range b.subs {
+		subs = append(subs, sub)
+	}
+	b.mu.Unlock()
+	for _, sub := range subs {
+		sub.Unsubscribe()
+	}
+	return nil
+}
+
+// do sends a request to the REST Proxy and decodes the JSON response into out.
+func (b *KafkaBroker) do(ctx context.Context, method, target, contentType string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", contentType)
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("messaging: kafka rest proxy: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("messaging: kafka rest proxy %s %s: %s: %s", method, target, resp.Status, strings.TrimSpace(string(detail)))
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func randomName() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
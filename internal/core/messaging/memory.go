@@ -0,0 +1,133 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrClosed is returned when using a closed broker.
+var ErrClosed = errors.New("messaging: broker closed")
+
+// MemoryBroker delivers messages in process. Each subscription receives its messages in
+// order, on its own goroutine.
+type MemoryBroker struct {
+	mu     sync.Mutex
+	subs   []*memorySubscription
+	next   map[string]int // round robin position per subject pattern and group
+	closed bool
+}
+
+type memorySubscription struct {
+	broker   *MemoryBroker
+	subject  string
+	group    string
+	handler  Handler
+	messages chan *Message
+	once     sync.Once
+	done     chan struct{}
+}
+
+// NewMemoryBroker creates an in-process broker.
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{next: map[string]int{}}
+}
+
+func (b *MemoryBroker) Publish(ctx context.Context, msg *Message) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return ErrClosed
+	}
+	var targets []*memorySubscription
+	groups := map[string][]*memorySubscription{}
+	var order []string
+	for _, sub := range b.subs {
+		if !MatchSubject(sub.subject, msg.Subject) {
+			continue
+		}
+		if sub.group == "" {
+			targets = append(targets, sub)
+			continue
+		}
+		key := sub.subject + " " + sub.group
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], sub)
+	}
+	for _, key := range order {
+		members := groups[key]
+		targets = append(targets, members[b.next[key]%len(members)])
+		b.next[key]++
+	}
+	b.mu.Unlock()
+
+	for _, sub := range targets {
+		select {
+		case sub.messages <- msg:
+		case <-sub.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (b *MemoryBroker) Subscribe(subject, group string, handler Handler) (Subscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, ErrClosed
+	}
+	sub := &memorySubscription{
+		broker:   b,
+		subject:  subject,
+		group:    group,
+		handler:  handler,
+		messages: make(chan *Message, 64),
+		done:     make(chan struct{}),
+	}
+	b.subs = append(b.subs, sub)
+	go sub.run()
+	return sub, nil
+}
+
+func (s *memorySubscription) run() {
+	for {
+		select {
+		case msg := <-s.messages:
+			s.handler(context.Background(), msg)
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *memorySubscription) Unsubscribe() error {
+	s.once.Do(func() {
+		b := s.broker
+		b.mu.Lock()
+		for i, sub := range b.subs {
+			if sub == s {
+				b.subs = append(b.subs[:i], b.subs[i+1:]...)
+				break
+			}
+		}
+		b.mu.Unlock()
+		close(s.done)
+	})
+	return nil
+}
+
+// Close unsubscribes all subscriptions.
+func (b *MemoryBroker) Close() error {
+	b.mu.Lock()
+	b.closed = true
+	subs := append([]*memorySubscription(nil), b.subs...)
+	b.mu.Unlock()
+	for _, sub := range subs {
+		sub.Unsubscribe()
+	}
+	return nil
+}
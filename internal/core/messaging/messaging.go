@@ -0,0 +1,239 @@
+/*
+Package messaging connects services to a message broker for event-driven communication.
+
+A Broker publishes messages to subjects (topics on Kafka) and delivers them to subscribers.
+Subscribers sharing a group split the messages between them, like a consumer group, while
+subscribers without a group each receive every message. MemoryBroker runs in process for tests
+and single instance deployments, NATSBroker speaks the NATS protocol and KafkaBroker talks to
+Kafka through the Confluent REST Proxy.
+
+A Dispatcher registers the handlers of the application, typically declared by modules through
+the Subscriber interface, and drains them on shutdown.
+
+Usage:
+
+	broker, err := messaging.NewNATSBroker("nats://localhost:4222")
+	if err != nil {
+		log.Fatal(err)
+	}
+	dispatcher := messaging.NewDispatcher(broker)
+	dispatcher.Handle("orders.created", "billing", func(ctx context.Context, msg *messaging.Message) error {
+		return billing.Invoice(ctx, msg.Data)
+	})
+	if err := dispatcher.Start(); err != nil {
+		log.Fatal(err)
+	}
+	defer dispatcher.Shutdown(context.Background())
+
+	broker.Publish(ctx, &messaging.Message{Subject: "orders.created", Data: payload})
+*/
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"strings"
+	"sync"
+
+	"github.com/hokamsingh/lessgo/internal/core/module"
+)
+
+// Message is a message published to or received from a broker.
+type Message struct {
+	// Subject is the NATS subject or Kafka topic.
+	Subject string
+	Data    []byte
+	// Key partitions Kafka messages; messages with the same key keep their order.
+	Key string
+	// Headers are carried by NATS and the memory broker. The Kafka REST Proxy drops them.
+	Headers map[string]string
+}
+
+// Handler processes a received message.
+type Handler func(ctx context.Context, msg *Message) error
+
+// Broker publishes and delivers messages.
+type Broker interface {
+	Publish(ctx context.Context, msg *Message) error
+	// Subscribe delivers the messages of subject to handler. Subscribers with the same
+	// non-empty group share the messages, each is delivered to one of them.
+	Subscribe(subject, group string, handler Handler) (Subscription, error)
+	Close() error
+}
+
+// Subscription stops the delivery of messages to a handler.
+type Subscription interface {
+	Unsubscribe() error
+}
+
+// Subscriber is implemented by controllers and services that consume messages, so the
+// handlers of a module are registered along with it.
+//
+// Example usage:
+//
+//	func (s *BillingService) RegisterSubscriptions(d *messaging.Dispatcher) {
+//		d.Handle("orders.created", "billing", s.onOrderCreated)
+//	}
+type Subscriber interface {
+	RegisterSubscriptions(d *Dispatcher)
+}
+
+type route struct {
+	subject string
+	group   string
+	handler Handler
+}
+
+// Dispatcher runs the registered handlers on a broker. Panics and errors of handlers are
+// logged, and Shutdown waits for the handlers still running.
+type Dispatcher struct {
+	broker Broker
+
+	mu            sync.Mutex
+	routes        []route
+	subscriptions []Subscription
+	started       bool
+
+	runningMu sync.Mutex // guards running and idle
+	running   int
+	idle      chan struct{} // closed when running drops to zero during Shutdown
+}
+
+// NewDispatcher creates a dispatcher for broker.
+func NewDispatcher(broker Broker) *Dispatcher {
+	return &Dispatcher{broker: broker}
+}
+
+// Broker returns the broker of the dispatcher, e.g. to publish.
+func (d *Dispatcher) Broker() Broker {
+	return d.broker
+}
+
+// Handle registers handler for the messages of subject. Handlers registered after Start are
+// subscribed right away.
+func (d *Dispatcher) Handle(subject, group string, handler Handler) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	r := route{subject: subject, group: group, handler: handler}
+	d.routes = append(d.routes, r)
+	if d.started {
+		return d.subscribe(r)
+	}
+	return nil
+}
+
+// Start subscribes the registered handlers.
+func (d *Dispatcher) Start() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.started {
+		return nil
+	}
+	for _, r := range d.routes {
+		if err := d.subscribe(r); err != nil {
+			return err
+		}
+	}
+	d.started = true
+	return nil
+}
+
+func (d *Dispatcher) subscribe(r route) error {
+	sub, err := d.broker.Subscribe(r.subject, r.group, d.wrap(r))
+	if err != nil {
+		return fmt.Errorf("messaging: subscribing to %s: %w", r.subject, err)
+	}
+	d.subscriptions = append(d.subscriptions, sub)
+	return nil
+}
+
+func (d *Dispatcher) wrap(r route) Handler {
+	return func(ctx context.Context, msg *Message) (err error) {
+		d.runningMu.Lock()
+		d.running++
+		d.runningMu.Unlock()
+		defer d.done()
+		defer func() {
+			if rec := recover(); rec != nil {
+				err = fmt.Errorf("panic: %v", rec)
+				log.Printf("Panic handling message on %s: %v\n%s", msg.Subject, rec, debug.Stack())
+			}
+		}()
+		if err = r.handler(ctx, msg); err != nil {
+			log.Printf("Error handling message on %s: %v", msg.Subject, err)
+		}
+		return err
+	}
+}
+
+func (d *Dispatcher) done() {
+	d.runningMu.Lock()
+	defer d.runningMu.Unlock()
+	d.running--
+	if d.running == 0 && d.idle != nil {
+		close(d.idle)
+		d.idle = nil
+	}
+}
+
+// Shutdown unsubscribes all handlers and waits until the running ones return or ctx is done.
+func (d *Dispatcher) Shutdown(ctx context.Context) error {
+	d.mu.Lock()
+	subscriptions := d.subscriptions
+	d.subscriptions = nil
+	d.started = false
+	d.mu.Unlock()
+
+	for _, sub := range subscriptions {
+		if err := sub.Unsubscribe(); err != nil {
+			log.Printf("Error unsubscribing: %v", err)
+		}
+	}
+	d.runningMu.Lock()
+	if d.running == 0 {
+		d.runningMu.Unlock()
+		return nil
+	}
+	if d.idle == nil {
+		d.idle = make(chan struct{})
+	}
+	idle := d.idle
+	d.runningMu.Unlock()
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RegisterModules registers the handlers of the controllers and services of the modules that
+// implement Subscriber.
+func RegisterModules(d *Dispatcher, modules []module.IModule) {
+	for _, m := range modules {
+		components := append(append([]interface{}{}, m.GetControllers()...), m.GetServices()...)
+		for _, component := range components {
+			if s, ok := component.(Subscriber); ok {
+				s.RegisterSubscriptions(d)
+			}
+		}
+	}
+}
+
+// MatchSubject reports whether subject matches pattern, where * matches one token and a
+// trailing > matches one or more tokens, e.g. orders.* or orders.>.
+func MatchSubject(pattern, subject string) bool {
+	patternTokens := strings.Split(pattern, ".")
+	subjectTokens := strings.Split(subject, ".")
+	for i, token := range patternTokens {
+		if token == ">" && i == len(patternTokens)-1 {
+			return len(subjectTokens) > i
+		}
+		if i >= len(subjectTokens) || (token != "*" && token != subjectTokens[i]) {
+			return false
+		}
+	}
+	return len(patternTokens) == len(subjectTokens)
+}
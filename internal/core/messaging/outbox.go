@@ -0,0 +1,324 @@
+package messaging
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hokamsingh/lessgo/internal/core/cache"
+	"github.com/hokamsingh/lessgo/internal/core/database"
+)
+
+// MessageIDHeader carries the ID of the outbox event a message was published from. The relay
+// delivers events at least once, so consumers use it to drop duplicates, see Deduplicate. As
+// the Kafka REST Proxy drops headers, Kafka consumers rely on idempotent handlers instead.
+const MessageIDHeader = "Message-Id"
+
+// Execer runs a statement, implemented by *sql.DB and *sql.Tx.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// Outbox makes publishing reliable with the transactional outbox pattern: events are inserted
+// in the same transaction as the state changes they describe, then a relay publishes them to
+// the broker, retrying with exponential backoff until the broker accepts them.
+//
+// The table has the columns id, subject, msg_key, headers, data, seq, created_at, attempts,
+// next_attempt_at, published_at and last_error, see CreateTable. Times are Unix milliseconds;
+// seq orders the events, it increases with every event added by the process and follows the
+// clock across processes.
+// Relays running on several instances may publish an event twice, which consumers already
+// handle as redeliveries.
+type Outbox struct {
+	db         *sql.DB
+	broker     Broker
+	table      string
+	dialect    database.Dialect
+	interval   time.Duration
+	batchSize  int
+	maxBackoff time.Duration
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewOutbox creates an outbox stored in table and relayed to broker. Queries use ?
+// placeholders unless WithOutboxDialect names the dialect of another driver.
+func NewOutbox(db *sql.DB, broker Broker, table string, options ...func(*Outbox)) *Outbox {
+	o := &Outbox{
+		db:         db,
+		broker:     broker,
+		table:      table,
+		interval:   time.Second,
+		batchSize:  100,
+		maxBackoff: 5 * time.Minute,
+	}
+	for _, option := range options {
+		option(o)
+	}
+	return o
+}
+
+// WithOutboxDialect sets the placeholder style of the database driver.
+//
+// Example usage:
+//
+//	outbox := messaging.NewOutbox(db, broker, "outbox", messaging.WithOutboxDialect(database.DialectOf("postgres")))
+func WithOutboxDialect(dialect database.Dialect) func(*Outbox) {
+	return func(o *Outbox) {
+		o.dialect = dialect
+	}
+}
+
+// WithOutboxInterval sets how often the relay looks for pending events.
+func WithOutboxInterval(interval time.Duration) func(*Outbox) {
+	return func(o *Outbox) {
+		o.interval = interval
+	}
+}
+
+// WithOutboxBatchSize sets how many events the relay publishes per round.
+func WithOutboxBatchSize(n int) func(*Outbox) {
+	return func(o *Outbox) {
+		o.batchSize = n
+	}
+}
+
+// WithOutboxMaxBackoff caps the delay between attempts to publish an event.
+func WithOutboxMaxBackoff(d time.Duration) func(*Outbox) {
+	return func(o *Outbox) {
+		o.maxBackoff = d
+	}
+}
+
+// CreateTable creates the outbox table if it does not exist.
+func (o *Outbox) CreateTable(ctx context.Context) error {
+	blob := "BLOB"
+	if o.dialect == database.Dollar {
+		blob = "BYTEA"
+	}
+	_, err := o.db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id VARCHAR(36) PRIMARY KEY,
+	subject VARCHAR(255) NOT NULL,
+	msg_key VARCHAR(255) NOT NULL,
+	headers TEXT NOT NULL,
+	data %s,
+	seq BIGINT NOT NULL,
+	created_at BIGINT NOT NULL,
+	attempts INTEGER NOT NULL,
+	next_attempt_at BIGINT NOT NULL,
+	published_at BIGINT,
+	last_error TEXT
+)`, o.table, blob))
+	return err
+}
+
+// Add inserts msg into the outbox within tx and returns the event ID, sent to consumers in
+// the MessageIDHeader. The event is published once tx commits.
+//
+// Example usage:
+//
+//	tx, err := db.BeginTx(ctx, nil)
+//	...
+//	tx.ExecContext(ctx, "UPDATE orders SET status = 'paid' WHERE id = ?", id)
+//	outbox.Add(ctx, tx, &messaging.Message{Subject: "orders.paid", Key: id, Data: payload})
+//	err = tx.Commit()
+func (o *Outbox) Add(ctx context.Context, tx Execer, msg *Message) (string, error) {
+	headers, err := json.Marshal(msg.Headers)
+	if err != nil {
+		return "", err
+	}
+	id := uuid.New().String()
+	now := time.Now().UnixMilli()
+	_, err = tx.ExecContext(ctx, o.dialect.Rebind(fmt.Sprintf(
+		"INSERT INTO %s (id, subject, msg_key, headers, data, seq, created_at, attempts, next_attempt_at) VALUES (?, ?, ?, ?, ?, ?, ?, 0, ?)", o.table)),
+		id, msg.Subject, msg.Key, string(headers), msg.Data, nextOutboxSeq(), now, now)
+	if err != nil {
+		return "", fmt.Errorf("messaging: adding to outbox: %w", err)
+	}
+	return id, nil
+}
+
+// lastOutboxSeq is the last sequence number given to an event.
+var lastOutboxSeq atomic.Int64
+
+// nextOutboxSeq returns the sequence number of a new event: the current time in nanoseconds,
+// or one more than the previous number when the clock did not advance.
+func nextOutboxSeq() int64 {
+	for {
+		last := lastOutboxSeq.Load()
+		seq := max(time.Now().UnixNano(), last+1)
+		if lastOutboxSeq.CompareAndSwap(last, seq) {
+			return seq
+		}
+	}
+}
+
+type outboxEvent struct {
+	id       string
+	msg      *Message
+	attempts int
+}
+
+// Relay publishes the pending events that are due, in the order they were added, and returns
+// how many were published. Events that fail are retried by a later round. The following
+// events with the same key wait until the failed one is published, which keeps their order.
+func (o *Outbox) Relay(ctx context.Context) (int, error) {
+	now := time.Now().UnixMilli()
+	// Events behind an earlier event of their key that is backing off are left out
+	rows, err := o.db.QueryContext(ctx, o.dialect.Rebind(fmt.Sprintf(
+		`SELECT id, subject, msg_key, headers, data, attempts FROM %[1]s e
+WHERE published_at IS NULL AND next_attempt_at <= ? AND (msg_key = '' OR NOT EXISTS (
+	SELECT 1 FROM %[1]s p WHERE p.msg_key = e.msg_key AND p.published_at IS NULL AND p.next_attempt_at > ? AND p.seq < e.seq))
+ORDER BY seq LIMIT %[2]d`,
+		o.table, o.batchSize)), now, now)
+	if err != nil {
+		return 0, fmt.Errorf("messaging: reading outbox: %w", err)
+	}
+	var events []outboxEvent
+	for rows.Next() {
+		var event outboxEvent
+		var headers string
+		event.msg = &Message{}
+		if err := rows.Scan(&event.id, &event.msg.Subject, &event.msg.Key, &headers, &event.msg.Data, &event.attempts); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("messaging: reading outbox: %w", err)
+		}
+		json.Unmarshal([]byte(headers), &event.msg.Headers)
+		if event.msg.Headers == nil {
+			event.msg.Headers = map[string]string{}
+		}
+		event.msg.Headers[MessageIDHeader] = event.id
+		events = append(events, event)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("messaging: reading outbox: %w", err)
+	}
+
+	published := 0
+	failedKeys := map[string]bool{}
+	for _, event := range events {
+		if event.msg.Key != "" && failedKeys[event.msg.Key] {
+			continue
+		}
+		now := time.Now()
+		if err := o.broker.Publish(ctx, event.msg); err != nil {
+			failedKeys[event.msg.Key] = true
+			attempts := event.attempts + 1
+			log.Printf("Error publishing outbox event %s on %s (attempt %d): %v", event.id, event.msg.Subject, attempts, err)
+			_, err = o.db.ExecContext(ctx, o.dialect.Rebind(fmt.Sprintf(
+				"UPDATE %s SET attempts = ?, next_attempt_at = ?, last_error = ? WHERE id = ?", o.table)),
+				attempts, now.Add(o.backoff(attempts)).UnixMilli(), err.Error(), event.id)
+			if err != nil {
+				return published, fmt.Errorf("messaging: updating outbox: %w", err)
+			}
+			continue
+		}
+		// Should this fail, the event is published again: consumers deduplicate on its ID
+		_, err := o.db.ExecContext(ctx, o.dialect.Rebind(fmt.Sprintf(
+			"UPDATE %s SET published_at = ?, attempts = ? WHERE id = ?", o.table)),
+			now.UnixMilli(), event.attempts+1, event.id)
+		if err != nil {
+			return published, fmt.Errorf("messaging: updating outbox: %w", err)
+		}
+		published++
+	}
+	return published, nil
+}
+
+// backoff returns the delay before the next attempt, doubling from one second.
+func (o *Outbox) backoff(attempts int) time.Duration {
+	d := time.Second
+	for i := 1; i < attempts && d < o.maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, o.maxBackoff)
+}
+
+// Start runs the relay in the background until Stop.
+func (o *Outbox) Start() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	o.cancel, o.done = cancel, make(chan struct{})
+	go func() {
+		defer close(o.done)
+		ticker := time.NewTicker(o.interval)
+		defer ticker.Stop()
+		for {
+			// Relay full batches right away, there are likely more events waiting
+			n, err := o.Relay(ctx)
+			if err != nil && ctx.Err() == nil {
+				log.Printf("Error relaying outbox: %v", err)
+			}
+			if n == o.batchSize && err == nil {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops the relay, waiting for the current round or until ctx is done.
+func (o *Outbox) Stop(ctx context.Context) error {
+	o.mu.Lock()
+	cancel, done := o.cancel, o.done
+	o.cancel, o.done = nil, nil
+	o.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Purge deletes the events published before the given time and returns how many were deleted.
+func (o *Outbox) Purge(ctx context.Context, before time.Time) (int64, error) {
+	result, err := o.db.ExecContext(ctx, o.dialect.Rebind(fmt.Sprintf(
+		"DELETE FROM %s WHERE published_at IS NOT NULL AND published_at < ?", o.table)), before.UnixMilli())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// Deduplicate wraps handler so messages with a MessageIDHeader already handled successfully in
+// the last ttl are skipped. The IDs are remembered in seen, so only the duplicates delivered to
+// the same instance are detected.
+func Deduplicate(seen *cache.Cache, ttl time.Duration, handler Handler) Handler {
+	return func(ctx context.Context, msg *Message) error {
+		id := msg.Headers[MessageIDHeader]
+		if id == "" {
+			return handler(ctx, msg)
+		}
+		key := "message-id:" + id
+		if _, ok := seen.Get(key); ok {
+			return nil
+		}
+		if err := handler(ctx, msg); err != nil {
+			return err
+		}
+		seen.Set(key, nil, ttl)
+		return nil
+	}
+}
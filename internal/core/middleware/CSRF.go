@@ -2,26 +2,80 @@ package middleware
 
 import (
 	"crypto/rand"
+	"crypto/subtle"
 	"encoding/base64"
 	"io"
 	"log"
 	"net/http"
+	"strings"
+
+	"github.com/hokamsingh/lessgo/internal/core/crypto"
 )
 
-type CSRFProtection struct{}
+type CSRFProtection struct {
+	keyring *crypto.Keyring
+	session func(r *http.Request) string
+}
 
-func NewCSRFProtection() *CSRFProtection {
-	return &CSRFProtection{}
+func NewCSRFProtection(options ...func(*CSRFProtection)) *CSRFProtection {
+	csrf := &CSRFProtection{}
+	for _, option := range options {
+		option(csrf)
+	}
+	return csrf
+}
+
+// WithCSRFKeyring signs the tokens, so tokens not issued by the application are rejected. A
+// signed token is still valid for anyone who obtains one, so to reject csrf_token cookies
+// planted by a sibling subdomain or a man in the middle on plain HTTP, bind the signature to
+// the session with WithCSRFSession.
+func WithCSRFKeyring(keyring *crypto.Keyring) func(*CSRFProtection) {
+	return func(csrf *CSRFProtection) {
+		csrf.keyring = keyring
+	}
+}
+
+// WithCSRFSession binds the signatures of WithCSRFKeyring to the session or user identifier
+// returned by session, so a token issued to one session is rejected in another one. It falls
+// back to the principal of the request when session is nil.
+//
+// Example usage:
+//
+//	csrf := middleware.NewCSRFProtection(
+//		middleware.WithCSRFKeyring(keyring),
+//		middleware.WithCSRFSession(func(r *http.Request) string {
+//			cookie, _ := r.Cookie("session_id")
+//			if cookie == nil {
+//				return ""
+//			}
+//			return cookie.Value
+//		}),
+//	)
+func WithCSRFSession(session func(r *http.Request) string) func(*CSRFProtection) {
+	return func(csrf *CSRFProtection) {
+		if session == nil {
+			session = principalSession
+		}
+		csrf.session = session
+	}
+}
+
+// principalSession identifies the session by the user of the principal of the request.
+func principalSession(r *http.Request) string {
+	if principal, ok := GetPrincipal(r.Context()); ok {
+		return principal.User
+	}
+	return ""
 }
 
 func (csrf *CSRFProtection) Handle(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodGet {
 			// Retrieve or set CSRF token for GET requests
-			_, err := getCSRFCookie(r)
-			if err != nil {
+			cookie, err := getCSRFCookie(r)
+			if err != nil || !csrf.validSignature(r, cookie) {
 				// Generate and set a new CSRF token if not present
-				token, err := GenerateCSRFToken()
+				token, err := csrf.generate(r)
 				if err != nil {
 					http.Error(w, "Failed to generate CSRF token", http.StatusInternalServerError)
 					return
@@ -30,7 +84,7 @@ func (csrf *CSRFProtection) Handle(next http.Handler) http.Handler {
 			}
 		} else if r.Method == http.MethodPost || r.Method == http.MethodPut || r.Method == http.MethodDelete {
 			// Validate CSRF token for state-changing requests
-			if !ValidateCSRFToken(r) {
+			if !ValidateCSRFToken(r) || !csrf.validSignature(r, r.Header.Get("X-CSRF-Token")) {
 				http.Error(w, "Invalid CSRF token", http.StatusForbidden)
 				return
 			}
@@ -39,6 +93,41 @@ func (csrf *CSRFProtection) Handle(next http.Handler) http.Handler {
 	})
 }
 
+// generate returns a new token, signed for the session of r when a keyring is set.
+func (csrf *CSRFProtection) generate(r *http.Request) (string, error) {
+	token, err := GenerateCSRFToken()
+	if err != nil || csrf.keyring == nil {
+		return token, err
+	}
+	signature := csrf.keyring.Sign(csrf.signed(r, token))
+	if signature == nil {
+		return "", crypto.ErrNoKeys
+	}
+	return token + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// signed returns the data signed for token: the token, bound to the session of r when set.
+func (csrf *CSRFProtection) signed(r *http.Request, token string) []byte {
+	if csrf.session == nil {
+		return []byte(token)
+	}
+	return []byte(csrf.session(r) + "\x00" + token)
+}
+
+// validSignature reports whether token was signed with the keyring for the session of r,
+// always true without a keyring.
+func (csrf *CSRFProtection) validSignature(r *http.Request, token string) bool {
+	if csrf.keyring == nil {
+		return true
+	}
+	value, encoded, found := strings.Cut(token, ".")
+	if !found {
+		return false
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encoded)
+	return err == nil && csrf.keyring.Verify(csrf.signed(r, value), signature)
+}
+
 // GenerateCSRFToken generates a new CSRF token.
 func GenerateCSRFToken() (string, error) {
 	token := make([]byte, 32) // 32 bytes = 256 bits
@@ -76,5 +165,5 @@ func ValidateCSRFToken(r *http.Request) bool {
 		return false
 	}
 	csrfToken := r.Header.Get("X-CSRF-Token") // Retrieve from request header
-	return csrfToken == cookie.Value
+	return csrfToken != "" && subtle.ConstantTimeCompare([]byte(csrfToken), []byte(cookie.Value)) == 1
 }
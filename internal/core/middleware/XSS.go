@@ -6,8 +6,15 @@ import (
 	"strings"
 )
 
+// XSSProtection rejects requests with a 400 when a query or form value, cookie or header
+// contains a pattern from a fixed list.
+//
+// Deprecated: use the sanitize package to clean the fields that end up in HTML.
 type XSSProtection struct{}
 
+// NewXSSProtection creates the XSS filter.
+//
+// Deprecated: use the sanitize package to clean the fields that end up in HTML.
 func NewXSSProtection() *XSSProtection {
 	return &XSSProtection{}
 }
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// AbortStats are the counters of an AbortCounter.
+type AbortStats struct {
+	Requests int64 `json:"requests"`
+	// Aborted counts the requests whose client went away before the handler returned.
+	Aborted int64 `json:"aborted"`
+	// Routes are the aborted requests per route, e.g. "GET /reports/{id}".
+	Routes map[string]int64 `json:"routes"`
+}
+
+// AbortCounter counts the requests abandoned by their client: the connection was closed or
+// the request cancelled, e.g. a browser navigating away, while the handler still ran.
+// Handlers notice it through ctx.Done() and ctx.IsAborted() and stop working; a route with
+// many aborts is usually too slow for its clients. Requests ended by a deadline are not
+// counted, they are answered with a timeout.
+type AbortCounter struct {
+	requests atomic.Int64
+	aborted  atomic.Int64
+
+	mu     sync.Mutex
+	routes map[string]int64
+}
+
+// NewAbortCounter creates the middleware counting aborted requests. Use it before the other
+// middlewares so every request is counted.
+//
+// Example usage:
+//
+//	aborts := middleware.NewAbortCounter()
+//	App.Use(aborts)
+//	App.ExposeAbortCounter(aborts)
+func NewAbortCounter() *AbortCounter {
+	return &AbortCounter{routes: map[string]int64{}}
+}
+
+func (a *AbortCounter) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r, _ = RequestValues(r)
+		next.ServeHTTP(w, r)
+		a.requests.Add(1)
+		if !errors.Is(r.Context().Err(), context.Canceled) {
+			return
+		}
+		a.aborted.Add(1)
+		route, _ := RequestRoute(r)
+		if route == "" {
+			route = r.URL.Path
+		}
+		a.mu.Lock()
+		a.routes[r.Method+" "+route]++
+		a.mu.Unlock()
+	})
+}
+
+// Stats returns the counters of the abort counter.
+func (a *AbortCounter) Stats() AbortStats {
+	a.mu.Lock()
+	routes := make(map[string]int64, len(a.routes))
+	for route, aborted := range a.routes {
+		routes[route] = aborted
+	}
+	a.mu.Unlock()
+	return AbortStats{Requests: a.requests.Load(), Aborted: a.aborted.Load(), Routes: routes}
+}
+
+// MetricsHandler serves the counters in the Prometheus text exposition format, the aborted
+// requests labeled by method and route.
+//
+// Example usage:
+//
+//	http.Handle("/metrics/aborted", aborts.MetricsHandler())
+func (a *AbortCounter) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stats := a.Stats()
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		fmt.Fprintf(w, "# HELP lessgo_http_requests_total Requests handled.\n# TYPE lessgo_http_requests_total counter\nlessgo_http_requests_total %d\n", stats.Requests)
+		fmt.Fprint(w, "# HELP lessgo_http_requests_aborted_total Requests abandoned by the client before the handler returned.\n# TYPE lessgo_http_requests_aborted_total counter\n")
+		routes := make([]string, 0, len(stats.Routes))
+		for route := range stats.Routes {
+			routes = append(routes, route)
+		}
+		sort.Strings(routes)
+		for _, route := range routes {
+			method, path, _ := strings.Cut(route, " ")
+			fmt.Fprintf(w, "lessgo_http_requests_aborted_total{method=%q,route=%q} %d\n", method, path, stats.Routes[route])
+		}
+	})
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// BearerAuth rejects requests without one of its tokens in the Authorization header, for
+// operational endpoints called by scripts rather than users.
+type BearerAuth struct {
+	tokens [][]byte
+}
+
+// NewBearerAuth creates the middleware accepting the tokens, several during a rotation. Empty
+// tokens are ignored, so without tokens every request is rejected.
+//
+// Example usage:
+//
+//	auth := middleware.NewBearerAuth(cfg.Get("ADMIN_TOKEN", ""))
+func NewBearerAuth(tokens ...string) *BearerAuth {
+	b := &BearerAuth{}
+	for _, token := range tokens {
+		if token != "" {
+			b.tokens = append(b.tokens, []byte(token))
+		}
+	}
+	return b
+}
+
+// IsAuthenticator implements Authenticator.
+func (b *BearerAuth) IsAuthenticator() bool { return true }
+
+func (b *BearerAuth) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || !b.valid([]byte(strings.TrimSpace(token))) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// valid compares the token with every accepted token in constant time.
+func (b *BearerAuth) valid(token []byte) bool {
+	found := 0
+	for _, accepted := range b.tokens {
+		found |= subtle.ConstantTimeCompare(token, accepted)
+	}
+	return found == 1 && len(token) > 0
+}
@@ -0,0 +1,159 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// DefaultBodyCaptureSize is the largest body BodyCapture keeps in memory by default.
+const DefaultBodyCaptureSize = 1 << 20
+
+// ErrBodyTooLarge is returned by RawBody when the body exceeded the capture limit.
+var ErrBodyTooLarge = errors.New("request body exceeds the capture limit")
+
+// bufferedBody is a request body held in memory, so middlewares and handlers can read it
+// again without another ReadAll. raw keeps the bytes as received, before any replacement.
+type bufferedBody struct {
+	*bytes.Reader
+	data []byte
+	raw  []byte
+}
+
+func (b *bufferedBody) Close() error {
+	return nil
+}
+
+type bodyTooLargeKey struct{}
+
+// BodyCapture reads request bodies up to a size limit into memory early in the chain, so
+// every later reader, including signature checks needing the exact bytes, shares one copy.
+// Larger bodies, e.g. file uploads, are streamed through unchanged.
+type BodyCapture struct {
+	maxSize int64
+}
+
+// NewBodyCapture creates the body capture middleware. A maxSize of 0 uses
+// DefaultBodyCaptureSize.
+func NewBodyCapture(maxSize int64) *BodyCapture {
+	if maxSize <= 0 {
+		maxSize = DefaultBodyCaptureSize
+	}
+	return &BodyCapture{maxSize: maxSize}
+}
+
+func (bc *BodyCapture) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body == nil || r.Body == http.NoBody || r.ContentLength > bc.maxSize {
+			if r.ContentLength > bc.maxSize {
+				r = r.WithContext(context.WithValue(r.Context(), bodyTooLargeKey{}, true))
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		if _, buffered := r.Body.(*bufferedBody); buffered {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		_, complete, err := PeekBody(r, bc.maxSize)
+		if err != nil {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+		if !complete {
+			r = r.WithContext(context.WithValue(r.Context(), bodyTooLargeKey{}, true))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ReadBody returns the request body and resets r.Body so it can be read again. A body that
+// is already held in memory is returned without reading it.
+func ReadBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+	if b, ok := r.Body.(*bufferedBody); ok {
+		r.Body = &bufferedBody{Reader: bytes.NewReader(b.data), data: b.data, raw: b.raw}
+		return b.data, nil
+	}
+	data, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	r.Body = &bufferedBody{Reader: bytes.NewReader(data), data: data, raw: data}
+	return data, err
+}
+
+// PeekBody returns the request body when it is at most maxSize bytes, keeping it in memory
+// like ReadBody so later readers share it. Larger bodies are left to stream through, with
+// what was read put back in front of the rest, and PeekBody reports false.
+//
+// Example usage:
+//
+//	body, complete, err := middleware.PeekBody(r, 64<<10)
+//	if err == nil && complete {
+//		log.Printf("Request body: %s", body)
+//	}
+func PeekBody(r *http.Request, maxSize int64) ([]byte, bool, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, true, nil
+	}
+	if b, ok := r.Body.(*bufferedBody); ok {
+		if int64(len(b.data)) > maxSize {
+			return nil, false, nil
+		}
+		data, err := ReadBody(r)
+		return data, true, err
+	}
+	if r.ContentLength > maxSize {
+		return nil, false, nil
+	}
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxSize+1))
+	if err != nil || int64(len(data)) > maxSize {
+		// Replay what was read in front of the rest of the stream
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(data), r.Body), r.Body}
+		return nil, false, err
+	}
+	r.Body.Close()
+	r.Body = &bufferedBody{Reader: bytes.NewReader(data), data: data, raw: data}
+	return data, true, nil
+}
+
+// ReplaceBody replaces the request body with data, keeping the raw bytes returned by RawBody.
+func ReplaceBody(r *http.Request, data []byte) {
+	var raw []byte
+	if b, ok := r.Body.(*bufferedBody); ok {
+		raw = b.raw
+	}
+	r.Body = &bufferedBody{Reader: bytes.NewReader(data), data: data, raw: raw}
+	r.ContentLength = int64(len(data))
+}
+
+// RawBody returns the body as it was received, even after a pipe replaced it. It returns
+// ErrBodyTooLarge when BodyCapture streamed the body because it exceeded the limit.
+//
+// Example usage:
+//
+//	body, err := middleware.RawBody(r)
+//	if err != nil || !validSignature(body, r.Header.Get("X-Signature")) {
+//		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+//		return
+//	}
+func RawBody(r *http.Request) ([]byte, error) {
+	if tooLarge, _ := r.Context().Value(bodyTooLargeKey{}).(bool); tooLarge {
+		return nil, ErrBodyTooLarge
+	}
+	data, err := ReadBody(r)
+	if err != nil {
+		return nil, err
+	}
+	if b, ok := r.Body.(*bufferedBody); ok && b.raw != nil {
+		return b.raw, nil
+	}
+	return data, nil
+}
@@ -4,99 +4,521 @@ import (
 	"bytes"
 	"context"
 	"encoding/gob"
-	"io"
 	"log"
 	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-redis/redis/v8"
+	"github.com/hokamsingh/lessgo/internal/core/cache"
 )
 
+// CacheStore stores encoded cached responses.
+type CacheStore interface {
+	// Get returns the value of key and false when it is not cached.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes the keys and returns how many were cached.
+	Delete(ctx context.Context, keys ...string) (int, error)
+	// Keys returns the cached keys matching pattern, where * matches any characters.
+	Keys(ctx context.Context, pattern string) ([]string, error)
+	// Tag associates key with the tags for ttl.
+	Tag(ctx context.Context, key string, tags []string, ttl time.Duration) error
+	// PurgeTag removes the keys associated with tag and returns how many were cached.
+	PurgeTag(ctx context.Context, tag string) (int, error)
+}
+
+// CacheTagHeader is the response header listing the comma separated tags of a response, so
+// all responses of e.g. a product can be purged at once.
+const CacheTagHeader = "Cache-Tag"
+
+// CacheStats are the counters of the response cache.
+type CacheStats struct {
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+	// Size is the number of cached responses.
+	Size int `json:"size"`
+}
+
+// Caching caches successful GET responses. Concurrent misses for the same URL are coalesced
+// into a single call of the handler. With a stale window, entries past their TTL can still be
+// served while one request refreshes them in the background (stale-while-revalidate) or when
+// the refresh fails (stale-if-error).
+//
+// Responses setting cookies or marked private, no-cache or no-store are never cached.
 type Caching struct {
-	client       *redis.Client
+	store        CacheStore
 	ttl          time.Duration
 	cacheControl bool
+
+	staleWhileRevalidate time.Duration
+	staleIfError         time.Duration
+
+	health *RedisHealth
+
+	bypassCookies []string
+	bypassHeaders []string
+	bypassParams  []string
+
+	flights    flightGroup
+	refreshing sync.Map
+
+	hits   atomic.Int64
+	misses atomic.Int64
 }
 
 // new caching
-func NewCaching(client *redis.Client, ttl time.Duration, cacheControl bool) *Caching {
-	return &Caching{
-		client:       client,
+func NewCaching(client redis.UniversalClient, ttl time.Duration, cacheControl bool, options ...func(*Caching)) *Caching {
+	c := &Caching{
+		store:        NewRedisCacheStore(client),
 		ttl:          ttl,
 		cacheControl: cacheControl,
 	}
+	for _, option := range options {
+		option(c)
+	}
+	if store, ok := c.store.(*RedisCacheStore); ok && c.health == nil && store.client != nil {
+		c.health = RedisHealthOf(store.client)
+	}
+	return c
 }
 
-func (c *Caching) Handle(next http.Handler) http.Handler {
+// WithCacheHealth skips the cache while health reports the store unavailable. Caches stored
+// in Redis use the health of their client by default.
+func WithCacheHealth(health *RedisHealth) func(*Caching) {
+	return func(c *Caching) {
+		c.health = health
+	}
+}
+
+// WithCacheStore stores responses in store instead of Redis.
+func WithCacheStore(store CacheStore) func(*Caching) {
+	return func(c *Caching) {
+		c.store = store
+	}
+}
+
+// WithStaleWhileRevalidate serves entries up to window past their TTL right away, while a
+// single background request refreshes them.
+func WithStaleWhileRevalidate(window time.Duration) func(*Caching) {
+	return func(c *Caching) {
+		c.staleWhileRevalidate = window
+	}
+}
+
+// WithStaleIfError serves entries up to window past their TTL when refreshing them fails
+// with a 5xx response.
+func WithStaleIfError(window time.Duration) func(*Caching) {
+	return func(c *Caching) {
+		c.staleIfError = window
+	}
+}
+
+// WithCacheBypassCookies skips the cache for requests carrying any of the cookies, e.g. the
+// session cookie, so personalized pages are neither served from nor stored in the cache.
+func WithCacheBypassCookies(names ...string) func(*Caching) {
+	return func(c *Caching) {
+		c.bypassCookies = append(c.bypassCookies, names...)
+	}
+}
+
+// WithCacheBypassHeaders skips the cache for requests carrying any of the headers, e.g.
+// Authorization.
+func WithCacheBypassHeaders(names ...string) func(*Caching) {
+	return func(c *Caching) {
+		c.bypassHeaders = append(c.bypassHeaders, names...)
+	}
+}
+
+// WithCacheBypassParams skips the cache for requests with any of the query parameters, e.g.
+// preview or nocache.
+func WithCacheBypassParams(names ...string) func(*Caching) {
+	return func(c *Caching) {
+		c.bypassParams = append(c.bypassParams, names...)
+	}
+}
+
+// CacheTTLKey is the request value key under which CacheTTL stores the TTL of a route.
+const CacheTTLKey = "cache.ttl"
+
+// CacheTTL overrides the TTL of the response cache for the routes it is registered on. A
+// TTL of zero or less keeps the responses of the routes out of the cache.
+//
+// Example usage:
+//
+//	r.With(middleware.CacheTTL(time.Hour)).Get("/countries", listCountries)
+//	r.With(middleware.CacheTTL(0)).Get("/cart", showCart)
+func CacheTTL(ttl time.Duration) *RouteCacheTTL {
+	return &RouteCacheTTL{ttl: ttl}
+}
+
+// RouteCacheTTL is the middleware returned by CacheTTL.
+type RouteCacheTTL struct {
+	ttl time.Duration
+}
+
+func (rc *RouteCacheTTL) Handle(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ctx := context.Background()
+		next.ServeHTTP(w, SetValue(r, CacheTTLKey, rc.ttl))
+	})
+}
+
+// hardTTL is how long entries are kept: the TTL plus the longest stale window.
+func (c *Caching) hardTTL(ttl time.Duration) time.Duration {
+	return ttl + max(c.staleWhileRevalidate, c.staleIfError)
+}
+
+// bypass reports whether the request must skip the cache.
+func (c *Caching) bypass(r *http.Request) bool {
+	// Respect Cache-Control: no-store
+	if r.Method != http.MethodGet || (c.cacheControl && r.Header.Get("Cache-Control") == "no-store") {
+		return true
+	}
+	for _, name := range c.bypassHeaders {
+		if r.Header.Get(name) != "" {
+			return true
+		}
+	}
+	for _, name := range c.bypassCookies {
+		if _, err := r.Cookie(name); err == nil {
+			return true
+		}
+	}
+	if len(c.bypassParams) > 0 {
+		query := r.URL.Query()
+		for _, name := range c.bypassParams {
+			if query.Has(name) {
+				return true
+			}
+		}
+	}
+	return false
+}
 
-		// Respect Cache-Control: no-store
-		if c.cacheControl && r.Header.Get("Cache-Control") == "no-store" {
+func (c *Caching) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c.bypass(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if c.health != nil && !c.health.Healthy() {
+			c.health.degrade()
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		if r.Method == http.MethodGet {
-			data, err := c.client.Get(ctx, r.RequestURI).Result()
-			if err == nil {
-				// Cache hit: deserialize cached response
-				var cachedResponse cachedResponse
-				decoder := gob.NewDecoder(bytes.NewReader([]byte(data)))
-				err := decoder.Decode(&cachedResponse)
-				if err != nil {
-					log.Printf("Error decoding cached response: %v", err)
-					next.ServeHTTP(w, r)
-					return
-				}
-
-				// Write cached headers
-				for key, values := range cachedResponse.Headers {
-					for _, value := range values {
-						w.Header().Add(key, value)
-					}
-				}
-
-				// Write cached body
-				w.Header().Set("X-Cache-Hit", "true")
-				io.WriteString(w, cachedResponse.Body)
+		cacheKey := tenantScoped(r, r.RequestURI)
+		// The lookup gives up with the request, on its deadline or when the client leaves
+		cached, found := c.lookup(r.Context(), cacheKey)
+		if !found {
+			if requestEnded(w, r) {
 				return
-			} else if err != redis.Nil {
-				log.Printf("Error retrieving from cache: %v", err)
 			}
+			c.misses.Add(1)
+			c.serve(w, c.fetch(cacheKey, next, r), next, r)
+			return
 		}
 
-		// Capture response
-		rec := &ResponseRecorder{ResponseWriter: w, StatusCode: http.StatusOK, Body: new(bytes.Buffer)}
+		age := time.Since(cached.Expires)
+		switch {
+		case cached.Expires.IsZero() || age < 0:
+			c.hits.Add(1)
+			cached.writeTo(w, true)
+		case age < c.staleWhileRevalidate:
+			c.hits.Add(1)
+			cached.writeTo(w, true)
+			c.refresh(cacheKey, next, r)
+		default:
+			fresh := c.fetch(cacheKey, next, r)
+			if (fresh == nil || fresh.Status >= http.StatusInternalServerError) && age < c.staleIfError {
+				c.hits.Add(1)
+				cached.writeTo(w, true)
+				return
+			}
+			c.misses.Add(1)
+			c.serve(w, fresh, next, r)
+		}
+	})
+}
+
+// Purge removes the cached response of a request URI, e.g. /products?page=2, or of all
+// request URIs matching a pattern with * wildcards, e.g. /products*. It returns the number of
+// responses removed. Responses of tenants are keyed tenant:{id}:{uri}, so *{uri} purges a
+// URI for every tenant.
+func (c *Caching) Purge(keyOrPattern string) (int, error) {
+	return c.PurgeContext(context.Background(), keyOrPattern)
+}
+
+// PurgeContext is Purge giving up when ctx ends, e.g. with the request purging the cache.
+func (c *Caching) PurgeContext(ctx context.Context, keyOrPattern string) (int, error) {
+	if !strings.Contains(keyOrPattern, "*") {
+		return c.store.Delete(ctx, keyOrPattern)
+	}
+	keys, err := c.store.Keys(ctx, keyOrPattern)
+	if err != nil || len(keys) == 0 {
+		return 0, err
+	}
+	return c.store.Delete(ctx, keys...)
+}
+
+// PurgeTag removes the cached responses tagged with tag through the Cache-Tag header.
+func (c *Caching) PurgeTag(tag string) (int, error) {
+	return c.PurgeTagContext(context.Background(), tag)
+}
+
+// PurgeTagContext is PurgeTag giving up when ctx ends.
+func (c *Caching) PurgeTagContext(ctx context.Context, tag string) (int, error) {
+	return c.store.PurgeTag(ctx, tag)
+}
+
+// Stats returns the hit and miss counters and the number of cached responses.
+func (c *Caching) Stats() CacheStats {
+	return c.StatsContext(context.Background())
+}
+
+// StatsContext is Stats giving up counting the cached responses when ctx ends.
+func (c *Caching) StatsContext(ctx context.Context) CacheStats {
+	stats := CacheStats{Hits: c.hits.Load(), Misses: c.misses.Load()}
+	keys, err := c.store.Keys(ctx, "*")
+	if err != nil {
+		log.Printf("Error counting cached responses: %v", err)
+	}
+	stats.Size = len(keys)
+	return stats
+}
+
+// serve writes a fetched response. When the request that fetched it panicked or its response
+// is private, the handler runs again for this request.
+func (c *Caching) serve(w http.ResponseWriter, response *cachedResponse, next http.Handler, r *http.Request) {
+	if response == nil {
+		next.ServeHTTP(w, r)
+		return
+	}
+	response.writeTo(w, false)
+}
+
+func (c *Caching) lookup(ctx context.Context, key string) (*cachedResponse, bool) {
+	data, found, err := c.store.Get(ctx, key)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, false
+		}
+		c.report(err)
+		log.Printf("Error retrieving from cache: %v", err)
+		return nil, false
+	}
+	if !found {
+		return nil, false
+	}
+	var cached cachedResponse
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&cached); err != nil {
+		log.Printf("Error decoding cached response: %v", err)
+		return nil, false
+	}
+	return &cached, true
+}
+
+// fetch runs the handler once for all concurrent requests of the key and caches a
+// successful response. It returns nil to the waiting requests when the handler panicked or
+// its response may not be shared, e.g. because it sets a cookie, so they run the handler
+// themselves.
+func (c *Caching) fetch(key string, next http.Handler, r *http.Request) *cachedResponse {
+	response, shared := c.flights.do(key, func() *cachedResponse {
+		// The response is shared, so it must not be cut short when the first client leaves
+		rec := &bufferedResponse{header: http.Header{}, status: http.StatusOK}
+		r, values := RequestValues(r.WithContext(context.WithoutCancel(r.Context())))
 		next.ServeHTTP(rec, r)
+		response := &cachedResponse{Headers: rec.header, Body: rec.body.String(), Status: rec.status}
+		response.private = !cacheable(rec.header)
+		ttl := c.ttl
+		if routeTTL, ok := values.Get(CacheTTLKey); ok {
+			ttl, _ = routeTTL.(time.Duration)
+		}
+		if rec.status == http.StatusOK && ttl > 0 && !response.private {
+			response.Expires = time.Now().Add(ttl)
+			c.save(r.Context(), key, response, ttl)
+		}
+		return response
+	})
+	if shared && response != nil && response.private {
+		return nil
+	}
+	return response
+}
 
-		// Cache only successful responses (status code 200)
-		if r.Method == http.MethodGet && rec.StatusCode == http.StatusOK {
-			cachedResponse := cachedResponse{
-				Headers: rec.Header(),
-				Body:    rec.Body.String(),
-			}
+// refresh fetches the key in the background unless a refresh is already running.
+func (c *Caching) refresh(key string, next http.Handler, r *http.Request) {
+	if _, running := c.refreshing.LoadOrStore(key, true); running {
+		return
+	}
+	r = r.Clone(context.WithoutCancel(r.Context()))
+	go func() {
+		defer c.refreshing.Delete(key)
+		c.fetch(key, next, r)
+	}()
+}
 
-			var buffer bytes.Buffer
-			encoder := gob.NewEncoder(&buffer)
-			err := encoder.Encode(cachedResponse)
-			if err != nil {
-				log.Printf("Error encoding cached response: %v", err)
-				return
-			}
+func (c *Caching) report(err error) {
+	if c.health != nil {
+		c.health.Report(err)
+	}
+}
 
-			err = c.client.Set(ctx, r.RequestURI, buffer.Bytes(), c.ttl).Err()
-			if err != nil {
-				log.Printf("Error setting cache: %v", err)
+// cacheable reports whether a response may be shared with other clients.
+func cacheable(header http.Header) bool {
+	if header.Get("Set-Cookie") != "" {
+		return false
+	}
+	cacheControl := strings.ToLower(header.Get("Cache-Control"))
+	for _, directive := range []string{"private", "no-store", "no-cache"} {
+		if strings.Contains(cacheControl, directive) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Caching) save(ctx context.Context, key string, response *cachedResponse, ttl time.Duration) {
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(response); err != nil {
+		log.Printf("Error encoding cached response: %v", err)
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	if err := c.store.Set(ctx, key, buffer.Bytes(), c.hardTTL(ttl)); err != nil {
+		c.report(err)
+		log.Printf("Error setting cache: %v", err)
+		return
+	}
+	if tags := cacheTags(response.Headers); len(tags) > 0 {
+		if err := c.store.Tag(ctx, key, tags, c.hardTTL(ttl)); err != nil {
+			log.Printf("Error tagging cached response: %v", err)
+		}
+	}
+}
+
+func cacheTags(header http.Header) []string {
+	var tags []string
+	for _, value := range header.Values(CacheTagHeader) {
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
 			}
 		}
-	})
+	}
+	return tags
+}
+
+// matchWildcard reports whether s matches pattern, where * matches any characters.
+func matchWildcard(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == s
+	}
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, part)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(part):]
+	}
+	last := parts[len(parts)-1]
+	return len(s) >= len(last) && strings.HasSuffix(s, last)
 }
 
 // cachedResponse stores both headers and body
 type cachedResponse struct {
 	Headers http.Header
 	Body    string
+	Status  int
+	// Expires is when the entry becomes stale; zero for entries cached without it
+	Expires time.Time
+	// private responses are only written to the request that produced them
+	private bool
+}
+
+func (c *cachedResponse) writeTo(w http.ResponseWriter, hit bool) {
+	for key, values := range c.Headers {
+		w.Header()[key] = append([]string(nil), values...)
+	}
+	if hit {
+		w.Header().Set("X-Cache-Hit", "true")
+	}
+	status := c.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	w.Write([]byte(c.Body))
+}
+
+// bufferedResponse buffers a response so it can be shared by coalesced requests.
+type bufferedResponse struct {
+	header http.Header
+	body   bytes.Buffer
+	status int
+	wrote  bool
+}
+
+func (b *bufferedResponse) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	b.wrote = true
+	return b.body.Write(p)
+}
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	// Informational responses such as early hints are not cached
+	if !b.wrote && status >= http.StatusOK {
+		b.status = status
+		b.wrote = true
+	}
+}
+
+// flightGroup coalesces concurrent calls for the same key into one.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	done     chan struct{}
+	response *cachedResponse
+}
+
+// do calls fn once for concurrent calls of the key. shared reports whether the response was
+// produced for another caller.
+func (g *flightGroup) do(key string, fn func() *cachedResponse) (response *cachedResponse, shared bool) {
+	g.mu.Lock()
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		<-call.done
+		return call.response, true
+	}
+	if g.calls == nil {
+		g.calls = map[string]*flightCall{}
+	}
+	call := &flightCall{done: make(chan struct{})}
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(call.done)
+	}()
+	call.response = fn()
+	return call.response, false
 }
 
 type ResponseRecorder struct {
@@ -122,6 +544,162 @@ func (rec *ResponseRecorder) Flush() {
 	}
 }
 
+// MemoryCacheStore keeps cached responses in an in-process LRU cache bounded by memory, for
+// single instance deployments and tests. The keys of a tag are kept in the cache too, so they
+// are evicted along with the responses.
+type MemoryCacheStore struct {
+	cache *cache.Cache
+	mu    sync.Mutex // serializes updates of the tags
+}
+
+// memoryCacheTagPrefix starts the cache keys of tags; request URIs never start with a NUL.
+const memoryCacheTagPrefix = "\x00tag:"
+
+// NewMemoryCacheStore creates an in-memory cache store with a cache created from the options.
+//
+// Example usage:
+//
+//	store := middleware.NewMemoryCacheStore(cache.WithMaxBytes(256 << 20))
+func NewMemoryCacheStore(options ...func(*cache.Cache)) *MemoryCacheStore {
+	return NewLRUCacheStore(cache.New(options...))
+}
+
+// NewLRUCacheStore creates a cache store keeping responses in c, e.g. a cache shared with
+// services through the DI container.
+func NewLRUCacheStore(c *cache.Cache) *MemoryCacheStore {
+	return &MemoryCacheStore{cache: c}
+}
+
+func (s *MemoryCacheStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, ok := s.cache.Get(key)
+	return value, ok, nil
+}
+
+func (s *MemoryCacheStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	s.cache.Set(key, value, ttl)
+	return nil
+}
+
+func (s *MemoryCacheStore) Delete(ctx context.Context, keys ...string) (int, error) {
+	return s.cache.Delete(keys...), nil
+}
+
+func (s *MemoryCacheStore) Keys(ctx context.Context, pattern string) ([]string, error) {
+	var keys []string
+	for _, key := range s.cache.Keys() {
+		if !strings.HasPrefix(key, memoryCacheTagPrefix) && matchWildcard(pattern, key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (s *MemoryCacheStore) Tag(ctx context.Context, key string, tags []string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, tag := range tags {
+		keys := s.taggedKeys(tag)
+		if !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+		s.cache.Set(memoryCacheTagPrefix+tag, []byte(strings.Join(keys, "\n")), ttl)
+	}
+	return nil
+}
+
+func (s *MemoryCacheStore) PurgeTag(ctx context.Context, tag string) (int, error) {
+	s.mu.Lock()
+	keys := s.taggedKeys(tag)
+	s.cache.Delete(memoryCacheTagPrefix + tag)
+	s.mu.Unlock()
+	return s.cache.Delete(keys...), nil
+}
+
+func (s *MemoryCacheStore) taggedKeys(tag string) []string {
+	value, ok := s.cache.Get(memoryCacheTagPrefix + tag)
+	if !ok {
+		return nil
+	}
+	return strings.Split(string(value), "\n")
+}
+
+// RedisCacheStore keeps cached responses in Redis, shared by all instances. Responses are
+// stored below the cache: prefix and the keys of a tag in a set at cache-tag:{tag}.
+type RedisCacheStore struct {
+	client redis.UniversalClient
+}
+
+const (
+	redisCachePrefix    = "cache:"
+	redisCacheTagPrefix = "cache-tag:"
+)
+
+// NewRedisCacheStore creates a Redis backed cache store.
+func NewRedisCacheStore(client redis.UniversalClient) *RedisCacheStore {
+	return &RedisCacheStore{client: client}
+}
+
+func (s *RedisCacheStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	data, err := s.client.Get(ctx, redisCachePrefix+key).Bytes()
+	if err == redis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+func (s *RedisCacheStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return s.client.Set(ctx, redisCachePrefix+key, value, ttl).Err()
+}
+
+func (s *RedisCacheStore) Delete(ctx context.Context, keys ...string) (int, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = redisCachePrefix + key
+	}
+	deleted, err := s.client.Del(ctx, prefixed...).Result()
+	return int(deleted), err
+}
+
+func (s *RedisCacheStore) Keys(ctx context.Context, pattern string) ([]string, error) {
+	// Only * is a wildcard, the other glob characters of Redis are escaped
+	match := redisCachePrefix + strings.NewReplacer(`\`, `\\`, "?", `\?`, "[", `\[`, "]", `\]`).Replace(pattern)
+	var keys []string
+	iter := s.client.Scan(ctx, 0, match, 100).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, strings.TrimPrefix(iter.Val(), redisCachePrefix))
+	}
+	return keys, iter.Err()
+}
+
+func (s *RedisCacheStore) Tag(ctx context.Context, key string, tags []string, ttl time.Duration) error {
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, tag := range tags {
+			pipe.SAdd(ctx, redisCacheTagPrefix+tag, key)
+			pipe.Expire(ctx, redisCacheTagPrefix+tag, ttl)
+		}
+		return nil
+	})
+	return err
+}
+
+func (s *RedisCacheStore) PurgeTag(ctx context.Context, tag string) (int, error) {
+	keys, err := s.client.SMembers(ctx, redisCacheTagPrefix+tag).Result()
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := s.Delete(ctx, keys...)
+	if err != nil {
+		return deleted, err
+	}
+	return deleted, s.client.Del(ctx, redisCacheTagPrefix+tag).Err()
+}
+
 func init() {
 	// Register the cachedResponse type with gob so it can be encoded/decoded
 	gob.Register(cachedResponse{})
@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+var trustedProxies atomic.Value // []*net.IPNet
+
+// SetTrustedProxies sets the IPs and CIDRs of the reverse proxies whose forwarding headers
+// ClientIP honors. Without trusted proxies the headers are ignored, since any client can
+// send them.
+//
+// Example usage:
+//
+//	middleware.SetTrustedProxies("10.0.0.0/8", "127.0.0.1")
+func SetTrustedProxies(entries ...string) {
+	trustedProxies.Store(parseNetworks(entries))
+}
+
+func isTrustedProxy(ip string) bool {
+	networks, _ := trustedProxies.Load().([]*net.IPNet)
+	return len(networks) > 0 && ipInNetworks(ip, networks)
+}
+
+// FromTrustedProxy reports whether the direct peer of r is a trusted proxy, whose headers,
+// e.g. the country set by a CDN, can be believed.
+func FromTrustedProxy(r *http.Request) bool {
+	return isTrustedProxy(hostOf(r.RemoteAddr))
+}
+
+// ClientIP returns the IP address of the client that made the request. When the direct peer
+// is a trusted proxy, the Forwarded, X-Forwarded-For and X-Real-IP headers are consulted in
+// that order, skipping further trusted proxies from the right, so clients cannot spoof
+// their address by prepending entries.
+func ClientIP(r *http.Request) string {
+	peer := hostOf(r.RemoteAddr)
+	if !isTrustedProxy(peer) {
+		return peer
+	}
+	if chain := forwardedFor(r.Header.Values("Forwarded")); len(chain) > 0 {
+		return fromChain(chain)
+	}
+	if chain := splitForwarded(r.Header.Values("X-Forwarded-For")); len(chain) > 0 {
+		return fromChain(chain)
+	}
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(realIP) != nil {
+		return realIP
+	}
+	return peer
+}
+
+// fromChain returns the rightmost address of the chain that is not a trusted proxy, or the
+// leftmost one when all of them are trusted.
+func fromChain(chain []string) string {
+	for i := len(chain) - 1; i >= 0; i-- {
+		if !isTrustedProxy(chain[i]) {
+			return chain[i]
+		}
+	}
+	return chain[0]
+}
+
+// splitForwarded returns the valid addresses of X-Forwarded-For headers in order.
+func splitForwarded(values []string) []string {
+	var chain []string
+	for _, value := range values {
+		for _, entry := range strings.Split(value, ",") {
+			if ip := hostOf(strings.TrimSpace(entry)); net.ParseIP(ip) != nil {
+				chain = append(chain, ip)
+			}
+		}
+	}
+	return chain
+}
+
+// forwardedFor returns the valid for= addresses of RFC 7239 Forwarded headers in order.
+func forwardedFor(values []string) []string {
+	var chain []string
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				name, node, found := strings.Cut(strings.TrimSpace(pair), "=")
+				if !found || !strings.EqualFold(name, "for") {
+					continue
+				}
+				node = strings.Trim(node, `"`)
+				if ip := hostOf(node); net.ParseIP(ip) != nil {
+					chain = append(chain, ip)
+				}
+			}
+		}
+	}
+	return chain
+}
+
+// hostOf strips the port and IPv6 brackets from an address.
+func hostOf(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+}
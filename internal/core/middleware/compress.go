@@ -0,0 +1,165 @@
+package middleware
+
+import (
+	"bufio"
+	"compress/gzip"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// compressibleTypes are the content types worth compressing, by prefix.
+var compressibleTypes = []string{
+	"text/", "application/json", "application/javascript", "application/xml", "application/yaml",
+	"application/problem+json", "application/ld+json", "image/svg+xml",
+}
+
+// Compress gzips responses of compressible content types for clients accepting gzip.
+// Responses that are already encoded, event streams and WebSocket upgrades are left alone.
+type Compress struct {
+	level   int
+	minSize int
+	writers sync.Pool
+}
+
+// NewCompress creates the compression middleware at a gzip level from 1 (fastest) to 9
+// (best), or gzip.DefaultCompression. Responses declaring a Content-Length below minSize
+// bytes are sent uncompressed.
+//
+// Example usage:
+//
+//	compress := middleware.NewCompress(gzip.DefaultCompression, 1024)
+func NewCompress(level, minSize int) *Compress {
+	if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+		level = gzip.DefaultCompression
+	}
+	c := &Compress{level: level, minSize: minSize}
+	c.writers.New = func() interface{} {
+		w, _ := gzip.NewWriterLevel(nil, c.level)
+		return w
+	}
+	return c
+}
+
+func (c *Compress) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsGzip(r) || r.Header.Get("Upgrade") != "" || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cw := &compressedResponse{ResponseWriter: w, compress: c}
+		defer cw.close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, encoding := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(encoding), ";")
+		if strings.TrimSpace(name) == "gzip" {
+			return strings.ReplaceAll(params, " ", "") != "q=0"
+		}
+	}
+	return false
+}
+
+// compressedResponse decides on the first write whether to compress, from the headers set
+// by the handler.
+type compressedResponse struct {
+	http.ResponseWriter
+	compress    *Compress
+	gz          *gzip.Writer
+	wroteHeader bool
+}
+
+func (cw *compressedResponse) WriteHeader(status int) {
+	if status < http.StatusOK {
+		// Informational responses such as early hints are passed through
+		cw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+	if cw.shouldCompress(status) {
+		header := cw.Header()
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		// Strong validators of the identity body do not match the compressed one
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag)
+		}
+		cw.gz = cw.compress.writers.Get().(*gzip.Writer)
+		cw.gz.Reset(cw.ResponseWriter)
+	}
+	cw.ResponseWriter.WriteHeader(status)
+}
+
+func (cw *compressedResponse) shouldCompress(status int) bool {
+	header := cw.Header()
+	if status == http.StatusNoContent || status == http.StatusNotModified || header.Get("Content-Encoding") != "" {
+		return false
+	}
+	if length, err := strconv.Atoi(header.Get("Content-Length")); err == nil && length < cw.compress.minSize {
+		return false
+	}
+	contentType := header.Get("Content-Type")
+	if strings.HasPrefix(contentType, "text/event-stream") {
+		return false
+	}
+	for _, prefix := range compressibleTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (cw *compressedResponse) Write(p []byte) (int, error) {
+	if !cw.wroteHeader {
+		if cw.Header().Get("Content-Type") == "" {
+			cw.Header().Set("Content-Type", http.DetectContentType(p))
+		}
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.gz != nil {
+		return cw.gz.Write(p)
+	}
+	return cw.ResponseWriter.Write(p)
+}
+
+func (cw *compressedResponse) Flush() {
+	if cw.gz != nil {
+		cw.gz.Flush()
+	}
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (cw *compressedResponse) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+func (cw *compressedResponse) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := cw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	return hijacker.Hijack()
+}
+
+// close writes the end of the gzip stream and returns the writer to the pool.
+func (cw *compressedResponse) close() {
+	if cw.gz == nil {
+		return
+	}
+	cw.gz.Close()
+	cw.gz.Reset(nil)
+	cw.compress.writers.Put(cw.gz)
+	cw.gz = nil
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// ConcurrencyLimit bounds the number of requests handled at the same time. Requests over the
+// limit wait in a queue for up to the timeout; when the queue is full or the wait times out
+// they get a 503, shedding load before the server degrades.
+type ConcurrencyLimit struct {
+	slots   chan struct{}
+	queue   chan struct{}
+	timeout time.Duration
+	waiting atomic.Int64
+}
+
+// NewConcurrencyLimit creates a limit of n requests in flight, with up to queueSize
+// requests waiting at most timeout for a slot.
+func NewConcurrencyLimit(n, queueSize int, timeout time.Duration) *ConcurrencyLimit {
+	if n < 1 {
+		n = 1
+	}
+	if queueSize < 0 {
+		queueSize = 0
+	}
+	return &ConcurrencyLimit{
+		slots:   make(chan struct{}, n),
+		queue:   make(chan struct{}, queueSize),
+		timeout: timeout,
+	}
+}
+
+// InFlight returns the number of requests currently being handled.
+func (cl *ConcurrencyLimit) InFlight() int {
+	return len(cl.slots)
+}
+
+// Waiting returns the number of requests waiting for a slot.
+func (cl *ConcurrencyLimit) Waiting() int {
+	return int(cl.waiting.Load())
+}
+
+func (cl *ConcurrencyLimit) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !cl.acquire(r) {
+			w.Header().Set("Retry-After", strconv.Itoa(int(cl.timeout.Seconds())+1))
+			http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		defer func() { <-cl.slots }()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// acquire takes a slot, waiting in the queue if needed. It reports false when the request
+// is shed.
+func (cl *ConcurrencyLimit) acquire(r *http.Request) bool {
+	select {
+	case cl.slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	select {
+	case cl.queue <- struct{}{}:
+	default:
+		// Queue full
+		return false
+	}
+	cl.waiting.Add(1)
+	defer func() {
+		cl.waiting.Add(-1)
+		<-cl.queue
+	}()
+
+	timer := time.NewTimer(cl.timeout)
+	defer timer.Stop()
+	select {
+	case cl.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-r.Context().Done():
+		return false
+	}
+}
@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Matcher selects requests for Skip and Only.
+type Matcher func(r *http.Request) bool
+
+// MatchPaths matches request paths against glob patterns as understood by path.Match. A
+// pattern ending in /** also matches everything below it, e.g. /webhooks/**.
+func MatchPaths(patterns ...string) Matcher {
+	return func(r *http.Request) bool {
+		for _, pattern := range patterns {
+			if prefix, ok := strings.CutSuffix(pattern, "/**"); ok {
+				if r.URL.Path == prefix || strings.HasPrefix(r.URL.Path, prefix+"/") {
+					return true
+				}
+				continue
+			}
+			if matched, _ := path.Match(pattern, r.URL.Path); matched {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// MatchMethods matches requests with one of the HTTP methods.
+func MatchMethods(methods ...string) Matcher {
+	return func(r *http.Request) bool {
+		for _, method := range methods {
+			if strings.EqualFold(r.Method, method) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// MatchHeader matches requests carrying the header with the given value, or with any value
+// when value is empty.
+func MatchHeader(name, value string) Matcher {
+	return func(r *http.Request) bool {
+		got := r.Header.Get(name)
+		if value == "" {
+			return got != ""
+		}
+		return got == value
+	}
+}
+
+// MatchAny matches requests matched by any of the matchers.
+func MatchAny(matchers ...Matcher) Matcher {
+	return func(r *http.Request) bool {
+		for _, match := range matchers {
+			if match(r) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// MatchAll matches requests matched by all of the matchers.
+func MatchAll(matchers ...Matcher) Matcher {
+	return func(r *http.Request) bool {
+		for _, match := range matchers {
+			if !match(r) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Conditional applies a middleware to some requests only, see Skip and Only.
+type Conditional struct {
+	middleware Middleware
+	match      Matcher
+	skip       bool
+}
+
+// Skip applies the middleware to all requests except those matched, e.g. CSRF protection
+// except for webhooks.
+//
+// Example usage:
+//
+//	r.Use(middleware.Skip(middleware.NewCSRFProtection(), middleware.MatchPaths("/webhooks/**")))
+func Skip(m Middleware, match Matcher) *Conditional {
+	return &Conditional{middleware: m, match: match, skip: true}
+}
+
+// Only applies the middleware to the matched requests.
+//
+// Example usage:
+//
+//	r.Use(middleware.Only(cacher, middleware.MatchMethods(http.MethodGet, http.MethodHead)))
+func Only(m Middleware, match Matcher) *Conditional {
+	return &Conditional{middleware: m, match: match}
+}
+
+// Unwrap returns the middleware applied conditionally.
+func (c *Conditional) Unwrap() Middleware {
+	return c.middleware
+}
+
+func (c *Conditional) Handle(next http.Handler) http.Handler {
+	wrapped := c.middleware.Handle(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c.match(r) == c.skip {
+			next.ServeHTTP(w, r)
+			return
+		}
+		wrapped.ServeHTTP(w, r)
+	})
+}
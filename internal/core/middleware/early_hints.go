@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Link is a resource the client should fetch early, sent in a Link header.
+type Link struct {
+	URL string
+	// Rel is the relation of the resource, "preload" by default. "preconnect" warms up a
+	// connection to another origin.
+	Rel string
+	// As is the kind of resource to preload: style, script, font, image or fetch.
+	As string
+	// Type is the MIME type, letting browsers skip formats they do not support.
+	Type string
+	// CrossOrigin is "anonymous" or "use-credentials" for resources fetched with CORS,
+	// which fonts always are.
+	CrossOrigin string
+}
+
+// String formats the link as a Link header value, e.g. </app.css>; rel=preload; as=style.
+func (l Link) String() string {
+	rel := l.Rel
+	if rel == "" {
+		rel = "preload"
+	}
+	var b strings.Builder
+	b.WriteString("<" + l.URL + ">; rel=" + rel)
+	if l.As != "" {
+		b.WriteString("; as=" + l.As)
+	}
+	if l.Type != "" {
+		b.WriteString(`; type="` + l.Type + `"`)
+	}
+	if l.CrossOrigin != "" {
+		b.WriteString("; crossorigin=" + l.CrossOrigin)
+	}
+	return b.String()
+}
+
+// SendEarlyHints adds the links to the response headers and sends them in a 103 Early Hints
+// response, so the client starts fetching them while the final response is being prepared.
+// The Link headers stay on the final response for clients that ignore 1xx responses.
+// Nothing is sent to HTTP/1.0 clients, which cannot receive informational responses.
+func SendEarlyHints(w http.ResponseWriter, r *http.Request, links ...Link) {
+	if len(links) == 0 {
+		return
+	}
+	for _, link := range links {
+		w.Header().Add("Link", link.String())
+	}
+	if r.ProtoAtLeast(1, 1) {
+		w.WriteHeader(http.StatusEarlyHints)
+	}
+}
+
+// Push pushes the same-origin resource at target to HTTP/2 clients. It returns
+// http.ErrNotSupported when the connection does not support push, e.g. over HTTP/1.1 or
+// when the client disabled it, as most browsers have.
+func Push(w http.ResponseWriter, target string, options *http.PushOptions) error {
+	for {
+		if pusher, ok := w.(http.Pusher); ok {
+			return pusher.Push(target, options)
+		}
+		// Look through the response wrappers of other middleware
+		unwrapper, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return http.ErrNotSupported
+		}
+		w = unwrapper.Unwrap()
+	}
+}
+
+// EarlyHints sends the links of a route in a 103 Early Hints response before the handler
+// runs, so pages rendered from templates can preload their critical assets while the data
+// is loaded.
+type EarlyHints struct {
+	links []Link
+	push  bool
+}
+
+// NewEarlyHints creates an EarlyHints middleware for the links, to be added to the routes
+// that need them.
+//
+// Example usage:
+//
+//	hints := middleware.NewEarlyHints([]middleware.Link{
+//		{URL: "/static/app.css", As: "style"},
+//		{URL: "/static/inter.woff2", As: "font", Type: "font/woff2", CrossOrigin: "anonymous"},
+//	})
+//	r.With(hints).Get("/", home)
+func NewEarlyHints(links []Link, options ...func(*EarlyHints)) *EarlyHints {
+	h := &EarlyHints{links: links}
+	for _, option := range options {
+		option(h)
+	}
+	return h
+}
+
+// WithServerPush also pushes the same-origin preloaded links to HTTP/2 clients that
+// accept push.
+func WithServerPush() func(*EarlyHints) {
+	return func(h *EarlyHints) {
+		h.push = true
+	}
+}
+
+// Handle implements the Middleware interface.
+func (h *EarlyHints) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Hints for GET and HEAD only, other methods do not render pages
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			if h.push {
+				for _, link := range h.links {
+					if (link.Rel == "" || link.Rel == "preload") && strings.HasPrefix(link.URL, "/") && !strings.HasPrefix(link.URL, "//") {
+						if err := Push(w, link.URL, nil); err != nil {
+							break
+						}
+					}
+				}
+			}
+			SendEarlyHints(w, r, h.links...)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
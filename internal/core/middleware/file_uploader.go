@@ -2,38 +2,75 @@ package middleware
 
 import (
 	"fmt"
-	"io"
 	"log"
 	"net/http"
-	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/google/uuid"
+	"github.com/hokamsingh/lessgo/internal/core/storage"
 )
 
 type FileUploadMiddleware struct {
 	uploadDir   string
 	maxFileSize int64    // Maximum file size in bytes
 	allowedExts []string // Allowed file extensions
+	storage     storage.Storage
+	local       *storage.LocalStorage
+	sniff       bool // Check the magic bytes of files against their extension
+	maxWidth    int
+	maxHeight   int
+	maxPixels   int64
+	reencode    bool
+	scanner     UploadScanner
 }
 
-// NewFileUploadMiddleware creates a new instance of FileUploadMiddleware
-func NewFileUploadMiddleware(uploadDir string, maxFileSize int64, allowedExts []string) *FileUploadMiddleware {
-	// Ensure the upload directory exists
-	if err := os.MkdirAll(uploadDir, 0750); err != nil {
-		log.Fatalf("Failed to create upload directory: %v", err)
-	}
-
+// NewFileUploadMiddleware creates a new instance of FileUploadMiddleware. Files are saved to
+// the storage given with WithUploadStorage, else to the storage of the request context (see
+// storage.Middleware), else to uploadDir. Before being saved, files go through the checks of
+// the upload pipeline: content sniffing, and the image limits, re-encoding and scanner when
+// configured.
+func NewFileUploadMiddleware(uploadDir string, maxFileSize int64, allowedExts []string, options ...func(*FileUploadMiddleware)) *FileUploadMiddleware {
 	if len(allowedExts) == 0 {
 		allowedExts = []string{".jpg"} // Default allowed extension if none provided
 	}
 
-	return &FileUploadMiddleware{
+	f := &FileUploadMiddleware{
 		uploadDir:   uploadDir,
 		maxFileSize: maxFileSize,
 		allowedExts: allowedExts,
+		sniff:       true,
+	}
+	for _, option := range options {
+		option(f)
+	}
+	if f.storage == nil {
+		// Ensure the upload directory exists
+		local, err := storage.NewLocalStorage(uploadDir)
+		if err != nil {
+			log.Fatalf("Failed to create upload directory: %v", err)
+		}
+		f.local = local
+	}
+	return f
+}
+
+// WithUploadStorage saves the uploaded files to s.
+func WithUploadStorage(s storage.Storage) func(*FileUploadMiddleware) {
+	return func(f *FileUploadMiddleware) {
+		f.storage = s
+	}
+}
+
+// storageFor returns the storage receiving the files of r.
+func (f *FileUploadMiddleware) storageFor(r *http.Request) storage.Storage {
+	if f.storage != nil {
+		return f.storage
 	}
+	if s, ok := storage.FromContext(r.Context()); ok {
+		return s
+	}
+	return f.local
 }
 
 // Handle is the middleware function that processes file uploads
@@ -66,30 +103,22 @@ func (f *FileUploadMiddleware) Handle(next http.Handler) http.Handler {
 			return
 		}
 
-		// Generate a unique file name
-		fileName := generateFileName() + ext
-		filePath := filepath.Join(f.uploadDir, fileName)
-
-		// Create the file
-		cleanFilePath := filepath.Clean(filePath)
-		if !strings.HasPrefix(cleanFilePath, f.uploadDir) {
-			log.Panic("invalid file path")
-			log.Printf("Error creating file: %v", err)
-			return
-		}
-
-		destFile, err := os.Create(cleanFilePath)
+		// Validate the file content
+		body, contentType, err := f.inspect(r.Context(), file, ext)
 		if err != nil {
-			http.Error(w, "Unable to save file", http.StatusInternalServerError)
-			log.Printf("Error creating file: %v", err)
+			rejection := err.(*uploadRejection)
+			http.Error(w, rejection.message, rejection.status)
+			log.Printf("Rejected upload %s: %v", fileHeader.Filename, err)
 			return
 		}
-		defer destFile.Close()
 
-		// Copy file content
-		if _, err := io.Copy(destFile, file); err != nil {
-			http.Error(w, "Unable to copy file content", http.StatusInternalServerError)
-			log.Printf("Error copying file content: %v", err)
+		// Generate a unique file name
+		fileName := generateFileName() + ext
+
+		// Save the file content
+		if err := f.storageFor(r).Put(r.Context(), fileName, body, contentType); err != nil {
+			http.Error(w, "Unable to save file", http.StatusInternalServerError)
+			log.Printf("Error saving file: %v", err)
 			return
 		}
 
@@ -0,0 +1,197 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultHoneypotPaths are paths vulnerability scanners probe and applications built with
+// lessgo do not serve. Entries ending with "/" match everything below them.
+var DefaultHoneypotPaths = []string{
+	"/wp-login.php",
+	"/wp-admin/",
+	"/xmlrpc.php",
+	"/.env",
+	"/.git/",
+	"/.aws/",
+	"/phpmyadmin/",
+	"/phpinfo.php",
+	"/config.php",
+	"/server-status",
+}
+
+// HoneypotOptions defines the decoy paths and what happens to the clients requesting them.
+type HoneypotOptions struct {
+	// Paths are the decoy paths. Entries ending with "/" match everything below them.
+	Paths []string
+	// Blocklist bans the IPs requesting a decoy for BanDuration, zero banning until restart.
+	// Nil disables banning.
+	Blocklist   *IPBlocklist
+	BanDuration time.Duration
+	// TarpitDelay keeps decoy responses open this long, trickling a byte every second, to
+	// slow scanners down. Zero answers 404 at once.
+	TarpitDelay time.Duration
+	// MaxTarpits bounds the connections held open at the same time; beyond it decoys answer
+	// 404 at once so a scan cannot exhaust the server.
+	MaxTarpits int
+	// OnTrigger is called for every request to a decoy, e.g. to publish an event or alert.
+	OnTrigger func(event HoneypotEvent)
+}
+
+// NewHoneypotOptions creates HoneypotOptions for the default paths, banning offending IPs in
+// blocklist for banDuration and tarpitting them for 30 seconds.
+func NewHoneypotOptions(blocklist *IPBlocklist, banDuration time.Duration) *HoneypotOptions {
+	return &HoneypotOptions{
+		Paths:       DefaultHoneypotPaths,
+		Blocklist:   blocklist,
+		BanDuration: banDuration,
+		TarpitDelay: 30 * time.Second,
+		MaxTarpits:  100,
+	}
+}
+
+// HoneypotEvent describes a request to a decoy path.
+type HoneypotEvent struct {
+	Time      time.Time `json:"time"`
+	IP        string    `json:"ip"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	Banned    bool      `json:"banned"`
+	Tarpitted bool      `json:"tarpitted"`
+}
+
+// HoneypotStats are the counters of a Honeypot.
+type HoneypotStats struct {
+	Hits      int64 `json:"hits"`
+	Bans      int64 `json:"bans"`
+	Tarpitted int64 `json:"tarpitted"`
+	// ActiveTarpits are the connections currently held open.
+	ActiveTarpits int64            `json:"active_tarpits"`
+	Paths         map[string]int64 `json:"paths"`
+}
+
+// Honeypot answers requests to decoy paths, bans the clients making them and holds their
+// connections open, leaving other requests untouched.
+type Honeypot struct {
+	options HoneypotOptions
+
+	hits      atomic.Int64
+	bans      atomic.Int64
+	tarpitted atomic.Int64
+	active    atomic.Int64
+
+	mu    sync.Mutex
+	paths map[string]int64
+}
+
+// NewHoneypot creates the honeypot middleware. Place the blocklist of the options before it
+// so banned clients are refused on their next request.
+//
+// Example usage:
+//
+//	blocklist := middleware.NewIPBlocklist()
+//	honeypot := middleware.NewHoneypot(*middleware.NewHoneypotOptions(blocklist, 24*time.Hour))
+func NewHoneypot(options HoneypotOptions) *Honeypot {
+	return &Honeypot{options: options, paths: map[string]int64{}}
+}
+
+// match returns the decoy entry path falls under.
+func (h *Honeypot) match(path string) (string, bool) {
+	for _, decoy := range h.options.Paths {
+		if path == decoy || strings.HasSuffix(decoy, "/") && (strings.HasPrefix(path, decoy) || path == strings.TrimSuffix(decoy, "/")) {
+			return decoy, true
+		}
+	}
+	return "", false
+}
+
+// Stats returns the counters of the honeypot.
+func (h *Honeypot) Stats() HoneypotStats {
+	h.mu.Lock()
+	paths := make(map[string]int64, len(h.paths))
+	for path, hits := range h.paths {
+		paths[path] = hits
+	}
+	h.mu.Unlock()
+	return HoneypotStats{
+		Hits:          h.hits.Load(),
+		Bans:          h.bans.Load(),
+		Tarpitted:     h.tarpitted.Load(),
+		ActiveTarpits: h.active.Load(),
+		Paths:         paths,
+	}
+}
+
+func (h *Honeypot) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		decoy, ok := h.match(r.URL.Path)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		event := HoneypotEvent{
+			Time:      time.Now(),
+			IP:        ClientIP(r),
+			Method:    r.Method,
+			Path:      r.URL.Path,
+			UserAgent: r.UserAgent(),
+		}
+		h.hits.Add(1)
+		h.mu.Lock()
+		h.paths[decoy]++
+		h.mu.Unlock()
+
+		if h.options.Blocklist != nil && !isTrustedProxy(event.IP) {
+			h.options.Blocklist.Ban(event.IP, h.options.BanDuration)
+			h.bans.Add(1)
+			event.Banned = true
+		}
+		if h.options.TarpitDelay > 0 && h.active.Add(1) <= int64(h.options.MaxTarpits) {
+			event.Tarpitted = true
+		} else if h.options.TarpitDelay > 0 {
+			h.active.Add(-1)
+		}
+		log.Printf("Honeypot %s %s from %s (banned=%v, tarpitted=%v)", event.Method, event.Path, event.IP, event.Banned, event.Tarpitted)
+		if h.options.OnTrigger != nil {
+			h.options.OnTrigger(event)
+		}
+		if !event.Tarpitted {
+			http.NotFound(w, r)
+			return
+		}
+		defer h.active.Add(-1)
+		h.tarpitted.Add(1)
+		tarpit(w, r, h.options.TarpitDelay)
+	})
+}
+
+// tarpit trickles a byte every second for delay or until the client gives up.
+func tarpit(w http.ResponseWriter, r *http.Request, delay time.Duration) {
+	w.Header().Set("Content-Type", "text/html")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	ticker := time.NewTicker(min(time.Second, delay))
+	defer ticker.Stop()
+	deadline := time.NewTimer(delay)
+	defer deadline.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-deadline.C:
+			return
+		case <-ticker.C:
+			if _, err := w.Write([]byte(" ")); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// IdempotencyKeyHeader is the header clients send to make a request safe to retry.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotencyRecord is what is stored for an idempotency key. It is pending until the
+// first request completes and then holds its response.
+type IdempotencyRecord struct {
+	BodyHash string      `json:"body_hash"`
+	Done     bool        `json:"done"`
+	Status   int         `json:"status,omitempty"`
+	Header   http.Header `json:"header,omitempty"`
+	Body     []byte      `json:"body,omitempty"`
+}
+
+// IdempotencyStore persists idempotency records.
+type IdempotencyStore interface {
+	// Reserve stores record under key unless the key exists. It returns the existing
+	// record and false when the key was already used.
+	Reserve(ctx context.Context, key string, record IdempotencyRecord, ttl time.Duration) (*IdempotencyRecord, bool, error)
+	// Save replaces the record under key.
+	Save(ctx context.Context, key string, record IdempotencyRecord, ttl time.Duration) error
+	// Release removes the key so the request can be retried.
+	Release(ctx context.Context, key string) error
+}
+
+// Idempotency replays the stored response for retried POST and PUT requests carrying the same
+// Idempotency-Key. Keys are scoped to the tenant, the authenticated user, the method and the
+// path, so clients cannot replay the responses of others; use it after the authentication
+// middleware. Reusing a key with a different body, or while the first request is still
+// running, is answered with 409 Conflict. Responses with a 5xx status are not stored so the
+// request can be retried.
+type Idempotency struct {
+	store IdempotencyStore
+	ttl   time.Duration
+}
+
+// NewIdempotency creates an Idempotency middleware keeping responses for ttl.
+//
+// Example usage:
+//
+//	idempotency := middleware.NewIdempotency(middleware.NewRedisIdempotencyStore(client), 24*time.Hour)
+//	r.Use(idempotency)
+func NewIdempotency(store IdempotencyStore, ttl time.Duration) *Idempotency {
+	return &Idempotency{store: store, ttl: ttl}
+}
+
+func (i *Idempotency) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idempotencyKey := r.Header.Get(IdempotencyKeyHeader)
+		if idempotencyKey == "" || (r.Method != http.MethodPost && r.Method != http.MethodPut) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := ReadBody(r)
+		if err != nil {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+		sum := sha256.Sum256(body)
+		bodyHash := hex.EncodeToString(sum[:])
+		key := idempotencyScope(r) + r.Method + ":" + r.URL.Path + ":" + idempotencyKey
+
+		ctx := r.Context()
+		existing, reserved, err := i.store.Reserve(ctx, key, IdempotencyRecord{BodyHash: bodyHash}, i.ttl)
+		if err != nil {
+			if requestEnded(w, r) {
+				return
+			}
+			log.Printf("Error reserving idempotency key: %v", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !reserved {
+			switch {
+			case existing.BodyHash != bodyHash:
+				http.Error(w, "Idempotency-Key was already used with a different request", http.StatusConflict)
+			case !existing.Done:
+				http.Error(w, "A request with this Idempotency-Key is still in progress", http.StatusConflict)
+			default:
+				for name, values := range existing.Header {
+					w.Header()[name] = values
+				}
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(existing.Status)
+				w.Write(existing.Body)
+			}
+			return
+		}
+
+		rec := &ResponseRecorder{ResponseWriter: w, StatusCode: http.StatusOK, Body: new(bytes.Buffer)}
+		completed := false
+		defer func() {
+			// The outcome is stored even when the client left, with the values of the request
+			ctx := context.WithoutCancel(ctx)
+			// Free the key when the handler panicked or failed so the client can retry
+			if !completed || rec.StatusCode >= http.StatusInternalServerError {
+				if err := i.store.Release(ctx, key); err != nil {
+					log.Printf("Error releasing idempotency key: %v", err)
+				}
+				return
+			}
+			record := IdempotencyRecord{
+				BodyHash: bodyHash,
+				Done:     true,
+				Status:   rec.StatusCode,
+				Header:   rec.Header().Clone(),
+				Body:     rec.Body.Bytes(),
+			}
+			if err := i.store.Save(ctx, key, record, i.ttl); err != nil {
+				log.Printf("Error saving idempotent response: %v", err)
+			}
+		}()
+		next.ServeHTTP(rec, r)
+		completed = true
+	})
+}
+
+// idempotencyScope returns the prefix of the idempotency keys of the tenant and user of r.
+func idempotencyScope(r *http.Request) string {
+	scope := "idempotency:"
+	if user := RequestUser(r); user != "" {
+		scope += "user:" + url.QueryEscape(user) + ":"
+	}
+	return tenantScoped(r, scope)
+}
+
+// MemoryIdempotencyStore keeps idempotency records in memory, for single instance deployments and tests.
+type MemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]memoryIdempotencyEntry
+}
+
+type memoryIdempotencyEntry struct {
+	record  IdempotencyRecord
+	expires time.Time
+}
+
+// NewMemoryIdempotencyStore creates an in-memory idempotency store.
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{records: make(map[string]memoryIdempotencyEntry)}
+}
+
+func (s *MemoryIdempotencyStore) Reserve(ctx context.Context, key string, record IdempotencyRecord, ttl time.Duration) (*IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if entry, ok := s.records[key]; ok && now.Before(entry.expires) {
+		existing := entry.record
+		return &existing, false, nil
+	}
+	// Drop expired entries while holding the lock anyway
+	for k, entry := range s.records {
+		if !now.Before(entry.expires) {
+			delete(s.records, k)
+		}
+	}
+	s.records[key] = memoryIdempotencyEntry{record: record, expires: now.Add(ttl)}
+	return nil, true, nil
+}
+
+func (s *MemoryIdempotencyStore) Save(ctx context.Context, key string, record IdempotencyRecord, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[key] = memoryIdempotencyEntry{record: record, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+// RedisIdempotencyStore keeps idempotency records in Redis so retries can hit any instance.
+type RedisIdempotencyStore struct {
+	client redis.UniversalClient
+}
+
+// NewRedisIdempotencyStore creates a Redis backed idempotency store.
+func NewRedisIdempotencyStore(client redis.UniversalClient) *RedisIdempotencyStore {
+	return &RedisIdempotencyStore{client: client}
+}
+
+func (s *RedisIdempotencyStore) Reserve(ctx context.Context, key string, record IdempotencyRecord, ttl time.Duration) (*IdempotencyRecord, bool, error) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, false, err
+	}
+	reserved, err := s.client.SetNX(ctx, key, data, ttl).Result()
+	if err != nil {
+		return nil, false, err
+	}
+	if reserved {
+		return nil, true, nil
+	}
+	stored, err := s.client.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		// Expired in between, try again
+		return s.Reserve(ctx, key, record, ttl)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var existing IdempotencyRecord
+	if err := json.Unmarshal(stored, &existing); err != nil {
+		return nil, false, err
+	}
+	return &existing, false, nil
+}
+
+func (s *RedisIdempotencyStore) Save(ctx context.Context, key string, record IdempotencyRecord, ttl time.Duration) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, key, data, ttl).Err()
+}
+
+func (s *RedisIdempotencyStore) Release(ctx context.Context, key string) error {
+	return s.client.Del(ctx, key).Err()
+}
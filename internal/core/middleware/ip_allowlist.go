@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+)
+
+// IPAllowlist only lets requests from the given IPs and CIDRs through and answers 403 to
+// everyone else. The client IP honors forwarding headers of trusted proxies only, see
+// SetTrustedProxies.
+type IPAllowlist struct {
+	networks []*net.IPNet
+}
+
+// NewIPAllowlist creates an IPAllowlist for the given IPs and CIDRs.
+//
+// Example usage:
+//
+//	allow := middleware.NewIPAllowlist("10.0.0.0/8", "127.0.0.1")
+func NewIPAllowlist(entries ...string) *IPAllowlist {
+	return &IPAllowlist{networks: parseNetworks(entries)}
+}
+
+func (a *IPAllowlist) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !ipInNetworks(ClientIP(r), a.networks) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
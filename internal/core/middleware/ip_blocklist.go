@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// IPBlocklist answers 403 to requests from blocked IPs and CIDRs, and from IPs banned at
+// runtime, e.g. by a Honeypot. Bans are kept in memory, per instance. The client IP honors
+// forwarding headers of trusted proxies only, see SetTrustedProxies.
+type IPBlocklist struct {
+	networks []*net.IPNet
+
+	mu      sync.Mutex
+	bans    map[string]time.Time
+	sweepAt int
+	now     func() time.Time
+}
+
+// NewIPBlocklist creates an IPBlocklist blocking the given IPs and CIDRs for good.
+//
+// Example usage:
+//
+//	block := middleware.NewIPBlocklist("203.0.113.0/24")
+//	block.Ban("198.51.100.7", time.Hour)
+func NewIPBlocklist(entries ...string) *IPBlocklist {
+	return &IPBlocklist{networks: parseNetworks(entries), bans: map[string]time.Time{}, sweepAt: 1024, now: time.Now}
+}
+
+// Ban blocks ip for duration, or until Unban when duration is zero.
+func (b *IPBlocklist) Ban(ip string, duration time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var until time.Time
+	if duration > 0 {
+		until = b.now().Add(duration)
+	}
+	b.bans[ip] = until
+	// Drop expired bans now and then, so scans from many addresses do not grow the map forever
+	if len(b.bans) >= b.sweepAt {
+		now := b.now()
+		for banned, until := range b.bans {
+			if !until.IsZero() && now.After(until) {
+				delete(b.bans, banned)
+			}
+		}
+		b.sweepAt = max(1024, 2*len(b.bans))
+	}
+}
+
+// Unban lifts the ban of ip. IPs blocked by NewIPBlocklist entries stay blocked.
+func (b *IPBlocklist) Unban(ip string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.bans, ip)
+}
+
+// Blocked reports whether requests from ip are refused.
+func (b *IPBlocklist) Blocked(ip string) bool {
+	if ipInNetworks(ip, b.networks) {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	until, ok := b.bans[ip]
+	if ok && !until.IsZero() && b.now().After(until) {
+		delete(b.bans, ip)
+		return false
+	}
+	return ok
+}
+
+// Bans returns the banned IPs and when their ban ends, zero for bans without end.
+func (b *IPBlocklist) Bans() map[string]time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	bans := make(map[string]time.Time, len(b.bans))
+	for ip, until := range b.bans {
+		if until.IsZero() || !now.After(until) {
+			bans[ip] = until
+		}
+	}
+	return bans
+}
+
+func (b *IPBlocklist) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if b.Blocked(ClientIP(r)) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
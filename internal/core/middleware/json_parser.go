@@ -1,10 +1,8 @@
 package middleware
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
-	"io"
 	"log"
 	"net/http"
 )
@@ -55,17 +53,19 @@ func (jp *JSONParser) Handle(next http.Handler) http.Handler {
 				http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
 				return
 			}
-			// Read the body into a byte slice
-			bodyBytes, err := io.ReadAll(r.Body)
+			// Read the body into a byte slice, leaving it readable for the handler. Bodies without
+			// a Content-Length and bodies captured earlier are limited too.
+			bodyBytes, complete, err := PeekBody(r, maxBodySize)
+			if err == nil && !complete {
+				http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
 			if err != nil {
 				log.Print(err)
 				http.Error(w, "Invalid JSON", http.StatusBadRequest)
 				return
 			}
 
-			// Restore the io.ReadCloser to its original state
-			r.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
-
 			// Decode the body into a map
 			var body interface{} // map[string]interface{} global
 			if err := json.Unmarshal(bodyBytes, &body); err != nil {
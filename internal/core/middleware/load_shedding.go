@@ -0,0 +1,187 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Priority orders requests for load shedding. Lower priorities are rejected first.
+type Priority int
+
+const (
+	PriorityLow Priority = iota
+	PriorityNormal
+	PriorityHigh
+	// PriorityCritical requests, e.g. health checks and payments, are never shed.
+	PriorityCritical
+)
+
+// ParsePriority parses low, normal, high and critical. It reports false for other values.
+func ParsePriority(s string) (Priority, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "low":
+		return PriorityLow, true
+	case "normal":
+		return PriorityNormal, true
+	case "high":
+		return PriorityHigh, true
+	case "critical":
+		return PriorityCritical, true
+	}
+	return PriorityNormal, false
+}
+
+// LoadShedderOptions defines the saturation limits and how requests are prioritized.
+type LoadShedderOptions struct {
+	// Limits of the p99 latency, the goroutine count and the heap size. Zero disables a signal.
+	MaxLatency    time.Duration
+	MaxGoroutines int
+	MaxHeapBytes  uint64
+	// ShedAt is the load, as the highest fraction of a limit reached, from which requests of
+	// each priority are rejected. Critical requests are never rejected.
+	ShedAt map[Priority]float64
+	// PriorityHeader names the request header carrying the priority, e.g. set by the gateway.
+	// Clients can set it too, so only trust it behind a proxy that overwrites it.
+	PriorityHeader string
+	// RoutePriorities maps path prefixes to priorities; the longest matching prefix wins.
+	RoutePriorities map[string]Priority
+	// DefaultPriority applies to requests without header or matching route.
+	DefaultPriority Priority
+	// SampleInterval is how often the load is measured, and Window the number of recent
+	// request latencies the p99 is computed from.
+	SampleInterval time.Duration
+	Window         int
+}
+
+// NewLoadShedderOptions creates LoadShedderOptions shedding low priority requests from 80% of
+// a limit, normal ones from 90% and high ones at the limit.
+func NewLoadShedderOptions(maxLatency time.Duration, maxGoroutines int, maxHeapBytes uint64) *LoadShedderOptions {
+	return &LoadShedderOptions{
+		MaxLatency:    maxLatency,
+		MaxGoroutines: maxGoroutines,
+		MaxHeapBytes:  maxHeapBytes,
+		ShedAt: map[Priority]float64{
+			PriorityLow:    0.8,
+			PriorityNormal: 0.9,
+			PriorityHigh:   1.0,
+		},
+		PriorityHeader:  "X-Priority",
+		DefaultPriority: PriorityNormal,
+		SampleInterval:  time.Second,
+		Window:          1000,
+	}
+}
+
+// LoadShedder rejects requests with 503 when the server approaches saturation, measured by
+// the p99 latency of recent requests, the number of goroutines and the heap size. The closer
+// the server gets to a limit, the more priorities are rejected, so important traffic keeps
+// being served while the server recovers.
+type LoadShedder struct {
+	options LoadShedderOptions
+
+	mu        sync.Mutex
+	latencies []time.Duration
+	next      int
+
+	load       atomic.Uint64 // math.Float64bits of the last measured load
+	lastSample atomic.Int64
+}
+
+// NewLoadShedder creates the load shedding middleware.
+func NewLoadShedder(options LoadShedderOptions) *LoadShedder {
+	if options.Window <= 0 {
+		options.Window = 1000
+	}
+	if options.SampleInterval <= 0 {
+		options.SampleInterval = time.Second
+	}
+	return &LoadShedder{options: options, latencies: make([]time.Duration, 0, options.Window)}
+}
+
+// Load returns the last measured load, the highest fraction of a limit reached. Values of 1
+// and above mean a limit is exceeded.
+func (ls *LoadShedder) Load() float64 {
+	return math.Float64frombits(ls.load.Load())
+}
+
+func (ls *LoadShedder) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ls.sample()
+		priority := ls.priority(r)
+		if threshold, ok := ls.options.ShedAt[priority]; ok && priority != PriorityCritical && ls.Load() >= threshold {
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+			return
+		}
+
+		start := time.Now()
+		next.ServeHTTP(w, r)
+		ls.observe(time.Since(start))
+	})
+}
+
+func (ls *LoadShedder) priority(r *http.Request) Priority {
+	if ls.options.PriorityHeader != "" {
+		if priority, ok := ParsePriority(r.Header.Get(ls.options.PriorityHeader)); ok {
+			return priority
+		}
+	}
+	priority, longest := ls.options.DefaultPriority, -1
+	for prefix, p := range ls.options.RoutePriorities {
+		if strings.HasPrefix(r.URL.Path, prefix) && len(prefix) > longest {
+			priority, longest = p, len(prefix)
+		}
+	}
+	return priority
+}
+
+func (ls *LoadShedder) observe(d time.Duration) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	if len(ls.latencies) < ls.options.Window {
+		ls.latencies = append(ls.latencies, d)
+		return
+	}
+	ls.latencies[ls.next] = d
+	ls.next = (ls.next + 1) % ls.options.Window
+}
+
+// sample measures the load once per SampleInterval, on the request that finds it stale.
+func (ls *LoadShedder) sample() {
+	now := time.Now().UnixNano()
+	last := ls.lastSample.Load()
+	if now-last < int64(ls.options.SampleInterval) || !ls.lastSample.CompareAndSwap(last, now) {
+		return
+	}
+
+	var load float64
+	if ls.options.MaxLatency > 0 {
+		load = max(load, float64(ls.p99())/float64(ls.options.MaxLatency))
+	}
+	if ls.options.MaxGoroutines > 0 {
+		load = max(load, float64(runtime.NumGoroutine())/float64(ls.options.MaxGoroutines))
+	}
+	if ls.options.MaxHeapBytes > 0 {
+		var stats runtime.MemStats
+		runtime.ReadMemStats(&stats)
+		load = max(load, float64(stats.HeapAlloc)/float64(ls.options.MaxHeapBytes))
+	}
+	ls.load.Store(math.Float64bits(load))
+}
+
+func (ls *LoadShedder) p99() time.Duration {
+	ls.mu.Lock()
+	latencies := append([]time.Duration(nil), ls.latencies...)
+	ls.mu.Unlock()
+	if len(latencies) == 0 {
+		return 0
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	return latencies[len(latencies)*99/100]
+}
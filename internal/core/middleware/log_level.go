@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// logLevel is the level of the application logs, changed at runtime with SetLogLevel.
+var logLevel = new(slog.LevelVar)
+
+// LogLevel returns the level of the application logs. slog handlers created with it follow
+// the changes made by SetLogLevel, and the access log of Logger is written at the info level.
+//
+// Example usage:
+//
+//	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: middleware.LogLevel()}))
+func LogLevel() *slog.LevelVar {
+	return logLevel
+}
+
+// SetLogLevel sets the level of the application logs from its name, debug, info, warn or
+// error, optionally with an offset such as warn+2. The default slog logger follows it too.
+func SetLogLevel(name string) error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(name))); err != nil {
+		return fmt.Errorf("invalid log level %q, expected debug, info, warn or error", name)
+	}
+	logLevel.Set(level)
+	slog.SetLogLoggerLevel(level)
+	return nil
+}
@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"bufio"
+	"log"
+	"log/slog"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// LoggerOptions configures the access log.
+type LoggerOptions struct {
+	// Verbose also logs the query, user agent, client IP and request headers, for development.
+	Verbose bool
+	// SkipPaths are not logged, e.g. health checks polled by the load balancer.
+	SkipPaths []string
+	// Logf writes the log lines, log.Printf by default.
+	Logf func(format string, args ...interface{})
+}
+
+// NewLoggerOptions creates LoggerOptions skipping the health check paths.
+func NewLoggerOptions() *LoggerOptions {
+	return &LoggerOptions{SkipPaths: []string{"/health", "/healthz", "/ready", "/readyz"}}
+}
+
+// Logger logs one line per request with its method, path, status, size, duration and
+// request ID once the response is written. Lines are written at the info level, so raising
+// the level above it with SetLogLevel stops them.
+type Logger struct {
+	options LoggerOptions
+	skip    map[string]bool
+}
+
+// NewLogger creates the access log middleware.
+//
+// Example usage:
+//
+//	logger := middleware.NewLogger(*middleware.NewLoggerOptions())
+func NewLogger(options LoggerOptions) *Logger {
+	if options.Logf == nil {
+		options.Logf = log.Printf
+	}
+	skip := make(map[string]bool, len(options.SkipPaths))
+	for _, path := range options.SkipPaths {
+		skip[path] = true
+	}
+	return &Logger{options: options, skip: skip}
+}
+
+func (l *Logger) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if l.skip[r.URL.Path] || logLevel.Level() > slog.LevelInfo {
+			next.ServeHTTP(w, r)
+			return
+		}
+		start := time.Now()
+		rec := &loggedResponse{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		duration := time.Since(start)
+
+		id := RequestID(r)
+		if id == "" {
+			id = r.Header.Get("X-Request-ID")
+		}
+		line := r.Method + " " + r.URL.Path
+		if !l.options.Verbose {
+			l.options.Logf("%s %d %dB %s id=%s", line, rec.status, rec.bytes, duration.Round(time.Microsecond), id)
+			return
+		}
+		if r.URL.RawQuery != "" {
+			line += "?" + r.URL.RawQuery
+		}
+		headers := make([]string, 0, len(r.Header))
+		for name := range r.Header {
+			if name == "Authorization" || name == "Cookie" {
+				// Credentials stay out of logs, even in development
+				headers = append(headers, name+"=[redacted]")
+				continue
+			}
+			headers = append(headers, name+"="+strings.Join(r.Header[name], ","))
+		}
+		sort.Strings(headers)
+		l.options.Logf("%s %s %d %dB %s id=%s ip=%s ua=%q headers=%s", line, r.Proto, rec.status, rec.bytes,
+			duration.Round(time.Microsecond), id, ClientIP(r), r.UserAgent(), strings.Join(headers, " "))
+	})
+}
+
+// loggedResponse captures the status code and size of a response.
+type loggedResponse struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+func (rec *loggedResponse) WriteHeader(status int) {
+	// Early hints are followed by the final status
+	if !rec.wroteHeader && status >= http.StatusOK {
+		rec.status = status
+		rec.wroteHeader = true
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *loggedResponse) Write(p []byte) (int, error) {
+	rec.wroteHeader = true
+	n, err := rec.ResponseWriter.Write(p)
+	rec.bytes += int64(n)
+	return n, err
+}
+
+func (rec *loggedResponse) Flush() {
+	if flusher, ok := rec.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (rec *loggedResponse) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// Hijack lets WebSocket upgrades through the access log.
+func (rec *loggedResponse) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := rec.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	rec.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"encoding/json"
+	"html/template"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/config"
+)
+
+// defaultMaintenancePage is shown to browsers while maintenance mode is on.
+var defaultMaintenancePage = template.Must(template.New("maintenance").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Down for maintenance</title></head>
+<body>
+<h1>Down for maintenance</h1>
+<p>We are performing scheduled maintenance{{if .RetryAfter}} and expect to be back in about {{.RetryAfter}}{{end}}.</p>
+</body>
+</html>
+`))
+
+// MaintenancePage is the data the maintenance page template is executed with.
+type MaintenancePage struct {
+	RetryAfter time.Duration
+}
+
+type maintenanceState struct {
+	enabled    bool
+	networks   []*net.IPNet
+	retryAfter time.Duration
+}
+
+// Maintenance answers all requests with 503 Service Unavailable while maintenance mode is on,
+// except for health checks and clients from allowed networks, e.g. the office or CI.
+type Maintenance struct {
+	state     atomic.Pointer[maintenanceState]
+	skipPaths []string
+	page      *template.Template
+}
+
+// NewMaintenance creates the maintenance middleware, initially off. Requests to /health,
+// /healthz, /livez and /readyz always pass.
+func NewMaintenance(options ...func(*Maintenance)) *Maintenance {
+	m := &Maintenance{
+		skipPaths: []string{"/health", "/healthz", "/livez", "/readyz"},
+		page:      defaultMaintenancePage,
+	}
+	m.state.Store(&maintenanceState{})
+	for _, option := range options {
+		option(m)
+	}
+	return m
+}
+
+// WithMaintenanceSkipPaths sets the paths, such as health checks, that are served during
+// maintenance. A path ending in / also matches everything below it.
+func WithMaintenanceSkipPaths(paths ...string) func(*Maintenance) {
+	return func(m *Maintenance) {
+		m.skipPaths = paths
+	}
+}
+
+// WithMaintenancePage sets the HTML template shown to browsers, executed with a
+// MaintenancePage.
+func WithMaintenancePage(page *template.Template) func(*Maintenance) {
+	return func(m *Maintenance) {
+		m.page = page
+	}
+}
+
+// Set turns maintenance mode on or off. Clients from allowCIDRs keep access, and retryAfter,
+// if positive, is sent in the Retry-After header.
+func (m *Maintenance) Set(enabled bool, allowCIDRs []string, retryAfter time.Duration) {
+	m.state.Store(&maintenanceState{enabled: enabled, networks: parseNetworks(allowCIDRs), retryAfter: retryAfter})
+}
+
+// SetFromConfig applies the MAINTENANCE_* keys, so maintenance can be toggled by reloading
+// the configuration:
+//
+//	MAINTENANCE_MODE=true
+//	MAINTENANCE_ALLOW=10.0.0.0/8,203.0.113.7
+//	MAINTENANCE_RETRY_AFTER=15m
+func (m *Maintenance) SetFromConfig(cfg config.Config) {
+	retryAfter, err := time.ParseDuration(cfg.Get("MAINTENANCE_RETRY_AFTER", "0s"))
+	if err != nil {
+		retryAfter = time.Duration(cfg.GetInt("MAINTENANCE_RETRY_AFTER", 0)) * time.Second
+	}
+	m.Set(cfg.GetBool("MAINTENANCE_MODE", false), splitList(cfg.Get("MAINTENANCE_ALLOW", "")), retryAfter)
+}
+
+// Enabled reports whether maintenance mode is on.
+func (m *Maintenance) Enabled() bool {
+	return m.state.Load().enabled
+}
+
+func (m *Maintenance) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		state := m.state.Load()
+		if !state.enabled || m.skipped(r.URL.Path) || ipInNetworks(ClientIP(r), state.networks) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if state.retryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(state.retryAfter.Seconds())))
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		if strings.Contains(r.Header.Get("Accept"), "text/html") {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			m.page.Execute(w, MaintenancePage{RetryAfter: state.retryAfter})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Service is down for maintenance"})
+	})
+}
+
+func (m *Maintenance) skipped(path string) bool {
+	for _, skip := range m.skipPaths {
+		if path == skip || (strings.HasSuffix(skip, "/") && strings.HasPrefix(path, skip)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// MethodOverrideHeader is the header legacy clients use to tunnel a method through POST.
+const MethodOverrideHeader = "X-HTTP-Method-Override"
+
+// MethodOverrideField is the form field HTML forms use to tunnel a method through POST.
+const MethodOverrideField = "_method"
+
+// MethodOverride rewrites the method of POST requests from the X-HTTP-Method-Override
+// header or the _method form field, so HTML forms and clients limited to GET and POST can
+// reach PUT, PATCH and DELETE routes. Requests with any other original method are untouched.
+type MethodOverride struct {
+	allowed map[string]bool
+}
+
+// NewMethodOverride creates a MethodOverride middleware allowing the given methods, by
+// default PUT, PATCH and DELETE.
+//
+// Example usage:
+//
+//	<form method="POST" action="/users/42">
+//		<input type="hidden" name="_method" value="DELETE">
+//	</form>
+func NewMethodOverride(methods ...string) *MethodOverride {
+	if len(methods) == 0 {
+		methods = []string{http.MethodPut, http.MethodPatch, http.MethodDelete}
+	}
+	allowed := make(map[string]bool, len(methods))
+	for _, method := range methods {
+		allowed[strings.ToUpper(method)] = true
+	}
+	return &MethodOverride{allowed: allowed}
+}
+
+func (m *MethodOverride) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			if method := strings.ToUpper(strings.TrimSpace(m.override(r))); m.allowed[method] {
+				r.Method = method
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// override returns the requested method, the header taking precedence over the form field.
+func (m *MethodOverride) override(r *http.Request) string {
+	if method := r.Header.Get(MethodOverrideHeader); method != "" {
+		return method
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/x-www-form-urlencoded":
+		if err := r.ParseForm(); err != nil {
+			return ""
+		}
+	case "multipart/form-data":
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			return ""
+		}
+	default:
+		return ""
+	}
+	return r.PostForm.Get(MethodOverrideField)
+}
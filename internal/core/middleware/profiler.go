@@ -0,0 +1,216 @@
+package middleware
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/redact"
+)
+
+// ProfilerOptions defines how many samples the profiler keeps and which requests it captures.
+type ProfilerOptions struct {
+	// SlowThreshold captures requests taking at least this long. Zero disables capturing.
+	SlowThreshold time.Duration
+	// Window is the number of recent latencies per route the percentiles are computed from.
+	Window int
+	// MaxSlow is the number of slow requests kept, the oldest are dropped first.
+	MaxSlow int
+}
+
+// NewProfilerOptions creates ProfilerOptions capturing requests slower than slowThreshold.
+func NewProfilerOptions(slowThreshold time.Duration) *ProfilerOptions {
+	return &ProfilerOptions{SlowThreshold: slowThreshold, Window: 1000, MaxSlow: 100}
+}
+
+// RouteStats are the latency percentiles of a route.
+type RouteStats struct {
+	Route string        `json:"route"`
+	Count int64         `json:"count"`
+	P50   time.Duration `json:"p50"`
+	P90   time.Duration `json:"p90"`
+	P99   time.Duration `json:"p99"`
+	Max   time.Duration `json:"max"`
+}
+
+// SlowRequest is the trace of a request that exceeded the slow threshold.
+type SlowRequest struct {
+	Time     time.Time     `json:"time"`
+	Method   string        `json:"method"`
+	Path     string        `json:"path"`
+	Route    string        `json:"route"`
+	Status   int           `json:"status"`
+	Duration time.Duration `json:"duration"`
+	Headers  http.Header   `json:"headers"`
+	// Timings of the middlewares, recorded when the router wraps them with Timed.
+	Timings []Timing `json:"timings,omitempty"`
+}
+
+type routeSamples struct {
+	latencies []time.Duration
+	next      int
+	count     int64
+	max       time.Duration
+}
+
+// Profiler records the latency of every request per route and captures the headers and
+// middleware timings of slow requests.
+type Profiler struct {
+	options  ProfilerOptions
+	disabled atomic.Bool
+
+	mu     sync.Mutex
+	routes map[string]*routeSamples
+	slow   []SlowRequest
+}
+
+// NewProfiler creates the profiler middleware.
+func NewProfiler(options ProfilerOptions) *Profiler {
+	if options.Window <= 0 {
+		options.Window = 1000
+	}
+	if options.MaxSlow <= 0 {
+		options.MaxSlow = 100
+	}
+	return &Profiler{options: options, routes: map[string]*routeSamples{}}
+}
+
+// SetEnabled turns the recording of requests on or off while serving. The profiler is
+// enabled when created.
+func (p *Profiler) SetEnabled(enabled bool) {
+	p.disabled.Store(!enabled)
+}
+
+// Enabled reports whether requests are recorded.
+func (p *Profiler) Enabled() bool {
+	return !p.disabled.Load()
+}
+
+func (p *Profiler) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if p.disabled.Load() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		// The router stores the matched route and the middleware timings in the value store
+		r, _ = RequestValues(r)
+		rec := &profiledResponse{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+		next.ServeHTTP(rec, r)
+		duration := time.Since(start)
+
+		route, _ := RequestRoute(r)
+		if route == "" {
+			route = "unmatched"
+		}
+		p.record(route, duration)
+		if p.options.SlowThreshold > 0 && duration >= p.options.SlowThreshold {
+			p.capture(SlowRequest{
+				Time:     start,
+				Method:   r.Method,
+				Path:     r.URL.Path,
+				Route:    route,
+				Status:   rec.status,
+				Duration: duration,
+				Headers:  redact.Default().Header(r.Header),
+				Timings:  RequestTimings(r),
+			})
+		}
+	})
+}
+
+func (p *Profiler) record(route string, d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	samples := p.routes[route]
+	if samples == nil {
+		samples = &routeSamples{}
+		p.routes[route] = samples
+	}
+	samples.count++
+	if d > samples.max {
+		samples.max = d
+	}
+	if len(samples.latencies) < p.options.Window {
+		samples.latencies = append(samples.latencies, d)
+		return
+	}
+	samples.latencies[samples.next] = d
+	samples.next = (samples.next + 1) % p.options.Window
+}
+
+func (p *Profiler) capture(slow SlowRequest) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.slow) >= p.options.MaxSlow {
+		p.slow = p.slow[1:]
+	}
+	p.slow = append(p.slow, slow)
+}
+
+// Stats returns the latency percentiles of every route seen, slowest p99 first.
+func (p *Profiler) Stats() []RouteStats {
+	p.mu.Lock()
+	stats := make([]RouteStats, 0, len(p.routes))
+	for route, samples := range p.routes {
+		latencies := append([]time.Duration(nil), samples.latencies...)
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		stats = append(stats, RouteStats{
+			Route: route,
+			Count: samples.count,
+			P50:   percentile(latencies, 50),
+			P90:   percentile(latencies, 90),
+			P99:   percentile(latencies, 99),
+			Max:   samples.max,
+		})
+	}
+	p.mu.Unlock()
+	sort.Slice(stats, func(i, j int) bool { return stats[i].P99 > stats[j].P99 })
+	return stats
+}
+
+// SlowRequests returns the captured slow requests, newest first.
+func (p *Profiler) SlowRequests() []SlowRequest {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	slow := make([]SlowRequest, len(p.slow))
+	for i, request := range p.slow {
+		slow[len(p.slow)-1-i] = request
+	}
+	return slow
+}
+
+// Reset drops all samples and captured requests.
+func (p *Profiler) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.routes = map[string]*routeSamples{}
+	p.slow = nil
+}
+
+// percentile returns the pth percentile of sorted latencies.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	return sorted[(len(sorted)-1)*p/100]
+}
+
+// profiledResponse captures the status code of a response.
+type profiledResponse struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *profiledResponse) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *profiledResponse) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,193 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// QuotaPeriod is the window after which usage starts again from zero.
+type QuotaPeriod int
+
+const (
+	Daily QuotaPeriod = iota
+	Monthly
+)
+
+// window returns the start of the period containing t and the start of the next one, in UTC.
+func (p QuotaPeriod) window(t time.Time) (string, time.Time) {
+	t = t.UTC()
+	if p == Monthly {
+		return t.Format("2006-01"), time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return t.Format("2006-01-02"), time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+}
+
+// QuotaPlan is the number of requests a subject may make per period.
+type QuotaPlan struct {
+	Name   string
+	Limit  int64
+	Period QuotaPeriod
+}
+
+// QuotaStore counts usage per key.
+type QuotaStore interface {
+	// Increment adds one to the usage of key, which expires at expiresAt, and returns the new usage.
+	Increment(ctx context.Context, key string, expiresAt time.Time) (int64, error)
+}
+
+// QuotaOptions defines how usage is counted and which plan applies.
+type QuotaOptions struct {
+	// Scope separates the usage of routes with their own quota, empty for one quota overall.
+	Scope string
+	// Subject identifies who uses the quota. By default the X-API-Key header, then the
+	// tenant, then the client IP.
+	Subject func(r *http.Request) string
+	// Plans by name and the plan of a subject. Subjects without plan use DefaultPlan.
+	Plans       map[string]QuotaPlan
+	PlanFor     func(r *http.Request, subject string) string
+	DefaultPlan string
+	// OnExhausted is called once per period when a subject exceeds its plan, e.g. to bill
+	// overages or notify the customer.
+	OnExhausted func(r *http.Request, subject string, plan QuotaPlan)
+}
+
+// NewQuotaOptions creates QuotaOptions with a single plan applied to every subject.
+func NewQuotaOptions(limit int64, period QuotaPeriod) *QuotaOptions {
+	return &QuotaOptions{
+		Plans:       map[string]QuotaPlan{"default": {Name: "default", Limit: limit, Period: period}},
+		DefaultPlan: "default",
+	}
+}
+
+// Quota limits the number of requests per day or month and subject, beyond the burst limits
+// of the rate limiter. Responses carry X-Quota-Limit, X-Quota-Remaining and X-Quota-Reset;
+// requests over the quota get a 429 until the period ends.
+type Quota struct {
+	store   QuotaStore
+	options QuotaOptions
+	now     func() time.Time
+}
+
+// NewQuota creates the quota middleware counting usage in the store.
+func NewQuota(store QuotaStore, options QuotaOptions) *Quota {
+	if options.Subject == nil {
+		options.Subject = defaultQuotaSubject
+	}
+	return &Quota{store: store, options: options, now: time.Now}
+}
+
+func defaultQuotaSubject(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return "key:" + key
+	}
+	if tenant := RequestTenant(r); tenant != "" {
+		return "tenant:" + tenant
+	}
+	return "ip:" + ClientIP(r)
+}
+
+func (q *Quota) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		subject := q.options.Subject(r)
+		planName := q.options.DefaultPlan
+		if q.options.PlanFor != nil {
+			if name := q.options.PlanFor(r, subject); name != "" {
+				planName = name
+			}
+		}
+		plan, ok := q.options.Plans[planName]
+		if !ok {
+			// Subjects without a plan are unlimited
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		period, reset := plan.Period.window(q.now())
+		key := "quota:" + q.options.Scope + ":" + subject + ":" + period
+		used, err := q.store.Increment(r.Context(), key, reset)
+		if err != nil {
+			if requestEnded(w, r) {
+				return
+			}
+			// Fail open, an unavailable store must not take the API down
+			log.Printf("Error counting quota usage: %v", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		remaining := plan.Limit - used
+		if remaining < 0 {
+			remaining = 0
+		}
+		w.Header().Set("X-Quota-Limit", strconv.FormatInt(plan.Limit, 10))
+		w.Header().Set("X-Quota-Remaining", strconv.FormatInt(remaining, 10))
+		w.Header().Set("X-Quota-Reset", strconv.FormatInt(reset.Unix(), 10))
+		if used > plan.Limit {
+			if used == plan.Limit+1 && q.options.OnExhausted != nil {
+				q.options.OnExhausted(r, subject, plan)
+			}
+			w.Header().Set("Retry-After", strconv.Itoa(int(reset.Sub(q.now()).Seconds())+1))
+			http.Error(w, "Quota exceeded", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// MemoryQuotaStore counts usage in memory, for tests and single instance deployments.
+type MemoryQuotaStore struct {
+	mu     sync.Mutex
+	counts map[string]quotaCount
+}
+
+type quotaCount struct {
+	used      int64
+	expiresAt time.Time
+}
+
+// NewMemoryQuotaStore creates an in-memory quota store.
+func NewMemoryQuotaStore() *MemoryQuotaStore {
+	return &MemoryQuotaStore{counts: make(map[string]quotaCount)}
+}
+
+func (s *MemoryQuotaStore) Increment(ctx context.Context, key string, expiresAt time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for k, count := range s.counts {
+		if now.After(count.expiresAt) {
+			delete(s.counts, k)
+		}
+	}
+	count := s.counts[key]
+	count.used++
+	count.expiresAt = expiresAt
+	s.counts[key] = count
+	return count.used, nil
+}
+
+// RedisQuotaStore counts usage in Redis, shared by all instances.
+type RedisQuotaStore struct {
+	client redis.UniversalClient
+}
+
+// NewRedisQuotaStore creates a Redis backed quota store.
+func NewRedisQuotaStore(client redis.UniversalClient) *RedisQuotaStore {
+	return &RedisQuotaStore{client: client}
+}
+
+func (s *RedisQuotaStore) Increment(ctx context.Context, key string, expiresAt time.Time) (int64, error) {
+	pipe := s.client.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.ExpireAt(ctx, key, expiresAt)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
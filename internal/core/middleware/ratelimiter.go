@@ -6,9 +6,11 @@ import (
 	"net/http"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-redis/redis/v8"
+	"github.com/hokamsingh/lessgo/internal/core/probe"
 )
 
 // RateLimiterType defines the type of rate limiter (InMemory or RedisBacked).
@@ -23,9 +25,11 @@ const (
 // a client can make to your server within a specified interval.
 type RateLimiter struct {
 	limiterType     RateLimiterType
-	limit           int
-	interval        time.Duration
-	redisClient     *redis.Client
+	limit           atomic.Int64 // Changed at runtime by SetLimit
+	interval        atomic.Int64 // Nanoseconds
+	redisClient     redis.UniversalClient
+	health          *RedisHealth
+	fallback        *RateLimiter
 	shards          []*shard
 	numShards       int
 	cleanupInterval time.Duration
@@ -57,19 +61,14 @@ func NewRateLimiter(limiterType RateLimiterType, config interface{}) *RateLimite
 		cfg := config.(InMemoryConfig)
 		rl := &RateLimiter{
 			limiterType:     InMemory,
-			limit:           cfg.Limit,
-			interval:        cfg.Interval,
 			cleanupInterval: cfg.CleanupInterval,
 			numShards:       cfg.NumShards,
 			shards:          make([]*shard, cfg.NumShards),
-			bufferPool: sync.Pool{
-				New: func() interface{} {
-					return &circularBuffer{
-						timestamps: make([]time.Time, cfg.Limit),
-						size:       cfg.Limit,
-					}
-				},
-			},
+		}
+		rl.limit.Store(int64(cfg.Limit))
+		rl.interval.Store(int64(cfg.Interval))
+		rl.bufferPool.New = func() interface{} {
+			return newCircularBuffer(int(rl.limit.Load()))
 		}
 		for i := 0; i < cfg.NumShards; i++ {
 			rl.shards[i] = &shard{
@@ -82,17 +81,26 @@ func NewRateLimiter(limiterType RateLimiterType, config interface{}) *RateLimite
 	case RedisBacked:
 		ctx := context.Background()
 		cfg := config.(*RedisConfig)
-		client := &cfg.Client
-		_, err := client.Ping(ctx).Result()
-		if err != nil {
-			log.Fatalf("Could not connect to Redis: %v", err)
+		client := cfg.Client
+		if result := probe.Default().CheckTarget(ctx, probe.Redis(client)); !result.Healthy {
+			log.Fatalf("Could not connect to Redis: %v", result.Err)
 		}
-		return &RateLimiter{
+		// While Redis is down, limits are enforced per instance
+		fallback := NewRateLimiter(InMemory, InMemoryConfig{
+			NumShards:       16,
+			Limit:           cfg.Limit,
+			Interval:        cfg.Interval,
+			CleanupInterval: cfg.Interval,
+		})
+		rl := &RateLimiter{
 			limiterType: RedisBacked,
-			limit:       cfg.Limit,
-			interval:    cfg.Interval,
 			redisClient: client,
+			health:      RedisHealthOf(client),
+			fallback:    fallback,
 		}
+		rl.limit.Store(int64(cfg.Limit))
+		rl.interval.Store(int64(cfg.Interval))
+		return rl
 
 	default:
 		panic("Unsupported rate limiter type")
@@ -118,19 +126,37 @@ func NewInMemoryConfig(NumShards int, Limit int, Interval time.Duration, Cleanup
 
 // RedisConfig is the configuration for the Redis-backed rate limiter.
 type RedisConfig struct {
-	Client   redis.Client
+	Client   redis.UniversalClient
 	Limit    int
 	Interval time.Duration
 }
 
-func NewRedisConfig(client *redis.Client, limit int, interval time.Duration) *RedisConfig {
+func NewRedisConfig(client redis.UniversalClient, limit int, interval time.Duration) *RedisConfig {
 	return &RedisConfig{
-		Client:   *client,
+		Client:   client,
 		Limit:    limit,
 		Interval: interval,
 	}
 }
 
+// Limit returns the number of requests allowed per client in the interval.
+func (rl *RateLimiter) Limit() (int, time.Duration) {
+	return int(rl.limit.Load()), time.Duration(rl.interval.Load())
+}
+
+// SetLimit changes the number of requests allowed per client in the interval while serving,
+// e.g. from the runtime controls of the admin router. Requests already counted stay counted.
+func (rl *RateLimiter) SetLimit(limit int, interval time.Duration) {
+	if limit < 1 || interval <= 0 {
+		return
+	}
+	rl.limit.Store(int64(limit))
+	rl.interval.Store(int64(interval))
+	if rl.fallback != nil {
+		rl.fallback.SetLimit(limit, interval)
+	}
+}
+
 // Handle is the middleware function that processes incoming HTTP requests.
 //
 // It applies rate limiting based on the specified rate limiter type (in-memory or Redis-backed).
@@ -150,8 +176,10 @@ func (rl *RateLimiter) Handle(next http.Handler) http.Handler {
 // It uses a circular buffer to store timestamps of requests and a sync.Pool to reuse buffers.
 func (rl *RateLimiter) handleInMemory(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		key := r.RemoteAddr
+		// Clients are limited across tenants, so naming another tenant does not reset their limit
+		key := ClientIP(r)
 		now := time.Now()
+		limit, interval := rl.Limit()
 
 		sh := rl.getShard(key)
 		sh.mu.Lock()
@@ -159,20 +187,24 @@ func (rl *RateLimiter) handleInMemory(next http.Handler) http.Handler {
 		cb, exists := sh.requests[key]
 		if !exists {
 			cb = rl.bufferPool.Get().(*circularBuffer)
-			sh.requests[key] = cb
 		}
+		if cb.size < limit {
+			// The limit was raised, the buffer must hold as many requests
+			cb = cb.grow(limit)
+		}
+		sh.requests[key] = cb
 
 		count := 0
 		for i := 0; i < cb.size; i++ {
 			if cb.timestamps[i].IsZero() {
 				break
 			}
-			if now.Sub(cb.timestamps[i]) < rl.interval {
+			if now.Sub(cb.timestamps[i]) < interval {
 				count++
 			}
 		}
 
-		if count >= rl.limit {
+		if count >= limit {
 			http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
 			sh.mu.Unlock()
 			return
@@ -188,33 +220,52 @@ func (rl *RateLimiter) handleInMemory(next http.Handler) http.Handler {
 // handleRedis handles rate limiting using a Redis-backed approach.
 //
 // It uses Redis sorted sets to store timestamps of requests and ensures rate limiting across distributed systems.
+// While Redis is unavailable, requests are limited in memory instead.
 func (rl *RateLimiter) handleRedis(next http.Handler) http.Handler {
+	fallback := rl.fallback.handleInMemory(next)
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		key := r.RemoteAddr
+		if !rl.health.Healthy() {
+			rl.health.degrade()
+			fallback.ServeHTTP(w, r)
+			return
+		}
+		key := ClientIP(r)
 		now := time.Now().UnixNano()
-		ctx := context.Background()
+		// Redis calls give up with the request, on its deadline or when the client leaves
+		ctx := r.Context()
+		limit, interval := rl.Limit()
 
-		windowStart := now - rl.interval.Nanoseconds()
+		windowStart := now - interval.Nanoseconds()
 
 		pipe := rl.redisClient.TxPipeline()
 		pipe.ZAdd(ctx, key, &redis.Z{Score: float64(now), Member: now})
 		pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(windowStart, 10))
 		pipe.ZCard(ctx, key)
-		pipe.Expire(ctx, key, rl.interval)
+		pipe.Expire(ctx, key, interval)
 
 		_, err := pipe.Exec(ctx)
 		if err != nil {
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			if requestEnded(w, r) {
+				return
+			}
+			rl.health.Report(err)
+			rl.health.degrade()
+			fallback.ServeHTTP(w, r)
 			return
 		}
 
 		reqCount, err := rl.redisClient.ZCard(ctx, key).Result()
 		if err != nil {
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			if requestEnded(w, r) {
+				return
+			}
+			rl.health.Report(err)
+			rl.health.degrade()
+			fallback.ServeHTTP(w, r)
 			return
 		}
 
-		if int(reqCount) > rl.limit {
+		if int(reqCount) > limit {
 			http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
 			return
 		}
@@ -231,6 +282,22 @@ func (rl *RateLimiter) getShard(key string) *shard {
 	return rl.shards[int(hash)%rl.numShards]
 }
 
+func newCircularBuffer(size int) *circularBuffer {
+	return &circularBuffer{timestamps: make([]time.Time, size), size: size}
+}
+
+// grow returns a buffer of the given size holding the timestamps of cb.
+func (cb *circularBuffer) grow(size int) *circularBuffer {
+	grown := newCircularBuffer(size)
+	n := copy(grown.timestamps, cb.timestamps)
+	if cb.full {
+		grown.end = n
+	} else {
+		grown.end = cb.end
+	}
+	return grown
+}
+
 // add inserts a new timestamp into the circular buffer.
 //
 // It updates the buffer's pointers and handles buffer wrapping.
@@ -249,6 +316,7 @@ func (cb *circularBuffer) add(t time.Time) {
 func (rl *RateLimiter) cleanup() {
 	for {
 		time.Sleep(rl.cleanupInterval)
+		limit, interval := rl.Limit()
 		for _, sh := range rl.shards {
 			sh.mu.Lock()
 			for key, cb := range sh.requests {
@@ -258,12 +326,14 @@ func (rl *RateLimiter) cleanup() {
 					if cb.timestamps[i].IsZero() {
 						break
 					}
-					if now.Sub(cb.timestamps[i]) < rl.interval {
+					if now.Sub(cb.timestamps[i]) < interval {
 						count++
 					}
 				}
 				if count == 0 {
-					rl.bufferPool.Put(cb)
+					if cb.size == limit {
+						rl.bufferPool.Put(cb)
+					}
 					delete(sh.requests, key)
 				}
 			}
@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"syscall"
+)
+
+// RecoveryOptions defines how the recovery middleware handles panics.
+type RecoveryOptions struct {
+	// PanicHandler writes the response for a panic. By default errors with a StatusCode
+	// method, such as router.HTTPError, get their status and message, others a 500.
+	PanicHandler func(w http.ResponseWriter, r *http.Request, err error)
+	// Reporter receives panics with their stack trace, e.g. to send them to Sentry.
+	Reporter func(r *http.Request, err error, stack []byte)
+	// StackTrace logs the stack trace of panics.
+	StackTrace bool
+	// Logf logs panics, log.Printf by default.
+	Logf func(format string, args ...interface{})
+}
+
+// NewRecoveryOptions creates RecoveryOptions logging stack traces.
+func NewRecoveryOptions() *RecoveryOptions {
+	return &RecoveryOptions{StackTrace: true}
+}
+
+// PanicError is the error of a panic whose value was not an error.
+type PanicError struct {
+	Value interface{}
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Recovery turns panics of later handlers into error responses instead of dropped
+// connections. Panics caused by clients that went away are logged in one line and not
+// reported.
+type Recovery struct {
+	options RecoveryOptions
+}
+
+// NewRecovery creates the recovery middleware.
+func NewRecovery(options RecoveryOptions) *Recovery {
+	if options.Logf == nil {
+		options.Logf = log.Printf
+	}
+	if options.PanicHandler == nil {
+		options.PanicHandler = defaultPanicHandler
+	}
+	return &Recovery{options: options}
+}
+
+func (rc *Recovery) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Attach the value store so reports of panics include the matched route
+		r, _ = RequestValues(r)
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if recovered == http.ErrAbortHandler {
+				// Deliberate aborts are handled by net/http
+				panic(recovered)
+			}
+			err, ok := recovered.(error)
+			if !ok {
+				err = &PanicError{Value: recovered}
+			}
+			if IsBrokenPipe(err) {
+				rc.options.Logf("Client disconnected during %s %s: %v", r.Method, r.URL.Path, err)
+				return
+			}
+
+			stack := debug.Stack()
+			rc.options.Logf("Panic recovered in %s %s: %v", r.Method, r.URL.Path, err)
+			if rc.options.StackTrace {
+				rc.options.Logf("Stack trace:\n%s", stack)
+			}
+			if rc.options.Reporter != nil {
+				rc.options.Reporter(r, err, stack)
+			}
+			rc.options.PanicHandler(w, r, err)
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+func defaultPanicHandler(w http.ResponseWriter, r *http.Request, err error) {
+	var status interface{ StatusCode() int }
+	if errors.As(err, &status) {
+		// The error itself may hold internal details, only public messages are sent
+		message := http.StatusText(status.StatusCode())
+		var httpError interface{ PublicMessage() string }
+		if errors.As(err, &httpError) {
+			message = httpError.PublicMessage()
+		}
+		http.Error(w, message, status.StatusCode())
+		return
+	}
+	http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+}
+
+// IsBrokenPipe reports whether err means the client closed the connection while the
+// response was written.
+func IsBrokenPipe(err error) bool {
+	if errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	message := strings.ToLower(err.Error())
+	return strings.Contains(message, "broken pipe") || strings.Contains(message, "connection reset by peer")
+}
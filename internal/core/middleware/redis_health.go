@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/hokamsingh/lessgo/internal/core/probe"
+)
+
+// RedisHealthStats are the counters of a RedisHealth.
+type RedisHealthStats struct {
+	Healthy bool `json:"healthy"`
+	// Since is when Redis became unavailable or available again; zero if it never failed.
+	Since    time.Time `json:"since"`
+	Failures int64     `json:"failures"`
+	// Degraded counts the requests served without Redis.
+	Degraded int64 `json:"degraded"`
+}
+
+// RedisHealth tracks whether a Redis client is usable, so the middlewares depending on it
+// degrade instead of failing requests while Redis is down: the response cache is skipped and
+// rate limits are enforced in memory, per instance. Failed commands mark Redis unavailable,
+// then it is checked through probe.Default every interval until it answers again.
+type RedisHealth struct {
+	client   redis.UniversalClient
+	interval time.Duration
+
+	down     atomic.Bool
+	failures atomic.Int64
+	degraded atomic.Int64
+
+	mu    sync.Mutex
+	since time.Time
+}
+
+var redisHealths sync.Map // redis.UniversalClient to *RedisHealth
+
+// RedisHealthOf returns the health of client shared by all middlewares using it, created with
+// a one second check interval on first use.
+//
+// Example usage:
+//
+//	stats := middleware.RedisHealthOf(client).Stats()
+//	log.Printf("redis healthy=%v degraded requests=%d", stats.Healthy, stats.Degraded)
+func RedisHealthOf(client redis.UniversalClient) *RedisHealth {
+	if health, ok := redisHealths.Load(client); ok {
+		return health.(*RedisHealth)
+	}
+	health, _ := redisHealths.LoadOrStore(client, NewRedisHealth(client, time.Second))
+	return health.(*RedisHealth)
+}
+
+// NewRedisHealth creates a health tracker pinging client every interval while it is down.
+func NewRedisHealth(client redis.UniversalClient, interval time.Duration) *RedisHealth {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	return &RedisHealth{client: client, interval: interval}
+}
+
+// Healthy reports whether Redis is considered available.
+func (h *RedisHealth) Healthy() bool {
+	return !h.down.Load()
+}
+
+// Report records the result of a Redis command. Errors other than a missing key or a reply
+// error of Redis mark it unavailable and start the recovery checks.
+func (h *RedisHealth) Report(err error) {
+	var replyErr redis.Error
+	if err == nil || err == redis.Nil || errors.Is(err, context.Canceled) || (errors.As(err, &replyErr) && !isConnError(err)) {
+		return
+	}
+	h.failures.Add(1)
+	if !h.down.CompareAndSwap(false, true) {
+		return
+	}
+	h.mu.Lock()
+	h.since = time.Now()
+	h.mu.Unlock()
+	log.Printf("Redis unavailable, degrading caching and rate limiting: %v", err)
+	go h.recover()
+}
+
+// isConnError reports whether a reply error means the server cannot serve commands.
+func isConnError(err error) bool {
+	for _, prefix := range []string{"LOADING", "READONLY", "CLUSTERDOWN", "MASTERDOWN", "TRYAGAIN"} {
+		if strings.HasPrefix(err.Error(), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// degrade counts a request served without Redis.
+func (h *RedisHealth) degrade() {
+	h.degraded.Add(1)
+}
+
+func (h *RedisHealth) recover() {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		target := probe.Redis(h.client)
+		target.Timeout = h.interval
+		if !probe.Default().CheckTarget(context.Background(), target).Healthy {
+			continue
+		}
+		h.mu.Lock()
+		down := time.Since(h.since)
+		h.since = time.Now()
+		h.mu.Unlock()
+		h.down.Store(false)
+		log.Printf("Redis available again after %s, leaving degraded mode", down.Round(time.Second))
+		return
+	}
+}
+
+// Stats returns the state and counters of the health tracker.
+func (h *RedisHealth) Stats() RedisHealthStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return RedisHealthStats{
+		Healthy:  h.Healthy(),
+		Since:    h.since,
+		Failures: h.failures.Load(),
+		Degraded: h.degraded.Load(),
+	}
+}
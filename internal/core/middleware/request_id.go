@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// RequestIDKey is the key of the request ID in the request values.
+const RequestIDKey = "request.id"
+
+// RequestIDMiddleware gives every request an ID, taken from the request header when a proxy already
+// set one or generated otherwise. The ID is stored in the request values, set on the
+// request header for the middlewares reading it there, and echoed in the response.
+type RequestIDMiddleware struct {
+	header   string
+	generate func() string
+	trust    bool
+}
+
+// NewRequestID creates the middleware using the X-Request-ID header and UUIDs.
+func NewRequestID(options ...func(*RequestIDMiddleware)) *RequestIDMiddleware {
+	rid := &RequestIDMiddleware{header: "X-Request-ID", generate: uuid.NewString, trust: true}
+	for _, option := range options {
+		option(rid)
+	}
+	return rid
+}
+
+// WithRequestIDHeader sets the header carrying the ID.
+func WithRequestIDHeader(header string) func(*RequestIDMiddleware) {
+	return func(rid *RequestIDMiddleware) {
+		rid.header = header
+	}
+}
+
+// WithRequestIDGenerator sets how new IDs are generated.
+func WithRequestIDGenerator(generate func() string) func(*RequestIDMiddleware) {
+	return func(rid *RequestIDMiddleware) {
+		rid.generate = generate
+	}
+}
+
+// WithoutIncomingRequestID ignores IDs sent by clients, for apps not behind a proxy that
+// sets them.
+func WithoutIncomingRequestID() func(*RequestIDMiddleware) {
+	return func(rid *RequestIDMiddleware) {
+		rid.trust = false
+	}
+}
+
+func (rid *RequestIDMiddleware) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(rid.header)
+		// Incoming IDs end up in logs, so overly long ones are replaced
+		if !rid.trust || id == "" || len(id) > 128 {
+			id = rid.generate()
+			r.Header.Set(rid.header, id)
+		}
+		w.Header().Set(rid.header, id)
+		next.ServeHTTP(w, SetValue(r, RequestIDKey, id))
+	})
+}
+
+// RequestID returns the ID given to the request by the RequestIDMiddleware.
+func RequestID(r *http.Request) string {
+	id, _ := valueOf(r, RequestIDKey).(string)
+	return id
+}
@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"context"
+	"hash/fnv"
+	"net/http"
+	"sync/atomic"
+)
+
+// FeatureFlags decides whether a feature is enabled for a subject, e.g. a user ID.
+// Feature flag services can be plugged into SoftLaunch by implementing it.
+type FeatureFlags interface {
+	IsEnabled(ctx context.Context, flag string, subject string) bool
+}
+
+// FeatureFlagsFunc adapts a function to the FeatureFlags interface.
+type FeatureFlagsFunc func(ctx context.Context, flag string, subject string) bool
+
+// IsEnabled implements FeatureFlags.
+func (f FeatureFlagsFunc) IsEnabled(ctx context.Context, flag string, subject string) bool {
+	return f(ctx, flag, subject)
+}
+
+// SoftLaunchOptions defines who can reach a dark-launched route.
+type SoftLaunchOptions struct {
+	Name    string // Salts the hash so different launches select different subjects
+	Percent int    // Share of subjects, 0 to 100, that can reach the route
+
+	// CohortHeader and CohortValues always admit requests carrying one of the values,
+	// e.g. X-Beta: internal.
+	CohortHeader string
+	CohortValues []string
+
+	// SubjectHeader identifies the subject for hashing, e.g. X-User-ID. The authenticated
+	// principal is used first and the client IP last.
+	SubjectHeader string
+
+	// Flags and Flag delegate the decision to a feature flag service. When set, the
+	// percentage is ignored.
+	Flags FeatureFlags
+	Flag  string
+}
+
+// NewSoftLaunchOptions creates options enabling a route for the given percentage of subjects.
+func NewSoftLaunchOptions(name string, percent int) *SoftLaunchOptions {
+	return &SoftLaunchOptions{Name: name, Percent: percent, SubjectHeader: "X-User-ID"}
+}
+
+// SoftLaunch only lets a share of traffic through and answers 404 to everyone else,
+// so the route looks like it does not exist yet.
+type SoftLaunch struct {
+	options SoftLaunchOptions
+	percent atomic.Int32
+}
+
+// NewSoftLaunch creates a SoftLaunch middleware.
+//
+// Example usage:
+//
+//	launch := middleware.NewSoftLaunch(*middleware.NewSoftLaunchOptions("checkout-v2", 10))
+//	launch.SetPercent(50) // widen the rollout at runtime
+func NewSoftLaunch(options SoftLaunchOptions) *SoftLaunch {
+	s := &SoftLaunch{options: options}
+	s.SetPercent(options.Percent)
+	return s
+}
+
+// Name returns the name of the launch.
+func (s *SoftLaunch) Name() string {
+	return s.options.Name
+}
+
+// SetPercent changes the share of subjects that can reach the route.
+func (s *SoftLaunch) SetPercent(percent int) {
+	if percent < 0 {
+		percent = 0
+	}
+	if percent > 100 {
+		percent = 100
+	}
+	s.percent.Store(int32(percent))
+}
+
+// Percent returns the current share of subjects that can reach the route.
+func (s *SoftLaunch) Percent() int {
+	return int(s.percent.Load())
+}
+
+// Enabled reports whether the request may reach the route.
+func (s *SoftLaunch) Enabled(r *http.Request) bool {
+	if s.options.CohortHeader != "" {
+		if value := r.Header.Get(s.options.CohortHeader); value != "" {
+			for _, allowed := range s.options.CohortValues {
+				if value == allowed {
+					return true
+				}
+			}
+		}
+	}
+
+	subject := s.subject(r)
+	if s.options.Flags != nil && s.options.Flag != "" {
+		return s.options.Flags.IsEnabled(r.Context(), s.options.Flag, subject)
+	}
+
+	percent := s.Percent()
+	if percent >= 100 {
+		return true
+	}
+	if percent <= 0 {
+		return false
+	}
+	return Bucket(s.options.Name, subject) < percent
+}
+
+// subject identifies who made the request so each subject gets a stable decision.
+func (s *SoftLaunch) subject(r *http.Request) string {
+	if principal, ok := GetPrincipal(r.Context()); ok && principal.User != "" {
+		return principal.User
+	}
+	if s.options.SubjectHeader != "" {
+		if value := r.Header.Get(s.options.SubjectHeader); value != "" {
+			return value
+		}
+	}
+	return ClientIP(r)
+}
+
+// Bucket deterministically maps a subject to a bucket from 0 to 99 for the named launch.
+func Bucket(name, subject string) int {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	h.Write([]byte{0})
+	h.Write([]byte(subject))
+	return int(h.Sum32() % 100)
+}
+
+// Handle answers 404 to requests outside of the launch.
+func (s *SoftLaunch) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.Enabled(r) {
+			http.NotFound(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
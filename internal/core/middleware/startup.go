@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/hokamsingh/lessgo/internal/core/probe"
+)
+
+// StartupChecker is implemented by middlewares depending on external services. The router
+// calls CheckStartup before serving, so an unreachable service fails the start instead of
+// every request.
+type StartupChecker interface {
+	CheckStartup(ctx context.Context) error
+}
+
+// Authenticator is implemented by middlewares that authenticate requests, so the router
+// can check they run before middlewares that must not see anonymous requests, such as
+// response caching. Custom authentication middlewares should implement it.
+type Authenticator interface {
+	IsAuthenticator() bool
+}
+
+// IsAuthenticator implements Authenticator.
+func (t *TrustedHeaderAuth) IsAuthenticator() bool { return true }
+
+// IsAuthenticator implements Authenticator for the wrapped middleware.
+func (c *Conditional) IsAuthenticator() bool {
+	auth, ok := c.middleware.(Authenticator)
+	return ok && auth.IsAuthenticator()
+}
+
+// CheckStartup implements StartupChecker for the wrapped middleware.
+func (c *Conditional) CheckStartup(ctx context.Context) error {
+	if checker, ok := c.middleware.(StartupChecker); ok {
+		return checker.CheckStartup(ctx)
+	}
+	return nil
+}
+
+// CheckStartup pings the Redis of a Redis cache store.
+func (c *Caching) CheckStartup(ctx context.Context) error {
+	if store, ok := c.store.(*RedisCacheStore); ok {
+		return pingRedis(ctx, "response cache", store.client)
+	}
+	return nil
+}
+
+// CheckStartup pings the Redis of a Redis idempotency store.
+func (i *Idempotency) CheckStartup(ctx context.Context) error {
+	if store, ok := i.store.(*RedisIdempotencyStore); ok {
+		return pingRedis(ctx, "idempotency store", store.client)
+	}
+	return nil
+}
+
+// CheckStartup pings the Redis of a Redis quota store.
+func (q *Quota) CheckStartup(ctx context.Context) error {
+	if store, ok := q.store.(*RedisQuotaStore); ok {
+		return pingRedis(ctx, "quota store", store.client)
+	}
+	return nil
+}
+
+func pingRedis(ctx context.Context, name string, client redis.UniversalClient) error {
+	if result := probe.Default().CheckTarget(ctx, probe.Redis(client)); !result.Healthy {
+		return fmt.Errorf("Redis of the %s is unreachable: %w", name, result.Err)
+	}
+	return nil
+}
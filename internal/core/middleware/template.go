@@ -6,14 +6,59 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+
+	"github.com/hokamsingh/lessgo/internal/core/sanitize"
 )
 
 type TemplateMiddleware struct {
-	Tmpl *template.Template
+	Tmpl    *template.Template
+	base    *template.Template // never executed, so it can be cloned to bind request functions
+	headers map[string]string  // security headers of rendered pages
+}
+
+// TemplateSecurityOptions are the headers sent with pages rendered from templates. Empty
+// values are not sent.
+type TemplateSecurityOptions struct {
+	ContentSecurityPolicy string // Content-Security-Policy
+	// XSSProtection is X-XSS-Protection. The filter of older browsers can be abused to remove
+	// scripts of a page, so the default disables it and relies on the CSP.
+	XSSProtection string
+}
+
+// NewTemplateSecurityOptions creates options allowing scripts, styles and images of the same
+// origin only, without plugins or framing.
+func NewTemplateSecurityOptions() *TemplateSecurityOptions {
+	return &TemplateSecurityOptions{
+		ContentSecurityPolicy: "default-src 'self'; object-src 'none'; base-uri 'self'; frame-ancestors 'none'",
+		XSSProtection:         "0",
+	}
 }
 
-func NewTemplateMiddleware(templateDir string) *TemplateMiddleware {
-	tmpl := template.New("")
+// WithTemplateSecurity sends the headers of options with every page rendered from the
+// templates.
+func WithTemplateSecurity(options TemplateSecurityOptions) func(*TemplateMiddleware) {
+	return func(tm *TemplateMiddleware) {
+		tm.headers = map[string]string{}
+		if options.ContentSecurityPolicy != "" {
+			tm.headers["Content-Security-Policy"] = options.ContentSecurityPolicy
+		}
+		if options.XSSProtection != "" {
+			tm.headers["X-XSS-Protection"] = options.XSSProtection
+		}
+	}
+}
+
+// requestFuncs are placeholders for functions that depend on the request, bound with
+// GetTemplateWithFuncs. They must exist when templates are parsed.
+var requestFuncs = template.FuncMap{
+	// flashes returns the flash messages of the request by kind
+	"flashes": func() map[string][]string { return nil },
+}
+
+// NewTemplateMiddleware parses the .html files of templateDir. The output encoding functions
+// of sanitize.FuncMap are available in the templates.
+func NewTemplateMiddleware(templateDir string, opts ...func(*TemplateMiddleware)) *TemplateMiddleware {
+	tmpl := template.New("").Funcs(sanitize.FuncMap()).Funcs(requestFuncs)
 
 	// Walk through the directory and parse all .html files
 	filepath.Walk(templateDir, func(path string, info os.FileInfo, err error) error {
@@ -23,13 +68,18 @@ func NewTemplateMiddleware(templateDir string) *TemplateMiddleware {
 		return nil
 	})
 
-	return &TemplateMiddleware{Tmpl: tmpl}
+	base, _ := tmpl.Clone()
+	tm := &TemplateMiddleware{Tmpl: tmpl, base: base}
+	for _, opt := range opts {
+		opt(tm)
+	}
+	return tm
 }
 
 func (tm *TemplateMiddleware) Handle(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Pass the template object into the context
-		ctx := context.WithValue(r.Context(), templateKey{}, tm.Tmpl)
+		ctx := context.WithValue(r.Context(), templateKey{}, tm)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -38,8 +88,40 @@ type templateKey struct{}
 
 // GetTemplate returns the template from the context
 func GetTemplate(ctx context.Context) *template.Template {
-	if tmpl, ok := ctx.Value(templateKey{}).(*template.Template); ok {
-		return tmpl
+	if tm, ok := ctx.Value(templateKey{}).(*TemplateMiddleware); ok {
+		return tm.Tmpl
 	}
 	return nil
 }
+
+// GetTemplateWithFuncs returns a copy of the templates from the context with request specific
+// functions, such as flashes, bound to funcs.
+func GetTemplateWithFuncs(ctx context.Context, funcs template.FuncMap) *template.Template {
+	tm, ok := ctx.Value(templateKey{}).(*TemplateMiddleware)
+	if !ok {
+		return nil
+	}
+	tmpl, err := tm.base.Clone()
+	if err != nil {
+		return tm.Tmpl
+	}
+	return tmpl.Funcs(funcs)
+}
+
+// SetTemplateHeaders sets the security headers configured with WithTemplateSecurity. Pages
+// rendered with ctx.Negotiate get them automatically, handlers executing the templates
+// themselves call it before writing.
+//
+// Example usage:
+//
+//	middleware.SetTemplateHeaders(w, r)
+//	middleware.GetTemplate(r.Context()).ExecuteTemplate(w, "index.html", nil)
+func SetTemplateHeaders(w http.ResponseWriter, r *http.Request) {
+	tm, ok := r.Context().Value(templateKey{}).(*TemplateMiddleware)
+	if !ok {
+		return
+	}
+	for name, value := range tm.headers {
+		w.Header().Set(name, value)
+	}
+}
@@ -2,7 +2,9 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -41,3 +43,44 @@ func (tm *TimeoutMiddleware) Handle(next http.Handler) http.Handler {
 		}
 	})
 }
+
+// Deadline sets a deadline on the request context, so database calls and outgoing requests
+// made with it give up when the request takes too long. Unlike TimeoutMiddleware the handler
+// runs on the request's goroutine and writes its own response, which keeps panics
+// recoverable and streaming responses intact. WebSocket upgrades and event streams, which
+// are meant to stay open, get no deadline.
+type Deadline struct {
+	timeout time.Duration
+}
+
+// NewDeadline creates the deadline middleware.
+func NewDeadline(timeout time.Duration) *Deadline {
+	return &Deadline{timeout: timeout}
+}
+
+func (d *Deadline) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if d.timeout <= 0 || r.Header.Get("Upgrade") != "" || strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), d.timeout)
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// requestEnded answers a request whose context ended while a middleware waited on a store such
+// as Redis, and reports whether it did. The error of the store then says nothing about its
+// health: the client left or the deadline of the request passed.
+func requestEnded(w http.ResponseWriter, r *http.Request) bool {
+	switch err := r.Context().Err(); {
+	case err == nil:
+		return false
+	case errors.Is(err, context.DeadlineExceeded):
+		http.Error(w, http.StatusText(http.StatusGatewayTimeout), http.StatusGatewayTimeout)
+	default:
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+	}
+	return true
+}
@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+const timingsKey = "timings"
+
+// Timing is the time a middleware spent on a request, excluding the handlers it called.
+type Timing struct {
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"duration"`
+}
+
+// timingRecorder collects the timings of one request. Frames are keyed by middleware, so
+// middlewares calling next on another goroutine are measured too.
+type timingRecorder struct {
+	mu      sync.Mutex
+	timings []Timing
+	inner   map[*timedMiddleware]time.Duration
+}
+
+// Timed wraps a middleware so the time it spends on each request is recorded under name,
+// see RequestTimings.
+func Timed(name string, m Middleware) Middleware {
+	return &timedMiddleware{name: name, middleware: m}
+}
+
+type timedMiddleware struct {
+	name       string
+	middleware Middleware
+}
+
+func (t *timedMiddleware) Handle(next http.Handler) http.Handler {
+	// Time spent in next is subtracted, so each middleware only accounts for its own work
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		next.ServeHTTP(w, r)
+		if recorder := timingsOf(r); recorder != nil {
+			recorder.mu.Lock()
+			recorder.inner[t] += time.Since(start)
+			recorder.mu.Unlock()
+		}
+	})
+	handler := t.middleware.Handle(inner)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r, values := RequestValues(r)
+		value, _ := values.Get(timingsKey)
+		recorder, _ := value.(*timingRecorder)
+		if recorder == nil {
+			recorder = &timingRecorder{inner: map[*timedMiddleware]time.Duration{}}
+			values.Set(timingsKey, recorder)
+		}
+		start := time.Now()
+		handler.ServeHTTP(w, r)
+		total := time.Since(start)
+
+		recorder.mu.Lock()
+		recorder.timings = append(recorder.timings, Timing{Name: t.name, Duration: total - recorder.inner[t]})
+		delete(recorder.inner, t)
+		recorder.mu.Unlock()
+	})
+}
+
+func timingsOf(r *http.Request) *timingRecorder {
+	recorder, _ := valueOf(r, timingsKey).(*timingRecorder)
+	return recorder
+}
+
+// RequestTimings returns the timings recorded for middlewares wrapped with Timed, innermost
+// first, as they complete.
+func RequestTimings(r *http.Request) []Timing {
+	recorder := timingsOf(r)
+	if recorder == nil {
+		return nil
+	}
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	return append([]Timing(nil), recorder.timings...)
+}
@@ -0,0 +1,191 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/hokamsingh/lessgo/internal/core/config"
+)
+
+// Principal represents the authenticated identity of a request.
+type Principal struct {
+	User   string
+	Email  string
+	Groups []string
+}
+
+// HasGroup reports whether the principal is a member of the given group.
+func (p *Principal) HasGroup(group string) bool {
+	for _, g := range p.Groups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the given principal.
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// GetPrincipal returns the principal stored in the context, if any.
+func GetPrincipal(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok
+}
+
+// TrustedHeaderOptions defines the configuration for the trusted header authentication middleware
+type TrustedHeaderOptions struct {
+	TrustedProxies []string // IPs or CIDRs of the SSO proxies allowed to assert identities
+	UserHeader     string
+	EmailHeader    string
+	GroupsHeader   string
+	GroupSeparator string
+	Required       bool // Reject requests without an identity with 401
+}
+
+// NewTrustedHeaderOptions creates TrustedHeaderOptions with the conventional X-Forwarded-* headers.
+func NewTrustedHeaderOptions(trustedProxies []string) *TrustedHeaderOptions {
+	return &TrustedHeaderOptions{
+		TrustedProxies: trustedProxies,
+		UserHeader:     "X-Forwarded-User",
+		EmailHeader:    "X-Forwarded-Email",
+		GroupsHeader:   "X-Forwarded-Groups",
+		GroupSeparator: ",",
+	}
+}
+
+// TrustedHeaderOptionsFromConfig builds TrustedHeaderOptions from configuration keys:
+//
+//	TRUSTED_PROXIES=10.0.0.0/8,127.0.0.1
+//	TRUSTED_HEADER_USER=X-Forwarded-User
+//	TRUSTED_HEADER_EMAIL=X-Forwarded-Email
+//	TRUSTED_HEADER_GROUPS=X-Forwarded-Groups
+//	TRUSTED_HEADER_GROUP_SEPARATOR=,
+//	TRUSTED_HEADER_REQUIRED=true
+func TrustedHeaderOptionsFromConfig(cfg config.Config) *TrustedHeaderOptions {
+	options := NewTrustedHeaderOptions(splitList(cfg.Get("TRUSTED_PROXIES", "")))
+	options.UserHeader = cfg.Get("TRUSTED_HEADER_USER", options.UserHeader)
+	options.EmailHeader = cfg.Get("TRUSTED_HEADER_EMAIL", options.EmailHeader)
+	options.GroupsHeader = cfg.Get("TRUSTED_HEADER_GROUPS", options.GroupsHeader)
+	options.GroupSeparator = cfg.Get("TRUSTED_HEADER_GROUP_SEPARATOR", options.GroupSeparator)
+	options.Required = cfg.GetBool("TRUSTED_HEADER_REQUIRED", false)
+	return options
+}
+
+// TrustedHeaderAuth authenticates requests from identity headers injected by an upstream SSO proxy.
+// Headers are only honored when the direct peer is a trusted proxy, otherwise they are stripped.
+type TrustedHeaderAuth struct {
+	options  TrustedHeaderOptions
+	networks []*net.IPNet
+}
+
+// NewTrustedHeaderAuth creates a new instance of TrustedHeaderAuth
+func NewTrustedHeaderAuth(options TrustedHeaderOptions) *TrustedHeaderAuth {
+	if options.GroupSeparator == "" {
+		options.GroupSeparator = ","
+	}
+	return &TrustedHeaderAuth{
+		options:  options,
+		networks: parseNetworks(options.TrustedProxies),
+	}
+}
+
+// Handle maps the identity headers to a Principal stored in the request context
+func (th *TrustedHeaderAuth) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !th.isTrustedPeer(r.RemoteAddr) {
+			// Never let downstream handlers see identities asserted by untrusted clients
+			if r.Header.Get(th.options.UserHeader) != "" {
+				log.Printf("Ignoring identity headers from untrusted peer %s", r.RemoteAddr)
+			}
+			r.Header.Del(th.options.UserHeader)
+			r.Header.Del(th.options.EmailHeader)
+			r.Header.Del(th.options.GroupsHeader)
+		} else if user := strings.TrimSpace(r.Header.Get(th.options.UserHeader)); user != "" {
+			principal := &Principal{
+				User:   user,
+				Email:  strings.TrimSpace(r.Header.Get(th.options.EmailHeader)),
+				Groups: splitListWith(r.Header.Get(th.options.GroupsHeader), th.options.GroupSeparator),
+			}
+			r = r.WithContext(WithPrincipal(r.Context(), principal))
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if th.options.Required {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isTrustedPeer checks whether the remote address belongs to a trusted proxy.
+func (th *TrustedHeaderAuth) isTrustedPeer(remoteAddr string) bool {
+	return ipInNetworks(remoteAddr, th.networks)
+}
+
+// parseNetworks converts a list of IPs and CIDRs to networks, skipping invalid entries.
+func parseNetworks(entries []string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil && ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			log.Printf("Invalid network %q: %v", entry, err)
+			continue
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// ipInNetworks reports whether the host of addr is contained in one of the networks.
+func ipInNetworks(addr string, networks []*net.IPNet) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitList splits a comma separated list, trimming blanks.
+func splitList(value string) []string {
+	return splitListWith(value, ",")
+}
+
+func splitListWith(value, sep string) []string {
+	var items []string
+	for _, item := range strings.Split(value, sep) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
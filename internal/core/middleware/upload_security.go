@@ -0,0 +1,269 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrUploadInfected is returned by scanners for files carrying malware.
+var ErrUploadInfected = errors.New("file is infected")
+
+// UploadScanner inspects uploaded files before they are stored, e.g. for malware. Scan
+// returns an error wrapping ErrUploadInfected to reject the file; other errors reject it as
+// well, since it could not be checked.
+type UploadScanner interface {
+	Scan(ctx context.Context, r io.Reader) error
+}
+
+// UploadScannerFunc adapts a function to UploadScanner.
+type UploadScannerFunc func(ctx context.Context, r io.Reader) error
+
+func (f UploadScannerFunc) Scan(ctx context.Context, r io.Reader) error {
+	return f(ctx, r)
+}
+
+// sniffedTypes lists the content types the magic bytes of files with these extensions may be
+// detected as. Files with other extensions are not sniffed.
+var sniffedTypes = map[string][]string{
+	".jpg":  {"image/jpeg"},
+	".jpeg": {"image/jpeg"},
+	".png":  {"image/png"},
+	".gif":  {"image/gif"},
+	".webp": {"image/webp"},
+	".bmp":  {"image/bmp"},
+	".ico":  {"image/x-icon"},
+	".pdf":  {"application/pdf"},
+	".zip":  {"application/zip"},
+	".docx": {"application/zip"},
+	".xlsx": {"application/zip"},
+	".pptx": {"application/zip"},
+	".gz":   {"application/x-gzip"},
+	".mp3":  {"audio/mpeg"},
+	".mp4":  {"video/mp4"},
+	".webm": {"video/webm"},
+	".ogg":  {"application/ogg", "audio/ogg"},
+	".wav":  {"audio/wave"},
+	".txt":  {"text/plain"},
+	".csv":  {"text/plain"},
+	".json": {"text/plain"},
+	".md":   {"text/plain"},
+}
+
+// WithUploadSniffing enables or disables checking that the magic bytes of files match their
+// extension, enabled by default. A .jpg holding HTML is rejected, for instance.
+func WithUploadSniffing(enabled bool) func(*FileUploadMiddleware) {
+	return func(f *FileUploadMiddleware) {
+		f.sniff = enabled
+	}
+}
+
+// WithImageLimits rejects JPEG, PNG and GIF images larger than maxWidth x maxHeight or with
+// more than maxPixels pixels, checked from the header before decoding. Zero disables a limit.
+func WithImageLimits(maxWidth, maxHeight int, maxPixels int64) func(*FileUploadMiddleware) {
+	return func(f *FileUploadMiddleware) {
+		f.maxWidth, f.maxHeight, f.maxPixels = maxWidth, maxHeight, maxPixels
+	}
+}
+
+// WithImageReencode decodes and re-encodes JPEG, PNG and GIF images before storing them,
+// dropping EXIF metadata such as GPS positions, and anything appended to the image data.
+func WithImageReencode() func(*FileUploadMiddleware) {
+	return func(f *FileUploadMiddleware) {
+		f.reencode = true
+	}
+}
+
+// WithUploadScanner scans files with s before storing them, see NewClamAVScanner.
+func WithUploadScanner(s UploadScanner) func(*FileUploadMiddleware) {
+	return func(f *FileUploadMiddleware) {
+		f.scanner = s
+	}
+}
+
+// uploadRejection is returned by inspect with the response of a rejected file.
+type uploadRejection struct {
+	status  int
+	message string
+	err     error
+}
+
+func (e *uploadRejection) Error() string {
+	if e.err != nil {
+		return e.message + ": " + e.err.Error()
+	}
+	return e.message
+}
+
+// inspect runs the checks of the upload pipeline on file and returns the content to store,
+// with its detected content type.
+func (f *FileUploadMiddleware) inspect(ctx context.Context, file io.ReadSeeker, ext string) (io.Reader, string, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, "", &uploadRejection{http.StatusBadRequest, "Unable to read file", err}
+	}
+	contentType := http.DetectContentType(head[:n])
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	if expected, ok := sniffedTypes[ext]; ok && f.sniff && !contains(expected, mediaType) {
+		return nil, "", &uploadRejection{http.StatusUnsupportedMediaType, "File content does not match its type", nil}
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, "", &uploadRejection{http.StatusInternalServerError, "Unable to read file", err}
+	}
+
+	var body io.Reader = file
+	if isDecodableImage(mediaType) {
+		config, _, err := image.DecodeConfig(file)
+		if err != nil {
+			return nil, "", &uploadRejection{http.StatusUnprocessableEntity, "Invalid image", err}
+		}
+		if (f.maxWidth > 0 && config.Width > f.maxWidth) || (f.maxHeight > 0 && config.Height > f.maxHeight) ||
+			(f.maxPixels > 0 && int64(config.Width)*int64(config.Height) > f.maxPixels) {
+			return nil, "", &uploadRejection{http.StatusUnprocessableEntity, "Image dimensions exceed limit", nil}
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return nil, "", &uploadRejection{http.StatusInternalServerError, "Unable to read file", err}
+		}
+		if f.reencode {
+			data, err := reencodeImage(file, mediaType)
+			if err != nil {
+				return nil, "", &uploadRejection{http.StatusUnprocessableEntity, "Invalid image", err}
+			}
+			body = bytes.NewReader(data)
+		}
+	}
+
+	if f.scanner != nil {
+		if err := f.scanner.Scan(ctx, body); err != nil {
+			if errors.Is(err, ErrUploadInfected) {
+				return nil, "", &uploadRejection{http.StatusUnprocessableEntity, "File rejected by virus scan", err}
+			}
+			return nil, "", &uploadRejection{http.StatusServiceUnavailable, "Unable to scan file", err}
+		}
+		if _, err := body.(io.Seeker).Seek(0, io.SeekStart); err != nil {
+			return nil, "", &uploadRejection{http.StatusInternalServerError, "Unable to read file", err}
+		}
+	}
+	return body, contentType, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func isDecodableImage(mediaType string) bool {
+	return mediaType == "image/jpeg" || mediaType == "image/png" || mediaType == "image/gif"
+}
+
+// reencodeImage decodes the image and encodes it again, keeping only the pixels and, for
+// GIFs, the animation.
+func reencodeImage(r io.Reader, mediaType string) ([]byte, error) {
+	var buffer bytes.Buffer
+	switch mediaType {
+	case "image/gif":
+		g, err := gif.DecodeAll(r)
+		if err != nil {
+			return nil, err
+		}
+		err = gif.EncodeAll(&buffer, g)
+		return buffer.Bytes(), err
+	case "image/png":
+		img, err := png.Decode(r)
+		if err != nil {
+			return nil, err
+		}
+		err = png.Encode(&buffer, img)
+		return buffer.Bytes(), err
+	default:
+		img, err := jpeg.Decode(r)
+		if err != nil {
+			return nil, err
+		}
+		err = jpeg.Encode(&buffer, img, &jpeg.Options{Quality: 90})
+		return buffer.Bytes(), err
+	}
+}
+
+// ClamAVScanner scans files with a clamd daemon over its INSTREAM command.
+type ClamAVScanner struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+// NewClamAVScanner creates a scanner for the clamd daemon listening on address, e.g.
+// NewClamAVScanner("tcp", "localhost:3310") or NewClamAVScanner("unix", "/run/clamav/clamd.ctl").
+func NewClamAVScanner(network, address string) *ClamAVScanner {
+	return &ClamAVScanner{network: network, address: address, timeout: time.Minute}
+}
+
+func (s *ClamAVScanner) Scan(ctx context.Context, r io.Reader) error {
+	dialer := net.Dialer{Timeout: 5 * time.Second}
+	conn, err := dialer.DialContext(ctx, s.network, s.address)
+	if err != nil {
+		return fmt.Errorf("clamav: %w", err)
+	}
+	defer conn.Close()
+	deadline := time.Now().Add(s.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	writer := bufio.NewWriter(conn)
+	writer.WriteString("zINSTREAM\x00")
+	chunk := make([]byte, 32<<10)
+	size := make([]byte, 4)
+	for {
+		n, err := r.Read(chunk)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size, uint32(n))
+			writer.Write(size)
+			writer.Write(chunk[:n])
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	writer.Write([]byte{0, 0, 0, 0})
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("clamav: %w", err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && reply == "" {
+		return fmt.Errorf("clamav: %w", err)
+	}
+	// stream: OK, stream: <signature> FOUND or <message> ERROR
+	reply = strings.TrimSuffix(strings.TrimSpace(strings.TrimRight(reply, "\x00")), "\n")
+	result := strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case result == "OK":
+		return nil
+	case strings.HasSuffix(result, " FOUND"):
+		return fmt.Errorf("%w: %s", ErrUploadInfected, strings.TrimSuffix(result, " FOUND"))
+	default:
+		return fmt.Errorf("clamav: %s", reply)
+	}
+}
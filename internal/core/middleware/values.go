@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// Keys of the values commonly shared between middlewares and handlers.
+const (
+	ClaimsKey  = "claims"
+	SessionKey = "session"
+	UserKey    = "user"
+	TenantKey  = "tenant"
+
+	// Set by the router for the matched route, e.g. /users/{id}, and its path variables
+	RouteKey     = "route"
+	RouteVarsKey = "route.vars"
+)
+
+// Claims holds the claims of an authenticated token, e.g. a decoded JWT payload.
+type Claims map[string]interface{}
+
+// String returns the claim as a string, or "" when it is missing or not a string.
+func (c Claims) String(name string) string {
+	s, _ := c[name].(string)
+	return s
+}
+
+// Values is the key value store of a request, shared by its middlewares and handler.
+// It is safe for concurrent use.
+type Values struct {
+	mu     sync.RWMutex
+	values map[string]interface{}
+}
+
+type valuesKey struct{}
+
+// RequestValues returns the value store of the request. When the request has none yet, a
+// store is attached and the returned request carries it; pass that request on.
+//
+// Example usage:
+//
+//	r, values := middleware.RequestValues(r)
+//	values.Set(middleware.ClaimsKey, claims)
+//	next.ServeHTTP(w, r)
+func RequestValues(r *http.Request) (*http.Request, *Values) {
+	if values, ok := r.Context().Value(valuesKey{}).(*Values); ok {
+		return r, values
+	}
+	values := &Values{values: make(map[string]interface{})}
+	return r.WithContext(context.WithValue(r.Context(), valuesKey{}, values)), values
+}
+
+// SetValue stores a value for the request and returns the request carrying the store.
+func SetValue(r *http.Request, key string, value interface{}) *http.Request {
+	r, values := RequestValues(r)
+	values.Set(key, value)
+	return r
+}
+
+// GetValue returns a value stored for the request.
+func GetValue(r *http.Request, key string) (interface{}, bool) {
+	values, ok := r.Context().Value(valuesKey{}).(*Values)
+	if !ok {
+		return nil, false
+	}
+	return values.Get(key)
+}
+
+// Set stores value under key, replacing any previous value.
+func (v *Values) Set(key string, value interface{}) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.values[key] = value
+}
+
+// Get returns the value stored under key.
+func (v *Values) Get(key string) (interface{}, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	value, ok := v.values[key]
+	return value, ok
+}
+
+// Delete removes the value stored under key.
+func (v *Values) Delete(key string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	delete(v.values, key)
+}
+
+// Keys returns the keys of all stored values.
+func (v *Values) Keys() []string {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	keys := make([]string, 0, len(v.values))
+	for key := range v.values {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// RequestUser identifies the user of the request from the principal, the value stored
+// under UserKey if it is a string or fmt.Stringer, or the sub claim, in that order.
+func RequestUser(r *http.Request) string {
+	if principal, ok := GetPrincipal(r.Context()); ok && principal.User != "" {
+		return principal.User
+	}
+	if user, ok := GetValue(r, UserKey); ok {
+		switch user := user.(type) {
+		case string:
+			return user
+		case fmt.Stringer:
+			return user.String()
+		}
+	}
+	switch claims := valueOf(r, ClaimsKey).(type) {
+	case Claims:
+		return claims.String("sub")
+	case map[string]interface{}:
+		return Claims(claims).String("sub")
+	}
+	return ""
+}
+
+// RequestRoute returns the template of the route that handled the request, e.g.
+// /users/{id}, and its path variables. They are only known once the router matched the
+// request, for middlewares after calling the next handler.
+func RequestRoute(r *http.Request) (string, map[string]string) {
+	template, _ := valueOf(r, RouteKey).(string)
+	variables, _ := valueOf(r, RouteVarsKey).(map[string]string)
+	return template, variables
+}
+
+// RequestTenant returns the ID of the tenant resolved for the request, or "". Tenants read
+// from values stored by other middlewares, such as token claims, are resolved on first use.
+func RequestTenant(r *http.Request) string {
+	switch tenant := valueOf(r, TenantKey).(type) {
+	case string:
+		return tenant
+	case func() string:
+		return tenant()
+	}
+	return ""
+}
+
+// tenantScoped prefixes key with the tenant of the request, so cached responses are kept
+// apart per tenant.
+func tenantScoped(r *http.Request, key string) string {
+	if tenant := RequestTenant(r); tenant != "" {
+		return "tenant:" + tenant + ":" + key
+	}
+	return key
+}
+
+func valueOf(r *http.Request, key string) interface{} {
+	value, _ := GetValue(r, key)
+	return value
+}
@@ -0,0 +1,446 @@
+/*
+Package mock serves routes from declared stub responses, so frontend teams can run a LessGo
+application as a mock API before the real handlers exist.
+
+Stubs are declared in Go or loaded from a YAML or JSON file. A stub answers a method and route
+template with a status, headers and a JSON body, a text body or a file. Strings in bodies are
+Go templates rendered with the request, e.g. {{.Params.id}}, {{.Query.page}}, {{.Body.name}} or
+{{index .Header "X-Tenant"}}.
+Several stubs of a route are tried in order, the first whose When conditions match answers.
+
+Usage:
+
+	server, err := mock.LoadServer("mocks/api.yaml")
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Registered after the real routes, stubs only answer the routes not implemented yet
+	server.RegisterRoutes(App, "/api")
+
+A stubs file lists the stubs:
+
+	# mocks/api.yaml
+	- method: GET
+	  path: /users/{id}
+	  body: {id: "{{.Params.id}}", name: Ada Lovelace}
+	- method: GET
+	  path: /users
+	  when: {query: {status: banned}}
+	  body: []
+	- method: POST
+	  path: /users
+	  status: 201
+	  delay: 300ms
+	  body: {id: "42", name: "{{.Body.name}}", created_at: "{{now}}"}
+*/
+package mock
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/context"
+	"github.com/hokamsingh/lessgo/internal/core/router"
+	"gopkg.in/yaml.v3"
+)
+
+// Stub is a declared response of a route.
+type Stub struct {
+	Method string `yaml:"method"` // GET when empty
+	Path   string `yaml:"path"`   // Route template, e.g. /users/{id}
+	When   When   `yaml:"when"`
+	Status int    `yaml:"status"` // 200 when empty
+	// Headers are added to the response. Values are templates.
+	Headers map[string]string `yaml:"headers"`
+	// Body is sent as JSON, with its strings rendered as templates.
+	Body interface{} `yaml:"body"`
+	// Text is a template of a text body, sent as text/plain unless Headers set Content-Type.
+	Text string `yaml:"text"`
+	// File is sent as the body, relative to the stubs file when loaded from one.
+	File  string        `yaml:"file"`
+	Delay time.Duration `yaml:"delay"` // Latency simulated before answering
+}
+
+// When restricts a stub to the requests with the given query parameters and headers.
+type When struct {
+	Query  map[string]string `yaml:"query"`
+	Header map[string]string `yaml:"header"`
+}
+
+// Request is the data of the templates of a stub.
+type Request struct {
+	Method string
+	Path   string
+	Params map[string]string      // Route variables
+	Query  map[string]string      // First value of each query parameter
+	Header map[string]string      // First value of each header, by canonical name
+	Body   map[string]interface{} // JSON object body, nil otherwise
+}
+
+// templateFuncs are the functions of the templates of stubs.
+var templateFuncs = template.FuncMap{
+	"now": func() string { return time.Now().UTC().Format(time.RFC3339) },
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"default": func(fallback, value interface{}) interface{} {
+		if value == nil || value == "" {
+			return fallback
+		}
+		return value
+	},
+}
+
+// Server answers routes with stubs.
+type Server struct {
+	stubs []*compiledStub
+}
+
+// compiledStub is a stub with its parsed templates.
+type compiledStub struct {
+	Stub
+	headers map[string]*template.Template
+	body    interface{} // Body with templates in place of strings holding actions
+	text    *template.Template
+}
+
+// NewServer creates a server of the stubs, failing on stubs without a path, with an
+// unsupported method, with more than one body or with invalid templates.
+//
+// Example usage:
+//
+//	server, err := mock.NewServer(mock.Stub{
+//		Path: "/orders/{id}",
+//		Body: map[string]interface{}{"id": "{{.Params.id}}", "status": "shipped"},
+//	})
+func NewServer(stubs ...Stub) (*Server, error) {
+	s := &Server{}
+	for i, stub := range stubs {
+		compiled, err := compile(stub)
+		if err != nil {
+			return nil, fmt.Errorf("mock: stub %d (%s %s): %w", i+1, stub.Method, stub.Path, err)
+		}
+		s.stubs = append(s.stubs, compiled)
+	}
+	return s, nil
+}
+
+// LoadServer creates a server of the stubs listed in a YAML or JSON file.
+func LoadServer(path string) (*Server, error) {
+	stubs, err := LoadStubs(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewServer(stubs...)
+}
+
+// LoadStubs reads the stubs listed in a YAML or JSON file. Their files are made relative to
+// the directory of the stubs file.
+func LoadStubs(path string) ([]Stub, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var stubs []Stub
+	if err := yaml.Unmarshal(data, &stubs); err != nil {
+		return nil, fmt.Errorf("mock: parsing %s: %w", path, err)
+	}
+	for i := range stubs {
+		if stubs[i].File != "" && !filepath.IsAbs(stubs[i].File) {
+			stubs[i].File = filepath.Join(filepath.Dir(path), stubs[i].File)
+		}
+	}
+	return stubs, nil
+}
+
+func compile(stub Stub) (*compiledStub, error) {
+	stub.Method = strings.ToUpper(stub.Method)
+	if stub.Method == "" {
+		stub.Method = http.MethodGet
+	}
+	switch {
+	case !strings.HasPrefix(stub.Path, "/"):
+		return nil, fmt.Errorf("path must begin with '/'")
+	case !supported(stub.Method):
+		return nil, fmt.Errorf("unsupported method")
+	}
+	bodies := 0
+	for _, set := range []bool{stub.Body != nil, stub.Text != "", stub.File != ""} {
+		if set {
+			bodies++
+		}
+	}
+	if bodies > 1 {
+		return nil, fmt.Errorf("only one of body, text and file can be set")
+	}
+	if stub.Status == 0 {
+		stub.Status = http.StatusOK
+	}
+
+	compiled := &compiledStub{Stub: stub, headers: map[string]*template.Template{}}
+	var err error
+	for name, value := range stub.Headers {
+		if compiled.headers[name], err = parse(value); err != nil {
+			return nil, err
+		}
+	}
+	if stub.Text != "" {
+		if compiled.text, err = parse(stub.Text); err != nil {
+			return nil, err
+		}
+	}
+	if compiled.body, err = compileValue(stub.Body); err != nil {
+		return nil, err
+	}
+	return compiled, nil
+}
+
+func parse(text string) (*template.Template, error) {
+	return template.New("").Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+}
+
+// compileValue replaces the strings holding template actions in a body by their templates.
+func compileValue(value interface{}) (interface{}, error) {
+	switch value := value.(type) {
+	case string:
+		if !strings.Contains(value, "{{") {
+			return value, nil
+		}
+		return parse(value)
+	case []interface{}:
+		compiled := make([]interface{}, len(value))
+		for i, item := range value {
+			var err error
+			if compiled[i], err = compileValue(item); err != nil {
+				return nil, err
+			}
+		}
+		return compiled, nil
+	case map[string]interface{}:
+		compiled := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			var err error
+			if compiled[key], err = compileValue(item); err != nil {
+				return nil, err
+			}
+		}
+		return compiled, nil
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			converted[fmt.Sprint(key)] = item
+		}
+		return compileValue(converted)
+	}
+	return value, nil
+}
+
+// renderValue renders the templates of a compiled body.
+func renderValue(value interface{}, req *Request) (interface{}, error) {
+	switch value := value.(type) {
+	case *template.Template:
+		return render(value, req)
+	case []interface{}:
+		rendered := make([]interface{}, len(value))
+		for i, item := range value {
+			var err error
+			if rendered[i], err = renderValue(item, req); err != nil {
+				return nil, err
+			}
+		}
+		return rendered, nil
+	case map[string]interface{}:
+		rendered := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			var err error
+			if rendered[key], err = renderValue(item, req); err != nil {
+				return nil, err
+			}
+		}
+		return rendered, nil
+	}
+	return value, nil
+}
+
+func render(t *template.Template, req *Request) (string, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, req); err != nil {
+		return "", err
+	}
+	// Missing fields of the JSON body render empty, like the other missing keys
+	return strings.ReplaceAll(buf.String(), "<no value>", ""), nil
+}
+
+func supported(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// Stubs returns the stubs of the server.
+func (s *Server) Stubs() []Stub {
+	stubs := make([]Stub, len(s.stubs))
+	for i, stub := range s.stubs {
+		stubs[i] = stub.Stub
+	}
+	return stubs
+}
+
+// RegisterRoutes registers a route for each method and path of the stubs, below the prefix.
+// Routes registered on r before take precedence, so stubs fill in the routes not implemented
+// yet. Requests matching none of the stubs of a route get a 404.
+//
+// Example usage:
+//
+//	server.RegisterRoutes(App, "/api/v1")
+func (s *Server) RegisterRoutes(r *router.Router, prefix string) {
+	if prefix = strings.TrimRight(prefix, "/"); prefix != "" {
+		r = r.SubRouter(prefix)
+	}
+	var order []string
+	routes := map[string][]*compiledStub{}
+	for _, stub := range s.stubs {
+		key := stub.Method + " " + stub.Path
+		if routes[key] == nil {
+			order = append(order, key)
+		}
+		routes[key] = append(routes[key], stub)
+	}
+	for _, key := range order {
+		stubs := routes[key]
+		handler := func(ctx *context.Context) { serve(ctx, stubs) }
+		switch path := stubs[0].Path; stubs[0].Method {
+		case http.MethodGet:
+			r.Get(path, handler)
+		case http.MethodPost:
+			r.Post(path, handler)
+		case http.MethodPut:
+			r.Put(path, handler)
+		case http.MethodPatch:
+			r.Patch(path, handler)
+		case http.MethodDelete:
+			r.Delete(path, handler)
+		}
+	}
+}
+
+// serve answers with the first stub matching the request.
+func serve(ctx *context.Context, stubs []*compiledStub) {
+	req := newRequest(ctx)
+	for _, stub := range stubs {
+		if stub.matches(req) {
+			if err := stub.respond(ctx, req); err != nil {
+				ctx.Error(http.StatusInternalServerError, "mock: "+err.Error())
+			}
+			return
+		}
+	}
+	ctx.Error(http.StatusNotFound, "No stub matches the request")
+}
+
+func newRequest(ctx *context.Context) *Request {
+	req := &Request{
+		Method: ctx.Req.Method,
+		Path:   ctx.Req.URL.Path,
+		Params: map[string]string{},
+		Query:  map[string]string{},
+		Header: map[string]string{},
+	}
+	if params, ok := ctx.GetAllParams(); ok {
+		req.Params = params
+	}
+	for name, values := range ctx.Req.URL.Query() {
+		req.Query[name] = values[0]
+	}
+	for name, values := range ctx.Req.Header {
+		req.Header[name] = values[0]
+	}
+	if body, err := ctx.RawBody(); err == nil && len(body) > 0 {
+		json.Unmarshal(body, &req.Body)
+	}
+	return req
+}
+
+// matches reports whether the request meets the When conditions of the stub.
+func (stub *compiledStub) matches(req *Request) bool {
+	for name, value := range stub.When.Query {
+		if req.Query[name] != value {
+			return false
+		}
+	}
+	for name, value := range stub.When.Header {
+		if req.Header[http.CanonicalHeaderKey(name)] != value {
+			return false
+		}
+	}
+	return true
+}
+
+func (stub *compiledStub) respond(ctx *context.Context, req *Request) error {
+	var body []byte
+	contentType := ""
+	switch {
+	case stub.File != "":
+		data, err := os.ReadFile(stub.File)
+		if err != nil {
+			return err
+		}
+		body, contentType = data, mimeType(stub.File, data)
+	case stub.text != nil:
+		text, err := render(stub.text, req)
+		if err != nil {
+			return err
+		}
+		body, contentType = []byte(text), "text/plain; charset=utf-8"
+	case stub.body != nil:
+		value, err := renderValue(stub.body, req)
+		if err != nil {
+			return err
+		}
+		if body, err = json.Marshal(value); err != nil {
+			return err
+		}
+		contentType = "application/json"
+	}
+
+	header := ctx.Res.Header()
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	for name, t := range stub.headers {
+		value, err := render(t, req)
+		if err != nil {
+			return err
+		}
+		header.Set(name, value)
+	}
+	if stub.Delay > 0 {
+		select {
+		case <-time.After(stub.Delay):
+		case <-ctx.Req.Context().Done():
+			return nil
+		}
+	}
+	ctx.Res.WriteHeader(stub.Status)
+	ctx.Res.Write(body)
+	return nil
+}
+
+// mimeType returns the media type of a file from its extension, or sniffed from its content.
+func mimeType(path string, data []byte) string {
+	if contentType := mime.TypeByExtension(filepath.Ext(path)); contentType != "" {
+		return contentType
+	}
+	return http.DetectContentType(data)
+}
@@ -0,0 +1,328 @@
+/*
+Package notify pushes real-time notifications to the users of an application.
+
+Server code calls Notify with the ID of a user and a payload. Clients connected to the
+Notifier's endpoint receive it at once, over a WebSocket or, for clients that cannot open
+one, over Server-Sent Events; the transport is picked from the request. Every notification
+stays in a Store until the client acknowledges it, so users who are offline receive their
+notifications when they connect and none are lost with a dropped connection. Delivery is at
+least once: clients skip notifications whose ID they have seen.
+
+With a messaging.Broker, notifications are fanned out to all instances, so a user connected
+to any of them receives them; a RedisStore shares the pending notifications between them.
+
+Usage:
+
+	notifier := notify.New("/notifications", notify.WithStore(notify.NewRedisStore(client)))
+	App.ServeNotifications(notifier)
+	App.Post("/orders", func(ctx *LessGo.Context) {
+		// ...
+		notifier.Notify(ctx.Req.Context(), order.UserID, map[string]string{"order": order.ID})
+	})
+
+In the browser:
+
+	const source = new EventSource("/notifications")
+	source.addEventListener("notification", e => {
+		show(JSON.parse(e.data))
+		fetch("/notifications/ack", {method: "POST", body: JSON.stringify({ids: [e.lastEventId]})})
+	})
+*/
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hokamsingh/lessgo/internal/core/messaging"
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+)
+
+// Notification is a message for a user.
+type Notification struct {
+	ID      string          `json:"id"`
+	UserID  string          `json:"user_id"`
+	Payload json.RawMessage `json:"payload"`
+	Time    time.Time       `json:"time"`
+}
+
+// Notifier delivers notifications to the connected clients of users and keeps them until
+// they are acknowledged.
+type Notifier struct {
+	basePath    string
+	store       Store
+	broker      messaging.Broker
+	subject     string
+	userFunc    func(r *http.Request) string
+	checkOrigin func(r *http.Request) bool
+	onAck       func(userID, id string)
+	heartbeat   time.Duration
+
+	mu    sync.RWMutex
+	conns map[string]map[*connection]struct{}
+	sub   messaging.Subscription
+}
+
+// New creates a notifier serving its clients under basePath. Clients connect with a GET
+// request to basePath and acknowledge with a POST to basePath+"/ack" or, on a WebSocket,
+// with an ack message. Without WithStore notifications are kept in memory.
+func New(basePath string, options ...func(*Notifier)) *Notifier {
+	n := &Notifier{
+		basePath:  strings.TrimSuffix(basePath, "/"),
+		subject:   "lessgo.notifications",
+		userFunc:  middleware.RequestUser,
+		heartbeat: 30 * time.Second,
+		conns:     map[string]map[*connection]struct{}{},
+	}
+	for _, option := range options {
+		option(n)
+	}
+	if n.store == nil {
+		n.store = NewMemoryStore(0, 0)
+	}
+	return n
+}
+
+// WithStore sets the store keeping pending notifications, e.g. a RedisStore shared by all
+// instances.
+func WithStore(store Store) func(*Notifier) {
+	return func(n *Notifier) {
+		n.store = store
+	}
+}
+
+// WithBroker fans notifications out to all instances over the broker. Call Start to
+// subscribe and Close to unsubscribe.
+func WithBroker(broker messaging.Broker, subject string) func(*Notifier) {
+	return func(n *Notifier) {
+		n.broker = broker
+		if subject != "" {
+			n.subject = subject
+		}
+	}
+}
+
+// WithUserFunc sets how the user of a connecting client is identified, by default the
+// authenticated user of the request, see middleware.RequestUser.
+func WithUserFunc(userFunc func(r *http.Request) string) func(*Notifier) {
+	return func(n *Notifier) {
+		n.userFunc = userFunc
+	}
+}
+
+// WithCheckOrigin sets which origins may open WebSockets, by default the host of the
+// request only.
+func WithCheckOrigin(checkOrigin func(r *http.Request) bool) func(*Notifier) {
+	return func(n *Notifier) {
+		n.checkOrigin = checkOrigin
+	}
+}
+
+// WithOnAck is called when a user acknowledges a notification, e.g. to mark it as read.
+func WithOnAck(onAck func(userID, id string)) func(*Notifier) {
+	return func(n *Notifier) {
+		n.onAck = onAck
+	}
+}
+
+// WithHeartbeat sets how often idle connections are pinged, 30 seconds by default.
+func WithHeartbeat(interval time.Duration) func(*Notifier) {
+	return func(n *Notifier) {
+		if interval > 0 {
+			n.heartbeat = interval
+		}
+	}
+}
+
+// BasePath returns the path the notifier is served under.
+func (n *Notifier) BasePath() string {
+	return n.basePath
+}
+
+// Start subscribes to the notifications published by all instances when a broker is set.
+func (n *Notifier) Start() error {
+	if n.broker == nil {
+		return nil
+	}
+	// Every instance receives every notification, so no group is used
+	sub, err := n.broker.Subscribe(n.subject, "", func(ctx context.Context, msg *messaging.Message) error {
+		var notification Notification
+		if err := json.Unmarshal(msg.Data, &notification); err != nil {
+			return err
+		}
+		n.deliver(notification)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	n.mu.Lock()
+	n.sub = sub
+	n.mu.Unlock()
+	return nil
+}
+
+// Close unsubscribes from the broker and disconnects all clients. Pending notifications
+// stay in the store.
+func (n *Notifier) Close() error {
+	n.mu.Lock()
+	sub := n.sub
+	n.sub = nil
+	for _, conns := range n.conns {
+		for conn := range conns {
+			conn.close()
+		}
+	}
+	clear(n.conns)
+	n.mu.Unlock()
+	if sub != nil {
+		return sub.Unsubscribe()
+	}
+	return nil
+}
+
+// Notify sends payload, encoded as JSON, to the user and returns the ID of the
+// notification. It is stored until acknowledged, so it reaches users who are offline when
+// they connect.
+//
+// Example usage:
+//
+//	id, err := notifier.Notify(ctx, "42", map[string]string{"title": "Your order shipped"})
+func (n *Notifier) Notify(ctx context.Context, userID string, payload interface{}) (string, error) {
+	if userID == "" {
+		return "", errors.New("notify: empty user ID")
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	notification := Notification{ID: uuid.NewString(), UserID: userID, Payload: data, Time: time.Now().UTC()}
+	if err := n.store.Push(ctx, notification); err != nil {
+		return "", err
+	}
+	if n.broker == nil {
+		n.deliver(notification)
+		return notification.ID, nil
+	}
+	message, err := json.Marshal(notification)
+	if err != nil {
+		return "", err
+	}
+	if err := n.broker.Publish(ctx, &messaging.Message{Subject: n.subject, Data: message, Key: userID}); err != nil {
+		// The notification is stored, the user receives it on the next connect
+		log.Printf("Failed to publish notification %s: %v", notification.ID, err)
+		n.deliver(notification)
+	}
+	return notification.ID, nil
+}
+
+// Ack acknowledges a notification of the user, removing it from the store.
+func (n *Notifier) Ack(ctx context.Context, userID, id string) error {
+	removed, err := n.store.Ack(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if removed && n.onAck != nil {
+		n.onAck(userID, id)
+	}
+	return nil
+}
+
+// Pending returns the unacknowledged notifications of the user.
+func (n *Notifier) Pending(ctx context.Context, userID string) ([]Notification, error) {
+	return n.store.Pending(ctx, userID)
+}
+
+// Online reports whether the user has a client connected to this instance.
+func (n *Notifier) Online(userID string) bool {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return len(n.conns[userID]) > 0
+}
+
+// deliver passes the notification to the local connections of its user.
+func (n *Notifier) deliver(notification Notification) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	for conn := range n.conns[notification.UserID] {
+		conn.push(notification)
+	}
+}
+
+func (n *Notifier) register(userID string, conn *connection) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.conns[userID] == nil {
+		n.conns[userID] = map[*connection]struct{}{}
+	}
+	n.conns[userID][conn] = struct{}{}
+}
+
+func (n *Notifier) unregister(userID string, conn *connection) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.conns[userID], conn)
+	if len(n.conns[userID]) == 0 {
+		delete(n.conns, userID)
+	}
+}
+
+// ServeHTTP connects clients with a GET to the base path, over a WebSocket when the request
+// asks for an upgrade and over Server-Sent Events otherwise, and takes acknowledgments with
+// a POST of {"ids": [...]} to the base path + "/ack".
+func (n *Notifier) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	userID := n.userFunc(r)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == n.basePath+"/ack":
+		n.serveAck(w, r, userID)
+	case r.Method == http.MethodGet && r.URL.Path == n.basePath:
+		if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			n.serveWebSocket(w, r, userID)
+		} else {
+			n.serveSSE(w, r, userID)
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// ackRequest is the body of acknowledgments, on the ack endpoint and on WebSockets.
+type ackRequest struct {
+	Type string   `json:"type,omitempty"`
+	ID   string   `json:"id,omitempty"`
+	IDs  []string `json:"ids,omitempty"`
+}
+
+func (a ackRequest) ids() []string {
+	if a.ID != "" {
+		return append(a.IDs, a.ID)
+	}
+	return a.IDs
+}
+
+func (n *Notifier) serveAck(w http.ResponseWriter, r *http.Request, userID string) {
+	var ack ackRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&ack); err != nil || len(ack.ids()) == 0 {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	for _, id := range ack.ids() {
+		if err := n.Ack(r.Context(), userID, id); err != nil {
+			log.Printf("Failed to acknowledge notification %s: %v", id, err)
+			http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,184 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Store keeps the notifications of a user until the user acknowledges them, so users who
+// are offline, or lose their connection, receive them when they connect.
+type Store interface {
+	// Push adds a pending notification.
+	Push(ctx context.Context, n Notification) error
+	// Pending returns the unacknowledged notifications of the user, oldest first.
+	Pending(ctx context.Context, userID string) ([]Notification, error)
+	// Ack removes a notification of the user and reports whether it was pending.
+	Ack(ctx context.Context, userID, id string) (bool, error)
+}
+
+// MemoryStore keeps pending notifications in process, for single instance deployments.
+type MemoryStore struct {
+	maxPerUser int
+	ttl        time.Duration
+
+	mu      sync.Mutex
+	pending map[string][]Notification
+}
+
+// NewMemoryStore creates a store keeping up to maxPerUser notifications per user for ttl,
+// dropping the oldest first. Zero values keep 100 notifications for a week.
+func NewMemoryStore(maxPerUser int, ttl time.Duration) *MemoryStore {
+	if maxPerUser <= 0 {
+		maxPerUser = 100
+	}
+	if ttl <= 0 {
+		ttl = 7 * 24 * time.Hour
+	}
+	return &MemoryStore{maxPerUser: maxPerUser, ttl: ttl, pending: map[string][]Notification{}}
+}
+
+func (s *MemoryStore) Push(ctx context.Context, n Notification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pending := append(s.live(n.UserID), n)
+	if len(pending) > s.maxPerUser {
+		pending = pending[len(pending)-s.maxPerUser:]
+	}
+	s.pending[n.UserID] = pending
+	return nil
+}
+
+func (s *MemoryStore) Pending(ctx context.Context, userID string) ([]Notification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pending := s.live(userID)
+	if len(pending) == 0 {
+		delete(s.pending, userID)
+		return nil, nil
+	}
+	s.pending[userID] = pending
+	return append([]Notification(nil), pending...), nil
+}
+
+func (s *MemoryStore) Ack(ctx context.Context, userID, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pending := s.pending[userID]
+	for i, n := range pending {
+		if n.ID == id {
+			pending = append(pending[:i:i], pending[i+1:]...)
+			if len(pending) == 0 {
+				delete(s.pending, userID)
+			} else {
+				s.pending[userID] = pending
+			}
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// live returns the notifications of the user that have not expired. The caller holds mu.
+func (s *MemoryStore) live(userID string) []Notification {
+	pending := s.pending[userID]
+	cutoff := time.Now().Add(-s.ttl)
+	i := 0
+	for i < len(pending) && pending[i].Time.Before(cutoff) {
+		i++
+	}
+	return pending[i:]
+}
+
+// RedisStore keeps pending notifications in Redis, shared by all instances. The
+// notifications of a user are a hash under prefix+userID expiring ttl after the last push.
+type RedisStore struct {
+	client     redis.UniversalClient
+	prefix     string
+	maxPerUser int
+	ttl        time.Duration
+}
+
+// NewRedisStore creates a Redis backed store keeping up to 100 notifications per user for a
+// week.
+func NewRedisStore(client redis.UniversalClient, options ...func(*RedisStore)) *RedisStore {
+	s := &RedisStore{client: client, prefix: "notify:", maxPerUser: 100, ttl: 7 * 24 * time.Hour}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// WithRedisPrefix sets the prefix of the keys, "notify:" by default.
+func WithRedisPrefix(prefix string) func(*RedisStore) {
+	return func(s *RedisStore) {
+		s.prefix = prefix
+	}
+}
+
+// WithRedisLimits sets how many notifications are kept per user and for how long.
+func WithRedisLimits(maxPerUser int, ttl time.Duration) func(*RedisStore) {
+	return func(s *RedisStore) {
+		if maxPerUser > 0 {
+			s.maxPerUser = maxPerUser
+		}
+		if ttl > 0 {
+			s.ttl = ttl
+		}
+	}
+}
+
+func (s *RedisStore) Push(ctx context.Context, n Notification) error {
+	data, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	key := s.prefix + n.UserID
+	pipe := s.client.TxPipeline()
+	pipe.HSet(ctx, key, n.ID, data)
+	pipe.Expire(ctx, key, s.ttl)
+	length := pipe.HLen(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+	if length.Val() <= int64(s.maxPerUser) {
+		return nil
+	}
+	pending, err := s.Pending(ctx, n.UserID)
+	if err != nil {
+		return err
+	}
+	if excess := len(pending) - s.maxPerUser; excess > 0 {
+		ids := make([]string, excess)
+		for i := range ids {
+			ids[i] = pending[i].ID
+		}
+		return s.client.HDel(ctx, key, ids...).Err()
+	}
+	return nil
+}
+
+func (s *RedisStore) Pending(ctx context.Context, userID string) ([]Notification, error) {
+	fields, err := s.client.HGetAll(ctx, s.prefix+userID).Result()
+	if err != nil {
+		return nil, err
+	}
+	pending := make([]Notification, 0, len(fields))
+	for _, data := range fields {
+		var n Notification
+		if json.Unmarshal([]byte(data), &n) == nil {
+			pending = append(pending, n)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].Time.Before(pending[j].Time) })
+	return pending, nil
+}
+
+func (s *RedisStore) Ack(ctx context.Context, userID, id string) (bool, error) {
+	removed, err := s.client.HDel(ctx, s.prefix+userID, id).Result()
+	return removed > 0, err
+}
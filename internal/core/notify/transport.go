@@ -0,0 +1,186 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// sendBuffer is the number of notifications queued per connection. When a client reads
+	// too slowly further notifications are skipped; they stay pending and are sent again on
+	// the next connect.
+	sendBuffer = 64
+
+	writeWait = 10 * time.Second
+)
+
+// connection is a connected client of a user.
+type connection struct {
+	send   chan Notification
+	done   chan struct{}
+	closer sync.Once
+}
+
+func newConnection() *connection {
+	return &connection{send: make(chan Notification, sendBuffer), done: make(chan struct{})}
+}
+
+// push queues the notification without blocking.
+func (c *connection) push(n Notification) {
+	select {
+	case c.send <- n:
+	case <-c.done:
+	default:
+	}
+}
+
+func (c *connection) close() {
+	c.closer.Do(func() { close(c.done) })
+}
+
+// connect registers the connection and queues the pending notifications of the user.
+// Notifications sent in between may be delivered twice, clients skip them by ID.
+func (n *Notifier) connect(r *http.Request, userID string) (*connection, error) {
+	conn := newConnection()
+	n.register(userID, conn)
+	pending, err := n.store.Pending(r.Context(), userID)
+	if err != nil {
+		n.unregister(userID, conn)
+		return nil, err
+	}
+	for _, notification := range pending {
+		conn.push(notification)
+	}
+	return conn, nil
+}
+
+// message is a notification as sent to clients.
+type message struct {
+	Type    string          `json:"type"`
+	ID      string          `json:"id"`
+	Payload json.RawMessage `json:"payload"`
+	Time    time.Time       `json:"time"`
+}
+
+func newMessage(n Notification) message {
+	return message{Type: "notification", ID: n.ID, Payload: n.Payload, Time: n.Time}
+}
+
+func (n *Notifier) serveSSE(w http.ResponseWriter, r *http.Request, userID string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	conn, err := n.connect(r, userID)
+	if err != nil {
+		log.Printf("Failed to load pending notifications of %s: %v", userID, err)
+		http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	defer n.unregister(userID, conn)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: 3000\n\n")
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(n.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case notification := <-conn.send:
+			data, err := json.Marshal(newMessage(notification))
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "id: %s\nevent: notification\ndata: %s\n\n", notification.ID, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			// Comments keep proxies from closing idle streams
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-conn.done:
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (n *Notifier) serveWebSocket(w http.ResponseWriter, r *http.Request, userID string) {
+	upgrader := websocket.Upgrader{CheckOrigin: n.checkOrigin}
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader already answered the request
+		return
+	}
+	defer ws.Close()
+	conn, err := n.connect(r, userID)
+	if err != nil {
+		log.Printf("Failed to load pending notifications of %s: %v", userID, err)
+		ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, ""), time.Now().Add(writeWait))
+		return
+	}
+	defer n.unregister(userID, conn)
+
+	// The read loop takes acknowledgments and notices when the client goes away
+	pongWait := 2 * n.heartbeat
+	ws.SetReadLimit(64 << 10)
+	ws.SetReadDeadline(time.Now().Add(pongWait))
+	ws.SetPongHandler(func(string) error {
+		return ws.SetReadDeadline(time.Now().Add(pongWait))
+	})
+	// Acknowledgments may still be stored while the handler returns
+	ctx := context.WithoutCancel(r.Context())
+	go func() {
+		defer conn.close()
+		for {
+			_, data, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			var ack ackRequest
+			if json.Unmarshal(data, &ack) != nil || ack.Type != "ack" {
+				continue
+			}
+			for _, id := range ack.ids() {
+				if err := n.Ack(ctx, userID, id); err != nil {
+					log.Printf("Failed to acknowledge notification %s: %v", id, err)
+				}
+			}
+		}
+	}()
+
+	heartbeat := time.NewTicker(n.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case notification := <-conn.send:
+			ws.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := ws.WriteJSON(newMessage(notification)); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if err := ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				return
+			}
+		case <-conn.done:
+			ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(writeWait))
+			return
+		}
+	}
+}
@@ -0,0 +1,246 @@
+/*
+Package pagination parses page, offset and cursor pagination parameters and builds
+consistent pagination metadata and links.
+
+Offset pagination uses `page` and `limit`; cursor pagination uses an opaque `cursor`
+together with `limit`. Limits are capped so clients cannot request unbounded pages.
+
+Usage:
+
+	p := pagination.FromRequest(r)
+	users, total := repo.List(p.Offset(), p.Limit)
+	ctx.Paginated(users, total, p)
+*/
+package pagination
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultLimit is the page size used when the request has no limit.
+	DefaultLimit = 20
+	// MaxLimit is the largest page size a request may ask for.
+	MaxLimit = 100
+)
+
+// Options configures how pagination parameters are parsed.
+type Options struct {
+	DefaultLimit int
+	MaxLimit     int
+	PageParam    string
+	LimitParam   string
+	CursorParam  string
+}
+
+// NewOptions returns Options with the package defaults.
+func NewOptions() *Options {
+	return &Options{
+		DefaultLimit: DefaultLimit,
+		MaxLimit:     MaxLimit,
+		PageParam:    "page",
+		LimitParam:   "limit",
+		CursorParam:  "cursor",
+	}
+}
+
+// WithLimits sets the default and maximum page size.
+func WithLimits(defaultLimit, maxLimit int) func(*Options) {
+	return func(o *Options) {
+		if defaultLimit > 0 {
+			o.DefaultLimit = defaultLimit
+		}
+		if maxLimit > 0 {
+			o.MaxLimit = maxLimit
+		}
+	}
+}
+
+// WithParamNames overrides the query parameter names.
+func WithParamNames(page, limit, cursor string) func(*Options) {
+	return func(o *Options) {
+		o.PageParam = page
+		o.LimitParam = limit
+		o.CursorParam = cursor
+	}
+}
+
+// Params holds the pagination parameters of a request.
+type Params struct {
+	Page   int
+	Limit  int
+	Cursor string
+
+	// NextCursor is set by the handler in cursor mode to the cursor of the next page.
+	NextCursor string
+
+	url     *url.URL
+	options Options
+}
+
+// Meta describes the current page in a paginated response.
+type Meta struct {
+	Page       int    `json:"page,omitempty"`
+	Limit      int    `json:"limit"`
+	Total      int    `json:"total,omitempty"`
+	TotalPages int    `json:"total_pages,omitempty"`
+	Cursor     string `json:"cursor,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// Links holds navigation links for a paginated response. Empty links are omitted.
+type Links struct {
+	Self  string `json:"self"`
+	First string `json:"first,omitempty"`
+	Prev  string `json:"prev,omitempty"`
+	Next  string `json:"next,omitempty"`
+	Last  string `json:"last,omitempty"`
+}
+
+// Envelope is the body rendered for paginated responses.
+type Envelope struct {
+	Data  interface{} `json:"data"`
+	Meta  Meta        `json:"meta"`
+	Links Links       `json:"links"`
+}
+
+// FromRequest parses pagination parameters from the request query. Invalid or missing
+// values fall back to the first page and the default limit; limits above the maximum are capped,
+// and pages so large that their offset would overflow are clamped.
+func FromRequest(r *http.Request, options ...func(*Options)) *Params {
+	opts := NewOptions()
+	for _, option := range options {
+		option(opts)
+	}
+
+	query := r.URL.Query()
+	p := &Params{Page: 1, Limit: opts.DefaultLimit, url: r.URL, options: *opts}
+
+	if page, err := strconv.Atoi(query.Get(opts.PageParam)); err == nil && page > 0 {
+		p.Page = page
+	}
+	if limit, err := strconv.Atoi(query.Get(opts.LimitParam)); err == nil && limit > 0 {
+		p.Limit = limit
+	}
+	if p.Limit > opts.MaxLimit {
+		p.Limit = opts.MaxLimit
+	}
+	// Huge pages are clamped so neither the offset nor the link to the next page overflows
+	if maxPage := (math.MaxInt - 1) / p.Limit; p.Page > maxPage {
+		p.Page = maxPage
+	}
+	p.Cursor = query.Get(opts.CursorParam)
+	return p
+}
+
+// Offset returns the number of items to skip for the current page.
+func (p *Params) Offset() int {
+	return (p.Page - 1) * p.Limit
+}
+
+// IsCursor reports whether the request uses cursor pagination.
+func (p *Params) IsCursor() bool {
+	return p.Cursor != "" || p.NextCursor != ""
+}
+
+// Build returns the envelope for a page of items. In offset mode total is the total number
+// of items; in cursor mode a negative total means the total is unknown.
+func (p *Params) Build(items interface{}, total int) Envelope {
+	meta := Meta{Limit: p.Limit}
+	links := Links{Self: p.link(nil)}
+
+	if p.IsCursor() {
+		meta.Cursor = p.Cursor
+		meta.NextCursor = p.NextCursor
+		if total >= 0 {
+			meta.Total = total
+		}
+		links.First = p.link(map[string]string{p.options.CursorParam: ""})
+		if p.NextCursor != "" {
+			links.Next = p.link(map[string]string{p.options.CursorParam: p.NextCursor})
+		}
+		return Envelope{Data: items, Meta: meta, Links: links}
+	}
+
+	totalPages := 0
+	if total > 0 {
+		totalPages = int(math.Ceil(float64(total) / float64(p.Limit)))
+	}
+	meta.Page = p.Page
+	meta.Total = total
+	meta.TotalPages = totalPages
+
+	links.First = p.pageLink(1)
+	if totalPages > 0 {
+		links.Last = p.pageLink(totalPages)
+	}
+	if p.Page > 1 {
+		prev := p.Page - 1
+		if totalPages > 0 && prev > totalPages {
+			prev = totalPages
+		}
+		links.Prev = p.pageLink(prev)
+	}
+	if p.Page < totalPages {
+		links.Next = p.pageLink(p.Page + 1)
+	}
+	return Envelope{Data: items, Meta: meta, Links: links}
+}
+
+// LinkHeader formats the links as an RFC 8288 Link header value.
+func (l Links) LinkHeader() string {
+	var parts []string
+	for _, link := range []struct{ rel, href string }{
+		{"first", l.First}, {"prev", l.Prev}, {"next", l.Next}, {"last", l.Last},
+	} {
+		if link.href != "" {
+			parts = append(parts, fmt.Sprintf(`<%s>; rel="%s"`, link.href, link.rel))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (p *Params) pageLink(page int) string {
+	return p.link(map[string]string{
+		p.options.PageParam:  strconv.Itoa(page),
+		p.options.LimitParam: strconv.Itoa(p.Limit),
+	})
+}
+
+// link returns the request URL with the given query parameters replaced.
+// An empty value removes the parameter.
+func (p *Params) link(params map[string]string) string {
+	if p.url == nil {
+		return ""
+	}
+	query := p.url.Query()
+	for key, value := range params {
+		if value == "" {
+			query.Del(key)
+			continue
+		}
+		query.Set(key, value)
+	}
+	u := url.URL{Path: p.url.Path, RawQuery: query.Encode()}
+	return u.String()
+}
+
+// EncodeCursor encodes a position, such as the last seen ID, as an opaque cursor.
+func EncodeCursor(position string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(position))
+}
+
+// DecodeCursor decodes a cursor created with EncodeCursor.
+func DecodeCursor(cursor string) (string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", fmt.Errorf("invalid cursor")
+	}
+	return string(data), nil
+}
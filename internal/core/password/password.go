@@ -0,0 +1,188 @@
+/*
+Package password hashes passwords with Argon2id, using golang.org/x/crypto/argon2.
+
+Hashes are encoded in the PHC string format, which records the parameters next to the salt
+and the key, so the cost can be raised over time: NeedsRehash reports hashes made with older
+parameters, to be replaced after the next successful login.
+
+	$argon2id$v=19$m=65536,t=3,p=4$<salt>$<key>
+
+Usage:
+
+	hash, err := password.Hash(form.Password)
+
+	ok, err := password.Verify(form.Password, user.PasswordHash)
+	if ok && password.NeedsRehash(user.PasswordHash) {
+		user.PasswordHash, _ = password.Hash(form.Password)
+	}
+*/
+package password
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// ErrInvalidHash is returned when a hash is not an Argon2id PHC string, or its parameters
+// exceed the limits below.
+var ErrInvalidHash = errors.New("password: invalid hash format")
+
+// Limits on the parameters read from stored hashes, so a tampered hash cannot make Verify
+// allocate or compute without bound.
+const (
+	maxMemory     = 1 << 20 // 1 GiB in KiB
+	maxIterations = 64
+	maxSaltLength = 256
+	maxKeyLength  = 256
+	minKeyLength  = 4
+)
+
+const (
+	argon2Version  = argon2.Version
+	argon2Variant  = "argon2id"
+	argon2Encoding = "$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s"
+)
+
+// Params tune the cost of Argon2id.
+type Params struct {
+	Memory      uint32 // Memory in KiB
+	Iterations  uint32 // Passes over the memory
+	Parallelism uint8  // Lanes computed in parallel
+	SaltLength  uint32 // Random salt length in bytes
+	KeyLength   uint32 // Derived key length in bytes
+}
+
+// DefaultParams are the second recommended option of RFC 9106: 64 MiB, 3 passes and 4 lanes.
+var DefaultParams = Params{Memory: 64 * 1024, Iterations: 3, Parallelism: 4, SaltLength: 16, KeyLength: 32}
+
+// Hasher hashes and verifies passwords with fixed parameters.
+type Hasher struct {
+	params Params
+	pepper []byte
+}
+
+// NewHasher creates a hasher with params.
+func NewHasher(params Params, options ...func(*Hasher)) *Hasher {
+	h := &Hasher{params: params}
+	for _, option := range options {
+		option(h)
+	}
+	return h
+}
+
+// WithPepper mixes a secret, kept out of the database, into every hash: the password is
+// replaced by its HMAC-SHA256 keyed with the secret before hashing. Hashes made with a pepper
+// only verify with the same pepper.
+func WithPepper(secret []byte) func(*Hasher) {
+	return func(h *Hasher) {
+		h.pepper = secret
+	}
+}
+
+// Hash returns the PHC encoded Argon2id hash of password with a random salt.
+func (h *Hasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := h.derive(password, salt, h.params)
+	return fmt.Sprintf(argon2Encoding, argon2Version,
+		h.params.Memory, h.params.Iterations, h.params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify reports whether password matches hash, comparing in constant time.
+func (h *Hasher) Verify(password, hash string) (bool, error) {
+	params, salt, key, err := decode(hash)
+	if err != nil {
+		return false, err
+	}
+	computed := h.derive(password, salt, params)
+	return subtle.ConstantTimeCompare(key, computed) == 1, nil
+}
+
+// derive computes the Argon2id key of password, peppered when the hasher has a pepper.
+func (h *Hasher) derive(password string, salt []byte, params Params) []byte {
+	input := []byte(password)
+	if len(h.pepper) > 0 {
+		mac := hmac.New(sha256.New, h.pepper)
+		mac.Write(input)
+		input = mac.Sum(nil)
+	}
+	return argon2.IDKey(input, salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+}
+
+// NeedsRehash reports whether hash was made with other parameters than the hasher's, or is
+// not an Argon2id hash at all.
+func (h *Hasher) NeedsRehash(hash string) bool {
+	params, _, _, err := decode(hash)
+	return err != nil || params != h.params
+}
+
+func decode(hash string) (Params, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=65536,t=3,p=4", salt, key
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != argon2Variant || parts[2] != fmt.Sprintf("v=%d", argon2Version) {
+		return Params{}, nil, nil, ErrInvalidHash
+	}
+	var params Params
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return Params{}, nil, nil, ErrInvalidHash
+	}
+	if params.Memory > maxMemory || params.Iterations < 1 || params.Iterations > maxIterations || params.Parallelism < 1 ||
+		len(parts[4]) > base64.RawStdEncoding.EncodedLen(maxSaltLength) || len(parts[5]) > base64.RawStdEncoding.EncodedLen(maxKeyLength) {
+		return Params{}, nil, nil, ErrInvalidHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return Params{}, nil, nil, ErrInvalidHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) < minKeyLength {
+		return Params{}, nil, nil, ErrInvalidHash
+	}
+	params.SaltLength, params.KeyLength = uint32(len(salt)), uint32(len(key))
+	return params, salt, key, nil
+}
+
+var defaultHasher atomic.Pointer[Hasher]
+
+func init() {
+	defaultHasher.Store(NewHasher(DefaultParams))
+}
+
+// SetDefault replaces the hasher used by Hash, Verify and NeedsRehash, e.g. to tune the cost
+// to the servers or add a pepper.
+//
+// Example usage:
+//
+//	password.SetDefault(password.NewHasher(password.Params{
+//		Memory: 19 * 1024, Iterations: 2, Parallelism: 1, SaltLength: 16, KeyLength: 32,
+//	}, password.WithPepper([]byte(cfg.Get("PASSWORD_PEPPER", "")))))
+func SetDefault(h *Hasher) {
+	defaultHasher.Store(h)
+}
+
+// Hash returns the Argon2id hash of password with the default hasher.
+func Hash(password string) (string, error) {
+	return defaultHasher.Load().Hash(password)
+}
+
+// Verify reports whether password matches hash with the default hasher.
+func Verify(password, hash string) (bool, error) {
+	return defaultHasher.Load().Verify(password, hash)
+}
+
+// NeedsRehash reports whether hash should be replaced to match the default hasher.
+func NeedsRehash(hash string) bool {
+	return defaultHasher.Load().NeedsRehash(hash)
+}
@@ -0,0 +1,262 @@
+/*
+Package pipe provides common pipes that run around route handlers.
+
+Input pipes transform (Trim, Defaults, Sanitize) and validate (Validate) the JSON request body before the
+handler runs. Output pipes post-process response data (FilterFields, SnakeCase) before it is encoded.
+
+Usage:
+
+	r.UsePipe(pipe.Trim(), pipe.SnakeCase())
+	r.Post("/users", context.WithPipes(createUser, pipe.Validate(CreateUserDTO{})))
+
+	func createUser(ctx *context.Context) {
+		dto := pipe.Validated(ctx).(*CreateUserDTO)
+		...
+	}
+*/
+package pipe
+
+import (
+	stdcontext "context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/hokamsingh/lessgo/internal/core/context"
+	"github.com/hokamsingh/lessgo/internal/core/i18n"
+	"github.com/hokamsingh/lessgo/internal/core/sanitize"
+)
+
+type validatedKey struct{}
+
+// Trim removes leading and trailing whitespace from every string in the JSON request body.
+func Trim() context.Pipe {
+	return context.InputPipe(func(c *context.Context) error {
+		return c.TransformJSONBody(func(body interface{}) (interface{}, error) {
+			return trimStrings(body), nil
+		})
+	})
+}
+
+func trimStrings(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = trimStrings(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = trimStrings(item)
+		}
+	}
+	return value
+}
+
+// Sanitize cleans the declared fields of the JSON request body with the named policies of the
+// sanitize package. Fields are dot separated paths into nested objects, arrays on the way
+// apply to every element. Use it per route, where the fields that end up in HTML are known.
+//
+// Example usage:
+//
+//	r.WithPipes(pipe.Sanitize(map[string]string{
+//		"title":       sanitize.Strict,
+//		"body":        sanitize.UGC,
+//		"tags":        sanitize.Strict,
+//		"author.name": sanitize.Strict,
+//	})).Post("/posts", createPost)
+func Sanitize(fields map[string]string) context.Pipe {
+	for _, name := range fields {
+		if _, ok := sanitize.Lookup(name); !ok {
+			panic(fmt.Sprintf("pipe: unknown sanitize policy %q", name))
+		}
+	}
+	return context.InputPipe(func(c *context.Context) error {
+		return c.TransformJSONBody(func(body interface{}) (interface{}, error) {
+			return sanitize.JSON(body, fields), nil
+		})
+	})
+}
+
+// Defaults sets top-level fields of a JSON object body that are missing or null.
+//
+// Example usage:
+//
+//	pipe.Defaults(map[string]interface{}{"role": "member", "active": true})
+func Defaults(defaults map[string]interface{}) context.Pipe {
+	return context.InputPipe(func(c *context.Context) error {
+		return c.TransformJSONBody(func(body interface{}) (interface{}, error) {
+			object, ok := body.(map[string]interface{})
+			if !ok {
+				return body, nil
+			}
+			for key, value := range defaults {
+				if existing, exists := object[key]; !exists || existing == nil {
+					object[key] = value
+				}
+			}
+			return object, nil
+		})
+	})
+}
+
+// Validate decodes the JSON request body into a new value of the DTO's type and checks its
+// `validate` struct tags. Protobuf bodies are decoded when the DTO is a protobuf message. Invalid input is rejected with a VALIDATION_FAILED response listing
+// the field errors. The decoded DTO is available through Validated.
+//
+// Supported rules: required, min=N, max=N (string length, number value or slice length),
+// email and oneof=a b c. A DTO can add its own checks by implementing `Validate() error`.
+// Messages are translated when the i18n bundle defines validation.<rule> keys.
+//
+// Example usage:
+//
+//	type CreateUserDTO struct {
+//		Name  string `json:"name" validate:"required,max=50"`
+//		Email string `json:"email" validate:"required,email"`
+//	}
+//	r.Post("/users", context.WithPipes(createUser, pipe.Validate(CreateUserDTO{})))
+func Validate(dto interface{}) context.Pipe {
+	dtoType := reflect.TypeOf(dto)
+	for dtoType.Kind() == reflect.Ptr {
+		dtoType = dtoType.Elem()
+	}
+	return context.InputPipe(func(c *context.Context) error {
+		value := reflect.New(dtoType).Interface()
+		if err := c.Body(value); err != nil {
+			return fmt.Errorf("invalid request body")
+		}
+		if failures := validateFields(value); len(failures) > 0 {
+			fields := make(map[string]string, len(failures))
+			for name, failure := range failures {
+				fields[name] = translateFailure(c, name, failure)
+			}
+			return &context.ValidationError{Fields: fields}
+		}
+		if validator, ok := value.(interface{ Validate() error }); ok {
+			if err := validator.Validate(); err != nil {
+				return err
+			}
+		}
+		c.Req = c.Req.WithContext(stdcontext.WithValue(c.Req.Context(), validatedKey{}, value))
+		return nil
+	})
+}
+
+// translateFailure returns the message of a failed rule in the request's locale when the
+// i18n bundle has a validation.<rule> key, e.g. validation.max = "darf höchstens {param} lang sein".
+// The {field} and {param} placeholders hold the field name and the rule argument.
+func translateFailure(c *context.Context, field string, failure ruleFailure) string {
+	bundle, locale, ok := i18n.FromContext(c.Req.Context())
+	key := "validation." + failure.rule
+	if !ok || !bundle.Has(locale, key) {
+		return failure.message
+	}
+	return bundle.Translate(locale, key, i18n.Args{"field": field, "param": failure.arg})
+}
+
+// Validated returns the DTO decoded by the Validate pipe, as a pointer to the DTO type.
+func Validated(c *context.Context) interface{} {
+	return c.Req.Context().Value(validatedKey{})
+}
+
+// FilterFields keeps only the fields listed in the given query parameter, e.g. ?fields=id,name.
+// Objects and arrays of objects are filtered at the top level; without the parameter the
+// response is unchanged.
+func FilterFields(param string) context.Pipe {
+	return context.OutputPipe(func(c *context.Context, data interface{}) (interface{}, error) {
+		list, _ := c.GetQuery(param)
+		if list == "" {
+			return data, nil
+		}
+		keep := make(map[string]bool)
+		for _, field := range strings.Split(list, ",") {
+			keep[strings.TrimSpace(field)] = true
+		}
+
+		generic, err := toGeneric(data)
+		if err != nil {
+			return nil, err
+		}
+		filter := func(value interface{}) interface{} {
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				return value
+			}
+			for key := range object {
+				if !keep[key] {
+					delete(object, key)
+				}
+			}
+			return object
+		}
+		if items, ok := generic.([]interface{}); ok {
+			for i, item := range items {
+				items[i] = filter(item)
+			}
+			return items, nil
+		}
+		return filter(generic), nil
+	})
+}
+
+// SnakeCase converts all object keys of the response to snake_case, e.g. userID becomes user_id.
+func SnakeCase() context.Pipe {
+	return context.OutputPipe(func(c *context.Context, data interface{}) (interface{}, error) {
+		generic, err := toGeneric(data)
+		if err != nil {
+			return nil, err
+		}
+		return convertKeys(generic, ToSnakeCase), nil
+	})
+}
+
+// toGeneric converts a value into maps, slices and scalars through its JSON representation.
+func toGeneric(data interface{}) (interface{}, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	if err := json.Unmarshal(encoded, &generic); err != nil {
+		return nil, err
+	}
+	return generic, nil
+}
+
+func convertKeys(value interface{}, convert func(string) string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			converted[convert(key)] = convertKeys(item, convert)
+		}
+		return converted
+	case []interface{}:
+		for i, item := range v {
+			v[i] = convertKeys(item, convert)
+		}
+	}
+	return value
+}
+
+// ToSnakeCase converts camelCase and PascalCase names to snake_case, keeping acronyms together.
+func ToSnakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && runes[i-1] != '_' &&
+				(unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) ||
+					(i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
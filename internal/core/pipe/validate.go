@@ -0,0 +1,164 @@
+package pipe
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ValidateStruct checks the `validate` tags of a struct (or pointer to struct) and returns
+// the failing fields keyed by their JSON name. Nested structs are validated as well, with
+// their fields reported as `parent.child`.
+func ValidateStruct(v interface{}) map[string]string {
+	messages := make(map[string]string)
+	for name, failure := range validateFields(v) {
+		messages[name] = failure.message
+	}
+	return messages
+}
+
+// ruleFailure describes a failed rule, e.g. rule "max" with arg "50".
+type ruleFailure struct {
+	rule    string
+	arg     string
+	message string
+}
+
+func validateFields(v interface{}) map[string]ruleFailure {
+	errors := make(map[string]ruleFailure)
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return errors
+		}
+		value = value.Elem()
+	}
+	if value.Kind() == reflect.Struct {
+		validateStruct(value, "", errors)
+	}
+	return errors
+}
+
+func validateStruct(value reflect.Value, prefix string, errors map[string]ruleFailure) {
+	t := value.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := jsonName(field)
+		if name == "-" {
+			continue
+		}
+		fieldValue := value.Field(i)
+
+		if rules := field.Tag.Get("validate"); rules != "" {
+			if failure, failed := checkRules(fieldValue, rules); failed {
+				errors[prefix+name] = failure
+				continue
+			}
+		}
+
+		inner := fieldValue
+		if inner.Kind() == reflect.Ptr && !inner.IsNil() {
+			inner = inner.Elem()
+		}
+		if inner.Kind() == reflect.Struct && inner.Type().PkgPath() != "time" {
+			if field.Anonymous {
+				validateStruct(inner, prefix, errors)
+			} else {
+				validateStruct(inner, prefix+name+".", errors)
+			}
+		}
+	}
+}
+
+// checkRules returns the first failing rule.
+func checkRules(value reflect.Value, rules string) (ruleFailure, bool) {
+	isZero := value.IsZero()
+	for value.Kind() == reflect.Ptr && !value.IsNil() {
+		value = value.Elem()
+	}
+
+	for _, rule := range strings.Split(rules, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch name {
+		case "required":
+			if isZero {
+				return ruleFailure{name, arg, "is required"}, true
+			}
+		case "min", "max":
+			if isZero {
+				continue
+			}
+			limit, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				continue
+			}
+			size, unit, ok := measure(value)
+			if !ok {
+				continue
+			}
+			if name == "min" && size < limit {
+				return ruleFailure{name, arg, fmt.Sprintf("must be at least %s%s", arg, unit)}, true
+			}
+			if name == "max" && size > limit {
+				return ruleFailure{name, arg, fmt.Sprintf("must be at most %s%s", arg, unit)}, true
+			}
+		case "email":
+			if isZero || value.Kind() != reflect.String {
+				continue
+			}
+			address, err := mail.ParseAddress(value.String())
+			if err != nil || address.Address != value.String() {
+				return ruleFailure{name, arg, "must be a valid email address"}, true
+			}
+		case "oneof":
+			if isZero {
+				continue
+			}
+			actual := fmt.Sprint(value.Interface())
+			allowed := strings.Fields(arg)
+			found := false
+			for _, option := range allowed {
+				if actual == option {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return ruleFailure{name, arg, "must be one of " + strings.Join(allowed, ", ")}, true
+			}
+		}
+	}
+	return ruleFailure{}, false
+}
+
+// measure returns the length of strings and collections or the value of numbers.
+func measure(value reflect.Value) (size float64, unit string, ok bool) {
+	switch value.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(value.String())), " characters", true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(value.Len()), " items", true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), "", true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), "", true
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), "", true
+	}
+	return 0, "", false
+}
+
+// jsonName returns the name a field has in JSON.
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
@@ -0,0 +1,103 @@
+package pipeline
+
+import (
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+)
+
+// registerBuiltins registers the middlewares that need no code to construct.
+func registerBuiltins(r *Registry) {
+	r.Register("cors", func(decode Decoder) (middleware.Middleware, error) {
+		var options struct {
+			AllowedOrigins []string `yaml:"allowed_origins"`
+			AllowedMethods []string `yaml:"allowed_methods"`
+			AllowedHeaders []string `yaml:"allowed_headers"`
+		}
+		options.AllowedOrigins = []string{"*"}
+		options.AllowedMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
+		options.AllowedHeaders = []string{"Content-Type", "Authorization"}
+		if err := decode(&options); err != nil {
+			return nil, err
+		}
+		return middleware.NewCORSMiddleware(*middleware.NewCorsOptions(options.AllowedOrigins, options.AllowedMethods, options.AllowedHeaders)), nil
+	})
+	r.Register("csrf", func(decode Decoder) (middleware.Middleware, error) {
+		return middleware.NewCSRFProtection(), nil
+	})
+	r.Register("xss", func(decode Decoder) (middleware.Middleware, error) {
+		return middleware.NewXSSProtection(), nil
+	})
+	r.Register("cookie_parser", func(decode Decoder) (middleware.Middleware, error) {
+		return middleware.NewCookieParser(), nil
+	})
+	r.Register("json_parser", func(decode Decoder) (middleware.Middleware, error) {
+		options := struct {
+			MaxSize int64 `yaml:"max_size"`
+		}{MaxSize: 5 << 20}
+		if err := decode(&options); err != nil {
+			return nil, err
+		}
+		return middleware.NewJsonParser(*middleware.NewParserOptions(options.MaxSize)), nil
+	})
+	r.Register("body_capture", func(decode Decoder) (middleware.Middleware, error) {
+		options := struct {
+			MaxSize int64 `yaml:"max_size"`
+		}{MaxSize: middleware.DefaultBodyCaptureSize}
+		if err := decode(&options); err != nil {
+			return nil, err
+		}
+		return middleware.NewBodyCapture(options.MaxSize), nil
+	})
+	r.Register("method_override", func(decode Decoder) (middleware.Middleware, error) {
+		var options struct {
+			Methods []string `yaml:"methods"`
+		}
+		if err := decode(&options); err != nil {
+			return nil, err
+		}
+		return middleware.NewMethodOverride(options.Methods...), nil
+	})
+	r.Register("rate_limit", func(decode Decoder) (middleware.Middleware, error) {
+		options := struct {
+			Shards          int           `yaml:"shards"`
+			Limit           int           `yaml:"limit"`
+			Interval        time.Duration `yaml:"interval"`
+			CleanupInterval time.Duration `yaml:"cleanup_interval"`
+		}{Shards: 10, Limit: 100, Interval: time.Second, CleanupInterval: time.Minute}
+		if err := decode(&options); err != nil {
+			return nil, err
+		}
+		config := middleware.NewInMemoryConfig(options.Shards, options.Limit, options.Interval, options.CleanupInterval)
+		return middleware.NewRateLimiter(middleware.InMemory, config), nil
+	})
+	r.Register("concurrency_limit", func(decode Decoder) (middleware.Middleware, error) {
+		options := struct {
+			Limit   int           `yaml:"limit"`
+			Queue   int           `yaml:"queue"`
+			Timeout time.Duration `yaml:"timeout"`
+		}{Limit: 100, Timeout: time.Second}
+		if err := decode(&options); err != nil {
+			return nil, err
+		}
+		return middleware.NewConcurrencyLimit(options.Limit, options.Queue, options.Timeout), nil
+	})
+	r.Register("timeout", func(decode Decoder) (middleware.Middleware, error) {
+		options := struct {
+			Timeout time.Duration `yaml:"timeout"`
+		}{Timeout: 30 * time.Second}
+		if err := decode(&options); err != nil {
+			return nil, err
+		}
+		return middleware.NewTimeoutMiddleware(options.Timeout), nil
+	})
+	r.Register("ip_allowlist", func(decode Decoder) (middleware.Middleware, error) {
+		var options struct {
+			Allow []string `yaml:"allow"`
+		}
+		if err := decode(&options); err != nil {
+			return nil, err
+		}
+		return middleware.NewIPAllowlist(options.Allow...), nil
+	})
+}
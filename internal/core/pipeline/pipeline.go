@@ -0,0 +1,153 @@
+/*
+Package pipeline assembles the middleware pipeline from configuration.
+
+Middlewares are registered by name with a factory that decodes their options. A YAML file
+then lists the middlewares in the order they run, with their options, so the order and
+settings can differ per environment without recompiling. The built-in middlewares are
+registered in every new Registry.
+
+Usage:
+
+	# middleware.production.yaml
+	middleware:
+	  - name: cors
+	    options:
+	      allowed_origins: ["https://example.com"]
+	  - name: rate_limit
+	    options: {limit: 100, interval: 1s}
+	    skip: ["/health"]
+	  - name: csrf
+	    skip: ["/webhooks/**"]
+	  - name: xss
+	    enabled: false
+
+	registry := pipeline.NewRegistry()
+	registry.Register("auth", func(decode pipeline.Decoder) (middleware.Middleware, error) {
+		return NewAuth(), nil
+	})
+	mws, err := registry.LoadFile("middleware." + env + ".yaml")
+	if err != nil {
+		log.Fatal(err)
+	}
+	App := LessGo.App(LessGo.WithMiddleware(mws...))
+*/
+package pipeline
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+	"gopkg.in/yaml.v3"
+)
+
+// Decoder decodes the options of a pipeline entry into v, a pointer to a struct with yaml tags.
+type Decoder func(v interface{}) error
+
+// Factory creates a middleware from its options.
+type Factory func(decode Decoder) (middleware.Middleware, error)
+
+// Entry is one middleware of a pipeline file.
+type Entry struct {
+	Name    string    `yaml:"name"`
+	Enabled *bool     `yaml:"enabled"`
+	Options yaml.Node `yaml:"options"`
+	// Skip and Only restrict the middleware to paths, as matched by middleware.MatchPaths.
+	Skip []string `yaml:"skip"`
+	Only []string `yaml:"only"`
+}
+
+// File is the layout of a pipeline file.
+type File struct {
+	Middleware []Entry `yaml:"middleware"`
+}
+
+// Registry maps middleware names to factories.
+type Registry struct {
+	mu        sync.RWMutex
+	factories map[string]Factory
+}
+
+// NewRegistry creates a registry with the built-in middlewares registered.
+func NewRegistry() *Registry {
+	r := &Registry{factories: map[string]Factory{}}
+	registerBuiltins(r)
+	return r
+}
+
+// Register adds a middleware under name, replacing a previous registration.
+func (r *Registry) Register(name string, factory Factory) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.factories[name] = factory
+}
+
+// Names returns the registered middleware names, sorted.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadFile builds the pipeline described by a YAML file, see Load.
+func (r *Registry) LoadFile(path string) ([]middleware.Middleware, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return r.Load(data)
+}
+
+// Load builds the pipeline described by YAML data. The middlewares are returned in the order
+// they run, the first one first, and disabled entries are left out.
+func (r *Registry) Load(data []byte) ([]middleware.Middleware, error) {
+	var file File
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("pipeline: %w", err)
+	}
+	return r.Build(file.Middleware)
+}
+
+// Build creates the middlewares of the entries in order.
+func (r *Registry) Build(entries []Entry) ([]middleware.Middleware, error) {
+	mws := make([]middleware.Middleware, 0, len(entries))
+	for i, entry := range entries {
+		if entry.Enabled != nil && !*entry.Enabled {
+			continue
+		}
+		r.mu.RLock()
+		factory, ok := r.factories[entry.Name]
+		r.mu.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("pipeline: entry %d: unknown middleware %q", i+1, entry.Name)
+		}
+		options := entry.Options
+		m, err := factory(func(v interface{}) error {
+			if options.Kind == 0 {
+				return nil
+			}
+			return options.Decode(v)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("pipeline: middleware %q: %w", entry.Name, err)
+		}
+		if len(entry.Skip) > 0 {
+			m = middleware.Skip(m, middleware.MatchPaths(entry.Skip...))
+		}
+		if len(entry.Only) > 0 {
+			m = middleware.Only(m, middleware.MatchPaths(entry.Only...))
+		}
+		mws = append(mws, m)
+	}
+	return mws, nil
+}
@@ -0,0 +1,46 @@
+package probe
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+// MetricsHandler serves the health, check counters and latency percentiles of every target in
+// the Prometheus text exposition format.
+//
+// Example usage:
+//
+//	http.Handle("/metrics/probes", probe.Default().MetricsHandler())
+func (p *Prober) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		snapshot := p.Snapshot()
+		names := make([]string, 0, len(snapshot))
+		for name := range snapshot {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		fmt.Fprint(w, "# HELP lessgo_probe_healthy Whether the last check of the target succeeded.\n# TYPE lessgo_probe_healthy gauge\n")
+		for _, name := range names {
+			healthy := 0
+			if snapshot[name].Healthy {
+				healthy = 1
+			}
+			fmt.Fprintf(w, "lessgo_probe_healthy{target=%q} %d\n", name, healthy)
+		}
+		fmt.Fprint(w, "# HELP lessgo_probe_checks_total Checks run against the target.\n# TYPE lessgo_probe_checks_total counter\n")
+		for _, name := range names {
+			fmt.Fprintf(w, "lessgo_probe_checks_total{target=%q,result=\"success\"} %d\n", name, snapshot[name].Successes)
+			fmt.Fprintf(w, "lessgo_probe_checks_total{target=%q,result=\"failure\"} %d\n", name, snapshot[name].Failures)
+		}
+		fmt.Fprint(w, "# HELP lessgo_probe_latency_seconds Latency of the recent successful checks.\n# TYPE lessgo_probe_latency_seconds summary\n")
+		for _, name := range names {
+			stats := snapshot[name]
+			fmt.Fprintf(w, "lessgo_probe_latency_seconds{target=%q,quantile=\"0.5\"} %g\n", name, stats.P50.Seconds())
+			fmt.Fprintf(w, "lessgo_probe_latency_seconds{target=%q,quantile=\"0.9\"} %g\n", name, stats.P90.Seconds())
+			fmt.Fprintf(w, "lessgo_probe_latency_seconds{target=%q,quantile=\"0.99\"} %g\n", name, stats.P99.Seconds())
+		}
+	})
+}
@@ -0,0 +1,540 @@
+/*
+Package probe provides health and latency probes for network dependencies.
+
+A Prober runs TCP connect, TLS handshake, HTTP, UDP and custom checks against registered
+targets, either on demand or periodically, and keeps a sliding window of latencies so callers
+can read percentiles instead of relying on a single ping at startup. The framework checks its
+own dependencies, such as Redis clients, through the Default prober, and MetricsHandler serves
+the percentiles in the Prometheus text format.
+
+Usage:
+
+	p := probe.NewProber()
+	p.Add(probe.Target{Name: "redis", Kind: probe.TCP, Address: "localhost:6379"})
+	p.Add(probe.Target{Name: "api", Kind: probe.HTTP, URL: "https://example.com/health"})
+	p.Start(10 * time.Second)
+	defer p.Stop()
+
+	stats := p.Stats("redis")
+	log.Printf("redis healthy=%v p99=%v", stats.Healthy, stats.P99)
+*/
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Kind defines the type of check performed against a target.
+type Kind string
+
+const (
+	TCP  Kind = "tcp"
+	TLS  Kind = "tls"
+	HTTP Kind = "http"
+	UDP  Kind = "udp"
+	// Func runs the Check function of the target, e.g. a Redis PING.
+	Func Kind = "func"
+)
+
+const (
+	// Default timeout of a single check.
+	defaultTimeout = 3 * time.Second
+
+	// Default number of latency samples kept per target.
+	defaultWindowSize = 128
+
+	// Time to wait for an ICMP refusal when a UDP check has no payload.
+	udpRefusalWait = 500 * time.Millisecond
+)
+
+// Target describes a dependency to probe.
+type Target struct {
+	Name    string
+	Kind    Kind
+	Address string        // host:port, used by TCP, TLS and UDP checks
+	URL     string        // used by HTTP checks
+	Timeout time.Duration // per-check timeout, defaults to 3 seconds
+
+	// TLSConfig is used by TLS checks and HTTPS requests. ServerName defaults to the target host.
+	TLSConfig *tls.Config
+
+	// ExpectStatus is the status code an HTTP check must return. Any 2xx/3xx is accepted when zero.
+	ExpectStatus int
+
+	// Payload is written by UDP checks. When set, a reply is required for the check to pass.
+	Payload []byte
+
+	// Check is run by Func checks; the target is healthy when it returns nil.
+	Check func(ctx context.Context) error
+}
+
+// Result holds the outcome of a single check.
+type Result struct {
+	Target    string
+	Healthy   bool
+	Latency   time.Duration
+	Err       error
+	CheckedAt time.Time
+}
+
+// Stats summarizes the recent results of a target.
+type Stats struct {
+	Target    string
+	Healthy   bool
+	Successes int64
+	Failures  int64
+	P50       time.Duration
+	P90       time.Duration
+	P99       time.Duration
+	Last      Result
+}
+
+// Prober runs checks against registered targets and records their latency.
+type Prober struct {
+	mu         sync.RWMutex
+	targets    map[string]Target
+	windows    map[string]*latencyWindow
+	windowSize int
+	client     *http.Client
+	clients    map[string]*http.Client // HTTP clients of targets with a TLSConfig
+	stop       chan struct{}
+	wg         sync.WaitGroup
+}
+
+// latencyWindow is a fixed-size ring of the latest successful check latencies.
+type latencyWindow struct {
+	samples   []time.Duration
+	next      int
+	full      bool
+	successes int64
+	failures  int64
+	last      Result
+}
+
+// NewProber creates a new Prober with optional settings.
+//
+// Example:
+//
+//	p := probe.NewProber(probe.WithWindowSize(256))
+func NewProber(options ...func(*Prober)) *Prober {
+	p := &Prober{
+		targets:    make(map[string]Target),
+		windows:    make(map[string]*latencyWindow),
+		clients:    make(map[string]*http.Client),
+		windowSize: defaultWindowSize,
+		client: &http.Client{
+			// Health endpoints that redirect are reported as-is
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+	for _, option := range options {
+		option(p)
+	}
+	return p
+}
+
+// WithWindowSize sets the number of latency samples kept per target.
+func WithWindowSize(size int) func(*Prober) {
+	return func(p *Prober) {
+		if size > 0 {
+			p.windowSize = size
+		}
+	}
+}
+
+// WithHTTPClient sets the client used by HTTP checks.
+func WithHTTPClient(client *http.Client) func(*Prober) {
+	return func(p *Prober) {
+		p.client = client
+	}
+}
+
+var defaultProber = NewProber()
+
+// Default returns the prober the framework registers its own dependencies with, such as the
+// Redis clients of rate limiters and caches, so their health and latency can be served next to
+// the targets of the application.
+//
+// Example usage:
+//
+//	probe.Default().Add(probe.Target{Name: "payments", Kind: probe.HTTP, URL: "https://payments.internal/health"})
+//	probe.Default().Start(10 * time.Second)
+func Default() *Prober {
+	return defaultProber
+}
+
+// Add registers a target. Registering a name twice replaces the previous target.
+func (p *Prober) Add(target Target) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.add(target)
+	p.windows[target.Name] = &latencyWindow{samples: make([]time.Duration, p.windowSize)}
+}
+
+// add stores target with its HTTP client, built once so checks reuse its connections.
+func (p *Prober) add(target Target) {
+	if previous, ok := p.clients[target.Name]; ok {
+		previous.CloseIdleConnections()
+		delete(p.clients, target.Name)
+	}
+	p.targets[target.Name] = target
+	if target.Kind == HTTP && target.TLSConfig != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = target.TLSConfig
+		client := *p.client
+		client.Transport = transport
+		p.clients[target.Name] = &client
+	}
+}
+
+// Remove unregisters a target and discards its statistics.
+func (p *Prober) Remove(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if client, ok := p.clients[name]; ok {
+		client.CloseIdleConnections()
+		delete(p.clients, name)
+	}
+	delete(p.targets, name)
+	delete(p.windows, name)
+}
+
+// CheckTarget registers target, keeping the statistics of a target of the same name, and
+// checks it. Dependencies are checked this way when they are created.
+//
+// Example usage:
+//
+//	if result := probe.Default().CheckTarget(ctx, probe.Redis(client)); !result.Healthy {
+//		log.Fatalf("Could not connect to Redis: %v", result.Err)
+//	}
+func (p *Prober) CheckTarget(ctx context.Context, target Target) Result {
+	p.mu.Lock()
+	p.add(target)
+	if _, ok := p.windows[target.Name]; !ok {
+		p.windows[target.Name] = &latencyWindow{samples: make([]time.Duration, p.windowSize)}
+	}
+	p.mu.Unlock()
+	return p.Check(ctx, target.Name)
+}
+
+// Check probes a single registered target and records the result.
+func (p *Prober) Check(ctx context.Context, name string) Result {
+	p.mu.RLock()
+	target, ok := p.targets[name]
+	p.mu.RUnlock()
+	if !ok {
+		return Result{Target: name, Err: fmt.Errorf("unknown probe target %q", name), CheckedAt: time.Now()}
+	}
+
+	result := p.run(ctx, target)
+	p.record(result)
+	return result
+}
+
+// CheckAll probes every registered target concurrently.
+func (p *Prober) CheckAll(ctx context.Context) []Result {
+	p.mu.RLock()
+	names := make([]string, 0, len(p.targets))
+	for name := range p.targets {
+		names = append(names, name)
+	}
+	p.mu.RUnlock()
+	sort.Strings(names)
+
+	results := make([]Result, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			results[i] = p.Check(ctx, name)
+		}(i, name)
+	}
+	wg.Wait()
+	return results
+}
+
+// Start probes all targets every interval until Stop is called.
+func (p *Prober) Start(interval time.Duration) {
+	p.mu.Lock()
+	if p.stop != nil {
+		p.mu.Unlock()
+		return
+	}
+	p.stop = make(chan struct{})
+	stop := p.stop
+	p.mu.Unlock()
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		p.CheckAll(context.Background())
+		for {
+			select {
+			case <-ticker.C:
+				p.CheckAll(context.Background())
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop halts periodic probing started with Start.
+func (p *Prober) Stop() {
+	p.mu.Lock()
+	if p.stop == nil {
+		p.mu.Unlock()
+		return
+	}
+	close(p.stop)
+	p.stop = nil
+	p.mu.Unlock()
+	p.wg.Wait()
+}
+
+// Healthy reports whether the last check of the target succeeded.
+func (p *Prober) Healthy(name string) bool {
+	return p.Stats(name).Healthy
+}
+
+// Stats returns the statistics of a target.
+func (p *Prober) Stats(name string) Stats {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	window, ok := p.windows[name]
+	if !ok {
+		return Stats{Target: name}
+	}
+	return window.stats(name)
+}
+
+// Snapshot returns the statistics of all targets, keyed by target name.
+func (p *Prober) Snapshot() map[string]Stats {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	snapshot := make(map[string]Stats, len(p.windows))
+	for name, window := range p.windows {
+		snapshot[name] = window.stats(name)
+	}
+	return snapshot
+}
+
+// run performs the check matching the target kind.
+func (p *Prober) run(ctx context.Context, target Target) Result {
+	timeout := target.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	var err error
+	switch target.Kind {
+	case TCP:
+		err = ProbeTCP(ctx, target.Address)
+	case TLS:
+		err = ProbeTLS(ctx, target.Address, target.TLSConfig)
+	case HTTP:
+		err = p.probeHTTP(ctx, target)
+	case UDP:
+		err = ProbeUDP(ctx, target.Address, target.Payload)
+	case Func:
+		if target.Check == nil {
+			err = errors.New("probe target has no check function")
+		} else {
+			err = target.Check(ctx)
+		}
+	default:
+		err = fmt.Errorf("unsupported probe kind %q", target.Kind)
+	}
+
+	return Result{
+		Target:    target.Name,
+		Healthy:   err == nil,
+		Latency:   time.Since(start),
+		Err:       err,
+		CheckedAt: start,
+	}
+}
+
+// record stores a result in the target's latency window.
+func (p *Prober) record(result Result) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	window, ok := p.windows[result.Target]
+	if !ok {
+		return
+	}
+	window.last = result
+	if !result.Healthy {
+		window.failures++
+		return
+	}
+	window.successes++
+	window.samples[window.next] = result.Latency
+	window.next = (window.next + 1) % len(window.samples)
+	if window.next == 0 {
+		window.full = true
+	}
+}
+
+func (w *latencyWindow) stats(name string) Stats {
+	n := w.next
+	if w.full {
+		n = len(w.samples)
+	}
+	sorted := make([]time.Duration, n)
+	copy(sorted, w.samples[:n])
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	return Stats{
+		Target:    name,
+		Healthy:   w.last.Healthy,
+		Successes: w.successes,
+		Failures:  w.failures,
+		P50:       percentile(sorted, 0.50),
+		P90:       percentile(sorted, 0.90),
+		P99:       percentile(sorted, 0.99),
+		Last:      w.last,
+	}
+}
+
+// percentile returns the nearest-rank percentile of sorted samples.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(p*float64(len(sorted))+0.5) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
+
+// ProbeTCP checks that a TCP connection can be established to addr.
+func ProbeTCP(ctx context.Context, addr string) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// ProbeTLS checks that a TLS handshake with addr succeeds.
+// When cfg has no ServerName, the host part of addr is used.
+func ProbeTLS(ctx context.Context, addr string, cfg *tls.Config) error {
+	if cfg == nil {
+		cfg = &tls.Config{MinVersion: tls.VersionTLS12}
+	} else {
+		cfg = cfg.Clone()
+	}
+	if cfg.ServerName == "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return err
+		}
+		cfg.ServerName = host
+	}
+	dialer := tls.Dialer{Config: cfg}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// ProbeHTTP sends a GET request to url and checks the response status.
+// Any 2xx or 3xx status is accepted when expectStatus is zero.
+func ProbeHTTP(ctx context.Context, url string, expectStatus int) error {
+	return NewProber().probeHTTP(ctx, Target{URL: url, ExpectStatus: expectStatus})
+}
+
+func (p *Prober) probeHTTP(ctx context.Context, target Target) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.URL, nil)
+	if err != nil {
+		return err
+	}
+	p.mu.RLock()
+	client, ok := p.clients[target.Name]
+	p.mu.RUnlock()
+	if !ok {
+		client = p.client
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+
+	if target.ExpectStatus != 0 {
+		if res.StatusCode != target.ExpectStatus {
+			return fmt.Errorf("unexpected status %d, want %d", res.StatusCode, target.ExpectStatus)
+		}
+		return nil
+	}
+	if res.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("unhealthy status %d", res.StatusCode)
+	}
+	return nil
+}
+
+// ProbeUDP checks a UDP endpoint. UDP is connectionless, so without a payload the check
+// only detects ports actively refused by the host. With a payload, a reply is required.
+func ProbeUDP(ctx context.Context, addr string, payload []byte) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(defaultTimeout)
+	}
+	if len(payload) == 0 {
+		// Refusals arrive quickly, there is no point in waiting for the full timeout
+		if wait := time.Now().Add(udpRefusalWait); wait.Before(deadline) {
+			deadline = wait
+		}
+	}
+	conn.SetDeadline(deadline)
+
+	probe := payload
+	if len(probe) == 0 {
+		probe = []byte{0}
+	}
+	if _, err := conn.Write(probe); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 512)
+	_, err = conn.Read(buf)
+	if err == nil {
+		return nil
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() && len(payload) == 0 {
+		// No reply and no ICMP refusal, the port is considered open
+		return nil
+	}
+	return err
+}
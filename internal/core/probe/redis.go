@@ -0,0 +1,35 @@
+package probe
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Redis returns a target checking client with a PING, named after its addresses, e.g.
+// redis://localhost:6379.
+func Redis(client redis.UniversalClient) Target {
+	name := "redis"
+	switch c := client.(type) {
+	case *redis.Client:
+		name = "redis://" + c.Options().Addr
+	case *redis.ClusterClient:
+		name = "redis://" + strings.Join(c.Options().Addrs, ",")
+	case *redis.Ring:
+		addrs := make([]string, 0, len(c.Options().Addrs))
+		for _, addr := range c.Options().Addrs {
+			addrs = append(addrs, addr)
+		}
+		sort.Strings(addrs)
+		name = "redis://" + strings.Join(addrs, ",")
+	}
+	return Target{
+		Name: name,
+		Kind: Func,
+		Check: func(ctx context.Context) error {
+			return client.Ping(ctx).Err()
+		},
+	}
+}
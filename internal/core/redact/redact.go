@@ -0,0 +1,246 @@
+/*
+Package redact masks sensitive data before it is written to access logs, audit logs or error
+reports.
+
+A Redactor masks configured headers, JSON body fields and query parameters. The default
+redactor, used by the request log export, the audit middleware and error reporting, masks
+credentials under their common names; SetDefault replaces it to add application specific names.
+
+Usage:
+
+	redact.SetDefault(redact.New(
+		redact.WithFields("iban", "ssn"),
+		redact.WithQueryParams("invite"),
+	))
+	redact.Default().Query("token=abc&page=2") // "token=%5BREDACTED%5D&page=2"
+*/
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+)
+
+// Mask replaces redacted values.
+const Mask = "[REDACTED]"
+
+// Redactor masks sensitive headers, body fields and query parameters. Names are matched
+// case-insensitively. A Redactor is immutable and safe for concurrent use.
+type Redactor struct {
+	headers map[string]bool
+	fields  map[string]bool
+	params  map[string]bool
+}
+
+// Option configures a Redactor.
+type Option func(*Redactor)
+
+// New creates a redactor masking the default names plus those added by the options:
+// the Authorization, Proxy-Authorization, Cookie, Set-Cookie and X-Api-Key headers, and
+// fields and query parameters named like passwords, tokens, secrets and API keys.
+func New(options ...Option) *Redactor {
+	r := &Redactor{headers: map[string]bool{}, fields: map[string]bool{}, params: map[string]bool{}}
+	WithHeaders("Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key")(r)
+	WithFields("password", "passwd", "secret", "client_secret", "token", "access_token", "refresh_token",
+		"id_token", "authorization", "api_key", "apikey", "credit_card", "card_number", "cvv")(r)
+	WithQueryParams("password", "token", "access_token", "refresh_token", "api_key", "apikey", "secret",
+		"signature", "sig", "code")(r)
+	for _, option := range options {
+		option(r)
+	}
+	return r
+}
+
+// WithHeaders masks the given headers.
+func WithHeaders(names ...string) Option {
+	return func(r *Redactor) {
+		for _, name := range names {
+			r.headers[strings.ToLower(name)] = true
+		}
+	}
+}
+
+// WithFields masks JSON fields with the given names at any depth.
+func WithFields(names ...string) Option {
+	return func(r *Redactor) {
+		for _, name := range names {
+			r.fields[strings.ToLower(name)] = true
+		}
+	}
+}
+
+// WithQueryParams masks the given query parameters.
+func WithQueryParams(names ...string) Option {
+	return func(r *Redactor) {
+		for _, name := range names {
+			r.params[strings.ToLower(name)] = true
+		}
+	}
+}
+
+// With returns a copy of the redactor with further options applied.
+func (r *Redactor) With(options ...Option) *Redactor {
+	clone := &Redactor{headers: copySet(r.headers), fields: copySet(r.fields), params: copySet(r.params)}
+	for _, option := range options {
+		option(clone)
+	}
+	return clone
+}
+
+func copySet(set map[string]bool) map[string]bool {
+	clone := make(map[string]bool, len(set))
+	for name := range set {
+		clone[name] = true
+	}
+	return clone
+}
+
+// Header returns a copy of the headers with the sensitive ones masked.
+func (r *Redactor) Header(header http.Header) http.Header {
+	redacted := make(http.Header, len(header))
+	for name, values := range header {
+		if r.headers[strings.ToLower(name)] {
+			redacted[name] = []string{Mask}
+		} else {
+			redacted[name] = append([]string(nil), values...)
+		}
+	}
+	return redacted
+}
+
+// Query returns the raw query with the sensitive parameters masked. Queries that cannot be
+// parsed are masked entirely when they mention a sensitive parameter.
+func (r *Redactor) Query(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		for name := range r.params {
+			if strings.Contains(strings.ToLower(rawQuery), name) {
+				return Mask
+			}
+		}
+		return rawQuery
+	}
+	changed := false
+	for name := range values {
+		if r.params[strings.ToLower(name)] {
+			values[name] = []string{Mask}
+			changed = true
+		}
+	}
+	if !changed {
+		return rawQuery
+	}
+	return values.Encode()
+}
+
+// URL returns the URL as a string with the sensitive query parameters and any password
+// masked.
+func (r *Redactor) URL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	redacted := *u
+	redacted.RawQuery = r.Query(u.RawQuery)
+	if redacted.User != nil {
+		if _, hasPassword := redacted.User.Password(); hasPassword {
+			redacted.User = url.UserPassword(redacted.User.Username(), Mask)
+		}
+	}
+	return redacted.String()
+}
+
+// Value returns a copy of a decoded JSON value with the sensitive fields masked.
+func (r *Redactor) Value(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(value))
+		for key, field := range value {
+			if r.fields[strings.ToLower(key)] {
+				redacted[key] = Mask
+			} else {
+				redacted[key] = r.Value(field)
+			}
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(value))
+		for i, item := range value {
+			redacted[i] = r.Value(item)
+		}
+		return redacted
+	}
+	return value
+}
+
+// JSON returns the JSON document with the sensitive fields masked. Data that is not JSON is
+// returned unchanged.
+func (r *Redactor) JSON(data []byte) []byte {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return data
+	}
+	redacted, err := json.Marshal(r.Value(value))
+	if err != nil {
+		return data
+	}
+	return redacted
+}
+
+// Multipart returns the multipart form with the values of sensitive fields masked, matched
+// like JSON fields and query parameters. Forms that cannot be parsed are masked entirely.
+func (r *Redactor) Multipart(data []byte, boundary string) []byte {
+	var buf bytes.Buffer
+	reader := multipart.NewReader(bytes.NewReader(data), boundary)
+	writer := multipart.NewWriter(&buf)
+	if err := writer.SetBoundary(boundary); err != nil {
+		return []byte(Mask)
+	}
+	for {
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return []byte(Mask)
+		}
+		out, err := writer.CreatePart(part.Header)
+		if err != nil {
+			return []byte(Mask)
+		}
+		name := strings.ToLower(part.FormName())
+		if r.fields[name] || r.params[name] {
+			io.WriteString(out, Mask)
+		} else if _, err := io.Copy(out, part); err != nil {
+			return []byte(Mask)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return []byte(Mask)
+	}
+	return buf.Bytes()
+}
+
+var defaultRedactor atomic.Pointer[Redactor]
+
+func init() {
+	defaultRedactor.Store(New())
+}
+
+// Default returns the redactor used by access logs, audit logs and error reports.
+func Default() *Redactor {
+	return defaultRedactor.Load()
+}
+
+// SetDefault replaces the default redactor.
+func SetDefault(r *Redactor) {
+	defaultRedactor.Store(r)
+}
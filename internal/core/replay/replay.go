@@ -0,0 +1,426 @@
+/*
+Package replay records requests with their bodies, redacted, and replays them against the
+application to reproduce bugs seen in production.
+
+The Recorder middleware keeps the last requests in a ring buffer and can append them to an
+NDJSON file. Records are replayed in-process with Replay, against a running application with
+ReplayTo or `lessgo replay`, or through the admin router, see Router.ExposeRequestRecorder.
+Credentials are redacted when recording, so replays set them again with a prepare function.
+
+Usage:
+
+	options := replay.NewOptions()
+	options.MinStatus = 500 // Only requests that failed
+	options.File = "/var/log/app/requests.ndjson"
+	recorder, err := replay.NewRecorder(*options)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer recorder.Close()
+	App.Use(recorder)
+
+	// $ lessgo replay -target http://localhost:8080 -id 9f86d081 -H "Authorization: Bearer $TOKEN" requests.ndjson
+*/
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+	"github.com/hokamsingh/lessgo/internal/core/redact"
+)
+
+// Header marks replayed requests with the ID of their record. The recorder does not record
+// them again, as long as it still holds the record; the header of other requests is ignored.
+const Header = "X-Replay-Of"
+
+// Options defines which requests are recorded and where.
+type Options struct {
+	// Capacity is the number of records kept in memory, the oldest are dropped first.
+	Capacity int
+	// MaxBodySize is the largest request body recorded. Larger bodies are left out and the
+	// record is marked truncated.
+	MaxBodySize int64
+	// MinStatus records only the requests answered with this status or above, e.g. 500.
+	MinStatus int
+	// SkipPaths are not recorded, e.g. health checks.
+	SkipPaths []string
+	// File receives every record as a line of JSON when set.
+	File string
+	// Redactor masks sensitive headers, query parameters and body fields, redact.Default()
+	// when nil.
+	Redactor *redact.Redactor
+}
+
+// NewOptions creates Options keeping the last 500 requests in memory, with bodies up to 64 KB.
+func NewOptions() *Options {
+	return &Options{
+		Capacity:    500,
+		MaxBodySize: 64 << 10,
+		SkipPaths:   []string{"/health", "/healthz", "/ready", "/readyz"},
+	}
+}
+
+// Record is a recorded request and the status it was answered with.
+type Record struct {
+	ID     string      `json:"id"`
+	Time   time.Time   `json:"time"`
+	Method string      `json:"method"`
+	URL    string      `json:"url"` // Path and query
+	Host   string      `json:"host,omitempty"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+	// BodyEncoding is base64 for binary bodies.
+	BodyEncoding string `json:"body_encoding,omitempty"`
+	// Truncated reports a body larger than MaxBodySize, not recorded.
+	Truncated  bool    `json:"truncated,omitempty"`
+	Status     int     `json:"status"`
+	DurationMs float64 `json:"duration_ms"`
+	RequestID  string  `json:"request_id,omitempty"`
+	Route      string  `json:"route,omitempty"`
+}
+
+// Recorder is the middleware recording requests.
+type Recorder struct {
+	options Options
+	skip    map[string]bool
+
+	mu      sync.Mutex
+	records []Record
+	next    int
+	full    bool
+	file    *os.File
+}
+
+// NewRecorder creates the recording middleware, opening the file of the options for appending.
+func NewRecorder(options Options) (*Recorder, error) {
+	if options.Capacity <= 0 {
+		options.Capacity = 500
+	}
+	if options.MaxBodySize <= 0 {
+		options.MaxBodySize = 64 << 10
+	}
+	if options.Redactor == nil {
+		options.Redactor = redact.Default()
+	}
+	rec := &Recorder{options: options, skip: map[string]bool{}, records: make([]Record, options.Capacity)}
+	for _, path := range options.SkipPaths {
+		rec.skip[path] = true
+	}
+	if options.File != "" {
+		file, err := os.OpenFile(options.File, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("replay: opening %s: %w", options.File, err)
+		}
+		rec.file = file
+	}
+	return rec, nil
+}
+
+func (rec *Recorder) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rec.skip[r.URL.Path] || rec.replayed(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		// The router stores the matched route in the value store
+		r, _ = middleware.RequestValues(r)
+		start := time.Now()
+		// Bodies captured earlier are shared, larger ones stream through unrecorded
+		body, complete, err := middleware.PeekBody(r, rec.options.MaxBodySize)
+		truncated := err != nil || !complete
+		status := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(status, r)
+		if status.status < rec.options.MinStatus {
+			return
+		}
+
+		record := Record{
+			ID:         newID(),
+			Time:       start.UTC(),
+			Method:     r.Method,
+			URL:        r.URL.Path,
+			Host:       r.Host,
+			Header:     rec.options.Redactor.Header(r.Header),
+			Truncated:  truncated,
+			Status:     status.status,
+			DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+			RequestID:  middleware.RequestID(r),
+		}
+		record.Route, _ = middleware.RequestRoute(r)
+		if r.URL.RawQuery != "" {
+			record.URL += "?" + rec.options.Redactor.Query(r.URL.RawQuery)
+		}
+		record.Body, record.BodyEncoding = rec.encodeBody(r.Header.Get("Content-Type"), body)
+		rec.add(record)
+	})
+}
+
+// replayed reports whether r is a replay of a record the recorder holds.
+func (rec *Recorder) replayed(r *http.Request) bool {
+	id := r.Header.Get(Header)
+	if id == "" {
+		return false
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	for _, record := range rec.records {
+		if record.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeBody redacts JSON and form bodies and encodes binary bodies in base64.
+func (rec *Recorder) encodeBody(contentType string, body []byte) (string, string) {
+	if len(body) == 0 {
+		return "", ""
+	}
+	media, params, _ := mime.ParseMediaType(contentType)
+	switch {
+	case media == "application/json" || strings.HasSuffix(media, "+json"):
+		body = rec.options.Redactor.JSON(body)
+	case media == "application/x-www-form-urlencoded":
+		body = []byte(rec.options.Redactor.Query(string(body)))
+	case strings.HasPrefix(media, "multipart/"):
+		body = rec.options.Redactor.Multipart(body, params["boundary"])
+	}
+	if !utf8.Valid(body) {
+		return base64.StdEncoding.EncodeToString(body), "base64"
+	}
+	return string(body), ""
+}
+
+// add stores the record in the ring buffer and appends it to the file.
+func (rec *Recorder) add(record Record) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.records[rec.next] = record
+	rec.next = (rec.next + 1) % len(rec.records)
+	if rec.next == 0 {
+		rec.full = true
+	}
+	if rec.file != nil {
+		if line, err := json.Marshal(record); err == nil {
+			rec.file.Write(append(line, '\n'))
+		}
+	}
+}
+
+// Records returns the records in memory, the oldest first.
+func (rec *Recorder) Records() []Record {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if !rec.full {
+		return append([]Record(nil), rec.records[:rec.next]...)
+	}
+	return append(append([]Record(nil), rec.records[rec.next:]...), rec.records[:rec.next]...)
+}
+
+// Record returns the record in memory with the ID.
+func (rec *Recorder) Record(id string) (Record, bool) {
+	for _, record := range rec.Records() {
+		if record.ID == id {
+			return record, true
+		}
+	}
+	return Record{}, false
+}
+
+// Close closes the file of the records.
+func (rec *Recorder) Close() error {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if rec.file == nil {
+		return nil
+	}
+	err := rec.file.Close()
+	rec.file = nil
+	return err
+}
+
+// LoadRecords reads the records of an NDJSON file written by a Recorder.
+func LoadRecords(path string) ([]Record, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var records []Record
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64<<10), 16<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("replay: %s line %d: %w", path, line, err)
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// Request rebuilds the recorded request, marked with the replay header, for the URL's base,
+// e.g. http://localhost:8080, or for in-process use when base is empty.
+func (r Record) Request(base string) (*http.Request, error) {
+	body := []byte(r.Body)
+	if r.BodyEncoding == "base64" {
+		var err error
+		if body, err = base64.StdEncoding.DecodeString(r.Body); err != nil {
+			return nil, fmt.Errorf("replay: body of %s: %w", r.ID, err)
+		}
+	}
+	var req *http.Request
+	if base == "" {
+		req = httptest.NewRequest(r.Method, r.URL, bytes.NewReader(body))
+		if r.Host != "" {
+			req.Host = r.Host
+		}
+	} else {
+		var err error
+		if req, err = http.NewRequest(r.Method, strings.TrimRight(base, "/")+r.URL, bytes.NewReader(body)); err != nil {
+			return nil, err
+		}
+	}
+	for name, values := range r.Header {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+	req.Header.Del("Content-Length")
+	req.Header.Set(Header, r.ID)
+	return req, nil
+}
+
+// Result is the outcome of a replay.
+type Result struct {
+	ID             string      `json:"id"`
+	Method         string      `json:"method"`
+	URL            string      `json:"url"`
+	RecordedStatus int         `json:"recorded_status"`
+	Status         int         `json:"status"`
+	Header         http.Header `json:"header"`
+	Body           string      `json:"body"`
+	DurationMs     float64     `json:"duration_ms"`
+}
+
+// Replay sends the recorded request to the handler in-process, e.g. the router of the
+// application. prepare, when not nil, changes the request first, e.g. to set the credentials
+// redacted when recording.
+//
+// Example usage:
+//
+//	result, err := replay.Replay(App.Handler(), record, func(r *http.Request) {
+//		r.Header.Set("Authorization", "Bearer "+testToken)
+//	})
+func Replay(handler http.Handler, record Record, prepare func(*http.Request)) (*Result, error) {
+	req, err := record.Request("")
+	if err != nil {
+		return nil, err
+	}
+	if prepare != nil {
+		prepare(req)
+	}
+	w := httptest.NewRecorder()
+	start := time.Now()
+	handler.ServeHTTP(w, req)
+	return &Result{
+		ID:             record.ID,
+		Method:         record.Method,
+		URL:            record.URL,
+		RecordedStatus: record.Status,
+		Status:         w.Code,
+		Header:         w.Header(),
+		Body:           w.Body.String(),
+		DurationMs:     float64(time.Since(start).Microseconds()) / 1000,
+	}, nil
+}
+
+// ReplayTo sends the recorded request to a running application at base, e.g.
+// http://localhost:8080. The client is http.DefaultClient when nil.
+func ReplayTo(client *http.Client, base string, record Record, prepare func(*http.Request)) (*Result, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := record.Request(base)
+	if err != nil {
+		return nil, err
+	}
+	if prepare != nil {
+		prepare(req)
+	}
+	start := time.Now()
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{
+		ID:             record.ID,
+		Method:         record.Method,
+		URL:            record.URL,
+		RecordedStatus: record.Status,
+		Status:         res.StatusCode,
+		Header:         res.Header,
+		Body:           string(body),
+		DurationMs:     float64(time.Since(start).Microseconds()) / 1000,
+	}, nil
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// statusWriter keeps the status of a response.
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if !w.wroteHeader && status >= http.StatusOK {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(p []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *statusWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
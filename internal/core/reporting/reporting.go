@@ -0,0 +1,163 @@
+/*
+Package reporting sends errors and panics to an error tracking service such as Sentry or
+Rollbar, together with the metadata of the request they happened in.
+
+The recovery middleware reports panics and ctx.Error reports 5xx responses to the reporter set
+with SetReporter. Other services are integrated by implementing Reporter.
+
+Usage:
+
+	sentry, err := reporting.NewSentry(cfg.Get("SENTRY_DSN", ""), reporting.WithEnvironment("production"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer sentry.Close(5 * time.Second)
+	reporting.SetReporter(sentry)
+*/
+package reporting
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+	"github.com/hokamsingh/lessgo/internal/core/redact"
+)
+
+// Level is the severity of an event.
+type Level string
+
+const (
+	LevelError Level = "error"
+	LevelFatal Level = "fatal"
+)
+
+// Event is an error with the metadata of the request it happened in.
+type Event struct {
+	Time      time.Time
+	Level     Level
+	Error     error
+	Stack     []byte // Stack trace of a panic, empty for other errors
+	Method    string
+	URL       string
+	Route     string // Route template, e.g. /users/{id}, or the path when unknown
+	Status    int    // Status of the response, 0 when unknown
+	User      string
+	RequestID string
+	ClientIP  string
+	UserAgent string
+	Tags      map[string]string
+}
+
+// Reporter sends events to an error tracking service. Report must not block the request
+// for long, implementations deliver events in the background.
+type Reporter interface {
+	Report(event Event)
+}
+
+// ReporterFunc adapts a function to the Reporter interface.
+type ReporterFunc func(event Event)
+
+func (f ReporterFunc) Report(event Event) {
+	f(event)
+}
+
+// multiReporter reports each event to several reporters.
+type multiReporter []Reporter
+
+func (m multiReporter) Report(event Event) {
+	for _, reporter := range m {
+		reporter.Report(event)
+	}
+}
+
+// Multi returns a reporter sending events to all of the given reporters.
+func Multi(reporters ...Reporter) Reporter {
+	return multiReporter(reporters)
+}
+
+type reporterHolder struct {
+	reporter Reporter
+}
+
+var current atomic.Value // reporterHolder
+
+// SetReporter sets the reporter receiving the errors of the application, nil to stop reporting.
+func SetReporter(reporter Reporter) {
+	current.Store(reporterHolder{reporter})
+}
+
+// CurrentReporter returns the reporter set with SetReporter, or nil.
+func CurrentReporter() Reporter {
+	holder, _ := current.Load().(reporterHolder)
+	return holder.reporter
+}
+
+// RequestIDHeader is the header events take their request ID from.
+var RequestIDHeader = "X-Request-ID"
+
+// NewEvent creates an error event with the metadata of the request: method, URL, route,
+// user, request ID, client IP and user agent. Sensitive query parameters are redacted.
+func NewEvent(r *http.Request, err error) Event {
+	event := Event{Time: time.Now().UTC(), Level: LevelError, Error: err}
+	if r == nil {
+		return event
+	}
+	event.Method = r.Method
+	event.URL = redact.Default().URL(r.URL)
+	event.Route = r.URL.Path
+	if template, _ := middleware.RequestRoute(r); template != "" {
+		event.Route = template
+	} else if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			event.Route = template
+		}
+	}
+	event.User = middleware.RequestUser(r)
+	event.RequestID = r.Header.Get(RequestIDHeader)
+	event.ClientIP = middleware.ClientIP(r)
+	event.UserAgent = r.UserAgent()
+	return event
+}
+
+// Report sends the event to the current reporter, if one is set.
+func Report(event Event) {
+	if reporter := CurrentReporter(); reporter != nil {
+		reporter.Report(event)
+	}
+}
+
+// ReportRequest reports an error of the request to the current reporter.
+func ReportRequest(r *http.Request, err error, status int) {
+	if CurrentReporter() == nil {
+		return
+	}
+	event := NewEvent(r, err)
+	event.Status = status
+	Report(event)
+}
+
+// PanicHook returns a RecoveryOptions.Reporter that reports panics to the reporter, or to the
+// current reporter when it is nil.
+//
+// Example usage:
+//
+//	options := middleware.NewRecoveryOptions()
+//	options.Reporter = reporting.PanicHook(sentry)
+func PanicHook(reporter Reporter) func(r *http.Request, err error, stack []byte) {
+	return func(r *http.Request, err error, stack []byte) {
+		target := reporter
+		if target == nil {
+			if target = CurrentReporter(); target == nil {
+				return
+			}
+		}
+		event := NewEvent(r, err)
+		event.Level = LevelFatal
+		event.Stack = stack
+		event.Status = http.StatusInternalServerError
+		target.Report(event)
+	}
+}
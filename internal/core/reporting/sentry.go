@@ -0,0 +1,217 @@
+package reporting
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sentry reports events to Sentry through its store endpoint. Events are sent by a
+// background worker; when the queue is full further events are dropped and logged.
+type Sentry struct {
+	endpoint    string
+	auth        string
+	client      *http.Client
+	environment string
+	release     string
+	serverName  string
+
+	mu      sync.RWMutex
+	closed  bool
+	queue   chan Event
+	stopped chan struct{}
+}
+
+// SentryOption configures a Sentry reporter.
+type SentryOption func(*Sentry)
+
+// WithEnvironment sets the environment events are tagged with, e.g. production.
+func WithEnvironment(environment string) SentryOption {
+	return func(s *Sentry) {
+		s.environment = environment
+	}
+}
+
+// WithRelease sets the release events are tagged with, e.g. the version of the application.
+func WithRelease(release string) SentryOption {
+	return func(s *Sentry) {
+		s.release = release
+	}
+}
+
+// WithHTTPClient sets the client used to send events.
+func WithHTTPClient(client *http.Client) SentryOption {
+	return func(s *Sentry) {
+		s.client = client
+	}
+}
+
+// NewSentry creates a Sentry reporter for the project of the DSN, e.g.
+// https://public@o123.ingest.sentry.io/456.
+func NewSentry(dsn string, options ...SentryOption) (*Sentry, error) {
+	parsed, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Sentry DSN: %w", err)
+	}
+	if parsed.User == nil || parsed.User.Username() == "" {
+		return nil, fmt.Errorf("invalid Sentry DSN: missing public key")
+	}
+	path := strings.TrimSuffix(parsed.Path, "/")
+	slash := strings.LastIndex(path, "/")
+	projectID := path[slash+1:]
+	if projectID == "" {
+		return nil, fmt.Errorf("invalid Sentry DSN: missing project ID")
+	}
+
+	auth := "Sentry sentry_version=7, sentry_client=lessgo/1.0, sentry_key=" + parsed.User.Username()
+	if secret, ok := parsed.User.Password(); ok {
+		auth += ", sentry_secret=" + secret
+	}
+	hostname, _ := os.Hostname()
+	s := &Sentry{
+		endpoint:   fmt.Sprintf("%s://%s%s/api/%s/store/", parsed.Scheme, parsed.Host, path[:slash], projectID),
+		auth:       auth,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		serverName: hostname,
+		queue:      make(chan Event, 100),
+		stopped:    make(chan struct{}),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	go s.run()
+	return s, nil
+}
+
+// Report queues the event for delivery.
+func (s *Sentry) Report(event Event) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.queue <- event:
+	default:
+		log.Printf("Sentry queue full, dropping event: %v", event.Error)
+	}
+}
+
+// Close stops accepting events and waits up to the timeout for queued events to be sent.
+func (s *Sentry) Close(timeout time.Duration) {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.mu.Unlock()
+	select {
+	case <-s.stopped:
+	case <-time.After(timeout):
+	}
+}
+
+func (s *Sentry) run() {
+	defer close(s.stopped)
+	for event := range s.queue {
+		if err := s.send(event); err != nil {
+			log.Printf("Failed to report event to Sentry: %v", err)
+		}
+	}
+}
+
+func (s *Sentry) send(event Event) error {
+	body, err := json.Marshal(s.payload(event))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", s.auth)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("sentry responded %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// payload converts the event to the JSON shape of the Sentry store API.
+func (s *Sentry) payload(event Event) map[string]interface{} {
+	id := make([]byte, 16)
+	rand.Read(id)
+
+	message := ""
+	errorType := "error"
+	if event.Error != nil {
+		message = event.Error.Error()
+		errorType = fmt.Sprintf("%T", event.Error)
+	}
+	tags := map[string]string{}
+	for name, value := range event.Tags {
+		tags[name] = value
+	}
+	if event.Route != "" {
+		tags["route"] = event.Route
+	}
+	if event.RequestID != "" {
+		tags["request_id"] = event.RequestID
+	}
+	if event.Status != 0 {
+		tags["status"] = strconv.Itoa(event.Status)
+	}
+
+	payload := map[string]interface{}{
+		"event_id":    hex.EncodeToString(id),
+		"timestamp":   event.Time.UTC().Format(time.RFC3339Nano),
+		"level":       string(event.Level),
+		"platform":    "go",
+		"logger":      "lessgo",
+		"server_name": s.serverName,
+		"exception": map[string]interface{}{
+			"values": []map[string]string{{"type": errorType, "value": message}},
+		},
+		"tags": tags,
+	}
+	if event.Route != "" {
+		payload["transaction"] = event.Method + " " + event.Route
+	}
+	if s.environment != "" {
+		payload["environment"] = s.environment
+	}
+	if s.release != "" {
+		payload["release"] = s.release
+	}
+	if event.URL != "" {
+		payload["request"] = map[string]interface{}{
+			"url":     event.URL,
+			"method":  event.Method,
+			"headers": map[string]string{"User-Agent": event.UserAgent},
+		}
+	}
+	if event.User != "" || event.ClientIP != "" {
+		payload["user"] = map[string]string{"id": event.User, "ip_address": event.ClientIP}
+	}
+	if len(event.Stack) > 0 {
+		payload["extra"] = map[string]string{"stack": string(event.Stack)}
+	}
+	return payload
+}
@@ -0,0 +1,288 @@
+/*
+Package retention enforces data retention policies.
+
+Modules register datasets with a time to live and a scrub function that deletes or anonymizes
+records older than a cutoff. The engine runs enforcement on a schedule, holds a lock per dataset
+so only one instance enforces it at a time, and reports every run to an audit log.
+
+Usage:
+
+	engine := retention.NewEngine(
+		retention.WithLocker(retention.NewRedisLocker(client)),
+		retention.WithAuditLog(exporter),
+	)
+	engine.Register(retention.Dataset{
+		Name: "sessions",
+		TTL:  30 * 24 * time.Hour,
+		Scrub: func(ctx context.Context, cutoff time.Time) (int, error) {
+			return repo.DeleteSessionsBefore(ctx, cutoff)
+		},
+	})
+	engine.Schedule(sched, "0 3 * * *")
+*/
+package retention
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	scheduler "github.com/hokamsingh/lessgo/internal/core/job"
+)
+
+// ScrubFunc deletes or anonymizes the records of a dataset created before cutoff
+// and returns the number of affected records.
+type ScrubFunc func(ctx context.Context, cutoff time.Time) (int, error)
+
+// Dataset describes data subject to a retention policy.
+type Dataset struct {
+	Name  string
+	TTL   time.Duration
+	Scrub ScrubFunc
+}
+
+// Report is the outcome of enforcing a dataset's policy.
+type Report struct {
+	Dataset  string        `json:"dataset"`
+	Cutoff   time.Time     `json:"cutoff"`
+	Affected int           `json:"affected"`
+	Duration time.Duration `json:"duration"`
+	Skipped  bool          `json:"skipped,omitempty"` // another instance held the lock
+	Error    string        `json:"error,omitempty"`
+	RanAt    time.Time     `json:"ran_at"`
+}
+
+// AuditLog receives a record for every enforcement run. *logexport.Exporter implements it.
+type AuditLog interface {
+	Record(record interface{}) error
+}
+
+// Locker acquires named locks shared by all instances of the application.
+type Locker interface {
+	// TryLock acquires the lock without waiting. It reports false when the lock is held elsewhere.
+	TryLock(ctx context.Context, key string, ttl time.Duration) (unlock func(), ok bool, err error)
+}
+
+// Engine holds registered datasets and enforces their policies.
+type Engine struct {
+	mu       sync.RWMutex
+	datasets map[string]Dataset
+	locker   Locker
+	audit    AuditLog
+	lockTTL  time.Duration
+	now      func() time.Time
+}
+
+// NewEngine creates a retention Engine. Without WithLocker, locks are only held within this process.
+func NewEngine(options ...func(*Engine)) *Engine {
+	e := &Engine{
+		datasets: make(map[string]Dataset),
+		locker:   NewLocalLocker(),
+		lockTTL:  time.Hour,
+		now:      time.Now,
+	}
+	for _, option := range options {
+		option(e)
+	}
+	return e
+}
+
+// WithLocker sets the lock used to make sure a dataset is enforced by a single instance.
+func WithLocker(locker Locker) func(*Engine) {
+	return func(e *Engine) {
+		e.locker = locker
+	}
+}
+
+// WithAuditLog reports enforcement runs to the given audit log.
+func WithAuditLog(audit AuditLog) func(*Engine) {
+	return func(e *Engine) {
+		e.audit = audit
+	}
+}
+
+// WithLockTTL sets how long a dataset lock is held at most, in case an instance dies while enforcing.
+func WithLockTTL(ttl time.Duration) func(*Engine) {
+	return func(e *Engine) {
+		e.lockTTL = ttl
+	}
+}
+
+// Register adds a dataset. Registering a name twice is an error.
+func (e *Engine) Register(dataset Dataset) error {
+	if dataset.Name == "" || dataset.Scrub == nil {
+		return fmt.Errorf("dataset requires a name and a scrub function")
+	}
+	if dataset.TTL <= 0 {
+		return fmt.Errorf("dataset %s requires a positive TTL", dataset.Name)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, exists := e.datasets[dataset.Name]; exists {
+		return fmt.Errorf("dataset %s is already registered", dataset.Name)
+	}
+	e.datasets[dataset.Name] = dataset
+	return nil
+}
+
+// Datasets returns the registered datasets sorted by name.
+func (e *Engine) Datasets() []Dataset {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	datasets := make([]Dataset, 0, len(e.datasets))
+	for _, dataset := range e.datasets {
+		datasets = append(datasets, dataset)
+	}
+	sort.Slice(datasets, func(i, j int) bool {
+		return datasets[i].Name < datasets[j].Name
+	})
+	return datasets
+}
+
+// Enforce runs the policies of all datasets. A failing dataset does not stop the others;
+// failures are recorded in the reports.
+func (e *Engine) Enforce(ctx context.Context) []Report {
+	datasets := e.Datasets()
+	reports := make([]Report, 0, len(datasets))
+	for _, dataset := range datasets {
+		reports = append(reports, e.enforce(ctx, dataset))
+	}
+	return reports
+}
+
+// EnforceDataset runs the policy of a single dataset.
+func (e *Engine) EnforceDataset(ctx context.Context, name string) (Report, error) {
+	e.mu.RLock()
+	dataset, ok := e.datasets[name]
+	e.mu.RUnlock()
+	if !ok {
+		return Report{}, fmt.Errorf("dataset %s is not registered", name)
+	}
+	return e.enforce(ctx, dataset), nil
+}
+
+func (e *Engine) enforce(ctx context.Context, dataset Dataset) Report {
+	start := e.now()
+	report := Report{Dataset: dataset.Name, Cutoff: start.Add(-dataset.TTL).UTC(), RanAt: start.UTC()}
+
+	unlock, ok, err := e.locker.TryLock(ctx, "retention:"+dataset.Name, e.lockTTL)
+	switch {
+	case err != nil:
+		report.Error = fmt.Sprintf("acquiring lock: %v", err)
+	case !ok:
+		report.Skipped = true
+	default:
+		affected, scrubErr := e.scrub(ctx, dataset, report.Cutoff)
+		unlock()
+		report.Affected = affected
+		if scrubErr != nil {
+			report.Error = scrubErr.Error()
+		}
+	}
+	report.Duration = time.Since(start)
+
+	if report.Error != "" {
+		log.Printf("Retention enforcement of %s failed: %s", dataset.Name, report.Error)
+	}
+	if e.audit != nil && !report.Skipped {
+		if err := e.audit.Record(auditRecord{Event: "retention.enforced", Report: report}); err != nil {
+			log.Printf("Failed to record retention report for %s: %v", dataset.Name, err)
+		}
+	}
+	return report
+}
+
+// scrub calls the dataset's scrub function, turning a panic into an error.
+func (e *Engine) scrub(ctx context.Context, dataset Dataset, cutoff time.Time) (affected int, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("scrub panicked: %v", r)
+		}
+	}()
+	return dataset.Scrub(ctx, cutoff)
+}
+
+// auditRecord is the entry written to the audit log for each run.
+type auditRecord struct {
+	Event string `json:"event"`
+	Report
+}
+
+// Schedule registers an enforcement job for all datasets on a scheduler using a cron expression.
+//
+// Example:
+//
+//	err := engine.Schedule(sched, "0 3 * * *") // every day at 03:00
+func (e *Engine) Schedule(s scheduler.Scheduler, spec string) error {
+	return s.AddJob(spec, func() {
+		e.Enforce(context.Background())
+	})
+}
+
+// LocalLocker is a Locker for a single instance.
+type LocalLocker struct {
+	mu    sync.Mutex
+	locks map[string]time.Time
+}
+
+// NewLocalLocker creates an in-process Locker.
+func NewLocalLocker() *LocalLocker {
+	return &LocalLocker{locks: make(map[string]time.Time)}
+}
+
+// TryLock implements Locker.
+func (l *LocalLocker) TryLock(ctx context.Context, key string, ttl time.Duration) (func(), bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if expires, held := l.locks[key]; held && time.Now().Before(expires) {
+		return nil, false, nil
+	}
+	l.locks[key] = time.Now().Add(ttl)
+	return func() {
+		l.mu.Lock()
+		delete(l.locks, key)
+		l.mu.Unlock()
+	}, true, nil
+}
+
+// unlockScript deletes the lock only if it still holds our token.
+var unlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// RedisLocker is a Locker shared by all instances using the same Redis.
+type RedisLocker struct {
+	client redis.UniversalClient
+}
+
+// NewRedisLocker creates a Locker backed by Redis.
+func NewRedisLocker(client redis.UniversalClient) *RedisLocker {
+	return &RedisLocker{client: client}
+}
+
+// TryLock implements Locker.
+func (l *RedisLocker) TryLock(ctx context.Context, key string, ttl time.Duration) (func(), bool, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return nil, false, err
+	}
+	token := hex.EncodeToString(random)
+	ok, err := l.client.SetNX(ctx, key, token, ttl).Result()
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	return func() {
+		if err := unlockScript.Run(context.Background(), l.client, []string{key}, token).Err(); err != nil {
+			log.Printf("Failed to release lock %s: %v", key, err)
+		}
+	}, true, nil
+}
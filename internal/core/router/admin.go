@@ -0,0 +1,551 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/hokamsingh/lessgo/internal/core/config"
+	"github.com/hokamsingh/lessgo/internal/core/context"
+	"github.com/hokamsingh/lessgo/internal/core/contract"
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+	"github.com/hokamsingh/lessgo/internal/core/probe"
+	"github.com/hokamsingh/lessgo/internal/core/replay"
+	"github.com/hokamsingh/lessgo/internal/core/websocket"
+)
+
+// adminState is the admin router and the diagnostics it exposes, shared by all routers of
+// an application.
+type adminState struct {
+	once     sync.Once
+	router   *Router
+	root     *Router
+	tracing  bool
+	chains   map[*mux.Route][]string
+	allow    []string
+	auth     []middleware.Middleware
+	profiler *middleware.Profiler
+	cache    *middleware.Caching
+
+	rateLimiters []*middleware.RateLimiter
+
+	honeypot  *middleware.Honeypot
+	blocklist *middleware.IPBlocklist
+
+	mu    sync.RWMutex
+	flags map[string]*middleware.SoftLaunch
+
+	// Boot report, see WithBootReport
+	report    *BootReportOptions
+	modules   []ModuleInfo
+	listeners []string
+	startedAt time.Time
+}
+
+func newAdminState(root *Router) *adminState {
+	return &adminState{
+		root:   root,
+		allow:  []string{"127.0.0.1", "::1"},
+		flags:  map[string]*middleware.SoftLaunch{},
+		chains: map[*mux.Route][]string{},
+	}
+}
+
+// WithAdminAllow sets the IPs and CIDRs allowed to reach the admin router, by default only
+// loopback addresses.
+//
+// Example usage:
+//
+//	r := router.NewRouter(router.WithAdminAllow("10.0.0.0/8"))
+func WithAdminAllow(entries ...string) Option {
+	return func(r *Router) {
+		r.admin.allow = entries
+	}
+}
+
+// WithAdminAuth sets the authentication middleware of the admin endpoints that change the
+// state of the application (maintenance, flags, cache purges, bans and contract resets) and of
+// the admin router mounted with MountAdmin. Those endpoints answer 403 without it, since the IP
+// allowlist alone admits every request forwarded by a reverse proxy on the same host.
+//
+// Example usage:
+//
+//	r := router.NewRouter(router.WithAdminAuth(middleware.NewBearerAuth(cfg.Get("ADMIN_TOKEN", ""))))
+func WithAdminAuth(auth ...middleware.Middleware) Option {
+	return func(r *Router) {
+		r.admin.auth = auth
+	}
+}
+
+// controls returns the admin router for endpoints that change state, behind the middleware
+// of WithAdminAuth, or refusing every request when there is none.
+func (s *adminState) controls(admin *Router) *Router {
+	if len(s.auth) == 0 {
+		return admin.With(middleware.MiddlewareWrapper{HandlerFunc: func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				http.Error(w, "Admin authentication is not configured, see WithAdminAuth", http.StatusForbidden)
+			})
+		}})
+	}
+	return admin.With(s.auth...)
+}
+
+// RouteInfo describes a registered route in the route table of the admin router.
+type RouteInfo struct {
+	Path    string   `json:"path"`
+	Methods []string `json:"methods,omitempty"`
+}
+
+// Admin returns the admin router of the application, for operational endpoints kept out of
+// the public surface. It has its own mux, is only reachable from the allowed IPs (loopback
+// unless WithAdminAllow is given) and serves the following, where the endpoints marked * also
+// require the authentication middleware of WithAdminAuth:
+//
+//	GET  /routes                the route table of the application
+//	GET  /middleware            ?method=GET&path=/users/1, the middleware chain of a route
+//	GET  /maintenance           the maintenance mode state
+//	PUT  /maintenance *         {"enabled": true, "allow": ["10.0.0.0/8"], "retry_after": "15m"}
+//	GET  /config                the configuration passed to ExposeConfig, secrets masked
+//	GET  /boot                  the boot report, see ExposeBootReport
+//	GET  /flags                 the soft launches passed to ExposeSoftLaunch
+//	PUT  /flags/{name} *        {"percent": 50}
+//	GET  /profiler/routes       latency percentiles per route, see WithProfiler
+//	GET  /profiler/slow         the captured slow requests
+//	GET  /cache                 response cache statistics, see ExposeCache
+//	GET  /honeypot              decoy hits and banned IPs, see WithHoneypot
+//	DELETE /honeypot/bans/{ip} * lifts a ban
+//	POST /cache/purge *         purges cached responses by URL, pattern or tag
+//	GET  /websocket             WebSocket hub counters and connections, see ExposeWebSocketHub
+//	GET  /websocket/metrics     the hub counters in the Prometheus text format
+//	GET  /aborted               requests abandoned by their clients, see ExposeAbortCounter
+//	GET  /probes/metrics        dependency health and latency percentiles, see ExposeProber
+//
+// EnableProfiling adds pprof and runtime diagnostics, and ExposeRuntimeControls the settings
+// that can change while serving.
+//
+// Serve it on an internal port with ListenAll or below a path prefix with MountAdmin.
+//
+// Example usage:
+//
+//	App.Admin().Get("/stats", stats)
+//	err := App.ListenAll([]router.ListenerConfig{
+//		{Addr: ":8080"},
+//		{Addr: "127.0.0.1:9090", Handler: App.Admin().Handler()},
+//	})
+func (r *Router) Admin() *Router {
+	r.admin.once.Do(func() {
+		admin := &Router{
+			Mux:         mux.NewRouter(),
+			maintenance: middleware.NewMaintenance(),
+			admin:       r.admin,
+		}
+		admin.Use(middleware.NewIPAllowlist(r.admin.allow...))
+		controls := r.admin.controls(admin)
+		root := r.admin.root
+		admin.Get("/routes", func(ctx *context.Context) {
+			ctx.JSON(http.StatusOK, routeTable(root.Mux))
+		})
+		admin.Get("/middleware", func(ctx *context.Context) {
+			query := ctx.Req.URL.Query()
+			method := query.Get("method")
+			if method == "" {
+				method = http.MethodGet
+			}
+			chain, found := root.MiddlewareChain(method, query.Get("path"))
+			ctx.JSON(http.StatusOK, map[string]interface{}{
+				"route_found": found,
+				"chain":       chain,
+				"warnings":    ChainWarnings(chain),
+			})
+		})
+		admin.Get("/maintenance", func(ctx *context.Context) {
+			ctx.JSON(http.StatusOK, map[string]bool{"enabled": r.maintenance.Enabled()})
+		})
+		controls.Put("/maintenance", func(ctx *context.Context) {
+			setMaintenance(ctx, r.maintenance)
+		})
+		admin.Get("/flags", func(ctx *context.Context) {
+			r.admin.mu.RLock()
+			defer r.admin.mu.RUnlock()
+			flags := make(map[string]int, len(r.admin.flags))
+			for name, launch := range r.admin.flags {
+				flags[name] = launch.Percent()
+			}
+			ctx.JSON(http.StatusOK, flags)
+		})
+		controls.Put("/flags/{name}", func(ctx *context.Context) {
+			name, _ := ctx.GetParam("name")
+			r.admin.mu.RLock()
+			launch, ok := r.admin.flags[name]
+			r.admin.mu.RUnlock()
+			if !ok {
+				ctx.Error(http.StatusNotFound, "Unknown flag")
+				return
+			}
+			var body struct {
+				Percent *int `json:"percent"`
+			}
+			if err := ctx.Body(&body); err != nil || body.Percent == nil {
+				ctx.Error(http.StatusBadRequest, "Expected {\"percent\": 0-100}")
+				return
+			}
+			launch.SetPercent(*body.Percent)
+			ctx.JSON(http.StatusOK, map[string]int{name: launch.Percent()})
+		})
+		admin.Get("/profiler/routes", func(ctx *context.Context) {
+			if r.admin.profiler == nil {
+				ctx.Error(http.StatusNotFound, "Profiler not enabled")
+				return
+			}
+			ctx.JSON(http.StatusOK, r.admin.profiler.Stats())
+		})
+		admin.Get("/profiler/slow", func(ctx *context.Context) {
+			if r.admin.profiler == nil {
+				ctx.Error(http.StatusNotFound, "Profiler not enabled")
+				return
+			}
+			ctx.JSON(http.StatusOK, r.admin.profiler.SlowRequests())
+		})
+		admin.Get("/honeypot", func(ctx *context.Context) {
+			if r.admin.honeypot == nil {
+				ctx.Error(http.StatusNotFound, "Honeypot not enabled")
+				return
+			}
+			stats := map[string]interface{}{"stats": r.admin.honeypot.Stats()}
+			if r.admin.blocklist != nil {
+				stats["bans"] = r.admin.blocklist.Bans()
+			}
+			ctx.JSON(http.StatusOK, stats)
+		})
+		controls.Delete("/honeypot/bans/{ip}", func(ctx *context.Context) {
+			if r.admin.blocklist == nil {
+				ctx.Error(http.StatusNotFound, "Honeypot not enabled")
+				return
+			}
+			ip, _ := ctx.GetParam("ip")
+			r.admin.blocklist.Unban(ip)
+			ctx.JSON(http.StatusOK, map[string]string{"unbanned": ip})
+		})
+		r.admin.router = admin
+	})
+	return r.admin.router
+}
+
+// setMaintenance applies the maintenance settings of the request body.
+func setMaintenance(ctx *context.Context, maintenance *middleware.Maintenance) {
+	var body struct {
+		Enabled    bool     `json:"enabled"`
+		Allow      []string `json:"allow"`
+		RetryAfter string   `json:"retry_after"`
+	}
+	if err := ctx.Body(&body); err != nil {
+		ctx.Error(http.StatusBadRequest, "Invalid maintenance settings")
+		return
+	}
+	var retryAfter time.Duration
+	if body.RetryAfter != "" {
+		var err error
+		if retryAfter, err = time.ParseDuration(body.RetryAfter); err != nil {
+			ctx.Error(http.StatusBadRequest, "Invalid retry_after")
+			return
+		}
+	}
+	maintenance.Set(body.Enabled, body.Allow, retryAfter)
+	ctx.JSON(http.StatusOK, map[string]bool{"enabled": body.Enabled})
+}
+
+// MountAdmin serves the admin router below the path prefix of the public router, e.g. when
+// no separate port is available. The IP restriction of the admin router still applies, and
+// every mounted endpoint requires the authentication middleware of WithAdminAuth, so an error
+// is returned without one.
+//
+// Example usage:
+//
+//	App := LessGo.App(LessGo.WithAdminAuth(middleware.NewBearerAuth(cfg.Get("ADMIN_TOKEN", ""))))
+//	if err := App.MountAdmin("/_admin"); err != nil {
+//		log.Fatal(err)
+//	}
+func (r *Router) MountAdmin(prefix string) error {
+	if len(r.admin.auth) == 0 {
+		return errors.New("mounting the admin router requires an authentication middleware, see WithAdminAuth")
+	}
+	var handler http.Handler = r.Admin().Handler()
+	for i := len(r.admin.auth) - 1; i >= 0; i-- {
+		handler = r.admin.auth[i].Handle(handler)
+	}
+	prefix = "/" + strings.Trim(prefix, "/")
+	r.Mux.PathPrefix(prefix + "/").Handler(http.StripPrefix(prefix, handler))
+	return nil
+}
+
+// ExposeConfig serves the configuration at GET /config of the admin router. Values of keys
+// that look like passwords, secrets, tokens or keys are masked, as are passwords in URLs.
+//
+// Example usage:
+//
+//	App.ExposeConfig(LessGo.LoadConfig())
+func (r *Router) ExposeConfig(cfg config.Config) {
+	r.Admin().Get("/config", func(ctx *context.Context) {
+		ctx.JSON(http.StatusOK, maskConfig(cfg))
+	})
+}
+
+func sensitiveConfigKey(key string) bool {
+	upper := strings.ToUpper(key)
+	for _, word := range []string{"PASSWORD", "PASSWD", "SECRET", "TOKEN", "KEY", "DSN", "CREDENTIAL"} {
+		if strings.Contains(upper, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExposeSoftLaunch lets operators change the percentage of soft launches at runtime through
+// PUT /flags/{name} of the admin router.
+//
+// Example usage:
+//
+//	launch := middleware.NewSoftLaunch(*middleware.NewSoftLaunchOptions("checkout-v2", 10))
+//	App.ExposeSoftLaunch(launch)
+func (r *Router) ExposeSoftLaunch(launches ...*middleware.SoftLaunch) {
+	r.Admin()
+	r.admin.mu.Lock()
+	defer r.admin.mu.Unlock()
+	for _, launch := range launches {
+		r.admin.flags[launch.Name()] = launch
+	}
+}
+
+// Cache returns the response cache enabled with WithCaching, or nil when caching is off.
+//
+// Example usage:
+//
+//	App.Cache().Purge("/products*")
+//	log.Printf("cache hits: %d", App.Cache().Stats().Hits)
+func (r *Router) Cache() *middleware.Caching {
+	return r.admin.cache
+}
+
+// ExposeWebSocketHub serves the counters and the connected clients of hub through the admin
+// router:
+//
+//	GET  /websocket             {"stats": {...}, "connections": [...]}
+//	GET  /websocket/metrics     the counters in the Prometheus text exposition format
+//
+// Example usage:
+//
+//	hub := websocket.NewHub(websocket.WithIdleReaper(websocket.IdlePolicy{Timeout: 10 * time.Minute}))
+//	App.ExposeWebSocketHub(hub)
+func (r *Router) ExposeWebSocketHub(hub *websocket.Hub) {
+	admin := r.Admin()
+	admin.Get("/websocket", func(ctx *context.Context) {
+		ctx.JSON(http.StatusOK, map[string]interface{}{
+			"stats":       hub.Stats(),
+			"connections": hub.Connections(),
+		})
+	})
+	admin.Mux.Handle("/websocket/metrics", hub.MetricsHandler()).Methods(http.MethodGet)
+}
+
+// ExposeAbortCounter serves the requests abandoned by their clients through the admin router:
+//
+//	GET  /aborted               {"requests": 1200, "aborted": 3, "routes": {"GET /reports/{id}": 3}}
+//	GET  /aborted/metrics       the counters in the Prometheus text exposition format
+//
+// Example usage:
+//
+//	aborts := middleware.NewAbortCounter()
+//	App.Use(aborts)
+//	App.ExposeAbortCounter(aborts)
+func (r *Router) ExposeAbortCounter(counter *middleware.AbortCounter) {
+	admin := r.Admin()
+	admin.Get("/aborted", func(ctx *context.Context) {
+		ctx.JSON(http.StatusOK, counter.Stats())
+	})
+	admin.Mux.Handle("/aborted/metrics", counter.MetricsHandler()).Methods(http.MethodGet)
+}
+
+// ExposeProber serves the health and latency percentiles of the targets of prober, by default
+// probe.Default with the dependencies of the framework, through the admin router:
+//
+//	GET  /probes/metrics        health, check counters and percentiles in the Prometheus text format
+//
+// Example usage:
+//
+//	probe.Default().Start(10 * time.Second)
+//	App.ExposeProber(nil)
+func (r *Router) ExposeProber(prober *probe.Prober) {
+	if prober == nil {
+		prober = probe.Default()
+	}
+	r.Admin().Mux.Handle("/probes/metrics", prober.MetricsHandler()).Methods(http.MethodGet)
+}
+
+// ExposeContractRecorder serves the contracts recorded by recorder through the admin router:
+//
+//	GET  /contracts                    the recording, see contract.Recording
+//	GET  /contracts?format=openapi     an OpenAPI document with examples, &title= and &version= set its info
+//	GET  /contracts?format=contract    the examples as a contract fixture, &consumer= names the consumer
+//	POST /contracts/reset *            drops the recorded contracts
+//
+// The reset requires the authentication middleware of WithAdminAuth.
+//
+// Example usage:
+//
+//	recorder := contract.NewRecorder(*contract.NewRecorderOptions())
+//	App.Use(recorder)
+//	App.ExposeContractRecorder(recorder)
+//	// curl 127.0.0.1:9090/contracts?format=openapi > openapi.json
+func (r *Router) ExposeContractRecorder(recorder *contract.Recorder) {
+	admin := r.Admin()
+	admin.Mux.Handle("/contracts", recorder).Methods(http.MethodGet)
+	r.admin.controls(admin).Post("/contracts/reset", func(ctx *context.Context) {
+		recorder.Reset()
+		ctx.JSON(http.StatusOK, map[string]bool{"reset": true})
+	})
+}
+
+// ExposeRequestRecorder serves the requests recorded by recorder through the admin router and
+// replays them against the application:
+//
+//	GET  /requests               the records in memory, the newest first
+//	GET  /requests/{id}          a record
+//	POST /requests/{id}/replay   replays the record, {"headers": {"Authorization": "Bearer ..."}} sets
+//	                             headers redacted when recording; responds with the replay.Result
+//
+// Replays run the whole application, so the endpoints require the authentication middleware
+// auth, on top of the IP allowlist of the admin router, and an error is returned without one.
+//
+// Example usage:
+//
+//	recorder, _ := replay.NewRecorder(*replay.NewOptions())
+//	App.Use(recorder)
+//	err := App.ExposeRequestRecorder(recorder, middleware.NewBearerAuth(cfg.Get("ADMIN_TOKEN", "")))
+func (r *Router) ExposeRequestRecorder(recorder *replay.Recorder, auth ...middleware.Middleware) error {
+	if len(auth) == 0 {
+		return errors.New("the request recorder requires an authentication middleware")
+	}
+	admin := r.Admin().With(auth...)
+	admin.Get("/requests", func(ctx *context.Context) {
+		records := recorder.Records()
+		for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+			records[i], records[j] = records[j], records[i]
+		}
+		ctx.JSON(http.StatusOK, records)
+	})
+	admin.Get("/requests/{id}", func(ctx *context.Context) {
+		id, _ := ctx.GetParam("id")
+		record, ok := recorder.Record(id)
+		if !ok {
+			ctx.Error(http.StatusNotFound, "Unknown request")
+			return
+		}
+		ctx.JSON(http.StatusOK, record)
+	})
+	admin.Post("/requests/{id}/replay", func(ctx *context.Context) {
+		id, _ := ctx.GetParam("id")
+		record, ok := recorder.Record(id)
+		if !ok {
+			ctx.Error(http.StatusNotFound, "Unknown request")
+			return
+		}
+		var body struct {
+			Headers map[string]string `json:"headers"`
+		}
+		if raw, _ := ctx.RawBody(); len(raw) > 0 {
+			if err := ctx.Body(&body); err != nil {
+				ctx.Error(http.StatusBadRequest, "Expected {\"headers\": {...}}")
+				return
+			}
+		}
+		result, err := replay.Replay(r.admin.root.Handler(), record, func(req *http.Request) {
+			for name, value := range body.Headers {
+				req.Header.Set(name, value)
+			}
+		})
+		if err != nil {
+			ctx.Error(http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx.JSON(http.StatusOK, result)
+	})
+	return nil
+}
+
+// ExposeCache serves the statistics of the response cache and lets operators purge cached
+// responses through the admin router, the purge requiring the middleware of WithAdminAuth:
+//
+//	GET  /cache                 hits, misses and the number of cached responses
+//	POST /cache/purge *         {"url": "/products?page=2"}, {"pattern": "/products*"} or {"tag": "product-42"}
+//
+// Example usage:
+//
+//	App.ExposeCache()
+func (r *Router) ExposeCache() {
+	admin := r.Admin()
+	admin.Get("/cache", func(ctx *context.Context) {
+		if r.admin.cache == nil {
+			ctx.Error(http.StatusNotFound, "Caching not enabled")
+			return
+		}
+		ctx.JSON(http.StatusOK, r.admin.cache.StatsContext(ctx.Context()))
+	})
+	r.admin.controls(admin).Post("/cache/purge", func(ctx *context.Context) {
+		if r.admin.cache == nil {
+			ctx.Error(http.StatusNotFound, "Caching not enabled")
+			return
+		}
+		var body struct {
+			URL     string `json:"url"`
+			Pattern string `json:"pattern"`
+			Tag     string `json:"tag"`
+		}
+		if err := ctx.Body(&body); err != nil {
+			ctx.Error(http.StatusBadRequest, "Invalid purge request")
+			return
+		}
+		var purged int
+		var err error
+		switch {
+		case body.Tag != "":
+			purged, err = r.admin.cache.PurgeTagContext(ctx.Context(), body.Tag)
+		case body.Pattern != "":
+			purged, err = r.admin.cache.PurgeContext(ctx.Context(), body.Pattern)
+		case body.URL != "":
+			// Purge the URL for every tenant as well
+			var tenants int
+			if purged, err = r.admin.cache.PurgeContext(ctx.Context(), body.URL); err == nil {
+				tenants, err = r.admin.cache.PurgeContext(ctx.Context(), "tenant:*:"+body.URL)
+				purged += tenants
+			}
+		default:
+			ctx.Error(http.StatusBadRequest, "Expected a url, pattern or tag")
+			return
+		}
+		if err != nil {
+			ctx.Error(http.StatusInternalServerError, "Failed to purge the cache")
+			return
+		}
+		ctx.JSON(http.StatusOK, map[string]int{"purged": purged})
+	})
+}
+
+// routeTable lists the routes registered on the mux, sorted by path.
+func routeTable(m *mux.Router) []RouteInfo {
+	var routes []RouteInfo
+	m.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil || route.GetHandler() == nil {
+			return nil
+		}
+		methods, _ := route.GetMethods()
+		routes = append(routes, RouteInfo{Path: path, Methods: methods})
+		return nil
+	})
+	sort.SliceStable(routes, func(i, j int) bool { return routes[i].Path < routes[j].Path })
+	return routes
+}
@@ -0,0 +1,51 @@
+package router
+
+import (
+	"strings"
+)
+
+// Host creates a router whose routes only match requests for the given host. The pattern
+// may contain variables, which are available through ctx.GetParam like path parameters.
+// A variable matches a single host label, except a trailing variable without a pattern,
+// which matches the rest of the host, so "admin.{domain}" matches admin.example.com.
+// The port of the Host header is ignored unless the pattern contains one.
+//
+// Routes are matched in registration order, so register host routers before routes
+// on the main router that would match the same paths.
+//
+// Example usage:
+//
+//	admin := r.Host("admin.{domain}")
+//	admin.Get("/", dashboard)
+//
+//	tenant := r.Host("{tenant}.example.com")
+//	tenant.Get("/users", func(ctx *context.Context) {
+//		name, _ := ctx.GetParam("tenant")
+//		...
+//	})
+func (r *Router) Host(pattern string, options ...Option) *Router {
+	return r.subRouter(r.Mux.Host(hostTemplate(pattern)).Subrouter(), options...)
+}
+
+// Subdomain creates a router for the given subdomain of any domain, e.g. Subdomain("api")
+// matches api.example.com and api.example.org. The domain is available as the "domain" parameter.
+//
+// Example usage:
+//
+//	api := r.Subdomain("api")
+//	api.Get("/v1/status", status)
+func (r *Router) Subdomain(name string, options ...Option) *Router {
+	return r.Host(name+".{domain}", options...)
+}
+
+// hostTemplate lets a trailing variable without a pattern match the rest of the host.
+func hostTemplate(pattern string) string {
+	if !strings.HasSuffix(pattern, "}") {
+		return pattern
+	}
+	start := strings.LastIndex(pattern, "{")
+	if start < 0 || strings.Contains(pattern[start:], ":") {
+		return pattern
+	}
+	return pattern[:len(pattern)-1] + ":.+}"
+}
@@ -0,0 +1,87 @@
+package router
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/config"
+)
+
+// HTTP3Server serves HTTP/3 on a UDP connection. The *http3.Server of
+// github.com/quic-go/quic-go satisfies it.
+type HTTP3Server interface {
+	Serve(conn net.PacketConn) error
+	Close() error
+}
+
+// HTTP3ServerFunc creates the HTTP/3 server for the handler of the router, with the TLS
+// configuration of the TCP listener.
+type HTTP3ServerFunc func(handler http.Handler, tlsConfig *tls.Config) HTTP3Server
+
+// http3MaxAge is how long clients remember the HTTP/3 endpoint advertised in Alt-Svc.
+const http3MaxAge = 24 * time.Hour
+
+// WithHTTP3 serves the router over HTTP/3 (QUIC) next to HTTP/1.1 and HTTP/2 when Start or
+// StartGraceful serve TLS. The HTTP/3 server listens on the UDP port of the TCP address,
+// with the certificate of the HttpConfig, and TCP responses advertise it to clients in the
+// Alt-Svc header. Support is experimental.
+//
+// Example usage:
+//
+//	r := router.NewRouter(router.WithHTTP3(func(handler http.Handler, tlsConfig *tls.Config) router.HTTP3Server {
+//		return &http3.Server{Handler: handler, TLSConfig: http3.ConfigureTLSConfig(tlsConfig)}
+//	}))
+func WithHTTP3(newServer HTTP3ServerFunc) Option {
+	return func(r *Router) {
+		r.http3 = newServer
+	}
+}
+
+// startHTTP3 starts the HTTP/3 server for addr when it is configured and returns handler
+// advertising it, along with a function stopping the server.
+func (r *Router) startHTTP3(addr string, handler http.Handler, httpConfig *config.HttpConfig) (http.Handler, func(), error) {
+	if r.http3 == nil || httpConfig.TLSCertFile == "" || httpConfig.TLSKeyFile == "" {
+		return handler, func() {}, nil
+	}
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("HTTP/3 needs a TCP address: %w", err)
+	}
+	certificate, err := tls.LoadX509KeyPair(httpConfig.TLSCertFile, httpConfig.TLSKeyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	server := r.http3(handler, &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   tls.VersionTLS13,
+		NextProtos:   []string{"h3"},
+	})
+	go func() {
+		if err := server.Serve(conn); err != nil {
+			log.Printf("HTTP/3 server failed: %v", err)
+		}
+	}()
+	log.Printf("Serving HTTP/3 on udp %s", conn.LocalAddr())
+
+	altSvc := fmt.Sprintf(`h3=":%s"; ma=%d`, port, int(http3MaxAge.Seconds()))
+	advertised := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		// Only clients that came over TCP need to learn about HTTP/3
+		if req.ProtoMajor < 3 {
+			w.Header().Set("Alt-Svc", altSvc)
+		}
+		handler.ServeHTTP(w, req)
+	})
+	stop := func() {
+		server.Close()
+		conn.Close()
+	}
+	return advertised, stop, nil
+}
@@ -0,0 +1,151 @@
+package router
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// TrailingSlashMode defines how requests differing from a route only by a trailing slash are handled.
+type TrailingSlashMode int
+
+const (
+	// TrailingSlashOff treats /users and /users/ as different paths.
+	TrailingSlashOff TrailingSlashMode = iota
+	// TrailingSlashRedirect redirects to the registered form of the path.
+	TrailingSlashRedirect
+	// TrailingSlashStrip serves the registered route without redirecting.
+	TrailingSlashStrip
+)
+
+// PathOptions configures how request paths are normalized before routing.
+type PathOptions struct {
+	TrailingSlash   TrailingSlashMode
+	CollapseSlashes bool // Serve //users//42 as /users/42 instead of redirecting
+	CaseInsensitive bool // Serve /Users/42 as /users/42 when only the lowercase path matches
+}
+
+// NewPathOptions creates path options that redirect trailing slashes, collapse duplicate
+// slashes and keep matching case sensitive.
+func NewPathOptions() *PathOptions {
+	return &PathOptions{TrailingSlash: TrailingSlashRedirect, CollapseSlashes: true}
+}
+
+// WithPathNormalization lets clients with sloppy URLs reach routes instead of getting a 404.
+// Normalization only kicks in when the path as sent does not match a route, so routes
+// registered with a trailing slash or uppercase letters keep working. Paths that only
+// matched after lowercasing also have lowercased path parameters.
+//
+// Example usage:
+//
+//	r := router.NewRouter(router.WithPathNormalization(router.PathOptions{
+//		TrailingSlash:   router.TrailingSlashRedirect,
+//		CollapseSlashes: true,
+//		CaseInsensitive: true,
+//	}))
+func WithPathNormalization(options PathOptions) Option {
+	return func(r *Router) {
+		r.Use(&pathNormalizer{mux: r.Mux, options: options})
+	}
+}
+
+// pathNormalizer rewrites or redirects request paths that do not match a route as sent.
+type pathNormalizer struct {
+	mux     *mux.Router
+	options PathOptions
+}
+
+func (p *pathNormalizer) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		path := req.URL.Path
+		if p.options.CollapseSlashes {
+			path = collapseSlashes(path)
+		}
+		if p.matches(req, path) {
+			next.ServeHTTP(w, withPath(req, path))
+			return
+		}
+
+		candidates := []string{}
+		if p.options.TrailingSlash != TrailingSlashOff && path != "/" {
+			candidates = append(candidates, toggleTrailingSlash(path))
+		}
+		if p.options.CaseInsensitive {
+			lower := strings.ToLower(path)
+			candidates = append(candidates, lower)
+			if p.options.TrailingSlash != TrailingSlashOff && lower != "/" {
+				candidates = append(candidates, toggleTrailingSlash(lower))
+			}
+		}
+
+		for _, candidate := range candidates {
+			if candidate == path || !p.matches(req, candidate) {
+				continue
+			}
+			slashChanged := strings.HasSuffix(candidate, "/") != strings.HasSuffix(path, "/")
+			if slashChanged && p.options.TrailingSlash == TrailingSlashRedirect {
+				redirectPath(w, req, candidate)
+				return
+			}
+			next.ServeHTTP(w, withPath(req, candidate))
+			return
+		}
+		next.ServeHTTP(w, withPath(req, path))
+	})
+}
+
+// matches reports whether a route matches the request with the given path.
+func (p *pathNormalizer) matches(req *http.Request, path string) bool {
+	var match mux.RouteMatch
+	return p.mux.Match(withPath(req, path), &match) && match.MatchErr == nil
+}
+
+// withPath returns a shallow copy of the request with a different URL path.
+func withPath(req *http.Request, path string) *http.Request {
+	if path == req.URL.Path {
+		return req
+	}
+	r := new(http.Request)
+	*r = *req
+	u := *req.URL
+	u.Path = path
+	u.RawPath = ""
+	r.URL = &u
+	return r
+}
+
+func redirectPath(w http.ResponseWriter, req *http.Request, path string) {
+	target := path
+	if req.URL.RawQuery != "" {
+		target += "?" + req.URL.RawQuery
+	}
+	code := http.StatusMovedPermanently
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		// Keep the method and body of non-GET requests
+		code = http.StatusPermanentRedirect
+	}
+	http.Redirect(w, req, target, code)
+}
+
+func toggleTrailingSlash(path string) string {
+	if strings.HasSuffix(path, "/") {
+		return strings.TrimSuffix(path, "/")
+	}
+	return path + "/"
+}
+
+func collapseSlashes(path string) string {
+	if !strings.Contains(path, "//") {
+		return path
+	}
+	var b strings.Builder
+	b.Grow(len(path))
+	for i := 0; i < len(path); i++ {
+		if path[i] == '/' && i > 0 && path[i-1] == '/' {
+			continue
+		}
+		b.WriteByte(path[i])
+	}
+	return b.String()
+}
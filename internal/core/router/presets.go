@@ -0,0 +1,100 @@
+package router
+
+import (
+	"compress/gzip"
+	"strings"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+)
+
+// Environments of the presets.
+const (
+	Production  = "production"
+	Development = "development"
+)
+
+// Preset is the bundle of options of an environment. Nil or zero fields are left out.
+type Preset struct {
+	Recovery      *middleware.RecoveryOptions
+	RequestID     bool
+	Logger        *middleware.LoggerOptions
+	SecureHeaders *middleware.SecureHeadersOptions
+	// Compress gzips responses at the Compression level.
+	Compress    bool
+	Compression int
+	Timeout     time.Duration
+	// Profiling serves pprof on the admin router.
+	Profiling *ProfilingOptions
+}
+
+// NewPreset returns the preset of env. Production gets recovery, request IDs, access logs,
+// security headers, compression and a 30 second deadline on requests; development gets
+// recovery, request IDs, verbose logs and pprof. "dev", "development" and "local" are
+// development, any other environment is production.
+func NewPreset(env string) *Preset {
+	preset := &Preset{
+		Recovery:    middleware.NewRecoveryOptions(),
+		RequestID:   true,
+		Logger:      middleware.NewLoggerOptions(),
+		Compression: gzip.DefaultCompression,
+	}
+	switch strings.ToLower(env) {
+	case "dev", Development, "local":
+		preset.Logger.Verbose = true
+		preset.Logger.SkipPaths = nil
+		preset.Profiling = NewProfilingOptions()
+	default:
+		preset.SecureHeaders = middleware.NewSecureHeadersOptions()
+		preset.Compress = true
+		preset.Timeout = 30 * time.Second
+	}
+	return preset
+}
+
+// Options returns the options of the preset, ordered so that request IDs are assigned
+// first and access logs include the responses of recovered panics.
+func (p *Preset) Options() []Option {
+	var options []Option
+	if p.Profiling != nil {
+		profiling := *p.Profiling
+		options = append(options, func(r *Router) { r.EnableProfiling(profiling) })
+	}
+	// Middleware added later runs earlier
+	if p.Timeout > 0 {
+		options = append(options, WithRequestTimeout(p.Timeout))
+	}
+	if p.Compress {
+		options = append(options, WithCompression(p.Compression, 1024))
+	}
+	if p.SecureHeaders != nil {
+		options = append(options, WithSecureHeaders(*p.SecureHeaders))
+	}
+	if p.Recovery != nil {
+		options = append(options, WithRecovery(*p.Recovery))
+	}
+	if p.Logger != nil {
+		options = append(options, WithLogger(*p.Logger))
+	}
+	if p.RequestID {
+		options = append(options, WithRequestID())
+	}
+	return options
+}
+
+// Defaults returns the options of the preset of env, see NewPreset, after applying the
+// customize functions. Options passed after them add to the preset.
+//
+// Example usage:
+//
+//	options := router.Defaults(os.Getenv("APP_ENV"), func(p *router.Preset) {
+//		p.Timeout = time.Minute
+//	})
+//	r := router.NewRouter(append(options, router.WithCORS(corsOptions))...)
+func Defaults(env string, customize ...func(*Preset)) []Option {
+	preset := NewPreset(env)
+	for _, fn := range customize {
+		fn(preset)
+	}
+	return preset.Options()
+}
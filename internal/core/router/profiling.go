@@ -0,0 +1,132 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/debug"
+	runtimepprof "runtime/pprof"
+	"strings"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+)
+
+// ProfilingOptions defines where the profiling endpoints are served and who may reach them.
+type ProfilingOptions struct {
+	// Prefix of the endpoints on the admin router, /debug by default.
+	Prefix string
+	// Allow further restricts the endpoints to these IPs and CIDRs, on top of the admin
+	// router's own allowlist.
+	Allow []string
+	// Middleware runs before the endpoints, e.g. to require authentication.
+	Middleware []middleware.Middleware
+}
+
+// NewProfilingOptions creates ProfilingOptions serving the endpoints below /debug.
+func NewProfilingOptions() *ProfilingOptions {
+	return &ProfilingOptions{Prefix: "/debug"}
+}
+
+// EnableProfiling serves net/http/pprof and runtime diagnostics on the admin router:
+//
+//	{prefix}/pprof/             the pprof index, profiles, cmdline, symbol and trace
+//	{prefix}/gc                 garbage collector and memory statistics as JSON
+//	{prefix}/goroutines         a dump of all goroutine stacks
+//	{prefix}/build              the Go version, module versions and VCS revision as JSON
+//
+// Example usage:
+//
+//	options := router.NewProfilingOptions()
+//	options.Middleware = []middleware.Middleware{basicAuth}
+//	r.EnableProfiling(*options)
+//	// go tool pprof http://127.0.0.1:9090/debug/pprof/heap
+func (r *Router) EnableProfiling(options ProfilingOptions) {
+	prefix := "/" + strings.Trim(options.Prefix, "/")
+	if prefix == "/" {
+		prefix = "/debug"
+	}
+	mws := options.Middleware
+	if len(options.Allow) > 0 {
+		mws = append([]middleware.Middleware{middleware.NewIPAllowlist(options.Allow...)}, mws...)
+	}
+	wrap := func(handler http.HandlerFunc) http.HandlerFunc {
+		var h http.Handler = handler
+		for i := len(mws) - 1; i >= 0; i-- {
+			h = mws[i].Handle(h)
+		}
+		return withRouteInfo(h.ServeHTTP)
+	}
+	admin := r.Admin().Mux
+	handle := func(path string, handler http.HandlerFunc) {
+		admin.HandleFunc(prefix+path, wrap(handler))
+	}
+
+	handle("/pprof/cmdline", pprof.Cmdline)
+	handle("/pprof/profile", pprof.Profile)
+	handle("/pprof/symbol", pprof.Symbol)
+	handle("/pprof/trace", pprof.Trace)
+	// The index also serves the named profiles, such as heap and goroutine
+	admin.PathPrefix(prefix + "/pprof/").HandlerFunc(wrap(func(w http.ResponseWriter, req *http.Request) {
+		// pprof.Index expects the profiles below /debug/pprof/
+		req.URL.Path = "/debug/pprof/" + strings.TrimPrefix(req.URL.Path, prefix+"/pprof/")
+		pprof.Index(w, req)
+	}))
+	handle("/gc", gcStats)
+	handle("/goroutines", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		runtimepprof.Lookup("goroutine").WriteTo(w, 2)
+	})
+	handle("/build", buildInfo)
+}
+
+func gcStats(w http.ResponseWriter, req *http.Request) {
+	var gc debug.GCStats
+	debug.ReadGCStats(&gc)
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	var lastPause time.Duration
+	if len(gc.Pause) > 0 {
+		lastPause = gc.Pause[0]
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"num_gc":         gc.NumGC,
+		"last_gc":        gc.LastGC,
+		"pause_total_ns": gc.PauseTotal.Nanoseconds(),
+		"last_pause_ns":  lastPause.Nanoseconds(),
+		"heap_alloc":     mem.HeapAlloc,
+		"heap_sys":       mem.HeapSys,
+		"heap_objects":   mem.HeapObjects,
+		"total_alloc":    mem.TotalAlloc,
+		"sys":            mem.Sys,
+		"next_gc":        mem.NextGC,
+		"goroutines":     runtime.NumGoroutine(),
+		"gomaxprocs":     runtime.GOMAXPROCS(0),
+	})
+}
+
+func buildInfo(w http.ResponseWriter, req *http.Request) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		http.Error(w, "Build information not available", http.StatusNotFound)
+		return
+	}
+	settings := make(map[string]string, len(info.Settings))
+	for _, setting := range info.Settings {
+		settings[setting.Key] = setting.Value
+	}
+	deps := make(map[string]string, len(info.Deps))
+	for _, dep := range info.Deps {
+		deps[dep.Path] = dep.Version
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"go_version": info.GoVersion,
+		"path":       info.Path,
+		"version":    info.Main.Version,
+		"settings":   settings,
+		"deps":       deps,
+	})
+}
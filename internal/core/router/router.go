@@ -7,17 +7,31 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 	"path/filepath"
 	"runtime/debug"
-	"strings"
 	"sync"
 	"time"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/gorilla/mux"
+	"github.com/hokamsingh/lessgo/internal/core/analytics"
+	"github.com/hokamsingh/lessgo/internal/core/audit"
 	"github.com/hokamsingh/lessgo/internal/core/config"
 	"github.com/hokamsingh/lessgo/internal/core/context"
+	"github.com/hokamsingh/lessgo/internal/core/enrich"
+	"github.com/hokamsingh/lessgo/internal/core/grace"
+	"github.com/hokamsingh/lessgo/internal/core/grpcweb"
+	"github.com/hokamsingh/lessgo/internal/core/i18n"
+	"github.com/hokamsingh/lessgo/internal/core/logexport"
+	"github.com/hokamsingh/lessgo/internal/core/media"
 	"github.com/hokamsingh/lessgo/internal/core/middleware"
+	"github.com/hokamsingh/lessgo/internal/core/notify"
+	"github.com/hokamsingh/lessgo/internal/core/redact"
+	"github.com/hokamsingh/lessgo/internal/core/reporting"
+	"github.com/hokamsingh/lessgo/internal/core/storage"
+	"github.com/hokamsingh/lessgo/internal/core/tenancy"
+	"github.com/hokamsingh/lessgo/internal/core/upload"
 	"github.com/hokamsingh/lessgo/internal/utils"
 )
 
@@ -25,6 +39,21 @@ import (
 type Router struct {
 	Mux        *mux.Router
 	middleware []middleware.Middleware
+	pipes      []context.Pipe
+
+	// Scoped to routes added through this router, see With and WithGuards
+	routeMiddleware []middleware.Middleware
+	guards          []Guard
+
+	// Shared by all routers of the application, see SetMaintenance, WithTenancy and Admin
+	maintenance *middleware.Maintenance
+	tenancy     *tenancy.Middleware
+	admin       *adminState
+
+	// Started next to the TCP listener by Start and StartGraceful, see WithHTTP3
+	http3 HTTP3ServerFunc
+	// Skips Validate when serving, see WithoutStartupValidation
+	skipValidation bool
 }
 
 // Option is a function that configures a Router.
@@ -79,9 +108,11 @@ func GetApp() *Router {
 //	)
 func NewRouter(options ...Option) *Router {
 	r := &Router{
-		Mux:        mux.NewRouter(),
-		middleware: []middleware.Middleware{},
+		Mux:         mux.NewRouter(),
+		middleware:  []middleware.Middleware{},
+		maintenance: middleware.NewMaintenance(),
 	}
+	r.admin = newAdminState(r)
 	for _, opt := range options {
 		opt(r)
 	}
@@ -98,9 +129,22 @@ func NewRouter(options ...Option) *Router {
 //	subRouter := r.SubRouter("/api")
 //	subRouter.AddRoute("/ping", handler)
 func (r *Router) SubRouter(pathPrefix string, options ...Option) *Router {
+	return r.subRouter(r.Mux.PathPrefix(pathPrefix).Subrouter(), options...)
+}
+
+// subRouter creates a router on the given gorilla subrouter that inherits the middleware,
+// pipes and route scope of r.
+func (r *Router) subRouter(m *mux.Router, options ...Option) *Router {
 	subRouter := &Router{
-		Mux:        r.Mux.PathPrefix(pathPrefix).Subrouter(),
+		Mux:        m,
 		middleware: append([]middleware.Middleware{}, r.middleware...),
+		pipes:      append([]context.Pipe{}, r.pipes...),
+
+		routeMiddleware: append([]middleware.Middleware{}, r.routeMiddleware...),
+		guards:          append([]Guard{}, r.guards...),
+		maintenance:     r.maintenance,
+		tenancy:         r.tenancy,
+		admin:           r.admin,
 	}
 	// Apply options to the subrouter
 	for _, opt := range options {
@@ -144,7 +188,7 @@ const (
 func WithInMemoryRateLimiter(NumShards int, Limit int, Interval time.Duration, CleanupInterval time.Duration) Option {
 	return func(r *Router) {
 		config := middleware.NewInMemoryConfig(NumShards, Limit, Interval, CleanupInterval)
-		rateLimiter := middleware.NewRateLimiter(InMemory, config)
+		rateLimiter := middleware.NewRateLimiter(InMemory, *config)
 		r.Use(rateLimiter)
 	}
 }
@@ -155,7 +199,7 @@ func WithInMemoryRateLimiter(NumShards int, Limit int, Interval time.Duration, C
 // Example usage:
 //
 //	r := router.NewRouter(router.WithRateLimiter(100, time.Minute))
-func WithRedisRateLimiter(client *redis.Client, limit int, interval time.Duration) Option {
+func WithRedisRateLimiter(client redis.UniversalClient, limit int, interval time.Duration) Option {
 	return func(r *Router) {
 		config := middleware.NewRedisConfig(client, limit, interval)
 		rateLimiter := middleware.NewRateLimiter(RedisBacked, config)
@@ -202,9 +246,17 @@ func WithJSONParser(options middleware.ParserOptions) Option {
 //
 // Note: Ensure that the Redis server is running and accessible at the specified
 // address.
-func WithCaching(client *redis.Client, ttl time.Duration, cacheControl bool) Option {
+//
+// Options such as middleware.WithStaleWhileRevalidate keep serving expired responses
+// while they are refreshed in the background:
+//
+//	router := NewRouter(
+//	    WithCaching(client, time.Minute, true, middleware.WithStaleWhileRevalidate(10*time.Minute)),
+//	)
+func WithCaching(client redis.UniversalClient, ttl time.Duration, cacheControl bool, options ...func(*middleware.Caching)) Option {
 	return func(r *Router) {
-		caching := middleware.NewCaching(client, ttl, cacheControl)
+		caching := middleware.NewCaching(client, ttl, cacheControl, options...)
+		r.admin.cache = caching
 		r.Use(caching)
 	}
 }
@@ -225,32 +277,27 @@ func WithCaching(client *redis.Client, ttl time.Duration, cacheControl bool) Opt
 //	    WithCsrf(),
 //	)
 //
-// This will enable CSRF protection for all routes in the router.
-func WithCsrf() Option {
+// This will enable CSRF protection for all routes in the router. Pass
+// middleware.WithCSRFKeyring to sign the tokens.
+func WithCsrf(options ...func(*middleware.CSRFProtection)) Option {
 	return func(r *Router) {
-		csrf := middleware.NewCSRFProtection()
+		csrf := middleware.NewCSRFProtection(options...)
 		r.Use(csrf)
 	}
 }
 
-// WithXss is an option function that enables XSS protection for the router.
+// WithXss rejects requests whose query, form values, cookies or headers contain anything that
+// looks like markup.
 //
-// This function returns an Option that can be passed to the Router to enable
-// Cross-Site Scripting (XSS) protection using a middleware. The middleware
-// helps to sanitize and filter out malicious scripts from user input, thereby
-// preventing XSS attacks.
-//
-// Returns:
-//   - Option: An option that applies XSS protection middleware to the router.
+// Deprecated: the blanket filter rejects legitimate content, such as text mentioning
+// "onclick=", and misses payloads it does not list. Declare the fields that end up in HTML
+// with `sanitize` struct tags, or per route with pipe.Sanitize, instead.
 //
 // Example usage:
 //
-//	router := NewRouter(
-//	    WithXss(),
-//	)
-//
-// This will enable XSS protection for all routes in the router, ensuring that
-// user input is sanitized and secure.
+//	type CommentDTO struct {
+//		Body string `json:"body" sanitize:"ugc"`
+//	}
 func WithXss() Option {
 	return func(r *Router) {
 		xss := middleware.NewXSSProtection()
@@ -272,19 +319,381 @@ func WithCookieParser() Option {
 	}
 }
 
+// WithMethodOverride lets POST requests choose their method through the X-HTTP-Method-Override
+// header or the _method form field. Only PUT, PATCH and DELETE are accepted unless other methods
+// are given. The method is rewritten before routing.
+//
+// Example usage:
+//
+//	r := router.NewRouter(router.WithMethodOverride())
+func WithMethodOverride(methods ...string) Option {
+	return func(r *Router) {
+		r.Use(middleware.NewMethodOverride(methods...))
+	}
+}
+
+// WithIdempotency replays stored responses for POST and PUT requests retried with the same
+// Idempotency-Key header.
+//
+// Example usage:
+//
+//	r := router.NewRouter(router.WithIdempotency(middleware.NewRedisIdempotencyStore(client), 24*time.Hour))
+func WithIdempotency(store middleware.IdempotencyStore, ttl time.Duration) Option {
+	return func(r *Router) {
+		r.Use(middleware.NewIdempotency(store, ttl))
+	}
+}
+
+// WithI18n detects the locale of each request so handlers can translate messages with ctx.T.
+//
+// Example usage:
+//
+//	bundle := i18n.NewBundle("en")
+//	bundle.LoadDir("locales")
+//	r := router.NewRouter(router.WithI18n(bundle))
+func WithI18n(bundle *i18n.Bundle, options ...func(*i18n.Middleware)) Option {
+	return func(r *Router) {
+		r.Use(i18n.NewMiddleware(bundle, options...))
+	}
+}
+
+// WithLocaleSettings sets where ctx.Locale and ctx.Location find user specific settings.
+//
+// Example usage:
+//
+//	r := router.NewRouter(router.WithLocaleSettings(func(req *http.Request) (string, string) {
+//		user := auth.User(req)
+//		return user.Locale, user.TimeZone
+//	}))
+func WithLocaleSettings(settings context.LocaleSettings) Option {
+	return func(r *Router) {
+		context.SetLocaleSettings(settings)
+	}
+}
+
+// WithCookieKeys sets the secrets of ctx.SetSignedCookie and ctx.SetEncryptedCookie, newest
+// first. Rotate by prepending a new key and dropping the oldest one later.
+//
+// Example usage:
+//
+//	r := router.NewRouter(router.WithCookieKeys(cfg.GetKeys("COOKIE_KEYS")...))
+func WithCookieKeys(keys ...[]byte) Option {
+	return func(r *Router) {
+		context.SetCookieKeys(keys...)
+	}
+}
+
+// WithRecovery turns panics into error responses, logging their stack trace and passing
+// them to the reporter of the options. Client disconnects are not treated as crashes.
+// Add it after the other options so it also covers their middlewares.
+//
+// Example usage:
+//
+//	options := middleware.NewRecoveryOptions()
+//	options.Reporter = func(r *http.Request, err error, stack []byte) { sentry.CaptureException(err) }
+//	r := router.NewRouter(router.WithCORS(corsOptions), router.WithRecovery(*options))
+func WithRecovery(options middleware.RecoveryOptions) Option {
+	return func(r *Router) {
+		if options.Reporter == nil {
+			// Report to the reporter set with WithErrorReporter, if any
+			options.Reporter = reporting.PanicHook(nil)
+		}
+		r.Use(middleware.NewRecovery(options))
+	}
+}
+
+// WithRequestID gives every request an ID, taken from the X-Request-ID header set by a proxy
+// or generated, and echoes it in the response. Logs, audit entries and error reports pick it
+// up from the request.
+//
+// Example usage:
+//
+//	r := router.NewRouter(router.WithRequestID())
+func WithRequestID(options ...func(*middleware.RequestIDMiddleware)) Option {
+	return func(r *Router) {
+		r.Use(middleware.NewRequestID(options...))
+	}
+}
+
+// WithLogger logs every request with its status, size, duration and request ID.
+//
+// Example usage:
+//
+//	r := router.NewRouter(router.WithLogger(*middleware.NewLoggerOptions()))
+func WithLogger(options middleware.LoggerOptions) Option {
+	return func(r *Router) {
+		r.Use(middleware.NewLogger(options))
+	}
+}
+
+// WithSecureHeaders sets security headers such as X-Content-Type-Options, X-Frame-Options
+// and, over TLS, Strict-Transport-Security on every response.
+//
+// Example usage:
+//
+//	r := router.NewRouter(router.WithSecureHeaders(*middleware.NewSecureHeadersOptions()))
+func WithSecureHeaders(options middleware.SecureHeadersOptions) Option {
+	return func(r *Router) {
+		r.Use(middleware.NewSecureHeaders(options))
+	}
+}
+
+// WithCompression gzips text, JSON and other compressible responses at the given level for
+// clients accepting it. Responses declaring a Content-Length below minSize stay uncompressed.
+//
+// Example usage:
+//
+//	r := router.NewRouter(router.WithCompression(gzip.DefaultCompression, 1024))
+func WithCompression(level, minSize int) Option {
+	return func(r *Router) {
+		r.Use(middleware.NewCompress(level, minSize))
+	}
+}
+
+// WithRequestTimeout sets a deadline on the context of every request, except WebSocket
+// upgrades and event streams.
+//
+// Example usage:
+//
+//	r := router.NewRouter(router.WithRequestTimeout(30 * time.Second))
+func WithRequestTimeout(timeout time.Duration) Option {
+	return func(r *Router) {
+		r.Use(middleware.NewDeadline(timeout))
+	}
+}
+
+// WithErrorReporter sends panics caught by WithRecovery and 5xx responses of ctx.Error to
+// an error tracking service, with the route, user and request ID of the request.
+//
+// Example usage:
+//
+//	sentry, _ := reporting.NewSentry(dsn, reporting.WithEnvironment("production"))
+//	r := router.NewRouter(router.WithErrorReporter(sentry), router.WithRecovery(*middleware.NewRecoveryOptions()))
+func WithErrorReporter(reporter reporting.Reporter) Option {
+	return func(r *Router) {
+		reporting.SetReporter(reporter)
+	}
+}
+
+// WithMiddleware adds middlewares that run in the given order, the first one first, e.g. a
+// pipeline loaded from configuration.
+//
+// Example usage:
+//
+//	mws, err := pipeline.NewRegistry().LoadFile("middleware.yaml")
+//	r := router.NewRouter(router.WithMiddleware(mws...))
+func WithMiddleware(mws ...middleware.Middleware) Option {
+	return func(r *Router) {
+		// The last middleware added runs first
+		for i := len(mws) - 1; i >= 0; i-- {
+			r.Use(mws[i])
+		}
+	}
+}
+
+// WithConcurrencyLimit bounds the requests handled at the same time to n. Up to queueSize
+// requests wait at most timeout for a slot, the others get a 503. Routes with their own limit
+// use r.With(middleware.NewConcurrencyLimit(...)).
+//
+// Example usage:
+//
+//	r := router.NewRouter(router.WithConcurrencyLimit(200, 100, 2*time.Second))
+//	reports := r.With(middleware.NewConcurrencyLimit(4, 10, 5*time.Second))
+func WithConcurrencyLimit(n, queueSize int, timeout time.Duration) Option {
+	return func(r *Router) {
+		r.Use(middleware.NewConcurrencyLimit(n, queueSize, timeout))
+	}
+}
+
+// WithLoadShedding rejects requests by priority as the p99 latency, goroutine count or heap
+// size approach the limits of the options, low priority requests first.
+//
+// Example usage:
+//
+//	options := middleware.NewLoadShedderOptions(500*time.Millisecond, 10000, 2<<30)
+//	options.RoutePriorities = map[string]middleware.Priority{"/health": middleware.PriorityCritical, "/reports/": middleware.PriorityLow}
+//	r := router.NewRouter(router.WithLoadShedding(*options))
+func WithLoadShedding(options middleware.LoadShedderOptions) Option {
+	return func(r *Router) {
+		r.Use(middleware.NewLoadShedder(options))
+	}
+}
+
+// WithProfiler records the latency percentiles of every route and captures the headers and
+// per-middleware timings of requests slower than the threshold of the profiler. The results
+// are served at /profiler/routes and /profiler/slow of the admin router.
+//
+// Example usage:
+//
+//	r := router.NewRouter(router.WithProfiler(middleware.NewProfiler(*middleware.NewProfilerOptions(time.Second))))
+func WithProfiler(profiler *middleware.Profiler) Option {
+	return func(r *Router) {
+		r.admin.profiler = profiler
+	}
+}
+
+// WithHoneypot registers decoy paths such as /wp-login.php and /.env. Clients requesting
+// them are banned through the blocklist of the options, which is placed in front of every
+// route, and their connection is held open. Hits and bans are served at /honeypot of the
+// admin router.
+//
+// Example usage:
+//
+//	blocklist := middleware.NewIPBlocklist()
+//	options := middleware.NewHoneypotOptions(blocklist, 24*time.Hour)
+//	options.OnTrigger = func(event middleware.HoneypotEvent) { alerts.Publish("scanner", event) }
+//	r := router.NewRouter(router.WithHoneypot(*options))
+func WithHoneypot(options middleware.HoneypotOptions) Option {
+	return func(r *Router) {
+		if options.Blocklist != nil {
+			r.Use(options.Blocklist)
+		}
+		r.admin.honeypot = middleware.NewHoneypot(options)
+		r.admin.blocklist = options.Blocklist
+		r.Use(r.admin.honeypot)
+	}
+}
+
+// WithQuota limits the requests per day or month of each API key, tenant or client according
+// to their plan. Routes with their own quota use r.With(middleware.NewQuota(...)) with a Scope.
+//
+// Example usage:
+//
+//	options := middleware.NewQuotaOptions(10000, middleware.Monthly)
+//	r := router.NewRouter(router.WithQuota(middleware.NewRedisQuotaStore(client), *options))
+func WithQuota(store middleware.QuotaStore, options middleware.QuotaOptions) Option {
+	return func(r *Router) {
+		r.Use(middleware.NewQuota(store, options))
+	}
+}
+
+// WithRedaction sets the names of the headers, body fields and query parameters masked in
+// access logs, audit logs and error reports, in addition to the default credential names.
+//
+// Example usage:
+//
+//	r := router.NewRouter(router.WithRedaction(redact.WithFields("iban"), redact.WithQueryParams("invite")))
+func WithRedaction(options ...redact.Option) Option {
+	return func(r *Router) {
+		redact.SetDefault(redact.New(options...))
+	}
+}
+
+// WithAudit records the actor, route, resource IDs, status and payload hash of every
+// mutating request to the audit sink.
+//
+// Example usage:
+//
+//	file, _ := audit.NewFileSink("/var/log/app/audit.ndjson")
+//	r := router.NewRouter(router.WithAudit(file, audit.WithRedactedFields("iban")))
+func WithAudit(sink audit.Sink, options ...func(*audit.Middleware)) Option {
+	return func(r *Router) {
+		r.Use(audit.New(sink, options...))
+	}
+}
+
+// WithTenancy resolves the tenant of every request before the other middlewares run, so
+// ctx.Tenant returns it and cached responses are kept per tenant. Rate limits stay per client
+// across tenants.
+//
+// Resolvers reading what other middlewares store, such as tenancy.FromClaim, find nothing
+// at that point. Their tenant is resolved when first asked for, by a cache or the handler,
+// once the authentication middleware stored the claims, so caches running after it still
+// key their entries by tenant. Missing, malformed and unknown tenants are then rejected right
+// before the route handler, after the middlewares of sub-routers.
+//
+// Example usage:
+//
+//	r := router.NewRouter(router.WithTenancy(tenancy.FromSubdomain("example.com"), tenancy.WithRequired()))
+//	api := router.NewRouter(router.WithTenancy(tenancy.FromClaim("tid"), tenancy.WithRequired()))
+//	api.Use(jwtMiddleware)
+func WithTenancy(resolver tenancy.Resolver, options ...func(*tenancy.Middleware)) Option {
+	return func(r *Router) {
+		r.tenancy = tenancy.New(resolver, options...)
+	}
+}
+
+// WithEnrichment parses the user agent of every request and locates its client with the
+// GeoProvider of the options, for ctx.UserAgentInfo, ctx.Geo and the access log export.
+//
+// Example usage:
+//
+//	r := router.NewRouter(router.WithEnrichment(enrich.WithGeoProvider(ranges), enrich.WithCDNHeaders()))
+func WithEnrichment(options ...func(*enrich.Middleware)) Option {
+	return func(r *Router) {
+		r.Use(enrich.New(options...))
+	}
+}
+
+// WithAnalytics makes the tracker available to ctx.Track in every handler. Close the
+// tracker on shutdown to write the buffered events.
+//
+// Example usage:
+//
+//	tracker := analytics.New(analytics.NewHTTPSink("https://api.segment.io/v1/batch", writeKey))
+//	defer tracker.Close()
+//	r := router.NewRouter(router.WithAnalytics(tracker))
+func WithAnalytics(tracker *analytics.Tracker) Option {
+	return func(r *Router) {
+		r.Use(tracker)
+	}
+}
+
+// WithGRPCWeb makes the services of the gRPC server callable from browsers: gRPC-Web and
+// Connect requests are translated to gRPC calls to grpcServer, any http.Handler speaking
+// gRPC such as *grpc.Server, and other requests are routed as usual.
+//
+// Example usage:
+//
+//	server := grpc.NewServer()
+//	pb.RegisterGreeterServer(server, &greeter{})
+//	r := router.NewRouter(router.WithGRPCWeb(server))
+func WithGRPCWeb(grpcServer http.Handler, options ...func(*grpcweb.Translator)) Option {
+	return func(r *Router) {
+		r.Use(grpcweb.New(grpcServer, options...))
+	}
+}
+
+// WithBodyCapture keeps request bodies up to maxSize bytes in memory, so the JSON parser,
+// idempotency keys, pipes and ctx.Body share one copy and ctx.RawBody returns the bytes as
+// received. A maxSize of 0 uses middleware.DefaultBodyCaptureSize.
+//
+// Example usage:
+//
+//	r := router.NewRouter(router.WithBodyCapture(512 << 10))
+func WithBodyCapture(maxSize int64) Option {
+	return func(r *Router) {
+		r.Use(middleware.NewBodyCapture(maxSize))
+	}
+}
+
 // WithFileUpload enables file upload middleware with the specified upload directory.
-// This option configures the router to handle file uploads and save them to the given directory.
+// This option configures the router to handle file uploads and save them to the given directory,
+// or to the storage configured with WithStorage.
 //
 // Example usage:
 //
 //	r := router.NewRouter(router.WithFileUpload("/uploads"))
-func WithFileUpload(uploadDir string, maxFileSize int64, allowedExts []string) Option {
+func WithFileUpload(uploadDir string, maxFileSize int64, allowedExts []string, options ...func(*middleware.FileUploadMiddleware)) Option {
 	return func(r *Router) {
-		fileUploadMiddleware := middleware.NewFileUploadMiddleware(uploadDir, maxFileSize, allowedExts)
+		fileUploadMiddleware := middleware.NewFileUploadMiddleware(uploadDir, maxFileSize, allowedExts, options...)
 		r.Use(fileUploadMiddleware)
 	}
 }
 
+// WithStorage makes s the storage of uploaded files, used by the file upload middleware and
+// ctx.SaveUpload. Add it before WithFileUpload.
+//
+// Example usage:
+//
+//	store := storage.NewS3Storage(storage.S3Config{Region: "eu-west-1", Bucket: "uploads"})
+//	r := router.NewRouter(router.WithStorage(store))
+func WithStorage(s storage.Storage) Option {
+	return func(r *Router) {
+		r.Use(storage.NewMiddleware(s))
+	}
+}
+
 // WithTemplateRendering sets up the router to use the TemplateMiddleware for rendering HTML templates.
 // It automatically loads all `.html` files from the specified directory and makes them available
 // for rendering within the application's handlers.
@@ -320,6 +729,100 @@ func WithTemplateRendering(templateDir string) Option {
 	}
 }
 
+// WithSecureTemplateRendering is WithTemplateRendering sending a Content-Security-Policy and
+// X-XSS-Protection header with every rendered page.
+//
+// Example usage:
+//
+//	options := middleware.NewTemplateSecurityOptions()
+//	options.ContentSecurityPolicy += "; img-src 'self' https://cdn.example.com"
+//	r := router.NewRouter(router.WithSecureTemplateRendering("templates", *options))
+func WithSecureTemplateRendering(templateDir string, options middleware.TemplateSecurityOptions) Option {
+	return func(r *Router) {
+		renderer := middleware.NewTemplateMiddleware(templateDir, middleware.WithTemplateSecurity(options))
+		r.Use(renderer)
+	}
+}
+
+// WithTrustedHeaderAuth enables authentication from identity headers set by an SSO proxy.
+// Headers such as X-Forwarded-User are only trusted when the request comes from one of the
+// configured proxies, and the resulting principal is available through ctx.Principal().
+//
+// Example usage:
+//
+//	r := router.NewRouter(
+//	    router.WithTrustedHeaderAuth(*middleware.NewTrustedHeaderOptions([]string{"10.0.0.0/8"})),
+//	)
+func WithTrustedHeaderAuth(options middleware.TrustedHeaderOptions) Option {
+	return func(r *Router) {
+		auth := middleware.NewTrustedHeaderAuth(options)
+		r.Use(auth)
+	}
+}
+
+// WithTrustedProxies sets the reverse proxies whose forwarding headers ctx.ClientIP honors.
+// The rate limiter and the request log export key on the resolved client IP.
+//
+// Example usage:
+//
+//	r := router.NewRouter(router.WithTrustedProxies("10.0.0.0/8", "127.0.0.1"))
+func WithTrustedProxies(proxies ...string) Option {
+	return func(r *Router) {
+		middleware.SetTrustedProxies(proxies...)
+	}
+}
+
+// WithDefaultContentType sets the content type ctx.Negotiate falls back to when the
+// request has no Accept header or accepts none of the registered renderers.
+//
+// Example usage:
+//
+//	r := router.NewRouter(router.WithDefaultContentType("application/xml"))
+func WithDefaultContentType(contentType string) Option {
+	return func(r *Router) {
+		context.SetDefaultContentType(contentType)
+	}
+}
+
+// WithPrettyJSON sets when JSON responses are indented. Use context.PrettyJSONQuery in
+// development to allow ?pretty=1, and leave the default context.PrettyJSONOff in production.
+//
+// Example usage:
+//
+//	r := router.NewRouter(router.WithPrettyJSON(context.PrettyJSONQuery))
+func WithPrettyJSON(mode context.PrettyJSONMode) Option {
+	return func(r *Router) {
+		context.SetPrettyJSON(mode)
+	}
+}
+
+// WithResponseEnvelope configures the envelope used by ctx.OK, ctx.Created and ctx.Fail.
+//
+// Example usage:
+//
+//	options := context.NewEnvelopeOptions()
+//	options.RequestIDHeader = "X-Request-ID"
+//	r := router.NewRouter(router.WithResponseEnvelope(*options))
+func WithResponseEnvelope(options context.EnvelopeOptions) Option {
+	return func(r *Router) {
+		context.SetEnvelopeOptions(options)
+	}
+}
+
+// WithRequestLogExport records every request as a structured access record and ships the
+// records in batches to object storage through the given exporter.
+//
+// Example usage:
+//
+//	exporter := logexport.NewExporter(logexport.NewFileStore("/var/log/export"))
+//	exporter.Start(time.Minute)
+//	r := router.NewRouter(router.WithRequestLogExport(exporter))
+func WithRequestLogExport(exporter *logexport.Exporter) Option {
+	return func(r *Router) {
+		r.Use(exporter)
+	}
+}
+
 // Use adds a middleware to the router's middleware stack.
 //
 // Example usage:
@@ -327,6 +830,21 @@ func WithTemplateRendering(templateDir string) Option {
 //	r.Use(middleware.LoggingMiddleware{})
 func (r *Router) Use(m middleware.Middleware) {
 	r.middleware = append(r.middleware, m)
+	// Rate limits can be changed at runtime, see ExposeRuntimeControls
+	if limiter, ok := m.(*middleware.RateLimiter); ok && r.admin != nil {
+		r.admin.rateLimiters = append(r.admin.rateLimiters, limiter)
+	}
+}
+
+// UsePipe adds pipes that run around every route handler. Pipes transform and validate
+// input before the handler runs and post-process the data passed to ctx.JSON afterwards.
+// Per-route pipes are added with context.WithPipes.
+//
+// Example usage:
+//
+//	r.UsePipe(pipe.Trim(), pipe.SnakeCase())
+func (r *Router) UsePipe(pipes ...context.Pipe) {
+	r.pipes = append(r.pipes, pipes...)
 }
 
 // AddRoute adds a route with the given path and handler function.
@@ -338,40 +856,151 @@ func (r *Router) Use(m middleware.Middleware) {
 //		ctx.JSON(http.StatusOK, map[string]string{"message": "pong"})
 //	})
 func (r *Router) AddRoute(path string, handler CustomHandler) {
+	r.addRoute(path, handler)
+}
+
+// addRoute registers the handler, matching only the given methods when there are any, so
+// handlers for other methods on the same path are reachable.
+func (r *Router) addRoute(path string, handler CustomHandler, methods ...string) {
 	utils.Assert(path[0] == '/', "path must begin with '/'")
 	// Create an HTTP handler function that uses the custom context
-	handlerFunc := WrapCustomHandler(handler)
+	handlerFunc := WrapCustomHandler(r.withGuards(r.withPipes(handler)))
+	if r.tenancy != nil {
+		handlerFunc = r.tenancy.Require(handlerFunc).ServeHTTP
+	}
+	// Apply middleware scoped to this router, the first one runs first
+	for i := len(r.routeMiddleware) - 1; i >= 0; i-- {
+		handlerFunc = r.routeMiddleware[i].Handle(handlerFunc).ServeHTTP
+	}
 	// Wrap the handler function with error handling and logging
 	handlerFunc = r.withErrorHandling(handlerFunc)
 	handlerFunc = r.withLogging(handlerFunc)
-	r.Mux.HandleFunc(path, handlerFunc)
+	route := r.Mux.HandleFunc(path, withRouteInfo(handlerFunc))
+	if len(methods) > 0 {
+		route.Methods(methods...)
+	}
+	r.recordChain(route)
+}
+
+// withRouteInfo stores the matched route template and path variables in the request's value
+// store, where middlewares running outside the mux, such as audit logging, find them.
+func withRouteInfo(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		req, values := middleware.RequestValues(req)
+		if route := mux.CurrentRoute(req); route != nil {
+			if template, err := route.GetPathTemplate(); err == nil {
+				values.Set(middleware.RouteKey, template)
+			}
+		}
+		values.Set(middleware.RouteVarsKey, mux.Vars(req))
+		next(w, req)
+	}
+}
+
+// Guard decides whether a request may reach a route. Requests rejected by a guard get a 403.
+type Guard func(ctx *context.Context) bool
+
+// With returns a router that registers routes on the same mux, wrapping each of them with the
+// given middleware. Unlike Use, the middleware only applies to routes added through the returned router.
+//
+// Example usage:
+//
+//	admin := r.With(authMiddleware, auditMiddleware)
+//	admin.Get("/admin/users", listUsers)
+func (r *Router) With(mws ...middleware.Middleware) *Router {
+	scoped := r.scoped()
+	scoped.routeMiddleware = append(scoped.routeMiddleware, mws...)
+	return scoped
+}
+
+// WithGuards returns a router whose routes are only reachable when all guards pass.
+//
+// Example usage:
+//
+//	admin := r.WithGuards(func(ctx *context.Context) bool {
+//		principal, ok := ctx.Principal()
+//		return ok && principal.HasGroup("admins")
+//	})
+func (r *Router) WithGuards(guards ...Guard) *Router {
+	scoped := r.scoped()
+	scoped.guards = append(scoped.guards, guards...)
+	return scoped
+}
+
+// WithEarlyHints returns a router whose GET routes send the links in a 103 Early Hints
+// response before the handler runs, so the client preloads them while the page is rendered.
+//
+// Example usage:
+//
+//	r.WithEarlyHints(middleware.Link{URL: "/static/app.css", As: "style"}).Get("/", home)
+func (r *Router) WithEarlyHints(links ...middleware.Link) *Router {
+	return r.With(middleware.NewEarlyHints(links))
+}
+
+// WithPipes returns a router whose routes run the given pipes in addition to the global ones.
+func (r *Router) WithPipes(pipes ...context.Pipe) *Router {
+	scoped := r.scoped()
+	scoped.pipes = append(scoped.pipes, pipes...)
+	return scoped
+}
+
+// scoped copies the router so route scoped settings can be added without affecting r.
+func (r *Router) scoped() *Router {
+	return &Router{
+		Mux:             r.Mux,
+		middleware:      r.middleware,
+		pipes:           append([]context.Pipe{}, r.pipes...),
+		routeMiddleware: append([]middleware.Middleware{}, r.routeMiddleware...),
+		guards:          append([]Guard{}, r.guards...),
+		maintenance:     r.maintenance,
+		tenancy:         r.tenancy,
+		admin:           r.admin,
+		http3:           r.http3,
+		skipValidation:  r.skipValidation,
+	}
+}
+
+// withGuards rejects requests that do not pass all guards of the router.
+func (r *Router) withGuards(handler CustomHandler) CustomHandler {
+	if len(r.guards) == 0 {
+		return handler
+	}
+	guards := r.guards
+	return func(ctx *context.Context) {
+		for _, guard := range guards {
+			if !guard(ctx) {
+				ctx.Error(http.StatusForbidden, "Forbidden")
+				return
+			}
+		}
+		handler(ctx)
+	}
 }
 
 // Start starts the HTTP server on the specified address.
 // It applies all middleware and listens for incoming requests.
+// The address is a TCP address, a unix socket such as unix:///var/run/app.sock, or
+// systemd / systemd:name for a socket passed by systemd socket activation.
 //
 // Example usage:
 //
-//	err := r.Start(":8080")
+//	err := r.Start(":8080", httpConfig)
 //	if err != nil {
 //		log.Fatalf("Server failed: %v", err)
 //	}
 func (r *Router) Start(addr string, httpConfig *config.HttpConfig) error {
-	// Apply middlewares
-	finalHandler := http.Handler(r.Mux)
-	for _, m := range r.middleware {
-		finalHandler = m.Handle(finalHandler)
+	if err := r.validateStartup(); err != nil {
+		log.Fatalf("HTTP server failed: %v", err)
+		return err
 	}
-
-	server := &http.Server{
-		Addr:         addr,
-		Handler:      finalHandler,
-		ReadTimeout:  time.Duration(httpConfig.ReadTimeout) * time.Second,  // Set read timeout
-		WriteTimeout: time.Duration(httpConfig.WriteTimeout) * time.Second, // Set write timeout
-		IdleTimeout:  time.Duration(httpConfig.IdleTimeout) * time.Second,  // Set idle timeout
-		// Set maximum header size
-		MaxHeaderBytes: httpConfig.MaxHeaderSize,
+	finalHandler, stopHTTP3, err := r.startHTTP3(addr, r.Handler(), httpConfig)
+	if err != nil {
+		log.Fatalf("HTTP/3 server failed: %v", err)
+		return err
 	}
+	defer stopHTTP3()
+	r.recordListeners(listenerName(addr, httpConfig, r.http3 != nil))
+	server := newServer(addr, finalHandler, httpConfig)
 	// Configure TLS if certificates are provided
 	if httpConfig.TLSCertFile != "" && httpConfig.TLSKeyFile != "" {
 		server.TLSConfig = &tls.Config{
@@ -387,7 +1016,10 @@ func (r *Router) Start(addr string, httpConfig *config.HttpConfig) error {
 		}
 
 		// Start HTTPS server with TLS
-		err := server.ListenAndServeTLS(httpConfig.TLSCertFile, httpConfig.TLSKeyFile)
+		listener, err := grace.Listen(addr, grace.ListenOptions{SocketMode: httpConfig.SocketMode})
+		if err == nil {
+			err = server.ServeTLS(listener, httpConfig.TLSCertFile, httpConfig.TLSKeyFile)
+		}
 		if err != nil {
 			log.Fatalf("HTTPS server failed: %v", err)
 		}
@@ -395,13 +1027,170 @@ func (r *Router) Start(addr string, httpConfig *config.HttpConfig) error {
 	}
 
 	// Start HTTP server if TLS is not configured
-	err := server.ListenAndServe()
+	listener, err := grace.Listen(addr, grace.ListenOptions{SocketMode: httpConfig.SocketMode})
+	if err == nil {
+		err = server.Serve(listener)
+	}
 	if err != nil {
 		log.Fatalf("HTTP server failed: %v", err)
 	}
 	return err
 }
 
+// StartGraceful serves like Start, but restarts without closing the listener: on SIGHUP a new
+// process of the binary takes over the socket and this one exits once its in-flight requests
+// have finished. SIGINT and SIGTERM shut down gracefully.
+//
+// Example usage:
+//
+//	err := r.StartGraceful(":8080", httpConfig, grace.WithShutdownTimeout(time.Minute))
+func (r *Router) StartGraceful(addr string, httpConfig *config.HttpConfig, options ...func(*grace.App)) error {
+	if err := r.validateStartup(); err != nil {
+		return err
+	}
+	app := grace.New(append([]func(*grace.App){grace.WithSocketMode(httpConfig.SocketMode)}, options...)...)
+	handler, stopHTTP3, err := r.startHTTP3(addr, r.Handler(), httpConfig)
+	if err != nil {
+		return err
+	}
+	// HTTP/3 connections are not handed over on restart, clients fall back to TCP meanwhile
+	defer stopHTTP3()
+	r.recordListeners(listenerName(addr, httpConfig, r.http3 != nil))
+	server := newServer(addr, handler, httpConfig)
+	if httpConfig.TLSCertFile != "" && httpConfig.TLSKeyFile != "" {
+		server.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		app.ServeTLS(server, httpConfig.TLSCertFile, httpConfig.TLSKeyFile)
+	} else {
+		app.Serve(server)
+	}
+	if grace.Inherited() {
+		log.Printf("Took over listener on %s from previous process", addr)
+	}
+	return app.Run()
+}
+
+// ListenerConfig defines one of the addresses served by ListenAll.
+type ListenerConfig struct {
+	// Addr is a TCP address, unix:///path or systemd[:name], as accepted by Start.
+	Addr string
+	// Handler serves the listener, e.g. the Handler of a separate admin router. Nil serves
+	// this router with its middleware.
+	Handler http.Handler
+	// Config sets the timeouts and, with TLSCertFile and TLSKeyFile, TLS of the listener.
+	// Nil uses the defaults of config.NewHttpConfig.
+	Config *config.HttpConfig
+}
+
+// ListenAll serves the router, or the handler of each listener, on several addresses at once.
+// All listeners are restarted together on SIGHUP and shut down in a single sequence on SIGINT
+// and SIGTERM, like StartGraceful.
+//
+// Example usage:
+//
+//	admin := router.NewRouter()
+//	err := r.ListenAll([]router.ListenerConfig{
+//		{Addr: ":8080"},
+//		{Addr: ":8443", Config: config.NewHttpConfig(config.WithTLSCertFile("cert.pem"), config.WithTLSKeyFile("key.pem"))},
+//		{Addr: "127.0.0.1:9090", Handler: admin.Handler()},
+//	})
+func (r *Router) ListenAll(listeners []ListenerConfig, options ...func(*grace.App)) error {
+	if err := r.validateStartup(); err != nil {
+		return err
+	}
+	app := grace.New(options...)
+	handler := r.Handler()
+	addrs := make([]string, len(listeners))
+	for i, listener := range listeners {
+		addrs[i] = listenerName(listener.Addr, listener.Config, false)
+	}
+	r.recordListeners(addrs...)
+	for _, listener := range listeners {
+		httpConfig := listener.Config
+		if httpConfig == nil {
+			httpConfig = config.NewHttpConfig()
+		}
+		serverHandler := listener.Handler
+		if serverHandler == nil {
+			serverHandler = handler
+		}
+		server := newServer(listener.Addr, serverHandler, httpConfig)
+		if httpConfig.TLSCertFile != "" && httpConfig.TLSKeyFile != "" {
+			server.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+			app.ServeTLS(server, httpConfig.TLSCertFile, httpConfig.TLSKeyFile)
+		} else {
+			app.Serve(server)
+		}
+		log.Printf("Listening on %s", listener.Addr)
+	}
+	return app.Run()
+}
+
+// newServer creates the http.Server for the handler with the timeouts of the config.
+func newServer(addr string, handler http.Handler, httpConfig *config.HttpConfig) *http.Server {
+	return &http.Server{
+		Addr:         addr,
+		Handler:      handler,
+		ReadTimeout:  time.Duration(httpConfig.ReadTimeout) * time.Second,  // Set read timeout
+		WriteTimeout: time.Duration(httpConfig.WriteTimeout) * time.Second, // Set write timeout
+		IdleTimeout:  time.Duration(httpConfig.IdleTimeout) * time.Second,  // Set idle timeout
+		// Set maximum header size
+		MaxHeaderBytes: httpConfig.MaxHeaderSize,
+	}
+}
+
+// Handler returns the router's mux wrapped with its middleware, as served by Start.
+// It can be used to serve the app from a custom http.Server or in tests.
+//
+// Example usage:
+//
+//	server := &http.Server{Addr: ":8080", Handler: r.Handler()}
+func (r *Router) Handler() http.Handler {
+	handler := http.Handler(r.Mux)
+	profiler, tracing := r.admin.profiler, r.admin.tracing
+	for _, m := range r.middleware {
+		if profiler != nil || tracing {
+			m = middleware.Timed(middlewareName(m), m)
+		}
+		handler = m.Handle(handler)
+	}
+	if tracing {
+		handler = traceMiddleware(handler)
+	}
+	if profiler != nil {
+		handler = profiler.Handle(handler)
+	}
+	// The tenant is resolved before any other middleware, so caches see it, or deferred until
+	// the authentication middleware stored the claims it is read from
+	if r.tenancy != nil {
+		handler = r.tenancy.Defer(handler)
+	}
+	// Maintenance mode runs first so no other middleware does work for rejected requests
+	return r.maintenance.Handle(handler)
+}
+
+// SetMaintenance turns maintenance mode on or off at runtime. While it is on, all routes
+// except health checks answer 503 with a maintenance page or JSON error, and clients from
+// allowCIDRs keep access. retryAfter, if positive, is sent in the Retry-After header.
+//
+// Example usage:
+//
+//	App.SetMaintenance(true, []string{"10.0.0.0/8"}, 30*time.Minute)
+//	defer App.SetMaintenance(false, nil, 0)
+func (r *Router) SetMaintenance(enabled bool, allowCIDRs []string, retryAfter time.Duration) {
+	r.maintenance.Set(enabled, allowCIDRs, retryAfter)
+}
+
+// Maintenance returns the maintenance mode middleware, e.g. to apply the MAINTENANCE_* keys
+// after reloading the configuration or to change the skipped health check paths.
+//
+// Example usage:
+//
+//	cfg.Reload()
+//	App.Maintenance().SetFromConfig(cfg)
+func (r *Router) Maintenance() *middleware.Maintenance {
+	return r.maintenance
+}
+
 // Start http server
 func (r *Router) Listen(addr string, httpConfig *config.HttpConfig) error {
 	return r.Start(addr, httpConfig)
@@ -418,6 +1207,16 @@ func (e *HTTPError) Error() string {
 	return fmt.Sprintf("%d - %s", e.Code, e.Message)
 }
 
+// StatusCode returns the HTTP status code, so the recovery middleware responds with it.
+func (e *HTTPError) StatusCode() int {
+	return e.Code
+}
+
+// PublicMessage returns the message sent to the client.
+func (e *HTTPError) PublicMessage() string {
+	return e.Message
+}
+
 // NewHTTPError creates a new HTTPError instance with the given status code and message.
 //
 // Example usage:
@@ -484,7 +1283,7 @@ type CustomHandler func(ctx *context.Context)
 
 // Server Swagger
 func (r *Router) Swagger(path string, handler http.HandlerFunc) {
-	r.AddRoute(path, UnWrapCustomHandler(r.withContext(UnWrapCustomHandler(handler), string(GET))))
+	r.addRoute(path, UnWrapCustomHandler(r.withContext(UnWrapCustomHandler(handler), string(GET))), string(GET))
 }
 
 func PathPrefix(path string) {
@@ -493,31 +1292,31 @@ func PathPrefix(path string) {
 
 // Get registers a handler for GET requests.
 func (r *Router) Get(path string, handler CustomHandler) *Router {
-	r.AddRoute(path, UnWrapCustomHandler(r.withContext(handler, string(GET))))
+	r.addRoute(path, UnWrapCustomHandler(r.withContext(handler, string(GET))), string(GET))
 	return r
 }
 
 // Post registers a handler for POST requests.
 func (r *Router) Post(path string, handler CustomHandler) *Router {
-	r.AddRoute(path, UnWrapCustomHandler(r.withContext(handler, string(POST))))
+	r.addRoute(path, UnWrapCustomHandler(r.withContext(handler, string(POST))), string(POST))
 	return r
 }
 
 // Put registers a handler for PUT requests.
 func (r *Router) Put(path string, handler CustomHandler) *Router {
-	r.AddRoute(path, UnWrapCustomHandler(r.withContext(handler, string(PUT))))
+	r.addRoute(path, UnWrapCustomHandler(r.withContext(handler, string(PUT))), string(PUT))
 	return r
 }
 
 // Delete registers a handler for DELETE requests.
 func (r *Router) Delete(path string, handler CustomHandler) *Router {
-	r.AddRoute(path, UnWrapCustomHandler(r.withContext(handler, string(DELETE))))
+	r.addRoute(path, UnWrapCustomHandler(r.withContext(handler, string(DELETE))), string(DELETE))
 	return r
 }
 
 // Patch registers a handler for PATCH requests.
 func (r *Router) Patch(path string, handler CustomHandler) *Router {
-	r.AddRoute(path, UnWrapCustomHandler(r.withContext(handler, string(PATCH))))
+	r.addRoute(path, UnWrapCustomHandler(r.withContext(handler, string(PATCH))), string(PATCH))
 	return r
 }
 
@@ -536,6 +1335,33 @@ func UnWrapCustomHandler(handler http.HandlerFunc) CustomHandler {
 	}
 }
 
+// SoftLaunched makes a route reachable only for the share of traffic selected by the launch.
+// Everyone else gets a 404, as if the route did not exist.
+//
+// Example usage:
+//
+//	launch := middleware.NewSoftLaunch(*middleware.NewSoftLaunchOptions("search-v2", 5))
+//	r.Get("/v2/search", router.SoftLaunched(launch, searchHandler))
+func SoftLaunched(launch *middleware.SoftLaunch, handler CustomHandler) CustomHandler {
+	return func(ctx *context.Context) {
+		if !launch.Enabled(ctx.Req) {
+			http.NotFound(ctx.Res, ctx.Req)
+			return
+		}
+		handler(ctx)
+	}
+}
+
+// withPipes runs the router's global pipes around the handler. Pipes are read per request,
+// so pipes added after a route is registered apply to it as well.
+func (r *Router) withPipes(handler CustomHandler) CustomHandler {
+	return func(ctx *context.Context) {
+		if ctx.RunPipes(r.pipes...) {
+			handler(ctx)
+		}
+	}
+}
+
 // withContext wraps the given handler with a custom context.
 // This provides utility methods for handling requests and responses.
 // It transforms the original handler to use the custom Context.
@@ -551,6 +1377,8 @@ func (r *Router) withContext(next CustomHandler, method string) http.HandlerFunc
 			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
 			return
 		}
+		// Attach the value store up front so every context of the request shares it
+		req, _ = middleware.RequestValues(req)
 		ctx := context.NewContext(req, w)
 		next(ctx)
 	}
@@ -568,13 +1396,53 @@ func (r *Router) withContext(next CustomHandler, method string) http.HandlerFunc
 //			LessGo.WithCookieParser(),
 //		)
 //	r.ServeStatic("/static/", "/path/to/static/files"))
-func (r *Router) ServeStatic(pathPrefix, dir string) {
+//
+// Static options can be passed to enable SPA index fallback, cache headers,
+// pre-compressed files or to disable directory listings:
+//
+//	r.ServeStatic("/", "dist",
+//		router.WithStaticIndexFallback("index.html"),
+//		router.WithStaticCacheControl(24*time.Hour, false),
+//		router.WithStaticPrecompressed(),
+//	)
+func (r *Router) ServeStatic(pathPrefix, dir string, options ...StaticOption) {
 	absPath, err := filepath.Abs(dir)
 	if err != nil {
 		log.Fatalf("Failed to resolve absolute path: %v", err)
 	}
-	fs := http.FileServer(http.Dir(absPath))
-	r.Mux.PathPrefix(pathPrefix).Handler(http.StripPrefix(pathPrefix, fs))
+	r.ServeStaticFS(pathPrefix, os.DirFS(absPath), options...)
+}
+
+// ServeUploads serves the resumable uploads of t under its base path.
+//
+// Example usage:
+//
+//	tus, err := upload.NewTus(store, "/files", upload.WithTusMaxSize(5<<30))
+//	r.ServeUploads(tus)
+func (r *Router) ServeUploads(t *upload.Tus) {
+	r.Mux.Handle(t.BasePath(), t)
+	r.Mux.PathPrefix(t.BasePath() + "/").Handler(t)
+}
+
+// ServeMedia serves the images rendered by s under its base path.
+//
+// Example usage:
+//
+//	images := media.NewServer(store, "/media", secret, media.WithPreset("thumb", media.Options{Width: 128}))
+//	r.ServeMedia(images)
+func (r *Router) ServeMedia(s *media.Server) {
+	r.Mux.PathPrefix(s.BasePath() + "/").Handler(s)
+}
+
+// ServeNotifications serves the clients of n under its base path.
+//
+// Example usage:
+//
+//	notifier := notify.New("/notifications", notify.WithStore(notify.NewRedisStore(client)))
+//	r.ServeNotifications(notifier)
+func (r *Router) ServeNotifications(n *notify.Notifier) {
+	r.Mux.Handle(n.BasePath(), n)
+	r.Mux.Handle(n.BasePath()+"/ack", n)
 }
 
 // Content negotiation
@@ -616,28 +1484,9 @@ func ContentNegotiationHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write(response)
 }
 
+// NegotiateContentType returns the best of JSON, XML and HTML for the given Accept header,
+// honoring quality values. It defaults to JSON.
 func NegotiateContentType(acceptHeader string) string {
-	// Default to JSON if nothing is specified
-	if acceptHeader == "" {
-		return ContentTypeJSON
-	}
-
-	// Split the Accept header into supported media types
-	acceptedTypes := strings.Split(acceptHeader, ",")
-
-	// Check for supported media types in order of preference
-	for _, acceptedType := range acceptedTypes {
-		acceptedType = strings.TrimSpace(strings.Split(acceptedType, ";")[0])
-		switch acceptedType {
-		case ContentTypeJSON:
-			return ContentTypeJSON
-		case ContentTypeXML:
-			return ContentTypeXML
-		case ContentTypeHTML:
-			return ContentTypeHTML
-		}
-	}
-
-	// Default to JSON if no match is found
-	return ContentTypeJSON
+	offers := []string{ContentTypeJSON, ContentTypeXML, ContentTypeHTML}
+	return context.NegotiateContentType(acceptHeader, offers, ContentTypeJSON)
 }
@@ -0,0 +1,213 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/config"
+	"github.com/hokamsingh/lessgo/internal/core/context"
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+)
+
+// RuntimeOptions defines who may use the runtime controls and how the configuration is
+// reloaded.
+type RuntimeOptions struct {
+	// Middleware authenticates operators before the controls run, on top of the IP
+	// allowlist of the admin router. It is required.
+	Middleware []middleware.Middleware
+	// Reload loads the configuration applied by POST /runtime/reload, config.LoadConfig by
+	// default.
+	Reload func() config.Config
+}
+
+// NewRuntimeOptions creates RuntimeOptions requiring one of the bearer tokens, e.g. from
+// ADMIN_TOKEN.
+func NewRuntimeOptions(tokens ...string) *RuntimeOptions {
+	return &RuntimeOptions{
+		Middleware: []middleware.Middleware{middleware.NewBearerAuth(tokens...)},
+		Reload:     config.LoadConfig,
+	}
+}
+
+// RateLimitInfo describes a rate limiter in the runtime controls.
+type RateLimitInfo struct {
+	Index    int    `json:"index"`
+	Limit    int    `json:"limit"`
+	Interval string `json:"interval"`
+}
+
+// RuntimeInfo is the state served at GET /runtime of the admin router.
+type RuntimeInfo struct {
+	LogLevel    string          `json:"log_level"`
+	Profiler    *bool           `json:"profiler,omitempty"` // Unset without WithProfiler
+	Maintenance bool            `json:"maintenance"`
+	RateLimits  []RateLimitInfo `json:"rate_limits"`
+}
+
+// ExposeRuntimeControls lets operators change settings of the running application through the
+// admin router, without restarting it:
+//
+//	GET  /runtime                      the log level, profiler, maintenance and rate limits
+//	PUT  /runtime/log-level            {"level": "debug"}, see middleware.SetLogLevel
+//	PUT  /runtime/profiler             {"enabled": false}, see WithProfiler
+//	PUT  /runtime/rate-limits/{index}  {"limit": 200, "interval": "1m"}
+//	POST /runtime/reload               reloads the configuration and applies it, see ApplyRuntimeConfig
+//
+// Rate limiters are those added with WithInMemoryRateLimiter, WithRedisRateLimiter or Use, in
+// that order. The controls require the authentication middleware of options and return an
+// error without one.
+//
+// Example usage:
+//
+//	if err := App.ExposeRuntimeControls(*router.NewRuntimeOptions(cfg.Get("ADMIN_TOKEN", ""))); err != nil {
+//		log.Fatal(err)
+//	}
+//	// curl -X PUT -H "Authorization: Bearer $ADMIN_TOKEN" -d '{"level":"debug"}' 127.0.0.1:9090/runtime/log-level
+func (r *Router) ExposeRuntimeControls(options RuntimeOptions) error {
+	if len(options.Middleware) == 0 {
+		return errors.New("runtime controls require an authentication middleware")
+	}
+	if options.Reload == nil {
+		options.Reload = config.LoadConfig
+	}
+	admin := r.Admin().With(options.Middleware...)
+	admin.Get("/runtime", func(ctx *context.Context) {
+		ctx.JSON(http.StatusOK, r.runtimeInfo())
+	})
+	admin.Put("/runtime/log-level", func(ctx *context.Context) {
+		var body struct {
+			Level string `json:"level"`
+		}
+		if err := ctx.Body(&body); err != nil {
+			ctx.Error(http.StatusBadRequest, "Expected {\"level\": \"debug|info|warn|error\"}")
+			return
+		}
+		if err := middleware.SetLogLevel(body.Level); err != nil {
+			ctx.Error(http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx.JSON(http.StatusOK, r.runtimeInfo())
+	})
+	admin.Put("/runtime/profiler", func(ctx *context.Context) {
+		if r.admin.profiler == nil {
+			ctx.Error(http.StatusNotFound, "Profiler not enabled")
+			return
+		}
+		var body struct {
+			Enabled *bool `json:"enabled"`
+		}
+		if err := ctx.Body(&body); err != nil || body.Enabled == nil {
+			ctx.Error(http.StatusBadRequest, "Expected {\"enabled\": true|false}")
+			return
+		}
+		r.admin.profiler.SetEnabled(*body.Enabled)
+		ctx.JSON(http.StatusOK, r.runtimeInfo())
+	})
+	admin.Put("/runtime/rate-limits/{index}", func(ctx *context.Context) {
+		param, _ := ctx.GetParam("index")
+		index, err := strconv.Atoi(param)
+		if err != nil || index < 0 || index >= len(r.admin.rateLimiters) {
+			ctx.Error(http.StatusNotFound, "Unknown rate limiter")
+			return
+		}
+		limiter := r.admin.rateLimiters[index]
+		limit, interval := limiter.Limit()
+		var body struct {
+			Limit    *int   `json:"limit"`
+			Interval string `json:"interval"`
+		}
+		if err := ctx.Body(&body); err != nil || body.Limit != nil && *body.Limit < 1 {
+			ctx.Error(http.StatusBadRequest, "Expected {\"limit\": 1 or more, \"interval\": \"1m\"}")
+			return
+		}
+		if body.Limit != nil {
+			limit = *body.Limit
+		}
+		if body.Interval != "" {
+			if interval, err = time.ParseDuration(body.Interval); err != nil || interval <= 0 {
+				ctx.Error(http.StatusBadRequest, "Invalid interval")
+				return
+			}
+		}
+		limiter.SetLimit(limit, interval)
+		ctx.JSON(http.StatusOK, r.runtimeInfo())
+	})
+	admin.Post("/runtime/reload", func(ctx *context.Context) {
+		if err := r.ApplyRuntimeConfig(options.Reload()); err != nil {
+			ctx.Error(http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx.JSON(http.StatusOK, r.runtimeInfo())
+	})
+	return nil
+}
+
+// ApplyRuntimeConfig applies the settings that can change while serving from the
+// configuration, e.g. after reloading it. Keys that are not set leave their setting as is:
+//
+//	LOG_LEVEL=warn
+//	PROFILER_ENABLED=false
+//	RATE_LIMIT=200              requests per interval, for every rate limiter
+//	RATE_LIMIT_INTERVAL=1m
+//	MAINTENANCE_MODE=true       and the other keys of Maintenance.SetFromConfig
+//
+// Example usage:
+//
+//	cfg.Reload()
+//	err := App.ApplyRuntimeConfig(cfg)
+func (r *Router) ApplyRuntimeConfig(cfg config.Config) error {
+	var problems []string
+	if level, ok := cfg["LOG_LEVEL"]; ok {
+		if err := middleware.SetLogLevel(level); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	if _, ok := cfg["PROFILER_ENABLED"]; ok && r.admin.profiler != nil {
+		r.admin.profiler.SetEnabled(cfg.GetBool("PROFILER_ENABLED", r.admin.profiler.Enabled()))
+	}
+	_, hasLimit := cfg["RATE_LIMIT"]
+	_, hasInterval := cfg["RATE_LIMIT_INTERVAL"]
+	if hasLimit || hasInterval {
+		for _, limiter := range r.admin.rateLimiters {
+			limit, interval := limiter.Limit()
+			if hasInterval {
+				parsed, err := time.ParseDuration(cfg["RATE_LIMIT_INTERVAL"])
+				if err != nil || parsed <= 0 {
+					problems = append(problems, "invalid RATE_LIMIT_INTERVAL "+strconv.Quote(cfg["RATE_LIMIT_INTERVAL"]))
+					break
+				}
+				interval = parsed
+			}
+			limiter.SetLimit(cfg.GetInt("RATE_LIMIT", limit), interval)
+		}
+	}
+	if _, ok := cfg["MAINTENANCE_MODE"]; ok {
+		r.maintenance.SetFromConfig(cfg)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid runtime configuration: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// runtimeInfo returns the current runtime settings.
+func (r *Router) runtimeInfo() RuntimeInfo {
+	info := RuntimeInfo{
+		LogLevel:    middleware.LogLevel().Level().String(),
+		Maintenance: r.maintenance.Enabled(),
+		RateLimits:  []RateLimitInfo{},
+	}
+	if r.admin.profiler != nil {
+		enabled := r.admin.profiler.Enabled()
+		info.Profiler = &enabled
+	}
+	for i, limiter := range r.admin.rateLimiters {
+		limit, interval := limiter.Limit()
+		info.RateLimits = append(info.RateLimits, RateLimitInfo{Index: i, Limit: limit, Interval: interval.String()})
+	}
+	return info
+}
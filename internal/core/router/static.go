@@ -0,0 +1,486 @@
+package router
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+)
+
+// StaticConfig holds the options used when serving static files.
+type StaticConfig struct {
+	IndexFile        string        // File served for directory requests, defaults to index.html
+	IndexFallback    bool          // Serve IndexFile for unknown paths (single page applications)
+	MaxAge           time.Duration // Cache-Control max-age for served files, zero disables the header
+	Immutable        bool          // Adds the immutable directive to Cache-Control
+	Precompressed    bool          // Look up .br and .gz siblings when the client accepts them
+	DirectoryListing bool          // Render a listing for directories without an index file
+	DenyHidden       bool          // Answer 404 for files and directories whose name starts with a dot
+	NotFound         http.Handler  // Handler for missing files, defaults to http.NotFound
+	Middleware       []StaticMiddleware
+	AccessHooks      []func(StaticAccess)
+}
+
+// StaticMiddleware applies a middleware to the files under a path prefix.
+type StaticMiddleware struct {
+	Prefix     string // Prefix of the paths relative to the served directory, e.g. /private/
+	Middleware middleware.Middleware
+}
+
+// StaticAccess describes a static file request, passed to the hooks of WithStaticAccessHook.
+type StaticAccess struct {
+	Request  *http.Request
+	Path     string // Path relative to the served directory
+	Status   int
+	Bytes    int64
+	Encoding string // Content-Encoding of pre-compressed variants
+	Duration time.Duration
+}
+
+// StaticOption is a function that configures a StaticConfig.
+type StaticOption func(*StaticConfig)
+
+// precompressedEncodings lists the supported pre-compressed encodings in order of preference.
+var precompressedEncodings = []struct {
+	encoding  string
+	extension string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// NewStaticConfig creates a new StaticConfig with optional settings.
+// Directory listing stays enabled by default to match http.FileServer.
+func NewStaticConfig(options ...StaticOption) *StaticConfig {
+	cfg := &StaticConfig{
+		IndexFile:        "index.html",
+		DirectoryListing: true,
+	}
+	for _, option := range options {
+		option(cfg)
+	}
+	return cfg
+}
+
+// WithStaticIndexFallback serves the given index file for any path that does not
+// match a file, which is what single page applications expect from their server.
+//
+// Example usage:
+//
+//	r.ServeStatic("/", "dist", router.WithStaticIndexFallback("index.html"))
+func WithStaticIndexFallback(indexFile string) StaticOption {
+	return func(cfg *StaticConfig) {
+		if indexFile != "" {
+			cfg.IndexFile = indexFile
+		}
+		cfg.IndexFallback = true
+	}
+}
+
+// WithStaticCacheControl sets the Cache-Control header on served files.
+// Set immutable for fingerprinted assets that never change under the same name.
+//
+// Example usage:
+//
+//	r.ServeStatic("/assets/", "dist/assets", router.WithStaticCacheControl(365*24*time.Hour, true))
+func WithStaticCacheControl(maxAge time.Duration, immutable bool) StaticOption {
+	return func(cfg *StaticConfig) {
+		cfg.MaxAge = maxAge
+		cfg.Immutable = immutable
+	}
+}
+
+// WithStaticPrecompressed serves `file.br` or `file.gz` instead of `file` when they
+// exist next to it and the client advertises support in Accept-Encoding.
+func WithStaticPrecompressed() StaticOption {
+	return func(cfg *StaticConfig) {
+		cfg.Precompressed = true
+	}
+}
+
+// WithStaticDirectoryListing enables or disables listings for directories
+// that do not contain an index file.
+func WithStaticDirectoryListing(enabled bool) StaticOption {
+	return func(cfg *StaticConfig) {
+		cfg.DirectoryListing = enabled
+	}
+}
+
+// WithStaticDenyHidden answers 404 for dotfiles and dot-directories such as .env or .git,
+// and leaves them out of directory listings.
+func WithStaticDenyHidden() StaticOption {
+	return func(cfg *StaticConfig) {
+		cfg.DenyHidden = true
+	}
+}
+
+// WithStaticNotFound sets the handler for missing files, e.g. to render a custom 404 page.
+//
+// Example usage:
+//
+//	r.ServeStatic("/", "public", router.WithStaticNotFound(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+//		w.WriteHeader(http.StatusNotFound)
+//		notFoundPage.Execute(w, nil)
+//	})))
+func WithStaticNotFound(handler http.Handler) StaticOption {
+	return func(cfg *StaticConfig) {
+		cfg.NotFound = handler
+	}
+}
+
+// WithStaticMiddleware runs m for the files under prefix, relative to the served directory.
+// Middleware for the longest matching prefix runs first.
+//
+// Example usage:
+//
+//	r.ServeStatic("/files/", "files", router.WithStaticMiddleware("/private/", middleware.NewJWTMiddleware(secret)))
+func WithStaticMiddleware(prefix string, m middleware.Middleware) StaticOption {
+	return func(cfg *StaticConfig) {
+		cfg.Middleware = append(cfg.Middleware, StaticMiddleware{Prefix: "/" + strings.TrimLeft(prefix, "/"), Middleware: m})
+	}
+}
+
+// WithStaticAccessHook calls fn after each static file request, for logging or metrics.
+//
+// Example usage:
+//
+//	r.ServeStatic("/assets/", "dist/assets", router.WithStaticAccessHook(func(a router.StaticAccess) {
+//		assetRequests.WithLabelValues(strconv.Itoa(a.Status)).Inc()
+//	}))
+func WithStaticAccessHook(fn func(StaticAccess)) StaticOption {
+	return func(cfg *StaticConfig) {
+		cfg.AccessHooks = append(cfg.AccessHooks, fn)
+	}
+}
+
+// WithStaticAccessLog logs each static file request with its status, size and duration.
+func WithStaticAccessLog() StaticOption {
+	return WithStaticAccessHook(func(a StaticAccess) {
+		log.Printf("static %s %s %d %dB %s", a.Request.Method, a.Path, a.Status, a.Bytes, a.Duration)
+	})
+}
+
+// ServeStaticFS serves static files from any fs.FS, such as an embed.FS.
+// The pathPrefix is stripped from the request URL before looking up the file.
+//
+// Example usage:
+//
+//	//go:embed dist
+//	var dist embed.FS
+//
+//	sub, _ := fs.Sub(dist, "dist")
+//	r.ServeStaticFS("/", sub, router.WithStaticIndexFallback("index.html"))
+func (r *Router) ServeStaticFS(pathPrefix string, fsys fs.FS, options ...StaticOption) {
+	cfg := NewStaticConfig(options...)
+	handler := newStaticHandler(fsys, cfg)
+	r.Mux.PathPrefix(pathPrefix).Handler(http.StripPrefix(pathPrefix, handler.withMiddleware()))
+}
+
+// staticHandler serves files from a fs.FS according to a StaticConfig.
+type staticHandler struct {
+	fsys    fs.FS
+	cfg     *StaticConfig
+	listing http.Handler
+}
+
+func newStaticHandler(fsys fs.FS, cfg *StaticConfig) *staticHandler {
+	if cfg.DenyHidden {
+		fsys = hiddenFS{fsys}
+	}
+	return &staticHandler{
+		fsys:    fsys,
+		cfg:     cfg,
+		listing: http.FileServer(http.FS(fsys)),
+	}
+}
+
+// withMiddleware wraps the handler with the per-prefix middleware and the access hooks.
+func (h *staticHandler) withMiddleware() http.Handler {
+	var handler http.Handler = h
+	if len(h.cfg.Middleware) > 0 {
+		// Longest prefixes first, so the most specific middleware runs first
+		rules := append([]StaticMiddleware(nil), h.cfg.Middleware...)
+		sort.SliceStable(rules, func(i, j int) bool { return len(rules[i].Prefix) > len(rules[j].Prefix) })
+		next := handler
+		handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			name := "/" + strings.TrimPrefix(path.Clean("/"+req.URL.Path), "/")
+			if strings.HasSuffix(req.URL.Path, "/") && name != "/" {
+				name += "/"
+			}
+			chain := next
+			for i := len(rules) - 1; i >= 0; i-- {
+				if matchesStaticPrefix(name, rules[i].Prefix) {
+					chain = rules[i].Middleware.Handle(chain)
+				}
+			}
+			chain.ServeHTTP(w, req)
+		})
+	}
+	if len(h.cfg.AccessHooks) > 0 {
+		next := handler
+		handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			start := time.Now()
+			rec := &staticRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, req)
+			access := StaticAccess{
+				Request:  req,
+				Path:     "/" + strings.TrimPrefix(req.URL.Path, "/"),
+				Status:   rec.status,
+				Bytes:    rec.bytes,
+				Encoding: rec.Header().Get("Content-Encoding"),
+				Duration: time.Since(start),
+			}
+			for _, hook := range h.cfg.AccessHooks {
+				hook(access)
+			}
+		})
+	}
+	return handler
+}
+
+// matchesStaticPrefix reports whether name is under prefix. A prefix without trailing slash
+// matches the path itself and everything below it.
+func matchesStaticPrefix(name, prefix string) bool {
+	if strings.HasSuffix(prefix, "/") {
+		return strings.HasPrefix(name, prefix) || name+"/" == prefix
+	}
+	return name == prefix || strings.HasPrefix(name, prefix+"/")
+}
+
+// staticRecorder records the status and size of a static response.
+type staticRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+func (r *staticRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status, r.wroteHeader = status, true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *staticRecorder) Write(p []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(p)
+	r.bytes += int64(n)
+	return n, err
+}
+
+func (r *staticRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (h *staticHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := fsName(req.URL.Path)
+	info, err := fs.Stat(h.fsys, name)
+	if err != nil {
+		h.serveFallback(w, req)
+		return
+	}
+
+	if info.IsDir() {
+		index := path.Join(name, h.cfg.IndexFile)
+		if indexInfo, err := fs.Stat(h.fsys, index); err == nil && !indexInfo.IsDir() {
+			h.serveFile(w, req, index, indexInfo)
+			return
+		}
+		if h.cfg.DirectoryListing {
+			h.listing.ServeHTTP(w, req)
+			return
+		}
+		h.serveFallback(w, req)
+		return
+	}
+
+	h.serveFile(w, req, name, info)
+}
+
+// notFound responds with the configured handler or a plain 404.
+func (h *staticHandler) notFound(w http.ResponseWriter, req *http.Request) {
+	if h.cfg.NotFound != nil {
+		h.cfg.NotFound.ServeHTTP(w, req)
+		return
+	}
+	http.NotFound(w, req)
+}
+
+// serveFallback serves the index file for SPA routes or responds with 404.
+func (h *staticHandler) serveFallback(w http.ResponseWriter, req *http.Request) {
+	if h.cfg.IndexFallback {
+		info, err := fs.Stat(h.fsys, h.cfg.IndexFile)
+		if err == nil && !info.IsDir() {
+			// The shell must always be revalidated so new deployments are picked up
+			w.Header().Set("Cache-Control", "no-cache")
+			h.serveContent(w, req, h.cfg.IndexFile, info)
+			return
+		}
+	}
+	h.notFound(w, req)
+}
+
+// serveFile writes cache headers and serves the file, preferring pre-compressed variants.
+func (h *staticHandler) serveFile(w http.ResponseWriter, req *http.Request, name string, info fs.FileInfo) {
+	if h.cfg.MaxAge > 0 {
+		cacheControl := "public, max-age=" + strconv.Itoa(int(h.cfg.MaxAge.Seconds()))
+		if h.cfg.Immutable {
+			cacheControl += ", immutable"
+		}
+		w.Header().Set("Cache-Control", cacheControl)
+	}
+
+	if h.cfg.Precompressed {
+		w.Header().Add("Vary", "Accept-Encoding")
+		accepted := req.Header.Get("Accept-Encoding")
+		for _, pc := range precompressedEncodings {
+			if !acceptsEncoding(accepted, pc.encoding) {
+				continue
+			}
+			compressedInfo, err := fs.Stat(h.fsys, name+pc.extension)
+			if err != nil || compressedInfo.IsDir() {
+				continue
+			}
+			if ctype := mime.TypeByExtension(filepath.Ext(name)); ctype != "" {
+				w.Header().Set("Content-Type", ctype)
+			}
+			w.Header().Set("Content-Encoding", pc.encoding)
+			h.serveContent(w, req, name+pc.extension, compressedInfo)
+			return
+		}
+	}
+
+	h.serveContent(w, req, name, info)
+}
+
+// serveContent streams the named file using http.ServeContent, which handles
+// Range, If-Modified-Since and HEAD requests.
+func (h *staticHandler) serveContent(w http.ResponseWriter, req *http.Request, name string, info fs.FileInfo) {
+	file, err := h.fsys.Open(name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			h.notFound(w, req)
+			return
+		}
+		log.Printf("Failed to open static file %s: %v", name, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	content, ok := file.(io.ReadSeeker)
+	if !ok {
+		// Not every fs.FS returns seekable files, buffer them instead
+		data, err := io.ReadAll(file)
+		if err != nil {
+			log.Printf("Failed to read static file %s: %v", name, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		content = bytes.NewReader(data)
+	}
+	// A strong validator lets clients revalidate with If-None-Match and resume with If-Range;
+	// it differs between pre-compressed variants since it derives from the served file
+	if w.Header().Get("ETag") == "" {
+		w.Header().Set("ETag", fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()))
+	}
+	http.ServeContent(w, req, info.Name(), info.ModTime(), content)
+}
+
+// fsName converts a URL path into a name accepted by fs.FS.
+func fsName(urlPath string) string {
+	name := strings.TrimPrefix(path.Clean("/"+urlPath), "/")
+	if name == "" {
+		return "."
+	}
+	return name
+}
+
+// acceptsEncoding reports whether the Accept-Encoding header allows the given encoding.
+func acceptsEncoding(header, encoding string) bool {
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		if !strings.EqualFold(strings.TrimSpace(fields[0]), encoding) {
+			continue
+		}
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if q, found := strings.CutPrefix(param, "q="); found {
+				if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// hiddenFS hides the files and directories whose name starts with a dot.
+type hiddenFS struct {
+	fs.FS
+}
+
+func isHidden(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, ".") && part != "." {
+			return true
+		}
+	}
+	return false
+}
+
+func (h hiddenFS) Open(name string) (fs.File, error) {
+	if isHidden(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	file, err := h.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if dir, ok := file.(fs.ReadDirFile); ok {
+		if info, err := file.Stat(); err == nil && info.IsDir() {
+			return hiddenDir{dir}, nil
+		}
+	}
+	return file, nil
+}
+
+// hiddenDir leaves hidden entries out of directory listings.
+type hiddenDir struct {
+	fs.ReadDirFile
+}
+
+func (d hiddenDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	for {
+		entries, err := d.ReadDirFile.ReadDir(n)
+		visible := entries[:0]
+		for _, entry := range entries {
+			if !strings.HasPrefix(entry.Name(), ".") {
+				visible = append(visible, entry)
+			}
+		}
+		// Keep reading when a whole batch was hidden, as an empty batch means the end
+		if len(visible) > 0 || err != nil || n <= 0 {
+			return visible, err
+		}
+	}
+}
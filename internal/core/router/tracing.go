@@ -0,0 +1,110 @@
+package router
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+)
+
+// WithMiddlewareTracing logs the time each middleware spent on every request, to find slow
+// or misordered middlewares while debugging. The chain of a route is served at /middleware
+// of the admin router. It adds overhead to every request and is not meant for production.
+//
+// Example usage:
+//
+//	r := router.NewRouter(router.WithCORS(corsOptions), router.WithMiddlewareTracing())
+func WithMiddlewareTracing() Option {
+	return func(r *Router) {
+		r.admin.tracing = true
+	}
+}
+
+// MiddlewareChain returns the middlewares a request for method and path runs through, the
+// first one runs first. It reports false when no route matches, in which case only the
+// middlewares of the whole application are listed.
+//
+// Example usage:
+//
+//	chain, _ := r.MiddlewareChain(http.MethodGet, "/users/1")
+//	log.Println(strings.Join(chain, " -> "))
+func (r *Router) MiddlewareChain(method, path string) ([]string, bool) {
+	root := r.admin.root
+	chain := []string{middlewareName(root.maintenance)}
+	if root.tenancy != nil {
+		chain = append(chain, middlewareName(root.tenancy))
+	}
+	if root.admin.profiler != nil {
+		chain = append(chain, middlewareName(root.admin.profiler))
+	}
+	// The last middleware registered wraps the others, so it runs first
+	for i := len(root.middleware) - 1; i >= 0; i-- {
+		chain = append(chain, middlewareName(root.middleware[i]))
+	}
+
+	req, err := http.NewRequest(method, path, nil)
+	if err != nil {
+		return chain, false
+	}
+	var match mux.RouteMatch
+	if !root.Mux.Match(req, &match) || match.Route == nil {
+		return chain, false
+	}
+	root.admin.mu.RLock()
+	defer root.admin.mu.RUnlock()
+	return append(chain, root.admin.chains[match.Route]...), true
+}
+
+// ChainWarnings points out middlewares of a chain that are likely misordered, such as a
+// response cache running before authentication, which serves cached responses to
+// unauthenticated clients.
+func ChainWarnings(chain []string) []string {
+	var warnings []string
+	cache := -1
+	for i, name := range chain {
+		lower := strings.ToLower(name)
+		switch {
+		case strings.Contains(lower, "caching"):
+			cache = i
+		case cache >= 0 && (strings.Contains(lower, "auth") || strings.Contains(lower, "session") || strings.Contains(lower, "jwt")):
+			warnings = append(warnings, fmt.Sprintf("%s runs before %s, cached responses may be served without authentication", chain[cache], name))
+		}
+	}
+	return warnings
+}
+
+func middlewareName(m interface{}) string {
+	if conditional, ok := m.(*middleware.Conditional); ok {
+		return "conditional " + middlewareName(conditional.Unwrap())
+	}
+	return strings.TrimPrefix(fmt.Sprintf("%T", m), "*")
+}
+
+// recordChain remembers the route scoped middlewares of a route for MiddlewareChain.
+func (r *Router) recordChain(route *mux.Route) {
+	names := make([]string, len(r.routeMiddleware))
+	for i, m := range r.routeMiddleware {
+		names[i] = middlewareName(m)
+	}
+	r.admin.mu.Lock()
+	defer r.admin.mu.Unlock()
+	r.admin.chains[route] = names
+}
+
+// traceMiddleware logs the timings recorded for the middlewares wrapped with Timed.
+func traceMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		req, _ = middleware.RequestValues(req)
+		next.ServeHTTP(w, req)
+		timings := middleware.RequestTimings(req)
+		parts := make([]string, len(timings))
+		// Timings complete innermost first, list them in execution order
+		for i, timing := range timings {
+			parts[len(timings)-1-i] = fmt.Sprintf("%s=%v", timing.Name, timing.Duration)
+		}
+		log.Printf("Middleware timings for %s %s: %s", req.Method, req.URL.Path, strings.Join(parts, " "))
+	})
+}
@@ -0,0 +1,141 @@
+package router
+
+import (
+	stdcontext "context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+)
+
+// ValidationError lists the misconfigurations found by Validate.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid router configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// WithoutStartupValidation starts serving without running Validate first.
+func WithoutStartupValidation() Option {
+	return func(r *Router) {
+		r.skipValidation = true
+	}
+}
+
+// Validate checks the configuration of the router for mistakes that otherwise only show as
+// misbehavior at runtime:
+//
+//   - response caching running before authentication, serving cached responses of one
+//     user to anyone
+//   - routes registered twice for the same host, methods, path and queries, the later of
+//     which is never reached
+//   - middlewares whose Redis or other service is unreachable, see
+//     middleware.StartupChecker
+//
+// Start, StartGraceful and ListenAll run it and refuse to serve when it fails, unless the
+// router was created with WithoutStartupValidation. Authentication middlewares are
+// recognized by implementing middleware.Authenticator.
+//
+// Example usage:
+//
+//	if err := r.Validate(ctx); err != nil {
+//		log.Fatal(err)
+//	}
+func (r *Router) Validate(ctx stdcontext.Context) error {
+	var problems []string
+	problems = append(problems, r.checkMiddlewareOrder()...)
+	problems = append(problems, r.checkDuplicateRoutes()...)
+	for _, m := range r.middleware {
+		if checker, ok := m.(middleware.StartupChecker); ok {
+			if err := checker.CheckStartup(ctx); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", middlewareName(m), err))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// validateStartup runs Validate before serving, with a bound on the checks of services.
+func (r *Router) validateStartup() error {
+	if r.skipValidation {
+		return nil
+	}
+	ctx, cancel := stdcontext.WithTimeout(stdcontext.Background(), 10*time.Second)
+	defer cancel()
+	if err := r.Validate(ctx); err != nil {
+		log.Printf("Startup validation failed, WithoutStartupValidation skips it: %v", err)
+		return err
+	}
+	return nil
+}
+
+// checkMiddlewareOrder reports caching middlewares that run before authentication. The
+// middleware added last runs first.
+func (r *Router) checkMiddlewareOrder() []string {
+	lastAuth := -1
+	for i, m := range r.middleware {
+		if auth, ok := m.(middleware.Authenticator); ok && auth.IsAuthenticator() {
+			lastAuth = i
+		}
+	}
+	var problems []string
+	for i, m := range r.middleware {
+		if !isCaching(m) || i < lastAuth {
+			continue
+		}
+		if lastAuth >= 0 {
+			problems = append(problems, fmt.Sprintf("%s runs before %s, so responses are cached before requests are authenticated; add the cache before the authentication",
+				middlewareName(m), middlewareName(r.middleware[lastAuth])))
+		}
+	}
+	return problems
+}
+
+func isCaching(m middleware.Middleware) bool {
+	if conditional, ok := m.(*middleware.Conditional); ok {
+		m = conditional.Unwrap()
+	}
+	_, ok := m.(*middleware.Caching)
+	return ok
+}
+
+// checkDuplicateRoutes reports routes that never match because an earlier route matches the
+// same requests. Routes added with AddRoute match any method and shadow all later routes
+// of their path.
+func (r *Router) checkDuplicateRoutes() []string {
+	seen := map[string]bool{}
+	var problems []string
+	r.Mux.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil || route.GetHandler() == nil {
+			// Subrouters and routes with custom matchers only
+			return nil
+		}
+		host, _ := route.GetHostTemplate()
+		queries, _ := route.GetQueriesTemplates()
+		target := host + path
+		if len(queries) > 0 {
+			target += "?" + strings.Join(queries, "&")
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			methods = []string{"ANY"}
+		}
+		for _, method := range methods {
+			if seen["ANY "+target] || seen[method+" "+target] {
+				problems = append(problems, fmt.Sprintf("route %s %s is registered twice, the second handler is never called", method, target))
+			}
+			seen[method+" "+target] = true
+		}
+		return nil
+	})
+	return problems
+}
@@ -0,0 +1,90 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/context"
+)
+
+// FrameworkVersion is the version of LessGo.
+const FrameworkVersion = "v1.0.4"
+
+// Version, commit and build time of the application, injected at build time with the flags
+// of VersionLdflags. When empty, the module version and VCS information embedded by the Go
+// toolchain are reported.
+var (
+	AppVersion string
+	Commit     string
+	BuildTime  string
+)
+
+// processStart is when the process started serving code, reported as the start time.
+var processStart = time.Now()
+
+// versionPackage is the import path of the package holding the injected variables.
+const versionPackage = "github.com/hokamsingh/lessgo/internal/core/router"
+
+// VersionLdflags returns the -ldflags value injecting the version, commit and build time into
+// the binary. Empty values are left out.
+//
+// Example usage:
+//
+//	flags := router.VersionLdflags("1.4.0", "9f2c1e7", time.Now().UTC().Format(time.RFC3339))
+//	// go build -ldflags "$flags" ./cmd/server
+func VersionLdflags(version, commit, buildTime string) string {
+	var flags []string
+	for name, value := range map[string]string{"AppVersion": version, "Commit": commit, "BuildTime": buildTime} {
+		if value != "" {
+			flags = append(flags, fmt.Sprintf("-X '%s.%s=%s'", versionPackage, name, value))
+		}
+	}
+	// Stable order for build scripts
+	sort.Strings(flags)
+	return strings.Join(flags, " ")
+}
+
+// VersionInfo is served by the version endpoint.
+type VersionInfo struct {
+	Framework string    `json:"framework"`
+	Version   string    `json:"version,omitempty"`
+	Commit    string    `json:"commit,omitempty"`
+	BuildTime string    `json:"build_time,omitempty"`
+	Modified  bool      `json:"modified,omitempty"`
+	GoVersion string    `json:"go_version"`
+	StartedAt time.Time `json:"started_at"`
+	Uptime    string    `json:"uptime"`
+}
+
+// Version returns the version information of the running binary.
+func Version() VersionInfo {
+	build := readBuildInfo()
+	return VersionInfo{
+		Framework: FrameworkVersion,
+		Version:   build.Version,
+		Commit:    build.Revision,
+		BuildTime: build.Time,
+		Modified:  build.Modified,
+		GoVersion: runtime.Version(),
+		StartedAt: processStart,
+		Uptime:    time.Since(processStart).Round(time.Second).String(),
+	}
+}
+
+// EnableVersionEndpoint serves the framework version, the version and commit of the
+// application, the Go version and the start time at path, so deployments can be verified.
+// The endpoint is public, unlike the build information of the admin router.
+//
+// Example usage:
+//
+//	App.EnableVersionEndpoint("/version")
+func (r *Router) EnableVersionEndpoint(path string) {
+	r.Get(path, func(ctx *context.Context) {
+		ctx.Res.Header().Set("Cache-Control", "no-store")
+		ctx.JSON(http.StatusOK, Version())
+	})
+}
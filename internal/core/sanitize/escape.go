@@ -0,0 +1,62 @@
+package sanitize
+
+import (
+	"encoding/json"
+	"html/template"
+)
+
+// HTMLEscape escapes s for HTML text and quoted attribute values. html/template already
+// escapes by context, use it for markup built by hand or with text/template.
+//
+// Example usage:
+//
+//	fmt.Fprintf(w, `<p title="%s">%s</p>`, sanitize.HTMLEscape(title), sanitize.HTMLEscape(body))
+func HTMLEscape(s string) string {
+	return template.HTMLEscapeString(s)
+}
+
+// JSAttrEscape escapes s for a JavaScript string literal inside a quoted HTML attribute, such
+// as an event handler. The value is JavaScript escaped first, then HTML escaped, so it can
+// neither end the string nor the attribute.
+//
+// Example usage:
+//
+//	fmt.Fprintf(w, `<button onclick="greet('%s')">Hi</button>`, sanitize.JSAttrEscape(name))
+func JSAttrEscape(s string) string {
+	return template.HTMLEscapeString(template.JSEscapeString(s))
+}
+
+// ScriptJSON encodes v as JSON safe to embed in a <script> element. encoding/json escapes <, >
+// and &, so the value cannot close the element.
+//
+// Example usage:
+//
+//	<script>const user = {{scriptJSON .User}};</script>
+func ScriptJSON(v interface{}) (template.JS, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return template.JS(data), nil
+}
+
+// FuncMap returns the output encoding functions for templates:
+//
+//   - htmlEscape and jsAttrEscape, see HTMLEscape and JSAttrEscape. html/template escapes by
+//     context already, they are meant for text/template.
+//   - scriptJSON, see ScriptJSON.
+//   - sanitize cleans a value with a policy and marks it as safe HTML, for user generated
+//     content that keeps its formatting: {{sanitize "ugc" .Comment}}.
+//
+// The view engine of WithTemplateRendering registers them.
+func FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"htmlEscape":   HTMLEscape,
+		"jsAttrEscape": JSAttrEscape,
+		"scriptJSON":   ScriptJSON,
+		"sanitize": func(policy, s string) template.HTML {
+			// The policy output contains only allowed markup
+			return template.HTML(String(policy, s))
+		},
+	}
+}
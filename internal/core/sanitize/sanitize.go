@@ -0,0 +1,179 @@
+/*
+Package sanitize cleans untrusted HTML in declared input fields with bluemonday policies.
+
+Rather than rejecting every request that looks like it contains markup, fields that end up in
+HTML are declared with a policy: "strict" removes all markup, "ugc" keeps the formatting safe
+for user generated content such as comments. Other policies are added with Register.
+
+Fields are declared with a `sanitize` struct tag, applied by Context.Body and Context.BindForm,
+or per route with the pipe.Sanitize pipe for JSON bodies.
+
+Usage:
+
+	type CommentDTO struct {
+		Author string `json:"author" sanitize:"strict"`
+		Body   string `json:"body" sanitize:"ugc"`
+	}
+
+	sanitize.Register("links", bluemonday.NewPolicy().AllowStandardURLs().AllowElements("a").AllowAttrs("href").OnElements("a"))
+*/
+package sanitize
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+// Names of the built-in policies.
+const (
+	Strict = "strict"
+	UGC    = "ugc"
+)
+
+var (
+	mu       sync.RWMutex
+	policies = map[string]*bluemonday.Policy{
+		Strict: bluemonday.StrictPolicy(),
+		UGC:    bluemonday.UGCPolicy(),
+	}
+)
+
+// Register adds a policy under name, replacing an existing policy of that name.
+func Register(name string, policy *bluemonday.Policy) {
+	mu.Lock()
+	defer mu.Unlock()
+	policies[name] = policy
+}
+
+// Lookup returns the policy registered under name.
+func Lookup(name string) (*bluemonday.Policy, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	policy, ok := policies[name]
+	return policy, ok
+}
+
+// String sanitizes s with the named policy. The text is HTML escaped, ready to be embedded in
+// a page. It panics when the policy is not registered, as that is a programming error.
+func String(policy, s string) string {
+	return mustLookup(policy).Sanitize(s)
+}
+
+func mustLookup(name string) *bluemonday.Policy {
+	policy, ok := Lookup(name)
+	if !ok {
+		panic(fmt.Sprintf("sanitize: unknown policy %q", name))
+	}
+	return policy
+}
+
+// Struct sanitizes the string fields of the struct v points to that have a `sanitize` tag
+// naming a policy. Slices and maps of strings, nested structs and pointers are followed.
+// Values other than struct pointers are left alone.
+//
+// Example usage:
+//
+//	var dto CommentDTO
+//	json.Unmarshal(body, &dto)
+//	sanitize.Struct(&dto)
+func Struct(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return nil
+	}
+	return sanitizeValue(rv.Elem(), nil)
+}
+
+// sanitizeValue applies policy to the strings in v, or the tags of its fields when policy is
+// nil.
+func sanitizeValue(v reflect.Value, policy *bluemonday.Policy) error {
+	switch v.Kind() {
+	case reflect.String:
+		if policy != nil && v.CanSet() {
+			v.SetString(policy.Sanitize(v.String()))
+		}
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			return sanitizeValue(v.Elem(), policy)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := sanitizeValue(v.Index(i), policy); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if policy == nil || v.Type().Elem().Kind() != reflect.String {
+			return nil
+		}
+		for _, key := range v.MapKeys() {
+			v.SetMapIndex(key, reflect.ValueOf(policy.Sanitize(v.MapIndex(key).String())).Convert(v.Type().Elem()))
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			fieldPolicy := policy
+			if name, ok := field.Tag.Lookup("sanitize"); ok {
+				if name == "-" {
+					continue
+				}
+				var found bool
+				if fieldPolicy, found = Lookup(name); !found {
+					return fmt.Errorf("sanitize: unknown policy %q on field %s", name, field.Name)
+				}
+			}
+			if err := sanitizeValue(v.Field(i), fieldPolicy); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// JSON sanitizes the fields of a decoded JSON object, as produced by encoding/json, with the
+// policy named for each field. Field names are dot separated paths into nested objects and
+// apply to every element of arrays on the way.
+//
+// Example usage:
+//
+//	sanitize.JSON(body, map[string]string{"title": sanitize.Strict, "post.body": sanitize.UGC})
+func JSON(body interface{}, fields map[string]string) interface{} {
+	for path, name := range fields {
+		policy := mustLookup(name)
+		body = sanitizePath(body, strings.Split(path, "."), policy)
+	}
+	return body
+}
+
+func sanitizePath(value interface{}, path []string, policy *bluemonday.Policy) interface{} {
+	switch v := value.(type) {
+	case string:
+		if len(path) == 0 {
+			return policy.Sanitize(v)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = sanitizePath(item, path, policy)
+		}
+	case map[string]interface{}:
+		if len(path) == 0 {
+			// The whole object was declared
+			for key, item := range v {
+				v[key] = sanitizePath(item, nil, policy)
+			}
+			return v
+		}
+		if item, ok := v[path[0]]; ok {
+			v[path[0]] = sanitizePath(item, path[1:], policy)
+		}
+	}
+	return value
+}
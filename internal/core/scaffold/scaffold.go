@@ -0,0 +1,271 @@
+/*
+Package scaffold generates the code of new resources in an application laid out like the REST
+example: one package per module under src, and a root module collecting them.
+
+A resource is a module with a REST controller validating its input, a service, a repository
+storing rows with the database package, DTOs and tests running against an in-memory store.
+
+Usage:
+
+	result, err := scaffold.GenerateResource(".", "user", *scaffold.NewResourceOptions())
+	for _, file := range result.Files {
+		fmt.Println("created", file)
+	}
+*/
+package scaffold
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"fmt"
+	"go/format"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+var templates = template.Must(template.ParseFS(templateFS, "templates/*.tmpl"))
+
+// resourceFiles maps the templates of a resource to the suffixes of the generated files.
+var resourceFiles = []struct {
+	template string
+	suffix   string
+}{
+	{"model.go.tmpl", "_model.go"},
+	{"dto.go.tmpl", "_dto.go"},
+	{"repository.go.tmpl", "_repository.go"},
+	{"service.go.tmpl", "_service.go"},
+	{"controller.go.tmpl", "_controller.go"},
+	{"module.go.tmpl", "_module.go"},
+	{"controller_test.go.tmpl", "_controller_test.go"},
+}
+
+// ResourceOptions configures GenerateResource.
+type ResourceOptions struct {
+	// Dir is the directory of the module packages relative to the application root.
+	Dir string
+	// RootModule is the file of the root module the new module is added to, relative to Dir.
+	// Empty leaves the wiring to the application.
+	RootModule string
+	// ModulePath is the import path of the application, read from go.mod when empty.
+	ModulePath string
+	// Path is the route prefix, the kebab-case plural of the name when empty.
+	Path string
+	// Table is the database table, the snake_case plural of the name when empty.
+	Table string
+	// Force overwrites existing files.
+	Force bool
+}
+
+// NewResourceOptions creates options for the layout of the REST example, with modules under
+// src wired into src/root_module.go.
+func NewResourceOptions() *ResourceOptions {
+	return &ResourceOptions{
+		Dir:        "src",
+		RootModule: "root_module.go",
+	}
+}
+
+// Result lists what GenerateResource did.
+type Result struct {
+	Files []string // Generated files
+	Wired string   // Root module file the module was added to, empty when not wired
+	Notes []string // Follow-up steps left to the developer
+}
+
+// names are the identifiers derived from the resource name.
+type names struct {
+	Package     string // user
+	Type        string // OrderItem
+	Var         string // orderItem
+	VarPlural   string // orderItems
+	Human       string // order item
+	HumanPlural string // order items
+	Table       string // order_items
+	Path        string // /order-items
+	File        string // order_item
+}
+
+// GenerateResource generates the module, controller, service, repository, DTOs and tests of
+// a resource into the package named after it under options.Dir, and adds the module to the
+// root module. Names may be given in any case, e.g. "user", "order-item" or "OrderItem".
+//
+// Example usage:
+//
+//	options := scaffold.NewResourceOptions()
+//	options.Path = "/api/v1/users"
+//	result, err := scaffold.GenerateResource(".", "user", *options)
+func GenerateResource(root, name string, options ResourceOptions) (*Result, error) {
+	n, err := resourceNames(name)
+	if err != nil {
+		return nil, err
+	}
+	if options.Path != "" {
+		n.Path = "/" + strings.Trim(options.Path, "/")
+	}
+	if options.Table != "" {
+		n.Table = options.Table
+	}
+
+	dir := filepath.Join(root, options.Dir, n.Package)
+	result := &Result{}
+	if !options.Force {
+		for _, file := range resourceFiles {
+			path := filepath.Join(dir, n.File+file.suffix)
+			if _, err := os.Stat(path); err == nil {
+				return nil, fmt.Errorf("scaffold: %s exists, use Force to overwrite it", path)
+			}
+		}
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	for _, file := range resourceFiles {
+		var buf bytes.Buffer
+		if err := templates.ExecuteTemplate(&buf, file.template, n); err != nil {
+			return nil, err
+		}
+		source, err := format.Source(buf.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("scaffold: formatting %s: %w", file.template, err)
+		}
+		path := filepath.Join(dir, n.File+file.suffix)
+		if err := os.WriteFile(path, source, 0o644); err != nil {
+			return nil, err
+		}
+		result.Files = append(result.Files, path)
+	}
+	result.Notes = append(result.Notes, fmt.Sprintf("create the %s table with the columns id, name, created_at, updated_at and deleted_at", n.Table))
+
+	if options.RootModule == "" {
+		result.Notes = append(result.Notes, fmt.Sprintf("add %s.New%sModule(db) to the modules of the application", n.Package, n.Type))
+		return result, nil
+	}
+	modulePath := options.ModulePath
+	if modulePath == "" {
+		if modulePath, err = readModulePath(root); err != nil {
+			return nil, err
+		}
+	}
+	importPath := strings.TrimSuffix(modulePath, "/") + "/" + filepath.ToSlash(filepath.Join(options.Dir, n.Package))
+	rootFile := filepath.Join(root, options.Dir, options.RootModule)
+	notes, err := wireModule(rootFile, importPath, n)
+	if err != nil {
+		return nil, err
+	}
+	result.Notes = append(result.Notes, notes...)
+	result.Wired = rootFile
+	return result, nil
+}
+
+// resourceNames derives the identifiers of a resource from its name.
+func resourceNames(name string) (names, error) {
+	words := splitWords(name)
+	if len(words) == 0 {
+		return names{}, fmt.Errorf("scaffold: invalid resource name %q", name)
+	}
+	if !unicode.IsLetter(rune(words[0][0])) {
+		return names{}, fmt.Errorf("scaffold: resource name %q must start with a letter", name)
+	}
+	plural := append(append([]string{}, words[:len(words)-1]...), pluralize(words[len(words)-1]))
+	n := names{
+		Package:     strings.Join(words, ""),
+		Type:        pascal(words),
+		Human:       strings.Join(words, " "),
+		HumanPlural: strings.Join(plural, " "),
+		Table:       strings.Join(plural, "_"),
+		Path:        "/" + strings.Join(plural, "-"),
+		File:        strings.Join(words, "_"),
+	}
+	n.Var = camel(words)
+	n.VarPlural = camel(plural)
+	if n.VarPlural == n.Var {
+		n.VarPlural += "List"
+	}
+	if token.IsKeyword(n.Package) || token.IsKeyword(n.Var) {
+		return names{}, fmt.Errorf("scaffold: resource name %q is a Go keyword", name)
+	}
+	return n, nil
+}
+
+// splitWords splits kebab-case, snake_case and camelCase names into lower case words.
+func splitWords(name string) []string {
+	var words []string
+	var word []rune
+	runes := []rune(strings.TrimSpace(name))
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, strings.ToLower(string(word)))
+			word = nil
+		}
+	}
+	for i, r := range runes {
+		switch {
+		case r == '-' || r == '_' || r == ' ':
+			flush()
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r) || r > unicode.MaxASCII:
+			return nil
+		case unicode.IsUpper(r) && i > 0 && (unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])):
+			flush()
+			word = append(word, r)
+		default:
+			word = append(word, r)
+		}
+	}
+	flush()
+	return words
+}
+
+// pluralize returns the English plural of a lower case noun for the common cases.
+func pluralize(word string) string {
+	switch {
+	case strings.HasSuffix(word, "y") && len(word) > 1 && !strings.ContainsRune("aeiou", rune(word[len(word)-2])):
+		return word[:len(word)-1] + "ies"
+	case strings.HasSuffix(word, "s"), strings.HasSuffix(word, "x"), strings.HasSuffix(word, "z"),
+		strings.HasSuffix(word, "ch"), strings.HasSuffix(word, "sh"):
+		return word + "es"
+	default:
+		return word + "s"
+	}
+}
+
+func pascal(words []string) string {
+	var b strings.Builder
+	for _, word := range words {
+		if word == "id" || word == "url" || word == "api" || word == "http" {
+			b.WriteString(strings.ToUpper(word))
+			continue
+		}
+		b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return b.String()
+}
+
+func camel(words []string) string {
+	s := pascal(words)
+	first := pascal(words[:1])
+	return strings.ToLower(first) + s[len(first):]
+}
+
+// readModulePath returns the module path declared in the go.mod file of root.
+func readModulePath(root string) (string, error) {
+	file, err := os.Open(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return "", fmt.Errorf("scaffold: reading the module path: %w", err)
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if path, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "module "); ok {
+			return strings.Trim(strings.TrimSpace(path), `"`), nil
+		}
+	}
+	return "", fmt.Errorf("scaffold: no module directive in %s", file.Name())
+}
@@ -0,0 +1,170 @@
+package scaffold
+
+import (
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// wireModule adds the module of the resource to the modules slice of NewRootModule in file.
+// NewRootModule gets a *LessGo.DB parameter when it has none, which its callers then pass.
+func wireModule(file, importPath string, n names) ([]string, error) {
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("scaffold: reading the root module: %w", err)
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, file, src, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("scaffold: parsing the root module: %w", err)
+	}
+
+	var constructor *ast.FuncDecl
+	for _, decl := range f.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil && fn.Name.Name == "NewRootModule" {
+			constructor = fn
+		}
+	}
+	if constructor == nil || constructor.Body == nil {
+		return nil, fmt.Errorf("scaffold: no NewRootModule function in %s", file)
+	}
+	modules := modulesLiteral(constructor)
+	if modules == nil {
+		return nil, fmt.Errorf("scaffold: no []LessGo.IModule literal in NewRootModule of %s", file)
+	}
+
+	lessgo := importName(f, "github.com/hokamsingh/lessgo/pkg/lessgo", "lessgo")
+	var notes []string
+	db := databaseParam(constructor, lessgo)
+	var edits []edit
+	if db == "" {
+		db = "db"
+		params := constructor.Type.Params
+		param := db + " *" + lessgo + ".DB"
+		if len(params.List) > 0 {
+			param = ", " + param
+		}
+		edits = append(edits, edit{offset(fset, params.Closing), param})
+		notes = append(notes, "NewRootModule takes the database now, pass it where it is called, e.g. src.NewRootModule(App, db)")
+	}
+
+	// The new module goes last, on its own line when the literal spans several lines
+	element := n.Package + ".New" + n.Type + "Module(" + db + "),"
+	if fset.Position(modules.Lbrace).Line == fset.Position(modules.Rbrace).Line {
+		element = " " + strings.TrimSuffix(element, ",")
+		if len(modules.Elts) > 0 {
+			element = "," + element
+		}
+	} else {
+		element = "\t" + element + "\n"
+	}
+	edits = append(edits, edit{offset(fset, modules.Rbrace), element})
+
+	if !hasImport(f, importPath) {
+		edits = append(edits, importEdit(fset, f, importPath))
+	}
+
+	out, err := format.Source(apply(src, edits))
+	if err != nil {
+		return nil, fmt.Errorf("scaffold: formatting the root module: %w", err)
+	}
+	return notes, os.WriteFile(file, out, 0o644)
+}
+
+// modulesLiteral returns the first []IModule composite literal in the body of fn.
+func modulesLiteral(fn *ast.FuncDecl) *ast.CompositeLit {
+	var found *ast.CompositeLit
+	ast.Inspect(fn.Body, func(node ast.Node) bool {
+		lit, ok := node.(*ast.CompositeLit)
+		if !ok || found != nil {
+			return found == nil
+		}
+		if array, ok := lit.Type.(*ast.ArrayType); ok && array.Len == nil {
+			if sel, ok := array.Elt.(*ast.SelectorExpr); ok && sel.Sel.Name == "IModule" {
+				found = lit
+				return false
+			}
+		}
+		return true
+	})
+	return found
+}
+
+// databaseParam returns the name of the *LessGo.DB parameter of fn.
+func databaseParam(fn *ast.FuncDecl, lessgo string) string {
+	for _, param := range fn.Type.Params.List {
+		star, ok := param.Type.(*ast.StarExpr)
+		if !ok {
+			continue
+		}
+		if sel, ok := star.X.(*ast.SelectorExpr); ok && sel.Sel.Name == "DB" {
+			if pkg, ok := sel.X.(*ast.Ident); ok && pkg.Name == lessgo && len(param.Names) > 0 {
+				return param.Names[0].Name
+			}
+		}
+	}
+	return ""
+}
+
+// importName returns the name the file imports path under, def when not imported.
+func importName(f *ast.File, path, def string) string {
+	for _, spec := range f.Imports {
+		if p, _ := strconv.Unquote(spec.Path.Value); p == path {
+			if spec.Name != nil {
+				return spec.Name.Name
+			}
+			return def
+		}
+	}
+	return def
+}
+
+// importEdit adds path to the first import declaration, or after the package clause.
+func importEdit(fset *token.FileSet, f *ast.File, path string) edit {
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			continue
+		}
+		if gen.Lparen.IsValid() {
+			return edit{offset(fset, gen.Rparen), "\t" + strconv.Quote(path) + "\n"}
+		}
+		return edit{offset(fset, gen.End()), "\nimport " + strconv.Quote(path)}
+	}
+	return edit{offset(fset, f.Name.End()), "\n\nimport " + strconv.Quote(path)}
+}
+
+func hasImport(f *ast.File, path string) bool {
+	for _, spec := range f.Imports {
+		if p, _ := strconv.Unquote(spec.Path.Value); p == path {
+			return true
+		}
+	}
+	return false
+}
+
+// edit inserts text at a byte offset of the source.
+type edit struct {
+	offset int
+	text   string
+}
+
+func offset(fset *token.FileSet, pos token.Pos) int {
+	return fset.Position(pos).Offset
+}
+
+// apply applies the insertions to src.
+func apply(src []byte, edits []edit) []byte {
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].offset > edits[j].offset })
+	out := append([]byte{}, src...)
+	for _, e := range edits {
+		out = append(out[:e.offset], append([]byte(e.text), out[e.offset:]...)...)
+	}
+	return out
+}
@@ -0,0 +1,374 @@
+package scim
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Filter is a parsed SCIM filter expression (RFC 7644 section 3.4.2.2).
+// Repositories can translate it into a database query or use Match for in-memory data.
+type Filter interface {
+	// Match reports whether the attributes of a resource satisfy the filter.
+	Match(attrs map[string]interface{}) bool
+}
+
+// Comparison is an attribute expression such as `userName eq "bjensen"` or `title pr`.
+type Comparison struct {
+	Path     string // attribute path, e.g. "userName" or "name.familyName"
+	Operator string // eq, ne, co, sw, ew, pr, gt, ge, lt, le
+	Value    interface{}
+}
+
+// Logical combines two filters with "and" or "or".
+type Logical struct {
+	Operator string
+	Left     Filter
+	Right    Filter
+}
+
+// Not negates a filter.
+type Not struct {
+	Filter Filter
+}
+
+// ParseFilter parses a SCIM filter expression. Attribute names are case-insensitive,
+// grouping with parentheses and the "not" operator are supported.
+//
+// Example:
+//
+//	f, err := scim.ParseFilter(`userName eq "bjensen" and (emails co "example.com" or active eq true)`)
+func ParseFilter(input string) (Filter, error) {
+	p := &filterParser{tokens: tokenizeFilter(input)}
+	if len(p.tokens) == 0 {
+		return nil, fmt.Errorf("empty filter")
+	}
+	f, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected token %q", p.tokens[p.pos])
+	}
+	return f, nil
+}
+
+// Match implements Filter.
+func (c *Comparison) Match(attrs map[string]interface{}) bool {
+	values := lookupPath(attrs, c.Path)
+	if c.Operator == "pr" {
+		for _, v := range values {
+			if v != nil && v != "" {
+				return true
+			}
+		}
+		return false
+	}
+	for _, v := range values {
+		if compare(v, c.Operator, c.Value) {
+			return true
+		}
+	}
+	// "ne" on a missing attribute is true
+	return len(values) == 0 && c.Operator == "ne"
+}
+
+// Match implements Filter.
+func (l *Logical) Match(attrs map[string]interface{}) bool {
+	if l.Operator == "and" {
+		return l.Left.Match(attrs) && l.Right.Match(attrs)
+	}
+	return l.Left.Match(attrs) || l.Right.Match(attrs)
+}
+
+// Match implements Filter.
+func (n *Not) Match(attrs map[string]interface{}) bool {
+	return !n.Filter.Match(attrs)
+}
+
+// lookupPath resolves a dotted attribute path, flattening multi-valued attributes.
+// A multi-valued attribute without a sub-attribute matches on its "value" entries.
+func lookupPath(attrs map[string]interface{}, path string) []interface{} {
+	current := []interface{}{attrs}
+	for _, part := range strings.Split(path, ".") {
+		var next []interface{}
+		for _, item := range current {
+			switch v := item.(type) {
+			case map[string]interface{}:
+				if value, ok := getFold(v, part); ok {
+					next = append(next, flatten(value)...)
+				}
+			}
+		}
+		current = next
+	}
+
+	var values []interface{}
+	for _, item := range current {
+		if m, ok := item.(map[string]interface{}); ok {
+			if value, ok := getFold(m, "value"); ok {
+				values = append(values, value)
+			}
+			continue
+		}
+		values = append(values, item)
+	}
+	return values
+}
+
+// getFold looks up a key case-insensitively, as SCIM attribute names are case-insensitive.
+func getFold(m map[string]interface{}, key string) (interface{}, bool) {
+	if v, ok := m[key]; ok {
+		return v, true
+	}
+	for k, v := range m {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+func flatten(value interface{}) []interface{} {
+	if list, ok := value.([]interface{}); ok {
+		return list
+	}
+	return []interface{}{value}
+}
+
+// compare evaluates a single comparison between an attribute value and a filter value.
+func compare(actual interface{}, op string, expected interface{}) bool {
+	switch a := actual.(type) {
+	case string:
+		e, ok := expected.(string)
+		if !ok {
+			return false
+		}
+		// Date-times compare chronologically
+		if at, err := time.Parse(time.RFC3339, a); err == nil {
+			if et, err := time.Parse(time.RFC3339, e); err == nil {
+				return compareOrdered(at.Sub(et).Seconds(), op)
+			}
+		}
+		al, el := strings.ToLower(a), strings.ToLower(e)
+		switch op {
+		case "eq":
+			return al == el
+		case "ne":
+			return al != el
+		case "co":
+			return strings.Contains(al, el)
+		case "sw":
+			return strings.HasPrefix(al, el)
+		case "ew":
+			return strings.HasSuffix(al, el)
+		default:
+			return compareOrdered(float64(strings.Compare(al, el)), op)
+		}
+	case bool:
+		e, ok := expected.(bool)
+		if !ok {
+			return false
+		}
+		switch op {
+		case "eq":
+			return a == e
+		case "ne":
+			return a != e
+		}
+	case float64:
+		e, ok := expected.(float64)
+		if !ok {
+			return false
+		}
+		if op == "ne" {
+			return a != e
+		}
+		return compareOrdered(a-e, op)
+	}
+	return false
+}
+
+// compareOrdered applies an ordering operator to the difference of two values.
+func compareOrdered(diff float64, op string) bool {
+	switch op {
+	case "eq":
+		return diff == 0
+	case "ne":
+		return diff != 0
+	case "gt":
+		return diff > 0
+	case "ge":
+		return diff >= 0
+	case "lt":
+		return diff < 0
+	case "le":
+		return diff <= 0
+	}
+	return false
+}
+
+// filterParser is a recursive descent parser over filter tokens.
+type filterParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *filterParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *filterParser) next() string {
+	token := p.peek()
+	p.pos++
+	return token
+}
+
+func (p *filterParser) parseOr() (Filter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for strings.EqualFold(p.peek(), "or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &Logical{Operator: "or", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (Filter, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for strings.EqualFold(p.peek(), "and") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &Logical{Operator: "and", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (Filter, error) {
+	token := p.peek()
+	switch {
+	case strings.EqualFold(token, "not"):
+		p.next()
+		if p.peek() != "(" {
+			return nil, fmt.Errorf("expected '(' after not")
+		}
+		f, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &Not{Filter: f}, nil
+	case token == "(":
+		p.next()
+		f, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		return f, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *filterParser) parseComparison() (Filter, error) {
+	path := p.next()
+	if path == "" || path == ")" || path == "(" {
+		return nil, fmt.Errorf("expected attribute path")
+	}
+	op := strings.ToLower(p.next())
+	switch op {
+	case "pr":
+		return &Comparison{Path: path, Operator: op}, nil
+	case "eq", "ne", "co", "sw", "ew", "gt", "ge", "lt", "le":
+	default:
+		return nil, fmt.Errorf("unknown operator %q", op)
+	}
+
+	raw := p.next()
+	if raw == "" {
+		return nil, fmt.Errorf("missing value for %s %s", path, op)
+	}
+	value, err := parseFilterValue(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &Comparison{Path: path, Operator: op, Value: value}, nil
+}
+
+// parseFilterValue converts a filter literal to a string, bool, number or nil.
+func parseFilterValue(raw string) (interface{}, error) {
+	if strings.HasPrefix(raw, `"`) {
+		value, err := strconv.Unquote(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid string literal %s", raw)
+		}
+		return value, nil
+	}
+	switch strings.ToLower(raw) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	number, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %s", raw)
+	}
+	return number, nil
+}
+
+// tokenizeFilter splits a filter into words, quoted strings and parentheses.
+func tokenizeFilter(input string) []string {
+	var tokens []string
+	for i := 0; i < len(input); {
+		ch := rune(input[i])
+		switch {
+		case unicode.IsSpace(ch):
+			i++
+		case ch == '(' || ch == ')':
+			tokens = append(tokens, string(ch))
+			i++
+		case ch == '"':
+			j := i + 1
+			for j < len(input) && input[j] != '"' {
+				if input[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j < len(input) {
+				j++
+			}
+			tokens = append(tokens, input[i:j])
+			i = j
+		default:
+			j := i
+			for j < len(input) && !unicode.IsSpace(rune(input[j])) && input[j] != '(' && input[j] != ')' {
+				j++
+			}
+			tokens = append(tokens, input[i:j])
+			i = j
+		}
+	}
+	return tokens
+}
@@ -0,0 +1,180 @@
+package scim
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PatchRequest is the body of a SCIM PATCH request.
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+// PatchOperation is a single add, replace or remove operation.
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// ApplyPatch applies PATCH operations to the attributes of a resource.
+// Supported paths are `attr`, `attr.sub` and value filters on multi-valued
+// attributes such as `members[value eq "2819c223"]`.
+func ApplyPatch(attrs map[string]interface{}, operations []PatchOperation) error {
+	for _, operation := range operations {
+		op := strings.ToLower(operation.Op)
+		var err error
+		switch op {
+		case "add", "replace":
+			err = applySet(attrs, operation.Path, operation.Value, op == "add")
+		case "remove":
+			err = applyRemove(attrs, operation.Path)
+		default:
+			err = fmt.Errorf("unsupported patch op %q", operation.Op)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applySet adds or replaces a value. Adding to a multi-valued attribute appends the new values.
+func applySet(attrs map[string]interface{}, path string, value interface{}, add bool) error {
+	if path == "" {
+		// Without a path the value is a map of attributes to merge
+		values, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("patch value without path must be an object")
+		}
+		for key, v := range values {
+			if err := applySet(attrs, key, v, add); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	attr, filter, sub, err := splitPatchPath(path)
+	if err != nil {
+		return err
+	}
+	key := keyFold(attrs, attr)
+
+	if filter != nil {
+		items, _ := attrs[key].([]interface{})
+		for _, item := range items {
+			m, ok := item.(map[string]interface{})
+			if !ok || !filter.Match(m) {
+				continue
+			}
+			if sub == "" {
+				if values, ok := value.(map[string]interface{}); ok {
+					for k, v := range values {
+						m[k] = v
+					}
+				}
+				continue
+			}
+			m[keyFold(m, sub)] = value
+		}
+		return nil
+	}
+
+	if sub != "" {
+		parent, ok := attrs[key].(map[string]interface{})
+		if !ok {
+			parent = map[string]interface{}{}
+			attrs[key] = parent
+		}
+		parent[keyFold(parent, sub)] = value
+		return nil
+	}
+
+	if existing, ok := attrs[key].([]interface{}); ok && add {
+		attrs[key] = append(existing, flatten(value)...)
+		return nil
+	}
+	attrs[key] = value
+	return nil
+}
+
+// applyRemove removes an attribute, a sub-attribute, or the matching values of a multi-valued attribute.
+func applyRemove(attrs map[string]interface{}, path string) error {
+	if path == "" {
+		return fmt.Errorf("remove requires a path")
+	}
+	attr, filter, sub, err := splitPatchPath(path)
+	if err != nil {
+		return err
+	}
+	key := keyFold(attrs, attr)
+
+	if filter != nil {
+		items, _ := attrs[key].([]interface{})
+		kept := make([]interface{}, 0, len(items))
+		for _, item := range items {
+			m, ok := item.(map[string]interface{})
+			if ok && filter.Match(m) {
+				if sub != "" {
+					delete(m, keyFold(m, sub))
+					kept = append(kept, m)
+				}
+				continue
+			}
+			kept = append(kept, item)
+		}
+		attrs[key] = kept
+		return nil
+	}
+
+	if sub != "" {
+		if parent, ok := attrs[key].(map[string]interface{}); ok {
+			delete(parent, keyFold(parent, sub))
+		}
+		return nil
+	}
+	delete(attrs, key)
+	return nil
+}
+
+// splitPatchPath splits `attr[filter].sub` into its parts.
+func splitPatchPath(path string) (attr string, filter Filter, sub string, err error) {
+	if strings.HasPrefix(strings.ToLower(path), "urn:") {
+		// Fully qualified paths carry the schema URN, e.g. urn:...:2.0:User:name.givenName
+		end := len(path)
+		if open := strings.IndexByte(path, '['); open >= 0 {
+			end = open
+		}
+		path = path[strings.LastIndexByte(path[:end], ':')+1:]
+	}
+	if open := strings.IndexByte(path, '['); open >= 0 {
+		closing := strings.LastIndexByte(path, ']')
+		if closing < open {
+			return "", nil, "", fmt.Errorf("invalid path %q", path)
+		}
+		filter, err = ParseFilter(path[open+1 : closing])
+		if err != nil {
+			return "", nil, "", fmt.Errorf("invalid path filter: %w", err)
+		}
+		attr = path[:open]
+		sub = strings.TrimPrefix(path[closing+1:], ".")
+		return attr, filter, sub, nil
+	}
+	attr, sub, _ = strings.Cut(path, ".")
+	return attr, nil, sub, nil
+}
+
+// keyFold returns the existing key matching name case-insensitively, or name itself.
+func keyFold(m map[string]interface{}, name string) string {
+	if _, ok := m[name]; ok {
+		return name
+	}
+	for k := range m {
+		if strings.EqualFold(k, name) {
+			return k
+		}
+	}
+	return name
+}
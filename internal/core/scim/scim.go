@@ -0,0 +1,583 @@
+/*
+Package scim provides a SCIM 2.0 (RFC 7643/7644) server scaffold for user and group provisioning.
+
+The Server implements the HTTP protocol (listing with filters and pagination, PATCH semantics,
+error responses) and delegates storage to repository interfaces supplied by the application.
+
+Usage:
+
+	server := scim.NewServer(myUserRepo, myGroupRepo, scim.WithBearerToken(cfg.Get("SCIM_TOKEN", "")))
+	if err := server.RegisterRoutes(App, "/scim/v2"); err != nil {
+		log.Fatal(err)
+	}
+*/
+package scim
+
+import (
+	stdcontext "context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/context"
+	"github.com/hokamsingh/lessgo/internal/core/router"
+)
+
+// Schema URNs used by the scaffold.
+const (
+	SchemaUser         = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SchemaGroup        = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SchemaListResponse = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SchemaPatchOp      = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SchemaError        = "urn:ietf:params:scim:api:messages:2.0:Error"
+	SchemaSPConfig     = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+)
+
+// ContentType is the media type of SCIM responses.
+const ContentType = "application/scim+json"
+
+const (
+	// Default maximum number of resources returned by a list request.
+	defaultMaxResults = 100
+	// Maximum size of a request body.
+	maxBodySize = 1 << 20
+)
+
+// Errors returned by repositories to produce the matching SCIM error responses.
+var (
+	ErrNotFound = errors.New("scim: resource not found")
+	ErrConflict = errors.New("scim: resource already exists")
+)
+
+// ErrNoBearerToken is returned by RegisterRoutes when the server has no bearer token, e.g.
+// because the environment variable holding it is not set.
+var ErrNoBearerToken = errors.New("scim: a bearer token is required, see WithBearerToken")
+
+// Meta holds the resource metadata.
+type Meta struct {
+	ResourceType string     `json:"resourceType,omitempty"`
+	Created      *time.Time `json:"created,omitempty"`
+	LastModified *time.Time `json:"lastModified,omitempty"`
+	Location     string     `json:"location,omitempty"`
+	Version      string     `json:"version,omitempty"`
+}
+
+// Name holds the components of a user's name.
+type Name struct {
+	Formatted  string `json:"formatted,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+}
+
+// MultiValue is an entry of a multi-valued attribute such as emails.
+type MultiValue struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// Member is a member of a group.
+type Member struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+// User is a SCIM user resource.
+type User struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id,omitempty"`
+	ExternalID  string       `json:"externalId,omitempty"`
+	UserName    string       `json:"userName"`
+	Name        *Name        `json:"name,omitempty"`
+	DisplayName string       `json:"displayName,omitempty"`
+	Emails      []MultiValue `json:"emails,omitempty"`
+	Active      bool         `json:"active"`
+	Groups      []MultiValue `json:"groups,omitempty"`
+	Meta        *Meta        `json:"meta,omitempty"`
+}
+
+// Group is a SCIM group resource.
+type Group struct {
+	Schemas     []string `json:"schemas"`
+	ID          string   `json:"id,omitempty"`
+	ExternalID  string   `json:"externalId,omitempty"`
+	DisplayName string   `json:"displayName"`
+	Members     []Member `json:"members,omitempty"`
+	Meta        *Meta    `json:"meta,omitempty"`
+}
+
+// Query describes a list request. StartIndex is 1-based as defined by SCIM.
+type Query struct {
+	Filter     Filter
+	StartIndex int
+	Count      int
+	SortBy     string
+	SortOrder  string
+}
+
+// UserRepository stores users. Implementations return ErrNotFound and ErrConflict
+// so the server can produce the right status codes.
+type UserRepository interface {
+	ListUsers(ctx stdcontext.Context, query Query) (users []User, total int, err error)
+	GetUser(ctx stdcontext.Context, id string) (*User, error)
+	CreateUser(ctx stdcontext.Context, user *User) (*User, error)
+	ReplaceUser(ctx stdcontext.Context, user *User) (*User, error)
+	DeleteUser(ctx stdcontext.Context, id string) error
+}
+
+// GroupRepository stores groups.
+type GroupRepository interface {
+	ListGroups(ctx stdcontext.Context, query Query) (groups []Group, total int, err error)
+	GetGroup(ctx stdcontext.Context, id string) (*Group, error)
+	CreateGroup(ctx stdcontext.Context, group *Group) (*Group, error)
+	ReplaceGroup(ctx stdcontext.Context, group *Group) (*Group, error)
+	DeleteGroup(ctx stdcontext.Context, id string) error
+}
+
+// ListResponse is the envelope of list results.
+type ListResponse struct {
+	Schemas      []string    `json:"schemas"`
+	TotalResults int         `json:"totalResults"`
+	StartIndex   int         `json:"startIndex"`
+	ItemsPerPage int         `json:"itemsPerPage"`
+	Resources    interface{} `json:"Resources"`
+}
+
+// Error is a SCIM error response.
+type Error struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+// Server serves the SCIM endpoints.
+type Server struct {
+	users       UserRepository
+	groups      GroupRepository
+	baseURL     string
+	maxResults  int
+	bearerToken string
+}
+
+// NewServer creates a new SCIM server. groups may be nil when only users are provisioned.
+func NewServer(users UserRepository, groups GroupRepository, options ...func(*Server)) *Server {
+	s := &Server{
+		users:      users,
+		groups:     groups,
+		maxResults: defaultMaxResults,
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// WithBaseURL sets the absolute URL used in meta.location, e.g. "https://app.example.com/scim/v2".
+func WithBaseURL(baseURL string) func(*Server) {
+	return func(s *Server) {
+		s.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithMaxResults caps the number of resources returned by a single list request.
+func WithMaxResults(max int) func(*Server) {
+	return func(s *Server) {
+		if max > 0 {
+			s.maxResults = max
+		}
+	}
+}
+
+// WithBearerToken requires identity providers to authenticate with the given bearer token.
+func WithBearerToken(token string) func(*Server) {
+	return func(s *Server) {
+		s.bearerToken = token
+	}
+}
+
+// RegisterRoutes mounts the SCIM endpoints under the given prefix. The endpoints provision
+// users and groups, so it returns ErrNoBearerToken and mounts nothing when the server has no
+// bearer token.
+//
+// Example usage:
+//
+//	if err := server.RegisterRoutes(r, "/scim/v2"); err != nil {
+//		log.Fatal(err)
+//	}
+func (s *Server) RegisterRoutes(r *router.Router, prefix string) error {
+	if s.bearerToken == "" {
+		return ErrNoBearerToken
+	}
+	prefix = strings.TrimSuffix(prefix, "/")
+	r.AddRoute(prefix+"/ServiceProviderConfig", s.guard(s.serviceProviderConfig))
+	r.AddRoute(prefix+"/Users", s.guard(s.usersCollection))
+	r.AddRoute(prefix+"/Users/{id}", s.guard(s.userResource))
+	if s.groups != nil {
+		r.AddRoute(prefix+"/Groups", s.guard(s.groupsCollection))
+		r.AddRoute(prefix+"/Groups/{id}", s.guard(s.groupResource))
+	}
+	return nil
+}
+
+// guard enforces bearer token authentication.
+func (s *Server) guard(next router.CustomHandler) router.CustomHandler {
+	return func(ctx *context.Context) {
+		scheme, token, _ := strings.Cut(ctx.GetHeader("Authorization"), " ")
+		if !strings.EqualFold(scheme, "Bearer") || s.bearerToken == "" ||
+			subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), []byte(s.bearerToken)) != 1 {
+			ctx.SetHeader("WWW-Authenticate", `Bearer realm="scim"`)
+			writeError(ctx, http.StatusUnauthorized, "", "invalid bearer token")
+			return
+		}
+		next(ctx)
+	}
+}
+
+func (s *Server) serviceProviderConfig(ctx *context.Context) {
+	if ctx.Req.Method != http.MethodGet {
+		writeError(ctx, http.StatusMethodNotAllowed, "", "method not allowed")
+		return
+	}
+	writeJSON(ctx, http.StatusOK, map[string]interface{}{
+		"schemas":        []string{SchemaSPConfig},
+		"patch":          map[string]bool{"supported": true},
+		"bulk":           map[string]interface{}{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]interface{}{"supported": true, "maxResults": s.maxResults},
+		"changePassword": map[string]bool{"supported": false},
+		"sort":           map[string]bool{"supported": false},
+		"etag":           map[string]bool{"supported": false},
+		"authenticationSchemes": []map[string]string{
+			{"type": "oauthbearertoken", "name": "OAuth Bearer Token", "description": "Authentication with a bearer token"},
+		},
+	})
+}
+
+func (s *Server) usersCollection(ctx *context.Context) {
+	switch ctx.Req.Method {
+	case http.MethodGet:
+		query, ok := s.parseQuery(ctx)
+		if !ok {
+			return
+		}
+		users, total, err := s.users.ListUsers(ctx.Req.Context(), query)
+		if err != nil {
+			writeRepositoryError(ctx, err)
+			return
+		}
+		for i := range users {
+			s.decorateUser(&users[i])
+		}
+		writeList(ctx, users, len(users), total, query.StartIndex)
+	case http.MethodPost:
+		var user User
+		if !decodeResource(ctx, &user) {
+			return
+		}
+		if user.UserName == "" {
+			writeError(ctx, http.StatusBadRequest, "invalidValue", "userName is required")
+			return
+		}
+		user.ID = ""
+		created, err := s.users.CreateUser(ctx.Req.Context(), &user)
+		if err != nil {
+			writeRepositoryError(ctx, err)
+			return
+		}
+		s.decorateUser(created)
+		writeJSON(ctx, http.StatusCreated, created)
+	default:
+		writeError(ctx, http.StatusMethodNotAllowed, "", "method not allowed")
+	}
+}
+
+func (s *Server) userResource(ctx *context.Context) {
+	id, _ := ctx.GetParam("id")
+	switch ctx.Req.Method {
+	case http.MethodGet:
+		user, err := s.users.GetUser(ctx.Req.Context(), id)
+		if err != nil {
+			writeRepositoryError(ctx, err)
+			return
+		}
+		s.decorateUser(user)
+		writeJSON(ctx, http.StatusOK, user)
+	case http.MethodPut:
+		var user User
+		if !decodeResource(ctx, &user) {
+			return
+		}
+		user.ID = id
+		replaced, err := s.users.ReplaceUser(ctx.Req.Context(), &user)
+		if err != nil {
+			writeRepositoryError(ctx, err)
+			return
+		}
+		s.decorateUser(replaced)
+		writeJSON(ctx, http.StatusOK, replaced)
+	case http.MethodPatch:
+		user, err := s.users.GetUser(ctx.Req.Context(), id)
+		if err != nil {
+			writeRepositoryError(ctx, err)
+			return
+		}
+		if !patchResource(ctx, user) {
+			return
+		}
+		user.ID = id
+		patched, err := s.users.ReplaceUser(ctx.Req.Context(), user)
+		if err != nil {
+			writeRepositoryError(ctx, err)
+			return
+		}
+		s.decorateUser(patched)
+		writeJSON(ctx, http.StatusOK, patched)
+	case http.MethodDelete:
+		if err := s.users.DeleteUser(ctx.Req.Context(), id); err != nil {
+			writeRepositoryError(ctx, err)
+			return
+		}
+		ctx.Res.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(ctx, http.StatusMethodNotAllowed, "", "method not allowed")
+	}
+}
+
+func (s *Server) groupsCollection(ctx *context.Context) {
+	switch ctx.Req.Method {
+	case http.MethodGet:
+		query, ok := s.parseQuery(ctx)
+		if !ok {
+			return
+		}
+		groups, total, err := s.groups.ListGroups(ctx.Req.Context(), query)
+		if err != nil {
+			writeRepositoryError(ctx, err)
+			return
+		}
+		for i := range groups {
+			s.decorateGroup(&groups[i])
+		}
+		writeList(ctx, groups, len(groups), total, query.StartIndex)
+	case http.MethodPost:
+		var group Group
+		if !decodeResource(ctx, &group) {
+			return
+		}
+		if group.DisplayName == "" {
+			writeError(ctx, http.StatusBadRequest, "invalidValue", "displayName is required")
+			return
+		}
+		group.ID = ""
+		created, err := s.groups.CreateGroup(ctx.Req.Context(), &group)
+		if err != nil {
+			writeRepositoryError(ctx, err)
+			return
+		}
+		s.decorateGroup(created)
+		writeJSON(ctx, http.StatusCreated, created)
+	default:
+		writeError(ctx, http.StatusMethodNotAllowed, "", "method not allowed")
+	}
+}
+
+func (s *Server) groupResource(ctx *context.Context) {
+	id, _ := ctx.GetParam("id")
+	switch ctx.Req.Method {
+	case http.MethodGet:
+		group, err := s.groups.GetGroup(ctx.Req.Context(), id)
+		if err != nil {
+			writeRepositoryError(ctx, err)
+			return
+		}
+		s.decorateGroup(group)
+		writeJSON(ctx, http.StatusOK, group)
+	case http.MethodPut:
+		var group Group
+		if !decodeResource(ctx, &group) {
+			return
+		}
+		group.ID = id
+		replaced, err := s.groups.ReplaceGroup(ctx.Req.Context(), &group)
+		if err != nil {
+			writeRepositoryError(ctx, err)
+			return
+		}
+		s.decorateGroup(replaced)
+		writeJSON(ctx, http.StatusOK, replaced)
+	case http.MethodPatch:
+		group, err := s.groups.GetGroup(ctx.Req.Context(), id)
+		if err != nil {
+			writeRepositoryError(ctx, err)
+			return
+		}
+		if !patchResource(ctx, group) {
+			return
+		}
+		group.ID = id
+		patched, err := s.groups.ReplaceGroup(ctx.Req.Context(), group)
+		if err != nil {
+			writeRepositoryError(ctx, err)
+			return
+		}
+		s.decorateGroup(patched)
+		writeJSON(ctx, http.StatusOK, patched)
+	case http.MethodDelete:
+		if err := s.groups.DeleteGroup(ctx.Req.Context(), id); err != nil {
+			writeRepositoryError(ctx, err)
+			return
+		}
+		ctx.Res.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(ctx, http.StatusMethodNotAllowed, "", "method not allowed")
+	}
+}
+
+// parseQuery reads the filter and pagination parameters of a list request.
+func (s *Server) parseQuery(ctx *context.Context) (Query, bool) {
+	query := Query{StartIndex: 1, Count: s.maxResults}
+	values := ctx.Req.URL.Query()
+
+	if raw := values.Get("filter"); raw != "" {
+		filter, err := ParseFilter(raw)
+		if err != nil {
+			writeError(ctx, http.StatusBadRequest, "invalidFilter", err.Error())
+			return query, false
+		}
+		query.Filter = filter
+	}
+	if raw := values.Get("startIndex"); raw != "" {
+		// Values below 1 are interpreted as 1
+		if n, err := strconv.Atoi(raw); err == nil && n > 1 {
+			query.StartIndex = n
+		}
+	}
+	if raw := values.Get("count"); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil {
+			if n < 0 {
+				n = 0
+			}
+			if n < s.maxResults {
+				query.Count = n
+			}
+		}
+	}
+	query.SortBy = values.Get("sortBy")
+	query.SortOrder = values.Get("sortOrder")
+	return query, true
+}
+
+func (s *Server) decorateUser(user *User) {
+	user.Schemas = []string{SchemaUser}
+	if user.Meta == nil {
+		user.Meta = &Meta{}
+	}
+	user.Meta.ResourceType = "User"
+	user.Meta.Location = s.baseURL + "/Users/" + user.ID
+}
+
+func (s *Server) decorateGroup(group *Group) {
+	group.Schemas = []string{SchemaGroup}
+	if group.Meta == nil {
+		group.Meta = &Meta{}
+	}
+	group.Meta.ResourceType = "Group"
+	group.Meta.Location = s.baseURL + "/Groups/" + group.ID
+}
+
+// ToAttributes converts a resource to a generic attribute map, as used by Filter.Match.
+func ToAttributes(resource interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return nil, err
+	}
+	attrs := map[string]interface{}{}
+	err = json.Unmarshal(data, &attrs)
+	return attrs, err
+}
+
+// decodeResource decodes the request body, up to maxBodySize, writing a SCIM error on failure.
+func decodeResource(ctx *context.Context, v interface{}) bool {
+	body := http.MaxBytesReader(ctx.Res, ctx.Req.Body, maxBodySize)
+	if err := json.NewDecoder(body).Decode(v); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(ctx, http.StatusRequestEntityTooLarge, "", "request body too large")
+			return false
+		}
+		writeError(ctx, http.StatusBadRequest, "invalidSyntax", err.Error())
+		return false
+	}
+	return true
+}
+
+// patchResource applies the PATCH body of the request to resource.
+func patchResource(ctx *context.Context, resource interface{}) bool {
+	var patch PatchRequest
+	if !decodeResource(ctx, &patch) {
+		return false
+	}
+	attrs, err := ToAttributes(resource)
+	if err == nil {
+		err = ApplyPatch(attrs, patch.Operations)
+	}
+	if err != nil {
+		writeError(ctx, http.StatusBadRequest, "invalidPath", err.Error())
+		return false
+	}
+	data, err := json.Marshal(attrs)
+	if err == nil {
+		err = json.Unmarshal(data, resource)
+	}
+	if err != nil {
+		writeError(ctx, http.StatusBadRequest, "invalidValue", err.Error())
+		return false
+	}
+	return true
+}
+
+func writeList(ctx *context.Context, resources interface{}, count, total, startIndex int) {
+	writeJSON(ctx, http.StatusOK, ListResponse{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: count,
+		Resources:    resources,
+	})
+}
+
+func writeRepositoryError(ctx *context.Context, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		writeError(ctx, http.StatusNotFound, "", "resource not found")
+	case errors.Is(err, ErrConflict):
+		writeError(ctx, http.StatusConflict, "uniqueness", "resource already exists")
+	default:
+		log.Printf("SCIM repository error: %v", err)
+		writeError(ctx, http.StatusInternalServerError, "", "internal server error")
+	}
+}
+
+func writeError(ctx *context.Context, status int, scimType, detail string) {
+	writeJSON(ctx, status, Error{
+		Schemas:  []string{SchemaError},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   detail,
+	})
+}
+
+func writeJSON(ctx *context.Context, status int, v interface{}) {
+	ctx.SetHeader("Content-Type", ContentType)
+	ctx.Res.WriteHeader(status)
+	if err := json.NewEncoder(ctx.Res).Encode(v); err != nil {
+		log.Printf("Failed to encode SCIM response: %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrSignedURLUnsupported is returned by SignedURL when the storage was not configured to sign
+// URLs.
+var ErrSignedURLUnsupported = errors.New("storage: signed URLs are not configured")
+
+// LocalStorage stores files in a directory. Signed URLs point to Handler, mounted under the
+// base URL given to WithLocalSignedURLs.
+type LocalStorage struct {
+	dir     string
+	baseURL string
+	secret  []byte
+}
+
+// NewLocalStorage stores files under dir, creating it when needed.
+func NewLocalStorage(dir string, options ...func(*LocalStorage)) (*LocalStorage, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, fmt.Errorf("storage: creating %s: %w", dir, err)
+	}
+	l := &LocalStorage{dir: dir}
+	for _, option := range options {
+		option(l)
+	}
+	return l, nil
+}
+
+// WithLocalSignedURLs enables SignedURL, returning URLs under baseURL signed with secret.
+//
+// Example usage:
+//
+//	store, err := storage.NewLocalStorage("uploads", storage.WithLocalSignedURLs("https://example.com/files", secret))
+//	r.Mux.PathPrefix("/files/").Handler(http.StripPrefix("/files", store.Handler()))
+func WithLocalSignedURLs(baseURL string, secret []byte) func(*LocalStorage) {
+	return func(l *LocalStorage) {
+		l.baseURL = strings.TrimRight(baseURL, "/")
+		l.secret = secret
+	}
+}
+
+// Dir returns the directory holding the files.
+func (l *LocalStorage) Dir() string {
+	return l.dir
+}
+
+// path returns the file of key, which cannot escape the directory.
+func (l *LocalStorage) path(key string) (string, error) {
+	clean := path.Clean("/" + key)
+	if clean == "/" {
+		return "", fmt.Errorf("storage: invalid key %q", key)
+	}
+	return filepath.Join(l.dir, filepath.FromSlash(clean)), nil
+}
+
+// Put writes to a temporary file renamed once complete, so readers never see partial files.
+func (l *LocalStorage) Put(ctx context.Context, key string, body io.Reader, contentType string) error {
+	name, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0750); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+func (l *LocalStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	name, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+func (l *LocalStorage) Delete(ctx context.Context, key string) error {
+	name, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(name); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (l *LocalStorage) SignedURL(ctx context.Context, key string, expires time.Duration) (string, error) {
+	if l.secret == nil {
+		return "", ErrSignedURLUnsupported
+	}
+	clean := path.Clean("/" + key)
+	expiry := strconv.FormatInt(time.Now().Add(expires).Unix(), 10)
+	query := url.Values{"expires": {expiry}, "signature": {l.sign(clean, expiry)}}
+	return l.baseURL + (&url.URL{Path: clean}).EscapedPath() + "?" + query.Encode(), nil
+}
+
+func (l *LocalStorage) sign(key, expiry string) string {
+	mac := hmac.New(sha256.New, l.secret)
+	mac.Write([]byte(key + "\n" + expiry))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Handler serves the files of signed URLs, rejecting requests with a missing, invalid or
+// expired signature.
+func (l *LocalStorage) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if l.secret == nil || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+			http.NotFound(w, r)
+			return
+		}
+		key := path.Clean("/" + r.URL.Path)
+		expiry := r.URL.Query().Get("expires")
+		unix, err := strconv.ParseInt(expiry, 10, 64)
+		if err != nil || time.Now().Unix() > unix ||
+			!hmac.Equal([]byte(l.sign(key, expiry)), []byte(r.URL.Query().Get("signature"))) {
+			http.Error(w, "Invalid or expired signature", http.StatusForbidden)
+			return
+		}
+		name, err := l.path(key)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		file, err := os.Open(name)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer file.Close()
+		info, err := file.Stat()
+		if err != nil || info.IsDir() {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeContent(w, r, info.Name(), info.ModTime(), file)
+	})
+}
@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/sigv4"
+)
+
+// S3Config configures an S3Storage.
+type S3Config struct {
+	// Endpoint is the URL of an S3 compatible service, e.g. http://localhost:9000 for MinIO.
+	// Empty for Amazon S3.
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// PathStyle addresses the bucket in the path rather than the host name, as most S3
+	// compatible services require.
+	PathStyle bool
+	// Client sends the requests, http.DefaultClient when nil.
+	Client *http.Client
+}
+
+// S3Storage stores files in an S3 bucket, signing requests with AWS Signature Version 4 so no
+// SDK is needed.
+type S3Storage struct {
+	config S3Config
+	signer *sigv4.Signer
+}
+
+// NewS3Storage creates a storage for the bucket described by cfg.
+func NewS3Storage(cfg S3Config) *S3Storage {
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://s3." + cfg.Region + ".amazonaws.com"
+	}
+	cfg.Endpoint = strings.TrimRight(cfg.Endpoint, "/")
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+	signer := &sigv4.Signer{AccessKey: cfg.AccessKey, SecretKey: cfg.SecretKey, Region: cfg.Region, Service: "s3"}
+	return &S3Storage{config: cfg, signer: signer}
+}
+
+// NewGCSStorage creates a storage for a Google Cloud Storage bucket through its S3 compatible
+// XML API, authenticated with an HMAC key of a service account.
+func NewGCSStorage(bucket, accessID, secret string) *S3Storage {
+	return NewS3Storage(S3Config{
+		Endpoint:  "https://storage.googleapis.com",
+		Region:    "auto",
+		Bucket:    bucket,
+		AccessKey: accessID,
+		SecretKey: secret,
+		PathStyle: true,
+	})
+}
+
+// objectURL returns the URL of key.
+func (s *S3Storage) objectURL(key string) (*url.URL, error) {
+	u, err := url.Parse(s.config.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	key = strings.TrimLeft(key, "/")
+	if s.config.PathStyle {
+		u.Path = "/" + s.config.Bucket + "/" + key
+	} else {
+		u.Host = s.config.Bucket + "." + u.Host
+		u.Path = "/" + key
+	}
+	u.RawPath = sigv4.URIEncode(u.Path, false)
+	return u, nil
+}
+
+func (s *S3Storage) Put(ctx context.Context, key string, body io.Reader, contentType string) error {
+	// S3 requires the length of uploads, so bodies of unknown size are buffered
+	size := int64(-1)
+	if seeker, ok := body.(io.Seeker); ok {
+		if end, err := seeker.Seek(0, io.SeekEnd); err == nil {
+			start, _ := seeker.Seek(0, io.SeekStart)
+			size = end - start
+		}
+	}
+	if size < 0 {
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return err
+		}
+		body, size = bytes.NewReader(data), int64(len(data))
+	}
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	resp, err := s.do(ctx, http.MethodPut, key, io.NopCloser(body), size, header)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *S3Storage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, nil, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, 0, nil)
+	if err == ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// do sends a signed request for key and turns error responses into errors.
+func (s *S3Storage) do(ctx context.Context, method, key string, body io.ReadCloser, size int64, header http.Header) (*http.Response, error) {
+	u, err := s.objectURL(key)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	// The payload is left unsigned, so bodies are streamed
+	s.signer.Sign(req, sigv4.UnsignedPayload, time.Now())
+	resp, err := s.config.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("storage: %s %s: %w", method, key, err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("storage: %s %s: %s: %s", method, key, resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return resp, nil
+}
+
+// SignedURL returns a presigned GET URL valid for expires, at most seven days.
+func (s *S3Storage) SignedURL(ctx context.Context, key string, expires time.Duration) (string, error) {
+	u, err := s.objectURL(key)
+	if err != nil {
+		return "", err
+	}
+	return s.signer.Presign(http.MethodGet, u, expires, time.Now()).String(), nil
+}
@@ -0,0 +1,75 @@
+/*
+Package storage stores files behind one Storage interface, on the local disk or in object
+storage (Amazon S3 and S3 compatible services, Google Cloud Storage).
+
+The middleware makes the storage of the application available to the upload middleware and to
+the upload helpers of the request context, so moving uploads from the disk to a bucket only
+changes the storage given at startup.
+
+Usage:
+
+	store := storage.NewS3Storage(storage.S3Config{
+		Region:    "eu-west-1",
+		Bucket:    "uploads",
+		AccessKey: os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+	})
+	r := router.NewRouter(router.WithStorage(store))
+
+	// In a handler
+	key, err := ctx.SaveUpload("avatar", "avatars/")
+	url, err := store.SignedURL(ctx.Req.Context(), key, 15*time.Minute)
+*/
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+)
+
+// ErrNotFound is returned when getting a file that does not exist.
+var ErrNotFound = errors.New("storage: file not found")
+
+// Storage stores files under slash separated keys, e.g. avatars/42.png.
+type Storage interface {
+	// Put stores the content of body under key, replacing any existing file.
+	Put(ctx context.Context, key string, body io.Reader, contentType string) error
+	// Get opens the file stored under key, or returns ErrNotFound.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the file stored under key. Deleting a missing file is not an error.
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a URL granting read access to the file for the given duration.
+	SignedURL(ctx context.Context, key string, expires time.Duration) (string, error)
+}
+
+type storageKey struct{}
+
+// WithStorage returns a copy of ctx carrying s.
+func WithStorage(ctx context.Context, s Storage) context.Context {
+	return context.WithValue(ctx, storageKey{}, s)
+}
+
+// FromContext returns the storage carried by ctx, see Middleware.
+func FromContext(ctx context.Context) (Storage, bool) {
+	s, ok := ctx.Value(storageKey{}).(Storage)
+	return s, ok
+}
+
+// Middleware makes a storage available in the request context.
+type Middleware struct {
+	storage Storage
+}
+
+// NewMiddleware creates a middleware providing s to the handlers.
+func NewMiddleware(s Storage) *Middleware {
+	return &Middleware{storage: s}
+}
+
+func (m *Middleware) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(WithStorage(r.Context(), m.storage)))
+	})
+}
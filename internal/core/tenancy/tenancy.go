@@ -0,0 +1,406 @@
+/*
+Package tenancy resolves the tenant of each request and keeps per-tenant resources apart.
+
+The middleware resolves the tenant from the subdomain, a header or a token claim and stores
+its ID with the request, where ctx.Tenant returns it. Response caching keys its entries by
+tenant automatically. Scoped holds one instance of a resource, such as a database connection
+or schema, per tenant and can be registered in the DI container.
+
+Claims are stored by the authentication middleware, which runs after the tenancy middleware
+of the router; tenants read from them are resolved on first use instead, see Middleware.Defer.
+
+Tenants resolved from a header or subdomain are chosen by the client. Check them with
+WithLookup, or Scoped.WithLookup, before resources are created for them.
+
+Usage:
+
+	databases := tenancy.NewScoped(func(ctx context.Context, tenant string) (*sql.DB, error) {
+		return sql.Open("postgres", "postgres://db/"+tenant)
+	}).WithLookup(tenants.Exists)
+	container.Register(func() *tenancy.Scoped[*sql.DB] { return databases })
+
+	App := LessGo.App(LessGo.WithTenancy(tenancy.FirstOf(
+		tenancy.FromSubdomain("example.com"),
+		tenancy.FromHeader("X-Tenant-ID"),
+	)))
+	App.Get("/orders", func(ctx *LessGo.Context) {
+		db, err := databases.For(ctx.Req)
+		// ...
+	})
+*/
+package tenancy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+)
+
+// ErrNoTenant is returned when a request has no tenant.
+var ErrNoTenant = errors.New("no tenant resolved for the request")
+
+// ErrInvalidTenant is returned for tenant IDs that are malformed or not found by the lookup.
+var ErrInvalidTenant = errors.New("invalid tenant")
+
+// ValidID reports whether tenant is a well-formed tenant ID: 1 to 63 letters, digits, dashes
+// and underscores. The middleware rejects requests naming other IDs.
+func ValidID(tenant string) bool {
+	if tenant == "" || len(tenant) > 63 {
+		return false
+	}
+	for _, c := range tenant {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9', c == '-', c == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Resolver finds the tenant ID of a request. It reports false when the request names none.
+type Resolver func(r *http.Request) (string, bool)
+
+// FromSubdomain resolves the tenant from the first label of hosts below the base domain, e.g.
+// acme for acme.example.com. The www subdomain is ignored.
+func FromSubdomain(baseDomain string) Resolver {
+	suffix := "." + strings.ToLower(strings.Trim(baseDomain, "."))
+	return func(r *http.Request) (string, bool) {
+		host := strings.ToLower(r.Host)
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if !strings.HasSuffix(host, suffix) {
+			return "", false
+		}
+		labels := strings.Split(strings.TrimSuffix(host, suffix), ".")
+		tenant := labels[len(labels)-1]
+		if tenant == "" || tenant == "www" {
+			return "", false
+		}
+		return tenant, true
+	}
+}
+
+// FromHeader resolves the tenant from a request header, e.g. X-Tenant-ID.
+func FromHeader(name string) Resolver {
+	return func(r *http.Request) (string, bool) {
+		tenant := strings.TrimSpace(r.Header.Get(name))
+		return tenant, tenant != ""
+	}
+}
+
+// FromClaim resolves the tenant from a claim of the token stored by the authentication
+// middleware, e.g. tenant_id.
+func FromClaim(name string) Resolver {
+	return func(r *http.Request) (string, bool) {
+		value, _ := middleware.GetValue(r, middleware.ClaimsKey)
+		var claims middleware.Claims
+		switch value := value.(type) {
+		case middleware.Claims:
+			claims = value
+		case map[string]interface{}:
+			claims = value
+		}
+		tenant := claims.String(name)
+		return tenant, tenant != ""
+	}
+}
+
+// FirstOf returns the tenant of the first resolver that finds one.
+func FirstOf(resolvers ...Resolver) Resolver {
+	return func(r *http.Request) (string, bool) {
+		for _, resolve := range resolvers {
+			if tenant, ok := resolve(r); ok {
+				return tenant, true
+			}
+		}
+		return "", false
+	}
+}
+
+// Middleware resolves the tenant of each request and stores it under middleware.TenantKey.
+type Middleware struct {
+	resolve  Resolver
+	required bool
+	exists   func(ctx context.Context, tenant string) (bool, error)
+}
+
+// New creates the tenancy middleware.
+func New(resolve Resolver, options ...func(*Middleware)) *Middleware {
+	m := &Middleware{resolve: resolve}
+	for _, option := range options {
+		option(m)
+	}
+	return m
+}
+
+// WithRequired rejects requests without a tenant with 400 Bad Request.
+func WithRequired() func(*Middleware) {
+	return func(m *Middleware) {
+		m.required = true
+	}
+}
+
+// WithLookup checks resolved tenants, e.g. against the tenants table. Requests for unknown
+// tenants get a 404.
+func WithLookup(exists func(ctx context.Context, tenant string) (bool, error)) func(*Middleware) {
+	return func(m *Middleware) {
+		m.exists = exists
+	}
+}
+
+func (m *Middleware) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant, rejected := m.check(r)
+		if rejected != accepted {
+			rejected.respond(w)
+			return
+		}
+		if tenant != "" {
+			r = middleware.SetValue(r, middleware.TenantKey, tenant)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Defer resolves the tenant like Handle when the request names one, and otherwise again once
+// the middlewares after it stored what the resolver needs, such as the claims of a token.
+// Until then middleware.RequestTenant resolves it on demand, so caches running after the
+// authentication middleware key their entries by tenant. Requests are only rejected for a
+// missing tenant by Require, which runs right before the route handler.
+//
+// Example usage:
+//
+//	tenants := tenancy.New(tenancy.FromClaim("tid"), tenancy.WithRequired())
+//	handler := tenants.Defer(authenticate(tenants.Require(routes)))
+func (m *Middleware) Defer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r, values := middleware.RequestValues(r)
+		if _, ok := m.resolve(r); ok {
+			m.Handle(next).ServeHTTP(w, r)
+			return
+		}
+		pending := &pendingTenant{m: m, r: r}
+		values.Set(pendingKey, pending)
+		values.Set(middleware.TenantKey, func() string {
+			tenant, _ := pending.resolve()
+			return tenant
+		})
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Require enforces the options of the middleware for tenants deferred by Defer: requests
+// without a tenant are rejected when it is required, unknown and malformed tenants always.
+func (m *Middleware) Require(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		value, _ := middleware.GetValue(r, pendingKey)
+		pending, ok := value.(*pendingTenant)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		tenant, rejected := pending.resolve()
+		if rejected == accepted && tenant == "" && m.required {
+			rejected = missingTenant
+		}
+		if rejected != accepted {
+			rejected.respond(w)
+			return
+		}
+		if tenant != "" {
+			r = middleware.SetValue(r, middleware.TenantKey, tenant)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rejection is why the tenant of a request was rejected.
+type rejection int
+
+const (
+	accepted rejection = iota
+	missingTenant
+	invalidTenant
+	unknownTenant
+	lookupFailed
+)
+
+func (r rejection) respond(w http.ResponseWriter) {
+	switch r {
+	case missingTenant:
+		http.Error(w, "Tenant required", http.StatusBadRequest)
+	case invalidTenant:
+		http.Error(w, "Invalid tenant", http.StatusBadRequest)
+	case unknownTenant:
+		http.Error(w, "Unknown tenant", http.StatusNotFound)
+	default:
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// check resolves and validates the tenant of r.
+func (m *Middleware) check(r *http.Request) (string, rejection) {
+	tenant, ok := m.resolve(r)
+	if !ok {
+		if m.required {
+			return "", missingTenant
+		}
+		return "", accepted
+	}
+	if !ValidID(tenant) {
+		return "", invalidTenant
+	}
+	if m.exists != nil {
+		found, err := m.exists(r.Context(), tenant)
+		if err != nil {
+			return "", lookupFailed
+		}
+		if !found {
+			return "", unknownTenant
+		}
+	}
+	return tenant, accepted
+}
+
+// pendingKey stores the tenant resolution deferred by Defer.
+const pendingKey = "tenancy.pending"
+
+// pendingTenant resolves the tenant of a request once the values its resolver reads are stored.
+type pendingTenant struct {
+	m        *Middleware
+	r        *http.Request
+	mu       sync.Mutex
+	done     bool
+	tenant   string
+	rejected rejection
+}
+
+// resolve returns the tenant and why it was rejected. Until the resolver finds a tenant
+// nothing is remembered, later middlewares may still store what it reads.
+func (p *pendingTenant) resolve() (string, rejection) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.done {
+		return p.tenant, p.rejected
+	}
+	if _, ok := p.m.resolve(p.r); !ok {
+		return "", accepted
+	}
+	p.done = true
+	p.tenant, p.rejected = p.m.check(p.r)
+	return p.tenant, p.rejected
+}
+
+// Scoped holds one instance of a resource per tenant, created on first use.
+type Scoped[T any] struct {
+	mu        sync.Mutex
+	instances map[string]*scopedInstance[T]
+	create    func(ctx context.Context, tenant string) (T, error)
+	exists    func(ctx context.Context, tenant string) (bool, error)
+}
+
+// scopedInstance is the instance of a tenant, ready once its creation finished.
+type scopedInstance[T any] struct {
+	ready    chan struct{}
+	instance T
+	err      error
+}
+
+// NewScoped creates a per-tenant holder using create to build the instance of a tenant.
+func NewScoped[T any](create func(ctx context.Context, tenant string) (T, error)) *Scoped[T] {
+	return &Scoped[T]{instances: make(map[string]*scopedInstance[T]), create: create}
+}
+
+// WithLookup checks tenants before their instance is created, e.g. against the tenants table,
+// so instances are only created for known tenants. Get returns ErrInvalidTenant for others.
+//
+// Example usage:
+//
+//	databases := tenancy.NewScoped(openDatabase).WithLookup(tenants.Exists)
+func (s *Scoped[T]) WithLookup(exists func(ctx context.Context, tenant string) (bool, error)) *Scoped[T] {
+	s.exists = exists
+	return s
+}
+
+// For returns the instance of the request's tenant. It returns ErrNoTenant when the request
+// has none.
+func (s *Scoped[T]) For(r *http.Request) (T, error) {
+	tenant := middleware.RequestTenant(r)
+	if tenant == "" {
+		var zero T
+		return zero, ErrNoTenant
+	}
+	return s.Get(r.Context(), tenant)
+}
+
+// Get returns the instance of the tenant, creating it on first use. Malformed tenant IDs and
+// tenants rejected by the lookup return ErrInvalidTenant. Instances of different tenants are
+// created concurrently; failed creations are retried on the next call.
+func (s *Scoped[T]) Get(ctx context.Context, tenant string) (T, error) {
+	var zero T
+	if !ValidID(tenant) {
+		return zero, ErrInvalidTenant
+	}
+	s.mu.Lock()
+	scoped, ok := s.instances[tenant]
+	if ok {
+		s.mu.Unlock()
+		select {
+		case <-scoped.ready:
+			return scoped.instance, scoped.err
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		}
+	}
+	scoped = &scopedInstance[T]{ready: make(chan struct{})}
+	s.instances[tenant] = scoped
+	s.mu.Unlock()
+
+	scoped.instance, scoped.err = s.build(ctx, tenant)
+	if scoped.err != nil {
+		s.mu.Lock()
+		delete(s.instances, tenant)
+		s.mu.Unlock()
+	}
+	close(scoped.ready)
+	return scoped.instance, scoped.err
+}
+
+// build checks the tenant with the lookup and creates its instance.
+func (s *Scoped[T]) build(ctx context.Context, tenant string) (T, error) {
+	if s.exists != nil {
+		found, err := s.exists(ctx, tenant)
+		if err != nil {
+			var zero T
+			return zero, err
+		}
+		if !found {
+			var zero T
+			return zero, ErrInvalidTenant
+		}
+	}
+	return s.create(ctx, tenant)
+}
+
+// Each calls fn for every instance created so far, e.g. to close connections on shutdown.
+func (s *Scoped[T]) Each(fn func(tenant string, instance T)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for tenant, scoped := range s.instances {
+		select {
+		case <-scoped.ready:
+			if scoped.err == nil {
+				fn(tenant, scoped.instance)
+			}
+		default:
+			// Still being created
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package twofactor
+
+import (
+	stdcontext "context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	"github.com/hokamsingh/lessgo/internal/core/cache"
+)
+
+const recoveryAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// RecoveryCodes manages single-use recovery codes, letting users in when they lost their
+// authenticator. Only hashes of the codes are kept in the store, and a code is used up by an
+// atomic add of a marker, so it cannot be used twice even by concurrent requests.
+type RecoveryCodes struct {
+	store  cache.Store
+	count  int
+	prefix string
+}
+
+// NewRecoveryCodes creates a manager keeping the codes of each account in store. Use a
+// persistent store such as Redis with persistence: codes in memory are lost on restart.
+func NewRecoveryCodes(store cache.Store, options ...func(*RecoveryCodes)) *RecoveryCodes {
+	r := &RecoveryCodes{store: store, count: 10, prefix: "2fa:recovery:"}
+	for _, option := range options {
+		option(r)
+	}
+	return r
+}
+
+// WithRecoveryCount sets how many codes Generate returns, 10 by default.
+func WithRecoveryCount(count int) func(*RecoveryCodes) {
+	return func(r *RecoveryCodes) {
+		r.count = count
+	}
+}
+
+// WithRecoveryPrefix sets the prefix of the store keys, "2fa:recovery:" by default.
+func WithRecoveryPrefix(prefix string) func(*RecoveryCodes) {
+	return func(r *RecoveryCodes) {
+		r.prefix = prefix
+	}
+}
+
+// normalizeRecoveryCode lets users type codes in any case, with or without the dash.
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(normalizeRecoveryCode(code)))
+	return hex.EncodeToString(sum[:])
+}
+
+// Generate replaces the codes of account with new ones, formatted like "k3m9p-x2qr7", and
+// returns them to be shown to the user once.
+//
+// Example usage:
+//
+//	codes, err := recovery.Generate(ctx.Req.Context(), user.ID)
+func (r *RecoveryCodes) Generate(ctx stdcontext.Context, account string) ([]string, error) {
+	codes := make([]string, r.count)
+	hashes := make([]string, r.count)
+	random := make([]byte, 1)
+	for i := range codes {
+		code := make([]byte, 0, 11)
+		for len(code) < 11 {
+			if len(code) == 5 {
+				code = append(code, '-')
+			}
+			if _, err := rand.Read(random); err != nil {
+				return nil, err
+			}
+			// Bytes past the last multiple of the alphabet size would favour its first letters
+			if int(random[0]) < 256/len(recoveryAlphabet)*len(recoveryAlphabet) {
+				code = append(code, recoveryAlphabet[int(random[0])%len(recoveryAlphabet)])
+			}
+		}
+		codes[i] = string(code)
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+	if err := r.Delete(ctx, account); err != nil {
+		return nil, err
+	}
+	return codes, r.save(ctx, account, hashes)
+}
+
+func (r *RecoveryCodes) load(ctx stdcontext.Context, account string) ([]string, error) {
+	data, found, err := r.store.Get(ctx, r.prefix+account)
+	if err != nil || !found {
+		return nil, err
+	}
+	var hashes []string
+	return hashes, json.Unmarshal(data, &hashes)
+}
+
+func (r *RecoveryCodes) save(ctx stdcontext.Context, account string, hashes []string) error {
+	data, err := json.Marshal(hashes)
+	if err != nil {
+		return err
+	}
+	return r.store.Set(ctx, r.prefix+account, data, 0)
+}
+
+// usedKey is the key of the marker recording that the code hashed to hash was used.
+func (r *RecoveryCodes) usedKey(account, hash string) string {
+	return r.prefix + account + ":used:" + hash
+}
+
+// Use consumes code and reports whether it was one of the remaining codes of account.
+func (r *RecoveryCodes) Use(ctx stdcontext.Context, account, code string) (bool, error) {
+	hashes, err := r.load(ctx, account)
+	if err != nil {
+		return false, err
+	}
+	hash := hashRecoveryCode(code)
+	for _, candidate := range hashes {
+		if subtle.ConstantTimeCompare([]byte(candidate), []byte(hash)) == 1 {
+			return cache.Add(ctx, r.store, r.usedKey(account, hash), []byte{1}, 0)
+		}
+	}
+	return false, nil
+}
+
+// Remaining returns how many unused codes account has, to suggest generating new ones.
+func (r *RecoveryCodes) Remaining(ctx stdcontext.Context, account string) (int, error) {
+	hashes, err := r.load(ctx, account)
+	if err != nil {
+		return 0, err
+	}
+	remaining := 0
+	for _, hash := range hashes {
+		_, used, err := r.store.Get(ctx, r.usedKey(account, hash))
+		if err != nil {
+			return 0, err
+		}
+		if !used {
+			remaining++
+		}
+	}
+	return remaining, nil
+}
+
+// Delete removes the codes of account, e.g. when two-factor authentication is disabled.
+func (r *RecoveryCodes) Delete(ctx stdcontext.Context, account string) error {
+	hashes, err := r.load(ctx, account)
+	if err != nil {
+		return err
+	}
+	for _, hash := range hashes {
+		if err := r.store.Delete(ctx, r.usedKey(account, hash)); err != nil {
+			return err
+		}
+	}
+	return r.store.Delete(ctx, r.prefix+account)
+}
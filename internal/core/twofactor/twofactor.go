@@ -0,0 +1,268 @@
+/*
+Package twofactor implements time-based one-time passwords (TOTP, RFC 6238) and recovery
+codes for two-factor authentication.
+
+Enrollment generates a secret, shows it to the user as a QR code for their authenticator app
+and stores it once the user confirmed a first code. Later logins check a code, or consume a
+recovery code when the device is lost, before marking the session as verified.
+
+Usage:
+
+	totp := twofactor.New("Acme")
+
+	// Enrollment
+	secret, _ := twofactor.GenerateSecret()
+	png, _ := totp.QRCode(user.Email, secret, 256)
+
+	// Login
+	if totp.Validate(user.TOTPSecret, form.Code) {
+		session.TwoFactor = true
+	}
+
+	// Routes requiring the second factor
+	controller.RegisterGuard("2fa", twofactor.Guard)
+*/
+package twofactor
+
+import (
+	stdcontext "context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/cache"
+	"github.com/hokamsingh/lessgo/internal/core/context"
+)
+
+// ErrInvalidSecret is returned when a secret is not valid base32.
+var ErrInvalidSecret = errors.New("twofactor: invalid secret")
+
+var secretEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret returns a random 160 bit secret encoded in base32, the form authenticator
+// apps expect.
+func GenerateSecret() (string, error) {
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return secretEncoding.EncodeToString(secret), nil
+}
+
+func decodeSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.TrimRight(strings.ReplaceAll(secret, " ", ""), "="))
+	key, err := secretEncoding.DecodeString(secret)
+	if err != nil || len(key) == 0 {
+		return nil, ErrInvalidSecret
+	}
+	return key, nil
+}
+
+// TOTP generates and validates time-based one-time passwords.
+type TOTP struct {
+	issuer    string
+	digits    int
+	period    time.Duration
+	skew      int
+	algorithm string
+	store     cache.Store
+	now       func() time.Time
+}
+
+// New creates a TOTP named issuer in authenticator apps, with 6 digit codes changing every
+// 30 seconds: the defaults all apps support.
+func New(issuer string, options ...func(*TOTP)) *TOTP {
+	t := &TOTP{issuer: issuer, digits: 6, period: 30 * time.Second, skew: 1, algorithm: "SHA1", now: time.Now}
+	for _, option := range options {
+		option(t)
+	}
+	return t
+}
+
+// WithDigits sets the length of codes, 6 by default. Some apps only support 6. Lengths outside
+// 6 to 9 are ignored: shorter codes are too easy to guess and longer ones overflow the 31 bit
+// value codes are derived from.
+func WithDigits(digits int) func(*TOTP) {
+	return func(t *TOTP) {
+		if digits >= 6 && digits <= 9 {
+			t.digits = digits
+		}
+	}
+}
+
+// WithPeriod sets how long a code is valid, 30 seconds by default. Periods that are not a
+// whole number of seconds, at least one, are ignored since apps count in seconds.
+func WithPeriod(period time.Duration) func(*TOTP) {
+	return func(t *TOTP) {
+		if period >= time.Second && period%time.Second == 0 {
+			t.period = period
+		}
+	}
+}
+
+// WithSkew sets how many periods before and after the current one are accepted, to allow for
+// clock drift and slow typing. The default of 1 accepts the previous and the next code.
+func WithSkew(periods int) func(*TOTP) {
+	return func(t *TOTP) {
+		t.skew = periods
+	}
+}
+
+// WithAlgorithm sets the HMAC hash, "SHA1" (default), "SHA256" or "SHA512".
+func WithAlgorithm(algorithm string) func(*TOTP) {
+	return func(t *TOTP) {
+		t.algorithm = strings.ToUpper(algorithm)
+	}
+}
+
+// WithReplayStore records the last code used by each account in store, so ValidateOnce
+// refuses a code seen before, e.g. by a shoulder surfer logging in right after the user.
+func WithReplayStore(store cache.Store) func(*TOTP) {
+	return func(t *TOTP) {
+		t.store = store
+	}
+}
+
+func (t *TOTP) hash() func() hash.Hash {
+	switch t.algorithm {
+	case "SHA256":
+		return sha256.New
+	case "SHA512":
+		return sha512.New
+	default:
+		return sha1.New
+	}
+}
+
+// code computes the HOTP value (RFC 4226) of counter.
+func (t *TOTP) code(key []byte, counter int64) string {
+	mac := hmac.New(t.hash(), key)
+	mac.Write(binary.BigEndian.AppendUint64(nil, uint64(counter)))
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:]) & 0x7fffffff
+	modulo := uint32(1)
+	for i := 0; i < t.digits; i++ {
+		modulo *= 10
+	}
+	return fmt.Sprintf("%0*d", t.digits, value%modulo)
+}
+
+func (t *TOTP) counter(at time.Time) int64 {
+	return at.Unix() / int64(t.period/time.Second)
+}
+
+// Code returns the code of secret at a given time, e.g. to test enrollment.
+func (t *TOTP) Code(secret string, at time.Time) (string, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return t.code(key, t.counter(at)), nil
+}
+
+// match returns the counter code was generated for within the accepted window.
+func (t *TOTP) match(secret, code string) (int64, bool) {
+	key, err := decodeSecret(secret)
+	code = strings.ReplaceAll(code, " ", "")
+	if err != nil || len(code) != t.digits {
+		return 0, false
+	}
+	current := t.counter(t.now())
+	for delta := -t.skew; delta <= t.skew; delta++ {
+		if hmac.Equal([]byte(t.code(key, current+int64(delta))), []byte(code)) {
+			return current + int64(delta), true
+		}
+	}
+	return 0, false
+}
+
+// Validate reports whether code is valid for secret now, within the skew.
+func (t *TOTP) Validate(secret, code string) bool {
+	_, ok := t.match(secret, code)
+	return ok
+}
+
+// ValidateOnce is Validate refusing codes already used by account, which requires
+// WithReplayStore. Each code is claimed with an atomic add to the store, so of concurrent
+// logins with the same code only one succeeds; a store shared by all instances, e.g. Redis,
+// covers every login.
+//
+// Example usage:
+//
+//	ok, err := totp.ValidateOnce(ctx.Req.Context(), user.ID, user.TOTPSecret, form.Code)
+func (t *TOTP) ValidateOnce(ctx stdcontext.Context, account, secret, code string) (bool, error) {
+	counter, ok := t.match(secret, code)
+	if !ok || t.store == nil {
+		return ok, nil
+	}
+	// Codes expire after the window, and so can the claim
+	ttl := time.Duration(2*t.skew+1) * t.period
+	return cache.Add(ctx, t.store, "2fa:totp:"+account+":"+strconv.FormatInt(counter, 10), []byte{1}, ttl)
+}
+
+// URI returns the otpauth:// URI provisioning secret for account in authenticator apps.
+func (t *TOTP) URI(account, secret string) string {
+	label := url.PathEscape(account)
+	if t.issuer != "" {
+		label = url.PathEscape(t.issuer) + ":" + label
+	}
+	query := url.Values{}
+	query.Set("secret", secret)
+	if t.issuer != "" {
+		query.Set("issuer", t.issuer)
+	}
+	query.Set("algorithm", t.algorithm)
+	query.Set("digits", strconv.Itoa(t.digits))
+	query.Set("period", strconv.Itoa(int(t.period/time.Second)))
+	return "otpauth://totp/" + label + "?" + strings.ReplaceAll(query.Encode(), "+", "%20")
+}
+
+// QRCode returns a PNG QR code of the URI of account, about size pixels wide, for the user
+// to scan with their authenticator app.
+//
+// Example usage:
+//
+//	png, err := totp.QRCode(user.Email, secret, 256)
+//	ctx.Res.Header().Set("Content-Type", "image/png")
+//	ctx.Res.Write(png)
+func (t *TOTP) QRCode(account, secret string, size int) ([]byte, error) {
+	return QRCode(t.URI(account, secret), size)
+}
+
+// Verified is implemented by sessions or users recording whether the second factor of the
+// current login was checked.
+type Verified interface {
+	TwoFactorVerified() bool
+}
+
+// Guard lets requests through when the session, or else the user, of the context implements
+// Verified and reports the second factor as checked. Register it as a controller guard.
+//
+// Example usage:
+//
+//	controller.RegisterGuard("2fa", twofactor.Guard)
+func Guard(ctx *context.Context) bool {
+	if session, ok := ctx.Session(); ok {
+		if verified, ok := session.(Verified); ok {
+			return verified.TwoFactorVerified()
+		}
+	}
+	if user, ok := ctx.User(); ok {
+		if verified, ok := user.(Verified); ok {
+			return verified.TwoFactorVerified()
+		}
+	}
+	return false
+}
@@ -0,0 +1,563 @@
+/*
+Package upload implements resumable uploads with the tus protocol (https://tus.io), version
+1.0.0 with the creation, creation-with-upload, expiration and termination extensions.
+
+Clients create an upload, then send its content in as many PATCH requests as needed: when the
+connection drops, they ask for the offset reached with HEAD and resume from there. Chunks are
+assembled on the local disk, and complete files are moved to a storage.Storage, so resumable
+uploads work the same on local disk and object storage. Incomplete uploads expire after a
+period without activity.
+
+Usage:
+
+	tus, err := upload.NewTus(store, "/files",
+		upload.WithTusMaxSize(5<<30),
+		upload.WithTusOnComplete(func(ctx context.Context, u *upload.Upload) error {
+			log.Printf("received %s as %s", u.Metadata["filename"], u.Key)
+			return nil
+		}),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer tus.Close()
+	r.ServeUploads(tus)
+*/
+package upload
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hokamsingh/lessgo/internal/core/storage"
+)
+
+const (
+	// TusVersion is the version of the protocol implemented.
+	TusVersion    = "1.0.0"
+	tusExtensions = "creation,creation-with-upload,expiration,termination"
+	offsetStream  = "application/offset+octet-stream"
+)
+
+// ErrNotFound is returned for unknown or expired uploads.
+var ErrNotFound = errors.New("upload: not found")
+
+// Upload describes a resumable upload.
+type Upload struct {
+	ID       string            `json:"id"`
+	Length   int64             `json:"length"`
+	Offset   int64             `json:"offset"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// RawMetadata is the Upload-Metadata header the upload was created with.
+	RawMetadata string    `json:"raw_metadata,omitempty"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	Completed   bool      `json:"completed"`
+	// Key is the storage key of the file once completed.
+	Key string `json:"key,omitempty"`
+}
+
+// Progress returns the share of the upload received, between 0 and 1.
+func (u *Upload) Progress() float64 {
+	if u.Length == 0 {
+		return 1
+	}
+	return float64(u.Offset) / float64(u.Length)
+}
+
+// Tus serves resumable uploads under a base path, see ServeHTTP.
+type Tus struct {
+	store      storage.Storage
+	basePath   string
+	dir        string
+	prefix     string
+	maxSize    int64
+	ttl        time.Duration
+	onProgress func(*Upload)
+	onComplete func(context.Context, *Upload) error
+
+	mu   sync.Mutex
+	held map[string]chan struct{} // locks taken by this instance, closed on unlock
+	stop chan struct{}
+	once sync.Once
+}
+
+// NewTus creates a tus server under basePath, e.g. /files, storing complete files in store.
+// A janitor removes the uploads expired since; Close stops it.
+func NewTus(store storage.Storage, basePath string, options ...func(*Tus)) (*Tus, error) {
+	t := &Tus{
+		store:    store,
+		basePath: "/" + strings.Trim(basePath, "/"),
+		dir:      filepath.Join(os.TempDir(), "lessgo-tus"),
+		ttl:      24 * time.Hour,
+		held:     map[string]chan struct{}{},
+		stop:     make(chan struct{}),
+	}
+	for _, option := range options {
+		option(t)
+	}
+	if err := os.MkdirAll(t.dir, 0750); err != nil {
+		return nil, fmt.Errorf("upload: creating %s: %w", t.dir, err)
+	}
+	go t.janitor()
+	return t, nil
+}
+
+// WithTusDir sets the directory where chunks are assembled, shared by the instances serving
+// the same uploads. Requests writing to an upload hold a lock file in it, so the directory
+// must support exclusive file creation, as local disks and NFSv3 and later do.
+func WithTusDir(dir string) func(*Tus) {
+	return func(t *Tus) {
+		t.dir = dir
+	}
+}
+
+// WithTusMaxSize limits the length of uploads.
+func WithTusMaxSize(n int64) func(*Tus) {
+	return func(t *Tus) {
+		t.maxSize = n
+	}
+}
+
+// WithTusExpiry sets how long incomplete uploads are kept without activity, a day by default.
+func WithTusExpiry(ttl time.Duration) func(*Tus) {
+	return func(t *Tus) {
+		t.ttl = ttl
+	}
+}
+
+// WithTusKeyPrefix sets the prefix of the storage keys of complete files.
+func WithTusKeyPrefix(prefix string) func(*Tus) {
+	return func(t *Tus) {
+		t.prefix = prefix
+	}
+}
+
+// WithTusOnProgress calls fn after each chunk received.
+func WithTusOnProgress(fn func(*Upload)) func(*Tus) {
+	return func(t *Tus) {
+		t.onProgress = fn
+	}
+}
+
+// WithTusOnComplete calls fn once a file is complete and stored. An error fails the last
+// PATCH request, which the client retries.
+func WithTusOnComplete(fn func(context.Context, *Upload) error) func(*Tus) {
+	return func(t *Tus) {
+		t.onComplete = fn
+	}
+}
+
+// BasePath returns the path the uploads are served under.
+func (t *Tus) BasePath() string {
+	return t.basePath
+}
+
+// Close stops the janitor.
+func (t *Tus) Close() error {
+	t.once.Do(func() { close(t.stop) })
+	return nil
+}
+
+func (t *Tus) infoPath(id string) string { return filepath.Join(t.dir, id+".info") }
+func (t *Tus) dataPath(id string) string { return filepath.Join(t.dir, id+".bin") }
+func (t *Tus) lockPath(id string) string { return filepath.Join(t.dir, id+".lock") }
+
+// validID reports whether id can name an upload, which keeps requests inside the directory.
+func validID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'f' || c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// Get returns the upload id, e.g. to report its progress.
+func (t *Tus) Get(id string) (*Upload, error) {
+	if !validID(id) {
+		return nil, ErrNotFound
+	}
+	data, err := os.ReadFile(t.infoPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var u Upload
+	if err := json.Unmarshal(data, &u); err != nil {
+		return nil, err
+	}
+	if !u.Completed && time.Now().After(u.ExpiresAt) {
+		return nil, ErrNotFound
+	}
+	return &u, nil
+}
+
+func (t *Tus) save(u *Upload) error {
+	data, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+	tmp := t.infoPath(u.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0640); err != nil {
+		return err
+	}
+	return os.Rename(tmp, t.infoPath(u.ID))
+}
+
+func (t *Tus) remove(id string) {
+	os.Remove(t.dataPath(id))
+	os.Remove(t.infoPath(id))
+}
+
+// tusLockStale is how long a lock file lives without being refreshed before it is taken as
+// left behind by a crashed instance. Held locks are refreshed three times as often.
+const tusLockStale = time.Minute
+
+// lock creates the lock file of id, failing when a request of any instance sharing the
+// directory is writing to it. The lock is refreshed until unlock.
+func (t *Tus) lock(id string) bool {
+	for attempt := 0; attempt < 2; attempt++ {
+		file, err := os.OpenFile(t.lockPath(id), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0640)
+		if err == nil {
+			file.Close()
+			t.hold(id)
+			return true
+		}
+		if !errors.Is(err, os.ErrExist) {
+			log.Printf("Error locking upload %s: %v", id, err)
+			return false
+		}
+		info, err := os.Stat(t.lockPath(id))
+		if err == nil && time.Since(info.ModTime()) < tusLockStale {
+			return false
+		}
+		if err == nil {
+			os.Remove(t.lockPath(id))
+		}
+	}
+	return false
+}
+
+// hold refreshes the lock file of id until unlock.
+func (t *Tus) hold(id string) {
+	done := make(chan struct{})
+	t.mu.Lock()
+	t.held[id] = done
+	t.mu.Unlock()
+	go func() {
+		ticker := time.NewTicker(tusLockStale / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				os.Chtimes(t.lockPath(id), now, now)
+			}
+		}
+	}()
+}
+
+func (t *Tus) unlock(id string) {
+	t.mu.Lock()
+	if done, ok := t.held[id]; ok {
+		close(done)
+		delete(t.held, id)
+	}
+	t.mu.Unlock()
+	os.Remove(t.lockPath(id))
+}
+
+// ServeHTTP implements the tus protocol: OPTIONS and POST on the base path, HEAD, PATCH and
+// DELETE on the uploads below it.
+func (t *Tus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", TusVersion)
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Tus-Version", TusVersion)
+		w.Header().Set("Tus-Extension", tusExtensions)
+		if t.maxSize > 0 {
+			w.Header().Set("Tus-Max-Size", strconv.FormatInt(t.maxSize, 10))
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Header.Get("Tus-Resumable") != TusVersion {
+		w.Header().Set("Tus-Version", TusVersion)
+		http.Error(w, "Unsupported tus version", http.StatusPreconditionFailed)
+		return
+	}
+
+	id := strings.Trim(strings.TrimPrefix(path.Clean(r.URL.Path), t.basePath), "/")
+	switch {
+	case id == "" && r.Method == http.MethodPost:
+		t.create(w, r)
+	case id != "" && r.Method == http.MethodHead:
+		t.head(w, id)
+	case id != "" && r.Method == http.MethodPatch:
+		t.patch(w, r, id)
+	case id != "" && r.Method == http.MethodDelete:
+		t.terminate(w, id)
+	default:
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (t *Tus) create(w http.ResponseWriter, r *http.Request) {
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		http.Error(w, "Invalid Upload-Length", http.StatusBadRequest)
+		return
+	}
+	if t.maxSize > 0 && length > t.maxSize {
+		http.Error(w, "Upload exceeds the maximum size", http.StatusRequestEntityTooLarge)
+		return
+	}
+	metadata, err := parseMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		http.Error(w, "Invalid Upload-Metadata", http.StatusBadRequest)
+		return
+	}
+	u := &Upload{
+		ID:          strings.ReplaceAll(uuid.New().String(), "-", ""),
+		Length:      length,
+		Metadata:    metadata,
+		RawMetadata: r.Header.Get("Upload-Metadata"),
+		ExpiresAt:   time.Now().Add(t.ttl),
+	}
+	file, err := os.OpenFile(t.dataPath(u.ID), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0640)
+	if err == nil {
+		file.Close()
+		err = t.save(u)
+	}
+	if err != nil {
+		http.Error(w, "Unable to create upload", http.StatusInternalServerError)
+		log.Printf("Error creating upload: %v", err)
+		return
+	}
+	w.Header().Set("Location", t.basePath+"/"+u.ID)
+	w.Header().Set("Upload-Expires", u.ExpiresAt.UTC().Format(http.TimeFormat))
+
+	if r.Header.Get("Content-Type") == offsetStream || length == 0 {
+		// creation-with-upload, and empty files complete right away
+		t.lock(u.ID)
+		defer t.unlock(u.ID)
+		if status, message := t.write(r, u); status != 0 {
+			http.Error(w, message, status)
+			return
+		}
+		w.Header().Set("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (t *Tus) head(w http.ResponseWriter, id string) {
+	u, err := t.Get(id)
+	if err != nil {
+		status := http.StatusNotFound
+		if err != ErrNotFound {
+			status = http.StatusInternalServerError
+		}
+		w.WriteHeader(status)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(u.Length, 10))
+	if u.RawMetadata != "" {
+		w.Header().Set("Upload-Metadata", u.RawMetadata)
+	}
+	if !u.Completed {
+		w.Header().Set("Upload-Expires", u.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (t *Tus) patch(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Header.Get("Content-Type") != offsetStream {
+		http.Error(w, "Content-Type must be "+offsetStream, http.StatusUnsupportedMediaType)
+		return
+	}
+	if !t.lock(id) {
+		http.Error(w, "Upload is in use", http.StatusLocked)
+		return
+	}
+	defer t.unlock(id)
+	u, err := t.Get(id)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset != u.Offset {
+		w.Header().Set("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+		http.Error(w, "Upload-Offset does not match", http.StatusConflict)
+		return
+	}
+	if status, message := t.write(r, u); status != 0 {
+		http.Error(w, message, status)
+		return
+	}
+	w.Header().Set("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+	if !u.Completed {
+		w.Header().Set("Upload-Expires", u.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// write appends the body of r to u, keeping what was received when the connection drops, and
+// completes the upload once all its bytes arrived. On failure, it returns the status and
+// message of the response.
+func (t *Tus) write(r *http.Request, u *Upload) (int, string) {
+	if !u.Completed && u.Offset < u.Length {
+		file, err := os.OpenFile(t.dataPath(u.ID), os.O_WRONLY, 0640)
+		if err != nil {
+			log.Printf("Error opening upload %s: %v", u.ID, err)
+			return http.StatusInternalServerError, "Unable to write upload"
+		}
+		// Bytes past a failed write may be partial, so the data file is rewritten from the offset
+		if _, err := file.Seek(u.Offset, io.SeekStart); err == nil {
+			file.Truncate(u.Offset)
+		}
+		n, copyErr := io.Copy(file, io.LimitReader(r.Body, u.Length-u.Offset))
+		closeErr := file.Close()
+		u.Offset += n
+		u.ExpiresAt = time.Now().Add(t.ttl)
+		if err := t.save(u); err != nil {
+			log.Printf("Error saving upload %s: %v", u.ID, err)
+			return http.StatusInternalServerError, "Unable to write upload"
+		}
+		if t.onProgress != nil {
+			t.onProgress(u)
+		}
+		if copyErr != nil || closeErr != nil {
+			// The client resumes from the offset saved
+			return http.StatusBadRequest, "Upload interrupted"
+		}
+	}
+	if u.Offset == u.Length && !u.Completed {
+		if err := t.complete(r.Context(), u); err != nil {
+			log.Printf("Error completing upload %s: %v", u.ID, err)
+			return http.StatusInternalServerError, "Unable to store upload"
+		}
+	}
+	return 0, ""
+}
+
+// complete moves the file to the storage. On failure, the next PATCH tries again.
+func (t *Tus) complete(ctx context.Context, u *Upload) error {
+	file, err := os.Open(t.dataPath(u.ID))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	key := t.prefix + u.ID + strings.ToLower(path.Ext(path.Base("/"+u.Metadata["filename"])))
+	if err := t.store.Put(ctx, key, file, u.Metadata["filetype"]); err != nil {
+		return err
+	}
+	u.Key = key
+	if t.onComplete != nil {
+		if err := t.onComplete(ctx, u); err != nil {
+			u.Key = ""
+			return err
+		}
+	}
+	u.Completed = true
+	u.ExpiresAt = time.Now().Add(t.ttl)
+	if err := t.save(u); err != nil {
+		return err
+	}
+	os.Remove(t.dataPath(u.ID))
+	return nil
+}
+
+func (t *Tus) terminate(w http.ResponseWriter, id string) {
+	if !t.lock(id) {
+		http.Error(w, "Upload is in use", http.StatusLocked)
+		return
+	}
+	defer t.unlock(id)
+	if _, err := t.Get(id); err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	t.remove(id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (t *Tus) janitor() {
+	interval := min(t.ttl, time.Hour)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.stop:
+			return
+		case <-ticker.C:
+			t.Sweep()
+		}
+	}
+}
+
+// Sweep removes the expired uploads, and the records of the completed ones once their expiry
+// passed, and returns how many were removed.
+func (t *Tus) Sweep() int {
+	entries, err := os.ReadDir(t.dir)
+	if err != nil {
+		return 0
+	}
+	removed := 0
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".info")
+		if !ok || !validID(id) || !t.lock(id) {
+			continue
+		}
+		data, err := os.ReadFile(t.infoPath(id))
+		var u Upload
+		if err == nil && json.Unmarshal(data, &u) == nil && time.Now().After(u.ExpiresAt) {
+			t.remove(id)
+			removed++
+		}
+		t.unlock(id)
+	}
+	return removed
+}
+
+// parseMetadata decodes the Upload-Metadata header, comma separated keys followed by their
+// base64 encoded value.
+func parseMetadata(header string) (map[string]string, error) {
+	metadata := map[string]string{}
+	if strings.TrimSpace(header) == "" {
+		return metadata, nil
+	}
+	for _, pair := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, errors.New("empty metadata key")
+		}
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, err
+		}
+		metadata[key] = string(decoded)
+	}
+	return metadata, nil
+}
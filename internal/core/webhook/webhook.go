@@ -0,0 +1,362 @@
+/*
+Package webhook receives webhooks from third-party services safely.
+
+A Receiver verifies the HMAC signature of each delivery (GitHub, Stripe and Slack styles are
+built in), rejects deliveries whose timestamp is outside the tolerance, ignores deliveries it
+has already processed and dispatches the payload, decoded into the registered type, to the
+handler of its event type. A receiver without a secret refuses every delivery, as anyone can
+sign with an empty key.
+
+Usage:
+
+	receiver := webhook.NewReceiver(webhook.GitHub, cfg.Get("GITHUB_WEBHOOK_SECRET", ""),
+		webhook.WithDedupeStore(cache.NewRedisStore(client), 72*time.Hour))
+	webhook.On(receiver, "push", func(ctx stdcontext.Context, event PushEvent) error {
+		return builds.Trigger(ctx, event.Repository.FullName, event.After)
+	})
+	App.Post("/webhooks/github", receiver.Handle)
+*/
+package webhook
+
+import (
+	stdcontext "context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/cache"
+	"github.com/hokamsingh/lessgo/internal/core/context"
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+)
+
+var (
+	// ErrInvalidSignature is returned when a delivery is not signed with the secret.
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	// ErrStaleTimestamp is returned when a delivery is older or newer than the tolerance allows.
+	ErrStaleTimestamp = errors.New("webhook timestamp outside of tolerance")
+	// ErrNoSecret is returned for every delivery to a receiver created without a secret.
+	ErrNoSecret = errors.New("webhook secret not configured")
+)
+
+// Scheme describes how a provider signs and labels its deliveries.
+type Scheme struct {
+	Name string
+	// Verify checks the signature of the delivery. It returns the signed timestamp, or the
+	// zero time when the provider does not sign one.
+	Verify func(r *http.Request, body []byte, secret []byte) (time.Time, error)
+	// DeliveryID identifies a delivery so retries of it are only processed once.
+	DeliveryID func(r *http.Request, body []byte) string
+	// EventType selects the handler.
+	EventType func(r *http.Request, body []byte) string
+}
+
+// GitHub verifies X-Hub-Signature-256 and dedupes by X-GitHub-Delivery.
+var GitHub = Scheme{
+	Name: "github",
+	Verify: func(r *http.Request, body []byte, secret []byte) (time.Time, error) {
+		signature := strings.TrimPrefix(r.Header.Get("X-Hub-Signature-256"), "sha256=")
+		return time.Time{}, verifyHex(secret, body, signature)
+	},
+	DeliveryID: Header("X-GitHub-Delivery"),
+	EventType:  Header("X-GitHub-Event"),
+}
+
+// Stripe verifies the Stripe-Signature header and dedupes by the event id.
+var Stripe = Scheme{
+	Name: "stripe",
+	Verify: func(r *http.Request, body []byte, secret []byte) (time.Time, error) {
+		var timestamp string
+		var signatures []string
+		for _, part := range strings.Split(r.Header.Get("Stripe-Signature"), ",") {
+			key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+			switch key {
+			case "t":
+				timestamp = value
+			case "v1":
+				signatures = append(signatures, value)
+			}
+		}
+		signedAt, err := parseUnix(timestamp)
+		if err != nil {
+			return time.Time{}, ErrInvalidSignature
+		}
+		payload := append([]byte(timestamp+"."), body...)
+		for _, signature := range signatures {
+			if verifyHex(secret, payload, signature) == nil {
+				return signedAt, nil
+			}
+		}
+		return time.Time{}, ErrInvalidSignature
+	},
+	DeliveryID: JSONField("id"),
+	EventType:  JSONField("type"),
+}
+
+// Slack verifies X-Slack-Signature with X-Slack-Request-Timestamp and dedupes by event_id.
+var Slack = Scheme{
+	Name: "slack",
+	Verify: func(r *http.Request, body []byte, secret []byte) (time.Time, error) {
+		timestamp := r.Header.Get("X-Slack-Request-Timestamp")
+		signedAt, err := parseUnix(timestamp)
+		if err != nil {
+			return time.Time{}, ErrInvalidSignature
+		}
+		signature := strings.TrimPrefix(r.Header.Get("X-Slack-Signature"), "v0=")
+		payload := append([]byte("v0:"+timestamp+":"), body...)
+		return signedAt, verifyHex(secret, payload, signature)
+	},
+	DeliveryID: JSONField("event_id"),
+	EventType: func(r *http.Request, body []byte) string {
+		if eventType := JSONField("event.type")(r, body); eventType != "" {
+			return eventType
+		}
+		return JSONField("type")(r, body)
+	},
+}
+
+// HMACSHA256 creates a scheme for providers signing the body with a hex encoded HMAC-SHA256
+// in a header, optionally prefixed, e.g. HMACSHA256("X-Signature", "sha256=").
+func HMACSHA256(signatureHeader, prefix string, deliveryID, eventType func(r *http.Request, body []byte) string) Scheme {
+	return Scheme{
+		Name: "hmac-sha256",
+		Verify: func(r *http.Request, body []byte, secret []byte) (time.Time, error) {
+			signature := strings.TrimPrefix(r.Header.Get(signatureHeader), prefix)
+			return time.Time{}, verifyHex(secret, body, signature)
+		},
+		DeliveryID: deliveryID,
+		EventType:  eventType,
+	}
+}
+
+// Header reads a delivery ID or event type from a request header.
+func Header(name string) func(r *http.Request, body []byte) string {
+	return func(r *http.Request, body []byte) string {
+		return r.Header.Get(name)
+	}
+}
+
+// JSONField reads a delivery ID or event type from a string field of the JSON body.
+// Nested fields are separated by dots, e.g. "event.type".
+func JSONField(path string) func(r *http.Request, body []byte) string {
+	return func(r *http.Request, body []byte) string {
+		var value interface{}
+		if err := json.Unmarshal(body, &value); err != nil {
+			return ""
+		}
+		for _, key := range strings.Split(path, ".") {
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				return ""
+			}
+			value = object[key]
+		}
+		s, _ := value.(string)
+		return s
+	}
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of payload, e.g. to sign test deliveries.
+func Sign(secret, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func verifyHex(secret, payload []byte, signature string) error {
+	expected, err := hex.DecodeString(signature)
+	if err != nil || len(expected) == 0 {
+		return ErrInvalidSignature
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	if !hmac.Equal(mac.Sum(nil), expected) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func parseUnix(timestamp string) (time.Time, error) {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(seconds, 0), nil
+}
+
+// Receiver verifies webhook deliveries and dispatches them to typed handlers.
+type Receiver struct {
+	scheme    Scheme
+	secret    []byte
+	tolerance time.Duration
+	dedupe    cache.Store
+	dedupeTTL time.Duration
+	maxBody   int64
+
+	mu       sync.RWMutex
+	handlers map[string]func(ctx stdcontext.Context, body []byte) error
+	fallback func(ctx stdcontext.Context, eventType string, body []byte) error
+}
+
+// NewReceiver creates a receiver for the given scheme and signing secret. Deliveries are
+// deduplicated in memory for 24 hours and timestamps may be off by five minutes unless
+// configured otherwise. An empty secret refuses all deliveries.
+func NewReceiver(scheme Scheme, secret string, options ...func(*Receiver)) *Receiver {
+	if secret == "" {
+		log.Printf("Webhook receiver %s has no secret, refusing all deliveries", scheme.Name)
+	}
+	r := &Receiver{
+		scheme:    scheme,
+		secret:    []byte(secret),
+		tolerance: 5 * time.Minute,
+		dedupe:    cache.NewMemoryStore(cache.New()),
+		dedupeTTL: 24 * time.Hour,
+		maxBody:   1 << 20,
+		handlers:  make(map[string]func(ctx stdcontext.Context, body []byte) error),
+	}
+	for _, option := range options {
+		option(r)
+	}
+	return r
+}
+
+// WithTolerance sets how far the signed timestamp may be from the current time.
+func WithTolerance(tolerance time.Duration) func(*Receiver) {
+	return func(r *Receiver) {
+		r.tolerance = tolerance
+	}
+}
+
+// WithDedupeStore sets the cache store remembering processed delivery IDs, and for how long.
+// A store shared by all instances, such as cache.NewRedisStore, dedupes deliveries retried to
+// another instance. A nil store disables deduplication.
+func WithDedupeStore(store cache.Store, ttl time.Duration) func(*Receiver) {
+	return func(r *Receiver) {
+		r.dedupe = store
+		r.dedupeTTL = ttl
+	}
+}
+
+// WithMaxBodySize limits the size of accepted deliveries, 1 MB by default.
+func WithMaxBodySize(size int64) func(*Receiver) {
+	return func(r *Receiver) {
+		r.maxBody = size
+	}
+}
+
+// On registers the handler for an event type. The payload is decoded from JSON into T.
+//
+// Example usage:
+//
+//	webhook.On(receiver, "invoice.paid", func(ctx stdcontext.Context, event StripeEvent) error {
+//		return billing.MarkPaid(ctx, event.Data.Object.ID)
+//	})
+func On[T any](r *Receiver, eventType string, handler func(ctx stdcontext.Context, payload T) error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[eventType] = func(ctx stdcontext.Context, body []byte) error {
+		var payload T
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return fmt.Errorf("decoding %s payload: %w", eventType, err)
+		}
+		return handler(ctx, payload)
+	}
+}
+
+// OnAny registers a handler for event types without a registered handler.
+func (r *Receiver) OnAny(handler func(ctx stdcontext.Context, eventType string, body []byte) error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fallback = handler
+}
+
+// Handle serves the receiver as a route handler.
+//
+// Example usage:
+//
+//	App.Post("/webhooks/stripe", receiver.Handle)
+func (r *Receiver) Handle(ctx *context.Context) {
+	r.ServeHTTP(ctx.Res, ctx.Req)
+}
+
+// ServeHTTP verifies and dispatches a delivery. Invalid signatures and stale timestamps get a
+// 401, duplicate deliveries and events without handler a 200 so the provider stops retrying,
+// and handler errors a 500 so the provider retries the delivery.
+func (r *Receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	req.Body = http.MaxBytesReader(w, req.Body, r.maxBody)
+	body, err := middleware.RawBody(req)
+	if err != nil {
+		http.Error(w, "Request Entity Too Large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	var signedAt time.Time
+	if len(r.secret) == 0 {
+		err = ErrNoSecret
+	} else {
+		signedAt, err = r.scheme.Verify(req, body, r.secret)
+	}
+	if err == nil && !signedAt.IsZero() && r.tolerance > 0 {
+		if age := time.Since(signedAt); age > r.tolerance || age < -r.tolerance {
+			err = ErrStaleTimestamp
+		}
+	}
+	if err != nil {
+		log.Printf("Rejected %s webhook: %v", r.scheme.Name, err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx := req.Context()
+	deliveryID := ""
+	if r.scheme.DeliveryID != nil && r.dedupe != nil {
+		deliveryID = r.scheme.DeliveryID(req, body)
+	}
+	dedupeKey := "webhook:" + r.scheme.Name + ":" + deliveryID
+	if deliveryID != "" {
+		first, err := cache.Add(ctx, r.dedupe, dedupeKey, []byte{1}, r.dedupeTTL)
+		if err != nil {
+			log.Printf("Error deduplicating %s webhook %s: %v", r.scheme.Name, deliveryID, err)
+		} else if !first {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+	}
+
+	eventType := ""
+	if r.scheme.EventType != nil {
+		eventType = r.scheme.EventType(req, body)
+	}
+	if err := r.dispatch(ctx, eventType, body); err != nil {
+		log.Printf("Error handling %s webhook %s: %v", r.scheme.Name, eventType, err)
+		if deliveryID != "" {
+			// Forget the delivery so the retry is processed
+			r.dedupe.Delete(stdcontext.WithoutCancel(ctx), dedupeKey)
+		}
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (r *Receiver) dispatch(ctx stdcontext.Context, eventType string, body []byte) error {
+	r.mu.RLock()
+	handler, ok := r.handlers[eventType]
+	fallback := r.fallback
+	r.mu.RUnlock()
+	if ok {
+		return handler(ctx, body)
+	}
+	if fallback != nil {
+		return fallback(ctx, eventType, body)
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package websocket
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+
+	"github.com/gorilla/websocket"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Codec encodes the typed messages of a hub.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+	// Binary reports whether encoded messages are sent in binary frames.
+	Binary() bool
+}
+
+// ErrNotProtobuf is returned by the Protobuf codec for values that are not protobuf
+// messages with generated Marshal and Unmarshal methods.
+var ErrNotProtobuf = errors.New("websocket: value is not a protobuf message")
+
+// Codecs for typed messages. JSON, sent in text frames, is the default.
+var (
+	JSON    Codec = jsonCodec{}
+	MsgPack Codec = msgpackCodec{}
+	// Protobuf encodes messages generated with Marshal() and Unmarshal([]byte) methods, as
+	// by gogo/protobuf or csproto, or implementing encoding.BinaryMarshaler. Use NewCodec to
+	// plug in google.golang.org/protobuf's proto.Marshal and proto.Unmarshal.
+	Protobuf Codec = protobufCodec{}
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+func (jsonCodec) Binary() bool                               { return false }
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) Marshal(v interface{}) ([]byte, error)      { return msgpack.Marshal(v) }
+func (msgpackCodec) Unmarshal(data []byte, v interface{}) error { return msgpack.Unmarshal(data, v) }
+func (msgpackCodec) Binary() bool                               { return true }
+
+type protobufCodec struct{}
+
+func (protobufCodec) Marshal(v interface{}) ([]byte, error) {
+	switch m := v.(type) {
+	case interface{ Marshal() ([]byte, error) }:
+		return m.Marshal()
+	case encoding.BinaryMarshaler:
+		return m.MarshalBinary()
+	}
+	return nil, fmt.Errorf("%w: %T", ErrNotProtobuf, v)
+}
+
+func (protobufCodec) Unmarshal(data []byte, v interface{}) error {
+	switch m := v.(type) {
+	case interface{ Unmarshal([]byte) error }:
+		return m.Unmarshal(data)
+	case encoding.BinaryUnmarshaler:
+		return m.UnmarshalBinary(data)
+	}
+	return fmt.Errorf("%w: %T", ErrNotProtobuf, v)
+}
+
+func (protobufCodec) Binary() bool { return true }
+
+type funcCodec struct {
+	marshal   func(v interface{}) ([]byte, error)
+	unmarshal func(data []byte, v interface{}) error
+	binary    bool
+}
+
+// NewCodec creates a codec from a pair of functions.
+//
+// Example usage:
+//
+//	codec := websocket.NewCodec(
+//		func(v interface{}) ([]byte, error) { return proto.Marshal(v.(proto.Message)) },
+//		func(data []byte, v interface{}) error { return proto.Unmarshal(data, v.(proto.Message)) },
+//		true,
+//	)
+func NewCodec(marshal func(v interface{}) ([]byte, error), unmarshal func(data []byte, v interface{}) error, binary bool) Codec {
+	return funcCodec{marshal: marshal, unmarshal: unmarshal, binary: binary}
+}
+
+func (c funcCodec) Marshal(v interface{}) ([]byte, error)      { return c.marshal(v) }
+func (c funcCodec) Unmarshal(data []byte, v interface{}) error { return c.unmarshal(data, v) }
+func (c funcCodec) Binary() bool                               { return c.binary }
+
+// WithCodec sets the codec of SendValue, BroadcastValue and OnMessage, JSON by default.
+func WithCodec(codec Codec) func(*Hub) {
+	return func(h *Hub) {
+		h.codec = codec
+	}
+}
+
+// WithMaxMessageSize sets the largest message read from clients in bytes, 512 by default.
+// Clients sending larger messages are disconnected.
+func WithMaxMessageSize(size int64) func(*Hub) {
+	return func(h *Hub) {
+		if size > 0 {
+			h.maxMessageSize = size
+		}
+	}
+}
+
+// WithCompression negotiates permessage-deflate with clients that support it and
+// compresses outbound messages at level, from -2 (Huffman only) to 9 (best compression).
+func WithCompression(level int) func(*Hub) {
+	return func(h *Hub) {
+		h.upgrader.EnableCompression = true
+		h.compressionLevel = level
+		if level == 0 {
+			// Zero would read as compression off, 1 is the fastest compressing level
+			h.compressionLevel = 1
+		}
+	}
+}
+
+// WithMessageHandler receives the messages of clients that are not room commands, text and
+// binary, instead of broadcasting them to all clients.
+func WithMessageHandler(handler func(c *Client, data []byte, binary bool)) func(*Hub) {
+	return func(h *Hub) {
+		h.messageHandler = handler
+	}
+}
+
+// OnMessage handles the messages of clients decoded into T with the codec of the hub, as
+// with WithMessageHandler. Messages that fail to decode are logged and dropped.
+//
+// Example usage:
+//
+//	websocket.OnMessage(hub, func(c *websocket.Client, move *Move) {
+//		game.Apply(c.Name(), move)
+//	})
+func OnMessage[T any](h *Hub, handler func(c *Client, msg T)) {
+	h.messageHandler = func(c *Client, data []byte, binary bool) {
+		var msg T
+		target := interface{}(&msg)
+		// Pointer types get a fresh value, as protobuf messages must not be nil
+		if t := reflect.TypeOf(msg); t != nil && t.Kind() == reflect.Pointer {
+			msg = reflect.New(t.Elem()).Interface().(T)
+			target = msg
+		}
+		if err := h.codec.Unmarshal(data, target); err != nil {
+			log.Printf("Failed to decode message of client %s: %v", c.id, err)
+			return
+		}
+		handler(c, msg)
+	}
+}
+
+// Name returns the name of the client's user.
+func (c *Client) Name() string {
+	return c.name
+}
+
+// Send queues a text message for the client.
+func (c *Client) Send(data []byte) {
+	c.send <- frame{data: data}
+}
+
+// SendBinary queues a binary message for the client.
+func (c *Client) SendBinary(data []byte) {
+	c.send <- frame{data: data, binary: true}
+}
+
+// SendValue encodes v with the codec of the hub and queues it for the client, in a binary
+// frame for binary codecs.
+func (c *Client) SendValue(v interface{}) error {
+	message, err := c.hub.encode(v)
+	if err != nil {
+		return err
+	}
+	c.send <- message
+	return nil
+}
+
+// BroadcastValue encodes v with the codec of the hub and sends it to the clients in the
+// room of this instance.
+func (h *Hub) BroadcastValue(room string, v interface{}) error {
+	message, err := h.encode(v)
+	if err != nil {
+		return err
+	}
+	h.roomBroadcast(room, message)
+	return nil
+}
+
+func (h *Hub) encode(v interface{}) (frame, error) {
+	data, err := h.codec.Marshal(v)
+	if err != nil {
+		return frame{}, err
+	}
+	return frame{data: data, binary: h.codec.Binary()}, nil
+}
+
+// messageType returns the WebSocket frame type of the message.
+func (f frame) messageType() int {
+	if f.binary {
+		return websocket.BinaryMessage
+	}
+	return websocket.TextMessage
+}
@@ -0,0 +1,175 @@
+package websocket
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ConnStats describes a connected client.
+type ConnStats struct {
+	ID          string    `json:"id"`
+	User        string    `json:"user"`
+	Rooms       []string  `json:"rooms,omitempty"`
+	ConnectedAt time.Time `json:"connected_at"`
+	// LastPong is zero until the client answered a ping.
+	LastPong     time.Time `json:"last_pong,omitempty"`
+	LastActivity time.Time `json:"last_activity"`
+	MessagesIn   int64     `json:"messages_in"`
+	MessagesOut  int64     `json:"messages_out"`
+	// QueueDepth is the number of messages waiting to be written to the client.
+	QueueDepth int `json:"queue_depth"`
+}
+
+// HubStats are the counters of a hub.
+type HubStats struct {
+	Connections      int   `json:"connections"`
+	Rooms            int   `json:"rooms"`
+	ConnectionsTotal int64 `json:"connections_total"`
+	MessagesIn       int64 `json:"messages_in"`
+	MessagesOut      int64 `json:"messages_out"`
+	// QueueDepth is the number of messages waiting to be written to all clients.
+	QueueDepth int   `json:"queue_depth"`
+	Reaped     int64 `json:"reaped"`
+}
+
+// IdlePolicy decides when idle connections are closed.
+type IdlePolicy struct {
+	// Timeout closes connections without activity for this long. Zero keeps them open.
+	Timeout time.Duration
+	// CountPongs treats answered pings as activity, so only dead connections are closed.
+	// By default only messages from the client count, closing live but unused connections.
+	CountPongs bool
+	// Interval is how often connections are checked, a quarter of Timeout by default.
+	Interval time.Duration
+	// OnReap is called with the stats of every connection closed for being idle.
+	OnReap func(stats ConnStats)
+}
+
+func (p IdlePolicy) interval() time.Duration {
+	if p.Interval > 0 {
+		return p.Interval
+	}
+	return max(p.Timeout/4, time.Second)
+}
+
+// WithIdleReaper closes connections that are idle according to policy.
+//
+// Example usage:
+//
+//	hub := websocket.NewHub(websocket.WithIdleReaper(websocket.IdlePolicy{Timeout: 10 * time.Minute}))
+func WithIdleReaper(policy IdlePolicy) func(*Hub) {
+	return func(h *Hub) {
+		h.idle = policy
+	}
+}
+
+func (c *Client) countSent() {
+	c.messagesOut.Add(1)
+	c.hub.messagesOut.Add(1)
+}
+
+// Stats returns the stats of the client.
+func (c *Client) Stats() ConnStats {
+	stats := ConnStats{
+		ID:           c.id,
+		User:         c.name,
+		ConnectedAt:  c.connectedAt,
+		LastActivity: time.Unix(0, c.lastActivity.Load()),
+		MessagesIn:   c.messagesIn.Load(),
+		MessagesOut:  c.messagesOut.Load(),
+		QueueDepth:   len(c.send),
+	}
+	if pong := c.lastPong.Load(); pong != 0 {
+		stats.LastPong = time.Unix(0, pong)
+	}
+	return stats
+}
+
+// Connections returns the stats of the clients connected to this instance, oldest first.
+func (h *Hub) Connections() []ConnStats {
+	h.mu.RLock()
+	connections := make([]ConnStats, 0, len(h.clients))
+	for _, client := range h.clients {
+		stats := client.Stats()
+		for room, clients := range h.rooms {
+			if clients[client] {
+				stats.Rooms = append(stats.Rooms, room)
+			}
+		}
+		sort.Strings(stats.Rooms)
+		connections = append(connections, stats)
+	}
+	h.mu.RUnlock()
+	sort.Slice(connections, func(i, j int) bool { return connections[i].ConnectedAt.Before(connections[j].ConnectedAt) })
+	return connections
+}
+
+// Stats returns the counters of the hub.
+func (h *Hub) Stats() HubStats {
+	h.mu.RLock()
+	stats := HubStats{Connections: len(h.clients), Rooms: len(h.rooms)}
+	for _, client := range h.clients {
+		stats.QueueDepth += len(client.send)
+	}
+	h.mu.RUnlock()
+	stats.ConnectionsTotal = h.connectionsTotal.Load()
+	stats.MessagesIn = h.messagesIn.Load()
+	stats.MessagesOut = h.messagesOut.Load()
+	stats.Reaped = h.reaped.Load()
+	return stats
+}
+
+// reapIdle closes the connections that have been idle longer than the timeout. The read
+// pump of a closed connection unregisters it.
+func (h *Hub) reapIdle() {
+	cutoff := time.Now().Add(-h.idle.Timeout).UnixNano()
+	var idle []*Client
+	h.mu.RLock()
+	for _, client := range h.clients {
+		if client.lastActivity.Load() < cutoff {
+			idle = append(idle, client)
+		}
+	}
+	h.mu.RUnlock()
+	for _, client := range idle {
+		h.reaped.Add(1)
+		if h.idle.OnReap != nil {
+			h.idle.OnReap(client.Stats())
+		}
+		// WriteControl and Close may be called concurrently with the pumps
+		client.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "idle timeout"), time.Now().Add(writeWait))
+		client.conn.Close()
+	}
+}
+
+// MetricsHandler serves the counters of the hub in the Prometheus text exposition format,
+// to be scraped next to the other metrics of the application.
+//
+// Example usage:
+//
+//	http.Handle("/metrics/websocket", hub.MetricsHandler())
+func (h *Hub) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stats := h.Stats()
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		metrics := []struct {
+			name, kind, help string
+			value            int64
+		}{
+			{"lessgo_websocket_connections", "gauge", "Open WebSocket connections.", int64(stats.Connections)},
+			{"lessgo_websocket_rooms", "gauge", "Rooms with connected clients.", int64(stats.Rooms)},
+			{"lessgo_websocket_queue_depth", "gauge", "Messages waiting to be written to clients.", int64(stats.QueueDepth)},
+			{"lessgo_websocket_connections_total", "counter", "WebSocket connections accepted.", stats.ConnectionsTotal},
+			{"lessgo_websocket_messages_received_total", "counter", "Messages received from clients.", stats.MessagesIn},
+			{"lessgo_websocket_messages_sent_total", "counter", "Messages sent to clients.", stats.MessagesOut},
+			{"lessgo_websocket_idle_reaped_total", "counter", "Connections closed for being idle.", stats.Reaped},
+		}
+		for _, metric := range metrics {
+			fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %d\n", metric.name, metric.help, metric.name, metric.kind, metric.name, metric.value)
+		}
+	})
+}
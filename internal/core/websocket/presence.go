@@ -0,0 +1,297 @@
+package websocket
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// Types of presence events.
+const (
+	PresenceJoin  = "join"
+	PresenceLeave = "leave"
+)
+
+// PresenceEvent reports a user joining or leaving a room. A user joins with its first client
+// in the room and leaves with its last one.
+type PresenceEvent struct {
+	Type string    `json:"type"`
+	Room string    `json:"room"`
+	User string    `json:"user"`
+	Time time.Time `json:"time"`
+}
+
+// Presence keeps track of the users in rooms.
+type Presence interface {
+	Join(ctx context.Context, room, user string) error
+	Leave(ctx context.Context, room, user string) error
+	// Heartbeat is called periodically for every user in a room, so presence kept outside
+	// the process can expire the users of crashed instances.
+	Heartbeat(ctx context.Context, room, user string) error
+	// Members returns the users in the room, sorted.
+	Members(ctx context.Context, room string) ([]string, error)
+}
+
+// WithPresence sets where presence is kept, e.g. a RedisPresence shared by all instances.
+func WithPresence(presence Presence) func(*Hub) {
+	return func(h *Hub) {
+		h.presence = presence
+	}
+}
+
+// WithPresenceHeartbeat sets how often the presence of local users is refreshed, 30
+// seconds by default. It must be well below the TTL of a RedisPresence.
+func WithPresenceHeartbeat(interval time.Duration) func(*Hub) {
+	return func(h *Hub) {
+		if interval > 0 {
+			h.presenceHeartbeat = interval
+		}
+	}
+}
+
+// Online returns the users in the room, across all instances when presence is shared.
+//
+// Example usage:
+//
+//	users, err := hub.Online(ctx, "lobby")
+func (h *Hub) Online(ctx context.Context, room string) ([]string, error) {
+	return h.presence.Members(ctx, room)
+}
+
+// Rooms returns the rooms with clients connected to this instance.
+func (h *Hub) Rooms() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	rooms := make([]string, 0, len(h.rooms))
+	for room := range h.rooms {
+		rooms = append(rooms, room)
+	}
+	sort.Strings(rooms)
+	return rooms
+}
+
+// SubscribePresence calls listener when a user joins or leaves a room on this instance and
+// returns a function that unsubscribes it. Listeners run on the goroutine of the client and
+// must not block.
+//
+// Example usage:
+//
+//	unsubscribe := hub.SubscribePresence(func(event websocket.PresenceEvent) {
+//		log.Printf("%s %s %s", event.User, event.Type, event.Room)
+//	})
+//	defer unsubscribe()
+func (h *Hub) SubscribePresence(listener func(PresenceEvent)) func() {
+	h.listenersMu.Lock()
+	defer h.listenersMu.Unlock()
+	id := h.nextListener
+	h.nextListener++
+	h.listeners[id] = listener
+	return func() {
+		h.listenersMu.Lock()
+		defer h.listenersMu.Unlock()
+		delete(h.listeners, id)
+	}
+}
+
+func (h *Hub) userJoined(room, user string) {
+	if err := h.presence.Join(context.Background(), room, user); err != nil {
+		log.Printf("Failed to record %s joining %s: %v", user, room, err)
+	}
+	h.emit(PresenceEvent{Type: PresenceJoin, Room: room, User: user, Time: time.Now()})
+}
+
+func (h *Hub) userLeft(room, user string) {
+	if err := h.presence.Leave(context.Background(), room, user); err != nil {
+		log.Printf("Failed to record %s leaving %s: %v", user, room, err)
+	}
+	h.emit(PresenceEvent{Type: PresenceLeave, Room: room, User: user, Time: time.Now()})
+}
+
+func (h *Hub) emit(event PresenceEvent) {
+	h.listenersMu.RLock()
+	defer h.listenersMu.RUnlock()
+	for _, listener := range h.listeners {
+		listener(event)
+	}
+}
+
+// heartbeat refreshes the presence of the users in the rooms of this instance.
+func (h *Hub) heartbeat() {
+	type member struct{ room, user string }
+	var members []member
+	h.mu.RLock()
+	for room, clients := range h.rooms {
+		seen := map[string]bool{}
+		for client := range clients {
+			if !seen[client.name] {
+				seen[client.name] = true
+				members = append(members, member{room, client.name})
+			}
+		}
+	}
+	h.mu.RUnlock()
+	for _, m := range members {
+		if err := h.presence.Heartbeat(context.Background(), m.room, m.user); err != nil {
+			log.Printf("Failed to refresh presence of %s in %s: %v", m.user, m.room, err)
+			return
+		}
+	}
+}
+
+// MemoryPresence keeps presence in process, for single instance deployments.
+type MemoryPresence struct {
+	mu    sync.RWMutex
+	rooms map[string]map[string]struct{}
+}
+
+// NewMemoryPresence creates an empty MemoryPresence.
+func NewMemoryPresence() *MemoryPresence {
+	return &MemoryPresence{rooms: map[string]map[string]struct{}{}}
+}
+
+func (p *MemoryPresence) Join(ctx context.Context, room, user string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rooms[room] == nil {
+		p.rooms[room] = map[string]struct{}{}
+	}
+	p.rooms[room][user] = struct{}{}
+	return nil
+}
+
+func (p *MemoryPresence) Leave(ctx context.Context, room, user string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.rooms[room], user)
+	if len(p.rooms[room]) == 0 {
+		delete(p.rooms, room)
+	}
+	return nil
+}
+
+func (p *MemoryPresence) Heartbeat(ctx context.Context, room, user string) error {
+	return nil
+}
+
+func (p *MemoryPresence) Members(ctx context.Context, room string) ([]string, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	users := make([]string, 0, len(p.rooms[room]))
+	for user := range p.rooms[room] {
+		users = append(users, user)
+	}
+	sort.Strings(users)
+	return users, nil
+}
+
+// RedisPresence keeps presence in Redis, shared by all instances. Each room is a set of
+// instance:user members, and each member has a key expiring after the TTL unless the
+// instance's heartbeat refreshes it, so the users of a crashed instance go offline on their
+// own.
+type RedisPresence struct {
+	client   redis.UniversalClient
+	prefix   string
+	ttl      time.Duration
+	instance string
+}
+
+// NewRedisPresence creates a Redis backed presence with keys under "presence:" and a TTL of
+// 90 seconds.
+func NewRedisPresence(client redis.UniversalClient, options ...func(*RedisPresence)) *RedisPresence {
+	p := &RedisPresence{client: client, prefix: "presence:", ttl: 90 * time.Second, instance: uuid.NewString()}
+	for _, option := range options {
+		option(p)
+	}
+	return p
+}
+
+// WithPresencePrefix sets the prefix of the Redis keys.
+func WithPresencePrefix(prefix string) func(*RedisPresence) {
+	return func(p *RedisPresence) {
+		p.prefix = prefix
+	}
+}
+
+// WithPresenceTTL sets how long users stay present without a heartbeat.
+func WithPresenceTTL(ttl time.Duration) func(*RedisPresence) {
+	return func(p *RedisPresence) {
+		if ttl > 0 {
+			p.ttl = ttl
+		}
+	}
+}
+
+func (p *RedisPresence) roomKey(room string) string {
+	return p.prefix + "room:" + room
+}
+
+func (p *RedisPresence) aliveKey(room, member string) string {
+	return p.prefix + "alive:" + room + ":" + member
+}
+
+func (p *RedisPresence) Join(ctx context.Context, room, user string) error {
+	member := p.instance + ":" + user
+	pipe := p.client.TxPipeline()
+	pipe.SAdd(ctx, p.roomKey(room), member)
+	pipe.Expire(ctx, p.roomKey(room), p.ttl)
+	pipe.Set(ctx, p.aliveKey(room, member), 1, p.ttl)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (p *RedisPresence) Leave(ctx context.Context, room, user string) error {
+	member := p.instance + ":" + user
+	pipe := p.client.TxPipeline()
+	pipe.SRem(ctx, p.roomKey(room), member)
+	pipe.Del(ctx, p.aliveKey(room, member))
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Heartbeat joins again, which also restores members pruned after a missed heartbeat.
+func (p *RedisPresence) Heartbeat(ctx context.Context, room, user string) error {
+	return p.Join(ctx, room, user)
+}
+
+func (p *RedisPresence) Members(ctx context.Context, room string) ([]string, error) {
+	members, err := p.client.SMembers(ctx, p.roomKey(room)).Result()
+	if err != nil || len(members) == 0 {
+		return []string{}, err
+	}
+	pipe := p.client.Pipeline()
+	alive := make([]*redis.IntCmd, len(members))
+	for i, member := range members {
+		alive[i] = pipe.Exists(ctx, p.aliveKey(room, member))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	users := []string{}
+	var stale []interface{}
+	for i, member := range members {
+		if alive[i].Val() == 0 {
+			stale = append(stale, member)
+			continue
+		}
+		_, user, _ := strings.Cut(member, ":")
+		if !seen[user] {
+			seen[user] = true
+			users = append(users, user)
+		}
+	}
+	if len(stale) > 0 {
+		// Members of crashed instances are pruned by whoever looks first
+		if err := p.client.SRem(ctx, p.roomKey(room), stale...).Err(); err != nil {
+			log.Printf("Failed to prune presence of %s: %v", room, err)
+		}
+	}
+	sort.Strings(users)
+	return users, nil
+}
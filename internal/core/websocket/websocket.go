@@ -5,6 +5,8 @@ import (
 	"flag"
 	"log"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -48,11 +50,23 @@ type Client struct {
 	id             string          // Unique client ID for reconnection
 	hub            *Hub            // Reference to the Hub
 	conn           *websocket.Conn // WebSocket connection
-	send           chan []byte     // Buffered channel for outbound messages
-	undeliveredMsg [][]byte        // Queue for undelivered messages
+	send           chan frame      // Buffered channel for outbound messages
+	undeliveredMsg []frame         // Queue for undelivered messages
+
+	connectedAt  time.Time
+	lastPong     atomic.Int64 // Unix nanoseconds
+	lastActivity atomic.Int64 // Unix nanoseconds
+	messagesIn   atomic.Int64
+	messagesOut  atomic.Int64
 }
 
-func (c *Client) addUndeliveredMsg(message []byte) {
+// frame is an outbound message, sent as a text frame unless binary is set.
+type frame struct {
+	data   []byte
+	binary bool
+}
+
+func (c *Client) addUndeliveredMsg(message frame) {
 	if len(c.undeliveredMsg) >= maxUndeliveredMsg {
 		// Deleting the oldest message to free up space
 		c.undeliveredMsg = c.undeliveredMsg[1:]
@@ -66,21 +80,39 @@ func (c *Client) readPump() {
 		c.hub.unregister <- c
 		c.conn.Close()
 	}()
-	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadLimit(c.hub.maxMessageSize)
 	c.conn.SetReadDeadline(time.Now().Add(pongWait))
 	c.conn.SetPongHandler(func(string) error {
-		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		now := time.Now()
+		c.lastPong.Store(now.UnixNano())
+		if c.hub.idle.CountPongs {
+			c.lastActivity.Store(now.UnixNano())
+		}
+		c.conn.SetReadDeadline(now.Add(pongWait))
 		return nil
 	})
 
 	for {
-		_, message, err := c.conn.ReadMessage()
+		messageType, message, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Printf("error: %v", err)
 			}
 			break
 		}
+		c.messagesIn.Add(1)
+		c.hub.messagesIn.Add(1)
+		c.lastActivity.Store(time.Now().UnixNano())
+
+		// Binary frames carry application data, never commands
+		if messageType == websocket.BinaryMessage {
+			if c.hub.messageHandler != nil {
+				c.hub.messageHandler(c, message, true)
+			} else {
+				c.hub.broadcast <- frame{data: message, binary: true}
+			}
+			continue
+		}
 
 		message = bytes.TrimSpace(bytes.Replace(message, newline, space, -1))
 
@@ -88,7 +120,7 @@ func (c *Client) readPump() {
 		case bytes.HasPrefix(message, []byte("join_room:")):
 			roomName := string(message[len("join_room:"):])
 			c.hub.HandleJoinRoom(c, roomName)
-			c.send <- []byte("join_room_success:" + roomName)
+			c.Send([]byte("join_room_success:" + roomName))
 
 		case bytes.HasPrefix(message, []byte("room_message:")):
 			roomNameAndMessage := bytes.SplitN(message[len("room_message:"):], []byte(" "), 2)
@@ -99,7 +131,7 @@ func (c *Client) readPump() {
 		case bytes.HasPrefix(message, []byte("leave_room:")):
 			roomName := string(message[len("leave_room:"):])
 			c.hub.handleLeaveRoom(c, roomName)
-			c.send <- []byte("leave_room_success:" + roomName)
+			c.Send([]byte("leave_room_success:" + roomName))
 
 		case bytes.HasPrefix(message, []byte("private_message:")):
 			receiverAndMessage := bytes.SplitN(message[len("private_message:"):], []byte(" "), 2)
@@ -108,7 +140,11 @@ func (c *Client) readPump() {
 			c.hub.handlePrivateMessage(receiver, privateMessage)
 
 		default:
-			c.hub.broadcast <- message
+			if c.hub.messageHandler != nil {
+				c.hub.messageHandler(c, message, false)
+			} else {
+				c.hub.broadcast <- frame{data: message}
+			}
 		}
 	}
 }
@@ -130,19 +166,36 @@ func (c *Client) writePump() {
 				return
 			}
 
-			w, err := c.conn.NextWriter(websocket.TextMessage)
+			w, err := c.conn.NextWriter(message.messageType())
 			if err != nil {
 				// If the connection is broken, add the message to the unread queue
 				c.addUndeliveredMsg(message)
 				return
 			}
-			w.Write(message)
-
-			// Add queued messages to current WebSocket message
-			n := len(c.send)
-			for i := 0; i < n; i++ {
+			w.Write(message.data)
+			c.countSent()
+
+			// Add queued text messages to current WebSocket message; binary messages
+			// cannot be split by newlines and are sent in frames of their own
+			for !message.binary && len(c.send) > 0 {
+				next := <-c.send
+				if next.binary {
+					// Channel order is kept by sending it right after this message
+					if err := w.Close(); err != nil {
+						return
+					}
+					c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+					if w, err = c.conn.NextWriter(websocket.BinaryMessage); err != nil {
+						c.addUndeliveredMsg(next)
+						return
+					}
+					w.Write(next.data)
+					c.countSent()
+					break
+				}
 				w.Write(newline)
-				w.Write(<-c.send)
+				w.Write(next.data)
+				c.countSent()
 			}
 
 			if err := w.Close(); err != nil {
@@ -161,10 +214,71 @@ func (c *Client) writePump() {
 // Hub manages clients and rooms.
 type Hub struct {
 	clients    map[string]*Client // Track clients by ID for reconnection
-	broadcast  chan []byte
+	broadcast  chan frame
 	register   chan *Client
 	unregister chan *Client
 	rooms      map[string]map[*Client]bool
+
+	// mu guards clients and rooms, which client goroutines access as well as Run
+	mu sync.RWMutex
+
+	upgrader         websocket.Upgrader
+	maxMessageSize   int64
+	compressionLevel int
+	codec            Codec
+	messageHandler   func(c *Client, data []byte, binary bool)
+
+	idle             IdlePolicy
+	messagesIn       atomic.Int64
+	messagesOut      atomic.Int64
+	connectionsTotal atomic.Int64
+	reaped           atomic.Int64
+
+	presence          Presence
+	presenceHeartbeat time.Duration
+	userFunc          func(r *http.Request) string
+	listenersMu       sync.RWMutex
+	listeners         map[int]func(PresenceEvent)
+	nextListener      int
+}
+
+// NewHub creates a hub tracking presence in memory unless WithPresence is given. Start it
+// with Run and serve its clients with ServeHTTP.
+//
+// Example usage:
+//
+//	hub := websocket.NewHub(websocket.WithPresence(websocket.NewRedisPresence(client)))
+//	go hub.Run()
+//	http.Handle("/ws", hub)
+func NewHub(options ...func(*Hub)) *Hub {
+	h := &Hub{
+		broadcast:         make(chan frame),
+		register:          make(chan *Client),
+		unregister:        make(chan *Client),
+		clients:           make(map[string]*Client),
+		rooms:             make(map[string]map[*Client]bool),
+		upgrader:          upgrader,
+		maxMessageSize:    maxMessageSize,
+		codec:             JSON,
+		presenceHeartbeat: 30 * time.Second,
+		listeners:         make(map[int]func(PresenceEvent)),
+	}
+	for _, option := range options {
+		option(h)
+	}
+	if h.presence == nil {
+		h.presence = NewMemoryPresence()
+	}
+	return h
+}
+
+// WithUserFunc names the clients of the hub after the user of their request, e.g.
+// middleware.RequestUser, so presence lists users rather than connections. Clients are
+// named "root" by default.
+func WithUserFunc(userFunc func(r *http.Request) string) func(*Hub) {
+	return func(h *Hub) {
+		h.userFunc = userFunc
+	}
 }
 
 // Create a new room.
@@ -183,16 +297,46 @@ func (h *Hub) joinRoom(client *Client, room string) {
 
 // Leave a room.
 func (h *Hub) handleLeaveRoom(client *Client, room string) {
-	if roomClients, ok := h.rooms[room]; ok {
-		delete(roomClients, client)
-		if len(roomClients) == 0 {
-			delete(h.rooms, room)
+	h.mu.Lock()
+	left := h.leaveRoom(client, room)
+	h.mu.Unlock()
+	if left {
+		h.userLeft(room, client.name)
+	}
+}
+
+// leaveRoom removes the client from the room and reports whether its user has no other
+// client left in it. The caller holds mu.
+func (h *Hub) leaveRoom(client *Client, room string) bool {
+	roomClients, ok := h.rooms[room]
+	if !ok || !roomClients[client] {
+		return false
+	}
+	delete(roomClients, client)
+	if len(roomClients) == 0 {
+		delete(h.rooms, room)
+	}
+	return !h.inRoom(client.name, room)
+}
+
+// inRoom reports whether a client of the user is in the room. The caller holds mu.
+func (h *Hub) inRoom(user, room string) bool {
+	for other := range h.rooms[room] {
+		if other.name == user {
+			return true
 		}
 	}
+	return false
 }
 
 // Broadcast message to a room.
 func (h *Hub) handleRoomBroadcast(roomName string, message []byte) {
+	h.roomBroadcast(roomName, frame{data: message})
+}
+
+func (h *Hub) roomBroadcast(roomName string, message frame) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
 	if clients, ok := h.rooms[roomName]; ok {
 		for client := range clients {
 			client.send <- message
@@ -202,64 +346,121 @@ func (h *Hub) handleRoomBroadcast(roomName string, message []byte) {
 
 // Handle private message.
 func (h *Hub) handlePrivateMessage(receiverName string, message []byte) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
 	for _, client := range h.clients {
 		if client.name == receiverName {
-			client.send <- message
+			client.send <- frame{data: message}
 		}
 	}
 }
 
 // Handle join room.
 func (h *Hub) HandleJoinRoom(client *Client, roomName string) {
+	h.mu.Lock()
+	joined := !h.inRoom(client.name, roomName)
 	h.createRoom(roomName)
 	h.joinRoom(client, roomName)
+	h.mu.Unlock()
+	if joined {
+		h.userJoined(roomName, client.name)
+	}
 }
 
 // Run starts the Hub.
 func (h *Hub) Run() {
+	heartbeat := time.NewTicker(h.presenceHeartbeat)
+	defer heartbeat.Stop()
+	var reap <-chan time.Time
+	if h.idle.Timeout > 0 {
+		reaper := time.NewTicker(h.idle.interval())
+		defer reaper.Stop()
+		reap = reaper.C
+	}
 	for {
 		select {
 		case client := <-h.register:
+			h.mu.Lock()
 			h.clients[client.id] = client
+			h.mu.Unlock()
+			h.connectionsTotal.Add(1)
 		case client := <-h.unregister:
+			h.mu.Lock()
+			var left []string
 			if _, ok := h.clients[client.id]; ok {
 				delete(h.clients, client.id)
 				close(client.send)
+				for room := range h.rooms {
+					if h.leaveRoom(client, room) {
+						left = append(left, room)
+					}
+				}
+			}
+			h.mu.Unlock()
+			for _, room := range left {
+				h.userLeft(room, client.name)
 			}
+		case <-reap:
+			go h.reapIdle()
 		case message := <-h.broadcast:
+			h.mu.RLock()
 			for _, client := range h.clients {
 				client.send <- message
 			}
+			h.mu.RUnlock()
+		case <-heartbeat.C:
+			go h.heartbeat()
 		}
 	}
 }
 
+// ServeHTTP upgrades the request to a WebSocket connection of the hub.
+func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	serveWs(h, w, r)
+}
+
 // Serve WebSocket connection and handle reconnections.
 func serveWs(hub *Hub, w http.ResponseWriter, r *http.Request) {
-	conn, err := upgrader.Upgrade(w, r, nil)
+	conn, err := hub.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Println(err)
 		return
 	}
+	if hub.compressionLevel != 0 {
+		// Both are no-ops when the client did not negotiate permessage-deflate
+		conn.EnableWriteCompression(true)
+		conn.SetCompressionLevel(hub.compressionLevel)
+	}
 
 	clientID := r.URL.Query().Get("client_id")
-	var client *Client
-	if clientID != "" && hub.clients[clientID] != nil {
+	hub.mu.RLock()
+	client := hub.clients[clientID]
+	hub.mu.RUnlock()
+	now := time.Now()
+	if clientID != "" && client != nil {
 		// Reconnect existing client
-		client = hub.clients[clientID]
 		client.conn = conn
+		client.lastActivity.Store(now.UnixNano())
 		client.sendUndeliveredMsg() // function that sends unread messages
 	} else {
 		// New client connection
 		clientID = uuid.NewString()
+		name := "root"
+		if hub.userFunc != nil {
+			if user := hub.userFunc(r); user != "" {
+				name = user
+			}
+		}
 		client = &Client{
 			hub:            hub,
 			conn:           conn,
-			send:           make(chan []byte, 256),
+			send:           make(chan frame, 256),
 			id:             clientID,
-			name:           "root",
-			undeliveredMsg: [][]byte{},
+			name:           name,
+			undeliveredMsg: []frame{},
+			connectedAt:    now,
 		}
+		client.lastActivity.Store(now.UnixNano())
 	}
 
 	client.hub.register <- client
@@ -273,7 +474,7 @@ func (c *Client) sendUndeliveredMsg() {
 		c.send <- msg
 	}
 	// Clearing the queue of unread messages after sending
-	c.undeliveredMsg = [][]byte{}
+	c.undeliveredMsg = []frame{}
 }
 
 // WebSocketServer manages the WebSocket server.
@@ -288,13 +489,7 @@ func NewWebSocketServer() *WebSocketServer {
 func (wss *WebSocketServer) NewWsServer(addr string) {
 	var _addr = flag.String("addr", addr, "http service address")
 	flag.Parse()
-	hub := &Hub{
-		broadcast:  make(chan []byte),
-		register:   make(chan *Client),
-		unregister: make(chan *Client),
-		clients:    make(map[string]*Client),
-		rooms:      make(map[string]map[*Client]bool),
-	}
+	hub := NewHub()
 	go hub.Run()
 
 	http.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
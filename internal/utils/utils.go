@@ -1,9 +1,10 @@
 package utils
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
 	"crypto/rand"
-	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -11,12 +12,15 @@ import (
 	"math/big"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	"unicode"
 
 	"github.com/go-redis/redis/v8"
+	passwords "github.com/hokamsingh/lessgo/internal/core/password"
+	"github.com/hokamsingh/lessgo/internal/core/probe"
 )
 
 func GetFolderPath(folderName string) string {
@@ -123,15 +127,19 @@ func Assert(guard bool, text string) {
 	}
 }
 
+// NewRedisClient connects to a single Redis node, authenticating with the REDIS_USERNAME and
+// REDIS_PASSWORD environment variables when set. The client is checked and registered with
+// probe.Default. Use config.NewRedisClient for other settings and for Sentinel or Cluster
+// deployments.
 func NewRedisClient(redisAddr string) *redis.Client {
 	ctx := context.Background()
 	client := redis.NewClient(&redis.Options{
 		Addr:     redisAddr, // e.g., "localhost:6379"
-		Password: "secret",
+		Username: os.Getenv("REDIS_USERNAME"),
+		Password: os.Getenv("REDIS_PASSWORD"),
 	})
-	_, err := client.Ping(ctx).Result()
-	if err != nil {
-		log.Fatalf("Could not connect to Redis: %v", err)
+	if result := probe.Default().CheckTarget(ctx, probe.Redis(client)); !result.Healthy {
+		log.Fatalf("Could not connect to Redis: %v", result.Err)
 	}
 	return client
 }
@@ -146,22 +154,15 @@ func GenerateSalt(length int) (string, error) {
 	return hex.EncodeToString(salt), nil
 }
 
-// HashPassword generates a hash for the given data using the provided salt.
-func HashPassword(data string, salt string, length int) (string, error) {
-	if len(salt) == 0 {
-		return "", errors.New("salt cannot be empty")
-	}
-	// Combine data and salt
-	combined := data + salt
-	// Create a SHA-256 hash
-	hash := sha256.Sum256([]byte(combined))
-	// Convert the hash to a hexadecimal string
-	hashed := hex.EncodeToString(hash[:])
-	// Truncate the hash to the desired length
-	if length > len(hashed) {
-		length = len(hashed)
-	}
-	return hashed[:length], nil
+// HashPassword returns the Argon2id hash of password, with a random salt and the parameters
+// encoded in the result. Store it as is and check passwords with VerifyPassword.
+func HashPassword(password string) (string, error) {
+	return passwords.Hash(password)
+}
+
+// VerifyPassword reports whether password matches a hash returned by HashPassword.
+func VerifyPassword(password, hash string) (bool, error) {
+	return passwords.Verify(password, hash)
 }
 
 // GenerateRandomToken creates a random token of the specified length.
@@ -281,3 +282,29 @@ func ShuffleNumbers(numbers []int) ([]int, error) {
 	}
 	return shuffled, nil
 }
+
+// ZipFiles creates a zip archive holding the given files, keyed by their path in the archive.
+// Files are written in name order so the same input always produces the same archive.
+func ZipFiles(files map[string][]byte) ([]byte, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range names {
+		w, err := zw.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(files[name]); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,230 @@
+package analytics_test
+
+import (
+	stdcontext "context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/analytics"
+	"github.com/hokamsingh/lessgo/internal/core/context"
+	"github.com/hokamsingh/lessgo/internal/core/messaging"
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+	"github.com/hokamsingh/lessgo/internal/core/router"
+)
+
+type memorySink struct {
+	mu      sync.Mutex
+	batches [][]analytics.Event
+	fail    bool
+}
+
+func (s *memorySink) Write(ctx stdcontext.Context, events []analytics.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail {
+		return errors.New("unavailable")
+	}
+	s.batches = append(s.batches, append([]analytics.Event(nil), events...))
+	return nil
+}
+
+func (s *memorySink) events() []analytics.Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var events []analytics.Event
+	for _, batch := range s.batches {
+		events = append(events, batch...)
+	}
+	return events
+}
+
+func TestContextTrack(t *testing.T) {
+	sink := &memorySink{}
+	tracker := analytics.New(sink, analytics.WithFlushInterval(time.Hour))
+	r := router.NewRouter(router.WithAnalytics(tracker))
+	r.Post("/checkout", func(ctx *context.Context) {
+		ctx.Track("checkout_completed", map[string]interface{}{
+			"total":  42.5,
+			"email":  "ann@example.com",
+			"note":   "contact ann@example.com",
+			"nested": map[string]string{"password": "secret"},
+		})
+		ctx.Send("ok")
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/checkout", nil)
+	req.Header.Set("X-Request-ID", "req-1")
+	req = middleware.SetValue(req, middleware.UserKey, "ann")
+	r.Handler().ServeHTTP(httptest.NewRecorder(), req)
+	if tracker.Buffered() != 1 {
+		t.Fatalf("Expected one buffered event, got %d", tracker.Buffered())
+	}
+	if err := tracker.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	events := sink.events()
+	if len(events) != 1 {
+		t.Fatalf("Expected one event, got %d", len(events))
+	}
+	event := events[0]
+	if event.Name != "checkout_completed" || event.UserID != "ann" || event.RequestID != "req-1" || event.ID == "" || event.Time.IsZero() {
+		t.Errorf("Unexpected event %+v", event)
+	}
+	props := event.Properties
+	if props["total"] != 42.5 || props["email"] != "[REDACTED]" || props["note"] != "contact [REDACTED]" {
+		t.Errorf("Expected scrubbed properties, got %v", props)
+	}
+	if nested, _ := props["nested"].(map[string]interface{}); nested["password"] != "[REDACTED]" {
+		t.Errorf("Expected nested fields to be scrubbed, got %v", props["nested"])
+	}
+	if tracker.Track(analytics.Event{Name: "late"}) {
+		t.Error("Expected events after Close to be dropped")
+	}
+}
+
+func TestContextTrack_WithoutTracker(t *testing.T) {
+	r := router.NewRouter()
+	var kept bool
+	r.Get("/", func(ctx *context.Context) {
+		kept = ctx.Track("page_view", nil)
+	})
+	r.Handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if kept {
+		t.Error("Expected no event without a tracker")
+	}
+}
+
+func TestTracker_FlushesFullBatches(t *testing.T) {
+	sink := &memorySink{}
+	tracker := analytics.New(sink, analytics.WithBatchSize(2), analytics.WithFlushInterval(time.Hour))
+	defer tracker.Close()
+	tracker.Track(analytics.Event{Name: "a"})
+	tracker.Track(analytics.Event{Name: "b"})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(sink.events()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if len(sink.events()) != 2 {
+		t.Fatalf("Expected the full batch to be written, got %d events", len(sink.events()))
+	}
+}
+
+func TestTracker_RetriesFailedBatches(t *testing.T) {
+	sink := &memorySink{fail: true}
+	tracker := analytics.New(sink, analytics.WithFlushInterval(time.Hour), analytics.WithMaxBuffered(2))
+	defer tracker.Close()
+	for _, name := range []string{"a", "b", "c"} {
+		tracker.Track(analytics.Event{Name: name})
+	}
+	if err := tracker.Flush(stdcontext.Background()); err == nil {
+		t.Fatal("Expected the failing sink's error")
+	}
+	if tracker.Buffered() != 2 || tracker.Dropped() != 1 {
+		t.Fatalf("Expected 2 buffered and 1 dropped event, got %d and %d", tracker.Buffered(), tracker.Dropped())
+	}
+
+	sink.mu.Lock()
+	sink.fail = false
+	sink.mu.Unlock()
+	if err := tracker.Flush(stdcontext.Background()); err != nil {
+		t.Fatal(err)
+	}
+	events := sink.events()
+	if len(events) != 2 || events[0].Name != "b" || events[1].Name != "c" {
+		t.Errorf("Expected the newest events in order, got %+v", events)
+	}
+}
+
+func TestTracker_Sampling(t *testing.T) {
+	tracker := analytics.New(&memorySink{}, analytics.WithSampleRate(0.5), analytics.WithEventSampleRate("purchase", 1))
+	defer tracker.Close()
+
+	kept := 0
+	for i := 0; i < 1000; i++ {
+		user := fmt.Sprintf("user-%d", i)
+		first := tracker.Track(analytics.Event{Name: "page_view", UserID: user})
+		if tracker.Track(analytics.Event{Name: "page_view", UserID: user}) != first {
+			t.Fatalf("Expected the events of %s to be sampled together", user)
+		}
+		if first {
+			kept++
+		}
+		if !tracker.Track(analytics.Event{Name: "purchase", UserID: user}) {
+			t.Fatal("Expected every purchase to be kept")
+		}
+	}
+	if kept < 400 || kept > 600 {
+		t.Errorf("Expected about half of the users, got %d", kept)
+	}
+}
+
+func TestHTTPSink(t *testing.T) {
+	var body map[string][]map[string]interface{}
+	var user string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, _, _ = r.BasicAuth()
+		data, _ := io.ReadAll(r.Body)
+		json.Unmarshal(data, &body)
+	}))
+	defer server.Close()
+
+	sink := analytics.NewHTTPSink(server.URL, "write-key")
+	events := []analytics.Event{
+		{ID: "1", Name: "signed_up", UserID: "ann", Time: time.Now(), Properties: map[string]interface{}{"plan": "pro"}},
+		{ID: "2", Name: "page_view", Time: time.Now()},
+	}
+	if err := sink.Write(stdcontext.Background(), events); err != nil {
+		t.Fatal(err)
+	}
+	if user != "write-key" || len(body["batch"]) != 2 {
+		t.Fatalf("Unexpected request by %q: %v", user, body)
+	}
+	first, second := body["batch"][0], body["batch"][1]
+	if first["type"] != "track" || first["event"] != "signed_up" || first["userId"] != "ann" || first["messageId"] != "1" {
+		t.Errorf("Unexpected track call %v", first)
+	}
+	if second["anonymousId"] != "2" {
+		t.Errorf("Expected an anonymous ID for events without user, got %v", second)
+	}
+}
+
+func TestBrokerSink(t *testing.T) {
+	broker := messaging.NewMemoryBroker()
+	defer broker.Close()
+	received := make(chan *messaging.Message, 2)
+	broker.Subscribe("analytics.events", "", func(ctx stdcontext.Context, msg *messaging.Message) error {
+		received <- msg
+		return nil
+	})
+
+	sink := analytics.NewBrokerSink(broker, "analytics.events")
+	events := []analytics.Event{{ID: "1", Name: "a", UserID: "ann"}, {ID: "2", Name: "b", AnonymousID: "anon"}}
+	if err := sink.Write(stdcontext.Background(), events); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"ann", "anon"} {
+		select {
+		case msg := <-received:
+			var event analytics.Event
+			if msg.Key != want || json.Unmarshal(msg.Data, &event) != nil || msg.Headers[messaging.MessageIDHeader] != event.ID {
+				t.Errorf("Expected the event of %s keyed by user with its ID, got %+v", want, msg)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected the event of %s to be published", want)
+		}
+	}
+
+	broker.Close()
+	if err := sink.Write(stdcontext.Background(), events); !errors.Is(err, messaging.ErrClosed) {
+		t.Errorf("Expected the error of the broker, got %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package audit_test
+
+import (
+	"bufio"
+	stdcontext "context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/audit"
+	"github.com/hokamsingh/lessgo/internal/core/context"
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+	"github.com/hokamsingh/lessgo/internal/core/router"
+)
+
+func TestAudit_RecordsMutatingRequests(t *testing.T) {
+	var entries []audit.Entry
+	sink := audit.SinkFunc(func(ctx stdcontext.Context, entry audit.Entry) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	r := router.NewRouter(router.WithAudit(sink, audit.WithPayload()))
+	r.Put("/users/{id}", func(ctx *context.Context) {
+		ctx.JSON(http.StatusOK, map[string]string{"status": "updated"})
+	})
+	r.Get("/users/{id}", func(ctx *context.Context) {
+		ctx.Send("ann")
+	})
+	handler := r.Handler()
+
+	req := httptest.NewRequest(http.MethodPut, "/users/7", strings.NewReader(`{"name":"Ann","password":"secret"}`))
+	req = middleware.SetValue(req, middleware.UserKey, "admin")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/7", nil))
+
+	if len(entries) != 1 {
+		t.Fatalf("Expected one entry, got %d", len(entries))
+	}
+	entry := entries[0]
+	if entry.Actor != "admin" || entry.Route != "/users/{id}" || entry.ResourceIDs["id"] != "7" || entry.Status != http.StatusOK {
+		t.Errorf("Unexpected entry %+v", entry)
+	}
+	if entry.PayloadHash == "" || entry.Payload["password"] != "[REDACTED]" || entry.Payload["name"] != "Ann" {
+		t.Errorf("Expected a hashed and redacted payload, got %+v", entry)
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.ndjson")
+	sink, err := audit.NewFileSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, actor := range []string{"ann", "bob"} {
+		if err := sink.Write(stdcontext.Background(), audit.Entry{Actor: actor, Method: http.MethodPost}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sink.Close()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var actors []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry audit.Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatal(err)
+		}
+		actors = append(actors, entry.Actor)
+	}
+	if strings.Join(actors, ",") != "ann,bob" {
+		t.Errorf("Expected both entries, got %v", actors)
+	}
+}
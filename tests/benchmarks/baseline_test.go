@@ -0,0 +1,75 @@
+package benchmarks_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/benchmarks"
+)
+
+const output = `goos: linux
+goarch: amd64
+pkg: github.com/hokamsingh/lessgo/benchmarks
+BenchmarkRouter/static-8         	 1000000	      1100 ns/op	     480 B/op	       6 allocs/op
+BenchmarkRouter/static-8         	 1000000	      1000 ns/op	     480 B/op	       6 allocs/op
+BenchmarkJSON/encode-8           	  500000	      2000 ns/op	    1024 B/op	      12 allocs/op
+PASS
+ok  	github.com/hokamsingh/lessgo/benchmarks	3.2s
+`
+
+func TestParseOutput(t *testing.T) {
+	results, err := benchmarks.ParseOutput(strings.NewReader(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	if results[0].Name != "BenchmarkRouter/static" || results[0].NsPerOp != 1000 {
+		t.Errorf("expected fastest run of BenchmarkRouter/static, got %+v", results[0])
+	}
+	if results[1].BytesPerOp != 1024 || results[1].AllocsPerOp != 12 {
+		t.Errorf("unexpected memory stats %+v", results[1])
+	}
+}
+
+func TestCompare(t *testing.T) {
+	baseline := benchmarks.Baseline{
+		"BenchmarkRouter/static": {Name: "BenchmarkRouter/static", NsPerOp: 1000, BytesPerOp: 480, AllocsPerOp: 6},
+		"BenchmarkJSON/encode":   {Name: "BenchmarkJSON/encode", NsPerOp: 1500, BytesPerOp: 1024, AllocsPerOp: 10},
+	}
+	results, _ := benchmarks.ParseOutput(strings.NewReader(output))
+
+	regressions := benchmarks.Compare(baseline, results, benchmarks.DefaultThresholds())
+	if len(regressions) != 2 {
+		t.Fatalf("expected ns/op and allocs/op regressions, got %v", regressions)
+	}
+	for _, regression := range regressions {
+		if regression.Name != "BenchmarkJSON/encode" {
+			t.Errorf("unexpected regression %v", regression)
+		}
+	}
+
+	lenient := benchmarks.Thresholds{NsPerOp: 0.5, BytesPerOp: -1, AllocsPerOp: -1}
+	if regressions := benchmarks.Compare(baseline, results, lenient); len(regressions) != 0 {
+		t.Errorf("expected no regressions, got %v", regressions)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	results, _ := benchmarks.ParseOutput(strings.NewReader(output))
+	if err := benchmarks.SaveBaseline(path, results); err != nil {
+		t.Fatal(err)
+	}
+	if err := benchmarks.Check(path, strings.NewReader(output), benchmarks.DefaultThresholds()); err != nil {
+		t.Errorf("expected identical results to pass, got %v", err)
+	}
+
+	slower := strings.ReplaceAll(output, "2000 ns/op", "3000 ns/op")
+	err := benchmarks.Check(path, strings.NewReader(slower), benchmarks.DefaultThresholds())
+	if err == nil || !strings.Contains(err.Error(), "BenchmarkJSON/encode") {
+		t.Errorf("expected regression error, got %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package benchmarks_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hokamsingh/lessgo/benchmarks"
+)
+
+func TestRunLoad(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/fail":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/limited":
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer server.Close()
+
+	options := benchmarks.LoadOptions{Concurrency: 4, Requests: 80}
+	report, err := benchmarks.RunLoad(context.Background(), []benchmarks.Target{
+		{URL: server.URL + "/ok", Weight: 2},
+		{Name: "fail", URL: server.URL + "/fail"},
+		{Method: http.MethodPost, URL: server.URL + "/limited", Body: []byte("{}")},
+	}, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Requests != 80 || report.Errors != 20 || report.Throughput <= 0 {
+		t.Fatalf("Expected 80 requests with 20 errors, got %+v", report)
+	}
+	ok, fail, limited := report.Routes[0], report.Routes[1], report.Routes[2]
+	if ok.Name != "GET "+server.URL+"/ok" || ok.Requests != 40 || ok.Errors != 0 || ok.Statuses[200] != 40 {
+		t.Errorf("Expected 40 successful requests to /ok, got %+v", ok)
+	}
+	if fail.Name != "fail" || fail.ErrorRate != 1 || fail.P50 <= 0 || fail.P99 < fail.P50 || fail.Max < fail.P99 {
+		t.Errorf("Expected /fail to fail with latency percentiles, got %+v", fail)
+	}
+	if limited.Errors != 0 || limited.Statuses[http.StatusTooManyRequests] != 20 {
+		t.Errorf("Expected rejections to be reported as statuses, got %+v", limited)
+	}
+
+	var out bytes.Buffer
+	if err := report.Write(&out); err != nil || !strings.Contains(out.String(), "fail") || !strings.Contains(out.String(), "80 requests") {
+		t.Errorf("Expected a table of the routes, got %v\n%s", err, out.String())
+	}
+}
+
+func TestRunLoad_Duration(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	start := time.Now()
+	report, err := benchmarks.RunLoad(context.Background(), []benchmarks.Target{{URL: server.URL}},
+		benchmarks.LoadOptions{Concurrency: 2, Duration: 200 * time.Millisecond, Rate: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the run to stop after its duration, took %s", elapsed)
+	}
+	if report.Requests == 0 || report.Requests > 12 {
+		t.Errorf("Expected about 10 requests at 50 req/s, got %d", report.Requests)
+	}
+
+	if _, err := benchmarks.RunLoad(context.Background(), nil, *benchmarks.NewLoadOptions()); err == nil {
+		t.Error("Expected an error without targets")
+	}
+}
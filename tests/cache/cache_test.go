@@ -0,0 +1,99 @@
+package cache_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/cache"
+)
+
+func TestCache_GetSetDelete(t *testing.T) {
+	c := cache.New()
+	defer c.Close()
+
+	c.Set("a", []byte("1"), 0)
+	if value, ok := c.Get("a"); !ok || string(value) != "1" {
+		t.Errorf("Expected 1, got %q %v", value, ok)
+	}
+	if _, ok := c.Get("b"); ok {
+		t.Error("Expected a miss for an unknown key")
+	}
+	if n := c.Delete("a", "b"); n != 1 {
+		t.Errorf("Expected 1 deleted key, got %d", n)
+	}
+
+	stats := c.Stats()
+	if stats.Hits != 1 || stats.Misses != 1 || stats.Entries != 0 || stats.Bytes != 0 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	// A single shard holding three entries of 10 bytes
+	c := cache.New(cache.WithShards(1), cache.WithMaxBytes(30))
+	defer c.Close()
+
+	for _, key := range []string{"k1", "k2", "k3"} {
+		c.Set(key, []byte("12345678"), 0)
+	}
+	c.Get("k1")
+	c.Set("k4", []byte("12345678"), 0)
+
+	if _, ok := c.Get("k2"); ok {
+		t.Error("Expected the least recently used entry to be evicted")
+	}
+	for _, key := range []string{"k1", "k3", "k4"} {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("Expected %s to be kept", key)
+		}
+	}
+	if stats := c.Stats(); stats.Evictions != 1 || stats.Bytes != 30 {
+		t.Errorf("Expected 1 eviction and 30 bytes, got %+v", stats)
+	}
+
+	c.Set("big", make([]byte, 100), 0)
+	if _, ok := c.Get("big"); ok {
+		t.Error("Expected values larger than the limit not to be stored")
+	}
+}
+
+func TestCache_ExpiresEntries(t *testing.T) {
+	c := cache.New(cache.WithDefaultTTL(10*time.Millisecond), cache.WithSweepInterval(5*time.Millisecond))
+	defer c.Close()
+
+	c.Set("short", []byte("1"), 0)
+	c.Set("long", []byte("2"), time.Minute)
+	time.Sleep(30 * time.Millisecond)
+
+	if stats := c.Stats(); stats.Entries != 1 || stats.Expirations != 1 {
+		t.Errorf("Expected the janitor to remove the expired entry, got %+v", stats)
+	}
+	if _, ok := c.Get("long"); !ok {
+		t.Error("Expected the entry with a longer TTL to be kept")
+	}
+}
+
+func TestCache_Concurrent(t *testing.T) {
+	c := cache.New(cache.WithMaxBytes(1 << 10))
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				key := fmt.Sprintf("%d-%d", i, j%50)
+				c.Set(key, []byte(key), 0)
+				c.Get(key)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if stats := c.Stats(); stats.Bytes > 1<<10 {
+		t.Errorf("Expected at most 1 KiB held, got %d bytes", stats.Bytes)
+	}
+}
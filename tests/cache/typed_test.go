@@ -0,0 +1,126 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/cache"
+)
+
+type user struct {
+	ID   int
+	Name string
+}
+
+func newUserCache(codec cache.Codec) *cache.Typed[user] {
+	c := cache.New(cache.WithSweepInterval(0))
+	options := cache.NewTypedOptions("user:")
+	options.Codec = codec
+	return cache.NewTyped[user](cache.NewMemoryStore(c), *options)
+}
+
+func TestTyped_GetOrLoadCodecs(t *testing.T) {
+	for name, codec := range map[string]cache.Codec{"json": cache.JSON, "gob": cache.Gob, "msgpack": cache.MsgPack} {
+		users := newUserCache(codec)
+		var loads atomic.Int32
+		load := func(ctx context.Context) (user, error) {
+			loads.Add(1)
+			return user{ID: 1, Name: "Ann"}, nil
+		}
+		for i := 0; i < 2; i++ {
+			got, err := users.GetOrLoad(context.Background(), "1", time.Minute, load)
+			if err != nil || got.Name != "Ann" {
+				t.Errorf("%s: expected Ann, got %+v %v", name, got, err)
+			}
+		}
+		if n := loads.Load(); n != 1 {
+			t.Errorf("%s: expected the loader to be called once, got %d", name, n)
+		}
+	}
+}
+
+func TestTyped_CoalescesLoads(t *testing.T) {
+	users := newUserCache(cache.JSON)
+	var loads atomic.Int32
+	release := make(chan struct{})
+	load := func(ctx context.Context) (user, error) {
+		loads.Add(1)
+		<-release
+		return user{ID: 2, Name: "Bob"}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got, err := users.GetOrLoad(context.Background(), "2", time.Minute, load); err != nil || got.Name != "Bob" {
+				t.Errorf("Expected Bob, got %+v %v", got, err)
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := loads.Load(); n != 1 {
+		t.Errorf("Expected concurrent loads to call the loader once, got %d", n)
+	}
+}
+
+func TestTyped_NegativeCaching(t *testing.T) {
+	users := newUserCache(cache.JSON)
+	var loads atomic.Int32
+	missing := func(ctx context.Context) (user, error) {
+		loads.Add(1)
+		return user{}, cache.ErrNotFound
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := users.GetOrLoad(context.Background(), "3", time.Minute, missing); !errors.Is(err, cache.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+	}
+	if n := loads.Load(); n != 1 {
+		t.Errorf("Expected the missing value to be cached, got %d loads", n)
+	}
+
+	failing := func(ctx context.Context) (user, error) {
+		loads.Add(1)
+		return user{}, errors.New("database down")
+	}
+	users.GetOrLoad(context.Background(), "4", time.Minute, failing)
+	users.GetOrLoad(context.Background(), "4", time.Minute, failing)
+	if n := loads.Load(); n != 3 {
+		t.Errorf("Expected other errors not to be cached, got %d loads", n)
+	}
+}
+
+func TestAdd_ClaimsKeyOnce(t *testing.T) {
+	store := cache.NewMemoryStore(cache.New(cache.WithSweepInterval(0)))
+	var claimed atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if first, err := cache.Add(context.Background(), store, "claim", []byte{1}, time.Minute); err == nil && first {
+				claimed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := claimed.Load(); n != 1 {
+		t.Errorf("Expected the key to be claimed once, got %d", n)
+	}
+
+	store.Set(context.Background(), "expired", []byte{1}, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if first, _ := cache.Add(context.Background(), store, "expired", []byte{2}, time.Minute); !first {
+		t.Error("Expected an expired key to be claimed again")
+	}
+}
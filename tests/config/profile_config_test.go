@@ -0,0 +1,79 @@
+package config_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/config"
+)
+
+func TestEnv(t *testing.T) {
+	cases := []struct {
+		cfg  config.Config
+		want string
+	}{
+		{config.Config{}, config.Development},
+		{config.Config{"ENV": "Prod"}, config.Production},
+		{config.Config{"APP_ENV": "staging"}, config.Staging},
+		{config.Config{"ENV": "testing", "APP_ENV": "production"}, config.Test},
+		{config.Config{"ENV": "qa"}, "qa"},
+	}
+	for _, c := range cases {
+		if env := c.cfg.Env(); env != c.want {
+			t.Errorf("Expected %s for %v, got %s", c.want, c.cfg, env)
+		}
+	}
+
+	cfg := config.Config{"ENV": "prod"}
+	if !cfg.IsProduction() || cfg.IsDevelopment() || cfg.IsStaging() || cfg.IsTest() {
+		t.Error("Expected only IsProduction to hold")
+	}
+	if !cfg.IsEnv("staging", "production") || cfg.IsEnv("dev") {
+		t.Error("Expected IsEnv to match aliases")
+	}
+}
+
+func TestResolveEnv(t *testing.T) {
+	cfg := config.Config{
+		"ENV":                "production",
+		"DB_HOST":            "localhost",
+		"DB_HOST.staging":    "db.staging.internal",
+		"DB_HOST.production": "db.internal",
+		"LOG_LEVEL.prod":     "warn",
+		"PORT":               "8080",
+	}.ResolveEnv()
+
+	if cfg.Get("DB_HOST", "") != "db.internal" || cfg.Get("LOG_LEVEL", "") != "warn" || cfg.Get("PORT", "") != "8080" {
+		t.Errorf("Expected the production values, got %v", cfg)
+	}
+	if _, ok := cfg["DB_HOST.staging"]; ok {
+		t.Error("Expected the values of other environments to be left out")
+	}
+
+	os.Setenv("ENV", "staging")
+	os.Setenv("API_URL", "http://localhost:3000")
+	os.Setenv("API_URL.staging", "https://staging.example.com")
+	defer os.Unsetenv("ENV")
+	defer os.Unsetenv("API_URL")
+	defer os.Unsetenv("API_URL.staging")
+	if url := config.LoadConfig().Get("API_URL", ""); url != "https://staging.example.com" {
+		t.Errorf("Expected LoadConfig to resolve the staging value, got %s", url)
+	}
+}
+
+func TestMergeWithEnvDefaults(t *testing.T) {
+	defaults := map[string]config.Config{
+		"":            {"LOG_LEVEL": "info", "TIMEOUT": "5s"},
+		"development": {"LOG_LEVEL": "debug"},
+		"prod":        {"LOG_LEVEL": "warn", "TIMEOUT": "2s"},
+	}
+
+	cfg := config.Config{"ENV": "production", "TIMEOUT": "1s"}.MergeWithEnvDefaults(defaults)
+	if cfg.Get("LOG_LEVEL", "") != "warn" || cfg.Get("TIMEOUT", "") != "1s" {
+		t.Errorf("Expected production defaults below the set values, got %v", cfg)
+	}
+	cfg = config.Config{"ENV": "staging"}.MergeWithEnvDefaults(defaults)
+	if cfg.Get("LOG_LEVEL", "") != "info" || cfg.Get("TIMEOUT", "") != "5s" {
+		t.Errorf("Expected the shared defaults, got %v", cfg)
+	}
+}
@@ -0,0 +1,51 @@
+package config_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/config"
+)
+
+func TestRedisConfigFromEnv(t *testing.T) {
+	cfg := config.RedisConfigFromEnv(config.Config{
+		"REDIS_ADDRS":        "10.0.0.1:26379, 10.0.0.2:26379",
+		"REDIS_MASTER_NAME":  "mymaster",
+		"REDIS_PASSWORD":     "hunter2",
+		"REDIS_DB":           "2",
+		"REDIS_TLS":          "true",
+		"REDIS_READ_TIMEOUT": "750ms",
+	})
+
+	if len(cfg.Addrs) != 2 || cfg.Addrs[1] != "10.0.0.2:26379" {
+		t.Errorf("Expected two addresses, got %v", cfg.Addrs)
+	}
+	if cfg.Password != "hunter2" || cfg.DB != 2 || !cfg.TLS {
+		t.Errorf("Unexpected settings %+v", cfg)
+	}
+	if cfg.ReadTimeout != 750*time.Millisecond || cfg.WriteTimeout != 3*time.Second {
+		t.Errorf("Expected parsed and default timeouts, got %v and %v", cfg.ReadTimeout, cfg.WriteTimeout)
+	}
+	if topology := cfg.ResolvedTopology(); topology != config.RedisSentinel {
+		t.Errorf("Expected sentinel with a master name, got %s", topology)
+	}
+}
+
+func TestRedisConfig_Topology(t *testing.T) {
+	if topology := config.NewRedisConfig("localhost:6379").ResolvedTopology(); topology != config.RedisStandalone {
+		t.Errorf("Expected standalone for one address, got %s", topology)
+	}
+	cluster := config.NewRedisConfig("", config.WithRedisAddrs("a:6379", "b:6379", "c:6379"))
+	if topology := cluster.ResolvedTopology(); topology != config.RedisCluster {
+		t.Errorf("Expected cluster for several addresses, got %s", topology)
+	}
+
+	cluster.DB = 1
+	if _, err := config.NewRedisClient(*cluster); err == nil {
+		t.Error("Expected an error for a database other than 0 on a cluster")
+	}
+	sentinel := config.NewRedisConfig("", config.WithRedisTopology(config.RedisSentinel))
+	if _, err := config.NewRedisClient(*sentinel); err == nil {
+		t.Error("Expected an error for sentinel without a master name")
+	}
+}
@@ -0,0 +1,109 @@
+package config_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/config"
+)
+
+func testSchema() *config.Schema {
+	return config.NewSchema(
+		config.Field{Key: "SERVER_PORT", Type: config.TypeInt, Default: "8080", Description: "Port the server listens on"},
+		config.Field{Key: "DATABASE_URL", Type: config.TypeURL, Required: true, Secret: true},
+		config.Field{Key: "LOG_LEVEL", Default: "info", Allowed: []string{"debug", "info", "warn", "error"}},
+		config.Field{Key: "FEATURES", Type: config.TypeList, Allowed: []string{"search", "export"}},
+		config.Field{Key: "TIMEOUT", Type: config.TypeDuration},
+	)
+}
+
+func TestSchemaValidate(t *testing.T) {
+	schema := testSchema()
+
+	cfg, err := schema.Apply(config.Config{"DATABASE_URL": "postgres://db/app", "FEATURES": "search, export", "TIMEOUT": "3s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.GetInt("SERVER_PORT", 0) != 8080 || cfg.Get("LOG_LEVEL", "") != "info" {
+		t.Errorf("Expected the defaults to be filled in, got %v", cfg)
+	}
+
+	err = schema.Validate(config.Config{
+		"SERVER_PORT": "eighty",
+		"LOG_LEVEL":   "trace",
+		"FEATURES":    "search,admin",
+		"TIMEOUT":     "3",
+	})
+	var schemaErr *config.SchemaError
+	if !errors.As(err, &schemaErr) {
+		t.Fatalf("Expected a SchemaError, got %v", err)
+	}
+	want := []string{
+		`SERVER_PORT: "eighty" is not a valid int`,
+		"DATABASE_URL is required",
+		`LOG_LEVEL: "trace" is not one of debug, info, warn, error`,
+		`FEATURES: "admin" is not one of search, export`,
+		`TIMEOUT: "3" is not a valid duration`,
+	}
+	if strings.Join(schemaErr.Problems, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected every problem at once, got %q", schemaErr.Problems)
+	}
+
+	err = schema.Validate(config.Config{"DATABASE_URL": "not a url"})
+	if err == nil || strings.Contains(err.Error(), "not a url") {
+		t.Errorf("Expected the secret to be left out of the error, got %v", err)
+	}
+}
+
+func TestSchemaSamples(t *testing.T) {
+	schema := testSchema()
+
+	var env bytes.Buffer
+	if err := schema.WriteEnvSample(&env); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"# Port the server listens on\n# int, default 8080\nSERVER_PORT=8080\n",
+		"# url, required\nDATABASE_URL=\n",
+		"# string, one of debug, info, warn, error, default info\nLOG_LEVEL=info\n",
+		"# duration\n# TIMEOUT=\n",
+	} {
+		if !strings.Contains(env.String(), want) {
+			t.Errorf("Expected %q in the sample:\n%s", want, env.String())
+		}
+	}
+
+	var doc bytes.Buffer
+	if err := schema.WriteYAMLSample(&doc); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(doc.String(), "# Port the server listens on\n# int, default 8080\nSERVER_PORT: \"8080\"\n") {
+		t.Errorf("Unexpected YAML sample:\n%s", doc.String())
+	}
+}
+
+func TestLoadSchemaFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.schema.yaml")
+	os.WriteFile(path, []byte(`
+- key: SERVER_PORT
+  type: int
+  default: 8080
+- key: DEBUG
+  type: bool
+  default: false
+`), 0o644)
+	schema, err := config.LoadSchemaFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(schema.Fields) != 2 || schema.Fields[0].Default != "8080" || schema.Fields[1].Default != "false" {
+		t.Errorf("Unexpected fields %+v", schema.Fields)
+	}
+	if err := schema.Validate(config.Config{"DEBUG": "yes"}); err == nil {
+		t.Error("Expected an invalid boolean to be reported")
+	}
+}
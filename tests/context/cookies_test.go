@@ -0,0 +1,99 @@
+package context_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/context"
+)
+
+// roundTrip sets a cookie with set and returns a context for a request carrying it back.
+func roundTrip(t *testing.T, set func(ctx *context.Context) error) (*context.Context, *http.Cookie) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	if err := set(context.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)); err != nil {
+		t.Fatal(err)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("Expected one cookie, got %d", len(cookies))
+	}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookies[0])
+	return context.NewContext(req, httptest.NewRecorder()), cookies[0]
+}
+
+func withCookie(name, value string) *context.Context {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: name, Value: value})
+	return context.NewContext(req, httptest.NewRecorder())
+}
+
+func TestContext_SignedCookie(t *testing.T) {
+	context.SetCookieKeys([]byte("current"))
+	ctx, cookie := roundTrip(t, func(ctx *context.Context) error {
+		return ctx.SetSignedCookie("cart", "cart-42", 3600, "/", true, true, http.SameSiteLaxMode)
+	})
+	if value, ok := ctx.GetSignedCookie("cart"); !ok || value != "cart-42" {
+		t.Fatalf("Expected the signed value, got %q %v", value, ok)
+	}
+	if !cookie.HttpOnly || !cookie.Secure || cookie.MaxAge != 3600 {
+		t.Errorf("Expected the cookie attributes to be kept, got %+v", cookie)
+	}
+
+	tampered := "Y2FydC0xMw" + cookie.Value[strings.IndexByte(cookie.Value, '.'):]
+	if _, ok := withCookie("cart", tampered).GetSignedCookie("cart"); ok {
+		t.Error("Expected a modified value to be rejected")
+	}
+	if _, ok := withCookie("other", cookie.Value).GetSignedCookie("other"); ok {
+		t.Error("Expected the cookie to be bound to its name")
+	}
+
+	// Rotation: cookies of the previous key stay valid until it is dropped
+	context.SetCookieKeys([]byte("next"), []byte("current"))
+	if value, ok := ctx.GetSignedCookie("cart"); !ok || value != "cart-42" {
+		t.Errorf("Expected cookies of the previous key to be accepted, got %q %v", value, ok)
+	}
+	context.SetCookieKeys([]byte("next"))
+	if _, ok := ctx.GetSignedCookie("cart"); ok {
+		t.Error("Expected cookies of a dropped key to be rejected")
+	}
+}
+
+func TestContext_EncryptedCookie(t *testing.T) {
+	context.SetCookieKeys([]byte("current"))
+	ctx, cookie := roundTrip(t, func(ctx *context.Context) error {
+		return ctx.SetEncryptedCookie("session", "user-7", 0, "/", true, true, http.SameSiteStrictMode)
+	})
+	if strings.Contains(cookie.Value, "user-7") {
+		t.Error("Expected the value to be encrypted")
+	}
+	if value, ok := ctx.GetEncryptedCookie("session"); !ok || value != "user-7" {
+		t.Fatalf("Expected the decrypted value, got %q %v", value, ok)
+	}
+
+	flipped := []byte(cookie.Value)
+	flipped[len(flipped)-2] ^= 1
+	if _, ok := withCookie("session", string(flipped)).GetEncryptedCookie("session"); ok {
+		t.Error("Expected a modified cookie to be rejected")
+	}
+
+	context.SetCookieKeys([]byte("next"), []byte("current"))
+	if value, ok := ctx.GetEncryptedCookie("session"); !ok || value != "user-7" {
+		t.Errorf("Expected cookies of the previous key to be decrypted, got %q %v", value, ok)
+	}
+}
+
+func TestContext_CookieKeysRequired(t *testing.T) {
+	context.SetCookieKeys()
+	ctx := context.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+	if err := ctx.SetSignedCookie("a", "b", 0, "/", true, true, http.SameSiteLaxMode); err != context.ErrNoCookieKeys {
+		t.Errorf("Expected ErrNoCookieKeys, got %v", err)
+	}
+	context.SetCookieKeys([]byte("key"))
+	if err := ctx.SetEncryptedCookie("a", strings.Repeat("x", 4000), 0, "/", true, true, http.SameSiteLaxMode); err != context.ErrCookieTooLarge {
+		t.Errorf("Expected ErrCookieTooLarge, got %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+package context_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/context"
+)
+
+func TestEnvelopeOK(t *testing.T) {
+	rec := httptest.NewRecorder()
+	ctx := context.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+	ctx.SetMeta("version", "v2")
+	ctx.Created(map[string]int{"id": 1})
+
+	if rec.Code != http.StatusCreated {
+		t.Errorf("status = %d", rec.Code)
+	}
+	var body map[string]interface{}
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	if body["error"] != nil || body["data"].(map[string]interface{})["id"] != 1.0 {
+		t.Errorf("unexpected body %v", body)
+	}
+	if body["meta"].(map[string]interface{})["version"] != "v2" {
+		t.Errorf("missing meta in %v", body)
+	}
+}
+
+func TestEnvelopeFail(t *testing.T) {
+	rec := httptest.NewRecorder()
+	ctx := context.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+	ctx.Fail(context.CodeValidation, "Invalid input", map[string]string{"email": "is required"})
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("status = %d", rec.Code)
+	}
+	var body struct {
+		Data  interface{}       `json:"data"`
+		Error *context.APIError `json:"error"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	if body.Data != nil || body.Error == nil || body.Error.Code != context.CodeValidation {
+		t.Errorf("unexpected body %s", rec.Body.String())
+	}
+}
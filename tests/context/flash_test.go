@@ -0,0 +1,90 @@
+package context_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/context"
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+)
+
+// nextRequest returns a request carrying back the cookies set in rec.
+func nextRequest(rec *httptest.ResponseRecorder) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.MaxAge >= 0 {
+			req.AddCookie(cookie)
+		}
+	}
+	return req
+}
+
+func TestContext_FlashIsShownOnce(t *testing.T) {
+	for _, keys := range [][][]byte{nil, {[]byte("key")}} {
+		context.SetCookieKeys(keys...)
+
+		rec := httptest.NewRecorder()
+		ctx := context.NewContext(httptest.NewRequest(http.MethodPost, "/profile", nil), rec)
+		ctx.Flash("success", "Saved!")
+		ctx.Flash("success", "Email sent, check your inbox.")
+		if cookies := rec.Header().Values("Set-Cookie"); len(cookies) != 1 {
+			t.Fatalf("Expected a single flash cookie, got %v", cookies)
+		}
+
+		rec2 := httptest.NewRecorder()
+		ctx = context.NewContext(nextRequest(rec), rec2)
+		flashes := ctx.Flashes()
+		if len(flashes["success"]) != 2 || flashes["success"][1] != "Email sent, check your inbox." {
+			t.Fatalf("Expected the flashes of the previous request, got %v", flashes)
+		}
+		cleared := rec2.Result().Cookies()
+		if len(cleared) != 1 || cleared[0].MaxAge >= 0 {
+			t.Fatalf("Expected the flash cookie to be cleared, got %v", cleared)
+		}
+
+		ctx = context.NewContext(nextRequest(rec2), httptest.NewRecorder())
+		if flashes := ctx.Flashes(); len(flashes) != 0 {
+			t.Errorf("Expected no flashes on the third request, got %v", flashes)
+		}
+	}
+}
+
+func TestContext_FlashInTemplates(t *testing.T) {
+	context.SetCookieKeys([]byte("key"))
+	dir := t.TempDir()
+	page := `{{define "page.html"}}{{range $kind, $messages := flashes}}{{range $messages}}<p class="{{$kind}}">{{.}}</p>{{end}}{{end}}{{.}}{{end}}`
+	if err := os.WriteFile(filepath.Join(dir, "page.html"), []byte(page), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	templates := middleware.NewTemplateMiddleware(dir)
+
+	render := func(req *http.Request, handler func(ctx *context.Context)) *httptest.ResponseRecorder {
+		req.Header.Set("Accept", "text/html")
+		rec := httptest.NewRecorder()
+		templates.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handler(context.NewContext(r, w))
+		})).ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := render(httptest.NewRequest(http.MethodPost, "/", nil), func(ctx *context.Context) {
+		ctx.Flash("error", "<b>Invalid</b> email")
+		ctx.Res.WriteHeader(http.StatusSeeOther)
+	})
+	second := render(nextRequest(first), func(ctx *context.Context) {
+		ctx.Negotiate(http.StatusOK, "body", "page.html")
+	})
+	if body := second.Body.String(); body != `<p class="error">&lt;b&gt;Invalid&lt;/b&gt; email</p>body` {
+		t.Errorf("Expected the escaped flash in the page, got %q", body)
+	}
+	third := render(nextRequest(second), func(ctx *context.Context) {
+		ctx.Negotiate(http.StatusOK, "body", "page.html")
+	})
+	if body := third.Body.String(); strings.Contains(body, "Invalid") {
+		t.Errorf("Expected the flash to be shown once, got %q", body)
+	}
+}
@@ -0,0 +1,88 @@
+package context_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/context"
+)
+
+type signupForm struct {
+	Name       string    `form:"name,sanitize"`
+	Age        int       `form:"age"`
+	Terms      bool      `form:"terms"`
+	Newsletter bool      `form:"newsletter"`
+	Interests  []string  `form:"interests"`
+	Birthday   time.Time `form:"birthday" layout:"2006-01-02"`
+	Country    string    `form:"country" default:"NP"`
+	Nickname   *string   `form:"nickname"`
+}
+
+func newFormContext(values url.Values) *context.Context {
+	req := httptest.NewRequest(http.MethodPost, "/signup", strings.NewReader(values.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return context.NewContext(req, httptest.NewRecorder())
+}
+
+func TestBindForm(t *testing.T) {
+	ctx := newFormContext(url.Values{
+		"name":      {"<b>Ada</b><script>alert(1)</script>"},
+		"age":       {"36"},
+		"terms":     {"on"},
+		"interests": {"go", "math"},
+		"birthday":  {"1815-12-10"},
+	})
+
+	var form signupForm
+	if err := ctx.BindForm(&form); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if form.Name != "Ada" {
+		t.Errorf("Expected sanitized name 'Ada', got %q", form.Name)
+	}
+	if form.Age != 36 || !form.Terms || form.Newsletter {
+		t.Errorf("Unexpected scalar values: %+v", form)
+	}
+	if len(form.Interests) != 2 || form.Interests[1] != "math" {
+		t.Errorf("Expected interests slice, got %v", form.Interests)
+	}
+	if form.Birthday.Year() != 1815 {
+		t.Errorf("Expected parsed birthday, got %v", form.Birthday)
+	}
+	if form.Country != "NP" {
+		t.Errorf("Expected default country, got %q", form.Country)
+	}
+	if form.Nickname != nil {
+		t.Errorf("Expected nil nickname, got %v", *form.Nickname)
+	}
+}
+
+func TestBindForm_InvalidValue(t *testing.T) {
+	ctx := newFormContext(url.Values{"age": {"old"}})
+
+	var form signupForm
+	if err := ctx.BindForm(&form); err == nil {
+		t.Fatal("Expected an error for a non numeric age")
+	}
+	if err := ctx.BindForm(form); err == nil {
+		t.Fatal("Expected an error for a non pointer destination")
+	}
+}
+
+func TestBindForm_SanitizeAmongOtherOptions(t *testing.T) {
+	ctx := newFormContext(url.Values{"bio": {`<img src=x onerror=alert(1)>Hi & bye`}})
+	var form struct {
+		Bio string `form:"bio,omitempty,sanitize"`
+	}
+	if err := ctx.BindForm(&form); err != nil {
+		t.Fatal(err)
+	}
+	if form.Bio != "Hi &amp; bye" {
+		t.Errorf("Expected the strict policy to clean the bio, got %q", form.Bio)
+	}
+}
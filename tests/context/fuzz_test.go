@@ -0,0 +1,64 @@
+package context_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/context"
+)
+
+func FuzzNegotiateContentType(f *testing.F) {
+	for _, seed := range []string{
+		"application/json",
+		"text/html;q=0.9, application/xml;q=0.8, */*;q=0.1",
+		"*/*;q=", ";;;,,,", "text/*;q=1e308", "a/b;q=NaN", "/", "*",
+	} {
+		f.Add(seed)
+	}
+	offers := []string{"application/json", "application/xml", "text/html"}
+	f.Fuzz(func(t *testing.T, accept string) {
+		got := context.NegotiateContentType(accept, offers, "application/json")
+		if got != "" && got != "application/json" && got != "application/xml" && got != "text/html" {
+			t.Errorf("Negotiated %q, which was not offered", got)
+		}
+	})
+}
+
+type fuzzQuery struct {
+	Name     string        `form:"name,sanitize"`
+	Page     int8          `form:"page"`
+	Limit    uint16        `form:"limit"`
+	Price    float32       `form:"price"`
+	Active   bool          `form:"active"`
+	Tags     []string      `form:"tag"`
+	IDs      []int         `form:"id"`
+	Since    time.Time     `form:"since" layout:"2006-01-02"`
+	Timeout  time.Duration `form:"timeout"`
+	Cursor   *int64        `form:"cursor"`
+	Optional *string       `form:"optional"`
+}
+
+func FuzzBindQuery(f *testing.F) {
+	for _, seed := range []string{
+		"name=<b>Ann</b>&page=2&limit=50&price=9.5&active=on&tag=a&tag=b&id=1&id=2&since=2024-01-31&timeout=5s&cursor=9",
+		"name=%3Cscript%3Ealert(1)%3C/script%3E&page=300",
+		"name=\xc4\xb0<i>&price=1e400&cursor=&optional=",
+		"%zz=1&;&&=&id=-1",
+		"name=\u212a\u212a\u212a<b>x</b>",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, query string) {
+		req := httptest.NewRequest(http.MethodGet, "/search", nil)
+		req.URL.RawQuery = query
+		ctx := context.NewContext(req, httptest.NewRecorder())
+		var dst fuzzQuery
+		// Invalid values are errors, never panics
+		if err := ctx.BindForm(&dst); err == nil && strings.ContainsAny(dst.Name, "<>") {
+			t.Errorf("Expected the name to be sanitized, got %q", dst.Name)
+		}
+	})
+}
@@ -0,0 +1,83 @@
+package context_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/context"
+	"github.com/hokamsingh/lessgo/internal/core/i18n"
+)
+
+func TestContext_LocaleAndLocation(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Language", "de-de;q=0.9, en;q=0.5")
+	req.Header.Set("Time-Zone", "Europe/Berlin")
+	ctx := context.NewContext(req, httptest.NewRecorder())
+
+	if ctx.Locale() != "de-DE" {
+		t.Errorf("Expected de-DE, got %s", ctx.Locale())
+	}
+	if ctx.Location().String() != "Europe/Berlin" {
+		t.Errorf("Expected Europe/Berlin, got %s", ctx.Location())
+	}
+
+	instant := time.Date(2024, 12, 31, 23, 30, 0, 0, time.UTC)
+	if date := ctx.FormatDateTime(instant); date != "01.01.2025 00:30" {
+		t.Errorf("Expected Berlin local date, got %q", date)
+	}
+	if amount := ctx.FormatCurrency(-1234.5, "EUR"); amount != "-1.234,50\u00a0€" {
+		t.Errorf("Unexpected currency %q", amount)
+	}
+
+	invalid := httptest.NewRequest(http.MethodGet, "/", nil)
+	invalid.Header.Set("X-Timezone", "Not/AZone")
+	if location := context.NewContext(invalid, httptest.NewRecorder()).Location(); location != time.UTC {
+		t.Errorf("Expected UTC for an unknown zone, got %s", location)
+	}
+}
+
+func TestContext_LocaleSettings(t *testing.T) {
+	context.SetLocaleSettings(func(r *http.Request) (string, string) {
+		if r.Header.Get("X-User") == "ann" {
+			return "ja", "Asia/Tokyo"
+		}
+		return "", ""
+	})
+	defer context.SetLocaleSettings(nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-User", "ann")
+	req.Header.Set("Accept-Language", "en")
+	ctx := context.NewContext(req, httptest.NewRecorder())
+	if ctx.Locale() != "ja" || ctx.Location().String() != "Asia/Tokyo" {
+		t.Errorf("Expected user settings, got %s %s", ctx.Locale(), ctx.Location())
+	}
+	if amount := ctx.FormatCurrency(1500, "JPY"); amount != "¥1,500" {
+		t.Errorf("Unexpected currency %q", amount)
+	}
+}
+
+func TestFormatNumber(t *testing.T) {
+	tests := []struct {
+		locale   string
+		value    float64
+		decimals int
+		expected string
+	}{
+		{"en", 1234567.891, 2, "1,234,567.89"},
+		{"de", 1234567.891, 1, "1.234.567,9"},
+		{"fr", 1234.5, 2, "1\u202f234,50"},
+		{"en", -0.001, 2, "0.00"},
+		{"xx", 999, 0, "999"},
+	}
+	for _, test := range tests {
+		if actual := i18n.FormatNumber(test.locale, test.value, test.decimals); actual != test.expected {
+			t.Errorf("%s %v: expected %q, got %q", test.locale, test.value, test.expected, actual)
+		}
+	}
+	if amount := i18n.FormatCurrency("en", 1234.5, "USD"); amount != "$1,234.50" {
+		t.Errorf("Unexpected currency %q", amount)
+	}
+}
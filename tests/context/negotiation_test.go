@@ -0,0 +1,89 @@
+package context_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/context"
+)
+
+func TestNegotiateContentType(t *testing.T) {
+	offers := []string{context.ContentTypeJSON, context.ContentTypeXML, context.ContentTypeText}
+
+	cases := []struct {
+		accept   string
+		expected string
+	}{
+		{"", context.ContentTypeJSON},
+		{"*/*", context.ContentTypeJSON},
+		{"application/xml", context.ContentTypeXML},
+		{"application/json;q=0.5, application/xml;q=0.9", context.ContentTypeXML},
+		{"text/*;q=0.8, application/json;q=0.2", context.ContentTypeText},
+		{"application/xml;q=0, */*;q=0.1", context.ContentTypeJSON},
+		{"image/png", context.ContentTypeJSON},
+		{"application/json;q=abc, application/xml", context.ContentTypeXML},
+	}
+
+	for _, c := range cases {
+		if got := context.NegotiateContentType(c.accept, offers, context.ContentTypeJSON); got != c.expected {
+			t.Errorf("Accept %q: expected %s, got %s", c.accept, c.expected, got)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "application/xml")
+	w := httptest.NewRecorder()
+
+	type user struct {
+		Name string `json:"name" xml:"name"`
+	}
+	context.NewContext(req, w).Negotiate(http.StatusCreated, user{Name: "ada"})
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("Expected status 201, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != context.ContentTypeXML {
+		t.Errorf("Expected XML content type, got %q", ct)
+	}
+	if !strings.Contains(w.Body.String(), "<name>ada</name>") {
+		t.Errorf("Expected XML body, got %q", w.Body.String())
+	}
+}
+
+func TestJSONP(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	context.NewContext(req, rec).JSONP(http.StatusOK, "app.cb", map[string]int{"a": 1})
+	if got := rec.Body.String(); got != `/**/app.cb({"a":1});` {
+		t.Errorf("unexpected body %q", got)
+	}
+
+	rec = httptest.NewRecorder()
+	context.NewContext(req, rec).JSONP(http.StatusOK, "alert(1)//", map[string]int{"a": 1})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid callback, got %d", rec.Code)
+	}
+}
+
+func TestPrettyJSONQuery(t *testing.T) {
+	context.SetPrettyJSON(context.PrettyJSONQuery)
+	defer context.SetPrettyJSON(context.PrettyJSONOff)
+
+	req := httptest.NewRequest(http.MethodGet, "/?pretty=1", nil)
+	rec := httptest.NewRecorder()
+	context.NewContext(req, rec).JSON(http.StatusOK, map[string]int{"a": 1})
+	if got := rec.Body.String(); got != "{\n  \"a\": 1\n}\n" {
+		t.Errorf("expected indented JSON, got %q", got)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	rec = httptest.NewRecorder()
+	context.NewContext(req, rec).JSON(http.StatusOK, map[string]int{"a": 1})
+	if got := rec.Body.String(); got != "{\"a\":1}\n" {
+		t.Errorf("expected compact JSON, got %q", got)
+	}
+}
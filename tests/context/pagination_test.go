@@ -0,0 +1,76 @@
+package context_test
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/context"
+	"github.com/hokamsingh/lessgo/internal/core/pagination"
+)
+
+func TestPaginated(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/users?page=2&limit=500&sort=name", nil)
+	rec := httptest.NewRecorder()
+	ctx := context.NewContext(req, rec)
+
+	p := ctx.Pagination()
+	if p.Limit != pagination.MaxLimit || p.Offset() != pagination.MaxLimit {
+		t.Fatalf("unexpected params %+v", p)
+	}
+	ctx.Paginated([]string{"a", "b"}, 250, p)
+
+	if got := rec.Header().Get("X-Total-Count"); got != "250" {
+		t.Errorf("X-Total-Count = %q", got)
+	}
+	var envelope pagination.Envelope
+	if err := json.Unmarshal(rec.Body.Bytes(), &envelope); err != nil {
+		t.Fatal(err)
+	}
+	if envelope.Meta.TotalPages != 3 {
+		t.Errorf("total pages = %d", envelope.Meta.TotalPages)
+	}
+	if envelope.Links.Next != "/users?limit=100&page=3&sort=name" {
+		t.Errorf("next = %q", envelope.Links.Next)
+	}
+	if envelope.Links.Prev != "/users?limit=100&page=1&sort=name" {
+		t.Errorf("prev = %q", envelope.Links.Prev)
+	}
+}
+
+func TestPaginatedCursor(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/events?cursor=abc", nil)
+	rec := httptest.NewRecorder()
+	ctx := context.NewContext(req, rec)
+
+	p := ctx.Pagination()
+	p.NextCursor = pagination.EncodeCursor("42")
+	ctx.Paginated([]int{1}, -1, p)
+
+	if rec.Header().Get("X-Total-Count") != "" {
+		t.Error("unexpected X-Total-Count for unknown total")
+	}
+	var envelope pagination.Envelope
+	json.Unmarshal(rec.Body.Bytes(), &envelope)
+	if envelope.Links.Next != "/events?cursor="+p.NextCursor {
+		t.Errorf("next = %q", envelope.Links.Next)
+	}
+}
+
+func TestPagination_ClampsHugePages(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/users?page="+strconv.Itoa(math.MaxInt)+"&limit=50", nil)
+	p := pagination.FromRequest(req)
+	if p.Offset() < 0 || p.Offset() > math.MaxInt-p.Limit {
+		t.Fatalf("Expected the offset not to overflow, got page %d offset %d", p.Page, p.Offset())
+	}
+
+	rec := httptest.NewRecorder()
+	context.NewContext(req, rec).Paginated([]string{}, 10, p)
+	var envelope pagination.Envelope
+	if err := json.Unmarshal(rec.Body.Bytes(), &envelope); err != nil || envelope.Links.Prev != "/users?limit=50&page=1" {
+		t.Errorf("Expected the previous link to point at the last page, got %+v %v", envelope.Links, err)
+	}
+}
@@ -0,0 +1,100 @@
+package context_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/context"
+)
+
+// order stands in for a generated protobuf message.
+type order struct {
+	ID uint32
+}
+
+func (o *order) Marshal() ([]byte, error) {
+	return binary.BigEndian.AppendUint32(nil, o.ID), nil
+}
+
+func (o *order) Unmarshal(data []byte) error {
+	if len(data) != 4 {
+		return errors.New("invalid order")
+	}
+	o.ID = binary.BigEndian.Uint32(data)
+	return nil
+}
+
+func init() {
+	context.RegisterProtobufType("shop.v1.Order", &order{})
+}
+
+func protobufRequest(contentType string, body []byte) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/orders", bytes.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	return req
+}
+
+func TestProtobuf(t *testing.T) {
+	rec := httptest.NewRecorder()
+	context.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec).Protobuf(http.StatusOK, &order{ID: 7})
+
+	if ct := rec.Header().Get("Content-Type"); ct != "application/x-protobuf; messageType=shop.v1.Order" {
+		t.Errorf("Expected the message type in the content type, got %q", ct)
+	}
+	if !bytes.Equal(rec.Body.Bytes(), []byte{0, 0, 0, 7}) {
+		t.Errorf("Unexpected body %v", rec.Body.Bytes())
+	}
+}
+
+func TestBody_Protobuf(t *testing.T) {
+	var o order
+	req := protobufRequest("application/x-protobuf", []byte{0, 0, 1, 0})
+	if err := context.NewContext(req, httptest.NewRecorder()).Body(&o); err != nil || o.ID != 256 {
+		t.Errorf("Expected order 256, got %+v (%v)", o, err)
+	}
+
+	req = protobufRequest("application/x-protobuf; messageType=shop.v1.Refund", []byte{0, 0, 1, 0})
+	if err := context.NewContext(req, httptest.NewRecorder()).Body(&o); err == nil {
+		t.Error("Expected a message type mismatch to fail")
+	}
+
+	var m map[string]interface{}
+	req = protobufRequest("application/protobuf", []byte{0, 0, 1, 0})
+	if err := context.NewContext(req, httptest.NewRecorder()).Body(&m); !errors.Is(err, context.ErrNotProtobuf) {
+		t.Errorf("Expected ErrNotProtobuf, got %v", err)
+	}
+}
+
+func TestProtobufBody(t *testing.T) {
+	req := protobufRequest("application/x-protobuf; messageType=shop.v1.Order", []byte{0, 0, 0, 9})
+	message, err := context.NewContext(req, httptest.NewRecorder()).ProtobufBody()
+	if o, ok := message.(*order); err != nil || !ok || o.ID != 9 {
+		t.Errorf("Expected order 9, got %#v (%v)", message, err)
+	}
+
+	req = protobufRequest("application/x-protobuf; messageType=shop.v1.Unknown", nil)
+	if _, err := context.NewContext(req, httptest.NewRecorder()).ProtobufBody(); err == nil {
+		t.Error("Expected an unregistered message type to fail")
+	}
+}
+
+func TestNegotiate_Protobuf(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "application/x-protobuf, application/json;q=0.5")
+	rec := httptest.NewRecorder()
+	context.NewContext(req, rec).Negotiate(http.StatusOK, &order{ID: 1})
+	if ct := rec.Header().Get("Content-Type"); ct != "application/x-protobuf; messageType=shop.v1.Order" {
+		t.Errorf("Expected protobuf for a message, got %q", ct)
+	}
+
+	// Values that are not messages fall back to the other content types
+	rec = httptest.NewRecorder()
+	context.NewContext(req, rec).Negotiate(http.StatusOK, map[string]int{"id": 1})
+	if ct := rec.Header().Get("Content-Type"); ct != context.ContentTypeJSON {
+		t.Errorf("Expected JSON for a map, got %q", ct)
+	}
+}
@@ -0,0 +1,86 @@
+package context_test
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/context"
+	"github.com/vmihailenco/msgpack/v5"
+	"gopkg.in/yaml.v3"
+)
+
+type invoice struct {
+	XMLName xml.Name `json:"-" xml:"invoice" yaml:"-"`
+	ID      int      `json:"id" xml:"id,attr" yaml:"id"`
+	Items   []string `json:"items" xml:"item" yaml:"items"`
+}
+
+func render(fn func(ctx *context.Context)) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	fn(context.NewContext(httptest.NewRequest(http.MethodGet, "/invoices/42", nil), w))
+	return w
+}
+
+func TestContext_XML(t *testing.T) {
+	w := render(func(ctx *context.Context) {
+		ctx.XML(http.StatusCreated, invoice{ID: 42, Items: []string{"book", "pen"}})
+	})
+	if w.Code != http.StatusCreated || w.Header().Get("Content-Type") != context.ContentTypeXML {
+		t.Errorf("Expected 201 with XML, got %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	want := xml.Header + `<invoice id="42"><item>book</item><item>pen</item></invoice>`
+	if w.Body.String() != want {
+		t.Errorf("Expected %q, got %q", want, w.Body.String())
+	}
+
+	// Maps cannot be encoded as XML, nothing of the body is sent
+	w = render(func(ctx *context.Context) {
+		ctx.XML(http.StatusOK, map[string]int{"id": 42})
+	})
+	if w.Code != http.StatusInternalServerError || strings.Contains(w.Body.String(), "<?xml") {
+		t.Errorf("Expected a 500 without a partial body, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestContext_YAML(t *testing.T) {
+	w := render(func(ctx *context.Context) {
+		ctx.YAML(http.StatusOK, invoice{ID: 42, Items: []string{"book"}})
+	})
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != context.ContentTypeYAML {
+		t.Errorf("Expected 200 with YAML, got %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	var decoded invoice
+	if err := yaml.Unmarshal(w.Body.Bytes(), &decoded); err != nil || decoded.ID != 42 || len(decoded.Items) != 1 {
+		t.Errorf("Expected the invoice back, got %+v %v from %q", decoded, err, w.Body.String())
+	}
+	if w.Body.String() != "id: 42\nitems:\n    - book\n" {
+		t.Errorf("Unexpected YAML %q", w.Body.String())
+	}
+}
+
+func TestContext_MsgPack(t *testing.T) {
+	// Twice, so the second render uses a pooled encoder
+	for i := 0; i < 2; i++ {
+		w := render(func(ctx *context.Context) {
+			ctx.MsgPack(http.StatusOK, invoice{ID: 42, Items: []string{"book"}})
+		})
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != context.ContentTypeMsgPack {
+			t.Errorf("Expected 200 with MessagePack, got %d %s", w.Code, w.Header().Get("Content-Type"))
+		}
+		// Fields are named after their json tags
+		var decoded map[string]interface{}
+		if err := msgpack.Unmarshal(w.Body.Bytes(), &decoded); err != nil || decoded["id"] != int8(42) {
+			t.Errorf("Expected the invoice with json field names, got %v %v", decoded, err)
+		}
+	}
+
+	w := render(func(ctx *context.Context) {
+		ctx.MsgPack(http.StatusOK, map[string]interface{}{"updates": make(chan int)})
+	})
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected a 500 for a value MessagePack cannot encode, got %d", w.Code)
+	}
+}
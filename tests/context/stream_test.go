@@ -0,0 +1,62 @@
+package context_test
+
+import (
+	stdcontext "context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/context"
+)
+
+func TestContext_SSEvent(t *testing.T) {
+	w := httptest.NewRecorder()
+	ctx := context.NewContext(httptest.NewRequest(http.MethodGet, "/events", nil), w)
+	if err := ctx.SSEvent("progress", map[string]int{"done": 1}); err != nil {
+		t.Fatal(err)
+	}
+	ctx.SSEvent("", "line 1\nline 2")
+
+	if w.Header().Get("Content-Type") != "text/event-stream" || !w.Flushed {
+		t.Errorf("Expected a flushed event stream, got %v", w.Header())
+	}
+	want := "event: progress\ndata: {\"done\":1}\n\ndata: line 1\ndata: line 2\n\n"
+	if w.Body.String() != want {
+		t.Errorf("Expected %q, got %q", want, w.Body.String())
+	}
+}
+
+func TestContext_StreamStopsWhenAborted(t *testing.T) {
+	cancelCtx, cancel := stdcontext.WithCancel(stdcontext.Background())
+	w := httptest.NewRecorder()
+	ctx := context.NewContext(httptest.NewRequest(http.MethodGet, "/export", nil).WithContext(cancelCtx), w)
+
+	steps := 0
+	completed := ctx.Stream(func(w io.Writer) bool {
+		steps++
+		fmt.Fprintf(w, "%d\n", steps)
+		if steps == 3 {
+			// The client goes away
+			cancel()
+		}
+		return true
+	})
+	if completed || steps != 3 || !ctx.IsAborted() {
+		t.Errorf("Expected the stream to stop after the client left, got %v after %d steps", completed, steps)
+	}
+	select {
+	case <-ctx.Done():
+	default:
+		t.Error("Expected Done to be closed")
+	}
+	if err := ctx.SSEvent("late", "x"); err != stdcontext.Canceled {
+		t.Errorf("Expected the events of an aborted request to fail, got %v", err)
+	}
+
+	ctx = context.NewContext(httptest.NewRequest(http.MethodGet, "/export", nil), httptest.NewRecorder())
+	if ctx.IsAborted() || !ctx.Stream(func(w io.Writer) bool { return false }) {
+		t.Error("Expected a stream of a connected client to complete")
+	}
+}
@@ -0,0 +1,64 @@
+package context_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/context"
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+)
+
+func TestContext_SetGet(t *testing.T) {
+	ctx := context.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+	if _, ok := ctx.Get("tenant"); ok {
+		t.Fatal("Expected no value before Set")
+	}
+
+	ctx.Set("tenant", "acme")
+	ctx.Set("limit", 10)
+	if ctx.GetString("tenant") != "acme" {
+		t.Errorf("Expected acme, got %q", ctx.GetString("tenant"))
+	}
+	if limit, ok := context.GetAs[int](ctx, "limit"); !ok || limit != 10 {
+		t.Errorf("Expected 10, got %v", limit)
+	}
+	if _, ok := context.GetAs[string](ctx, "limit"); ok {
+		t.Error("Expected GetAs to fail for another type")
+	}
+	if value, ok := middleware.GetValue(ctx.Req, "tenant"); !ok || value != "acme" {
+		t.Errorf("Expected the value in the request store, got %v", value)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected MustGet to panic for a missing key")
+		}
+	}()
+	ctx.MustGet("missing")
+}
+
+func TestContext_ValuesFromMiddleware(t *testing.T) {
+	var claims context.Claims
+	var user interface{}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.NewContext(r, w)
+		claims, _ = ctx.Claims()
+		user, _ = ctx.User()
+	})
+	mw := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r = middleware.SetValue(r, middleware.ClaimsKey, map[string]interface{}{"sub": "42"})
+			r = r.WithContext(middleware.WithPrincipal(r.Context(), &middleware.Principal{User: "ann"}))
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	mw(handler).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if claims.String("sub") != "42" {
+		t.Errorf("Expected sub claim 42, got %v", claims)
+	}
+	if principal, ok := user.(*middleware.Principal); !ok || principal.User != "ann" {
+		t.Errorf("Expected the principal as user, got %v", user)
+	}
+}
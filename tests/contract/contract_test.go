@@ -0,0 +1,213 @@
+package contract_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/context"
+	"github.com/hokamsingh/lessgo/internal/core/contract"
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+	"github.com/hokamsingh/lessgo/internal/core/router"
+)
+
+func TestInferAndMerge(t *testing.T) {
+	s := contract.Infer([]byte(`{"id": 1, "price": 2.5, "tags": ["a"], "note": null}`))
+	if s.Type != contract.TypeObject || s.Properties["id"].Type != contract.TypeInteger ||
+		s.Properties["price"].Type != contract.TypeNumber || s.Properties["tags"].Items.Type != contract.TypeString {
+		t.Fatalf("Unexpected schema %+v", s)
+	}
+	if contract.Infer([]byte("not json")) != nil {
+		t.Error("Expected no schema for invalid JSON")
+	}
+
+	merged := contract.Merge(s, contract.Infer([]byte(`{"id": 1.5, "tags": [], "note": "x"}`)))
+	if merged.Properties["id"].Type != contract.TypeNumber {
+		t.Errorf("Expected integers to widen to numbers, got %q", merged.Properties["id"].Type)
+	}
+	if note := merged.Properties["note"]; note.Type != contract.TypeString || !note.Nullable {
+		t.Errorf("Expected a nullable string, got %+v", note)
+	}
+	if strings.Join(merged.Required, ",") != "id,note,tags" {
+		t.Errorf("Expected price to be optional, got %v", merged.Required)
+	}
+	if mixed := contract.Merge(&contract.Schema{Type: contract.TypeString}, &contract.Schema{Type: contract.TypeBoolean}); mixed.Type != "" {
+		t.Errorf("Expected any type, got %q", mixed.Type)
+	}
+}
+
+func recordedApp(options ...router.Option) (*router.Router, *contract.Recorder) {
+	recorder := contract.NewRecorder(*contract.NewRecorderOptions())
+	r := router.NewRouter(options...)
+	r.Use(recorder)
+	r.Get("/users/{id:[0-9]+}", func(ctx *context.Context) {
+		if id, _ := ctx.GetParam("id"); id == "0" {
+			ctx.Error(http.StatusNotFound, "Not Found")
+			return
+		}
+		ctx.JSON(http.StatusOK, map[string]interface{}{"id": 1, "name": "Ada", "token": "abc"})
+	})
+	r.Post("/users", func(ctx *context.Context) {
+		var body map[string]interface{}
+		ctx.Body(&body)
+		ctx.JSON(http.StatusCreated, map[string]interface{}{"id": 2, "name": body["name"]})
+	})
+	return r, recorder
+}
+
+func send(h http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.RemoteAddr = "127.0.0.1:1234"
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestRecorder(t *testing.T) {
+	r, recorder := recordedApp()
+	app := r.Handler()
+	send(app, http.MethodGet, "/users/1?fields=name", "")
+	send(app, http.MethodGet, "/users/0", "")
+	if w := send(app, http.MethodPost, "/users", `{"name":"Bob","password":"hunter2"}`); w.Code != http.StatusCreated || !strings.Contains(w.Body.String(), "Bob") {
+		t.Fatalf("Expected the handler to read the body, got %d %s", w.Code, w.Body.String())
+	}
+	send(app, http.MethodGet, "/missing", "")
+
+	recording := recorder.Recording()
+	if len(recording.Routes) != 2 {
+		t.Fatalf("Expected 2 routes, got %+v", recording.Routes)
+	}
+	get := recording.Route(http.MethodGet, "/users/{id:[0-9]+}")
+	if get == nil || len(get.Responses) != 2 || get.Responses["200"].Schema.Properties["name"].Type != contract.TypeString {
+		t.Fatalf("Unexpected route %+v", get)
+	}
+	if strings.Join(get.Query, ",") != "fields" || len(get.Examples) != 2 {
+		t.Errorf("Expected the query and an example per status, got %v %d", get.Query, len(get.Examples))
+	}
+	if body := string(get.Examples[0].Response.Body); strings.Contains(body, "abc") {
+		t.Errorf("Expected the token to be redacted, got %s", body)
+	}
+	post := recording.Route(http.MethodPost, "/users")
+	if post.Request == nil || post.Request.Properties["name"] == nil {
+		t.Fatalf("Expected the request schema, got %+v", post.Request)
+	}
+	if body := string(post.Examples[0].Request.Body); strings.Contains(body, "hunter2") {
+		t.Errorf("Expected the password to be redacted, got %s", body)
+	}
+
+	doc, _ := json.Marshal(recording.OpenAPI("Users", "1.0.0"))
+	for _, want := range []string{`"/users/{id}"`, `"in":"path"`, `"in":"query"`, `"requestBody"`, `"201"`, `"example"`} {
+		if !strings.Contains(string(doc), want) {
+			t.Errorf("Expected %s in the OpenAPI document %s", want, doc)
+		}
+	}
+	if c := recording.Contract("web"); c.Consumer != "web" || len(c.Interactions) != 3 {
+		t.Errorf("Expected 3 interactions, got %+v", c)
+	}
+
+	path := filepath.Join(t.TempDir(), "contracts", "recording.json")
+	if err := recording.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := contract.LoadRecording(path)
+	if err != nil || len(contract.Diff(recording, loaded)) != 0 {
+		t.Errorf("Expected the loaded recording to equal the recording, got %v %v", err, contract.Diff(recording, loaded))
+	}
+}
+
+func TestExposeContractRecorder(t *testing.T) {
+	r, recorder := recordedApp(router.WithAdminAuth(middleware.NewBearerAuth("admin-token")))
+	r.ExposeContractRecorder(recorder)
+	send(r.Handler(), http.MethodGet, "/users/1", "")
+	admin := r.Admin().Handler()
+
+	if w := send(admin, http.MethodGet, "/contracts?format=openapi&title=Users", ""); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"openapi": "3.0.3"`) {
+		t.Errorf("Expected the OpenAPI document, got %d %s", w.Code, w.Body.String())
+	}
+	if w := send(admin, http.MethodPost, "/contracts/reset", ""); w.Code != http.StatusUnauthorized || len(recorder.Recording().Routes) == 0 {
+		t.Errorf("Expected the reset to require the admin token, got %d", w.Code)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/contracts/reset", nil)
+	req.RemoteAddr = "127.0.0.1:1234"
+	req.Header.Set("Authorization", "Bearer admin-token")
+	admin.ServeHTTP(httptest.NewRecorder(), req)
+	if len(recorder.Recording().Routes) != 0 {
+		t.Error("Expected the recording to be reset")
+	}
+}
+
+func recording(routes map[string][]string) *contract.Recording {
+	recorder := contract.NewRecorder(*contract.NewRecorderOptions())
+	r := router.NewRouter()
+	r.Use(recorder)
+	for route, bodies := range routes {
+		method, path, _ := strings.Cut(route, " ")
+		bodies := bodies
+		handler := func(ctx *context.Context) {
+			ctx.Res.Header().Set("Content-Type", "application/json")
+			ctx.Res.Write([]byte(bodies[0]))
+			bodies = bodies[1:]
+		}
+		if method == http.MethodPost {
+			r.Post(path, handler)
+		} else {
+			r.Get(path, handler)
+		}
+	}
+	app := r.Handler()
+	for route, bodies := range routes {
+		method, path, _ := strings.Cut(route, " ")
+		for range bodies {
+			send(app, method, path, `{"name":"x"}`)
+		}
+	}
+	return recorder.Recording()
+}
+
+func TestDiff(t *testing.T) {
+	baseline := recording(map[string][]string{
+		"GET /users":  {`[{"id": 1, "name": "Ada", "email": "a@b.c"}]`},
+		"GET /orders": {`{"total": 1}`},
+	})
+	current := recording(map[string][]string{
+		"GET /users":    {`[{"id": "1", "name": null, "nickname": "A"}]`},
+		"GET /products": {`[]`},
+	})
+
+	changes := contract.Diff(baseline, current)
+	var messages []string
+	for _, change := range changes {
+		messages = append(messages, change.String())
+	}
+	got := strings.Join(messages, "\n")
+	for _, want := range []string{
+		"BREAKING GET /orders: route removed",
+		"change GET /products: route added",
+		"BREAKING GET /users: response 200[].email removed",
+		"BREAKING GET /users: response 200[].id changed from integer to string",
+		"BREAKING GET /users: response 200[].name may be null",
+		"change GET /users: response 200[].nickname added",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %q in\n%s", want, got)
+		}
+	}
+	if len(contract.Breaking(changes)) != 4 {
+		t.Errorf("Expected 4 breaking changes, got\n%s", got)
+	}
+
+	// New response fields do not break clients
+	compatible := recording(map[string][]string{
+		"GET /users":  {`[{"id": 1, "name": "Ada", "email": "a@b.c", "age": 3}]`},
+		"GET /orders": {`{"total": 1}`},
+	})
+	if breaking := contract.Breaking(contract.Diff(baseline, compatible)); len(breaking) != 0 {
+		t.Errorf("Expected no breaking changes, got %v", breaking)
+	}
+}
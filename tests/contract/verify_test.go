@@ -0,0 +1,118 @@
+package contract_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/context"
+	"github.com/hokamsingh/lessgo/internal/core/contract"
+	"github.com/hokamsingh/lessgo/internal/core/router"
+)
+
+type fakeT struct {
+	errors []string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestVerify(t *testing.T) {
+	// The consumer contract is recorded from the first version of the application
+	r, recorder := recordedApp()
+	app := r.Handler()
+	send(app, http.MethodGet, "/users/1", "")
+	send(app, http.MethodGet, "/users/0", "")
+	send(app, http.MethodPost, "/users", `{"name":"Bob"}`)
+	c := recorder.Recording().Contract("web")
+
+	if err := contract.Verify(app, c, *contract.NewVerifyOptions()).Err(); err != nil {
+		t.Fatalf("Expected the contract to hold, got %v", err)
+	}
+
+	changed := router.NewRouter()
+	changed.Get("/users/{id:[0-9]+}", func(ctx *context.Context) {
+		ctx.JSON(http.StatusOK, map[string]interface{}{"id": "1", "email": "a@b.c"})
+	})
+	verification := contract.Verify(changed.Handler(), c, *contract.NewVerifyOptions())
+	if verification.Passed() || len(verification.Failed()) != 3 {
+		t.Fatalf("Expected 3 failed interactions, got %+v", verification.Results)
+	}
+	err := verification.Err().Error()
+	for _, want := range []string{
+		"contract of web: 3 of 3 interactions failed",
+		"body.id is string, expected integer",
+		"body.name is missing",
+		"status 200, expected 404",
+		"status 404, expected 201",
+		"body is not JSON",
+	} {
+		if !strings.Contains(err, want) {
+			t.Errorf("Expected %q in\n%s", want, err)
+		}
+	}
+
+	ft := &fakeT{}
+	contract.AssertVerified(ft, changed.Handler(), c, *contract.NewVerifyOptions())
+	if len(ft.errors) != 3 {
+		t.Errorf("Expected 3 test errors, got %v", ft.errors)
+	}
+}
+
+func TestVerifyProviderStates(t *testing.T) {
+	users := map[string]bool{}
+	r := router.NewRouter()
+	r.Get("/users/{id}", func(ctx *context.Context) {
+		id, _ := ctx.GetParam("id")
+		if !users[id] {
+			ctx.Error(http.StatusNotFound, "Not Found")
+			return
+		}
+		ctx.JSON(http.StatusOK, map[string]string{"id": id})
+	})
+	c := &contract.Contract{Interactions: []contract.Interaction{{
+		ProviderState: "user 7 exists",
+		Request:       contract.InteractionRequest{Method: http.MethodGet, Path: "/users/7"},
+		Response: contract.InteractionResponse{
+			Status:  http.StatusOK,
+			Headers: map[string]string{"Content-Type": "application/json"},
+			Body:    []byte(`{"id": "7"}`),
+		},
+	}}}
+
+	options := contract.NewVerifyOptions()
+	if err := contract.Verify(r.Handler(), c, *options).Err(); err == nil || !strings.Contains(err.Error(), `unknown provider state "user 7 exists"`) {
+		t.Errorf("Expected an unknown provider state, got %v", err)
+	}
+	options.States["user 7 exists"] = func() error { return errors.New("database down") }
+	if err := contract.Verify(r.Handler(), c, *options).Err(); err == nil || !strings.Contains(err.Error(), "database down") {
+		t.Errorf("Expected the state error, got %v", err)
+	}
+	options.States["user 7 exists"] = func() error {
+		users["7"] = true
+		return nil
+	}
+	if err := contract.Verify(r.Handler(), c, *options).Err(); err != nil {
+		t.Errorf("Expected the contract to hold, got %v", err)
+	}
+
+	dir := t.TempDir()
+	if err := c.WriteFile(filepath.Join(dir, "mobile.json")); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a contract"), 0o644)
+	verifications, err := contract.VerifyFiles(r.Handler(), filepath.Join(dir, "*.json"), *options)
+	if err != nil || len(verifications) != 1 || verifications[0].Consumer != "mobile" || !verifications[0].Passed() {
+		t.Errorf("Expected the mobile contract to hold, got %v %+v", err, verifications)
+	}
+	if _, err := contract.VerifyFiles(r.Handler(), filepath.Join(dir, "*.yaml"), *options); err == nil {
+		t.Error("Expected an error without contracts")
+	}
+}
@@ -0,0 +1,71 @@
+package controller_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/context"
+	"github.com/hokamsingh/lessgo/internal/core/controller"
+	"github.com/hokamsingh/lessgo/internal/core/module"
+	"github.com/hokamsingh/lessgo/internal/core/router"
+)
+
+type headerMiddleware struct{}
+
+func (headerMiddleware) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Admin", "1")
+		next.ServeHTTP(w, r)
+	})
+}
+
+type AdminController struct {
+	controller.BaseController `use:"admin-header"`
+}
+
+func (ac *AdminController) Guards() []router.Guard {
+	return []router.Guard{func(ctx *context.Context) bool {
+		return ctx.GetHeader("X-Role") == "admin"
+	}}
+}
+
+func (ac *AdminController) RegisterRoutes(r *router.Router) {
+	r.Get("/admin", func(ctx *context.Context) { ctx.Send("admin") })
+}
+
+type PublicController struct {
+	controller.BaseController
+}
+
+func (pc *PublicController) RegisterRoutes(r *router.Router) {
+	r.Get("/public", func(ctx *context.Context) { ctx.Send("public") })
+}
+
+func TestControllerGuardsAndMiddleware(t *testing.T) {
+	controller.RegisterMiddleware("admin-header", headerMiddleware{})
+
+	r := router.NewRouter()
+	m := module.NewModule("admin", []interface{}{&AdminController{}, &PublicController{}}, nil, nil)
+	controller.RegisterModuleRoutes(r, m)
+
+	rec := httptest.NewRecorder()
+	r.Mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 without role, got %d", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+	req.Header.Set("X-Role", "admin")
+	rec = httptest.NewRecorder()
+	r.Mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("X-Admin") != "1" {
+		t.Errorf("expected admin response with middleware header, got %d %v", rec.Code, rec.Header())
+	}
+
+	rec = httptest.NewRecorder()
+	r.Mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/public", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("X-Admin") != "" {
+		t.Errorf("controller middleware leaked to other controllers: %d %v", rec.Code, rec.Header())
+	}
+}
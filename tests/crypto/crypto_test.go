@@ -0,0 +1,99 @@
+package crypto_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/crypto"
+)
+
+func TestHKDF(t *testing.T) {
+	// RFC 5869, test case 1
+	secret := bytes.Repeat([]byte{0x0b}, 22)
+	salt, _ := hex.DecodeString("000102030405060708090a0b0c")
+	info, _ := hex.DecodeString("f0f1f2f3f4f5f6f7f8f9")
+	expected := "3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf34007208d5b887185865"
+	if got := hex.EncodeToString(crypto.HKDF(secret, salt, info, 42)); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+	if bytes.Equal(crypto.DeriveKey(secret, "sign"), crypto.DeriveKey(secret, "encrypt")) {
+		t.Error("Expected keys derived for different purposes to differ")
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	key, err := crypto.RandomBytes(32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := crypto.Encrypt(key, []byte("card 4242"), []byte("user:1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := crypto.Decrypt(key, sealed, []byte("user:1"))
+	if err != nil || string(plaintext) != "card 4242" {
+		t.Fatalf("Expected the plaintext, got %q %v", plaintext, err)
+	}
+	if _, err := crypto.Decrypt(key, sealed, []byte("user:2")); !errors.Is(err, crypto.ErrDecrypt) {
+		t.Errorf("Expected other additional data to be refused, got %v", err)
+	}
+	sealed[len(sealed)-1] ^= 1
+	if _, err := crypto.Decrypt(key, sealed, []byte("user:1")); !errors.Is(err, crypto.ErrDecrypt) {
+		t.Errorf("Expected a tampered value to be refused, got %v", err)
+	}
+	if _, err := crypto.Encrypt([]byte("short"), nil, nil); !errors.Is(err, crypto.ErrInvalidKey) {
+		t.Errorf("Expected an invalid key error, got %v", err)
+	}
+}
+
+func TestKeyring_Rotation(t *testing.T) {
+	keyring := crypto.NewKeyring(crypto.Key{ID: "2024", Secret: []byte("old secret")})
+	sealed, err := keyring.Encrypt([]byte("hello"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature := keyring.Sign([]byte("data"))
+
+	keyring.Rotate(crypto.Key{ID: "2025", Secret: []byte("new secret")})
+	if key, _ := keyring.Primary(); key.ID != "2025" || keyring.Len() != 2 {
+		t.Fatalf("Expected the new key to be primary, got %q with %d keys", key.ID, keyring.Len())
+	}
+	if plaintext, err := keyring.Decrypt(sealed, nil); err != nil || string(plaintext) != "hello" {
+		t.Errorf("Expected values of the old key to decrypt, got %q %v", plaintext, err)
+	}
+	if !keyring.Verify([]byte("data"), signature) {
+		t.Error("Expected signatures of the old key to verify")
+	}
+	if keyring.Verify([]byte("other"), signature) {
+		t.Error("Expected a signature of other data to be refused")
+	}
+
+	keyring.Remove("2024")
+	if _, err := keyring.Decrypt(sealed, nil); !errors.Is(err, crypto.ErrDecrypt) {
+		t.Errorf("Expected values of a removed key to be refused, got %v", err)
+	}
+	if keyring.Verify([]byte("data"), signature) {
+		t.Error("Expected signatures of a removed key to be refused")
+	}
+
+	keyring.Remove("2025")
+	if _, err := keyring.Encrypt([]byte("hello"), nil); !errors.Is(err, crypto.ErrNoKeys) {
+		t.Errorf("Expected an empty keyring to refuse encryption, got %v", err)
+	}
+}
+
+func TestKeyringFromSecrets(t *testing.T) {
+	a := crypto.KeyringFromSecrets([]byte("one"), []byte("two"))
+	b := crypto.KeyringFromSecrets([]byte("two"))
+	if !b.Verify([]byte("data"), crypto.KeyringFromSecrets([]byte("two")).Sign([]byte("data"))) {
+		t.Error("Expected key IDs to be stable across keyrings")
+	}
+	if !a.Verify([]byte("data"), b.Sign([]byte("data"))) {
+		t.Error("Expected the older secret to be accepted")
+	}
+	if b.Verify([]byte("data"), a.Sign([]byte("data"))) {
+		t.Error("Expected a signature of an unknown secret to be refused")
+	}
+}
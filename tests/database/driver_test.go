@@ -0,0 +1,128 @@
+package database_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/database"
+)
+
+// recorder is a database/sql driver recording the statements it runs and answering queries
+// with the rows returned by respond.
+type recorder struct {
+	mu         sync.Mutex
+	statements []statement
+	respond    func(query string, args []driver.Value) ([]string, [][]driver.Value)
+	commits    int
+	rollbacks  int
+}
+
+type statement struct {
+	query string
+	args  []driver.Value
+}
+
+var recorders sync.Map
+
+func init() {
+	sql.Register("recorder", recorderDriver{})
+}
+
+type recorderDriver struct{}
+
+func (recorderDriver) Open(name string) (driver.Conn, error) {
+	r, _ := recorders.Load(name)
+	return &recorderConn{r: r.(*recorder)}, nil
+}
+
+type recorderConn struct {
+	r *recorder
+}
+
+func (c *recorderConn) Prepare(query string) (driver.Stmt, error) {
+	return &recorderStmt{r: c.r, query: query}, nil
+}
+func (c *recorderConn) Close() error              { return nil }
+func (c *recorderConn) Begin() (driver.Tx, error) { return c, nil }
+func (c *recorderConn) Commit() error {
+	c.r.mu.Lock()
+	defer c.r.mu.Unlock()
+	c.r.commits++
+	return nil
+}
+func (c *recorderConn) Rollback() error {
+	c.r.mu.Lock()
+	defer c.r.mu.Unlock()
+	c.r.rollbacks++
+	return nil
+}
+
+type recorderStmt struct {
+	r     *recorder
+	query string
+}
+
+func (s *recorderStmt) Close() error  { return nil }
+func (s *recorderStmt) NumInput() int { return -1 }
+
+func (s *recorderStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.r.mu.Lock()
+	defer s.r.mu.Unlock()
+	s.r.statements = append(s.r.statements, statement{s.query, args})
+	return driver.RowsAffected(1), nil
+}
+
+func (s *recorderStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.r.mu.Lock()
+	s.r.statements = append(s.r.statements, statement{s.query, args})
+	respond := s.r.respond
+	s.r.mu.Unlock()
+	var columns []string
+	var values [][]driver.Value
+	if respond != nil {
+		columns, values = respond(s.query, args)
+	}
+	return &recorderRows{columns: columns, values: values}, nil
+}
+
+type recorderRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *recorderRows) Columns() []string { return r.columns }
+func (r *recorderRows) Close() error      { return nil }
+func (r *recorderRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+// openRecorder opens a database recording its statements, with the placeholders of driverName.
+func openRecorder(t *testing.T, driverName string) (*database.DB, *recorder) {
+	t.Helper()
+	r := &recorder{}
+	recorders.Store(t.Name(), r)
+	db, err := sql.Open("recorder", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return database.New(db, driverName), r
+}
+
+// last returns the last statement run.
+func (r *recorder) last() statement {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.statements) == 0 {
+		return statement{}
+	}
+	return r.statements[len(r.statements)-1]
+}
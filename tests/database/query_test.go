@@ -0,0 +1,165 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/database"
+)
+
+type user struct {
+	ID    int64  `db:"id,omitempty"`
+	Email string `db:"email"`
+	Name  string `db:"name"`
+	Notes string // Not a column
+}
+
+func TestStatements(t *testing.T) {
+	tests := []struct {
+		name    string
+		stmt    database.Statement
+		dialect database.Dialect
+		query   string
+		args    []interface{}
+	}{
+		{
+			"select", database.Select("id", "email").From("users").Where("team_id = ?", 7).Where("active = ? OR role = ?", true, "admin").
+				OrderBy("name", "id desc").Limit(20).Offset(40),
+			database.Dollar,
+			"SELECT id, email FROM users WHERE (team_id = $1) AND (active = $2 OR role = $3) ORDER BY name, id DESC LIMIT 20 OFFSET 40",
+			[]interface{}{7, true, "admin"},
+		},
+		{
+			"select_in", database.Select().From("users").WhereIn("id", 1, 2, 3),
+			database.AtP,
+			"SELECT * FROM users WHERE (id IN (@p1, @p2, @p3))",
+			[]interface{}{1, 2, 3},
+		},
+		{
+			"select_in_empty", database.Select().From("users").WhereIn("id"),
+			database.Question,
+			"SELECT * FROM users WHERE (1 = 0)",
+			nil,
+		},
+		{
+			"insert_struct", database.Insert("users").Values(user{Email: "ann@example.com", Name: "Ann"}).Returning("id"),
+			database.Dollar,
+			"INSERT INTO users (email, name) VALUES ($1, $2) RETURNING id",
+			[]interface{}{"ann@example.com", "Ann"},
+		},
+		{
+			"update", database.Update("users").Set("name", "Bob").Where("id = ?", 1),
+			database.Colon,
+			"UPDATE users SET name = :1 WHERE (id = :2)",
+			[]interface{}{"Bob", 1},
+		},
+		{
+			"delete", database.Delete("users").Where("id = ?", 1),
+			database.Question,
+			"DELETE FROM users WHERE (id = ?)",
+			[]interface{}{1},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			query, args, err := tc.stmt.SQL(tc.dialect)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if query != tc.query || !reflect.DeepEqual(args, tc.args) {
+				t.Errorf("Expected %q %v, got %q %v", tc.query, tc.args, query, args)
+			}
+		})
+	}
+}
+
+func TestStatementErrors(t *testing.T) {
+	for name, stmt := range map[string]database.Statement{
+		"table":        database.Select().From("users; DROP TABLE users"),
+		"column":       database.Select("id", "(SELECT password FROM admins)").From("users"),
+		"order":        database.Select().From("users").OrderBy("name; --"),
+		"in":           database.Select().From("users").WhereIn("id) OR (1=1", 1),
+		"placeholders": database.Select().From("users").Where("id = ? AND team_id = ?", 1),
+		"set":          database.Update("users").Set("name = 'x', role", "admin").Where("id = ?", 1),
+	} {
+		if _, _, err := stmt.SQL(database.Question); err == nil {
+			t.Errorf("Expected %s to be rejected", name)
+		}
+	}
+	if _, _, err := database.Delete("users").SQL(database.Question); !errors.Is(err, database.ErrNoConditions) {
+		t.Errorf("Expected deletes without conditions to be refused, got %v", err)
+	}
+	if query, _, err := database.Delete("sessions").All().SQL(database.Question); err != nil || query != "DELETE FROM sessions" {
+		t.Errorf("Expected All to allow deleting every row, got %q %v", query, err)
+	}
+}
+
+func TestDialects(t *testing.T) {
+	if database.DialectOf("pgx") != database.Dollar || database.DialectOf("mysql") != database.Question {
+		t.Error("Unexpected dialects of drivers")
+	}
+	got := database.Dollar.Rebind("SELECT '?' FROM t WHERE a = ? AND b = ?")
+	if got != "SELECT '?' FROM t WHERE a = $1 AND b = $2" {
+		t.Errorf("Unexpected rebinding %q", got)
+	}
+}
+
+func TestQueries(t *testing.T) {
+	db, rec := openRecorder(t, "postgres")
+	rec.respond = func(query string, args []driver.Value) ([]string, [][]driver.Value) {
+		if strings.HasPrefix(query, "SELECT COUNT(*)") {
+			return []string{"count"}, [][]driver.Value{{int64(2)}}
+		}
+		return []string{"id", "email", "name"}, [][]driver.Value{{int64(1), "ann@example.com", "Ann"}, {int64(2), "bob@example.com", "Bob"}}
+	}
+	ctx := context.Background()
+
+	users, err := database.All[user](ctx, db, database.Select().From("users").Where("team_id = ?", 7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 || users[1].Email != "bob@example.com" || users[1].ID != 2 {
+		t.Errorf("Unexpected users %+v", users)
+	}
+	if last := rec.last(); last.query != "SELECT id, email, name FROM users WHERE (team_id = $1)" {
+		t.Errorf("Expected the columns of the type to be selected, got %q", last.query)
+	}
+
+	first, err := database.One[user](ctx, db, database.Select().From("users").OrderBy("id"))
+	if err != nil || first.Name != "Ann" || !strings.HasSuffix(rec.last().query, "LIMIT 1") {
+		t.Errorf("Expected the first user, got %+v %v %q", first, err, rec.last().query)
+	}
+
+	total, err := database.Count(ctx, db, database.Select().From("users").Where("team_id = ?", 7).Limit(1))
+	if err != nil || total != 2 || rec.last().query != "SELECT COUNT(*) FROM users WHERE (team_id = $1)" {
+		t.Errorf("Expected the rows to be counted, got %d %v %q", total, err, rec.last().query)
+	}
+
+	rec.respond = nil
+	if _, err := database.One[user](ctx, db, database.Select().From("users")); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows, got %v", err)
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	db, rec := openRecorder(t, "mysql")
+	ctx := context.Background()
+	err := db.Transaction(ctx, func(tx *database.Tx) error {
+		_, err := database.Exec(ctx, tx, database.Insert("users").Set("email", "ann@example.com"))
+		return err
+	})
+	if err != nil || rec.commits != 1 || rec.last().query != "INSERT INTO users (email) VALUES (?)" {
+		t.Errorf("Expected the insert to be committed, got %v %+v", err, rec.last())
+	}
+
+	failure := errors.New("failed")
+	err = db.Transaction(ctx, func(tx *database.Tx) error { return failure })
+	if !errors.Is(err, failure) || rec.rollbacks != 1 {
+		t.Errorf("Expected the transaction to be rolled back, got %v", err)
+	}
+}
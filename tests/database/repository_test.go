@@ -0,0 +1,105 @@
+package database_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/database"
+)
+
+type article struct {
+	ID    int64  `db:"id,omitempty"`
+	Title string `db:"title"`
+	database.Timestamps
+	database.SoftDelete
+}
+
+type tag struct {
+	ID   int64  `db:"id,omitempty"`
+	Name string `db:"name"`
+}
+
+func TestRepositoryConventions(t *testing.T) {
+	db, rec := openRecorder(t, "postgres")
+	rec.respond = func(query string, args []driver.Value) ([]string, [][]driver.Value) {
+		return []string{"id"}, [][]driver.Value{{int64(7)}}
+	}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	articles := database.NewRepository[article](db, "articles")
+	articles.Now = func() time.Time { return now }
+	ctx := context.Background()
+
+	a := article{Title: "Hello"}
+	id, err := articles.Insert(ctx, &a)
+	if err != nil || id != 7 {
+		t.Fatalf("Expected the generated ID, got %d %v", id, err)
+	}
+	if !a.CreatedAt.Equal(now) || !a.UpdatedAt.Equal(now) {
+		t.Errorf("Expected the timestamps to be set, got %+v", a.Timestamps)
+	}
+	if last := rec.last(); last.query != "INSERT INTO articles (title, created_at, updated_at, deleted_at) VALUES ($1, $2, $3, $4) RETURNING id" {
+		t.Errorf("Unexpected insert %q", last.query)
+	}
+
+	a.ID, a.Title = 7, "Updated"
+	now = now.Add(time.Hour)
+	if err := articles.Update(ctx, &a); err != nil {
+		t.Fatal(err)
+	}
+	last := rec.last()
+	if last.query != "UPDATE articles SET title = $1, updated_at = $2 WHERE (id = $3) AND (deleted_at IS NULL)" || !last.args[1].(time.Time).Equal(now) {
+		t.Errorf("Expected the update time to be refreshed, got %q %v", last.query, last.args)
+	}
+
+	if _, err := articles.Find(ctx, 7); err != nil {
+		t.Fatal(err)
+	}
+	if last := rec.last(); last.query != "SELECT id, title, created_at, updated_at, deleted_at FROM articles WHERE (deleted_at IS NULL) AND (id = $1) LIMIT 1" {
+		t.Errorf("Expected deleted rows to be left out, got %q", last.query)
+	}
+
+	steps := []struct {
+		run   func() error
+		query string
+	}{
+		{func() error { return articles.Delete(ctx, 7) }, "UPDATE articles SET deleted_at = $1, updated_at = $2 WHERE (id = $3) AND (deleted_at IS NULL)"},
+		{func() error { return articles.Restore(ctx, 7) }, "UPDATE articles SET deleted_at = $1, updated_at = $2 WHERE (id = $3) AND (deleted_at IS NOT NULL)"},
+		{func() error { return articles.Purge(ctx, 7) }, "DELETE FROM articles WHERE (id = $1)"},
+		{func() error { _, err := articles.PurgeDeleted(ctx, now); return err }, "DELETE FROM articles WHERE (deleted_at IS NOT NULL) AND (deleted_at < $1)"},
+	}
+	for _, step := range steps {
+		if err := step.run(); err != nil {
+			t.Fatal(err)
+		}
+		if last := rec.last(); last.query != step.query {
+			t.Errorf("Expected %q, got %q", step.query, last.query)
+		}
+	}
+	if query, _, _ := articles.Trashed().SQL(database.Question); query != "SELECT * FROM articles WHERE (deleted_at IS NOT NULL)" {
+		t.Errorf("Unexpected trashed query %q", query)
+	}
+}
+
+func TestRepositoryWithoutConventions(t *testing.T) {
+	db, rec := openRecorder(t, "mysql")
+	tags := database.NewRepository[tag](db, "tags")
+	ctx := context.Background()
+
+	if query, _, _ := tags.Query().SQL(database.Question); query != "SELECT * FROM tags" {
+		t.Errorf("Expected no soft delete scope, got %q", query)
+	}
+	if err := tags.Delete(ctx, 3); err != nil {
+		t.Fatal(err)
+	}
+	if last := rec.last(); last.query != "DELETE FROM tags WHERE (id = ?)" {
+		t.Errorf("Expected rows without SoftDelete to be deleted, got %q", last.query)
+	}
+	if err := tags.Update(ctx, &tag{ID: 3, Name: "go"}); err != nil {
+		t.Fatal(err)
+	}
+	if last := rec.last(); last.query != "UPDATE tags SET name = ? WHERE (id = ?)" {
+		t.Errorf("Unexpected update %q", last.query)
+	}
+}
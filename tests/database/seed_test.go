@@ -0,0 +1,112 @@
+package database_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/database"
+)
+
+func TestSeeder(t *testing.T) {
+	db, rec := openRecorder(t, "postgres")
+	rec.respond = func(query string, args []driver.Value) ([]string, [][]driver.Value) {
+		return []string{"name"}, [][]driver.Value{{"roles"}}
+	}
+	var ran []string
+	seed := func(name string) database.SeedFunc {
+		return func(ctx context.Context, tx *database.Tx) error {
+			ran = append(ran, name)
+			_, err := database.Exec(ctx, tx, database.Insert("things").Set("name", name))
+			return err
+		}
+	}
+	seeder := database.NewSeeder(db).
+		Add("roles", seed("roles")).
+		Add("demo-users", seed("demo-users"), "development").
+		Add("plans", seed("plans")).
+		Add("load-test", seed("load-test"), "staging")
+
+	applied, err := seeder.Run(context.Background(), "development")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(applied, ",") != "demo-users,plans" || strings.Join(ran, ",") != "demo-users,plans" {
+		t.Errorf("Expected the pending development seeds in order, got %v %v", applied, ran)
+	}
+	if rec.commits != 2 {
+		t.Errorf("Expected a transaction per seed, got %d commits", rec.commits)
+	}
+	last := rec.last()
+	if last.query != "INSERT INTO lessgo_seeds (name, env, applied_at) VALUES ($1, $2, $3)" || last.args[0] != "plans" || last.args[1] != "development" {
+		t.Errorf("Expected the marker in the transaction of the seed, got %q %v", last.query, last.args)
+	}
+	if !strings.HasPrefix(rec.statements[0].query, "CREATE TABLE IF NOT EXISTS lessgo_seeds") {
+		t.Errorf("Expected the markers table to be created, got %q", rec.statements[0].query)
+	}
+}
+
+func TestSeederFailure(t *testing.T) {
+	db, rec := openRecorder(t, "mysql")
+	failed := errors.New("constraint violated")
+	seeder := database.NewSeeder(db).
+		Add("first", func(ctx context.Context, tx *database.Tx) error { return failed }).
+		Add("second", func(ctx context.Context, tx *database.Tx) error {
+			t.Error("Expected the seeds after a failure not to run")
+			return nil
+		})
+	applied, err := seeder.Run(context.Background(), "test")
+	if !errors.Is(err, failed) || len(applied) != 0 {
+		t.Fatalf("Expected the seed error, got %v %v", applied, err)
+	}
+	if rec.rollbacks != 1 || rec.commits != 0 {
+		t.Errorf("Expected the failed seed to be rolled back, got %d rollbacks", rec.rollbacks)
+	}
+
+	seeder = database.NewSeeder(db).Add("same", seedNothing).Add("same", seedNothing)
+	if _, err := seeder.Run(context.Background(), "test"); err == nil {
+		t.Error("Expected seeds with the same name to be refused")
+	}
+	if _, err := database.NewSeeder(db).WithTable("seeds; DROP TABLE users").Run(context.Background(), "test"); err == nil {
+		t.Error("Expected an invalid markers table to be refused")
+	}
+}
+
+func TestRunSeedCommand(t *testing.T) {
+	db, rec := openRecorder(t, "mysql")
+	rec.respond = func(query string, args []driver.Value) ([]string, [][]driver.Value) {
+		return []string{"name"}, [][]driver.Value{{"roles"}}
+	}
+	seeder := database.NewSeeder(db).Add("roles", seedNothing).Add("demo", seedNothing, "development").Add("load", seedNothing, "staging")
+	ctx := context.Background()
+
+	var out bytes.Buffer
+	if err := database.RunSeedCommand(ctx, seeder, []string{"-env", "development", "-list"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "applied  roles\npending  demo\n" {
+		t.Errorf("Unexpected list %q", out.String())
+	}
+
+	out.Reset()
+	if err := database.RunSeedCommand(ctx, seeder, []string{"-env=staging", "-reset", "roles"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "seeded load\n" {
+		t.Errorf("Unexpected output %q", out.String())
+	}
+	var reset bool
+	for _, s := range rec.statements {
+		reset = reset || s.query == "DELETE FROM lessgo_seeds WHERE (name IN (?))"
+	}
+	if !reset {
+		t.Error("Expected -reset to remove the marker of the seed")
+	}
+}
+
+func seedNothing(ctx context.Context, tx *database.Tx) error {
+	return nil
+}
@@ -0,0 +1,124 @@
+package dataexport_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/dataexport"
+	"github.com/hokamsingh/lessgo/internal/core/mailer"
+	"github.com/hokamsingh/lessgo/internal/core/notify"
+)
+
+type memoryStore struct {
+	archives map[string][]byte
+}
+
+func (s *memoryStore) SaveArchive(ctx context.Context, name string, data []byte) (string, error) {
+	s.archives[name] = data
+	return "/exports/" + name, nil
+}
+
+func TestExport(t *testing.T) {
+	store := &memoryStore{archives: map[string][]byte{}}
+	var notified *dataexport.Result
+	orchestrator := dataexport.NewOrchestrator(store, dataexport.WithNotifier(
+		dataexport.NotifierFunc(func(ctx context.Context, request dataexport.Request, result *dataexport.Result) error {
+			notified = result
+			return nil
+		}),
+	))
+
+	orchestrator.Register("profile", func(ctx context.Context, subjectID string) ([]dataexport.File, error) {
+		return []dataexport.File{{Name: "profile.json", Data: []byte(`{"id":"` + subjectID + `"}`)}}, nil
+	})
+	orchestrator.Register("orders", func(ctx context.Context, subjectID string) ([]dataexport.File, error) {
+		return nil, errors.New("orders database unavailable")
+	})
+
+	result, err := orchestrator.Export(context.Background(), dataexport.Request{ID: "abc", SubjectID: "42"})
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if result.Status != dataexport.StatusCompleted || result.Location != "/exports/abc.zip" {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if notified == nil || notified.ID != "abc" {
+		t.Error("expected notification")
+	}
+
+	archive := store.archives["abc.zip"]
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := map[string]bool{}
+	for _, f := range zr.File {
+		names[f.Name] = true
+	}
+	if !names["profile/profile.json"] || !names["manifest.json"] {
+		t.Errorf("unexpected archive contents %v", names)
+	}
+	if len(result.Modules) != 2 || result.Modules[0].Module != "orders" || result.Modules[0].Error == "" {
+		t.Errorf("expected orders failure to be recorded, got %+v", result.Modules)
+	}
+}
+
+func TestMailAndPushNotifiers(t *testing.T) {
+	sent := make(chan *mailer.Message, 1)
+	m := mailer.NewMailer(mailer.TransportFunc(func(ctx context.Context, msg *mailer.Message) error {
+		sent <- msg
+		return nil
+	}), "privacy@example.com")
+	defer m.Close()
+	notifications := notify.New("/notifications")
+	defer notifications.Close()
+
+	orchestrator := dataexport.NewOrchestrator(&memoryStore{archives: map[string][]byte{}}, dataexport.WithNotifier(
+		dataexport.MultiNotifier(dataexport.MailNotifier(m), dataexport.PushNotifier(notifications)),
+	))
+	if _, err := orchestrator.Export(context.Background(), dataexport.Request{ID: "abc", SubjectID: "42", Email: "ann@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case msg := <-sent:
+		if msg.To[0] != "ann@example.com" || msg.Subject != "Your data export is ready" || !strings.Contains(msg.Text, "/exports/abc.zip") {
+			t.Errorf("Expected the download link to be emailed, got %+v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected an email")
+	}
+	pending, err := notifications.Pending(context.Background(), "42")
+	if err != nil || len(pending) != 1 || !strings.Contains(string(pending[0].Payload), `"status":"completed"`) {
+		t.Errorf("Expected a notification of the subject, got %+v %v", pending, err)
+	}
+}
+
+func TestResultsExpire(t *testing.T) {
+	orchestrator := dataexport.NewOrchestrator(&memoryStore{archives: map[string][]byte{}}, dataexport.WithResultTTL(10*time.Millisecond))
+	orchestrator.Export(context.Background(), dataexport.Request{ID: "abc", SubjectID: "42"})
+	if _, ok := orchestrator.Result("abc"); !ok {
+		t.Fatal("Expected the result of a finished export")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := orchestrator.Result("abc"); ok {
+		t.Error("Expected the result to expire")
+	}
+}
+
+func TestExport_GeneratesUnguessableIDs(t *testing.T) {
+	orchestrator := dataexport.NewOrchestrator(&memoryStore{archives: map[string][]byte{}})
+	first, err := orchestrator.Export(context.Background(), dataexport.Request{SubjectID: "42"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _ := orchestrator.Export(context.Background(), dataexport.Request{SubjectID: "42"})
+	if len(first.ID) != 32 || first.ID == second.ID || strings.HasPrefix(first.ID, "42-") {
+		t.Errorf("Expected random export IDs, got %q and %q", first.ID, second.ID)
+	}
+}
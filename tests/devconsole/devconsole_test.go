@@ -0,0 +1,115 @@
+package devconsole_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/config"
+	"github.com/hokamsingh/lessgo/internal/core/devconsole"
+	"github.com/hokamsingh/lessgo/internal/core/di"
+)
+
+type Greeter struct {
+	Prefix string
+}
+
+func (g *Greeter) Greet(name string, times int) string {
+	return strings.Repeat(g.Prefix+" "+name+"!", times)
+}
+
+func (g *Greeter) Fail() string {
+	panic("boom")
+}
+
+func TestConsoleCallsContainerService(t *testing.T) {
+	container := di.NewContainer()
+	container.Register(func() *Greeter { return &Greeter{Prefix: "Hello"} })
+
+	cfg := config.Config{"ENV": "development", "DB_PASSWORD": "hunter2", "DB_HOST": "localhost"}
+	console := devconsole.NewConsole(cfg, devconsole.WithContainer(container))
+	console.ExposeService("greeter", (*Greeter)(nil))
+
+	out, err := console.Execute(`call greeter Greet "Ann" 2`)
+	if err != nil {
+		t.Fatalf("call: %v", err)
+	}
+	if !strings.Contains(out, "Hello Ann!Hello Ann!") {
+		t.Errorf("unexpected output %s", out)
+	}
+
+	out, _ = console.Execute("config DB_")
+	if strings.Contains(out, "hunter2") || !strings.Contains(out, "DB_HOST=localhost") {
+		t.Errorf("unexpected config output %s", out)
+	}
+}
+
+func TestConsoleDisabledOutsideDevelopment(t *testing.T) {
+	console := devconsole.NewConsole(config.Config{"ENV": "production"})
+	if _, err := console.Execute("services"); err != devconsole.ErrDisabled {
+		t.Errorf("expected ErrDisabled, got %v", err)
+	}
+}
+
+func TestConsoleRecoversFromPanickingMethods(t *testing.T) {
+	console := devconsole.NewConsole(config.Config{"ENV": "development"})
+	console.Expose("greeter", &Greeter{})
+	if _, err := console.Execute("call greeter Fail"); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("Expected the panic as an error, got %v", err)
+	}
+}
+
+func TestConsoleHandlerRejectsCrossOriginAndMissingToken(t *testing.T) {
+	console := devconsole.NewConsole(config.Config{"ENV": "development"}, devconsole.WithToken("s3cret"))
+	console.Expose("greeter", &Greeter{Prefix: "Hi"})
+	post := func(headers map[string]string) int {
+		req := httptest.NewRequest(http.MethodPost, "http://localhost:8080/_console", strings.NewReader(`call greeter Greet "Ann" 1`))
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		console.Handler().ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := post(map[string]string{devconsole.TokenHeader: "s3cret"}); code != http.StatusOK {
+		t.Errorf("Expected 200 with the token, got %d", code)
+	}
+	if code := post(nil); code != http.StatusForbidden {
+		t.Errorf("Expected 403 without the token, got %d", code)
+	}
+	if code := post(map[string]string{devconsole.TokenHeader: "s3cret", "Origin": "https://evil.example"}); code != http.StatusForbidden {
+		t.Errorf("Expected 403 for another origin, got %d", code)
+	}
+	if code := post(map[string]string{devconsole.TokenHeader: "s3cret", "Sec-Fetch-Site": "cross-site"}); code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a cross-site request, got %d", code)
+	}
+	if code := post(map[string]string{devconsole.TokenHeader: "s3cret", "Origin": "http://localhost:8080"}); code != http.StatusOK {
+		t.Errorf("Expected 200 for the same origin, got %d", code)
+	}
+}
+
+func TestConsoleSocketIsPrivate(t *testing.T) {
+	console := devconsole.NewConsole(config.Config{"ENV": "development"})
+	path := filepath.Join(t.TempDir(), "console.sock")
+	go console.ListenUnix(path)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		info, err := os.Stat(path)
+		if err == nil {
+			if mode := info.Mode().Perm(); mode&0077 != 0 {
+				t.Errorf("Expected a socket only accessible to the user, got %v", mode)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("The socket was not created")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,199 @@
+package enrich_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/enrich"
+	"github.com/hokamsingh/lessgo/internal/core/logexport"
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+)
+
+func TestParseUserAgent(t *testing.T) {
+	cases := []struct {
+		header string
+		want   enrich.UserAgent
+	}{
+		{
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			enrich.UserAgent{Browser: "Chrome", BrowserVersion: "120.0.0.0", OS: "Windows", OSVersion: "10", Device: enrich.DeviceDesktop},
+		},
+		{
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.2210.91",
+			enrich.UserAgent{Browser: "Edge", BrowserVersion: "120.0.2210.91", OS: "Windows", OSVersion: "10", Device: enrich.DeviceDesktop},
+		},
+		{
+			"Mozilla/5.0 (iPhone; CPU iPhone OS 17_1_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1.2 Mobile/15E148 Safari/604.1",
+			enrich.UserAgent{Browser: "Safari", BrowserVersion: "17.1.2", OS: "iOS", OSVersion: "17.1.2", Device: enrich.DeviceMobile},
+		},
+		{
+			"Mozilla/5.0 (iPad; CPU OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/119.0.6045.169 Mobile/15E148 Safari/604.1",
+			enrich.UserAgent{Browser: "Chrome", BrowserVersion: "119.0.6045.169", OS: "iOS", OSVersion: "16.6", Device: enrich.DeviceTablet},
+		},
+		{
+			"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.6099.43 Mobile Safari/537.36",
+			enrich.UserAgent{Browser: "Chrome", BrowserVersion: "120.0.6099.43", OS: "Android", OSVersion: "14", Device: enrich.DeviceMobile},
+		},
+		{
+			"Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/23.0 Chrome/115.0.0.0 Safari/537.36",
+			enrich.UserAgent{Browser: "Samsung Internet", BrowserVersion: "23.0", OS: "Android", OSVersion: "13", Device: enrich.DeviceTablet},
+		},
+		{
+			"Mozilla/5.0 (Macintosh; Intel Mac OS X 10.15; rv:121.0) Gecko/20100101 Firefox/121.0",
+			enrich.UserAgent{Browser: "Firefox", BrowserVersion: "121.0", OS: "macOS", OSVersion: "10.15", Device: enrich.DeviceDesktop},
+		},
+		{
+			"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			enrich.UserAgent{Browser: "Googlebot", BrowserVersion: "2.1", Device: enrich.DeviceBot, Bot: true},
+		},
+		{
+			"curl/8.4.0",
+			enrich.UserAgent{Browser: "curl", BrowserVersion: "8.4.0", Device: enrich.DeviceBot, Bot: true},
+		},
+		{"", enrich.UserAgent{}},
+	}
+	for _, c := range cases {
+		if got := enrich.ParseUserAgent(c.header); got != c.want {
+			t.Errorf("%q:\n got %+v\nwant %+v", c.header, got, c.want)
+		}
+	}
+}
+
+func TestGeoRanges(t *testing.T) {
+	ranges, err := enrich.LoadGeoCSV(strings.NewReader(`network,country,region,city,latitude,longitude
+# documentation networks
+203.0.113.0/24,DE,Berlin,Berlin,52.52,13.40
+198.51.100.0/25,FR
+2001:db8::/32,JP,Tokyo,Tokyo
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ranges.Add("192.0.2.10", enrich.GeoLocation{Country: "US"})
+	lookup := func(ip string) (enrich.GeoLocation, bool) {
+		location, ok, err := ranges.Lookup(context.Background(), net.ParseIP(ip))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return location, ok
+	}
+	if location, ok := lookup("203.0.113.200"); !ok || location.City != "Berlin" || location.Latitude != 52.52 {
+		t.Errorf("Unexpected location %+v %v", location, ok)
+	}
+	if location, ok := lookup("198.51.100.1"); !ok || location.Country != "FR" {
+		t.Errorf("Unexpected location %+v %v", location, ok)
+	}
+	if _, ok := lookup("198.51.100.200"); ok {
+		t.Error("Expected addresses outside of the networks to be unknown")
+	}
+	if location, ok := lookup("2001:db8:1::1"); !ok || location.Country != "JP" {
+		t.Errorf("Unexpected location %+v %v", location, ok)
+	}
+	if location, ok := lookup("192.0.2.10"); !ok || location.Country != "US" {
+		t.Errorf("Unexpected location %+v %v", location, ok)
+	}
+	if _, ok := lookup("192.0.2.11"); ok {
+		t.Error("Expected a single IP entry to match only that IP")
+	}
+	if _, err := enrich.LoadGeoCSV(strings.NewReader("not-a-network,DE\n")); err == nil {
+		t.Error("Expected invalid networks to be reported")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var lookups atomic.Int32
+	provider := enrich.GeoProviderFunc(func(ctx context.Context, ip net.IP) (enrich.GeoLocation, bool, error) {
+		lookups.Add(1)
+		if ip.String() == "192.0.2.99" {
+			return enrich.GeoLocation{}, false, errors.New("unavailable")
+		}
+		return enrich.GeoLocation{Country: "DE", City: "Berlin"}, true, nil
+	})
+	var gotUA enrich.UserAgent
+	var gotGeo enrich.GeoLocation
+	var gotOK bool
+	handler := enrich.New(enrich.WithGeoProvider(provider), enrich.WithCDNHeaders()).Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUA = enrich.RequestUserAgent(r)
+		gotGeo, gotOK = enrich.RequestGeo(r)
+	}))
+	serve := func(remoteAddr string, header http.Header) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header = header
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	serve("203.0.113.7:1234", http.Header{"User-Agent": {"curl/8.4.0"}})
+	serve("203.0.113.7:1234", http.Header{})
+	if !gotOK || gotGeo.City != "Berlin" || lookups.Load() != 1 {
+		t.Errorf("Expected a cached lookup, got %+v %v after %d lookups", gotGeo, gotOK, lookups.Load())
+	}
+	if gotUA.Device != "" {
+		t.Errorf("Expected the user agent of the request, got %+v", gotUA)
+	}
+
+	serve("127.0.0.1:1234", http.Header{"User-Agent": {"curl/8.4.0"}})
+	if gotOK || gotUA.Browser != "curl" || lookups.Load() != 1 {
+		t.Errorf("Expected loopback addresses not to be located, got %+v %v", gotGeo, gotOK)
+	}
+	serve("192.0.2.99:1234", http.Header{})
+	if gotOK {
+		t.Errorf("Expected failed lookups to leave the location unknown, got %+v", gotGeo)
+	}
+
+	// CDN headers count only from trusted proxies
+	serve("203.0.113.8:1234", http.Header{"Cf-Ipcountry": {"FR"}})
+	if gotGeo.Country != "DE" {
+		t.Errorf("Expected the header of an untrusted peer to be ignored, got %+v", gotGeo)
+	}
+	middleware.SetTrustedProxies("10.0.0.1")
+	defer middleware.SetTrustedProxies()
+	serve("10.0.0.1:1234", http.Header{"Cf-Ipcountry": {"fr"}, "X-Forwarded-For": {"203.0.113.9"}})
+	if gotGeo.Country != "FR" || gotGeo.City != "" {
+		t.Errorf("Expected the CDN country without the details of another country, got %+v", gotGeo)
+	}
+}
+
+type memoryStore struct {
+	body []byte
+}
+
+func (s *memoryStore) PutObject(ctx context.Context, key string, body []byte, contentType string) error {
+	if strings.HasSuffix(key, ".ndjson.gz") {
+		s.body = body
+	}
+	return nil
+}
+
+func TestAccessLogRecordsEnrichment(t *testing.T) {
+	store := &memoryStore{}
+	exporter := logexport.NewExporter(store)
+	provider := enrich.GeoProviderFunc(func(ctx context.Context, ip net.IP) (enrich.GeoLocation, bool, error) {
+		return enrich.GeoLocation{Country: "DE", City: "Berlin"}, true, nil
+	})
+	handler := exporter.Handle(enrich.New(enrich.WithGeoProvider(provider)).Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "203.0.113.7:1234"
+	req.Header.Set("User-Agent", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) Mobile/15E148")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if err := exporter.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(store.body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(gz)
+	if !strings.Contains(string(data), `"device":"mobile","country":"DE","city":"Berlin"`) {
+		t.Errorf("Expected the enrichment in the record, got %s", data)
+	}
+}
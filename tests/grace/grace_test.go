@@ -0,0 +1,32 @@
+package grace_test
+
+import (
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/grace"
+)
+
+func TestRunWithoutServers(t *testing.T) {
+	if err := grace.New().Run(); err == nil {
+		t.Error("Expected an error without servers")
+	}
+}
+
+func TestRunFailsOnUsedAddress(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	app := grace.New()
+	app.Serve(&http.Server{Addr: listener.Addr().String()})
+	if err := app.Run(); err == nil {
+		t.Error("Expected an error listening on an address in use")
+	}
+	if grace.Inherited() {
+		t.Error("Expected no inherited listeners in tests")
+	}
+}
@@ -0,0 +1,55 @@
+package grace_test
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/grace"
+)
+
+func TestListenUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.sock")
+	listener, err := grace.Listen("unix://"+path, grace.ListenOptions{SocketMode: 0660})
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0660 {
+		t.Errorf("Expected socket mode 0660, got %v", info.Mode().Perm())
+	}
+
+	// A socket in use must not be replaced
+	if _, err := grace.Listen("unix://"+path, grace.ListenOptions{}); err == nil {
+		t.Error("Expected an error for a socket in use")
+	}
+
+	// A stale socket file is replaced
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	listener.Close()
+	listener, err = grace.Listen("unix://"+path, grace.ListenOptions{})
+	if err != nil {
+		t.Fatalf("Expected stale socket to be replaced: %v", err)
+	}
+	listener.Close()
+}
+
+func TestListenSystemdWithoutActivation(t *testing.T) {
+	t.Setenv("LISTEN_PID", "")
+	t.Setenv("LISTEN_FDS", "")
+	if _, err := grace.Listen("systemd", grace.ListenOptions{}); err == nil {
+		t.Error("Expected an error without socket activation")
+	}
+}
+
+func TestListenTCP(t *testing.T) {
+	listener, err := grace.Listen("127.0.0.1:0", grace.ListenOptions{ReusePort: true})
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	listener.Close()
+}
@@ -0,0 +1,139 @@
+package grpcweb_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/grpcweb"
+)
+
+func frame(flag byte, message []byte) []byte {
+	data := []byte{flag, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(data[1:], uint32(len(message)))
+	return append(data, message...)
+}
+
+// echoServer answers like a gRPC server: the request message in upper case, or NOT_FOUND
+// for an empty message.
+func echoServer(t *testing.T) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") || r.Header.Get("Te") != "trailers" {
+			t.Errorf("Unexpected gRPC request %s %v", r.Proto, r.Header)
+		}
+		body, _ := io.ReadAll(r.Body)
+		message := body[5:]
+		w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+		w.Header().Set("X-Served-By", "echo")
+		w.Header().Add("Trailer", "Grpc-Status")
+		w.Header().Add("Trailer", "Grpc-Message")
+		w.WriteHeader(http.StatusOK)
+		if len(message) == 0 {
+			w.Header().Set("Grpc-Status", "5")
+			w.Header().Set("Grpc-Message", "no%20such%20greeting")
+			return
+		}
+		w.Write(frame(0, bytes.ToUpper(message)))
+		w.Header().Set("Grpc-Status", "0")
+		w.Header().Set(http.TrailerPrefix+"X-Greeting-Count", "1")
+	})
+}
+
+func serve(t *testing.T, translator *grpcweb.Translator, req *http.Request) *httptest.ResponseRecorder {
+	rest := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("rest"))
+	})
+	rec := httptest.NewRecorder()
+	translator.Handle(rest).ServeHTTP(rec, req)
+	return rec
+}
+
+func TestTranslator_GRPCWeb(t *testing.T) {
+	translator := grpcweb.New(echoServer(t))
+	req := httptest.NewRequest(http.MethodPost, "/helloworld.Greeter/SayHello", bytes.NewReader(frame(0, []byte("hello"))))
+	req.Header.Set("Content-Type", "application/grpc-web+proto")
+	rec := serve(t, translator, req)
+
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/grpc-web+proto" || rec.Header().Get("X-Served-By") != "echo" {
+		t.Fatalf("Unexpected response %d %v", rec.Code, rec.Header())
+	}
+	body := rec.Body.Bytes()
+	if want := frame(0, []byte("HELLO")); !bytes.HasPrefix(body, want) {
+		t.Fatalf("Expected the message frame first, got %q", body)
+	}
+	trailer := body[10:]
+	if trailer[0] != 0x80 || int(binary.BigEndian.Uint32(trailer[1:5])) != len(trailer)-5 {
+		t.Fatalf("Expected a trailer frame, got %q", trailer)
+	}
+	for _, line := range []string{"grpc-status: 0\r\n", "x-greeting-count: 1\r\n"} {
+		if !strings.Contains(string(trailer[5:]), line) {
+			t.Errorf("Expected trailer %q in %q", line, trailer[5:])
+		}
+	}
+}
+
+func TestTranslator_GRPCWebText(t *testing.T) {
+	translator := grpcweb.New(echoServer(t))
+	body := base64.StdEncoding.EncodeToString(frame(0, []byte("hi")))
+	req := httptest.NewRequest(http.MethodPost, "/helloworld.Greeter/SayHello", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/grpc-web-text")
+	rec := serve(t, translator, req)
+
+	// Frames are encoded one by one, each chunk padded on its own
+	message, err := base64.StdEncoding.DecodeString(rec.Body.String()[:12])
+	if err != nil || !bytes.Equal(message, frame(0, []byte("HI"))) {
+		t.Errorf("Expected the base64 message frame, got %q (%v)", rec.Body.String(), err)
+	}
+}
+
+func TestTranslator_Connect(t *testing.T) {
+	translator := grpcweb.New(echoServer(t))
+	req := httptest.NewRequest(http.MethodPost, "/helloworld.Greeter/SayHello", strings.NewReader("hello"))
+	req.Header.Set("Content-Type", "application/proto")
+	req.Header.Set("Connect-Protocol-Version", "1")
+	rec := serve(t, translator, req)
+
+	if rec.Code != http.StatusOK || rec.Body.String() != "HELLO" || rec.Header().Get("Content-Type") != "application/proto" {
+		t.Errorf("Unexpected response %d %v %q", rec.Code, rec.Header(), rec.Body)
+	}
+	if rec.Header().Get("Trailer-X-Greeting-Count") != "1" || rec.Header().Get("Grpc-Status") != "" {
+		t.Errorf("Expected trailers as Trailer- headers, got %v", rec.Header())
+	}
+}
+
+func TestTranslator_ConnectError(t *testing.T) {
+	translator := grpcweb.New(echoServer(t))
+	req := httptest.NewRequest(http.MethodPost, "/helloworld.Greeter/SayHello", strings.NewReader(""))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Connect-Protocol-Version", "1")
+	rec := serve(t, translator, req)
+
+	var body map[string]string
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	if rec.Code != http.StatusNotFound || body["code"] != "not_found" || body["message"] != "no such greeting" {
+		t.Errorf("Unexpected error %d %v", rec.Code, body)
+	}
+}
+
+func TestTranslator_PassesThrough(t *testing.T) {
+	translator := grpcweb.New(echoServer(t), grpcweb.WithServices("helloworld.Greeter"))
+	requests := []*http.Request{
+		// A JSON API without the Connect header
+		httptest.NewRequest(http.MethodPost, "/helloworld.Greeter/SayHello", strings.NewReader("{}")),
+		// A service that is not translated
+		httptest.NewRequest(http.MethodPost, "/admin.Users/List", bytes.NewReader(frame(0, []byte("x")))),
+	}
+	requests[0].Header.Set("Content-Type", "application/json")
+	requests[1].Header.Set("Content-Type", "application/grpc-web")
+	for _, req := range requests {
+		if rec := serve(t, translator, req); rec.Body.String() != "rest" {
+			t.Errorf("Expected %s to reach the router, got %q", req.URL.Path, rec.Body)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package httpsign_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/crypto"
+	"github.com/hokamsingh/lessgo/internal/core/httpsign"
+)
+
+func newServer(t *testing.T, verifier *httpsign.Verifier) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(verifier.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write([]byte(httpsign.KeyID(r.Context()) + ":" + string(body)))
+	})))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func post(t *testing.T, client *http.Client, url, body string) (int, string) {
+	t.Helper()
+	res, err := client.Post(url, "text/plain", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	data, _ := io.ReadAll(res.Body)
+	return res.StatusCode, string(data)
+}
+
+func TestVerifier_AcceptsSignedRequests(t *testing.T) {
+	verifier := httpsign.NewVerifier(map[string][]byte{"v1": []byte("old"), "v2": []byte("new")})
+	server := newServer(t, verifier)
+
+	for _, key := range []struct{ id, secret string }{{"v1", "old"}, {"v2", "new"}} {
+		client := &http.Client{Transport: httpsign.NewSigner(key.id, []byte(key.secret)).Transport(nil)}
+		status, body := post(t, client, server.URL+"/orders?id=1", `{"total":42}`)
+		if status != http.StatusOK || body != key.id+`:{"total":42}` {
+			t.Errorf("Expected the request signed with %s to pass, got %d %q", key.id, status, body)
+		}
+	}
+
+	verifier.RemoveKey("v1")
+	client := &http.Client{Transport: httpsign.NewSigner("v1", []byte("old")).Transport(nil)}
+	if status, _ := post(t, client, server.URL+"/orders", "{}"); status != http.StatusUnauthorized {
+		t.Errorf("Expected a removed key to be refused, got %d", status)
+	}
+	if status, _ := post(t, http.DefaultClient, server.URL+"/orders", "{}"); status != http.StatusUnauthorized {
+		t.Errorf("Expected an unsigned request to be refused, got %d", status)
+	}
+}
+
+func TestVerifier_Keyring(t *testing.T) {
+	keyring := crypto.NewKeyring(crypto.Key{ID: "k1", Secret: []byte("first")})
+	server := newServer(t, httpsign.NewVerifier(nil, httpsign.WithKeyring(keyring)))
+	client := &http.Client{Transport: httpsign.NewKeyringSigner(keyring).Transport(nil)}
+
+	if status, body := post(t, client, server.URL, "a"); status != http.StatusOK || body != "k1:a" {
+		t.Fatalf("Expected the request to be accepted, got %d %q", status, body)
+	}
+	keyring.Rotate(crypto.Key{ID: "k2", Secret: []byte("second")})
+	if status, body := post(t, client, server.URL, "b"); status != http.StatusOK || body != "k2:b" {
+		t.Fatalf("Expected the rotated key to sign, got %d %q", status, body)
+	}
+	old := &http.Client{Transport: httpsign.NewSigner("k1", []byte("first")).Transport(nil)}
+	keyring.Remove("k1")
+	if status, _ := post(t, old, server.URL, "c"); status != http.StatusUnauthorized {
+		t.Errorf("Expected a removed key to be refused, got %d", status)
+	}
+}
+
+func TestVerifier_RejectsTampering(t *testing.T) {
+	verifier := httpsign.NewVerifier(map[string][]byte{"v1": []byte("secret")})
+	signer := httpsign.NewSigner("v1", []byte("secret"))
+
+	sign := func(method, target, body string) *http.Request {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if err := signer.Sign(req); err != nil {
+			t.Fatal(err)
+		}
+		return req
+	}
+
+	req := sign(http.MethodPost, "/transfer?to=alice", "amount=10")
+	if _, err := verifier.Verify(req); err != nil {
+		t.Fatalf("Expected a valid signature, got %v", err)
+	}
+	if body, _ := io.ReadAll(req.Body); string(body) != "amount=10" {
+		t.Errorf("Expected the body to stay readable, got %q", body)
+	}
+
+	req = sign(http.MethodPost, "/transfer?to=alice", "amount=10")
+	req.Body = io.NopCloser(strings.NewReader("amount=10000"))
+	if _, err := verifier.Verify(req); err != httpsign.ErrInvalidSignature {
+		t.Errorf("Expected a modified body to be refused, got %v", err)
+	}
+
+	req = sign(http.MethodPost, "/transfer?to=alice", "amount=10")
+	req.URL.RawQuery = "to=mallory"
+	req.RequestURI = "/transfer?to=mallory"
+	if _, err := verifier.Verify(req); err != httpsign.ErrInvalidSignature {
+		t.Errorf("Expected a modified query to be refused, got %v", err)
+	}
+
+	req = sign(http.MethodPost, "/transfer", "")
+	req.Header.Set(httpsign.Header, strings.Replace(req.Header.Get(httpsign.Header), "ts=", "ts=1", 1))
+	if _, err := verifier.Verify(req); err != httpsign.ErrInvalidSignature {
+		t.Errorf("Expected a modified timestamp to be refused, got %v", err)
+	}
+
+	req = sign(http.MethodPost, "/transfer", "")
+	req.Header.Set(httpsign.Header, "garbage")
+	if _, err := verifier.Verify(req); err != httpsign.ErrMalformedSignature {
+		t.Errorf("Expected a malformed header to be refused, got %v", err)
+	}
+}
+
+func TestVerifier_LimitsBodySize(t *testing.T) {
+	verifier := httpsign.NewVerifier(map[string][]byte{"v1": []byte("secret")}, httpsign.WithMaxBodySize(4))
+	client := &http.Client{Transport: httpsign.NewSigner("v1", []byte("secret")).Transport(nil)}
+	server := newServer(t, verifier)
+	if status, _ := post(t, client, server.URL, "too large"); status != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413, got %d", status)
+	}
+}
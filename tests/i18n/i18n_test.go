@@ -0,0 +1,133 @@
+package i18n_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/hokamsingh/lessgo/internal/core/context"
+	"github.com/hokamsingh/lessgo/internal/core/i18n"
+	"github.com/hokamsingh/lessgo/internal/core/pipe"
+	"github.com/hokamsingh/lessgo/internal/core/router"
+)
+
+func newBundle(t *testing.T) *i18n.Bundle {
+	t.Helper()
+	bundle := i18n.NewBundle("en")
+	err := bundle.LoadFS(fstest.MapFS{
+		"locales/en.json": {Data: []byte(`{
+			"greeting": "Hello {name}",
+			"cart": {"items": {"zero": "Your cart is empty", "one": "{count} item", "other": "{count} items"}}
+		}`)},
+		"locales/de.toml": {Data: []byte(`
+# German
+greeting = "Hallo {name}"
+
+[cart.items]
+one = "{count} Artikel"
+other = '{count} Artikel'
+
+[validation]
+required = "{field} ist erforderlich"
+`)},
+		"locales/ru.json": {Data: []byte(`{"files": {"one": "{count} файл", "few": "{count} файла", "many": "{count} файлов", "other": "{count} файла"}}`)},
+	}, "locales")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bundle
+}
+
+func TestBundle_Translate(t *testing.T) {
+	bundle := newBundle(t)
+	tests := []struct {
+		locale, key string
+		args        []interface{}
+		expected    string
+	}{
+		{"en", "greeting", []interface{}{i18n.Args{"name": "Ann"}}, "Hello Ann"},
+		{"de-AT", "greeting", []interface{}{i18n.Args{"name": "Ann"}}, "Hallo Ann"},
+		{"en", "cart.items", []interface{}{0}, "Your cart is empty"},
+		{"en", "cart.items", []interface{}{1}, "1 item"},
+		{"en", "cart.items", []interface{}{5}, "5 items"},
+		{"de", "cart.items", []interface{}{2}, "2 Artikel"},
+		{"ru", "files", []interface{}{3}, "3 файла"},
+		{"ru", "files", []interface{}{11}, "11 файлов"},
+		{"ru", "files", []interface{}{21}, "21 файл"},
+		{"fr", "greeting", []interface{}{i18n.Args{"name": "Ann"}}, "Hello Ann"},
+		{"en", "missing.key", nil, "missing.key"},
+	}
+	for _, test := range tests {
+		if actual := bundle.Translate(test.locale, test.key, test.args...); actual != test.expected {
+			t.Errorf("%s %s: expected %q, got %q", test.locale, test.key, test.expected, actual)
+		}
+	}
+}
+
+func TestMiddleware_Detect(t *testing.T) {
+	middleware := i18n.NewMiddleware(newBundle(t))
+	tests := []struct {
+		target, cookie, acceptLanguage, expected string
+	}{
+		{"/", "", "fr-FR, de;q=0.8, en;q=0.5", "de"},
+		{"/", "", "ru-RU", "ru"},
+		{"/", "", "ja", "en"},
+		{"/", "de", "en", "de"},
+		{"/?lang=ru", "de", "en", "ru"},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, test.target, nil)
+		if test.cookie != "" {
+			req.AddCookie(&http.Cookie{Name: "lang", Value: test.cookie})
+		}
+		req.Header.Set("Accept-Language", test.acceptLanguage)
+		if actual := middleware.Detect(req); actual != test.expected {
+			t.Errorf("%s %q %q: expected %s, got %s", test.target, test.cookie, test.acceptLanguage, test.expected, actual)
+		}
+	}
+}
+
+type signupDTO struct {
+	Email string `json:"email" validate:"required"`
+	Name  string `json:"name" validate:"max=3"`
+}
+
+func TestContext_TAndValidationMessages(t *testing.T) {
+	r := router.NewRouter(router.WithI18n(newBundle(t)))
+	r.Get("/greet", func(ctx *context.Context) {
+		ctx.Send(ctx.T("greeting", i18n.Args{"name": "Ann"}))
+	})
+	r.Post("/signup", context.WithPipes(func(ctx *context.Context) {
+		ctx.Send("ok")
+	}, pipe.Validate(signupDTO{})))
+
+	req := httptest.NewRequest(http.MethodGet, "/greet", nil)
+	req.Header.Set("Accept-Language", "de-DE")
+	w := httptest.NewRecorder()
+	r.Handler().ServeHTTP(w, req)
+	if w.Body.String() != "Hallo Ann" || w.Header().Get("Content-Language") != "de" {
+		t.Errorf("Expected German greeting, got %q (%s)", w.Body.String(), w.Header().Get("Content-Language"))
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/signup?lang=de", bytes.NewReader([]byte(`{"name":"Annabel"}`)))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.Handler().ServeHTTP(w, req)
+	var response struct {
+		Error struct {
+			Details map[string]string `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Unexpected response %d %q", w.Code, w.Body.String())
+	}
+	if response.Error.Details["email"] != "email ist erforderlich" {
+		t.Errorf("Expected translated message, got %v", response.Error.Details)
+	}
+	if response.Error.Details["name"] != "must be at most 3 characters" {
+		t.Errorf("Expected English fallback for untranslated rules, got %v", response.Error.Details)
+	}
+}
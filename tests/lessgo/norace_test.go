@@ -0,0 +1,5 @@
+//go:build !race
+
+package lessgo_test
+
+const raceEnabled = false
@@ -0,0 +1,6 @@
+//go:build race
+
+package lessgo_test
+
+// The race detector allocates on its own, allocation budgets do not apply.
+const raceEnabled = true
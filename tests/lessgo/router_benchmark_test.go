@@ -0,0 +1,178 @@
+package lessgo_test
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	LessGo "github.com/hokamsingh/lessgo/pkg/lessgo"
+)
+
+func TestMain(m *testing.M) {
+	// The router logs every request, which would dominate the measurements
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+type product struct {
+	ID       int               `json:"id"`
+	Name     string            `json:"name"`
+	Price    float64           `json:"price"`
+	Tags     []string          `json:"tags"`
+	Attrs    map[string]string `json:"attrs"`
+	InStock  bool              `json:"in_stock"`
+	Category string            `json:"category"`
+}
+
+func products(n int) []product {
+	list := make([]product, n)
+	for i := range list {
+		list[i] = product{
+			ID: i, Name: fmt.Sprintf("Product %d", i), Price: 9.99 + float64(i),
+			Tags:    []string{"new", "sale"},
+			Attrs:   map[string]string{"color": "blue", "size": "M"},
+			InStock: i%2 == 0, Category: "apparel",
+		}
+	}
+	return list
+}
+
+// passthrough is a middleware doing nothing, to measure the cost of the chain itself.
+type passthrough struct{}
+
+func (passthrough) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+	})
+}
+
+func noContent(ctx *LessGo.Context) {
+	ctx.Res.WriteHeader(http.StatusNoContent)
+}
+
+// newApp creates an app with the routes of a typical REST API for n resources.
+func newApp(n int) *LessGo.Router {
+	App := LessGo.App()
+	for i := 0; i < n; i++ {
+		App.Get(fmt.Sprintf("/api/v1/resource%d", i), noContent)
+		App.Post(fmt.Sprintf("/api/v1/resource%d", i), noContent)
+		App.Get(fmt.Sprintf("/api/v1/resource%d/{id}", i), noContent)
+		App.Put(fmt.Sprintf("/api/v1/resource%d/{id}", i), noContent)
+		App.Get(fmt.Sprintf("/api/v1/resource%d/{id}/items/{item}", i), noContent)
+	}
+	return App
+}
+
+// benchServe runs the request against the handler b.N times.
+func benchServe(b *testing.B, handler http.Handler, method, target string) {
+	b.Helper()
+	req := httptest.NewRequest(method, target, nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code >= 400 {
+			b.Fatalf("unexpected status %d for %s %s", rec.Code, method, target)
+		}
+	}
+}
+
+func BenchmarkParamRoutes(b *testing.B) {
+	App := newApp(20)
+	handler := App.Handler()
+	b.Run("static", func(b *testing.B) {
+		benchServe(b, handler, http.MethodGet, "/api/v1/resource10")
+	})
+	b.Run("one_param", func(b *testing.B) {
+		benchServe(b, handler, http.MethodGet, "/api/v1/resource10/42")
+	})
+	b.Run("two_params", func(b *testing.B) {
+		benchServe(b, handler, http.MethodGet, "/api/v1/resource10/42/items/7")
+	})
+	b.Run("method", func(b *testing.B) {
+		benchServe(b, handler, http.MethodPut, "/api/v1/resource10/42")
+	})
+}
+
+func BenchmarkRouteTable(b *testing.B) {
+	for _, n := range []int{10, 100, 1000} {
+		App := newApp(n)
+		handler := App.Handler()
+		// The last resource is matched after all others
+		b.Run(fmt.Sprintf("routes_%d/first", n*5), func(b *testing.B) {
+			benchServe(b, handler, http.MethodGet, "/api/v1/resource0/42")
+		})
+		b.Run(fmt.Sprintf("routes_%d/last", n*5), func(b *testing.B) {
+			benchServe(b, handler, http.MethodGet, fmt.Sprintf("/api/v1/resource%d/42/items/7", n-1))
+		})
+	}
+}
+
+func BenchmarkMiddlewareDepth(b *testing.B) {
+	for _, depth := range []int{0, 5, 10, 20} {
+		App := newApp(1)
+		for i := 0; i < depth; i++ {
+			App.Use(passthrough{})
+		}
+		handler := App.Handler()
+		b.Run(fmt.Sprintf("depth_%d", depth), func(b *testing.B) {
+			benchServe(b, handler, http.MethodGet, "/api/v1/resource0/42")
+		})
+	}
+}
+
+func BenchmarkJSONResponse(b *testing.B) {
+	for _, n := range []int{1, 100} {
+		list := products(n)
+		App := newApp(0)
+		App.Get("/products", func(ctx *LessGo.Context) {
+			ctx.JSON(http.StatusOK, list)
+		})
+		handler := App.Handler()
+		b.Run(fmt.Sprintf("products_%d", n), func(b *testing.B) {
+			benchServe(b, handler, http.MethodGet, "/products")
+		})
+	}
+}
+
+// allocationBudgets are the allocations per request allowed on the hot paths. Changes to the
+// router or context that allocate more fail TestAllocationBudget; lower the budgets when an
+// optimization lands, so the gain is kept.
+var allocationBudgets = []struct {
+	name   string
+	method string
+	target string
+	depth  int
+	budget float64
+}{
+	{"static", http.MethodGet, "/api/v1/resource10", 0, 25},
+	{"two_params", http.MethodGet, "/api/v1/resource10/42/items/7", 0, 26},
+	{"middleware_depth_10", http.MethodGet, "/api/v1/resource10/42", 10, 26},
+}
+
+func TestAllocationBudget(t *testing.T) {
+	if testing.Short() || raceEnabled {
+		t.Skip("allocation budgets are checked in full test runs without the race detector")
+	}
+	for _, tc := range allocationBudgets {
+		t.Run(tc.name, func(t *testing.T) {
+			App := newApp(20)
+			for i := 0; i < tc.depth; i++ {
+				App.Use(passthrough{})
+			}
+			handler := App.Handler()
+			req := httptest.NewRequest(tc.method, tc.target, nil)
+			allocs := testing.AllocsPerRun(100, func() {
+				handler.ServeHTTP(httptest.NewRecorder(), req)
+			})
+			if allocs > tc.budget {
+				t.Errorf("%s %s allocates %.0f times per request, the budget is %.0f", tc.method, tc.target, allocs, tc.budget)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package logexport_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/logexport"
+)
+
+type memoryStore struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	keys    []string
+	fail    bool
+}
+
+func (s *memoryStore) PutObject(ctx context.Context, key string, body []byte, contentType string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail {
+		return errors.New("unavailable")
+	}
+	if s.objects == nil {
+		s.objects = map[string][]byte{}
+	}
+	s.objects[key] = body
+	s.keys = append(s.keys, key)
+	return nil
+}
+
+func TestExporterFlush(t *testing.T) {
+	store := &memoryStore{}
+	exporter := logexport.NewExporter(store, logexport.WithPrefix("audit"))
+
+	exporter.Record(map[string]string{"event": "login"})
+	exporter.Record(map[string]string{"event": "logout"})
+	if err := exporter.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if len(store.keys) != 2 {
+		t.Fatalf("expected batch and manifest, got %v", store.keys)
+	}
+
+	key := store.keys[0]
+	if !strings.HasPrefix(key, "audit/") || !strings.HasSuffix(key, ".ndjson.gz") {
+		t.Errorf("unexpected key %q", key)
+	}
+
+	var manifest logexport.Manifest
+	if err := json.Unmarshal(store.objects[key+".manifest.json"], &manifest); err != nil {
+		t.Fatalf("manifest: %v", err)
+	}
+	sum := sha256.Sum256(store.objects[key])
+	if manifest.SHA256 != hex.EncodeToString(sum[:]) || manifest.Records != 2 {
+		t.Errorf("unexpected manifest %+v", manifest)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(store.objects[key]))
+	if err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	data, _ := io.ReadAll(gz)
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 2 {
+		t.Errorf("expected 2 records, got %q", data)
+	}
+}
+
+func TestExporterRetainsRecordsOnFailure(t *testing.T) {
+	store := &memoryStore{fail: true}
+	exporter := logexport.NewExporter(store)
+
+	exporter.Record(map[string]string{"event": "first"})
+	if err := exporter.Flush(context.Background()); err == nil {
+		t.Fatal("expected upload error")
+	}
+
+	store.fail = false
+	exporter.Record(map[string]string{"event": "second"})
+	if err := exporter.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	var manifest logexport.Manifest
+	json.Unmarshal(store.objects[store.keys[1]], &manifest)
+	if manifest.Records != 2 {
+		t.Errorf("expected retried records in batch, got %d", manifest.Records)
+	}
+
+	exporter.Record(map[string]string{"event": "third"})
+	exporter.Flush(context.Background())
+	var next logexport.Manifest
+	json.Unmarshal(store.objects[store.keys[3]], &next)
+	if next.PreviousHash != manifest.SHA256 {
+		t.Errorf("batches are not chained: %q != %q", next.PreviousHash, manifest.SHA256)
+	}
+}
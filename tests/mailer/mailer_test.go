@@ -0,0 +1,189 @@
+package mailer_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"html/template"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/di"
+	"github.com/hokamsingh/lessgo/internal/core/mailer"
+)
+
+func TestMailer_RenderTemplates(t *testing.T) {
+	templates := template.Must(template.New("").Parse(
+		`{{define "welcome.html"}}<h1>Hi {{.}}</h1>{{end}}{{define "welcome.txt"}}Hi {{.}}{{end}}`))
+	m := mailer.NewMailer(mailer.TransportFunc(func(ctx context.Context, msg *mailer.Message) error {
+		return nil
+	}), "app@example.com", mailer.WithTemplates(templates))
+
+	msg := &mailer.Message{To: []string{"ann@example.com"}, Subject: "Welcome"}
+	if err := m.Render(msg, "welcome.html", "Ann"); err != nil {
+		t.Fatal(err)
+	}
+	if msg.HTML != "<h1>Hi Ann</h1>" || msg.Text != "Hi Ann" {
+		t.Errorf("Unexpected bodies %q %q", msg.HTML, msg.Text)
+	}
+}
+
+func TestMailer_SendAsyncRetries(t *testing.T) {
+	var mu sync.Mutex
+	attempts := 0
+	transport := mailer.TransportFunc(func(ctx context.Context, msg *mailer.Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		if attempts < 3 {
+			return errors.New("temporary failure")
+		}
+		if msg.From != "app@example.com" {
+			t.Errorf("Expected default sender, got %q", msg.From)
+		}
+		return nil
+	})
+	m := mailer.NewMailer(transport, "app@example.com", mailer.WithRetry(3, time.Millisecond))
+
+	if err := m.SendAsync(&mailer.Message{To: []string{"ann@example.com"}, Subject: "Hi", Text: "Hello"}); err != nil {
+		t.Fatal(err)
+	}
+	m.Close()
+	if attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", attempts)
+	}
+	if err := m.SendAsync(&mailer.Message{}); err != mailer.ErrClosed {
+		t.Errorf("Expected ErrClosed after Close, got %v", err)
+	}
+}
+
+func TestMailer_SendAsyncDoesNotBlockOnAFullQueue(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	sent := 0
+	transport := mailer.TransportFunc(func(ctx context.Context, msg *mailer.Message) error {
+		<-release
+		mu.Lock()
+		sent++
+		mu.Unlock()
+		return nil
+	})
+	// Workers below 1 are ignored, otherwise nothing would be delivered
+	m := mailer.NewMailer(transport, "app@example.com", mailer.WithWorkers(0))
+
+	queued := 0
+	for ; queued < 2000; queued++ {
+		err := m.SendAsync(&mailer.Message{To: []string{"ann@example.com"}, Subject: "Hi"})
+		if errors.Is(err, mailer.ErrQueueFull) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if queued == 2000 {
+		t.Fatal("Expected ErrQueueFull once the queue is full")
+	}
+	close(release)
+	m.Close()
+	if sent != queued {
+		t.Errorf("Expected the %d queued messages to be delivered, got %d", queued, sent)
+	}
+}
+
+func TestBuildMIME_Attachments(t *testing.T) {
+	msg := &mailer.Message{
+		From:    "App <app@example.com>",
+		To:      []string{"ann@example.com"},
+		Bcc:     []string{"audit@example.com"},
+		Subject: "Your invoice",
+		Text:    "See attachment",
+		HTML:    "<p>See attachment</p>",
+	}
+	msg.Attach("invoice.pdf", "", []byte("%PDF-1.4"))
+
+	raw, err := mailer.BuildMIME(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Header.Get("Bcc") != "" {
+		t.Error("Bcc must not be included in the headers")
+	}
+	mediaType, params, _ := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if mediaType != "multipart/mixed" {
+		t.Fatalf("Expected multipart/mixed, got %s", mediaType)
+	}
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+	var types []string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		contentType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		types = append(types, contentType)
+		if contentType == "application/pdf" && part.FileName() != "invoice.pdf" {
+			t.Errorf("Expected attachment file name, got %q", part.FileName())
+		}
+	}
+	if strings.Join(types, ",") != "multipart/alternative,application/pdf" {
+		t.Errorf("Unexpected parts %v", types)
+	}
+}
+
+func TestContainer_WithMailer(t *testing.T) {
+	container := di.NewContainer(di.WithMailer(mailer.Config{Transport: "smtp", From: "app@example.com", SMTPHost: "localhost", SMTPPort: 25}))
+	err := container.Invoke(func(m *mailer.Mailer) {
+		if m == nil {
+			t.Error("Expected a mailer")
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBuildMIME_RejectsHeaderInjection(t *testing.T) {
+	for name, msg := range map[string]*mailer.Message{
+		"reply-to": {From: "app@example.com", ReplyTo: "x@example.com\r\nBcc: victim@example.com"},
+		"from":     {From: "App\r\nBcc: victim@example.com <app@example.com>"},
+		"header":   {From: "app@example.com", Headers: map[string]string{"X-Campaign": "spring\r\nBcc: victim@example.com"}},
+		"name":     {From: "app@example.com", Headers: map[string]string{"X-Bad: x\r\nBcc": "victim@example.com"}},
+	} {
+		if _, err := mailer.BuildMIME(msg); err == nil {
+			t.Errorf("%s: expected the message to be rejected", name)
+		}
+	}
+
+	raw, err := mailer.BuildMIME(&mailer.Message{
+		From:    "Ünicode App <app@example.com>",
+		To:      []string{"ann@example.com"},
+		ReplyTo: "support@example.com",
+		Headers: map[string]string{"X-Campaign": "spring"},
+		Text:    "Hi",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	from, err := parsed.Header.AddressList("From")
+	if err != nil || from[0].Name != "Ünicode App" || parsed.Header.Get("X-Campaign") != "spring" {
+		t.Errorf("Expected encoded headers, got %v %v", parsed.Header, err)
+	}
+}
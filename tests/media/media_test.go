@@ -0,0 +1,162 @@
+package media_test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/media"
+	"github.com/hokamsingh/lessgo/internal/core/storage"
+)
+
+// newServer stores a 200x100 PNG, red on the left half and blue on the right, as photo.png.
+func newServer(t *testing.T, options ...func(*media.Server)) (*media.Server, *storage.LocalStorage) {
+	t.Helper()
+	store, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 200; x++ {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= 100 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	var buffer bytes.Buffer
+	png.Encode(&buffer, img)
+	if err := store.Put(context.Background(), "photo.png", &buffer, "image/png"); err != nil {
+		t.Fatal(err)
+	}
+	return media.NewServer(store, "/media", []byte("secret"), options...), store
+}
+
+func get(s *media.Server, target string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	return rec
+}
+
+func decode(t *testing.T, rec *httptest.ResponseRecorder) image.Image {
+	t.Helper()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected the image, got %d %s", rec.Code, rec.Body.String())
+	}
+	img, _, err := image.Decode(rec.Body)
+	if err != nil {
+		t.Fatalf("Expected a valid image: %v", err)
+	}
+	return img
+}
+
+func TestServer_Resizes(t *testing.T) {
+	s, _ := newServer(t)
+
+	img := decode(t, get(s, s.URL("photo.png", media.Options{Width: 50})))
+	if img.Bounds().Dx() != 50 || img.Bounds().Dy() != 25 {
+		t.Errorf("Expected the height to follow the aspect ratio, got %v", img.Bounds())
+	}
+
+	img = decode(t, get(s, s.URL("photo.png", media.Options{Width: 50, Height: 50})))
+	if img.Bounds().Dx() != 50 || img.Bounds().Dy() != 25 {
+		t.Errorf("Expected contain to fit within the size, got %v", img.Bounds())
+	}
+
+	img = decode(t, get(s, s.URL("photo.png", media.Options{Width: 50, Height: 50, Fit: media.FitCover})))
+	if img.Bounds().Dx() != 50 || img.Bounds().Dy() != 50 {
+		t.Fatalf("Expected cover to fill the size, got %v", img.Bounds())
+	}
+	// The center crop keeps both halves
+	if r, _, _, _ := img.At(5, 25).RGBA(); r>>8 != 255 {
+		t.Errorf("Expected red on the left, got %v", img.At(5, 25))
+	}
+	if _, _, b, _ := img.At(45, 25).RGBA(); b>>8 != 255 {
+		t.Errorf("Expected blue on the right, got %v", img.At(45, 25))
+	}
+}
+
+func TestServer_ConvertsFormat(t *testing.T) {
+	s, _ := newServer(t)
+	rec := get(s, s.URL("photo.png", media.Options{Width: 20, Format: "jpeg", Quality: 70}))
+	if rec.Header().Get("Content-Type") != "image/jpeg" || !strings.Contains(rec.Header().Get("Cache-Control"), "immutable") {
+		t.Fatalf("Unexpected headers %v", rec.Header())
+	}
+	if _, err := jpeg.Decode(rec.Body); err != nil {
+		t.Errorf("Expected a JPEG, got %v", err)
+	}
+}
+
+func TestServer_RequiresSignature(t *testing.T) {
+	s, _ := newServer(t, media.WithPreset("thumb", media.Options{Width: 10, Height: 10, Fit: media.FitCover}))
+
+	signed := s.URL("photo.png", media.Options{Width: 50})
+	if rec := get(s, strings.Replace(signed, "w=50", "w=2000", 1)); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected a tampered URL to be refused, got %d", rec.Code)
+	}
+	if rec := get(s, "/media/photo.png?w=50"); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected an unsigned URL to be refused, got %d", rec.Code)
+	}
+	if rec := get(s, "/media/photo.png?p=thumb"); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected an unsigned preset to be refused, got %d", rec.Code)
+	}
+	img := decode(t, get(s, s.PresetURL("photo.png", "thumb")))
+	if img.Bounds().Dx() != 10 || img.Bounds().Dy() != 10 {
+		t.Errorf("Expected the preset size, got %v", img.Bounds())
+	}
+	if rec := get(s, s.URL("missing.png", media.Options{Width: 10})); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected missing images to be not found, got %d", rec.Code)
+	}
+
+	open, _ := newServer(t, media.WithPreset("thumb", media.Options{Width: 10}), media.WithUnsignedPresets())
+	decode(t, get(open, "/media/photo.png?p=thumb"))
+	if rec := get(open, "/media/photo.png?p=huge"); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected unknown presets to be refused, got %d", rec.Code)
+	}
+}
+
+func TestServer_CachesResults(t *testing.T) {
+	s, store := newServer(t, media.WithMediaWorkers(2, time.Second))
+	url := s.URL("photo.png", media.Options{Width: 30})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if rec := get(s, url); rec.Code != http.StatusOK && rec.Code != http.StatusServiceUnavailable {
+				t.Errorf("Unexpected status %d", rec.Code)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Served from the cache once the source is gone
+	rec := get(s, url)
+	if err := store.Delete(context.Background(), "photo.png"); err != nil {
+		t.Fatal(err)
+	}
+	decode(t, get(s, url))
+
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	req.Header.Set("If-None-Match", rec.Header().Get("ETag"))
+	cached := httptest.NewRecorder()
+	s.ServeHTTP(cached, req)
+	if cached.Code != http.StatusNotModified {
+		t.Errorf("Expected a conditional request to be not modified, got %d", cached.Code)
+	}
+	if rec := get(s, "/media/_media/anything"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected the cache to be hidden, got %d", rec.Code)
+	}
+}
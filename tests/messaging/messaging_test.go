@@ -0,0 +1,358 @@
+package messaging_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/messaging"
+	"github.com/hokamsingh/lessgo/internal/core/module"
+)
+
+func receive(t *testing.T, ch <-chan *messaging.Message) *messaging.Message {
+	t.Helper()
+	select {
+	case msg := <-ch:
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for a message")
+		return nil
+	}
+}
+
+func TestMatchSubject(t *testing.T) {
+	tests := []struct {
+		pattern, subject string
+		match            bool
+	}{
+		{"orders.created", "orders.created", true},
+		{"orders.*", "orders.created", true},
+		{"orders.*", "orders.created.eu", false},
+		{"orders.>", "orders.created.eu", true},
+		{"orders.>", "orders", false},
+		{"orders", "orders.created", false},
+	}
+	for _, tt := range tests {
+		if got := messaging.MatchSubject(tt.pattern, tt.subject); got != tt.match {
+			t.Errorf("MatchSubject(%q, %q) = %v, want %v", tt.pattern, tt.subject, got, tt.match)
+		}
+	}
+}
+
+func TestMemoryBroker_Groups(t *testing.T) {
+	broker := messaging.NewMemoryBroker()
+	defer broker.Close()
+
+	var mu sync.Mutex
+	counts := map[string]int{}
+	done := make(chan *messaging.Message, 100)
+	handler := func(name string) messaging.Handler {
+		return func(ctx context.Context, msg *messaging.Message) error {
+			mu.Lock()
+			counts[name]++
+			mu.Unlock()
+			done <- msg
+			return nil
+		}
+	}
+	broker.Subscribe("orders.*", "billing", handler("billing-1"))
+	broker.Subscribe("orders.*", "billing", handler("billing-2"))
+	broker.Subscribe("orders.>", "", handler("audit"))
+
+	for i := 0; i < 4; i++ {
+		if err := broker.Publish(context.Background(), &messaging.Message{Subject: "orders.created", Data: []byte("x")}); err != nil {
+			t.Fatalf("Failed to publish: %v", err)
+		}
+	}
+	for i := 0; i < 8; i++ {
+		receive(t, done)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if counts["billing-1"] != 2 || counts["billing-2"] != 2 || counts["audit"] != 4 {
+		t.Errorf("Expected the group to share the messages and audit to get all, got %v", counts)
+	}
+}
+
+func TestDispatcher_ShutdownWaitsForHandlers(t *testing.T) {
+	broker := messaging.NewMemoryBroker()
+	defer broker.Close()
+	dispatcher := messaging.NewDispatcher(broker)
+
+	started := make(chan struct{})
+	var finished atomic.Bool
+	dispatcher.Handle("jobs", "", func(ctx context.Context, msg *messaging.Message) error {
+		close(started)
+		time.Sleep(50 * time.Millisecond)
+		finished.Store(true)
+		return nil
+	})
+	dispatcher.Handle("panics", "", func(ctx context.Context, msg *messaging.Message) error {
+		panic("boom")
+	})
+	if err := dispatcher.Start(); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+	broker.Publish(context.Background(), &messaging.Message{Subject: "panics"})
+	broker.Publish(context.Background(), &messaging.Message{Subject: "jobs"})
+	<-started
+
+	if err := dispatcher.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Failed to shut down: %v", err)
+	}
+	if !finished.Load() {
+		t.Error("Expected Shutdown to wait for the running handler")
+	}
+}
+
+type billingService struct {
+	received chan *messaging.Message
+}
+
+func (s *billingService) RegisterSubscriptions(d *messaging.Dispatcher) {
+	d.Handle("orders.created", "billing", func(ctx context.Context, msg *messaging.Message) error {
+		s.received <- msg
+		return nil
+	})
+}
+
+func TestRegisterModules(t *testing.T) {
+	broker := messaging.NewMemoryBroker()
+	defer broker.Close()
+	dispatcher := messaging.NewDispatcher(broker)
+	service := &billingService{received: make(chan *messaging.Message, 1)}
+	messaging.RegisterModules(dispatcher, []module.IModule{
+		module.NewModule("billing", nil, []interface{}{service}, nil),
+	})
+	if err := dispatcher.Start(); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+	defer dispatcher.Shutdown(context.Background())
+
+	broker.Publish(context.Background(), &messaging.Message{Subject: "orders.created", Data: []byte("42")})
+	if msg := receive(t, service.received); string(msg.Data) != "42" {
+		t.Errorf("Expected the service to receive the order, got %q", msg.Data)
+	}
+}
+
+// serveFakeNATS speaks enough of the NATS protocol to route published messages back to the
+// subscriptions of the connection.
+func serveFakeNATS(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	conn.Write([]byte("INFO {\"server_id\":\"fake\",\"headers\":true}\r\n"))
+	subs := map[string]string{} // sid -> subject
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "PING":
+			conn.Write([]byte("PONG\r\n"))
+		case "SUB":
+			subs[fields[len(fields)-1]] = fields[1]
+		case "UNSUB":
+			delete(subs, fields[1])
+		case "PUB", "HPUB":
+			total, _ := strconv.Atoi(fields[len(fields)-1])
+			payload := make([]byte, total+2)
+			if _, err := io.ReadFull(reader, payload); err != nil {
+				return
+			}
+			for sid, subject := range subs {
+				if !messaging.MatchSubject(subject, fields[1]) {
+					continue
+				}
+				if fields[0] == "PUB" {
+					fmt.Fprintf(conn, "MSG %s %s %d\r\n", fields[1], sid, total)
+				} else {
+					fmt.Fprintf(conn, "HMSG %s %s %s %d\r\n", fields[1], sid, fields[2], total)
+				}
+				conn.Write(payload)
+			}
+		}
+	}
+}
+
+// startFakeNATS serves the fake NATS server until the test ends and returns its URL.
+func startFakeNATS(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveFakeNATS(conn)
+		}
+	}()
+	return "nats://" + listener.Addr().String()
+}
+
+func TestNATSBroker(t *testing.T) {
+	broker, err := messaging.NewNATSBroker(startFakeNATS(t))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer broker.Close()
+
+	received := make(chan *messaging.Message, 2)
+	if _, err := broker.Subscribe("orders.*", "billing", func(ctx context.Context, msg *messaging.Message) error {
+		received <- msg
+		return nil
+	}); err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	broker.Publish(context.Background(), &messaging.Message{Subject: "orders.created", Data: []byte("plain")})
+	if msg := receive(t, received); msg.Subject != "orders.created" || string(msg.Data) != "plain" {
+		t.Errorf("Unexpected message %+v", msg)
+	}
+	broker.Publish(context.Background(), &messaging.Message{
+		Subject: "orders.paid",
+		Data:    []byte("with headers"),
+		Headers: map[string]string{"Trace-Id": "abc"},
+	})
+	if msg := receive(t, received); string(msg.Data) != "with headers" || msg.Headers["Trace-Id"] != "abc" {
+		t.Errorf("Expected the headers to be carried, got %+v", msg)
+	}
+}
+
+func TestNATSBroker_RejectsProtocolInjection(t *testing.T) {
+	broker, err := messaging.NewNATSBroker(startFakeNATS(t))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer broker.Close()
+
+	for _, msg := range []*messaging.Message{
+		{Subject: "orders.created 0\r\nPUB admin.reset"},
+		{Subject: ""},
+		{Subject: "orders.created", Headers: map[string]string{"Trace-Id": "abc\r\nX-Role: admin"}},
+		{Subject: "orders.created", Headers: map[string]string{"Bad Key": "x"}},
+	} {
+		if err := broker.Publish(context.Background(), msg); err != messaging.ErrInvalidSubject {
+			t.Errorf("Expected ErrInvalidSubject for %+v, got %v", msg, err)
+		}
+	}
+	if _, err := broker.Subscribe("orders.* billing", "", func(context.Context, *messaging.Message) error { return nil }); err != messaging.ErrInvalidSubject {
+		t.Errorf("Expected ErrInvalidSubject for a subject with a space, got %v", err)
+	}
+}
+
+func TestNATSBroker_SlowSubscriberDoesNotBlockOthers(t *testing.T) {
+	broker, err := messaging.NewNATSBroker(startFakeNATS(t), messaging.WithNATSPendingLimit(1))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer broker.Close()
+
+	release := make(chan struct{})
+	defer close(release)
+	broker.Subscribe("reports.built", "", func(ctx context.Context, msg *messaging.Message) error {
+		<-release
+		return nil
+	})
+	received := make(chan *messaging.Message, 1)
+	broker.Subscribe("orders.created", "", func(ctx context.Context, msg *messaging.Message) error {
+		received <- msg
+		return nil
+	})
+
+	for i := 0; i < 10; i++ {
+		broker.Publish(context.Background(), &messaging.Message{Subject: "reports.built", Data: []byte("report")})
+	}
+	broker.Publish(context.Background(), &messaging.Message{Subject: "orders.created", Data: []byte("order")})
+	if msg := receive(t, received); string(msg.Data) != "order" {
+		t.Errorf("Unexpected message %+v", msg)
+	}
+}
+
+func TestKafkaBroker(t *testing.T) {
+	var mu sync.Mutex
+	var queue []map[string]interface{}
+	var committed, deleted int
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/vnd.kafka.v2+json")
+		switch {
+		case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/topics/"):
+			var body struct {
+				Records []map[string]interface{} `json:"records"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			for i, record := range body.Records {
+				record["topic"] = strings.TrimPrefix(r.URL.Path, "/topics/")
+				record["partition"] = 0
+				record["offset"] = len(queue) + i
+				queue = append(queue, record)
+			}
+			w.Write([]byte(`{"offsets":[]}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/consumers/billing":
+			fmt.Fprintf(w, `{"instance_id":"c1","base_uri":"%s/consumers/billing/instances/c1"}`, server.URL)
+		case strings.HasSuffix(r.URL.Path, "/subscription"), strings.HasSuffix(r.URL.Path, "/offsets"):
+			if strings.HasSuffix(r.URL.Path, "/offsets") {
+				committed++
+			}
+			w.WriteHeader(http.StatusNoContent)
+		case strings.HasSuffix(r.URL.Path, "/records"):
+			records := queue
+			queue = nil
+			json.NewEncoder(w).Encode(records)
+		case r.Method == http.MethodDelete:
+			deleted++
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	broker := messaging.NewKafkaBroker(server.URL, messaging.WithKafkaPollInterval(10*time.Millisecond))
+	received := make(chan *messaging.Message, 1)
+	sub, err := broker.Subscribe("orders", "billing", func(ctx context.Context, msg *messaging.Message) error {
+		received <- msg
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	if err := broker.Publish(context.Background(), &messaging.Message{Subject: "orders", Key: "42", Data: []byte("created")}); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+	msg := receive(t, received)
+	if msg.Subject != "orders" || msg.Key != "42" || string(msg.Data) != "created" {
+		t.Errorf("Unexpected message %+v", msg)
+	}
+	if err := sub.Unsubscribe(); err != nil {
+		t.Fatalf("Failed to unsubscribe: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if committed != 1 || deleted != 1 {
+		t.Errorf("Expected the offsets committed and the consumer deleted, got %d commits and %d deletes", committed, deleted)
+	}
+}
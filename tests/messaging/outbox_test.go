@@ -0,0 +1,363 @@
+package messaging_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/cache"
+	"github.com/hokamsingh/lessgo/internal/core/database"
+	"github.com/hokamsingh/lessgo/internal/core/messaging"
+)
+
+// outboxDB is a database/sql driver understanding the statements of the outbox. Writes made in
+// a transaction are applied on commit.
+type outboxDB struct {
+	mu      sync.Mutex
+	rows    map[string]*outboxRow
+	queries []string
+}
+
+type outboxRow struct {
+	id, subject, key, headers string
+	data                      []byte
+	seq, created, next        int64
+	attempts                  int64
+	published                 *int64
+	lastError                 string
+}
+
+var outboxDBs sync.Map
+
+func init() {
+	sql.Register("outbox", outboxDriver{})
+}
+
+type outboxDriver struct{}
+
+func (outboxDriver) Open(name string) (driver.Conn, error) {
+	db, _ := outboxDBs.Load(name)
+	return &outboxConn{db: db.(*outboxDB)}, nil
+}
+
+type outboxConn struct {
+	db      *outboxDB
+	pending []func()
+	inTx    bool
+}
+
+func (c *outboxConn) Prepare(query string) (driver.Stmt, error) {
+	return &outboxStmt{conn: c, query: query}, nil
+}
+func (c *outboxConn) Close() error { return nil }
+func (c *outboxConn) Begin() (driver.Tx, error) {
+	c.inTx = true
+	return c, nil
+}
+func (c *outboxConn) Commit() error {
+	c.db.mu.Lock()
+	for _, apply := range c.pending {
+		apply()
+	}
+	c.db.mu.Unlock()
+	c.pending, c.inTx = nil, false
+	return nil
+}
+func (c *outboxConn) Rollback() error {
+	c.pending, c.inTx = nil, false
+	return nil
+}
+
+type outboxStmt struct {
+	conn  *outboxConn
+	query string
+}
+
+func (s *outboxStmt) Close() error  { return nil }
+func (s *outboxStmt) NumInput() int { return -1 }
+
+func (s *outboxStmt) Exec(args []driver.Value) (driver.Result, error) {
+	db := s.conn.db
+	db.mu.Lock()
+	db.queries = append(db.queries, s.query)
+	db.mu.Unlock()
+	var apply func() int64
+	switch {
+	case strings.HasPrefix(s.query, "CREATE TABLE"):
+		return driver.RowsAffected(0), nil
+	case strings.HasPrefix(s.query, "INSERT"):
+		data, _ := args[4].([]byte)
+		row := &outboxRow{id: args[0].(string), subject: args[1].(string), key: args[2].(string),
+			headers: args[3].(string), data: data, seq: args[5].(int64), created: args[6].(int64), next: args[7].(int64)}
+		apply = func() int64 { db.rows[row.id] = row; return 1 }
+	case strings.Contains(s.query, "SET attempts = ?"):
+		apply = func() int64 {
+			row := db.rows[args[3].(string)]
+			row.attempts, row.next, row.lastError = args[0].(int64), args[1].(int64), args[2].(string)
+			return 1
+		}
+	case strings.Contains(s.query, "SET published_at = ?"):
+		apply = func() int64 {
+			row := db.rows[args[2].(string)]
+			published := args[0].(int64)
+			row.published, row.attempts = &published, args[1].(int64)
+			return 1
+		}
+	case strings.HasPrefix(s.query, "DELETE"):
+		apply = func() int64 {
+			var n int64
+			for id, row := range db.rows {
+				if row.published != nil && *row.published < args[0].(int64) {
+					delete(db.rows, id)
+					n++
+				}
+			}
+			return n
+		}
+	default:
+		return nil, errors.New("unexpected statement: " + s.query)
+	}
+	if s.conn.inTx {
+		s.conn.pending = append(s.conn.pending, func() { apply() })
+		return driver.RowsAffected(1), nil
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return driver.RowsAffected(apply()), nil
+}
+
+func (s *outboxStmt) Query(args []driver.Value) (driver.Rows, error) {
+	db := s.conn.db
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var due []*outboxRow
+	for _, row := range db.rows {
+		if row.published == nil && row.next <= args[0].(int64) && !db.blocked(row, args[1].(int64)) {
+			due = append(due, row)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].seq < due[j].seq })
+	values := make([][]driver.Value, len(due))
+	for i, row := range due {
+		values[i] = []driver.Value{row.id, row.subject, row.key, row.headers, row.data, row.attempts}
+	}
+	return &outboxRows{values: values}, nil
+}
+
+// blocked reports whether an earlier event with the key of row is backing off at now.
+func (db *outboxDB) blocked(row *outboxRow, now int64) bool {
+	if row.key == "" {
+		return false
+	}
+	for _, other := range db.rows {
+		if other.key == row.key && other.published == nil && other.next > now && other.seq < row.seq {
+			return true
+		}
+	}
+	return false
+}
+
+type outboxRows struct {
+	values [][]driver.Value
+}
+
+func (r *outboxRows) Columns() []string {
+	return []string{"id", "subject", "msg_key", "headers", "data", "attempts"}
+}
+func (r *outboxRows) Close() error { return nil }
+func (r *outboxRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func openOutboxDB(t *testing.T) (*sql.DB, *outboxDB) {
+	t.Helper()
+	store := &outboxDB{rows: map[string]*outboxRow{}}
+	outboxDBs.Store(t.Name(), store)
+	db, err := sql.Open("outbox", t.Name())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	return db, store
+}
+
+// flakyBroker fails the first publications.
+type flakyBroker struct {
+	*messaging.MemoryBroker
+	mu       sync.Mutex
+	failures int
+}
+
+func (b *flakyBroker) Publish(ctx context.Context, msg *messaging.Message) error {
+	b.mu.Lock()
+	if b.failures > 0 {
+		b.failures--
+		b.mu.Unlock()
+		return errors.New("broker unavailable")
+	}
+	b.mu.Unlock()
+	return b.MemoryBroker.Publish(ctx, msg)
+}
+
+func TestOutbox_PublishesCommittedEvents(t *testing.T) {
+	db, store := openOutboxDB(t)
+	defer db.Close()
+	broker := messaging.NewMemoryBroker()
+	defer broker.Close()
+	received := make(chan *messaging.Message, 2)
+	broker.Subscribe("orders.*", "", func(ctx context.Context, msg *messaging.Message) error {
+		received <- msg
+		return nil
+	})
+	outbox := messaging.NewOutbox(db, broker, "outbox")
+	ctx := context.Background()
+
+	rolledBack, _ := db.BeginTx(ctx, nil)
+	outbox.Add(ctx, rolledBack, &messaging.Message{Subject: "orders.cancelled"})
+	rolledBack.Rollback()
+
+	tx, _ := db.BeginTx(ctx, nil)
+	id, err := outbox.Add(ctx, tx, &messaging.Message{Subject: "orders.created", Key: "42", Data: []byte("order"),
+		Headers: map[string]string{"Trace-Id": "abc"}})
+	if err != nil {
+		t.Fatalf("Failed to add event: %v", err)
+	}
+	if n, _ := outbox.Relay(ctx); n != 0 {
+		t.Errorf("Expected uncommitted events not to be published, got %d", n)
+	}
+	tx.Commit()
+
+	if n, err := outbox.Relay(ctx); n != 1 || err != nil {
+		t.Fatalf("Expected 1 event published, got %d: %v", n, err)
+	}
+	msg := receive(t, received)
+	if msg.Subject != "orders.created" || string(msg.Data) != "order" || msg.Headers[messaging.MessageIDHeader] != id || msg.Headers["Trace-Id"] != "abc" {
+		t.Errorf("Unexpected message %+v", msg)
+	}
+	if n, _ := outbox.Relay(ctx); n != 0 {
+		t.Errorf("Expected published events not to be published again, got %d", n)
+	}
+	if n, _ := outbox.Purge(ctx, time.Now().Add(time.Second)); n != 1 || len(store.rows) != 0 {
+		t.Errorf("Expected the published event purged, got %d", n)
+	}
+}
+
+func TestOutbox_RetriesFailedEvents(t *testing.T) {
+	db, store := openOutboxDB(t)
+	defer db.Close()
+	broker := &flakyBroker{MemoryBroker: messaging.NewMemoryBroker(), failures: 1}
+	defer broker.Close()
+	outbox := messaging.NewOutbox(db, broker, "outbox", messaging.WithOutboxMaxBackoff(10*time.Millisecond))
+	ctx := context.Background()
+	outbox.Add(ctx, db, &messaging.Message{Subject: "orders.created", Key: "42", Data: []byte("first")})
+	outbox.Add(ctx, db, &messaging.Message{Subject: "orders.created", Key: "42", Data: []byte("second")})
+
+	if n, _ := outbox.Relay(ctx); n != 0 {
+		t.Errorf("Expected the events of the failed key to wait for the retry, got %d published", n)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if n, _ := outbox.Relay(ctx); n != 2 {
+		t.Errorf("Expected both events published on retry, got %d", n)
+	}
+	failed := 0
+	for _, row := range store.rows {
+		if row.attempts == 2 && row.lastError == "broker unavailable" {
+			failed++
+		}
+	}
+	if failed != 1 {
+		t.Errorf("Expected the failed attempt recorded on one event, got %d", failed)
+	}
+}
+
+func TestOutbox_KeepsOrderWhileBackingOff(t *testing.T) {
+	db, store := openOutboxDB(t)
+	defer db.Close()
+	broker := &flakyBroker{MemoryBroker: messaging.NewMemoryBroker(), failures: 1}
+	defer broker.Close()
+	var mu sync.Mutex
+	var received []string
+	broker.Subscribe("orders.*", "", func(ctx context.Context, msg *messaging.Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, msg.Key+":"+string(msg.Data))
+		return nil
+	})
+	outbox := messaging.NewOutbox(db, broker, "outbox")
+	ctx := context.Background()
+	// Added within the same millisecond, the sequence still orders them
+	for _, data := range []string{"first", "second", "third"} {
+		outbox.Add(ctx, db, &messaging.Message{Subject: "orders.created", Key: "42", Data: []byte(data)})
+	}
+	outbox.Add(ctx, db, &messaging.Message{Subject: "orders.created", Key: "7", Data: []byte("other")})
+
+	if n, _ := outbox.Relay(ctx); n != 1 {
+		t.Errorf("Expected only the event of the other key published, got %d", n)
+	}
+	// The failed event is backing off, the following events of its key must keep waiting
+	if n, _ := outbox.Relay(ctx); n != 0 {
+		t.Errorf("Expected the events behind the failed one to wait, got %d published", n)
+	}
+	store.mu.Lock()
+	for _, row := range store.rows {
+		row.next = 0
+	}
+	store.mu.Unlock()
+	if n, _ := outbox.Relay(ctx); n != 3 {
+		t.Errorf("Expected the events of the key published once the retry is due, got %d", n)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		mu.Lock()
+		got := strings.Join(received, " ")
+		mu.Unlock()
+		if got == "7:other 42:first 42:second 42:third" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the events of key 42 in order, got %s", got)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestDeduplicate(t *testing.T) {
+	seen := cache.New()
+	defer seen.Close()
+	calls := 0
+	handler := messaging.Deduplicate(seen, time.Minute, func(ctx context.Context, msg *messaging.Message) error {
+		calls++
+		return nil
+	})
+	msg := &messaging.Message{Subject: "orders.created", Headers: map[string]string{messaging.MessageIDHeader: "1"}}
+	handler(context.Background(), msg)
+	handler(context.Background(), msg)
+	handler(context.Background(), &messaging.Message{Subject: "orders.created"})
+	if calls != 2 {
+		t.Errorf("Expected the redelivery skipped, got %d calls", calls)
+	}
+}
+
+func TestOutbox_UsesDialectPlaceholders(t *testing.T) {
+	db, store := openOutboxDB(t)
+	defer db.Close()
+	outbox := messaging.NewOutbox(db, messaging.NewMemoryBroker(), "outbox", messaging.WithOutboxDialect(database.DialectOf("postgres")))
+	if _, err := outbox.Add(context.Background(), db, &messaging.Message{Subject: "orders.created"}); err != nil {
+		t.Fatal(err)
+	}
+	if query := store.queries[0]; strings.Contains(query, "?") || !strings.Contains(query, "$8") {
+		t.Errorf("Expected PostgreSQL placeholders, got %s", query)
+	}
+}
@@ -0,0 +1,77 @@
+package middleware_test
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/context"
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+	"github.com/hokamsingh/lessgo/internal/core/router"
+)
+
+func TestAbortCounter(t *testing.T) {
+	aborts := middleware.NewAbortCounter()
+	stopped := make(chan struct{})
+	r := router.NewRouter()
+	r.Use(aborts)
+	r.Get("/jobs/{id}/events", func(ctx *context.Context) {
+		defer close(stopped)
+		ticker := time.NewTicker(5 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if ctx.SSEvent("progress", map[string]int{"percent": 10}) != nil {
+					return
+				}
+			}
+		}
+	})
+	r.Get("/ping", func(ctx *context.Context) {
+		ctx.Send("pong")
+	})
+	r.ExposeAbortCounter(aborts)
+	server := httptest.NewServer(r.Handler())
+	defer server.Close()
+
+	if resp, err := http.Get(server.URL + "/ping"); err == nil {
+		resp.Body.Close()
+	}
+	resp, err := http.Get(server.URL + "/jobs/7/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line, _ := bufio.NewReader(resp.Body).ReadString('\n'); line != "event: progress\n" {
+		t.Fatalf("Expected an event, got %q", line)
+	}
+	// The client goes away
+	resp.Body.Close()
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the handler to stop when the client disconnected")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for aborts.Stats().Aborted == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	stats := aborts.Stats()
+	if stats.Requests != 2 || stats.Aborted != 1 || stats.Routes["GET /jobs/{id}/events"] != 1 {
+		t.Errorf("Expected one aborted request of the event route, got %+v", stats)
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/aborted/metrics", nil)
+	req.RemoteAddr = "127.0.0.1:1234"
+	r.Admin().Handler().ServeHTTP(w, req)
+	if want := `lessgo_http_requests_aborted_total{method="GET",route="/jobs/{id}/events"} 1`; !strings.Contains(w.Body.String(), want) {
+		t.Errorf("Expected %s in\n%s", want, w.Body.String())
+	}
+}
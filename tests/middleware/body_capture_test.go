@@ -0,0 +1,59 @@
+package middleware_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+)
+
+func TestBodyCapture(t *testing.T) {
+	var raw, body []byte
+	var rawErr error
+	handler := middleware.NewBodyCapture(16).Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := middleware.ReadBody(r); err != nil {
+			t.Fatal(err)
+		}
+		middleware.ReplaceBody(r, []byte(`{"b":2}`))
+		raw, rawErr = middleware.RawBody(r)
+		body, _ = io.ReadAll(r.Body)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"a":1}`)))
+	if rawErr != nil || string(raw) != `{"a":1}` {
+		t.Errorf("Expected the raw body, got %q (%v)", raw, rawErr)
+	}
+	if string(body) != `{"b":2}` {
+		t.Errorf("Expected the replaced body, got %q", body)
+	}
+
+	large := strings.Repeat("x", 64)
+	req := httptest.NewRequest(http.MethodPost, "/", io.NopCloser(strings.NewReader(large)))
+	req.ContentLength = -1
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if !errors.Is(rawErr, middleware.ErrBodyTooLarge) {
+		t.Errorf("Expected ErrBodyTooLarge, got %v", rawErr)
+	}
+}
+
+func TestJSONParser_LimitsCapturedBodies(t *testing.T) {
+	parser := middleware.NewJsonParser(*middleware.NewParserOptions(8))
+	handler := middleware.NewBodyCapture(1 << 10).Handle(parser.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})))
+
+	for body, status := range map[string]int{`{"a":1}`: http.StatusNoContent, `{"a":"` + strings.Repeat("x", 64) + `"}`: http.StatusRequestEntityTooLarge} {
+		req := httptest.NewRequest(http.MethodPost, "/", io.NopCloser(strings.NewReader(body)))
+		req.ContentLength = -1
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != status {
+			t.Errorf("Expected %d for a %d byte body, got %d", status, len(body), w.Code)
+		}
+	}
+}
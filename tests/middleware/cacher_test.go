@@ -0,0 +1,190 @@
+package middleware_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+)
+
+func serveCached(handler http.Handler) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items", nil))
+	return rec
+}
+
+func TestCachingCoalescesMisses(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	caching := middleware.NewCaching(nil, time.Minute, true, middleware.WithCacheStore(middleware.NewMemoryCacheStore()))
+	handler := caching.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		<-release
+		w.Write([]byte("items"))
+	}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if rec := serveCached(handler); rec.Body.String() != "items" {
+				t.Errorf("Expected the shared response, got %q", rec.Body.String())
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("Expected concurrent misses to call the handler once, got %d", n)
+	}
+	if rec := serveCached(handler); rec.Header().Get("X-Cache-Hit") != "true" {
+		t.Error("Expected the response to be cached")
+	}
+}
+
+func TestCachingDoesNotSharePrivateResponses(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	caching := middleware.NewCaching(nil, time.Minute, true, middleware.WithCacheStore(middleware.NewMemoryCacheStore()))
+	handler := caching.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			<-release
+		}
+		user := r.Header.Get("X-User")
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: user})
+		w.Write([]byte("hello " + user))
+	}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(user string) {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodGet, "/me", nil)
+			req.Header.Set("X-User", user)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Body.String() != "hello "+user || rec.Header().Get("Set-Cookie") != "session="+user {
+				t.Errorf("Expected the response of %s, got %q %q", user, rec.Body.String(), rec.Header().Get("Set-Cookie"))
+			}
+		}(fmt.Sprintf("user%d", i))
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := calls.Load(); n != 5 {
+		t.Errorf("Expected every request to run the handler, got %d calls", n)
+	}
+}
+
+func TestCachingStaleWhileRevalidate(t *testing.T) {
+	var calls atomic.Int32
+	caching := middleware.NewCaching(nil, 20*time.Millisecond, true,
+		middleware.WithCacheStore(middleware.NewMemoryCacheStore()),
+		middleware.WithStaleWhileRevalidate(time.Minute),
+	)
+	handler := caching.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "v%d", calls.Add(1))
+	}))
+
+	serveCached(handler)
+	time.Sleep(30 * time.Millisecond)
+
+	rec := serveCached(handler)
+	if rec.Body.String() != "v1" || rec.Header().Get("X-Cache-Hit") != "true" {
+		t.Errorf("Expected the stale response to be served, got %q", rec.Body.String())
+	}
+	for i := 0; i < 100 && calls.Load() < 2; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if rec := serveCached(handler); rec.Body.String() != "v2" {
+		t.Errorf("Expected the refreshed response, got %q", rec.Body.String())
+	}
+}
+
+func TestCachingStaleIfError(t *testing.T) {
+	var failing atomic.Bool
+	caching := middleware.NewCaching(nil, 10*time.Millisecond, true,
+		middleware.WithCacheStore(middleware.NewMemoryCacheStore()),
+		middleware.WithStaleIfError(time.Minute),
+	)
+	handler := caching.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("items"))
+	}))
+
+	serveCached(handler)
+	failing.Store(true)
+	time.Sleep(20 * time.Millisecond)
+
+	rec := serveCached(handler)
+	if rec.Code != http.StatusOK || rec.Body.String() != "items" {
+		t.Errorf("Expected the stale response when the refresh fails, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestCachingBypassRules(t *testing.T) {
+	var calls atomic.Int32
+	caching := middleware.NewCaching(nil, time.Minute, true,
+		middleware.WithCacheStore(middleware.NewMemoryCacheStore()),
+		middleware.WithCacheBypassCookies("session_id"),
+		middleware.WithCacheBypassHeaders("Authorization"),
+		middleware.WithCacheBypassParams("preview"),
+	)
+	handler := caching.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "session_id", Value: "abc"})
+		}
+		w.Write([]byte("page"))
+	}))
+	serve := func(target string, modify func(*http.Request)) {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if modify != nil {
+			modify(req)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	serve("/page", func(r *http.Request) { r.AddCookie(&http.Cookie{Name: "session_id", Value: "abc"}) })
+	serve("/page", func(r *http.Request) { r.Header.Set("Authorization", "Bearer token") })
+	serve("/page?preview=1", nil)
+	serve("/page?preview=1", nil)
+	if n := calls.Load(); n != 4 {
+		t.Errorf("Expected bypassed requests to reach the handler, got %d calls", n)
+	}
+
+	serve("/login", nil)
+	serve("/login", nil)
+	if n := calls.Load(); n != 6 {
+		t.Errorf("Expected responses setting cookies not to be cached, got %d calls", n)
+	}
+}
+
+func TestCachingRouteTTL(t *testing.T) {
+	var calls atomic.Int32
+	caching := middleware.NewCaching(nil, time.Minute, true, middleware.WithCacheStore(middleware.NewMemoryCacheStore()))
+	handler := caching.Handle(middleware.CacheTTL(0).Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Write([]byte("cart"))
+	})))
+
+	serveCached(handler)
+	serveCached(handler)
+	if n := calls.Load(); n != 2 {
+		t.Errorf("Expected a zero route TTL to skip the cache, got %d calls", n)
+	}
+}
@@ -0,0 +1,46 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+)
+
+func TestCompress(t *testing.T) {
+	compress := middleware.NewCompress(5, 100)
+	serve := func(contentType, contentLength, encoding string) *httptest.ResponseRecorder {
+		handler := compress.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", contentType)
+			if contentLength != "" {
+				w.Header().Set("Content-Length", contentLength)
+			}
+			if encoding != "" {
+				w.Header().Set("Content-Encoding", encoding)
+			}
+			w.Write([]byte(strings.Repeat("a", 200)))
+		}))
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Encoding", "br, gzip;q=0.8")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := serve("application/json", "", ""); rec.Header().Get("Content-Encoding") != "gzip" || rec.Body.Len() >= 200 {
+		t.Errorf("Expected JSON to be compressed, got %v with %d bytes", rec.Header(), rec.Body.Len())
+	}
+	cases := []struct{ name, contentType, contentLength, encoding string }{
+		{"images", "image/png", "", ""},
+		{"small responses", "text/plain", "50", ""},
+		{"event streams", "text/event-stream", "", ""},
+		{"encoded responses", "text/plain", "", "br"},
+	}
+	for _, c := range cases {
+		if rec := serve(c.contentType, c.contentLength, c.encoding); rec.Header().Get("Content-Encoding") == "gzip" || rec.Body.Len() != 200 {
+			t.Errorf("Expected %s to stay uncompressed, got %v", c.name, rec.Header())
+		}
+	}
+}
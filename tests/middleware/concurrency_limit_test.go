@@ -0,0 +1,65 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+)
+
+func TestConcurrencyLimit(t *testing.T) {
+	limit := middleware.NewConcurrencyLimit(1, 1, 50*time.Millisecond)
+	release := make(chan struct{})
+	started := make(chan struct{}, 3)
+	handler := limit.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+	}))
+	serve := func() int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		return rec.Code
+	}
+
+	var wg sync.WaitGroup
+	codes := make(chan int, 2)
+	wg.Add(1)
+	go func() { defer wg.Done(); codes <- serve() }()
+	<-started
+
+	// The second request waits in the queue, the third is shed right away
+	wg.Add(1)
+	go func() { defer wg.Done(); codes <- serve() }()
+	for limit.Waiting() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	if code := serve(); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 with a full queue, got %d", code)
+	}
+
+	close(release)
+	wg.Wait()
+	close(codes)
+	for code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("Expected queued requests to succeed, got %d", code)
+		}
+	}
+
+	slow := middleware.NewConcurrencyLimit(1, 1, 10*time.Millisecond)
+	block := make(chan struct{})
+	blocked := slow.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { <-block }))
+	go blocked.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	for slow.InFlight() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	rec := httptest.NewRecorder()
+	blocked.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 after the queue timeout, got %d", rec.Code)
+	}
+	close(block)
+}
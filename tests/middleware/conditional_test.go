@@ -0,0 +1,61 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+)
+
+// headerMiddleware marks responses it handled.
+type headerMiddleware struct{}
+
+func (headerMiddleware) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Applied", "1")
+		next.ServeHTTP(w, r)
+	})
+}
+
+func applied(m middleware.Middleware, method, path string, header http.Header) bool {
+	req := httptest.NewRequest(method, path, nil)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	rec := httptest.NewRecorder()
+	m.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rec, req)
+	return rec.Header().Get("X-Applied") != ""
+}
+
+func TestSkipAndOnly(t *testing.T) {
+	skip := middleware.Skip(headerMiddleware{}, middleware.MatchPaths("/webhooks/**", "/health"))
+	if applied(skip, http.MethodPost, "/webhooks/stripe", nil) || applied(skip, http.MethodGet, "/health", nil) {
+		t.Error("Expected matched paths to be skipped")
+	}
+	if !applied(skip, http.MethodPost, "/orders", nil) || !applied(skip, http.MethodGet, "/webhooksx", nil) {
+		t.Error("Expected other paths to be handled")
+	}
+
+	only := middleware.Only(headerMiddleware{}, middleware.MatchAll(
+		middleware.MatchMethods(http.MethodGet),
+		middleware.MatchPaths("/api/*/items"),
+	))
+	if !applied(only, http.MethodGet, "/api/v1/items", nil) {
+		t.Error("Expected matching request to be handled")
+	}
+	if applied(only, http.MethodPost, "/api/v1/items", nil) || applied(only, http.MethodGet, "/api/v1/x/items", nil) {
+		t.Error("Expected other requests to pass through")
+	}
+
+	header := middleware.Only(headerMiddleware{}, middleware.MatchAny(
+		middleware.MatchHeader("X-Debug", ""),
+		func(r *http.Request) bool { return r.URL.Query().Get("debug") == "1" },
+	))
+	if !applied(header, http.MethodGet, "/", http.Header{"X-Debug": {"yes"}}) || !applied(header, http.MethodGet, "/?debug=1", nil) {
+		t.Error("Expected header and func matchers to match")
+	}
+	if applied(header, http.MethodGet, "/", nil) {
+		t.Error("Expected request without header to pass through")
+	}
+}
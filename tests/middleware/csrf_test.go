@@ -0,0 +1,85 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/crypto"
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+)
+
+func TestCSRFProtection_Keyring(t *testing.T) {
+	keyring := crypto.KeyringFromSecrets([]byte("csrf secret"))
+	handler := middleware.NewCSRFProtection(middleware.WithCSRFKeyring(keyring)).Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("Expected a CSRF cookie, got %v", cookies)
+	}
+	token := cookies[0].Value
+
+	post := func(cookie, header string) int {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.AddCookie(&http.Cookie{Name: "csrf_token", Value: cookie})
+		req.Header.Set("X-CSRF-Token", header)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if code := post(token, token); code != http.StatusOK {
+		t.Errorf("Expected a signed token to be accepted, got %d", code)
+	}
+	// A cookie planted by another origin matches its header but is not signed
+	if code := post("planted", "planted"); code != http.StatusForbidden {
+		t.Errorf("Expected an unsigned token to be refused, got %d", code)
+	}
+	if code := post("", ""); code != http.StatusForbidden {
+		t.Errorf("Expected an empty token to be refused, got %d", code)
+	}
+
+	// A planted unsigned cookie is replaced on the next GET
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: "csrf_token", Value: "planted"})
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if cookies := rec.Result().Cookies(); len(cookies) != 1 || cookies[0].Value == "planted" {
+		t.Errorf("Expected the planted cookie to be replaced, got %v", cookies)
+	}
+}
+
+func TestCSRFProtection_BoundToSession(t *testing.T) {
+	keyring := crypto.KeyringFromSecrets([]byte("csrf secret"))
+	csrf := middleware.NewCSRFProtection(middleware.WithCSRFKeyring(keyring), middleware.WithCSRFSession(func(r *http.Request) string {
+		return r.Header.Get("X-Session")
+	}))
+	handler := csrf.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	issue := func(session string) string {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Session", session)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Result().Cookies()[0].Value
+	}
+	post := func(session, token string) int {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Header.Set("X-Session", session)
+		req.AddCookie(&http.Cookie{Name: "csrf_token", Value: token})
+		req.Header.Set("X-CSRF-Token", token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	victim := issue("victim")
+	if code := post("victim", victim); code != http.StatusOK {
+		t.Errorf("Expected the token of the session to be accepted, got %d", code)
+	}
+	// An attacker plants a token issued to their own session on a sibling subdomain
+	planted := issue("attacker")
+	if code := post("victim", planted); code != http.StatusForbidden {
+		t.Errorf("Expected a token of another session to be refused, got %d", code)
+	}
+}
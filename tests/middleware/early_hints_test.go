@@ -0,0 +1,98 @@
+package middleware_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/textproto"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/context"
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+	"github.com/hokamsingh/lessgo/internal/core/router"
+)
+
+// getWithHints requests url and returns the Link headers of the 103 responses received.
+func getWithHints(t *testing.T, url string) ([]string, *http.Response) {
+	var hints []string
+	trace := &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			if code == http.StatusEarlyHints {
+				hints = append(hints, header.Values("Link")...)
+			}
+			return nil
+		},
+	}
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return hints, resp
+}
+
+func TestEarlyHints(t *testing.T) {
+	r := router.NewRouter()
+	r.WithEarlyHints(
+		middleware.Link{URL: "/static/app.css", As: "style"},
+		middleware.Link{URL: "/static/inter.woff2", As: "font", Type: "font/woff2", CrossOrigin: "anonymous"},
+	).Get("/", func(ctx *context.Context) { ctx.Send("home") })
+	r.Get("/products", func(ctx *context.Context) {
+		ctx.EarlyHints(middleware.Link{URL: "https://cdn.example.com", Rel: "preconnect"})
+		ctx.Send("products")
+	})
+	r.Get("/plain", func(ctx *context.Context) { ctx.Send("plain") })
+	server := httptest.NewServer(r.Handler())
+	defer server.Close()
+
+	hints, resp := getWithHints(t, server.URL+"/")
+	want := []string{
+		"</static/app.css>; rel=preload; as=style",
+		`</static/inter.woff2>; rel=preload; as=font; type="font/woff2"; crossorigin=anonymous`,
+	}
+	if len(hints) != 2 || hints[0] != want[0] || hints[1] != want[1] {
+		t.Errorf("Expected hints %q, got %q", want, hints)
+	}
+	if resp.StatusCode != http.StatusOK || len(resp.Header.Values("Link")) != 2 {
+		t.Errorf("Expected the final response to keep the links, got %d %v", resp.StatusCode, resp.Header)
+	}
+
+	if hints, _ := getWithHints(t, server.URL+"/products"); len(hints) != 1 || hints[0] != "<https://cdn.example.com>; rel=preconnect" {
+		t.Errorf("Expected the preconnect hint of the handler, got %q", hints)
+	}
+	if hints, _ := getWithHints(t, server.URL+"/plain"); len(hints) != 0 {
+		t.Errorf("Expected no hints on other routes, got %q", hints)
+	}
+}
+
+type pushRecorder struct {
+	*httptest.ResponseRecorder
+	pushed []string
+}
+
+func (p *pushRecorder) Push(target string, options *http.PushOptions) error {
+	p.pushed = append(p.pushed, target)
+	return nil
+}
+
+func TestEarlyHints_ServerPush(t *testing.T) {
+	hints := middleware.NewEarlyHints([]middleware.Link{
+		{URL: "/static/app.css", As: "style"},
+		{URL: "https://cdn.example.com/lib.js", As: "script"},
+		{URL: "https://fonts.example.com", Rel: "preconnect"},
+	}, middleware.WithServerPush())
+	handler := hints.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rec := &pushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if len(rec.pushed) != 1 || rec.pushed[0] != "/static/app.css" {
+		t.Errorf("Expected only the same-origin preload to be pushed, got %v", rec.pushed)
+	}
+
+	if err := middleware.Push(httptest.NewRecorder(), "/static/app.css", nil); !errors.Is(err, http.ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported without HTTP/2, got %v", err)
+	}
+}
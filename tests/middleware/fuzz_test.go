@@ -0,0 +1,76 @@
+package middleware_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+)
+
+func FuzzJSONParser(f *testing.F) {
+	for _, seed := range []string{
+		`{"name":"Ann","tags":["a","b"],"age":36}`,
+		`[1, 2.5e308, null, true]`,
+		`{"a":`, `"\ud800"`, `{"a":{"b":{"c":[[[[[]]]]]}}}`, "\x00\xff", `1e999999`,
+	} {
+		f.Add([]byte(seed))
+	}
+	parser := middleware.NewJsonParser(*middleware.NewParserOptions(1 << 16))
+	handler := parser.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	f.Fuzz(func(t *testing.T, body []byte) {
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		switch rec.Code {
+		case http.StatusNoContent, http.StatusBadRequest, http.StatusRequestEntityTooLarge:
+		default:
+			t.Errorf("Unexpected status %d for %q", rec.Code, body)
+		}
+	})
+}
+
+func TestJSONParser_UnknownLength(t *testing.T) {
+	parser := middleware.NewJsonParser(*middleware.NewParserOptions(16))
+	handler := parser.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"padding":"`+strings.Repeat("x", 64)+`"}`))
+	req.ContentLength = -1
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected a chunked body over the limit to be rejected with 413, got %d", rec.Code)
+	}
+}
+
+func FuzzXSSProtection(f *testing.F) {
+	for _, seed := range []string{
+		"<script>alert(1)</script>", "javascript:alert(1)", "%3Cscript%3E", "plain text",
+		"\xc4\xb0nvalid \xff utf8", "&#x3C;script&#x3E;", "",
+	} {
+		f.Add(seed)
+	}
+	xss := middleware.NewXSSProtection()
+	handler := xss.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	f.Fuzz(func(t *testing.T, value string) {
+		req := httptest.NewRequest(http.MethodPost, "/?q="+url.QueryEscape(value), bytes.NewReader([]byte("comment="+url.QueryEscape(value))))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("X-Custom", value)
+		req.AddCookie(&http.Cookie{Name: "session", Value: url.QueryEscape(value)})
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusNoContent && rec.Code != http.StatusBadRequest {
+			t.Errorf("Unexpected status %d for %q", rec.Code, value)
+		}
+	})
+}
@@ -0,0 +1,119 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+)
+
+func serveFrom(h http.Handler, path, remoteAddr string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = remoteAddr
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestIPBlocklist(t *testing.T) {
+	blocklist := middleware.NewIPBlocklist("203.0.113.0/24")
+	h := blocklist.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	if rec := serveFrom(h, "/", "203.0.113.9:1234"); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected a blocked network to be refused, got %d", rec.Code)
+	}
+	blocklist.Ban("198.51.100.7", 50*time.Millisecond)
+	blocklist.Ban("198.51.100.8", 0)
+	if rec := serveFrom(h, "/", "198.51.100.7:1234"); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected a banned IP to be refused, got %d", rec.Code)
+	}
+	if bans := blocklist.Bans(); len(bans) != 2 || !bans["198.51.100.8"].IsZero() {
+		t.Errorf("Unexpected bans %v", bans)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if rec := serveFrom(h, "/", "198.51.100.7:1234"); rec.Code != http.StatusOK {
+		t.Errorf("Expected the ban to expire, got %d", rec.Code)
+	}
+	blocklist.Unban("198.51.100.8")
+	if blocklist.Blocked("198.51.100.8") {
+		t.Error("Expected the ban to be lifted")
+	}
+}
+
+func TestHoneypot_BansAndReports(t *testing.T) {
+	blocklist := middleware.NewIPBlocklist()
+	options := middleware.NewHoneypotOptions(blocklist, time.Hour)
+	options.TarpitDelay = 0
+	var mu sync.Mutex
+	var events []middleware.HoneypotEvent
+	options.OnTrigger = func(event middleware.HoneypotEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	}
+	honeypot := middleware.NewHoneypot(*options)
+	h := blocklist.Handle(honeypot.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	if rec := serveFrom(h, "/users", "198.51.100.7:1234"); rec.Code != http.StatusOK {
+		t.Fatalf("Expected other paths to be served, got %d", rec.Code)
+	}
+	if rec := serveFrom(h, "/wp-admin/setup.php", "198.51.100.7:1234"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected a decoy to answer 404, got %d", rec.Code)
+	}
+	if rec := serveFrom(h, "/users", "198.51.100.7:1234"); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected the scanner to be banned, got %d", rec.Code)
+	}
+	if rec := serveFrom(h, "/users", "198.51.100.8:1234"); rec.Code != http.StatusOK {
+		t.Errorf("Expected other clients to be served, got %d", rec.Code)
+	}
+
+	stats := honeypot.Stats()
+	if stats.Hits != 1 || stats.Bans != 1 || stats.Paths["/wp-admin/"] != 1 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != 1 || events[0].IP != "198.51.100.7" || !events[0].Banned || events[0].Path != "/wp-admin/setup.php" {
+		t.Errorf("Unexpected events %+v", events)
+	}
+}
+
+func TestHoneypot_Tarpit(t *testing.T) {
+	options := middleware.NewHoneypotOptions(nil, 0)
+	options.TarpitDelay = 100 * time.Millisecond
+	options.MaxTarpits = 1
+	honeypot := middleware.NewHoneypot(*options)
+	h := honeypot.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	start := time.Now()
+	if rec := serveFrom(h, "/.env", "198.51.100.7:1234"); rec.Code != http.StatusOK || rec.Body.Len() == 0 {
+		t.Errorf("Expected the tarpit to trickle a body, got %d %q", rec.Code, rec.Body.String())
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("Expected the response to be delayed, took %s", elapsed)
+	}
+
+	// While the only tarpit slot is held, further decoys answer at once
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		req := httptest.NewRequest(http.MethodGet, "/.env", nil).WithContext(ctx)
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}()
+	for honeypot.Stats().ActiveTarpits == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if rec := serveFrom(h, "/.git/config", "198.51.100.8:1234"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 once the tarpits are full, got %d", rec.Code)
+	}
+	cancel()
+	<-done
+	if stats := honeypot.Stats(); stats.ActiveTarpits != 0 || stats.Tarpitted != 2 || stats.Hits != 3 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
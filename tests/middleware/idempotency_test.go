@@ -0,0 +1,109 @@
+package middleware_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+)
+
+func newIdempotentHandler(calls *int, status int) http.Handler {
+	idempotency := middleware.NewIdempotency(middleware.NewMemoryIdempotencyStore(), time.Minute)
+	return idempotency.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(`{"call":` + string(rune('0'+*calls)) + `,"body":` + string(body) + `}`))
+	}))
+}
+
+func sendIdempotent(handler http.Handler, method, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/payments", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(middleware.IdempotencyKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotency_Replay(t *testing.T) {
+	calls := 0
+	handler := newIdempotentHandler(&calls, http.StatusCreated)
+
+	first := sendIdempotent(handler, http.MethodPost, "abc", `{"amount":10}`)
+	second := sendIdempotent(handler, http.MethodPost, "abc", `{"amount":10}`)
+	if calls != 1 {
+		t.Fatalf("Expected the handler to run once, ran %d times", calls)
+	}
+	if second.Code != http.StatusCreated || second.Body.String() != first.Body.String() {
+		t.Errorf("Expected replayed response, got %d %q", second.Code, second.Body.String())
+	}
+	if second.Header().Get("Idempotent-Replayed") != "true" || second.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Expected replayed headers, got %v", second.Header())
+	}
+
+	sendIdempotent(handler, http.MethodPost, "", `{"amount":10}`)
+	sendIdempotent(handler, http.MethodPatch, "abc", `{"amount":10}`)
+	if calls != 3 {
+		t.Errorf("Expected requests without key or with other methods to pass through, ran %d times", calls)
+	}
+}
+
+func TestIdempotency_ConflictingPayload(t *testing.T) {
+	calls := 0
+	handler := newIdempotentHandler(&calls, http.StatusCreated)
+
+	sendIdempotent(handler, http.MethodPost, "abc", `{"amount":10}`)
+	w := sendIdempotent(handler, http.MethodPost, "abc", `{"amount":20}`)
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for a different payload, got %d", w.Code)
+	}
+}
+
+func TestIdempotency_ServerErrorsAreRetried(t *testing.T) {
+	calls := 0
+	handler := newIdempotentHandler(&calls, http.StatusServiceUnavailable)
+
+	sendIdempotent(handler, http.MethodPost, "abc", `{}`)
+	sendIdempotent(handler, http.MethodPost, "abc", `{}`)
+	if calls != 2 {
+		t.Errorf("Expected failed requests to be retried, ran %d times", calls)
+	}
+}
+
+func TestIdempotency_ScopedToTenantAndUser(t *testing.T) {
+	calls := 0
+	handler := newIdempotentHandler(&calls, http.StatusCreated)
+	send := func(tenant, user string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/payments", strings.NewReader(`{"amount":10}`))
+		req.Header.Set(middleware.IdempotencyKeyHeader, "abc")
+		if tenant != "" {
+			req = middleware.SetValue(req, middleware.TenantKey, tenant)
+		}
+		if user != "" {
+			req = req.WithContext(middleware.WithPrincipal(req.Context(), &middleware.Principal{User: user}))
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	send("acme", "ann")
+	for _, other := range [][2]string{{"acme", "bob"}, {"globex", "ann"}, {"", ""}} {
+		if w := send(other[0], other[1]); w.Header().Get("Idempotent-Replayed") != "" {
+			t.Errorf("Expected the response of ann at acme not to be replayed to %v", other)
+		}
+	}
+	if calls != 4 {
+		t.Errorf("Expected the handler to run for every tenant and user, ran %d times", calls)
+	}
+	if w := send("acme", "ann"); w.Header().Get("Idempotent-Replayed") != "true" || calls != 4 {
+		t.Errorf("Expected the retry of ann at acme to be replayed, got %v", w.Header())
+	}
+}
@@ -0,0 +1,61 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+)
+
+func TestLoadShedderByPriority(t *testing.T) {
+	options := middleware.NewLoadShedderOptions(time.Millisecond, 0, 0)
+	options.SampleInterval = time.Nanosecond
+	options.Window = 10
+	options.RoutePriorities = map[string]middleware.Priority{"/health": middleware.PriorityCritical}
+	shedder := middleware.NewLoadShedder(*options)
+	handler := shedder.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("slow") != "" {
+			time.Sleep(3 * time.Millisecond)
+		}
+	}))
+	serve := func(path, priority string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if priority != "" {
+			req.Header.Set("X-Priority", priority)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := serve("/", "low"); code != http.StatusOK {
+		t.Fatalf("Expected 200 without load, got %d", code)
+	}
+	for i := 0; i < 10; i++ {
+		serve("/?slow=1", "critical")
+	}
+	serve("/health", "")
+	if shedder.Load() < 1 {
+		t.Fatalf("Expected load over the latency limit, got %f", shedder.Load())
+	}
+	if code := serve("/", "low"); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected low priority request to be shed, got %d", code)
+	}
+	if code := serve("/", ""); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected normal priority request to be shed, got %d", code)
+	}
+	if code := serve("/health", ""); code != http.StatusOK {
+		t.Errorf("Expected critical route to be served, got %d", code)
+	}
+}
+
+func TestParsePriority(t *testing.T) {
+	if p, ok := middleware.ParsePriority(" High "); !ok || p != middleware.PriorityHigh {
+		t.Errorf("Expected high priority, got %v %v", p, ok)
+	}
+	if _, ok := middleware.ParsePriority("urgent"); ok {
+		t.Error("Expected unknown priority to be rejected")
+	}
+}
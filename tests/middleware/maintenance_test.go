@@ -0,0 +1,51 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/config"
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+)
+
+func TestMaintenance(t *testing.T) {
+	m := middleware.NewMaintenance()
+	handler := m.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	serve := func(path, remoteAddr, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := serve("/", "203.0.113.7:1", ""); rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 while off, got %d", rec.Code)
+	}
+
+	m.Set(true, []string{"10.0.0.0/8"}, 10*time.Minute)
+	rec := serve("/orders", "203.0.113.7:1", "application/json")
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") != "600" {
+		t.Errorf("Expected 503 with Retry-After, got %d %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if rec := serve("/", "203.0.113.7:1", "text/html"); !strings.Contains(rec.Body.String(), "maintenance") {
+		t.Errorf("Expected the maintenance page, got %q", rec.Body.String())
+	}
+	if rec := serve("/healthz", "203.0.113.7:1", ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected health checks to pass, got %d", rec.Code)
+	}
+	if rec := serve("/orders", "10.1.2.3:1", ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected allowed networks to pass, got %d", rec.Code)
+	}
+
+	m.SetFromConfig(config.Config{"MAINTENANCE_MODE": "false"})
+	if m.Enabled() {
+		t.Error("Expected maintenance to be turned off by the config")
+	}
+}
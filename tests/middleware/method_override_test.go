@@ -0,0 +1,58 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+)
+
+func methodOf(req *http.Request, methods ...string) string {
+	var seen string
+	handler := middleware.NewMethodOverride(methods...).Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r.Method
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	return seen
+}
+
+func TestMethodOverride_Header(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/users/42", nil)
+	req.Header.Set(middleware.MethodOverrideHeader, "patch")
+	if method := methodOf(req); method != http.MethodPatch {
+		t.Errorf("Expected PATCH, got %s", method)
+	}
+}
+
+func TestMethodOverride_FormField(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/users/42", strings.NewReader("_method=DELETE&name=ann"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if method := methodOf(req); method != http.MethodDelete {
+		t.Errorf("Expected DELETE, got %s", method)
+	}
+	if req.PostForm.Get("name") != "ann" {
+		t.Errorf("Expected the form to stay readable, got %v", req.PostForm)
+	}
+}
+
+func TestMethodOverride_Restrictions(t *testing.T) {
+	get := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	get.Header.Set(middleware.MethodOverrideHeader, "DELETE")
+	if method := methodOf(get); method != http.MethodGet {
+		t.Errorf("Expected GET requests to be untouched, got %s", method)
+	}
+
+	connect := httptest.NewRequest(http.MethodPost, "/users/42", nil)
+	connect.Header.Set(middleware.MethodOverrideHeader, "CONNECT")
+	if method := methodOf(connect); method != http.MethodPost {
+		t.Errorf("Expected disallowed methods to be ignored, got %s", method)
+	}
+
+	put := httptest.NewRequest(http.MethodPost, "/users/42", nil)
+	put.Header.Set(middleware.MethodOverrideHeader, "PUT")
+	if method := methodOf(put, http.MethodDelete); method != http.MethodPost {
+		t.Errorf("Expected only configured methods to be accepted, got %s", method)
+	}
+}
@@ -0,0 +1,78 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/context"
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+	"github.com/hokamsingh/lessgo/internal/core/router"
+)
+
+type sleepMiddleware struct{ d time.Duration }
+
+func (s sleepMiddleware) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(s.d)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func TestProfilerCapturesSlowRequests(t *testing.T) {
+	profiler := middleware.NewProfiler(*middleware.NewProfilerOptions(5 * time.Millisecond))
+	r := router.NewRouter(router.WithProfiler(profiler))
+	r.Use(sleepMiddleware{d: 5 * time.Millisecond})
+	r.Get("/users/{id}", func(ctx *context.Context) { ctx.Send("ok") })
+	r.Get("/fast", func(ctx *context.Context) { ctx.Send("ok") })
+	handler := r.Handler()
+
+	for _, path := range []string{"/users/1", "/users/2", "/fast"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	stats := profiler.Stats()
+	if len(stats) != 2 {
+		t.Fatalf("Expected stats for 2 routes, got %+v", stats)
+	}
+	if stats[0].Route != "/users/{id}" && stats[0].Route != "/fast" {
+		t.Errorf("Expected stats per route template, got %q", stats[0].Route)
+	}
+
+	slow := profiler.SlowRequests()
+	if len(slow) != 3 {
+		t.Fatalf("Expected 3 slow requests, got %d", len(slow))
+	}
+	if slow[0].Path != "/fast" || slow[0].Status != http.StatusOK {
+		t.Errorf("Expected newest slow request first, got %+v", slow[0])
+	}
+	if got := slow[0].Headers.Get("Authorization"); got == "Bearer secret" {
+		t.Error("Expected the Authorization header to be redacted")
+	}
+	if len(slow[0].Timings) != 1 || slow[0].Timings[0].Duration < 5*time.Millisecond {
+		t.Errorf("Expected the timing of the sleeping middleware, got %+v", slow[0].Timings)
+	}
+}
+
+func TestTimedExcludesInnerHandlers(t *testing.T) {
+	outer := middleware.Timed("outer", sleepMiddleware{d: time.Millisecond})
+	inner := middleware.Timed("inner", sleepMiddleware{d: 20 * time.Millisecond})
+	var timings []middleware.Timing
+	chain := outer.Handle(inner.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r, _ = middleware.RequestValues(r)
+		chain.ServeHTTP(w, r)
+		timings = middleware.RequestTimings(r)
+	})
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if len(timings) != 2 || timings[0].Name != "inner" || timings[1].Name != "outer" {
+		t.Fatalf("Expected inner then outer timings, got %+v", timings)
+	}
+	if timings[1].Duration >= 20*time.Millisecond {
+		t.Errorf("Expected outer timing to exclude the inner middleware, got %v", timings[1].Duration)
+	}
+}
@@ -0,0 +1,53 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+)
+
+func TestQuota(t *testing.T) {
+	var exhausted []string
+	options := middleware.NewQuotaOptions(2, middleware.Daily)
+	options.Plans["pro"] = middleware.QuotaPlan{Name: "pro", Limit: 5, Period: middleware.Monthly}
+	options.PlanFor = func(r *http.Request, subject string) string {
+		if subject == "key:pro-key" {
+			return "pro"
+		}
+		return ""
+	}
+	options.OnExhausted = func(r *http.Request, subject string, plan middleware.QuotaPlan) {
+		exhausted = append(exhausted, subject)
+	}
+	handler := middleware.NewQuota(middleware.NewMemoryQuotaStore(), *options).Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func(apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-API-Key", apiKey)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i, remaining := range []string{"1", "0"} {
+		rec := serve("free-key")
+		if rec.Code != http.StatusOK || rec.Header().Get("X-Quota-Remaining") != remaining {
+			t.Errorf("Request %d: expected 200 with %s remaining, got %d %s", i, remaining, rec.Code, rec.Header().Get("X-Quota-Remaining"))
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if rec := serve("free-key"); rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+			t.Errorf("Expected 429 with Retry-After, got %d", rec.Code)
+		}
+	}
+	if len(exhausted) != 1 || exhausted[0] != "key:free-key" {
+		t.Errorf("Expected one exhaustion callback, got %v", exhausted)
+	}
+
+	if rec := serve("pro-key"); rec.Header().Get("X-Quota-Limit") != "5" {
+		t.Errorf("Expected the pro plan limit, got %s", rec.Header().Get("X-Quota-Limit"))
+	}
+}
@@ -0,0 +1,87 @@
+package middleware_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+	"github.com/hokamsingh/lessgo/internal/core/router"
+)
+
+func TestRecovery(t *testing.T) {
+	var reported []error
+	options := middleware.NewRecoveryOptions()
+	options.StackTrace = false
+	options.Logf = func(string, ...interface{}) {}
+	options.Reporter = func(r *http.Request, err error, stack []byte) {
+		if len(stack) == 0 {
+			t.Error("Expected a stack trace")
+		}
+		reported = append(reported, err)
+	}
+	recovery := middleware.NewRecovery(*options)
+
+	tests := []struct {
+		name     string
+		panicked interface{}
+		status   int
+		reported bool
+	}{
+		{"value", "boom", http.StatusInternalServerError, true},
+		{"http error", router.NewHTTPError(http.StatusConflict, "taken"), http.StatusConflict, true},
+		{"broken pipe", fmt.Errorf("write: %w", syscall.EPIPE), http.StatusOK, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reported = nil
+			rec := httptest.NewRecorder()
+			recovery.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				panic(tt.panicked)
+			})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			if rec.Code != tt.status {
+				t.Errorf("Expected %d, got %d", tt.status, rec.Code)
+			}
+			if (len(reported) > 0) != tt.reported {
+				t.Errorf("Expected reported=%v, got %v", tt.reported, reported)
+			}
+		})
+	}
+
+	var panicErr *middleware.PanicError
+	recovery.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(42)
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if len(reported) != 1 || !errors.As(reported[0], &panicErr) || panicErr.Value != 42 {
+		t.Errorf("Expected a PanicError, got %v", reported)
+	}
+}
+
+// statusError has a status code but no public message
+type statusError struct{}
+
+func (statusError) Error() string   { return "lookup of tenant db-7 failed: password expired" }
+func (statusError) StatusCode() int { return http.StatusServiceUnavailable }
+
+func TestRecovery_HidesErrorsWithoutPublicMessage(t *testing.T) {
+	options := middleware.NewRecoveryOptions()
+	options.StackTrace = false
+	options.Logf = func(string, ...interface{}) {}
+	recovery := middleware.NewRecovery(*options)
+
+	for panicked, want := range map[error]string{
+		statusError{}: "Service Unavailable\n",
+		router.NewHTTPError(http.StatusConflict, "taken"): "taken\n",
+	} {
+		rec := httptest.NewRecorder()
+		recovery.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic(panicked)
+		})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		if rec.Body.String() != want {
+			t.Errorf("Expected %q, got %q", want, rec.Body.String())
+		}
+	}
+}
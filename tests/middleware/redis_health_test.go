@@ -0,0 +1,128 @@
+package middleware_test
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+	"github.com/hokamsingh/lessgo/internal/core/probe"
+)
+
+// fakeRedis answers every command with +PONG, enough for health checks.
+type fakeRedis struct {
+	listener net.Listener
+	mu       sync.Mutex
+	conns    []net.Conn
+}
+
+func startFakeRedis(t *testing.T, addr string) *fakeRedis {
+	t.Helper()
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	f := &fakeRedis{listener: listener}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			f.mu.Lock()
+			f.conns = append(f.conns, conn)
+			f.mu.Unlock()
+			go serveFakeRedis(conn)
+		}
+	}()
+	return f
+}
+
+func serveFakeRedis(conn net.Conn) {
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		for i := 0; i < 2*n; i++ {
+			if _, err := reader.ReadString('\n'); err != nil {
+				return
+			}
+		}
+		conn.Write([]byte("+PONG\r\n"))
+	}
+}
+
+func (f *fakeRedis) Close() {
+	f.listener.Close()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, conn := range f.conns {
+		conn.Close()
+	}
+}
+
+func TestCaching_DegradesWhileRedisIsDown(t *testing.T) {
+	listener, _ := net.Listen("tcp", "127.0.0.1:0")
+	addr := listener.Addr().String()
+	listener.Close()
+
+	client := redis.NewClient(&redis.Options{Addr: addr, DialTimeout: 50 * time.Millisecond, MaxRetries: -1})
+	health := middleware.NewRedisHealth(client, 10*time.Millisecond)
+	caching := middleware.NewCaching(client, time.Minute, true, middleware.WithCacheHealth(health))
+	handler := caching.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+
+	for i := 0; i < 3; i++ {
+		if rec := serveCached(handler); rec.Code != http.StatusOK || rec.Body.String() != "ok" {
+			t.Fatalf("Expected requests to be served without Redis, got %d %q", rec.Code, rec.Body.String())
+		}
+	}
+	stats := health.Stats()
+	if stats.Healthy || stats.Degraded != 2 {
+		t.Errorf("Expected Redis down and 2 degraded requests, got %+v", stats)
+	}
+
+	server := startFakeRedis(t, addr)
+	defer server.Close()
+	for i := 0; i < 100 && !health.Healthy(); i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !health.Healthy() {
+		t.Error("Expected Redis to be healthy again once it answers pings")
+	}
+}
+
+func TestRateLimiter_FallsBackToMemory(t *testing.T) {
+	server := startFakeRedis(t, "127.0.0.1:0")
+	client := redis.NewClient(&redis.Options{Addr: server.listener.Addr().String(), DialTimeout: 50 * time.Millisecond, MaxRetries: -1})
+	limiter := middleware.NewRateLimiter(middleware.RedisBacked, middleware.NewRedisConfig(client, 2, time.Minute))
+	server.Close()
+	if stats := probe.Default().Stats(probe.Redis(client).Name); stats.Successes != 1 {
+		t.Errorf("Expected the Redis of the limiter to be checked through the default prober, got %+v", stats)
+	}
+
+	handler := limiter.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	var codes []int
+	for i := 0; i < 3; i++ {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		codes = append(codes, rec.Code)
+	}
+	if codes[0] != http.StatusOK || codes[1] != http.StatusOK || codes[2] != http.StatusTooManyRequests {
+		t.Errorf("Expected the in-memory limit to apply while Redis is down, got %v", codes)
+	}
+	if middleware.RedisHealthOf(client).Healthy() {
+		t.Error("Expected Redis to be reported unavailable")
+	}
+}
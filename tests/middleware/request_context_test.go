@@ -0,0 +1,64 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+)
+
+// blockingCacheStore waits for the context of the lookup to end.
+type blockingCacheStore struct {
+	*middleware.MemoryCacheStore
+	deadline bool
+}
+
+func (s *blockingCacheStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	_, s.deadline = ctx.Deadline()
+	<-ctx.Done()
+	return nil, false, ctx.Err()
+}
+
+// blockingQuotaStore waits for the context of the increment to end.
+type blockingQuotaStore struct{}
+
+func (blockingQuotaStore) Increment(ctx context.Context, key string, expiresAt time.Time) (int64, error) {
+	<-ctx.Done()
+	return 0, ctx.Err()
+}
+
+func TestStoresUseTheRequestContext(t *testing.T) {
+	called := false
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+	store := &blockingCacheStore{MemoryCacheStore: middleware.NewMemoryCacheStore()}
+	caching := middleware.NewCaching(nil, time.Minute, true, middleware.WithCacheStore(store))
+	quota := middleware.NewQuota(blockingQuotaStore{}, *middleware.NewQuotaOptions(10, middleware.Daily))
+	deadline := middleware.NewDeadline(20 * time.Millisecond)
+
+	for name, m := range map[string]middleware.Middleware{"caching": caching, "quota": quota} {
+		called = false
+		w := httptest.NewRecorder()
+		start := time.Now()
+		deadline.Handle(m.Handle(handler)).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/products", nil))
+		if w.Code != http.StatusGatewayTimeout || called || time.Since(start) > time.Second {
+			t.Errorf("%s: expected a 504 at the deadline of the request, got %d after %s", name, w.Code, time.Since(start))
+		}
+	}
+	if !store.deadline {
+		t.Error("Expected the cache lookup to get the deadline of the request")
+	}
+
+	// Clients that leave stop the lookup too
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w := httptest.NewRecorder()
+	caching.Handle(handler).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/products", nil).WithContext(ctx))
+	if called || w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected the handler not to run for a canceled request, got %d", w.Code)
+	}
+}
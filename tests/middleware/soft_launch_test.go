@@ -0,0 +1,53 @@
+package middleware_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+)
+
+func TestSoftLaunchPercentage(t *testing.T) {
+	launch := middleware.NewSoftLaunch(*middleware.NewSoftLaunchOptions("search-v2", 30))
+
+	enabled := 0
+	for i := 0; i < 1000; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-User-ID", fmt.Sprintf("user-%d", i))
+		if launch.Enabled(req) {
+			enabled++
+		}
+		// The decision for a subject must be stable
+		if launch.Enabled(req) != launch.Enabled(req) {
+			t.Fatal("decision is not stable")
+		}
+	}
+	if enabled < 230 || enabled > 370 {
+		t.Errorf("expected about 30%% enabled, got %d of 1000", enabled)
+	}
+}
+
+func TestSoftLaunchCohortAndNotFound(t *testing.T) {
+	options := middleware.NewSoftLaunchOptions("search-v2", 0)
+	options.CohortHeader = "X-Beta"
+	options.CohortValues = []string{"internal"}
+	handler := middleware.NewSoftLaunch(*options).Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 outside the launch, got %d", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Beta", "internal")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected cohort to be admitted, got %d", rec.Code)
+	}
+}
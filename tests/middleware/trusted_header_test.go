@@ -0,0 +1,78 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/config"
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+)
+
+// serveTrusted sends a request with identity headers from remoteAddr through auth and returns
+// the status, the principal and the user header seen by the handler.
+func serveTrusted(auth *middleware.TrustedHeaderAuth, remoteAddr string) (int, *middleware.Principal, string) {
+	var principal *middleware.Principal
+	var userHeader string
+	handler := auth.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ = middleware.GetPrincipal(r.Context())
+		userHeader = r.Header.Get("X-Forwarded-User")
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = remoteAddr
+	req.Header.Set("X-Forwarded-User", "alice")
+	req.Header.Set("X-Forwarded-Email", "alice@example.com")
+	req.Header.Set("X-Forwarded-Groups", "admins, staff")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w.Code, principal, userHeader
+}
+
+func TestTrustedHeaderAuth_TrustedPeer(t *testing.T) {
+	auth := middleware.NewTrustedHeaderAuth(*middleware.NewTrustedHeaderOptions([]string{"10.0.0.5"}))
+	code, principal, _ := serveTrusted(auth, "10.0.0.5:4321")
+	if code != http.StatusOK || principal == nil {
+		t.Fatalf("Expected the principal of a trusted proxy, got %d %v", code, principal)
+	}
+	if principal.User != "alice" || principal.Email != "alice@example.com" || !reflect.DeepEqual(principal.Groups, []string{"admins", "staff"}) {
+		t.Errorf("Unexpected principal %+v", principal)
+	}
+	if !principal.HasGroup("admins") || principal.HasGroup("root") {
+		t.Errorf("Unexpected groups %v", principal.Groups)
+	}
+}
+
+func TestTrustedHeaderAuth_UntrustedPeerHeadersStripped(t *testing.T) {
+	options := middleware.NewTrustedHeaderOptions([]string{"10.0.0.5"})
+	code, principal, userHeader := serveTrusted(middleware.NewTrustedHeaderAuth(*options), "203.0.113.9:4321")
+	if code != http.StatusOK || principal != nil || userHeader != "" {
+		t.Errorf("Expected the identity headers of an untrusted peer to be stripped, got %d %v %q", code, principal, userHeader)
+	}
+
+	options.Required = true
+	if code, _, _ := serveTrusted(middleware.NewTrustedHeaderAuth(*options), "203.0.113.9:4321"); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 when an identity is required, got %d", code)
+	}
+}
+
+func TestTrustedHeaderAuth_CIDRs(t *testing.T) {
+	cfg := config.Config{"TRUSTED_PROXIES": "10.0.0.0/8, 2001:db8::/32, invalid", "TRUSTED_HEADER_GROUP_SEPARATOR": ";"}
+	auth := middleware.NewTrustedHeaderAuth(*middleware.TrustedHeaderOptionsFromConfig(cfg))
+	for addr, trusted := range map[string]bool{
+		"10.1.2.3:80":         true,
+		"[2001:db8::1]:80":    true,
+		"11.0.0.1:80":         false,
+		"[2001:db9::1]:80":    false,
+		"10.1.2.3":            true,
+		"not-an-address:1234": false,
+	} {
+		_, principal, _ := serveTrusted(auth, addr)
+		if (principal != nil) != trusted {
+			t.Errorf("%s: expected trusted %v, got principal %v", addr, trusted, principal)
+		}
+		if principal != nil && !reflect.DeepEqual(principal.Groups, []string{"admins, staff"}) {
+			t.Errorf("Expected the configured separator to be used, got %v", principal.Groups)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package middleware_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+)
+
+func uploadFile(t *testing.T, uploads *middleware.FileUploadMiddleware, filename string, content []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, _ := writer.CreateFormFile("file", filename)
+	part.Write(content)
+	writer.Close()
+	req := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	rec := httptest.NewRecorder()
+	uploads.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rec, req)
+	return rec
+}
+
+func pngImage(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buffer bytes.Buffer
+	if err := png.Encode(&buffer, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+func TestFileUpload_SniffsContent(t *testing.T) {
+	uploads := middleware.NewFileUploadMiddleware(t.TempDir(), 1<<20, []string{".jpg", ".png"})
+	if rec := uploadFile(t, uploads, "photo.jpg", []byte("<html><script>alert(1)</script></html>")); rec.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected HTML disguised as an image to be rejected, got %d", rec.Code)
+	}
+	if rec := uploadFile(t, uploads, "photo.png", pngImage(t, 2, 2)); rec.Code != http.StatusCreated {
+		t.Errorf("Expected a real image to be accepted, got %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestFileUpload_ImageLimitsAndReencode(t *testing.T) {
+	dir := t.TempDir()
+	uploads := middleware.NewFileUploadMiddleware(dir, 1<<20, []string{".png"},
+		middleware.WithImageLimits(100, 100, 5000), middleware.WithImageReencode())
+
+	if rec := uploadFile(t, uploads, "wide.png", pngImage(t, 101, 1)); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected an image over the width limit to be rejected, got %d", rec.Code)
+	}
+	if rec := uploadFile(t, uploads, "big.png", pngImage(t, 80, 80)); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected an image over the pixel limit to be rejected, got %d", rec.Code)
+	}
+
+	payload := append(pngImage(t, 10, 10), []byte("<?php system($_GET['c']); ?>")...)
+	rec := uploadFile(t, uploads, "small.png", payload)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected the image to be accepted, got %d %s", rec.Code, rec.Body.String())
+	}
+	name := strings.TrimPrefix(rec.Body.String(), "File uploaded successfully: ")
+	stored, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatalf("Failed to read the stored image: %v", err)
+	}
+	if bytes.Contains(stored, []byte("php")) {
+		t.Error("Expected the data appended to the image to be dropped")
+	}
+	if _, err := png.Decode(bytes.NewReader(stored)); err != nil {
+		t.Errorf("Expected a valid image, got %v", err)
+	}
+}
+
+// startFakeClamd answers INSTREAM scans, finding files containing EICAR.
+func startFakeClamd(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				if command, _ := reader.ReadString(0); command != "zINSTREAM\x00" {
+					return
+				}
+				var data []byte
+				for {
+					var size uint32
+					if binary.Read(reader, binary.BigEndian, &size) != nil {
+						return
+					}
+					if size == 0 {
+						break
+					}
+					chunk := make([]byte, size)
+					io.ReadFull(reader, chunk)
+					data = append(data, chunk...)
+				}
+				if bytes.Contains(data, []byte("EICAR")) {
+					conn.Write([]byte("stream: Eicar-Test-Signature FOUND\x00"))
+				} else {
+					conn.Write([]byte("stream: OK\x00"))
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestFileUpload_ClamAVScanner(t *testing.T) {
+	scanner := middleware.NewClamAVScanner("tcp", startFakeClamd(t))
+	uploads := middleware.NewFileUploadMiddleware(t.TempDir(), 1<<20, []string{".txt"}, middleware.WithUploadScanner(scanner))
+
+	if rec := uploadFile(t, uploads, "virus.txt", []byte("X5O!P%@AP[4\\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*")); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected the infected file to be rejected, got %d", rec.Code)
+	}
+	if rec := uploadFile(t, uploads, "notes.txt", []byte("hello")); rec.Code != http.StatusCreated {
+		t.Errorf("Expected the clean file to be accepted, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	down := middleware.NewFileUploadMiddleware(t.TempDir(), 1<<20, []string{".txt"},
+		middleware.WithUploadScanner(middleware.NewClamAVScanner("tcp", "127.0.0.1:1")))
+	if rec := uploadFile(t, down, "notes.txt", []byte("hello")); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected uploads to be refused while the scanner is down, got %d", rec.Code)
+	}
+}
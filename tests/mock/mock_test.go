@@ -0,0 +1,125 @@
+package mock_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/context"
+	"github.com/hokamsingh/lessgo/internal/core/mock"
+	"github.com/hokamsingh/lessgo/internal/core/router"
+)
+
+const stubsFile = `
+- method: GET
+  path: /users/{id}
+  headers: {X-User: "{{.Params.id}}"}
+  body: {id: "{{.Params.id}}", name: Ada, roles: [admin], page: "{{.Query.page}}"}
+- method: get
+  path: /users
+  when: {query: {status: banned}}
+  body: []
+- path: /users
+  body: [{id: "1"}]
+- method: POST
+  path: /users
+  status: 201
+  body: {name: "{{.Body.name}}", nickname: "{{.Body.nickname}}"}
+- path: /health
+  text: ok {{index .Header "X-Probe"}}
+- path: /logo
+  file: logo.svg
+`
+
+func serve(h http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestMockServer(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "api.yaml")
+	os.WriteFile(path, []byte(stubsFile), 0o644)
+	os.WriteFile(filepath.Join(dir, "logo.svg"), []byte("<svg></svg>"), 0o644)
+	server, err := mock.LoadServer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := router.NewRouter()
+	r.SubRouter("/api").Get("/users/me", func(ctx *context.Context) {
+		ctx.JSON(http.StatusOK, map[string]string{"id": "real"})
+	})
+	server.RegisterRoutes(r, "/api")
+	app := r.Handler()
+
+	w := serve(app, http.MethodGet, "/api/users/7?page=2", "")
+	if w.Code != http.StatusOK || w.Body.String() != `{"id":"7","name":"Ada","page":"2","roles":["admin"]}` || w.Header().Get("X-User") != "7" {
+		t.Errorf("Unexpected templated response %d %s %v", w.Code, w.Body.String(), w.Header())
+	}
+	if w := serve(app, http.MethodGet, "/api/users/me", ""); !strings.Contains(w.Body.String(), "real") {
+		t.Errorf("Expected the real handler to take precedence, got %s", w.Body.String())
+	}
+	if w := serve(app, http.MethodGet, "/api/users?status=banned", ""); w.Body.String() != `[]` {
+		t.Errorf("Expected the conditional stub, got %s", w.Body.String())
+	}
+	if w := serve(app, http.MethodGet, "/api/users", ""); w.Body.String() != `[{"id":"1"}]` {
+		t.Errorf("Expected the fallback stub, got %s", w.Body.String())
+	}
+	w = serve(app, http.MethodPost, "/api/users", `{"name":"Bob"}`)
+	if w.Code != http.StatusCreated || w.Body.String() != `{"name":"Bob","nickname":""}` {
+		t.Errorf("Expected the body in the response, got %d %s", w.Code, w.Body.String())
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/health", nil)
+	req.Header.Set("X-Probe", "k8s")
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, req)
+	if w.Body.String() != "ok k8s" || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain") {
+		t.Errorf("Unexpected text response %q %s", w.Body.String(), w.Header().Get("Content-Type"))
+	}
+	if w := serve(app, http.MethodGet, "/api/logo", ""); w.Body.String() != "<svg></svg>" || w.Header().Get("Content-Type") != "image/svg+xml" {
+		t.Errorf("Unexpected file response %q %s", w.Body.String(), w.Header().Get("Content-Type"))
+	}
+}
+
+func TestMockServerStubs(t *testing.T) {
+	server, err := mock.NewServer(mock.Stub{Path: "/slow", Delay: 20 * time.Millisecond, When: mock.When{Header: map[string]string{"x-variant": "b"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := router.NewRouter()
+	server.RegisterRoutes(r, "")
+
+	if w := serve(r.Handler(), http.MethodGet, "/slow", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 without a matching stub, got %d", w.Code)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/slow", nil)
+	req.Header.Set("X-Variant", "b")
+	w := httptest.NewRecorder()
+	start := time.Now()
+	r.Handler().ServeHTTP(w, req)
+	if w.Code != http.StatusOK || time.Since(start) < 20*time.Millisecond {
+		t.Errorf("Expected a delayed 200, got %d after %s", w.Code, time.Since(start))
+	}
+
+	for _, stub := range []mock.Stub{
+		{Path: "users"},
+		{Path: "/users", Method: "TRACE"},
+		{Path: "/users", Text: "a", Body: "b"},
+		{Path: "/users", Text: "{{.Missing"},
+	} {
+		if _, err := mock.NewServer(stub); err == nil {
+			t.Errorf("Expected an error for %+v", stub)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package notify_test
+
+import (
+	"bufio"
+	stdcontext "context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/hokamsingh/lessgo/internal/core/messaging"
+	"github.com/hokamsingh/lessgo/internal/core/notify"
+	"github.com/hokamsingh/lessgo/internal/core/router"
+)
+
+type message struct {
+	Type    string            `json:"type"`
+	ID      string            `json:"id"`
+	Payload map[string]string `json:"payload"`
+}
+
+func userHeader(r *http.Request) string {
+	return r.Header.Get("X-User")
+}
+
+func newServer(t *testing.T, notifier *notify.Notifier) *httptest.Server {
+	r := router.NewRouter()
+	r.ServeNotifications(notifier)
+	server := httptest.NewServer(r.Handler())
+	t.Cleanup(func() {
+		notifier.Close()
+		server.Close()
+	})
+	return server
+}
+
+// readEvents returns the notifications of an SSE stream.
+func readEvents(t *testing.T, server *httptest.Server, user string) <-chan message {
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/notifications", nil)
+	req.Header.Set("X-User", user)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %s", resp.Header.Get("Content-Type"))
+	}
+	events := make(chan message, 16)
+	go func() {
+		defer resp.Body.Close()
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+				var msg message
+				json.Unmarshal([]byte(data), &msg)
+				events <- msg
+			}
+		}
+	}()
+	return events
+}
+
+func receive(t *testing.T, events <-chan message) message {
+	select {
+	case msg := <-events:
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for a notification")
+		return message{}
+	}
+}
+
+func waitOnline(t *testing.T, notifier *notify.Notifier, user string) {
+	deadline := time.Now().Add(2 * time.Second)
+	for !notifier.Online(user) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %s to be online", user)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestNotifier_SSE(t *testing.T) {
+	var mu sync.Mutex
+	var acked []string
+	notifier := notify.New("/notifications", notify.WithUserFunc(userHeader), notify.WithOnAck(func(userID, id string) {
+		mu.Lock()
+		defer mu.Unlock()
+		acked = append(acked, userID+"/"+id)
+	}))
+	server := newServer(t, notifier)
+	ctx := stdcontext.Background()
+
+	// Notifications sent while the user is offline are delivered on connect
+	offline, err := notifier.Notify(ctx, "ann", map[string]string{"title": "welcome"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := readEvents(t, server, "ann")
+	if msg := receive(t, events); msg.ID != offline || msg.Type != "notification" || msg.Payload["title"] != "welcome" {
+		t.Errorf("Unexpected notification %+v", msg)
+	}
+	waitOnline(t, notifier, "ann")
+	live, _ := notifier.Notify(ctx, "ann", map[string]string{"title": "shipped"})
+	notifier.Notify(ctx, "bob", map[string]string{"title": "not for ann"})
+	if msg := receive(t, events); msg.ID != live {
+		t.Errorf("Expected the live notification, got %+v", msg)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/notifications/ack", strings.NewReader(`{"ids":["`+offline+`","`+live+`"]}`))
+	req.Header.Set("X-User", "ann")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("Expected 204, got %d", resp.StatusCode)
+	}
+	if pending, _ := notifier.Pending(ctx, "ann"); len(pending) != 0 {
+		t.Errorf("Expected no pending notifications after ack, got %d", len(pending))
+	}
+	if pending, _ := notifier.Pending(ctx, "bob"); len(pending) != 1 {
+		t.Errorf("Expected bob's notification to stay pending, got %d", len(pending))
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(acked) != 2 || acked[0] != "ann/"+offline {
+		t.Errorf("Unexpected acknowledgments %v", acked)
+	}
+}
+
+func TestNotifier_RequiresUser(t *testing.T) {
+	server := newServer(t, notify.New("/notifications", notify.WithUserFunc(userHeader)))
+	resp, err := http.Get(server.URL + "/notifications")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401, got %d", resp.StatusCode)
+	}
+}
+
+func TestNotifier_WebSocket(t *testing.T) {
+	notifier := notify.New("/notifications", notify.WithUserFunc(userHeader))
+	server := newServer(t, notifier)
+	ctx := stdcontext.Background()
+	pending, _ := notifier.Notify(ctx, "ann", map[string]string{"title": "queued"})
+
+	header := http.Header{"X-User": {"ann"}}
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/notifications", header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	var msg message
+	if err := ws.ReadJSON(&msg); err != nil || msg.ID != pending || msg.Payload["title"] != "queued" {
+		t.Fatalf("Expected the queued notification, got %+v (%v)", msg, err)
+	}
+	live, _ := notifier.Notify(ctx, "ann", map[string]string{"title": "live"})
+	if err := ws.ReadJSON(&msg); err != nil || msg.ID != live {
+		t.Fatalf("Expected the live notification, got %+v (%v)", msg, err)
+	}
+
+	ws.WriteJSON(map[string]interface{}{"type": "ack", "ids": []string{pending, live}})
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		left, _ := notifier.Pending(ctx, "ann")
+		if len(left) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the acknowledged notifications to be removed, %d left", len(left))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestNotifier_Broker(t *testing.T) {
+	broker := messaging.NewMemoryBroker()
+	defer broker.Close()
+	store := notify.NewMemoryStore(0, 0)
+	first := notify.New("/notifications", notify.WithUserFunc(userHeader), notify.WithStore(store), notify.WithBroker(broker, ""))
+	second := notify.New("/notifications", notify.WithUserFunc(userHeader), notify.WithStore(store), notify.WithBroker(broker, ""))
+	for _, notifier := range []*notify.Notifier{first, second} {
+		if err := notifier.Start(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server := newServer(t, second)
+	events := readEvents(t, server, "ann")
+	waitOnline(t, second, "ann")
+
+	id, err := first.Notify(stdcontext.Background(), "ann", map[string]string{"title": "from another instance"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg := receive(t, events); msg.ID != id {
+		t.Errorf("Expected the notification published by the other instance, got %+v", msg)
+	}
+}
+
+func TestMemoryStore_Limits(t *testing.T) {
+	store := notify.NewMemoryStore(2, time.Hour)
+	ctx := stdcontext.Background()
+	for _, id := range []string{"a", "b", "c"} {
+		store.Push(ctx, notify.Notification{ID: id, UserID: "ann", Time: time.Now()})
+	}
+	store.Push(ctx, notify.Notification{ID: "old", UserID: "bob", Time: time.Now().Add(-2 * time.Hour)})
+
+	pending, _ := store.Pending(ctx, "ann")
+	if len(pending) != 2 || pending[0].ID != "b" || pending[1].ID != "c" {
+		t.Errorf("Expected the newest two notifications, got %+v", pending)
+	}
+	if pending, _ := store.Pending(ctx, "bob"); len(pending) != 0 {
+		t.Errorf("Expected expired notifications to be dropped, got %+v", pending)
+	}
+	if ok, _ := store.Ack(ctx, "bob", "b"); ok {
+		t.Error("Expected users not to acknowledge the notifications of others")
+	}
+	if ok, _ := store.Ack(ctx, "ann", "b"); !ok {
+		t.Error("Expected the notification to be acknowledged")
+	}
+}
@@ -0,0 +1,94 @@
+package password_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/password"
+	"golang.org/x/crypto/argon2"
+)
+
+// Cheap parameters keep the tests fast
+var testParams = password.Params{Memory: 64, Iterations: 1, Parallelism: 2, SaltLength: 16, KeyLength: 32}
+
+func TestHasher_InteroperatesWithArgon2id(t *testing.T) {
+	hash, err := password.NewHasher(testParams).Hash("correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(hash, "$")
+	salt, _ := base64.RawStdEncoding.DecodeString(parts[4])
+	key := argon2.IDKey([]byte("correct horse battery staple"), salt, 1, 64, 2, 32)
+	if parts[5] != base64.RawStdEncoding.EncodeToString(key) {
+		t.Errorf("Expected the key of golang.org/x/crypto/argon2, got %s", parts[5])
+	}
+}
+
+func TestHasher_RejectsExcessiveParameters(t *testing.T) {
+	hasher := password.NewHasher(testParams)
+	salt := base64.RawStdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 16))
+	key := base64.RawStdEncoding.EncodeToString(bytes.Repeat([]byte{2}, 32))
+	for _, params := range []string{"m=4294967295,t=1,p=1", "m=64,t=4294967295,p=1", "m=64,t=1,p=0"} {
+		hash := "$argon2id$v=19$" + params + "$" + salt + "$" + key
+		if _, err := hasher.Verify("secret", hash); err != password.ErrInvalidHash {
+			t.Errorf("Expected ErrInvalidHash for %s, got %v", params, err)
+		}
+	}
+	long := "$argon2id$v=19$m=64,t=1,p=1$" + strings.Repeat("A", 4096) + "$" + key
+	if _, err := hasher.Verify("secret", long); err != password.ErrInvalidHash {
+		t.Errorf("Expected ErrInvalidHash for a long salt, got %v", err)
+	}
+}
+
+func TestHasher_HashAndVerify(t *testing.T) {
+	hasher := password.NewHasher(testParams)
+	hash, err := hasher.Hash("correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=64,t=1,p=2$") {
+		t.Errorf("Expected a PHC string, got %s", hash)
+	}
+	if other, _ := hasher.Hash("correct horse battery staple"); other == hash {
+		t.Error("Expected a random salt per hash")
+	}
+
+	if ok, err := hasher.Verify("correct horse battery staple", hash); !ok || err != nil {
+		t.Errorf("Expected the password to match, got %v %v", ok, err)
+	}
+	if ok, _ := hasher.Verify("Correct horse battery staple", hash); ok {
+		t.Error("Expected another password not to match")
+	}
+	if _, err := hasher.Verify("x", "5f4dcc3b5aa765d61d8327deb882cf99"); err != password.ErrInvalidHash {
+		t.Errorf("Expected ErrInvalidHash, got %v", err)
+	}
+
+	peppered := password.NewHasher(testParams, password.WithPepper([]byte("pepper")))
+	if ok, _ := peppered.Verify("correct horse battery staple", hash); ok {
+		t.Error("Expected hashes without pepper not to verify with one")
+	}
+}
+
+func TestHasher_NeedsRehash(t *testing.T) {
+	old := password.NewHasher(testParams)
+	hash, _ := old.Hash("secret")
+	if old.NeedsRehash(hash) {
+		t.Error("Expected a hash with the current parameters to be kept")
+	}
+
+	stronger := testParams
+	stronger.Iterations = 2
+	current := password.NewHasher(stronger)
+	if !current.NeedsRehash(hash) {
+		t.Error("Expected a hash with older parameters to need a rehash")
+	}
+	// Old hashes still verify until they are replaced
+	if ok, _ := current.Verify("secret", hash); !ok {
+		t.Error("Expected an old hash to verify")
+	}
+	if !current.NeedsRehash("legacy-sha256") {
+		t.Error("Expected foreign hashes to need a rehash")
+	}
+}
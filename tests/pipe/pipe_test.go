@@ -0,0 +1,91 @@
+package pipe_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/context"
+	"github.com/hokamsingh/lessgo/internal/core/pipe"
+	"github.com/hokamsingh/lessgo/internal/core/router"
+	"github.com/hokamsingh/lessgo/internal/core/sanitize"
+)
+
+type createUserDTO struct {
+	Name  string `json:"name" validate:"required,max=10"`
+	Email string `json:"email" validate:"required,email"`
+	Role  string `json:"role" validate:"oneof=admin member"`
+}
+
+func newJSONRequest(body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func TestValidatePipe(t *testing.T) {
+	var got *createUserDTO
+	handler := context.WithPipes(func(ctx *context.Context) {
+		got = pipe.Validated(ctx).(*createUserDTO)
+		ctx.JSON(http.StatusCreated, got)
+	}, pipe.Trim(), pipe.Defaults(map[string]interface{}{"role": "member"}), pipe.Validate(createUserDTO{}))
+
+	rec := httptest.NewRecorder()
+	handler(context.NewContext(newJSONRequest(`{"name":"  Ann  ","email":"ann@example.com"}`), rec))
+	if rec.Code != http.StatusCreated || got == nil || got.Name != "Ann" || got.Role != "member" {
+		t.Fatalf("unexpected result %d %+v", rec.Code, got)
+	}
+
+	rec = httptest.NewRecorder()
+	handler(context.NewContext(newJSONRequest(`{"name":"Ann","email":"not-an-email","role":"root"}`), rec))
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d", rec.Code)
+	}
+	var body struct {
+		Error context.APIError `json:"error"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	details, _ := body.Error.Details.(map[string]interface{})
+	if details["email"] == nil || details["role"] == nil {
+		t.Errorf("expected email and role errors, got %v", body.Error.Details)
+	}
+}
+
+func TestRouterUsePipe(t *testing.T) {
+	r := router.NewRouter()
+	r.UsePipe(pipe.SnakeCase(), pipe.FilterFields("fields"))
+	r.Get("/user", func(ctx *context.Context) {
+		ctx.JSON(http.StatusOK, map[string]interface{}{"userID": 1, "firstName": "Ann", "lastName": "Lee"})
+	})
+
+	rec := httptest.NewRecorder()
+	r.Mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/user?fields=user_id,first_name", nil))
+	if got := strings.TrimSpace(rec.Body.String()); got != `{"first_name":"Ann","user_id":1}` {
+		t.Errorf("unexpected body %s", got)
+	}
+}
+
+func TestSanitizePipe(t *testing.T) {
+	var got map[string]interface{}
+	handler := context.WithPipes(func(ctx *context.Context) {
+		ctx.Body(&got)
+		ctx.JSON(http.StatusCreated, got)
+	}, pipe.Sanitize(map[string]string{"title": sanitize.Strict, "body": sanitize.UGC}))
+
+	rec := httptest.NewRecorder()
+	handler(context.NewContext(newJSONRequest(`{"title":"<b>Hi</b>","body":"<p onclick=\"x()\">ok</p>","note":"onclick= is fine"}`), rec))
+	if got["title"] != "Hi" || got["body"] != "<p>ok</p>" || got["note"] != "onclick= is fine" {
+		t.Errorf("Expected only the declared fields to be sanitized, got %v", got)
+	}
+}
+
+func TestToSnakeCase(t *testing.T) {
+	cases := map[string]string{"userID": "user_id", "HTTPServer": "http_server", "createdAt": "created_at", "id": "id"}
+	for in, want := range cases {
+		if got := pipe.ToSnakeCase(in); got != want {
+			t.Errorf("ToSnakeCase(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package pipeline_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+	"github.com/hokamsingh/lessgo/internal/core/pipeline"
+	"github.com/hokamsingh/lessgo/internal/core/router"
+)
+
+// tagMiddleware appends its tag to the X-Order response header.
+type tagMiddleware struct{ tag string }
+
+func (t tagMiddleware) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("X-Order", t.tag)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func TestLoadPipeline(t *testing.T) {
+	registry := pipeline.NewRegistry()
+	registry.Register("tag", func(decode pipeline.Decoder) (middleware.Middleware, error) {
+		var options struct {
+			Tag string `yaml:"tag"`
+		}
+		if err := decode(&options); err != nil {
+			return nil, err
+		}
+		return tagMiddleware{tag: options.Tag}, nil
+	})
+
+	mws, err := registry.Load([]byte(`
+middleware:
+  - name: tag
+    options: {tag: first}
+  - name: tag
+    options: {tag: second}
+    skip: ["/health"]
+  - name: xss
+    enabled: false
+  - name: concurrency_limit
+    options: {limit: 10, queue: 5, timeout: 2s}
+`))
+	if err != nil {
+		t.Fatalf("Failed to load pipeline: %v", err)
+	}
+	if len(mws) != 3 {
+		t.Fatalf("Expected 3 enabled middlewares, got %d", len(mws))
+	}
+
+	r := router.NewRouter(router.WithMiddleware(mws...))
+	r.Mux.HandleFunc("/{path}", func(w http.ResponseWriter, r *http.Request) {})
+	handler := r.Handler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders", nil))
+	if got := strings.Join(rec.Header().Values("X-Order"), ","); got != "first,second" {
+		t.Errorf("Expected middlewares in file order, got %q", got)
+	}
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if got := strings.Join(rec.Header().Values("X-Order"), ","); got != "first" {
+		t.Errorf("Expected skipped middleware on /health, got %q", got)
+	}
+}
+
+func TestLoadPipelineErrors(t *testing.T) {
+	registry := pipeline.NewRegistry()
+	if _, err := registry.Load([]byte("middleware:\n  - name: missing\n")); err == nil {
+		t.Error("Expected an error for an unknown middleware")
+	}
+	if _, err := registry.Load([]byte("middleware:\n  - name: rate_limit\n    options: {limit: lots}\n")); err == nil {
+		t.Error("Expected an error for invalid options")
+	}
+	if _, err := registry.Load([]byte("middlewares: []\n")); err == nil {
+		t.Error("Expected an error for an unknown key")
+	}
+}
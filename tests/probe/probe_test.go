@@ -0,0 +1,144 @@
+package probe_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/probe"
+)
+
+func TestProber_TCPAndPercentiles(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	p := probe.NewProber(probe.WithWindowSize(4))
+	p.Add(probe.Target{Name: "db", Kind: probe.TCP, Address: listener.Addr().String()})
+	for i := 0; i < 6; i++ {
+		if result := p.Check(context.Background(), "db"); !result.Healthy {
+			t.Fatalf("Expected the listener to be reachable, got %v", result.Err)
+		}
+	}
+	listener.Close()
+	if result := p.Check(context.Background(), "db"); result.Healthy {
+		t.Error("Expected a closed port to be unhealthy")
+	}
+
+	stats := p.Stats("db")
+	if stats.Healthy || stats.Successes != 6 || stats.Failures != 1 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+	if stats.P50 <= 0 || stats.P50 > stats.P90 || stats.P90 > stats.P99 {
+		t.Errorf("Expected ordered percentiles, got %v %v %v", stats.P50, stats.P90, stats.P99)
+	}
+	if result := p.Check(context.Background(), "unknown"); result.Err == nil {
+		t.Error("Expected an unknown target to fail")
+	}
+}
+
+func TestProber_HTTPSReusesTransport(t *testing.T) {
+	var conns int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	server.StartTLS()
+	defer server.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+
+	p := probe.NewProber()
+	config := &tls.Config{RootCAs: roots}
+	p.Add(probe.Target{Name: "api", Kind: probe.HTTP, URL: server.URL + "/health", TLSConfig: config})
+	p.Add(probe.Target{Name: "down", Kind: probe.HTTP, URL: server.URL + "/down", TLSConfig: config})
+	for i := 0; i < 5; i++ {
+		if result := p.Check(context.Background(), "api"); !result.Healthy {
+			t.Fatalf("Expected the HTTPS check to pass, got %v", result.Err)
+		}
+	}
+	if n := atomic.LoadInt32(&conns); n != 1 {
+		t.Errorf("Expected the checks of a target to share one connection, got %d", n)
+	}
+	if result := p.Check(context.Background(), "down"); result.Healthy {
+		t.Error("Expected a 503 to be unhealthy")
+	}
+}
+
+func TestProber_CheckTargetAndMetrics(t *testing.T) {
+	p := probe.NewProber()
+	var fail atomic.Bool
+	target := probe.Target{Name: "redis://cache:6379", Kind: probe.Func, Check: func(ctx context.Context) error {
+		if fail.Load() {
+			return errors.New("connection refused")
+		}
+		return nil
+	}}
+	if result := p.CheckTarget(context.Background(), target); !result.Healthy {
+		t.Fatalf("Expected the check to pass, got %v", result.Err)
+	}
+	fail.Store(true)
+	// Checking the target again keeps its statistics
+	p.CheckTarget(context.Background(), target)
+	if stats := p.Stats(target.Name); stats.Successes != 1 || stats.Failures != 1 || stats.Healthy {
+		t.Errorf("Expected one success and one failure, got %+v", stats)
+	}
+	if result := p.CheckTarget(context.Background(), probe.Target{Name: "nil", Kind: probe.Func}); result.Healthy {
+		t.Error("Expected a target without a check function to fail")
+	}
+
+	w := httptest.NewRecorder()
+	p.MetricsHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, want := range []string{
+		`lessgo_probe_healthy{target="redis://cache:6379"} 0`,
+		`lessgo_probe_checks_total{target="redis://cache:6379",result="success"} 1`,
+		`lessgo_probe_checks_total{target="redis://cache:6379",result="failure"} 1`,
+		`lessgo_probe_latency_seconds{target="redis://cache:6379",quantile="0.99"}`,
+	} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("Expected %s in\n%s", want, w.Body.String())
+		}
+	}
+}
+
+func TestProber_StartAndStop(t *testing.T) {
+	p := probe.NewProber()
+	var checks atomic.Int32
+	p.Add(probe.Target{Name: "job", Kind: probe.Func, Check: func(ctx context.Context) error {
+		checks.Add(1)
+		return nil
+	}})
+	p.Start(5 * time.Millisecond)
+	deadline := time.Now().Add(time.Second)
+	for checks.Load() < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	p.Stop()
+	if !p.Healthy("job") || checks.Load() < 3 {
+		t.Errorf("Expected periodic checks, got %d", checks.Load())
+	}
+}
@@ -0,0 +1,182 @@
+package replay_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/context"
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+	"github.com/hokamsingh/lessgo/internal/core/replay"
+	"github.com/hokamsingh/lessgo/internal/core/router"
+)
+
+func send(h http.Handler, method, path, contentType, body string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.RemoteAddr = "127.0.0.1:1234"
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+// app fails orders without items, and echoes the authenticated user.
+func app(recorder *replay.Recorder) *router.Router {
+	r := router.NewRouter()
+	r.Use(recorder)
+	r.Post("/orders", func(ctx *context.Context) {
+		var order struct {
+			Items []string `json:"items"`
+		}
+		if err := ctx.Body(&order); err != nil || len(order.Items) == 0 {
+			ctx.Error(http.StatusInternalServerError, "no items")
+			return
+		}
+		ctx.JSON(http.StatusCreated, map[string]interface{}{"items": order.Items, "user": ctx.GetHeader("Authorization")})
+	})
+	r.Get("/ping", func(ctx *context.Context) { ctx.Send("pong") })
+	return r
+}
+
+func TestRecorder(t *testing.T) {
+	options := replay.NewOptions()
+	options.Capacity = 2
+	options.File = filepath.Join(t.TempDir(), "requests.ndjson")
+	recorder, err := replay.NewRecorder(*options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer recorder.Close()
+	h := app(recorder).Handler()
+
+	send(h, http.MethodGet, "/ping?token=abc&page=2", "", "")
+	send(h, http.MethodPost, "/orders", "application/json", `{"items":[],"password":"hunter2"}`, "Authorization", "Bearer s3cret")
+	send(h, http.MethodPost, "/orders", "application/x-www-form-urlencoded", "token=abc&items=x")
+	send(h, http.MethodGet, "/health", "", "")
+
+	records := recorder.Records()
+	if len(records) != 2 || records[0].Method != http.MethodPost || records[0].Status != http.StatusInternalServerError {
+		t.Fatalf("Expected the last 2 requests, got %+v", records)
+	}
+	failed := records[0]
+	if strings.Contains(failed.Body, "hunter2") || !strings.Contains(failed.Body, `"items":[]`) {
+		t.Errorf("Expected the password to be redacted, got %s", failed.Body)
+	}
+	if failed.Header.Get("Authorization") == "Bearer s3cret" || failed.Route != "/orders" {
+		t.Errorf("Expected the credentials to be redacted and the route, got %+v", failed)
+	}
+	if strings.Contains(records[1].Body, "abc") {
+		t.Errorf("Expected the form token to be redacted, got %s", records[1].Body)
+	}
+	if record, ok := recorder.Record(failed.ID); !ok || record.URL != "/orders" {
+		t.Errorf("Expected the record by ID, got %+v", record)
+	}
+
+	fromFile, err := replay.LoadRecords(options.File)
+	if err != nil || len(fromFile) != 3 || !strings.Contains(fromFile[0].URL, "page=2") || strings.Contains(fromFile[0].URL, "abc") {
+		t.Fatalf("Expected the 3 requests in the file, got %v %+v", err, fromFile)
+	}
+}
+
+func TestRecorder_MultipartAndForgedReplays(t *testing.T) {
+	recorder, _ := replay.NewRecorder(*replay.NewOptions())
+	r := router.NewRouter()
+	r.Use(recorder)
+	r.Use(middleware.NewBodyCapture(1 << 20))
+	r.Post("/login", func(ctx *context.Context) {
+		raw, err := middleware.RawBody(ctx.Req)
+		if err != nil || ctx.Req.FormValue("user") != "ann" || !bytes.Contains(raw, []byte("hunter2")) {
+			ctx.Error(http.StatusBadRequest, "unreadable body")
+			return
+		}
+		ctx.Send("ok")
+	})
+	h := r.Handler()
+
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	writer.WriteField("user", "ann")
+	writer.WriteField("password", "hunter2")
+	writer.Close()
+	if w := send(h, http.MethodPost, "/login", writer.FormDataContentType(), form.String()); w.Code != http.StatusOK {
+		t.Fatalf("Expected the handler to read the captured body, got %d %s", w.Code, w.Body.String())
+	}
+	records := recorder.Records()
+	if len(records) != 1 || strings.Contains(records[0].Body, "hunter2") || !strings.Contains(records[0].Body, "ann") {
+		t.Fatalf("Expected the multipart password to be redacted, got %+v", records)
+	}
+
+	send(h, http.MethodPost, "/login", writer.FormDataContentType(), form.String(), replay.Header, "forged")
+	if len(recorder.Records()) != 2 {
+		t.Error("Expected requests with an unknown replay ID to be recorded")
+	}
+	send(h, http.MethodPost, "/login", writer.FormDataContentType(), form.String(), replay.Header, records[0].ID)
+	if len(recorder.Records()) != 2 {
+		t.Error("Expected replays of known records not to be recorded")
+	}
+}
+
+func TestReplay(t *testing.T) {
+	options := replay.NewOptions()
+	options.MinStatus = http.StatusInternalServerError
+	recorder, _ := replay.NewRecorder(*options)
+	r := app(recorder)
+	h := r.Handler()
+	send(h, http.MethodGet, "/ping", "", "")
+	send(h, http.MethodPost, "/orders", "application/json", `{"items":[]}`)
+	records := recorder.Records()
+	if len(records) != 1 {
+		t.Fatalf("Expected only the failed request, got %+v", records)
+	}
+
+	result, err := replay.Replay(h, records[0], nil)
+	if err != nil || result.RecordedStatus != http.StatusInternalServerError || result.Status != http.StatusInternalServerError {
+		t.Fatalf("Expected the failure to be reproduced, got %v %+v", err, result)
+	}
+	if len(recorder.Records()) != 1 {
+		t.Error("Expected replays not to be recorded")
+	}
+
+	server := httptest.NewServer(h)
+	defer server.Close()
+	result, err = replay.ReplayTo(server.Client(), server.URL, records[0], func(req *http.Request) {
+		req.Header.Set("Authorization", "Bearer t")
+	})
+	if err != nil || result.Status != http.StatusInternalServerError {
+		t.Errorf("Expected the failure over HTTP, got %v %+v", err, result)
+	}
+
+	if err := r.ExposeRequestRecorder(recorder); err == nil {
+		t.Fatal("Expected the recorder endpoints to require authentication")
+	}
+	if err := r.ExposeRequestRecorder(recorder, middleware.NewBearerAuth("admin")); err != nil {
+		t.Fatal(err)
+	}
+	admin := r.Admin().Handler()
+	if w := send(admin, http.MethodGet, "/requests", "", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without the token, got %d", w.Code)
+	}
+	w := send(admin, http.MethodGet, "/requests/"+records[0].ID, "", "", "Authorization", "Bearer admin")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), records[0].ID) {
+		t.Errorf("Expected the record, got %d %s", w.Code, w.Body.String())
+	}
+	w = send(admin, http.MethodPost, "/requests/"+records[0].ID+"/replay", "application/json", `{"headers":{"X-Debug":"1"}}`, "Authorization", "Bearer admin")
+	var replayed replay.Result
+	json.Unmarshal(w.Body.Bytes(), &replayed)
+	if w.Code != http.StatusOK || replayed.Status != http.StatusInternalServerError {
+		t.Errorf("Expected the replay result, got %d %s", w.Code, w.Body.String())
+	}
+	if w := send(admin, http.MethodPost, "/requests/unknown/replay", "", "", "Authorization", "Bearer admin"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown record, got %d", w.Code)
+	}
+}
@@ -0,0 +1,84 @@
+package reporting_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/context"
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+	"github.com/hokamsingh/lessgo/internal/core/reporting"
+)
+
+func TestReporting_RecoveryAndContextError(t *testing.T) {
+	var events []reporting.Event
+	reporting.SetReporter(reporting.ReporterFunc(func(event reporting.Event) {
+		events = append(events, event)
+	}))
+	defer reporting.SetReporter(nil)
+
+	options := middleware.NewRecoveryOptions()
+	options.StackTrace = false
+	options.Logf = func(string, ...interface{}) {}
+	options.Reporter = reporting.PanicHook(nil)
+	req := httptest.NewRequest(http.MethodGet, "/orders/7", nil)
+	req.Header.Set("X-Request-ID", "req-1")
+	req = middleware.SetValue(req, middleware.UserKey, "ann")
+	middleware.NewRecovery(*options).Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})).ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(events) != 1 {
+		t.Fatalf("Expected one event, got %d", len(events))
+	}
+	event := events[0]
+	if event.Level != reporting.LevelFatal || event.User != "ann" || event.RequestID != "req-1" ||
+		event.Route != "/orders/7" || len(event.Stack) == 0 {
+		t.Errorf("Unexpected panic event %+v", event)
+	}
+
+	ctx := context.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+	ctx.Error(http.StatusBadRequest, "invalid")
+	ctx = context.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+	ctx.Error(http.StatusBadGateway, "upstream down")
+	if len(events) != 2 || events[1].Status != http.StatusBadGateway || events[1].Error.Error() != "upstream down" {
+		t.Errorf("Expected only the 5xx error to be reported, got %+v", events)
+	}
+}
+
+func TestSentry(t *testing.T) {
+	received := make(chan map[string]interface{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/42/store/" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		if auth := r.Header.Get("X-Sentry-Auth"); auth == "" {
+			t.Error("Expected the X-Sentry-Auth header")
+		}
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		received <- payload
+	}))
+	defer server.Close()
+
+	sentry, err := reporting.NewSentry("http://public@"+server.Listener.Addr().String()+"/42",
+		reporting.WithEnvironment("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := reporting.NewEvent(httptest.NewRequest(http.MethodPost, "/pay", nil), errors.New("card declined"))
+	sentry.Report(event)
+	sentry.Close(time.Second)
+
+	payload := <-received
+	if payload["environment"] != "test" || payload["transaction"] != "POST /pay" {
+		t.Errorf("Unexpected payload %v", payload)
+	}
+
+	if _, err := reporting.NewSentry("https://sentry.io/42"); err == nil {
+		t.Error("Expected an error for a DSN without key")
+	}
+}
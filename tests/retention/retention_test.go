@@ -0,0 +1,79 @@
+package retention_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/retention"
+)
+
+type auditLog struct {
+	records []interface{}
+}
+
+func (a *auditLog) Record(record interface{}) error {
+	a.records = append(a.records, record)
+	return nil
+}
+
+func TestEnforce(t *testing.T) {
+	audit := &auditLog{}
+	engine := retention.NewEngine(retention.WithAuditLog(audit))
+
+	var cutoff time.Time
+	engine.Register(retention.Dataset{
+		Name: "sessions",
+		TTL:  time.Hour,
+		Scrub: func(ctx context.Context, before time.Time) (int, error) {
+			cutoff = before
+			return 3, nil
+		},
+	})
+	engine.Register(retention.Dataset{
+		Name: "uploads",
+		TTL:  time.Hour,
+		Scrub: func(ctx context.Context, before time.Time) (int, error) {
+			return 0, errors.New("storage unavailable")
+		},
+	})
+
+	reports := engine.Enforce(context.Background())
+	if len(reports) != 2 {
+		t.Fatalf("expected 2 reports, got %d", len(reports))
+	}
+	if reports[0].Dataset != "sessions" || reports[0].Affected != 3 {
+		t.Errorf("unexpected report %+v", reports[0])
+	}
+	if time.Since(cutoff) < time.Hour {
+		t.Errorf("cutoff %v is not an hour ago", cutoff)
+	}
+	if reports[1].Error == "" {
+		t.Error("expected the uploads failure to be reported")
+	}
+	if len(audit.records) != 2 {
+		t.Errorf("expected 2 audit records, got %d", len(audit.records))
+	}
+}
+
+func TestEnforceSkipsLockedDataset(t *testing.T) {
+	locker := retention.NewLocalLocker()
+	engine := retention.NewEngine(retention.WithLocker(locker))
+	engine.Register(retention.Dataset{
+		Name:  "sessions",
+		TTL:   time.Hour,
+		Scrub: func(ctx context.Context, before time.Time) (int, error) { return 1, nil },
+	})
+
+	unlock, ok, _ := locker.TryLock(context.Background(), "retention:sessions", time.Minute)
+	if !ok {
+		t.Fatal("expected to acquire lock")
+	}
+	defer unlock()
+
+	report, err := engine.EnforceDataset(context.Background(), "sessions")
+	if err != nil || !report.Skipped {
+		t.Errorf("expected skipped report, got %+v, %v", report, err)
+	}
+}
@@ -0,0 +1,213 @@
+package router_test
+
+import (
+	stdcontext "context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/config"
+	"github.com/hokamsingh/lessgo/internal/core/context"
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+	"github.com/hokamsingh/lessgo/internal/core/probe"
+	"github.com/hokamsingh/lessgo/internal/core/router"
+	"github.com/hokamsingh/lessgo/internal/core/websocket"
+)
+
+const adminToken = "admin-token"
+
+func withAdminToken() router.Option {
+	return router.WithAdminAuth(middleware.NewBearerAuth(adminToken))
+}
+
+func serveAdmin(h http.Handler, method, path, remoteAddr, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.RemoteAddr = remoteAddr
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestAdmin_RestrictedToAllowedIPs(t *testing.T) {
+	r := router.NewRouter()
+	admin := r.Admin().Handler()
+
+	if w := serveAdmin(admin, http.MethodGet, "/routes", "203.0.113.7:1234", ""); w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a public IP, got %d", w.Code)
+	}
+	if w := serveAdmin(admin, http.MethodGet, "/routes", "127.0.0.1:1234", ""); w.Code != http.StatusOK {
+		t.Errorf("Expected 200 from loopback, got %d", w.Code)
+	}
+}
+
+func TestAdmin_RouteTableAndMaintenance(t *testing.T) {
+	r := router.NewRouter(router.WithAdminAllow("10.0.0.0/8"), withAdminToken())
+	r.Get("/users/{id}", func(ctx *context.Context) { ctx.Send("ok") })
+	admin := r.Admin().Handler()
+
+	w := serveAdmin(admin, http.MethodGet, "/routes", "10.1.2.3:1234", "")
+	var routes []router.RouteInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &routes); err != nil {
+		t.Fatalf("Invalid route table %q: %v", w.Body.String(), err)
+	}
+	if len(routes) != 1 || routes[0].Path != "/users/{id}" {
+		t.Errorf("Expected the users route, got %+v", routes)
+	}
+
+	w = serveAdmin(admin, http.MethodPut, "/maintenance", "10.1.2.3:1234", `{"enabled": true, "retry_after": "1m"}`)
+	if w.Code != http.StatusOK || !r.Maintenance().Enabled() {
+		t.Errorf("Expected maintenance to be enabled, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAdmin_MountedConfigAndFlags(t *testing.T) {
+	if err := router.NewRouter().MountAdmin("/_admin"); err == nil {
+		t.Error("Expected mounting without authentication to fail")
+	}
+	r := router.NewRouter(withAdminToken())
+	launch := middleware.NewSoftLaunch(*middleware.NewSoftLaunchOptions("checkout-v2", 10))
+	r.ExposeSoftLaunch(launch)
+	r.ExposeConfig(config.Config{"APP_NAME": "shop", "DB_PASSWORD": "hunter2"})
+	if err := r.MountAdmin("/_admin"); err != nil {
+		t.Fatal(err)
+	}
+	h := r.Handler()
+
+	w := serveAdmin(h, http.MethodGet, "/_admin/config", "127.0.0.1:1234", "")
+	if strings.Contains(w.Body.String(), "hunter2") || !strings.Contains(w.Body.String(), "shop") {
+		t.Errorf("Expected masked config, got %s", w.Body.String())
+	}
+
+	w = serveAdmin(h, http.MethodPut, "/_admin/flags/checkout-v2", "127.0.0.1:1234", `{"percent": 50}`)
+	if w.Code != http.StatusOK || launch.Percent() != 50 {
+		t.Errorf("Expected percent 50, got %d (%d %s)", launch.Percent(), w.Code, w.Body.String())
+	}
+	if w := serveAdmin(h, http.MethodGet, "/_admin/config", "198.51.100.1:1234", ""); w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a public IP on the mounted admin router, got %d", w.Code)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/_admin/config", nil)
+	req.RemoteAddr = "127.0.0.1:1234"
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without the admin token, got %d", w.Code)
+	}
+}
+
+func TestAdmin_ControlsRequireAuth(t *testing.T) {
+	launch := middleware.NewSoftLaunch(*middleware.NewSoftLaunchOptions("checkout-v2", 10))
+	// A reverse proxy on the same host forwards requests from loopback
+	unauthenticated := router.NewRouter()
+	unauthenticated.ExposeSoftLaunch(launch)
+	admin := unauthenticated.Admin().Handler()
+	if w := serveAdmin(admin, http.MethodPut, "/maintenance", "127.0.0.1:1234", `{"enabled": true}`); w.Code != http.StatusForbidden || unauthenticated.Maintenance().Enabled() {
+		t.Errorf("Expected 403 without admin authentication, got %d", w.Code)
+	}
+	if w := serveAdmin(admin, http.MethodPut, "/flags/checkout-v2", "127.0.0.1:1234", `{"percent": 100}`); w.Code != http.StatusForbidden || launch.Percent() != 10 {
+		t.Errorf("Expected 403 without admin authentication, got %d", w.Code)
+	}
+	if w := serveAdmin(admin, http.MethodGet, "/maintenance", "127.0.0.1:1234", ""); w.Code != http.StatusOK {
+		t.Errorf("Expected reads to stay available, got %d", w.Code)
+	}
+
+	r := router.NewRouter(withAdminToken())
+	req := httptest.NewRequest(http.MethodPut, "/maintenance", strings.NewReader(`{"enabled": true}`))
+	req.RemoteAddr = "127.0.0.1:1234"
+	req.Header.Set("Authorization", "Bearer wrong")
+	w := httptest.NewRecorder()
+	r.Admin().Handler().ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized || r.Maintenance().Enabled() {
+		t.Errorf("Expected 401 for a wrong token, got %d", w.Code)
+	}
+}
+
+func TestAdmin_CachePurge(t *testing.T) {
+	r := router.NewRouter(router.WithCaching(nil, time.Minute, true, middleware.WithCacheStore(middleware.NewMemoryCacheStore())), withAdminToken())
+	r.Get("/products/{id}", func(ctx *context.Context) {
+		id, _ := ctx.GetParam("id")
+		ctx.SetHeader(middleware.CacheTagHeader, "products, product-"+id)
+		ctx.Send("product " + id)
+	})
+	r.ExposeCache()
+	h := r.Handler()
+	admin := r.Admin().Handler()
+
+	for _, path := range []string{"/products/1", "/products/2", "/products/1"} {
+		serveAdmin(h, http.MethodGet, path, "203.0.113.7:1234", "")
+	}
+	if stats := r.Cache().Stats(); stats.Hits != 1 || stats.Misses != 2 || stats.Size != 2 {
+		t.Errorf("Expected 1 hit, 2 misses and 2 entries, got %+v", stats)
+	}
+
+	w := serveAdmin(admin, http.MethodPost, "/cache/purge", "127.0.0.1:1234", `{"tag": "product-1"}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"purged":1`) {
+		t.Errorf("Expected one response purged by tag, got %d %s", w.Code, w.Body.String())
+	}
+	if w := serveAdmin(h, http.MethodGet, "/products/1", "203.0.113.7:1234", ""); w.Header().Get("X-Cache-Hit") != "" {
+		t.Error("Expected the purged response to be fetched again")
+	}
+
+	w = serveAdmin(admin, http.MethodPost, "/cache/purge", "127.0.0.1:1234", `{"pattern": "/products/*"}`)
+	if !strings.Contains(w.Body.String(), `"purged":2`) {
+		t.Errorf("Expected both responses purged by pattern, got %s", w.Body.String())
+	}
+	if w := serveAdmin(admin, http.MethodGet, "/cache", "127.0.0.1:1234", ""); !strings.Contains(w.Body.String(), `"size":0`) {
+		t.Errorf("Expected an empty cache, got %s", w.Body.String())
+	}
+}
+
+func TestAdmin_Honeypot(t *testing.T) {
+	options := middleware.NewHoneypotOptions(middleware.NewIPBlocklist(), time.Hour)
+	options.TarpitDelay = 0
+	r := router.NewRouter(router.WithHoneypot(*options), withAdminToken())
+	r.Get("/users", func(ctx *context.Context) { ctx.Send("ok") })
+	admin := r.Admin().Handler()
+	app := r.Handler()
+
+	serveAdmin(app, http.MethodGet, "/.env", "198.51.100.7:1234", "")
+	if w := serveAdmin(app, http.MethodGet, "/users", "198.51.100.7:1234", ""); w.Code != http.StatusForbidden {
+		t.Errorf("Expected the scanner to be banned, got %d", w.Code)
+	}
+	w := serveAdmin(admin, http.MethodGet, "/honeypot", "127.0.0.1:1234", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"198.51.100.7"`) || !strings.Contains(w.Body.String(), `"hits":1`) {
+		t.Errorf("Expected the hit and the ban, got %d %s", w.Code, w.Body.String())
+	}
+	serveAdmin(admin, http.MethodDelete, "/honeypot/bans/198.51.100.7", "127.0.0.1:1234", "")
+	if w := serveAdmin(app, http.MethodGet, "/users", "198.51.100.7:1234", ""); w.Code != http.StatusOK {
+		t.Errorf("Expected the ban to be lifted, got %d", w.Code)
+	}
+}
+
+func TestAdmin_WebSocketHub(t *testing.T) {
+	r := router.NewRouter()
+	r.ExposeWebSocketHub(websocket.NewHub())
+	admin := r.Admin().Handler()
+
+	w := serveAdmin(admin, http.MethodGet, "/websocket", "127.0.0.1:1234", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"connections":0`) {
+		t.Errorf("Expected the hub stats, got %d %s", w.Code, w.Body.String())
+	}
+	w = serveAdmin(admin, http.MethodGet, "/websocket/metrics", "127.0.0.1:1234", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "lessgo_websocket_connections 0") {
+		t.Errorf("Expected the hub metrics, got %d %s", w.Code, w.Body.String())
+	}
+	if w := serveAdmin(admin, http.MethodGet, "/websocket/metrics", "203.0.113.9:1234", ""); w.Code != http.StatusForbidden {
+		t.Errorf("Expected the metrics to be restricted to the admin network, got %d", w.Code)
+	}
+}
+
+func TestAdmin_ExposeProber(t *testing.T) {
+	p := probe.NewProber()
+	p.CheckTarget(stdcontext.Background(), probe.Target{Name: "payments", Kind: probe.Func, Check: func(stdcontext.Context) error { return nil }})
+	r := router.NewRouter()
+	r.ExposeProber(p)
+
+	w := serveAdmin(r.Admin().Handler(), http.MethodGet, "/probes/metrics", "127.0.0.1:1234", "")
+	if want := `lessgo_probe_healthy{target="payments"} 1`; !strings.Contains(w.Body.String(), want) {
+		t.Errorf("Expected %s in\n%s", want, w.Body.String())
+	}
+}
@@ -0,0 +1,57 @@
+package router_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/context"
+	"github.com/hokamsingh/lessgo/internal/core/router"
+)
+
+func serveHost(r *router.Router, host, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Host = host
+	w := httptest.NewRecorder()
+	r.Mux.ServeHTTP(w, req)
+	return w
+}
+
+func TestHost_Variables(t *testing.T) {
+	r := router.NewRouter()
+	r.Host("{tenant}.example.com").Get("/whoami", func(ctx *context.Context) {
+		tenant, _ := ctx.GetParam("tenant")
+		ctx.Send(tenant)
+	})
+	r.Host("admin.{domain}").Get("/", func(ctx *context.Context) {
+		domain, _ := ctx.GetParam("domain")
+		ctx.Send("admin of " + domain)
+	})
+
+	if w := serveHost(r, "acme.example.com:8080", "/whoami"); w.Body.String() != "acme" {
+		t.Errorf("Expected tenant acme, got %d %q", w.Code, w.Body.String())
+	}
+	if w := serveHost(r, "admin.example.co.uk", "/"); w.Body.String() != "admin of example.co.uk" {
+		t.Errorf("Expected admin router, got %d %q", w.Code, w.Body.String())
+	}
+	if w := serveHost(r, "example.com", "/whoami"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unmatched host, got %d", w.Code)
+	}
+}
+
+func TestSubdomain(t *testing.T) {
+	r := router.NewRouter()
+	r.Subdomain("api").Get("/status", func(ctx *context.Context) {
+		ctx.Send("api")
+	})
+	r.Get("/status", func(ctx *context.Context) {
+		ctx.Send("www")
+	})
+
+	if w := serveHost(r, "api.example.com", "/status"); w.Body.String() != "api" {
+		t.Errorf("Expected api router, got %q", w.Body.String())
+	}
+	if w := serveHost(r, "www.example.com", "/status"); w.Body.String() != "www" {
+		t.Errorf("Expected main router, got %q", w.Body.String())
+	}
+}
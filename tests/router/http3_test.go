@@ -0,0 +1,107 @@
+package router_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/config"
+	"github.com/hokamsingh/lessgo/internal/core/context"
+	"github.com/hokamsingh/lessgo/internal/core/router"
+)
+
+// writeCertificate writes a self-signed certificate for 127.0.0.1 and returns the files.
+func writeCertificate(t *testing.T) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	dir := t.TempDir()
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	return certFile, keyFile
+}
+
+type fakeHTTP3Server struct {
+	tlsConfig *tls.Config
+	conns     chan net.PacketConn
+}
+
+func (s *fakeHTTP3Server) Serve(conn net.PacketConn) error {
+	s.conns <- conn
+	return nil
+}
+
+func (s *fakeHTTP3Server) Close() error {
+	return nil
+}
+
+func TestStart_HTTP3(t *testing.T) {
+	certFile, keyFile := writeCertificate(t)
+	// Pick a port that is free for TCP, HTTP/3 listens on the same one over UDP
+	free, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := free.Addr().String()
+	free.Close()
+	_, port, _ := net.SplitHostPort(addr)
+
+	h3 := &fakeHTTP3Server{conns: make(chan net.PacketConn, 1)}
+	r := router.NewRouter(router.WithHTTP3(func(handler http.Handler, tlsConfig *tls.Config) router.HTTP3Server {
+		h3.tlsConfig = tlsConfig
+		return h3
+	}))
+	r.Get("/", func(ctx *context.Context) { ctx.Send("ok") })
+	go r.Start(addr, config.NewHttpConfig(config.WithTLSCertFile(certFile), config.WithTLSKeyFile(keyFile)))
+
+	select {
+	case conn := <-h3.conns:
+		if got := conn.LocalAddr().(*net.UDPAddr).Port; strconv.Itoa(got) != port {
+			t.Errorf("Expected HTTP/3 on udp port %s, got %d", port, got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("HTTP/3 server was not started")
+	}
+	if len(h3.tlsConfig.Certificates) != 1 || h3.tlsConfig.NextProtos[0] != "h3" {
+		t.Errorf("Expected the certificate of the HttpConfig, got %+v", h3.tlsConfig)
+	}
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	var resp *http.Response
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if resp, err = client.Get("https://" + addr + "/"); err == nil || time.Now().After(deadline) {
+			break
+		}
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if want := `h3=":` + port + `"; ma=86400`; resp.Header.Get("Alt-Svc") != want {
+		t.Errorf("Expected Alt-Svc %q, got %q", want, resp.Header.Get("Alt-Svc"))
+	}
+}
@@ -0,0 +1,25 @@
+package router_test
+
+import (
+	"net"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/router"
+)
+
+func TestListenAllFailsOnUsedAddress(t *testing.T) {
+	used, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer used.Close()
+
+	r := router.NewRouter()
+	err = r.ListenAll([]router.ListenerConfig{
+		{Addr: "127.0.0.1:0"},
+		{Addr: used.Addr().String()},
+	})
+	if err == nil {
+		t.Error("Expected an error when one of the addresses is in use")
+	}
+}
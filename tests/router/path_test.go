@@ -0,0 +1,72 @@
+package router_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/context"
+	"github.com/hokamsingh/lessgo/internal/core/router"
+)
+
+// serveApp runs the request through the router's middleware like Start does.
+func serveApp(r *router.Router, method, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	w := httptest.NewRecorder()
+	r.Handler().ServeHTTP(w, req)
+	return w
+}
+
+func newPathRouter(options router.PathOptions) *router.Router {
+	r := router.NewRouter(router.WithPathNormalization(options))
+	r.Get("/users/{id}", func(ctx *context.Context) {
+		id, _ := ctx.GetParam("id")
+		ctx.Send("user " + id)
+	})
+	r.Get("/docs/", func(ctx *context.Context) {
+		ctx.Send("docs")
+	})
+	return r
+}
+
+func TestPathNormalization_TrailingSlashRedirect(t *testing.T) {
+	r := newPathRouter(router.PathOptions{TrailingSlash: router.TrailingSlashRedirect})
+
+	w := serveApp(r, http.MethodGet, "/users/42/?full=1")
+	if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/users/42?full=1" {
+		t.Errorf("Expected redirect to /users/42?full=1, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+	w = serveApp(r, http.MethodGet, "/docs")
+	if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/docs/" {
+		t.Errorf("Expected redirect to /docs/, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+	if w := serveApp(r, http.MethodGet, "/users/42"); w.Body.String() != "user 42" {
+		t.Errorf("Expected exact path to be served, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestPathNormalization_StripCollapseAndCase(t *testing.T) {
+	r := newPathRouter(router.PathOptions{
+		TrailingSlash:   router.TrailingSlashStrip,
+		CollapseSlashes: true,
+		CaseInsensitive: true,
+	})
+
+	for path, expected := range map[string]string{
+		"/users/42/":   "user 42",
+		"//users///42": "user 42",
+		"/USERS/42/":   "user 42",
+		"/Docs":        "docs",
+	} {
+		if w := serveApp(r, http.MethodGet, path); w.Code != http.StatusOK || w.Body.String() != expected {
+			t.Errorf("%s: expected %q, got %d %q", path, expected, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestPathNormalization_Off(t *testing.T) {
+	r := newPathRouter(router.PathOptions{})
+	if w := serveApp(r, http.MethodGet, "/Users/42"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 without case-insensitive matching, got %d", w.Code)
+	}
+}
@@ -0,0 +1,80 @@
+package router_test
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/context"
+	"github.com/hokamsingh/lessgo/internal/core/router"
+)
+
+func TestDefaults_Production(t *testing.T) {
+	var mu sync.Mutex
+	var logs []string
+	logf := func(format string, args ...interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		logs = append(logs, fmt.Sprintf(format, args...))
+	}
+	r := router.NewRouter(router.Defaults("production", func(p *router.Preset) {
+		p.Logger.Logf = logf
+		p.Recovery.Logf = logf
+	})...)
+	r.Get("/items", func(ctx *context.Context) {
+		ctx.JSON(http.StatusOK, map[string]string{"items": strings.Repeat("item ", 500)})
+	})
+	r.Get("/panic", func(ctx *context.Context) { panic("boom") })
+	handler := r.Handler()
+
+	req := httptest.NewRequest(http.MethodGet, "/items", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Header().Get("Content-Encoding") != "gzip" || rec.Header().Get("X-Content-Type-Options") != "nosniff" || rec.Header().Get("X-Request-ID") == "" {
+		t.Fatalf("Expected a compressed response with security headers and a request ID, got %v", rec.Header())
+	}
+	body, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := io.ReadAll(body); !strings.Contains(string(data), "item item") {
+		t.Errorf("Unexpected body %q", data)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/panic", nil)
+	req.Header.Set("X-Request-ID", "req-1")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusInternalServerError || rec.Header().Get("X-Request-ID") != "req-1" {
+		t.Errorf("Expected a 500 carrying the request ID, got %d %v", rec.Code, rec.Header())
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if last := logs[len(logs)-1]; !strings.HasPrefix(last, "GET /panic 500") || !strings.HasSuffix(last, "id=req-1") {
+		t.Errorf("Expected the access log to record the recovered panic, got %q", last)
+	}
+}
+
+func TestDefaults_Development(t *testing.T) {
+	var logs []string
+	preset := router.NewPreset("dev")
+	preset.Logger.Logf = func(format string, args ...interface{}) { logs = append(logs, fmt.Sprintf(format, args...)) }
+	if preset.Profiling == nil || preset.Compress || preset.SecureHeaders != nil {
+		t.Errorf("Unexpected development preset %+v", preset)
+	}
+	r := router.NewRouter(preset.Options()...)
+	r.Get("/items", func(ctx *context.Context) { ctx.Send("ok") })
+
+	req := httptest.NewRequest(http.MethodGet, "/items?page=2", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	r.Handler().ServeHTTP(httptest.NewRecorder(), req)
+	if len(logs) != 1 || !strings.Contains(logs[0], "/items?page=2") || !strings.Contains(logs[0], "Authorization=[redacted]") {
+		t.Errorf("Expected a verbose log line without credentials, got %q", logs)
+	}
+}
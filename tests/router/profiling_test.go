@@ -0,0 +1,49 @@
+package router_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/router"
+)
+
+func TestEnableProfiling(t *testing.T) {
+	r := router.NewRouter()
+	r.EnableProfiling(*router.NewProfilingOptions())
+	admin := r.Admin().Handler()
+
+	w := serveAdmin(admin, http.MethodGet, "/debug/pprof/", "127.0.0.1:1234", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "goroutine") {
+		t.Errorf("Expected the pprof index, got %d", w.Code)
+	}
+	w = serveAdmin(admin, http.MethodGet, "/debug/pprof/heap?debug=1", "127.0.0.1:1234", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "heap profile") {
+		t.Errorf("Expected the heap profile, got %d", w.Code)
+	}
+	w = serveAdmin(admin, http.MethodGet, "/debug/gc", "127.0.0.1:1234", "")
+	var stats map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil || stats["goroutines"] == nil {
+		t.Errorf("Expected GC stats, got %s", w.Body.String())
+	}
+	w = serveAdmin(admin, http.MethodGet, "/debug/goroutines", "127.0.0.1:1234", "")
+	if !strings.Contains(w.Body.String(), "goroutine ") {
+		t.Errorf("Expected a goroutine dump, got %q", w.Body.String())
+	}
+}
+
+func TestEnableProfiling_Allow(t *testing.T) {
+	r := router.NewRouter(router.WithAdminAllow("10.0.0.0/8"))
+	options := router.NewProfilingOptions()
+	options.Allow = []string{"10.0.0.5"}
+	r.EnableProfiling(*options)
+	admin := r.Admin().Handler()
+
+	if w := serveAdmin(admin, http.MethodGet, "/debug/gc", "10.0.0.6:1234", ""); w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 outside the profiling allowlist, got %d", w.Code)
+	}
+	if w := serveAdmin(admin, http.MethodGet, "/debug/gc", "10.0.0.5:1234", ""); w.Code != http.StatusOK {
+		t.Errorf("Expected 200 from the allowed IP, got %d", w.Code)
+	}
+}
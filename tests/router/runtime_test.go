@@ -0,0 +1,121 @@
+package router_test
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/config"
+	"github.com/hokamsingh/lessgo/internal/core/context"
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+	"github.com/hokamsingh/lessgo/internal/core/router"
+)
+
+func serveRuntime(h http.Handler, method, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.RemoteAddr = "127.0.0.1:1234"
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestRuntimeControls(t *testing.T) {
+	defer middleware.SetLogLevel("info")
+	profiler := middleware.NewProfiler(*middleware.NewProfilerOptions(time.Second))
+	r := router.NewRouter(router.WithProfiler(profiler), router.WithInMemoryRateLimiter(4, 2, time.Minute, time.Minute))
+	r.Get("/ping", func(ctx *context.Context) { ctx.Send("pong") })
+
+	if err := r.ExposeRuntimeControls(router.RuntimeOptions{}); err == nil {
+		t.Fatal("Expected the controls to require authentication")
+	}
+	if err := r.ExposeRuntimeControls(*router.NewRuntimeOptions("s3cret")); err != nil {
+		t.Fatal(err)
+	}
+	admin := r.Admin().Handler()
+
+	if w := serveRuntime(admin, http.MethodPut, "/runtime/log-level", "", `{"level":"debug"}`); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without the token, got %d", w.Code)
+	}
+	if w := serveRuntime(admin, http.MethodPut, "/runtime/log-level", "wrong", `{"level":"debug"}`); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with a wrong token, got %d", w.Code)
+	}
+	w := serveRuntime(admin, http.MethodPut, "/runtime/log-level", "s3cret", `{"level":"debug"}`)
+	if w.Code != http.StatusOK || middleware.LogLevel().Level() != slog.LevelDebug {
+		t.Errorf("Expected the debug level, got %d %s", w.Code, w.Body.String())
+	}
+	if w := serveRuntime(admin, http.MethodPut, "/runtime/log-level", "s3cret", `{"level":"loud"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown level, got %d", w.Code)
+	}
+
+	w = serveRuntime(admin, http.MethodPut, "/runtime/profiler", "s3cret", `{"enabled":false}`)
+	if w.Code != http.StatusOK || profiler.Enabled() {
+		t.Errorf("Expected the profiler to be disabled, got %d %s", w.Code, w.Body.String())
+	}
+	app := r.Handler()
+	serveRuntime(app, http.MethodGet, "/ping", "", "")
+	for _, stats := range profiler.Stats() {
+		if stats.Route == "/ping" {
+			t.Error("Expected a disabled profiler not to record requests")
+		}
+	}
+
+	// The limit of 2 is reached, then raised to 5
+	serveRuntime(app, http.MethodGet, "/ping", "", "")
+	if w := serveRuntime(app, http.MethodGet, "/ping", "", ""); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected the limit to be reached, got %d", w.Code)
+	}
+	w = serveRuntime(admin, http.MethodPut, "/runtime/rate-limits/0", "s3cret", `{"limit":5}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"limit":5`) {
+		t.Fatalf("Expected the new limit, got %d %s", w.Code, w.Body.String())
+	}
+	for i := 0; i < 3; i++ {
+		if w := serveRuntime(app, http.MethodGet, "/ping", "", ""); w.Code != http.StatusOK {
+			t.Fatalf("Expected request %d under the raised limit to pass, got %d", i, w.Code)
+		}
+	}
+	if w := serveRuntime(app, http.MethodGet, "/ping", "", ""); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected the raised limit to be enforced, got %d", w.Code)
+	}
+	if w := serveRuntime(admin, http.MethodPut, "/runtime/rate-limits/3", "s3cret", `{"limit":5}`); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown rate limiter, got %d", w.Code)
+	}
+
+	w = serveRuntime(admin, http.MethodGet, "/runtime", "s3cret", "")
+	if !strings.Contains(w.Body.String(), `"log_level":"DEBUG","profiler":false`) {
+		t.Errorf("Unexpected runtime state %s", w.Body.String())
+	}
+}
+
+func TestApplyRuntimeConfig(t *testing.T) {
+	defer middleware.SetLogLevel("info")
+	profiler := middleware.NewProfiler(*middleware.NewProfilerOptions(time.Second))
+	r := router.NewRouter(router.WithProfiler(profiler), router.WithInMemoryRateLimiter(4, 10, time.Minute, time.Minute))
+
+	err := r.ApplyRuntimeConfig(config.Config{
+		"LOG_LEVEL":           "warn",
+		"PROFILER_ENABLED":    "false",
+		"RATE_LIMIT":          "50",
+		"RATE_LIMIT_INTERVAL": "1s",
+		"MAINTENANCE_MODE":    "true",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if middleware.LogLevel().Level() != slog.LevelWarn || profiler.Enabled() || !r.Maintenance().Enabled() {
+		t.Error("Expected the configuration to be applied")
+	}
+
+	if err := r.ApplyRuntimeConfig(config.Config{"LOG_LEVEL": "loud", "RATE_LIMIT": "20"}); err == nil {
+		t.Error("Expected an invalid level to be reported")
+	}
+	w := serveRuntime(r.Admin().Handler(), http.MethodGet, "/routes", "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected the admin router, got %d", w.Code)
+	}
+}
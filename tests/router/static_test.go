@@ -0,0 +1,169 @@
+package router_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/router"
+)
+
+func newStaticRouter(options ...router.StaticOption) *router.Router {
+	fsys := fstest.MapFS{
+		"index.html":      {Data: []byte("<html>app</html>")},
+		"app.js":          {Data: []byte("console.log('app')")},
+		"app.js.gz":       {Data: []byte("gzipped")},
+		"docs/readme.txt": {Data: []byte("readme")},
+	}
+	r := router.NewRouter()
+	r.ServeStaticFS("/", fsys, options...)
+	return r
+}
+
+func serve(r *router.Router, path string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	r.Mux.ServeHTTP(w, req)
+	return w
+}
+
+func TestServeStaticFS_IndexFallback(t *testing.T) {
+	r := newStaticRouter(router.WithStaticIndexFallback("index.html"))
+
+	w := serve(r, "/users/42", nil)
+	if w.Code != http.StatusOK || w.Body.String() != "<html>app</html>" {
+		t.Fatalf("Expected index fallback, got %d %q", w.Code, w.Body.String())
+	}
+	if cc := w.Header().Get("Cache-Control"); cc != "no-cache" {
+		t.Errorf("Expected no-cache on fallback, got %q", cc)
+	}
+
+	w = serve(newStaticRouter(), "/users/42", nil)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 without fallback, got %d", w.Code)
+	}
+}
+
+func TestServeStaticFS_CacheAndPrecompressed(t *testing.T) {
+	r := newStaticRouter(
+		router.WithStaticCacheControl(time.Hour, true),
+		router.WithStaticPrecompressed(),
+	)
+
+	w := serve(r, "/app.js", map[string]string{"Accept-Encoding": "gzip, deflate"})
+	if w.Body.String() != "gzipped" {
+		t.Fatalf("Expected gzip variant, got %q", w.Body.String())
+	}
+	if enc := w.Header().Get("Content-Encoding"); enc != "gzip" {
+		t.Errorf("Expected Content-Encoding gzip, got %q", enc)
+	}
+	if cc := w.Header().Get("Cache-Control"); cc != "public, max-age=3600, immutable" {
+		t.Errorf("Unexpected Cache-Control %q", cc)
+	}
+
+	w = serve(r, "/app.js", nil)
+	if w.Body.String() != "console.log('app')" {
+		t.Errorf("Expected uncompressed file, got %q", w.Body.String())
+	}
+}
+
+func TestServeStaticFS_DirectoryListing(t *testing.T) {
+	w := serve(newStaticRouter(router.WithStaticDirectoryListing(false)), "/docs/", nil)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 with listing disabled, got %d", w.Code)
+	}
+
+	w = serve(newStaticRouter(), "/docs/", nil)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected listing with default options, got %d", w.Code)
+	}
+}
+
+func TestServeStaticFS_RangeAndConditional(t *testing.T) {
+	r := newStaticRouter()
+	w := serve(r, "/app.js", map[string]string{"Range": "bytes=0-6"})
+	if w.Code != http.StatusPartialContent || w.Body.String() != "console" {
+		t.Fatalf("Expected a partial response, got %d %q", w.Code, w.Body.String())
+	}
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("Expected an ETag")
+	}
+	if w := serve(r, "/app.js", map[string]string{"If-None-Match": etag}); w.Code != http.StatusNotModified {
+		t.Errorf("Expected 304 for a matching ETag, got %d", w.Code)
+	}
+	w = serve(r, "/app.js", map[string]string{"Range": "bytes=0-6", "If-Range": `"stale"`})
+	if w.Code != http.StatusOK || w.Body.String() != "console.log('app')" {
+		t.Errorf("Expected the full file for a stale If-Range, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestServeStaticFS_HiddenFilesAndNotFound(t *testing.T) {
+	fsys := fstest.MapFS{
+		".env":            {Data: []byte("SECRET=1")},
+		".git/config":     {Data: []byte("[core]")},
+		"docs/readme.txt": {Data: []byte("readme")},
+		"docs/.draft.txt": {Data: []byte("draft")},
+	}
+	r := router.NewRouter()
+	r.ServeStaticFS("/", fsys,
+		router.WithStaticDenyHidden(),
+		router.WithStaticNotFound(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("custom 404"))
+		})),
+	)
+
+	for _, path := range []string{"/.env", "/.git/config", "/docs/.draft.txt", "/missing"} {
+		if w := serve(r, path, nil); w.Code != http.StatusNotFound || w.Body.String() != "custom 404" {
+			t.Errorf("Expected the custom 404 for %s, got %d %q", path, w.Code, w.Body.String())
+		}
+	}
+	w := serve(r, "/docs/", nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "readme.txt") || strings.Contains(w.Body.String(), ".draft") {
+		t.Errorf("Expected hidden files left out of the listing, got %q", w.Body.String())
+	}
+}
+
+type denyMiddleware struct{}
+
+func (denyMiddleware) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func TestServeStaticFS_PrefixMiddlewareAndAccessHook(t *testing.T) {
+	var accesses []router.StaticAccess
+	r := newStaticRouter(
+		router.WithStaticMiddleware("/docs/", denyMiddleware{}),
+		router.WithStaticAccessHook(func(a router.StaticAccess) { accesses = append(accesses, a) }),
+	)
+
+	if w := serve(r, "/docs/readme.txt", nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the private prefix to require authorization, got %d", w.Code)
+	}
+	if w := serve(r, "/docs/readme.txt", map[string]string{"Authorization": "Bearer x"}); w.Code != http.StatusOK {
+		t.Errorf("Expected authorized requests to be served, got %d", w.Code)
+	}
+	if w := serve(r, "/app.js", nil); w.Code != http.StatusOK {
+		t.Errorf("Expected public files to be served, got %d", w.Code)
+	}
+
+	if len(accesses) != 3 {
+		t.Fatalf("Expected 3 accesses, got %d", len(accesses))
+	}
+	if accesses[0].Status != http.StatusUnauthorized || accesses[2].Path != "/app.js" || accesses[2].Bytes != int64(len("console.log('app')")) {
+		t.Errorf("Unexpected accesses %+v", accesses)
+	}
+}
@@ -0,0 +1,50 @@
+package router_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/context"
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+	"github.com/hokamsingh/lessgo/internal/core/router"
+)
+
+func TestMiddlewareChain(t *testing.T) {
+	r := router.NewRouter(router.WithMiddlewareTracing())
+	r.Use(middleware.NewCookieParser())
+	r.Use(middleware.NewXSSProtection())
+	r.With(middleware.NewCSRFProtection()).Post("/orders", func(ctx *context.Context) { ctx.Send("ok") })
+
+	chain, found := r.MiddlewareChain(http.MethodPost, "/orders")
+	if !found {
+		t.Fatal("Expected the route to be found")
+	}
+	want := "middleware.Maintenance middleware.XSSProtection middleware.CookieParser middleware.CSRFProtection"
+	if got := strings.Join(chain, " "); got != want {
+		t.Errorf("Expected chain %q, got %q", want, got)
+	}
+
+	if _, found := r.MiddlewareChain(http.MethodGet, "/missing"); found {
+		t.Error("Expected no route for /missing")
+	}
+
+	w := serveAdmin(r.Admin().Handler(), http.MethodGet, "/middleware?method=POST&path=/orders", "127.0.0.1:1234", "")
+	var body struct {
+		Chain []string `json:"chain"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || len(body.Chain) != 4 {
+		t.Errorf("Expected the chain from the admin router, got %s", w.Body.String())
+	}
+}
+
+func TestChainWarnings(t *testing.T) {
+	warnings := router.ChainWarnings([]string{"middleware.Caching", "middleware.TrustedHeaderAuth"})
+	if len(warnings) != 1 {
+		t.Errorf("Expected a cache-before-auth warning, got %v", warnings)
+	}
+	if warnings := router.ChainWarnings([]string{"middleware.TrustedHeaderAuth", "middleware.Caching"}); len(warnings) != 0 {
+		t.Errorf("Expected no warnings, got %v", warnings)
+	}
+}
@@ -0,0 +1,67 @@
+package router_test
+
+import (
+	stdcontext "context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/hokamsingh/lessgo/internal/core/context"
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+	"github.com/hokamsingh/lessgo/internal/core/router"
+)
+
+func TestValidate(t *testing.T) {
+	r := router.NewRouter()
+	handler := func(ctx *context.Context) { ctx.Send("ok") }
+	r.Get("/users", handler)
+	r.Post("/users", handler)
+	r.Get("/users/{id}", handler)
+	if err := r.Validate(stdcontext.Background()); err != nil {
+		t.Fatalf("Expected a valid router, got %v", err)
+	}
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/users", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected the POST handler next to the GET one to be reached, got %d", rec.Code)
+	}
+
+	r.Get("/users", handler)
+	var invalid *router.ValidationError
+	if err := r.Validate(stdcontext.Background()); !errors.As(err, &invalid) || len(invalid.Problems) != 1 || !strings.Contains(invalid.Problems[0], "GET /users") {
+		t.Errorf("Expected the duplicate route to be reported, got %v", err)
+	}
+}
+
+func TestValidate_CachingBeforeAuth(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1, DialTimeout: 100 * time.Millisecond})
+	defer client.Close()
+	auth := middleware.NewTrustedHeaderAuth(*middleware.NewTrustedHeaderOptions([]string{"127.0.0.1"}))
+	caching := middleware.NewCaching(client, time.Minute, false)
+
+	// The middleware added last runs first
+	r := router.NewRouter(router.WithoutStartupValidation())
+	r.Use(auth)
+	r.Use(caching)
+	var invalid *router.ValidationError
+	if err := r.Validate(stdcontext.Background()); !errors.As(err, &invalid) || len(invalid.Problems) != 2 {
+		t.Fatalf("Expected the order and the unreachable Redis to be reported, got %v", err)
+	}
+	if !strings.Contains(invalid.Problems[0], "before middleware.TrustedHeaderAuth") || !strings.Contains(invalid.Problems[1], "unreachable") {
+		t.Errorf("Expected clear messages, got %q", invalid.Problems)
+	}
+
+	r = router.NewRouter()
+	r.Use(caching)
+	r.Use(auth)
+	if err := r.Validate(stdcontext.Background()); !errors.As(err, &invalid) || len(invalid.Problems) != 1 {
+		t.Errorf("Expected only the unreachable Redis to be reported, got %v", err)
+	}
+	if err := r.StartGraceful("127.0.0.1:0", nil); err == nil {
+		t.Error("Expected the start to fail")
+	}
+}
@@ -0,0 +1,38 @@
+package router_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/router"
+)
+
+func TestEnableVersionEndpoint(t *testing.T) {
+	router.AppVersion, router.Commit = "1.4.0", "9f2c1e7"
+	defer func() { router.AppVersion, router.Commit = "", "" }()
+	r := router.NewRouter()
+	r.EnableVersionEndpoint("/version")
+
+	w := httptest.NewRecorder()
+	r.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/version", nil))
+	var info router.VersionInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &info); err != nil {
+		t.Fatal(err)
+	}
+	if info.Framework != router.FrameworkVersion || info.Version != "1.4.0" || info.Commit != "9f2c1e7" || info.GoVersion != runtime.Version() || info.StartedAt.IsZero() {
+		t.Errorf("Expected the injected version information, got %+v", info)
+	}
+	if w.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("Expected the version not to be cached, got %q", w.Header().Get("Cache-Control"))
+	}
+}
+
+func TestVersionLdflags(t *testing.T) {
+	want := "-X 'github.com/hokamsingh/lessgo/internal/core/router.AppVersion=1.4.0' -X 'github.com/hokamsingh/lessgo/internal/core/router.Commit=9f2c1e7'"
+	if got := router.VersionLdflags("1.4.0", "9f2c1e7", ""); got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
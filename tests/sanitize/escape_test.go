@@ -0,0 +1,63 @@
+package sanitize_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/context"
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+	"github.com/hokamsingh/lessgo/internal/core/sanitize"
+)
+
+func TestEscaping(t *testing.T) {
+	if got := sanitize.HTMLEscape(`<a href="x">'&'</a>`); got != "&lt;a href=&#34;x&#34;&gt;&#39;&amp;&#39;&lt;/a&gt;" {
+		t.Errorf("Unexpected HTML escaping %q", got)
+	}
+	got := sanitize.JSAttrEscape(`');alert("1`)
+	if strings.ContainsAny(got, `"<>`) || strings.Contains(got, "')") {
+		t.Errorf("Expected the value to stay inside the string and attribute, got %q", got)
+	}
+	js, err := sanitize.ScriptJSON(map[string]string{"name": "</script><script>alert(1)"})
+	if err != nil || strings.Contains(string(js), "</script>") {
+		t.Errorf("Expected JSON safe for a script element, got %q, %v", js, err)
+	}
+}
+
+func TestTemplateOutputEncoding(t *testing.T) {
+	dir := t.TempDir()
+	page := `{{define "page.html"}}<div>{{sanitize "ugc" .Comment}}</div><script>var user = {{scriptJSON .}};</script>{{end}}`
+	if err := os.WriteFile(filepath.Join(dir, "page.html"), []byte(page), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	templates := middleware.NewTemplateMiddleware(dir, middleware.WithTemplateSecurity(*middleware.NewTemplateSecurityOptions()))
+
+	render := func(accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+		templates.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			context.NewContext(r, w).Negotiate(http.StatusOK, map[string]string{"Comment": `<em>hi</em><script>x()</script>`}, "page.html")
+		})).ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := render("text/html")
+	body := rec.Body.String()
+	if !strings.Contains(body, "<div><em>hi</em></div>") || strings.Contains(body, "<script>x()") {
+		t.Errorf("Expected the comment to be sanitized, got %q", body)
+	}
+	if !strings.Contains(body, `\u003cscript\u003ex()`) {
+		t.Errorf("Expected the JSON to be escaped for the script element, got %q", body)
+	}
+	if rec.Header().Get("Content-Security-Policy") == "" || rec.Header().Get("X-XSS-Protection") != "0" {
+		t.Errorf("Expected the security headers on the page, got %v", rec.Header())
+	}
+
+	if rec := render("application/json"); rec.Header().Get("Content-Security-Policy") != "" {
+		t.Errorf("Expected no page headers on JSON responses, got %v", rec.Header())
+	}
+}
@@ -0,0 +1,97 @@
+package sanitize_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/context"
+	"github.com/hokamsingh/lessgo/internal/core/sanitize"
+	"github.com/microcosm-cc/bluemonday"
+)
+
+type author struct {
+	Name string `json:"name" sanitize:"strict"`
+}
+
+type commentDTO struct {
+	Title   string            `json:"title" sanitize:"strict"`
+	Body    string            `json:"body" sanitize:"ugc"`
+	Tags    []string          `json:"tags" sanitize:"strict"`
+	Labels  map[string]string `json:"labels" sanitize:"strict"`
+	Author  *author           `json:"author"`
+	Snippet string            `json:"snippet"` // Not declared, kept as sent
+}
+
+func TestStruct(t *testing.T) {
+	dto := commentDTO{
+		Title:   `<b>Hello</b><script>alert(1)</script>`,
+		Body:    `<p onclick="steal()">Nice <a href="javascript:alert(1)">post</a> <em>indeed</em></p>`,
+		Tags:    []string{"<i>go</i>"},
+		Labels:  map[string]string{"color": "<u>blue</u>"},
+		Author:  &author{Name: `<img src=x onerror=alert(1)>Ann`},
+		Snippet: "<code>onclick=</code>",
+	}
+	if err := sanitize.Struct(&dto); err != nil {
+		t.Fatal(err)
+	}
+	if dto.Title != "Hello" || dto.Tags[0] != "go" || dto.Labels["color"] != "blue" || dto.Author.Name != "Ann" {
+		t.Errorf("Expected all markup to be removed from strict fields, got %+v %+v", dto, dto.Author)
+	}
+	if dto.Body != `<p>Nice post <em>indeed</em></p>` {
+		t.Errorf("Expected safe formatting to be kept, got %q", dto.Body)
+	}
+	if dto.Snippet != "<code>onclick=</code>" {
+		t.Errorf("Expected undeclared fields to be left alone, got %q", dto.Snippet)
+	}
+
+	var invalid struct {
+		Name string `sanitize:"unknown"`
+	}
+	if err := sanitize.Struct(&invalid); err == nil {
+		t.Error("Expected an error for an unknown policy")
+	}
+}
+
+func TestJSON(t *testing.T) {
+	body := map[string]interface{}{
+		"title": "<h1>Hi</h1>",
+		"posts": []interface{}{
+			map[string]interface{}{"body": "<script>x</script><b>bold</b>", "raw": "<b>raw</b>"},
+		},
+	}
+	sanitize.Register("bold", bluemonday.NewPolicy().AllowElements("b"))
+	sanitize.JSON(body, map[string]string{"title": sanitize.Strict, "posts.body": "bold"})
+	post := body["posts"].([]interface{})[0].(map[string]interface{})
+	if body["title"] != "Hi" || post["body"] != "<b>bold</b>" || post["raw"] != "<b>raw</b>" {
+		t.Errorf("Expected the declared fields to be sanitized, got %v", body)
+	}
+}
+
+func TestContextBinding(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/comments", strings.NewReader(`{"title":"<b>Hi</b>","body":"<p>ok</p><iframe src=x></iframe>"}`))
+	req.Header.Set("Content-Type", "application/json")
+	var dto commentDTO
+	if err := context.NewContext(req, httptest.NewRecorder()).Body(&dto); err != nil {
+		t.Fatal(err)
+	}
+	if dto.Title != "Hi" || dto.Body != "<p>ok</p>" {
+		t.Errorf("Expected Body to sanitize the declared fields, got %+v", dto)
+	}
+
+	form := url.Values{"title": {"<b>Hi</b>"}, "body": {"<em>x</em><script>y</script>"}}
+	req = httptest.NewRequest(http.MethodPost, "/comments", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var formDTO struct {
+		Title string `form:"title" sanitize:"strict"`
+		Body  string `form:"body" sanitize:"ugc"`
+	}
+	if err := context.NewContext(req, httptest.NewRecorder()).BindForm(&formDTO); err != nil {
+		t.Fatal(err)
+	}
+	if formDTO.Title != "Hi" || formDTO.Body != "<em>x</em>" {
+		t.Errorf("Expected BindForm to sanitize the declared fields, got %+v", formDTO)
+	}
+}
@@ -0,0 +1,120 @@
+package scaffold_test
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/scaffold"
+)
+
+const rootModule = `package src
+
+import (
+	"example.com/app/src/upload"
+	LessGo "github.com/hokamsingh/lessgo/pkg/lessgo"
+)
+
+type RootModule struct {
+	LessGo.Module
+}
+
+func NewRootModule(r *LessGo.Router, database *LessGo.DB) *RootModule {
+	modules := []LessGo.IModule{
+		upload.NewUploadModule(),
+	}
+	LessGo.RegisterModules(r, modules)
+	return &RootModule{Module: *LessGo.NewModule("Root", nil, nil, modules)}
+}
+`
+
+func newApp(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "src"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"go.mod":             "module example.com/app\n\ngo 1.22\n",
+		"src/root_module.go": rootModule,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestGenerateResource(t *testing.T) {
+	root := newApp(t)
+	result, err := scaffold.GenerateResource(root, "OrderItem", *scaffold.NewResourceOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Files) != 7 {
+		t.Errorf("Expected 7 files, got %v", result.Files)
+	}
+	fset := token.NewFileSet()
+	for _, file := range result.Files {
+		if !strings.HasPrefix(file, filepath.Join(root, "src", "orderitem", "order_item_")) {
+			t.Errorf("Unexpected file %s", file)
+		}
+		if _, err := parser.ParseFile(fset, file, nil, 0); err != nil {
+			t.Errorf("Expected valid Go in %s: %v", file, err)
+		}
+	}
+	controller, _ := os.ReadFile(filepath.Join(root, "src", "orderitem", "order_item_controller.go"))
+	if !strings.Contains(string(controller), `LessGo.ValidatePipe(CreateOrderItemDTO{})`) {
+		t.Errorf("Expected the controller to validate its input:\n%s", controller)
+	}
+	repository, _ := os.ReadFile(filepath.Join(root, "src", "orderitem", "order_item_repository.go"))
+	if !strings.Contains(string(repository), `const orderItemTable = "order_items"`) {
+		t.Errorf("Expected the plural table name:\n%s", repository)
+	}
+
+	wired, _ := os.ReadFile(result.Wired)
+	for _, want := range []string{`"example.com/app/src/orderitem"`, "\t\torderitem.NewOrderItemModule(database),\n\t}"} {
+		if !strings.Contains(string(wired), want) {
+			t.Errorf("Expected the root module to contain %q:\n%s", want, wired)
+		}
+	}
+	if len(result.Notes) != 1 {
+		t.Errorf("Expected only the table to be left to do, got %v", result.Notes)
+	}
+
+	if _, err := scaffold.GenerateResource(root, "order_item", *scaffold.NewResourceOptions()); err == nil {
+		t.Error("Expected existing files to be kept without Force")
+	}
+}
+
+func TestGenerateResourceNames(t *testing.T) {
+	root := newApp(t)
+	options := scaffold.NewResourceOptions()
+	options.RootModule = ""
+	options.Path = "api/v1/people/"
+	result, err := scaffold.GenerateResource(root, "category", *options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	module, _ := os.ReadFile(filepath.Join(root, "src", "category", "category_module.go"))
+	if !strings.Contains(string(module), `"/api/v1/people"`) {
+		t.Errorf("Expected the custom path:\n%s", module)
+	}
+	repository, _ := os.ReadFile(filepath.Join(root, "src", "category", "category_repository.go"))
+	if !strings.Contains(string(repository), `"categories"`) {
+		t.Errorf("Expected the plural table name:\n%s", repository)
+	}
+	if result.Wired != "" {
+		t.Errorf("Expected no wiring without a root module, got %s", result.Wired)
+	}
+
+	for _, name := range []string{"", "9lives", "type", "user!"} {
+		if _, err := scaffold.GenerateResource(root, name, *options); err == nil {
+			t.Errorf("Expected %q to be rejected", name)
+		}
+	}
+}
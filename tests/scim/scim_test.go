@@ -0,0 +1,68 @@
+package scim_test
+
+import (
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/scim"
+)
+
+func TestParseFilter_Match(t *testing.T) {
+	user := scim.User{
+		UserName: "bjensen",
+		Name:     &scim.Name{FamilyName: "Jensen"},
+		Emails:   []scim.MultiValue{{Value: "bjensen@example.com", Type: "work"}},
+		Active:   true,
+	}
+	attrs, err := scim.ToAttributes(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]bool{
+		`userName eq "BJensen"`:                          true,
+		`name.familyName sw "jen"`:                       true,
+		`emails co "example.com" and active eq true`:     true,
+		`userName eq "other" or (emails.type eq "work")`: true,
+		`not (active eq true)`:                           false,
+		`displayName pr`:                                 false,
+		`userName ne "bjensen"`:                          false,
+	}
+	for expr, expected := range cases {
+		filter, err := scim.ParseFilter(expr)
+		if err != nil {
+			t.Fatalf("Failed to parse %q: %v", expr, err)
+		}
+		if got := filter.Match(attrs); got != expected {
+			t.Errorf("Filter %q: expected %v, got %v", expr, expected, got)
+		}
+	}
+
+	if _, err := scim.ParseFilter(`userName zz "x"`); err == nil {
+		t.Error("Expected an error for an unknown operator")
+	}
+}
+
+func TestApplyPatch(t *testing.T) {
+	group := scim.Group{
+		DisplayName: "Admins",
+		Members:     []scim.Member{{Value: "1"}, {Value: "2"}},
+	}
+	attrs, _ := scim.ToAttributes(group)
+
+	err := scim.ApplyPatch(attrs, []scim.PatchOperation{
+		{Op: "remove", Path: `members[value eq "1"]`},
+		{Op: "add", Path: "members", Value: []interface{}{map[string]interface{}{"value": "3"}}},
+		{Op: "replace", Path: "displayName", Value: "Owners"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	members := attrs["members"].([]interface{})
+	if len(members) != 2 || members[1].(map[string]interface{})["value"] != "3" {
+		t.Errorf("Unexpected members after patch: %v", members)
+	}
+	if attrs["displayName"] != "Owners" {
+		t.Errorf("Expected displayName Owners, got %v", attrs["displayName"])
+	}
+}
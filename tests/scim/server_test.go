@@ -0,0 +1,76 @@
+package scim_test
+
+import (
+	stdcontext "context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hokamsingh/lessgo/internal/core/router"
+	"github.com/hokamsingh/lessgo/internal/core/scim"
+)
+
+// users is an in-memory user repository.
+type users struct {
+	created []scim.User
+}
+
+func (u *users) ListUsers(ctx stdcontext.Context, query scim.Query) ([]scim.User, int, error) {
+	return u.created, len(u.created), nil
+}
+
+func (u *users) GetUser(ctx stdcontext.Context, id string) (*scim.User, error) {
+	return nil, scim.ErrNotFound
+}
+
+func (u *users) CreateUser(ctx stdcontext.Context, user *scim.User) (*scim.User, error) {
+	user.ID = "1"
+	u.created = append(u.created, *user)
+	return user, nil
+}
+
+func (u *users) ReplaceUser(ctx stdcontext.Context, user *scim.User) (*scim.User, error) {
+	return user, nil
+}
+
+func (u *users) DeleteUser(ctx stdcontext.Context, id string) error {
+	return nil
+}
+
+func TestServer_RequiresBearerToken(t *testing.T) {
+	r := router.NewRouter()
+	if err := scim.NewServer(&users{}, nil, scim.WithBearerToken("")).RegisterRoutes(r, "/scim/v2"); !errors.Is(err, scim.ErrNoBearerToken) {
+		t.Fatalf("Expected ErrNoBearerToken without a token, got %v", err)
+	}
+
+	repo := &users{}
+	if err := scim.NewServer(repo, nil, scim.WithBearerToken("s3cret")).RegisterRoutes(r, "/scim/v2"); err != nil {
+		t.Fatal(err)
+	}
+	send := func(authorization, body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/scim/v2/Users", strings.NewReader(body))
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		rec := httptest.NewRecorder()
+		r.Handler().ServeHTTP(rec, req)
+		return rec.Code
+	}
+	user := `{"userName": "bjensen"}`
+	for _, authorization := range []string{"", "s3cret", "Basic s3cret", "Bearer wrong"} {
+		if code := send(authorization, user); code != http.StatusUnauthorized {
+			t.Errorf("Expected 401 for %q, got %d", authorization, code)
+		}
+	}
+	if code := send("bearer s3cret", user); code != http.StatusCreated {
+		t.Errorf("Expected the scheme to be case-insensitive, got %d", code)
+	}
+	if code := send("Bearer s3cret", `{"userName": "`+strings.Repeat("a", 2<<20)+`"}`); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 for a large body, got %d", code)
+	}
+	if len(repo.created) != 1 {
+		t.Errorf("Expected one user to be created, got %d", len(repo.created))
+	}
+}
@@ -0,0 +1,199 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	lesscontext "github.com/hokamsingh/lessgo/internal/core/context"
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+	"github.com/hokamsingh/lessgo/internal/core/storage"
+)
+
+func TestLocalStorage(t *testing.T) {
+	store, err := storage.NewLocalStorage(t.TempDir(), storage.WithLocalSignedURLs("http://files.test/files", []byte("secret")))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	ctx := context.Background()
+	if err := store.Put(ctx, "avatars/42.png", strings.NewReader("png"), "image/png"); err != nil {
+		t.Fatalf("Failed to put: %v", err)
+	}
+	file, err := store.Get(ctx, "avatars/42.png")
+	if err != nil {
+		t.Fatalf("Failed to get: %v", err)
+	}
+	data, _ := io.ReadAll(file)
+	file.Close()
+	if string(data) != "png" {
+		t.Errorf("Expected the stored content, got %q", data)
+	}
+
+	// Keys cannot escape the directory
+	if err := store.Put(ctx, "../../escape.txt", strings.NewReader("x"), ""); err != nil {
+		t.Fatalf("Failed to put: %v", err)
+	}
+	if _, err := store.Get(ctx, "escape.txt"); err != nil {
+		t.Errorf("Expected the key to be confined to the directory, got %v", err)
+	}
+
+	signed, err := store.SignedURL(ctx, "avatars/42.png", time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	u, _ := url.Parse(signed)
+	handler := http.StripPrefix("/files", store.Handler())
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, u.RequestURI(), nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "png" {
+		t.Errorf("Expected the signed URL to serve the file, got %d %q", rec.Code, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, strings.Replace(u.RequestURI(), "42", "43", 1), nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected a tampered URL to be rejected, got %d", rec.Code)
+	}
+
+	if err := store.Delete(ctx, "avatars/42.png"); err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+	if _, err := store.Get(ctx, "avatars/42.png"); err != storage.ErrNotFound {
+		t.Errorf("Expected ErrNotFound after delete, got %v", err)
+	}
+	if err := store.Delete(ctx, "avatars/42.png"); err != nil {
+		t.Errorf("Expected deleting a missing file to succeed, got %v", err)
+	}
+}
+
+// fakeS3 keeps objects in memory and requires signed requests.
+func fakeS3(t *testing.T) (*httptest.Server, map[string][]byte) {
+	var mu sync.Mutex
+	objects := map[string][]byte{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(auth, "/eu-west-1/s3/aws4_request") {
+			http.Error(w, "unsigned", http.StatusForbidden)
+			return
+		}
+		switch r.Method {
+		case http.MethodPut:
+			if r.ContentLength < 0 {
+				http.Error(w, "length required", http.StatusLengthRequired)
+				return
+			}
+			objects[r.URL.Path], _ = io.ReadAll(r.Body)
+		case http.MethodGet:
+			data, ok := objects[r.URL.Path]
+			if !ok {
+				http.Error(w, "NoSuchKey", http.StatusNotFound)
+				return
+			}
+			w.Write(data)
+		case http.MethodDelete:
+			delete(objects, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, objects
+}
+
+func TestS3Storage(t *testing.T) {
+	server, objects := fakeS3(t)
+	store := storage.NewS3Storage(storage.S3Config{
+		Endpoint:  server.URL,
+		Region:    "eu-west-1",
+		Bucket:    "uploads",
+		AccessKey: "AKID",
+		SecretKey: "secret",
+		PathStyle: true,
+	})
+	ctx := context.Background()
+	// A reader of unknown length is buffered to send its length
+	if err := store.Put(ctx, "docs/a b.txt", io.MultiReader(strings.NewReader("hello")), "text/plain"); err != nil {
+		t.Fatalf("Failed to put: %v", err)
+	}
+	if string(objects["/uploads/docs/a b.txt"]) != "hello" {
+		t.Errorf("Expected the object in the bucket, got %v", objects)
+	}
+	file, err := store.Get(ctx, "docs/a b.txt")
+	if err != nil {
+		t.Fatalf("Failed to get: %v", err)
+	}
+	data, _ := io.ReadAll(file)
+	file.Close()
+	if string(data) != "hello" {
+		t.Errorf("Expected the object content, got %q", data)
+	}
+	if _, err := store.Get(ctx, "missing"); err != storage.ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	signed, err := store.SignedURL(ctx, "docs/a b.txt", time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	query, _ := url.Parse(signed)
+	if !strings.Contains(signed, "/uploads/docs/a%20b.txt?") || query.Query().Get("X-Amz-Expires") != "3600" || len(query.Query().Get("X-Amz-Signature")) != 64 {
+		t.Errorf("Unexpected signed URL %s", signed)
+	}
+}
+
+func upload(t *testing.T, handler http.Handler, filename string) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, _ := writer.CreateFormFile("file", filename)
+	part.Write([]byte("content"))
+	writer.Close()
+	req := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestFileUploadMiddleware_UsesContextStorage(t *testing.T) {
+	server, objects := fakeS3(t)
+	store := storage.NewS3Storage(storage.S3Config{Endpoint: server.URL, Region: "eu-west-1", Bucket: "uploads", AccessKey: "AKID", SecretKey: "secret", PathStyle: true})
+	uploads := middleware.NewFileUploadMiddleware(t.TempDir(), 1<<20, []string{".txt"})
+	handler := storage.NewMiddleware(store).Handle(uploads.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	if rec := upload(t, handler, "notes.txt"); rec.Code != http.StatusCreated {
+		t.Fatalf("Expected the upload to succeed, got %d %s", rec.Code, rec.Body.String())
+	}
+	if len(objects) != 1 {
+		t.Errorf("Expected the file saved to the bucket, got %v", objects)
+	}
+}
+
+func TestContext_SaveUpload(t *testing.T) {
+	store, _ := storage.NewLocalStorage(t.TempDir())
+	var key string
+	var saveErr error
+	handler := storage.NewMiddleware(store).Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, saveErr = lesscontext.NewContext(r, w).SaveUpload("file", "notes/")
+	}))
+	upload(t, handler, "Notes.TXT")
+	if saveErr != nil {
+		t.Fatalf("Failed to save upload: %v", saveErr)
+	}
+	if !strings.HasPrefix(key, "notes/") || !strings.HasSuffix(key, ".txt") {
+		t.Errorf("Expected a random key under the prefix, got %q", key)
+	}
+	file, err := store.Get(context.Background(), key)
+	if err != nil {
+		t.Fatalf("Failed to get the upload: %v", err)
+	}
+	file.Close()
+}
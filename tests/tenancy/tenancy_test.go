@@ -0,0 +1,230 @@
+package tenancy_test
+
+import (
+	stdcontext "context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/context"
+	"github.com/hokamsingh/lessgo/internal/core/middleware"
+	"github.com/hokamsingh/lessgo/internal/core/router"
+	"github.com/hokamsingh/lessgo/internal/core/tenancy"
+)
+
+func TestResolvers(t *testing.T) {
+	resolve := tenancy.FirstOf(tenancy.FromSubdomain("example.com"), tenancy.FromHeader("X-Tenant-ID"), tenancy.FromClaim("tid"))
+
+	tests := []struct {
+		name  string
+		setup func(r *http.Request) *http.Request
+		want  string
+	}{
+		{"subdomain", func(r *http.Request) *http.Request { r.Host = "acme.example.com:8080"; return r }, "acme"},
+		{"www", func(r *http.Request) *http.Request { r.Host = "www.example.com"; return r }, ""},
+		{"header", func(r *http.Request) *http.Request { r.Header.Set("X-Tenant-ID", "globex"); return r }, "globex"},
+		{"claim", func(r *http.Request) *http.Request {
+			return middleware.SetValue(r, middleware.ClaimsKey, map[string]interface{}{"tid": "initech"})
+		}, "initech"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tenant, _ := resolve(tt.setup(httptest.NewRequest(http.MethodGet, "/", nil)))
+			if tenant != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, tenant)
+			}
+		})
+	}
+}
+
+func TestTenancy_RouterAndScoped(t *testing.T) {
+	created := 0
+	databases := tenancy.NewScoped(func(ctx stdcontext.Context, tenant string) (string, error) {
+		created++
+		return "db_" + tenant, nil
+	})
+	r := router.NewRouter(router.WithTenancy(tenancy.FromHeader("X-Tenant-ID"), tenancy.WithRequired(),
+		tenancy.WithLookup(func(ctx stdcontext.Context, tenant string) (bool, error) {
+			return tenant != "unknown", nil
+		})))
+	r.Get("/db", func(ctx *context.Context) {
+		db, err := databases.For(ctx.Req)
+		if err != nil {
+			ctx.Error(http.StatusInternalServerError, err.Error())
+			return
+		}
+		ctx.Send(ctx.Tenant() + ":" + db)
+	})
+
+	serve := func(tenant string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/db", nil)
+		if tenant != "" {
+			req.Header.Set("X-Tenant-ID", tenant)
+		}
+		rec := httptest.NewRecorder()
+		r.Handler().ServeHTTP(rec, req)
+		return rec
+	}
+	for i := 0; i < 2; i++ {
+		if rec := serve("acme"); rec.Body.String() != "acme:db_acme" {
+			t.Errorf("Unexpected body %q", rec.Body.String())
+		}
+	}
+	if created != 1 {
+		t.Errorf("Expected one instance per tenant, created %d", created)
+	}
+	if rec := serve(""); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without tenant, got %d", rec.Code)
+	}
+	if rec := serve("unknown"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown tenant, got %d", rec.Code)
+	}
+
+	if _, err := databases.For(httptest.NewRequest(http.MethodGet, "/", nil)); !errors.Is(err, tenancy.ErrNoTenant) {
+		t.Errorf("Expected ErrNoTenant, got %v", err)
+	}
+}
+
+func TestTenancy_RateLimitIgnoresTenant(t *testing.T) {
+	limiter := middleware.NewRateLimiter(middleware.InMemory, middleware.InMemoryConfig{
+		NumShards: 1, Limit: 2, Interval: time.Minute, CleanupInterval: time.Minute,
+	})
+	handler := tenancy.New(tenancy.FromHeader("X-Tenant-ID")).Handle(limiter.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	var codes []int
+	for _, tenant := range []string{"a", "b", "c"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Tenant-ID", tenant)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		codes = append(codes, rec.Code)
+	}
+	if codes[2] != http.StatusTooManyRequests {
+		t.Errorf("Expected switching tenants not to reset the limit, got %v", codes)
+	}
+}
+
+func TestTenancy_InvalidID(t *testing.T) {
+	handler := tenancy.New(tenancy.FromHeader("X-Tenant-ID")).Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Tenant-ID", "acme:other")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a malformed tenant, got %d", rec.Code)
+	}
+	if tenancy.ValidID(strings.Repeat("a", 64)) || !tenancy.ValidID("acme_2-eu") {
+		t.Error("Unexpected tenant ID validation")
+	}
+}
+
+func TestScoped_LookupAndConcurrentCreation(t *testing.T) {
+	release := make(chan struct{})
+	var created atomic.Int32
+	scoped := tenancy.NewScoped(func(ctx stdcontext.Context, tenant string) (string, error) {
+		created.Add(1)
+		if tenant == "slow" {
+			<-release
+		}
+		return "db_" + tenant, nil
+	}).WithLookup(func(ctx stdcontext.Context, tenant string) (bool, error) {
+		return tenant != "unknown", nil
+	})
+	ctx := stdcontext.Background()
+
+	if _, err := scoped.Get(ctx, "unknown"); !errors.Is(err, tenancy.ErrInvalidTenant) {
+		t.Errorf("Expected ErrInvalidTenant for an unknown tenant, got %v", err)
+	}
+	if _, err := scoped.Get(ctx, "../etc"); !errors.Is(err, tenancy.ErrInvalidTenant) {
+		t.Errorf("Expected ErrInvalidTenant for a malformed tenant, got %v", err)
+	}
+
+	slow := make(chan string)
+	go func() {
+		db, _ := scoped.Get(ctx, "slow")
+		slow <- db
+	}()
+	for created.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	// Another tenant is not blocked by the creation in progress
+	if db, err := scoped.Get(ctx, "acme"); err != nil || db != "db_acme" {
+		t.Errorf("Expected db_acme, got %q %v", db, err)
+	}
+	waiting, cancel := stdcontext.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := scoped.Get(waiting, "slow"); !errors.Is(err, stdcontext.DeadlineExceeded) {
+		t.Errorf("Expected waiting for the instance to end with the context, got %v", err)
+	}
+	close(release)
+	if db := <-slow; db != "db_slow" {
+		t.Errorf("Expected db_slow, got %q", db)
+	}
+	if db, _ := scoped.Get(ctx, "slow"); db != "db_slow" || created.Load() != 2 {
+		t.Errorf("Expected the instance to be reused, got %q after %d creations", db, created.Load())
+	}
+	count := 0
+	scoped.Each(func(tenant string, db string) { count++ })
+	if count != 2 {
+		t.Errorf("Expected 2 instances, got %d", count)
+	}
+}
+
+// middlewareFunc adapts a function to middleware.Middleware.
+type middlewareFunc func(next http.Handler) http.Handler
+
+func (f middlewareFunc) Handle(next http.Handler) http.Handler {
+	return f(next)
+}
+
+func TestTenancy_FromClaimThroughRouter(t *testing.T) {
+	r := router.NewRouter(router.WithTenancy(tenancy.FromClaim("tid"), tenancy.WithRequired()))
+	// Registered first, so it runs after the authentication like a response cache
+	var seenByCache []string
+	r.Use(middlewareFunc(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			seenByCache = append(seenByCache, middleware.RequestTenant(req))
+			next.ServeHTTP(w, req)
+		})
+	}))
+	r.Use(middlewareFunc(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if tenant := req.Header.Get("Authorization"); tenant != "" {
+				req = middleware.SetValue(req, middleware.ClaimsKey, middleware.Claims{"tid": strings.TrimPrefix(tenant, "Bearer ")})
+			}
+			next.ServeHTTP(w, req)
+		})
+	}))
+	r.Get("/orders", func(ctx *context.Context) {
+		ctx.Send("orders of " + ctx.Tenant())
+	})
+
+	serve := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		r.Handler().ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := serve("initech"); rec.Code != http.StatusOK || rec.Body.String() != "orders of initech" {
+		t.Errorf("Expected the tenant of the claim, got %d %q", rec.Code, rec.Body.String())
+	}
+	if len(seenByCache) != 1 || seenByCache[0] != "initech" {
+		t.Errorf("Expected middlewares after the authentication to see the tenant, got %v", seenByCache)
+	}
+	if rec := serve(""); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without a claim, got %d", rec.Code)
+	}
+	if rec := serve("acme:other"); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "Invalid tenant") {
+		t.Errorf("Expected 400 for a malformed claim, got %d %q", rec.Code, rec.Body.String())
+	}
+	if seenByCache[2] != "" {
+		t.Errorf("Expected a malformed tenant not to reach the cache, got %q", seenByCache[2])
+	}
+}
@@ -0,0 +1,249 @@
+package twofactor_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base32"
+	"image/png"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/cache"
+	lessctx "github.com/hokamsingh/lessgo/internal/core/context"
+	"github.com/hokamsingh/lessgo/internal/core/twofactor"
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+)
+
+// concurrently runs fn in n goroutines at once and returns how many returned true.
+func concurrently(n int, fn func() bool) int32 {
+	var wg sync.WaitGroup
+	var succeeded int32
+	start := make(chan struct{})
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			if fn() {
+				atomic.AddInt32(&succeeded, 1)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+	return succeeded
+}
+
+func TestTOTP_RFC6238(t *testing.T) {
+	secret := func(s string) string { return base32.StdEncoding.EncodeToString([]byte(s)) }
+	cases := []struct {
+		algorithm, secret string
+		at                int64
+		code              string
+	}{
+		{"SHA1", "12345678901234567890", 59, "94287082"},
+		{"SHA1", "12345678901234567890", 1111111109, "07081804"},
+		{"SHA256", "12345678901234567890123456789012", 59, "46119246"},
+		{"SHA512", "1234567890123456789012345678901234567890123456789012345678901234", 59, "90693936"},
+		{"SHA1", "12345678901234567890", 20000000000, "65353130"},
+	}
+	for _, c := range cases {
+		totp := twofactor.New("Acme", twofactor.WithDigits(8), twofactor.WithAlgorithm(c.algorithm))
+		code, err := totp.Code(secret(c.secret), time.Unix(c.at, 0))
+		if err != nil || code != c.code {
+			t.Errorf("%s at %d: expected %s, got %s %v", c.algorithm, c.at, c.code, code, err)
+		}
+	}
+}
+
+func TestTOTP_Validate(t *testing.T) {
+	secret, err := twofactor.GenerateSecret()
+	if err != nil || len(secret) != 32 {
+		t.Fatalf("Expected a 32 character secret, got %q %v", secret, err)
+	}
+	totp := twofactor.New("Acme")
+	now, _ := totp.Code(secret, time.Now())
+	if !totp.Validate(secret, now) || !totp.Validate(strings.ToLower(secret), now[:3]+" "+now[3:]) {
+		t.Error("Expected the current code to be valid")
+	}
+	// Whole periods keep the distance to the current counter
+	drifted, _ := totp.Code(secret, time.Now().Add(-90*time.Second))
+	if totp.Validate(secret, drifted) {
+		t.Error("Expected codes outside of the skew to be refused")
+	}
+	wide := twofactor.New("Acme", twofactor.WithSkew(4))
+	if !wide.Validate(secret, drifted) {
+		t.Error("Expected a wider skew to accept the code")
+	}
+	if totp.Validate("not base32!", now) || totp.Validate(secret, "12345") {
+		t.Error("Expected invalid input to be refused")
+	}
+}
+
+func TestTOTP_ValidateOnce(t *testing.T) {
+	secret, _ := twofactor.GenerateSecret()
+	totp := twofactor.New("Acme", twofactor.WithReplayStore(cache.NewMemoryStore(cache.New())))
+	code, _ := totp.Code(secret, time.Now())
+	ctx := context.Background()
+	if ok, err := totp.ValidateOnce(ctx, "alice", secret, code); !ok || err != nil {
+		t.Fatalf("Expected the first use to be accepted, got %v %v", ok, err)
+	}
+	if ok, _ := totp.ValidateOnce(ctx, "alice", secret, code); ok {
+		t.Error("Expected a replayed code to be refused")
+	}
+	if ok, _ := totp.ValidateOnce(ctx, "bob", secret, code); !ok {
+		t.Error("Expected other accounts to be tracked separately")
+	}
+
+	accepted := concurrently(20, func() bool {
+		ok, _ := totp.ValidateOnce(ctx, "carol", secret, code)
+		return ok
+	})
+	if accepted != 1 {
+		t.Errorf("Expected one of the concurrent logins to be accepted, got %d", accepted)
+	}
+}
+
+func TestTOTP_InvalidOptionsAreIgnored(t *testing.T) {
+	totp := twofactor.New("Acme", twofactor.WithPeriod(500*time.Millisecond), twofactor.WithDigits(10))
+	code, err := totp.Code("JBSWY3DPEHPK3PXP", time.Now())
+	if err != nil || len(code) != 6 {
+		t.Errorf("Expected a 6 digit code with the default period, got %q %v", code, err)
+	}
+	if uri := totp.URI("alice", "JBSWY3DPEHPK3PXP"); !strings.Contains(uri, "digits=6") || !strings.Contains(uri, "period=30") {
+		t.Errorf("Expected the defaults in %s", uri)
+	}
+	if code, _ := twofactor.New("Acme", twofactor.WithDigits(9)).Code("JBSWY3DPEHPK3PXP", time.Now()); len(code) != 9 {
+		t.Errorf("Expected a 9 digit code, got %q", code)
+	}
+}
+
+func TestTOTP_Provisioning(t *testing.T) {
+	totp := twofactor.New("Acme Inc")
+	uri := totp.URI("alice@example.com", "JBSWY3DPEHPK3PXP")
+	expected := "otpauth://totp/Acme%20Inc:alice@example.com?algorithm=SHA1&digits=6&issuer=Acme%20Inc&period=30&secret=JBSWY3DPEHPK3PXP"
+	if uri != expected {
+		t.Errorf("Expected %s, got %s", expected, uri)
+	}
+
+	data, err := totp.QRCode("alice@example.com", "JBSWY3DPEHPK3PXP", 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Expected a PNG, got %v", err)
+	}
+	if bounds := img.Bounds(); bounds.Dx() != bounds.Dy() || bounds.Dx() > 200 {
+		t.Errorf("Unexpected size %v", bounds)
+	}
+	// A quiet zone of 4 modules precedes the finder pattern, giving the module size
+	quiet := 0
+	for r, _, _, _ := img.At(quiet, quiet).RGBA(); r != 0; r, _, _, _ = img.At(quiet, quiet).RGBA() {
+		quiet++
+	}
+	if modules := img.Bounds().Dx()*4/quiet - 8; quiet%4 != 0 || (modules-17)%4 != 0 {
+		t.Errorf("Expected the width of a QR code version, got %d modules", modules)
+	}
+	if _, err := twofactor.QRCode(strings.Repeat("a", 300), 200); err != twofactor.ErrQRTooLong {
+		t.Errorf("Expected content too long, got %v", err)
+	}
+}
+
+func TestQRCode_DecodesBack(t *testing.T) {
+	// Contents of several versions, up to the largest one supported
+	for _, content := range []string{
+		"otpauth://totp/Acme:alice@example.com?secret=JBSWY3DPEHPK3PXP&issuer=Acme",
+		"hello",
+		strings.Repeat("0123456789", 10),
+		strings.Repeat("x", 213),
+	} {
+		data, err := twofactor.QRCode(content, 400)
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		bitmap, err := gozxing.NewBinaryBitmapFromImage(img)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := qrcode.NewQRCodeReader().Decode(bitmap, map[gozxing.DecodeHintType]interface{}{gozxing.DecodeHintType_PURE_BARCODE: true})
+		if err != nil {
+			t.Errorf("Expected %q to decode, got %v", content, err)
+			continue
+		}
+		if result.GetText() != content {
+			t.Errorf("Expected %q, decoded %q", content, result.GetText())
+		}
+	}
+}
+
+func TestQRCode_TooLong(t *testing.T) {
+	if _, err := twofactor.QRCode(strings.Repeat("a", 300), 200); err != twofactor.ErrQRTooLong {
+		t.Errorf("Expected content too long, got %v", err)
+	}
+}
+
+type session struct{ verified bool }
+
+func (s session) TwoFactorVerified() bool { return s.verified }
+
+func TestGuard(t *testing.T) {
+	newContext := func() *lessctx.Context {
+		return lessctx.NewContext(httptest.NewRequest("GET", "/", nil), httptest.NewRecorder())
+	}
+	ctx := newContext()
+	if twofactor.Guard(ctx) {
+		t.Error("Expected requests without a session to be refused")
+	}
+	ctx.SetSession(session{verified: false})
+	if twofactor.Guard(ctx) {
+		t.Error("Expected an unverified session to be refused")
+	}
+	ctx = newContext()
+	ctx.SetSession(session{verified: true})
+	if !twofactor.Guard(ctx) {
+		t.Error("Expected a verified session to be accepted")
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	recovery := twofactor.NewRecoveryCodes(cache.NewMemoryStore(cache.New()), twofactor.WithRecoveryCount(3))
+	ctx := context.Background()
+	codes, err := recovery.Generate(ctx, "alice")
+	if err != nil || len(codes) != 3 || len(codes[0]) != 11 || codes[0][5] != '-' {
+		t.Fatalf("Unexpected codes %v %v", codes, err)
+	}
+	if ok, _ := recovery.Use(ctx, "alice", strings.ToUpper(strings.Replace(codes[1], "-", "", 1))); !ok {
+		t.Error("Expected a code to be accepted in any case and without the dash")
+	}
+	if ok, _ := recovery.Use(ctx, "alice", codes[1]); ok {
+		t.Error("Expected a used code to be refused")
+	}
+	if ok, _ := recovery.Use(ctx, "bob", codes[0]); ok {
+		t.Error("Expected codes of another account to be refused")
+	}
+	if remaining, _ := recovery.Remaining(ctx, "alice"); remaining != 2 {
+		t.Errorf("Expected 2 remaining codes, got %d", remaining)
+	}
+	codes, _ = recovery.Generate(ctx, "alice")
+	if ok, _ := recovery.Use(ctx, "alice", codes[1]); !ok {
+		t.Error("Expected a regenerated code to be accepted")
+	}
+
+	accepted := concurrently(20, func() bool {
+		ok, _ := recovery.Use(ctx, "alice", codes[2])
+		return ok
+	})
+	if accepted != 1 {
+		t.Errorf("Expected the code to be used once, got %d", accepted)
+	}
+}
@@ -0,0 +1,201 @@
+package upload_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/storage"
+	"github.com/hokamsingh/lessgo/internal/core/upload"
+)
+
+func newTus(t *testing.T, options ...func(*upload.Tus)) (*upload.Tus, *storage.LocalStorage) {
+	t.Helper()
+	store, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tus, err := upload.NewTus(store, "/files", append([]func(*upload.Tus){upload.WithTusDir(t.TempDir())}, options...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { tus.Close() })
+	return tus, store
+}
+
+func tusRequest(tus *upload.Tus, method, target string, body io.Reader, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, body)
+	req.Header.Set("Tus-Resumable", upload.TusVersion)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	rec := httptest.NewRecorder()
+	tus.ServeHTTP(rec, req)
+	return rec
+}
+
+// brokenReader returns data then fails, like a dropped connection.
+type brokenReader struct {
+	data string
+	read bool
+}
+
+func (r *brokenReader) Read(p []byte) (int, error) {
+	if r.read {
+		return 0, errors.New("connection reset")
+	}
+	r.read = true
+	return copy(p, r.data), nil
+}
+
+func TestTus_ResumesInterruptedUploads(t *testing.T) {
+	var completed *upload.Upload
+	tus, store := newTus(t, upload.WithTusOnComplete(func(ctx context.Context, u *upload.Upload) error {
+		completed = u
+		return nil
+	}))
+
+	rec := tusRequest(tus, http.MethodPost, "/files", nil, map[string]string{
+		"Upload-Length":   "11",
+		"Upload-Metadata": "filename aGVsbG8udHh0,filetype dGV4dC9wbGFpbg==",
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected the upload to be created, got %d %s", rec.Code, rec.Body.String())
+	}
+	location := rec.Header().Get("Location")
+	if !strings.HasPrefix(location, "/files/") || rec.Header().Get("Upload-Expires") == "" {
+		t.Fatalf("Unexpected creation headers %v", rec.Header())
+	}
+
+	patch := map[string]string{"Content-Type": "application/offset+octet-stream", "Upload-Offset": "0"}
+	if rec := tusRequest(tus, http.MethodPatch, location, &brokenReader{data: "hello"}, patch); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected the interrupted chunk to fail, got %d", rec.Code)
+	}
+	rec = tusRequest(tus, http.MethodHead, location, nil, nil)
+	if rec.Header().Get("Upload-Offset") != "5" || rec.Header().Get("Upload-Length") != "11" {
+		t.Fatalf("Expected the received bytes to be kept, got %v", rec.Header())
+	}
+	if rec := tusRequest(tus, http.MethodPatch, location, strings.NewReader(" world"), patch); rec.Code != http.StatusConflict {
+		t.Errorf("Expected a stale offset to conflict, got %d", rec.Code)
+	}
+
+	patch["Upload-Offset"] = "5"
+	rec = tusRequest(tus, http.MethodPatch, location, strings.NewReader(" world"), patch)
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Upload-Offset") != "11" {
+		t.Fatalf("Expected the upload to resume, got %d %v", rec.Code, rec.Header())
+	}
+	if completed == nil || completed.Progress() != 1 || !strings.HasSuffix(completed.Key, ".txt") {
+		t.Fatalf("Expected the completion hook to be called, got %+v", completed)
+	}
+	file, err := store.Get(context.Background(), completed.Key)
+	if err != nil {
+		t.Fatalf("Expected the file in the storage: %v", err)
+	}
+	data, _ := io.ReadAll(file)
+	file.Close()
+	if string(data) != "hello world" {
+		t.Errorf("Expected the assembled file, got %q", data)
+	}
+}
+
+func TestTus_CreationWithUpload(t *testing.T) {
+	tus, _ := newTus(t, upload.WithTusMaxSize(100))
+	rec := tusRequest(tus, http.MethodOptions, "/files", nil, nil)
+	if rec.Header().Get("Tus-Max-Size") != "100" || !strings.Contains(rec.Header().Get("Tus-Extension"), "creation-with-upload") {
+		t.Errorf("Unexpected OPTIONS headers %v", rec.Header())
+	}
+	if rec := tusRequest(tus, http.MethodPost, "/files", nil, map[string]string{"Upload-Length": "101"}); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected uploads over the maximum size to be refused, got %d", rec.Code)
+	}
+
+	rec = tusRequest(tus, http.MethodPost, "/files", strings.NewReader("abc"), map[string]string{
+		"Upload-Length": "3",
+		"Content-Type":  "application/offset+octet-stream",
+	})
+	if rec.Code != http.StatusCreated || rec.Header().Get("Upload-Offset") != "3" {
+		t.Fatalf("Expected the upload to be created complete, got %d %v", rec.Code, rec.Header())
+	}
+	id := strings.TrimPrefix(rec.Header().Get("Location"), "/files/")
+	if u, err := tus.Get(id); err != nil || !u.Completed {
+		t.Errorf("Expected the upload completed, got %+v %v", u, err)
+	}
+
+	req := httptest.NewRequest(http.MethodHead, "/files/"+id, nil)
+	rec = httptest.NewRecorder()
+	tus.ServeHTTP(rec, req)
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected requests without Tus-Resumable to be refused, got %d", rec.Code)
+	}
+}
+
+func TestTus_ExpiresIncompleteUploads(t *testing.T) {
+	dir := t.TempDir()
+	tus, _ := newTus(t, upload.WithTusDir(dir), upload.WithTusExpiry(20*time.Millisecond))
+	rec := tusRequest(tus, http.MethodPost, "/files", nil, map[string]string{"Upload-Length": strconv.Itoa(10)})
+	location := rec.Header().Get("Location")
+	time.Sleep(30 * time.Millisecond)
+
+	if rec := tusRequest(tus, http.MethodHead, location, nil, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected the expired upload to be gone, got %d", rec.Code)
+	}
+	// The janitor may have swept it already
+	tus.Sweep()
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Expected the files of the expired upload removed, got %d", len(entries))
+	}
+}
+
+func TestTus_Terminate(t *testing.T) {
+	tus, _ := newTus(t)
+	location := tusRequest(tus, http.MethodPost, "/files", nil, map[string]string{"Upload-Length": "10"}).Header().Get("Location")
+	if rec := tusRequest(tus, http.MethodDelete, location, nil, nil); rec.Code != http.StatusNoContent {
+		t.Errorf("Expected the upload terminated, got %d", rec.Code)
+	}
+	if rec := tusRequest(tus, http.MethodHead, location, nil, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected the terminated upload to be gone, got %d", rec.Code)
+	}
+	if rec := tusRequest(tus, http.MethodHead, "/files/../../etc", nil, nil); rec.Code != http.StatusNotFound && rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected invalid IDs to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestTus_LocksUploadsAcrossInstances(t *testing.T) {
+	dir := t.TempDir()
+	first, _ := newTus(t, upload.WithTusDir(dir))
+	second, _ := newTus(t, upload.WithTusDir(dir))
+	location := tusRequest(first, http.MethodPost, "/files", nil, map[string]string{"Upload-Length": "10"}).Header().Get("Location")
+	patch := map[string]string{"Content-Type": "application/offset+octet-stream", "Upload-Offset": "0"}
+
+	body, writer := io.Pipe()
+	done := make(chan int)
+	go func() { done <- tusRequest(first, http.MethodPatch, location, body, patch).Code }()
+	writer.Write([]byte("hello"))
+	if rec := tusRequest(second, http.MethodPatch, location, strings.NewReader("hello"), patch); rec.Code != http.StatusLocked {
+		t.Errorf("Expected the other instance to see the upload in use, got %d", rec.Code)
+	}
+	if rec := tusRequest(second, http.MethodDelete, location, nil, nil); rec.Code != http.StatusLocked {
+		t.Errorf("Expected the upload not to be terminated while written, got %d", rec.Code)
+	}
+	writer.Close()
+	if code := <-done; code != http.StatusNoContent {
+		t.Fatalf("Expected the first chunk to be written, got %d", code)
+	}
+
+	// Locks left behind by a crashed instance are taken over once stale
+	id := strings.TrimPrefix(location, "/files/")
+	os.WriteFile(filepath.Join(dir, id+".lock"), nil, 0640)
+	stale := time.Now().Add(-time.Hour)
+	os.Chtimes(filepath.Join(dir, id+".lock"), stale, stale)
+	patch["Upload-Offset"] = "5"
+	if rec := tusRequest(second, http.MethodPatch, location, strings.NewReader("world"), patch); rec.Code != http.StatusNoContent {
+		t.Errorf("Expected the stale lock to be taken over, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,156 @@
+package webhook_test
+
+import (
+	stdcontext "context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hokamsingh/lessgo/internal/core/cache"
+	"github.com/hokamsingh/lessgo/internal/core/webhook"
+)
+
+const secret = "whsec_test"
+
+type pushEvent struct {
+	Ref string `json:"ref"`
+}
+
+func githubDelivery(body, delivery string, signature string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/github", strings.NewReader(body))
+	req.Header.Set("X-GitHub-Event", "push")
+	req.Header.Set("X-GitHub-Delivery", delivery)
+	req.Header.Set("X-Hub-Signature-256", "sha256="+signature)
+	return req
+}
+
+func TestReceiver_GitHubDispatchAndDedupe(t *testing.T) {
+	receiver := webhook.NewReceiver(webhook.GitHub, secret)
+	var refs []string
+	webhook.On(receiver, "push", func(ctx stdcontext.Context, event pushEvent) error {
+		refs = append(refs, event.Ref)
+		return nil
+	})
+
+	body := `{"ref":"refs/heads/main"}`
+	signature := webhook.Sign([]byte(secret), []byte(body))
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		receiver.ServeHTTP(w, githubDelivery(body, "delivery-1", signature))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", w.Code)
+		}
+	}
+	if len(refs) != 1 || refs[0] != "refs/heads/main" {
+		t.Errorf("Expected a single dispatched push event, got %v", refs)
+	}
+
+	w := httptest.NewRecorder()
+	receiver.ServeHTTP(w, githubDelivery(body, "delivery-2", webhook.Sign([]byte("wrong"), []byte(body))))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a wrong signature, got %d", w.Code)
+	}
+}
+
+func stripeDelivery(body string, signedAt time.Time) *http.Request {
+	timestamp := strconv.FormatInt(signedAt.Unix(), 10)
+	signature := webhook.Sign([]byte(secret), []byte(timestamp+"."+body))
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/stripe", strings.NewReader(body))
+	req.Header.Set("Stripe-Signature", "t="+timestamp+",v1="+signature)
+	return req
+}
+
+func TestReceiver_StripeTimestampTolerance(t *testing.T) {
+	receiver := webhook.NewReceiver(webhook.Stripe, secret, webhook.WithTolerance(time.Minute))
+	var received []string
+	receiver.OnAny(func(ctx stdcontext.Context, eventType string, body []byte) error {
+		received = append(received, eventType)
+		return nil
+	})
+
+	body := `{"id":"evt_1","type":"invoice.paid"}`
+	w := httptest.NewRecorder()
+	receiver.ServeHTTP(w, stripeDelivery(body, time.Now()))
+	if w.Code != http.StatusOK || len(received) != 1 || received[0] != "invoice.paid" {
+		t.Fatalf("Expected invoice.paid to be dispatched, got %d %v", w.Code, received)
+	}
+
+	w = httptest.NewRecorder()
+	receiver.ServeHTTP(w, stripeDelivery(`{"id":"evt_2","type":"invoice.paid"}`, time.Now().Add(-time.Hour)))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a stale delivery, got %d", w.Code)
+	}
+}
+
+func TestReceiver_FailedDeliveriesAreRetried(t *testing.T) {
+	receiver := webhook.NewReceiver(webhook.GitHub, secret)
+	calls := 0
+	webhook.On(receiver, "push", func(ctx stdcontext.Context, event pushEvent) error {
+		calls++
+		if calls == 1 {
+			return errors.New("database unavailable")
+		}
+		return nil
+	})
+
+	body := `{"ref":"refs/heads/main"}`
+	signature := webhook.Sign([]byte(secret), []byte(body))
+	w := httptest.NewRecorder()
+	receiver.ServeHTTP(w, githubDelivery(body, "delivery-1", signature))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected 500 when the handler fails, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	receiver.ServeHTTP(w, githubDelivery(body, "delivery-1", signature))
+	if w.Code != http.StatusOK || calls != 2 {
+		t.Errorf("Expected the retry to be processed, got %d after %d calls", w.Code, calls)
+	}
+}
+
+func TestReceiver_EmptySecretRefusesDeliveries(t *testing.T) {
+	receiver := webhook.NewReceiver(webhook.GitHub, "")
+	called := false
+	webhook.On(receiver, "push", func(ctx stdcontext.Context, event pushEvent) error {
+		called = true
+		return nil
+	})
+
+	body := `{"ref":"refs/heads/main"}`
+	w := httptest.NewRecorder()
+	receiver.ServeHTTP(w, githubDelivery(body, "delivery-1", webhook.Sign(nil, []byte(body))))
+	if w.Code != http.StatusUnauthorized || called {
+		t.Errorf("Expected deliveries signed with an empty key to be refused, got %d", w.Code)
+	}
+}
+
+func TestReceiver_SharedDedupeStore(t *testing.T) {
+	store := cache.NewMemoryStore(cache.New())
+	calls := 0
+	var receivers []*webhook.Receiver
+	for i := 0; i < 2; i++ {
+		// One receiver per instance of the application
+		receiver := webhook.NewReceiver(webhook.GitHub, secret, webhook.WithDedupeStore(store, time.Hour))
+		webhook.On(receiver, "push", func(ctx stdcontext.Context, event pushEvent) error {
+			calls++
+			return nil
+		})
+		receivers = append(receivers, receiver)
+	}
+
+	body := `{"ref":"refs/heads/main"}`
+	signature := webhook.Sign([]byte(secret), []byte(body))
+	for _, receiver := range receivers {
+		w := httptest.NewRecorder()
+		receiver.ServeHTTP(w, githubDelivery(body, "delivery-1", signature))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", w.Code)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Expected the retry to another instance to be skipped, got %d calls", calls)
+	}
+}
@@ -0,0 +1,121 @@
+package websocket_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	gorilla "github.com/gorilla/websocket"
+	"github.com/hokamsingh/lessgo/internal/core/websocket"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+type move struct {
+	X, Y int
+}
+
+func TestHub_BinaryCodec(t *testing.T) {
+	hub := websocket.NewHub(websocket.WithCodec(websocket.MsgPack))
+	websocket.OnMessage(hub, func(c *websocket.Client, m *move) {
+		c.SendValue(move{X: m.Y, Y: m.X})
+	})
+	go hub.Run()
+	server := httptest.NewServer(hub)
+	defer server.Close()
+
+	conn := dial(t, server, "ann")
+	defer conn.Close()
+	data, _ := msgpack.Marshal(move{X: 1, Y: 2})
+	if err := conn.WriteMessage(gorilla.BinaryMessage, data); err != nil {
+		t.Fatal(err)
+	}
+	messageType, reply, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got move
+	if messageType != gorilla.BinaryMessage || msgpack.Unmarshal(reply, &got) != nil || got != (move{X: 2, Y: 1}) {
+		t.Errorf("Expected a binary reply with the swapped move, got type %d: %v", messageType, got)
+	}
+}
+
+func TestHub_Compression(t *testing.T) {
+	hub := websocket.NewHub(websocket.WithCompression(6), websocket.WithMessageHandler(func(c *websocket.Client, data []byte, binary bool) {
+		c.Send([]byte(strings.Repeat(string(data), 100)))
+	}))
+	go hub.Run()
+	server := httptest.NewServer(hub)
+	defer server.Close()
+
+	dialer := gorilla.Dialer{EnableCompression: true}
+	conn, resp, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if !strings.Contains(resp.Header.Get("Sec-WebSocket-Extensions"), "permessage-deflate") {
+		t.Fatalf("Expected permessage-deflate to be negotiated, got %q", resp.Header.Get("Sec-WebSocket-Extensions"))
+	}
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	conn.WriteMessage(gorilla.TextMessage, []byte("abc"))
+	_, reply, err := conn.ReadMessage()
+	if err != nil || string(reply) != strings.Repeat("abc", 100) {
+		t.Errorf("Unexpected reply %q (%v)", reply, err)
+	}
+}
+
+func TestHub_MaxMessageSize(t *testing.T) {
+	hub := websocket.NewHub(websocket.WithMaxMessageSize(16), websocket.WithMessageHandler(func(c *websocket.Client, data []byte, binary bool) {
+		c.Send(data)
+	}))
+	go hub.Run()
+	server := httptest.NewServer(hub)
+	defer server.Close()
+
+	conn := dial(t, server, "ann")
+	defer conn.Close()
+	conn.WriteMessage(gorilla.TextMessage, []byte("small"))
+	if _, reply, err := conn.ReadMessage(); err != nil || string(reply) != "small" {
+		t.Fatalf("Expected the small message back, got %q (%v)", reply, err)
+	}
+	conn.WriteMessage(gorilla.TextMessage, []byte(strings.Repeat("x", 17)))
+	if _, _, err := conn.ReadMessage(); err == nil {
+		t.Error("Expected the connection to be closed after an oversized message")
+	}
+}
+
+type point struct {
+	X uint32
+}
+
+func (p *point) MarshalBinary() ([]byte, error) {
+	return binary.BigEndian.AppendUint32(nil, p.X), nil
+}
+
+func (p *point) UnmarshalBinary(data []byte) error {
+	if len(data) != 4 {
+		return errors.New("invalid point")
+	}
+	p.X = binary.BigEndian.Uint32(data)
+	return nil
+}
+
+func TestProtobufCodec(t *testing.T) {
+	data, err := websocket.Protobuf.Marshal(&point{X: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p point
+	if err := websocket.Protobuf.Unmarshal(data, &p); err != nil || p.X != 7 {
+		t.Errorf("Expected the point to round-trip, got %+v (%v)", p, err)
+	}
+	if _, err := websocket.Protobuf.Marshal(move{}); !errors.Is(err, websocket.ErrNotProtobuf) {
+		t.Errorf("Expected ErrNotProtobuf, got %v", err)
+	}
+	if !websocket.Protobuf.Binary() || websocket.JSON.Binary() {
+		t.Error("Expected protobuf in binary and JSON in text frames")
+	}
+}
@@ -0,0 +1,77 @@
+package websocket_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	gorilla "github.com/gorilla/websocket"
+	"github.com/hokamsingh/lessgo/internal/core/websocket"
+)
+
+func TestHub_Stats(t *testing.T) {
+	hub := websocket.NewHub(websocket.WithMessageHandler(func(c *websocket.Client, data []byte, binary bool) {
+		c.Send(data)
+	}))
+	go hub.Run()
+	server := httptest.NewServer(hub)
+	defer server.Close()
+
+	conn := dial(t, server, "ann")
+	defer conn.Close()
+	send(t, conn, "join_room:lobby", "join_room_success:lobby")
+	send(t, conn, "hello", "hello")
+
+	waitFor(t, func() bool { return hub.Stats().MessagesOut == 2 })
+	stats := hub.Stats()
+	if stats.Connections != 1 || stats.Rooms != 1 || stats.ConnectionsTotal != 1 || stats.MessagesIn != 2 {
+		t.Errorf("Unexpected hub stats %+v", stats)
+	}
+	connections := hub.Connections()
+	if len(connections) != 1 {
+		t.Fatalf("Expected one connection, got %d", len(connections))
+	}
+	if c := connections[0]; c.MessagesIn != 2 || c.MessagesOut != 2 || len(c.Rooms) != 1 || c.Rooms[0] != "lobby" || c.LastActivity.IsZero() {
+		t.Errorf("Unexpected connection stats %+v", c)
+	}
+
+	w := httptest.NewRecorder()
+	hub.MetricsHandler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	for _, line := range []string{"lessgo_websocket_connections 1", "lessgo_websocket_messages_received_total 2", "# TYPE lessgo_websocket_messages_sent_total counter"} {
+		if !strings.Contains(w.Body.String(), line) {
+			t.Errorf("Expected %q in the metrics, got:\n%s", line, w.Body.String())
+		}
+	}
+}
+
+func TestHub_IdleReaper(t *testing.T) {
+	var mu sync.Mutex
+	var reaped []websocket.ConnStats
+	hub := websocket.NewHub(websocket.WithIdleReaper(websocket.IdlePolicy{
+		Timeout:  100 * time.Millisecond,
+		Interval: 20 * time.Millisecond,
+		OnReap: func(stats websocket.ConnStats) {
+			mu.Lock()
+			defer mu.Unlock()
+			reaped = append(reaped, stats)
+		},
+	}))
+	go hub.Run()
+	server := httptest.NewServer(hub)
+	defer server.Close()
+
+	conn := dial(t, server, "ann")
+	defer conn.Close()
+	_, _, err := conn.ReadMessage()
+	if !gorilla.IsCloseError(err, gorilla.CloseGoingAway) {
+		t.Fatalf("Expected the idle connection to be closed, got %v", err)
+	}
+	waitFor(t, func() bool { return hub.Stats().Connections == 0 })
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reaped) == 0 || reaped[0].User != "root" || hub.Stats().Reaped == 0 {
+		t.Errorf("Expected the reaped connection to be reported, got %+v", reaped)
+	}
+}
@@ -0,0 +1,126 @@
+package websocket_test
+
+import (
+	stdcontext "context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	gorilla "github.com/gorilla/websocket"
+	"github.com/hokamsingh/lessgo/internal/core/websocket"
+)
+
+func dial(t *testing.T, server *httptest.Server, user string) *gorilla.Conn {
+	header := http.Header{"X-User": {user}}
+	conn, _, err := gorilla.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	return conn
+}
+
+func send(t *testing.T, conn *gorilla.Conn, message, reply string) {
+	if err := conn.WriteMessage(gorilla.TextMessage, []byte(message)); err != nil {
+		t.Fatal(err)
+	}
+	_, data, err := conn.ReadMessage()
+	if err != nil || string(data) != reply {
+		t.Fatalf("Expected %q, got %q (%v)", reply, data, err)
+	}
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestHub_Presence(t *testing.T) {
+	hub := websocket.NewHub(websocket.WithUserFunc(func(r *http.Request) string {
+		return r.Header.Get("X-User")
+	}))
+	go hub.Run()
+	server := httptest.NewServer(hub)
+	defer server.Close()
+
+	var mu sync.Mutex
+	var events []string
+	unsubscribe := hub.SubscribePresence(func(event websocket.PresenceEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event.User+" "+event.Type+" "+event.Room)
+	})
+	defer unsubscribe()
+	eventCount := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(events)
+	}
+	online := func() string {
+		users, err := hub.Online(stdcontext.Background(), "lobby")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.Join(users, ",")
+	}
+
+	ann := dial(t, server, "ann")
+	defer ann.Close()
+	annAgain := dial(t, server, "ann")
+	defer annAgain.Close()
+	bob := dial(t, server, "bob")
+	defer bob.Close()
+	send(t, ann, "join_room:lobby", "join_room_success:lobby")
+	send(t, annAgain, "join_room:lobby", "join_room_success:lobby")
+	send(t, bob, "join_room:lobby", "join_room_success:lobby")
+	if got := online(); got != "ann,bob" {
+		t.Errorf("Expected ann and bob online, got %q", got)
+	}
+	if rooms := hub.Rooms(); len(rooms) != 1 || rooms[0] != "lobby" {
+		t.Errorf("Unexpected rooms %v", rooms)
+	}
+
+	// Ann stays in the room while one of her clients is connected
+	send(t, ann, "leave_room:lobby", "leave_room_success:lobby")
+	if got := online(); got != "ann,bob" {
+		t.Errorf("Expected ann to stay online with her second client, got %q", got)
+	}
+	annAgain.Close()
+	waitFor(t, func() bool { return online() == "bob" })
+	bob.Close()
+	waitFor(t, func() bool { return online() == "" && eventCount() == 4 })
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"ann join lobby", "bob join lobby", "ann leave lobby", "bob leave lobby"}
+	for i, event := range want {
+		if events[i] != event {
+			t.Errorf("Expected events %v, got %v", want, events)
+			break
+		}
+	}
+}
+
+func TestMemoryPresence(t *testing.T) {
+	presence := websocket.NewMemoryPresence()
+	ctx := stdcontext.Background()
+	presence.Join(ctx, "lobby", "bob")
+	presence.Join(ctx, "lobby", "ann")
+	presence.Join(ctx, "games", "ann")
+	presence.Leave(ctx, "games", "ann")
+
+	if users, _ := presence.Members(ctx, "lobby"); strings.Join(users, ",") != "ann,bob" {
+		t.Errorf("Expected sorted members, got %v", users)
+	}
+	if users, _ := presence.Members(ctx, "games"); len(users) != 0 {
+		t.Errorf("Expected an empty room, got %v", users)
+	}
+}